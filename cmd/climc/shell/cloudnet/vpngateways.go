@@ -0,0 +1,149 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudnet
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/cloudnet"
+	base_options "yunion.io/x/onecloud/pkg/mcclient/options"
+	options "yunion.io/x/onecloud/pkg/mcclient/options/cloudnet"
+)
+
+func init() {
+	R(&options.VpnGatewayCreateOptions{}, "vpngateway-create", "Create vpn gateway", func(s *mcclient.ClientSession, opts *options.VpnGatewayCreateOptions) error {
+		params, err := base_options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		gw, err := modules.VpnGateways.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(gw)
+		return nil
+	})
+	R(&options.VpnGatewayGetOptions{}, "vpngateway-show", "Show vpn gateway", func(s *mcclient.ClientSession, opts *options.VpnGatewayGetOptions) error {
+		gw, err := modules.VpnGateways.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(gw)
+		return nil
+	})
+	R(&options.VpnGatewayListOptions{}, "vpngateway-list", "List vpn gateways", func(s *mcclient.ClientSession, opts *options.VpnGatewayListOptions) error {
+		params, err := base_options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.VpnGateways.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.VpnGateways.GetColumns(s))
+		return nil
+	})
+	R(&options.VpnGatewayUpdateOptions{}, "vpngateway-update", "Update vpn gateway", func(s *mcclient.ClientSession, opts *options.VpnGatewayUpdateOptions) error {
+		params, err := base_options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		gw, err := modules.VpnGateways.Update(s, opts.ID, params)
+		if err != nil {
+			return err
+		}
+		printObject(gw)
+		return nil
+	})
+	R(&options.VpnGatewayDeleteOptions{}, "vpngateway-delete", "Delete vpn gateway", func(s *mcclient.ClientSession, opts *options.VpnGatewayDeleteOptions) error {
+		gw, err := modules.VpnGateways.Delete(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(gw)
+		return nil
+	})
+	R(&options.VpnGatewayActionRealizeOptions{}, "vpngateway-realize", "Realize vpn gateway", func(s *mcclient.ClientSession, opts *options.VpnGatewayActionRealizeOptions) error {
+		gw, err := modules.VpnGateways.PerformAction(s, opts.ID, "realize", nil)
+		if err != nil {
+			return err
+		}
+		printObject(gw)
+		return nil
+	})
+	R(&options.VpnGatewayActionReportStatusOptions{}, "vpngateway-report-status", "Report tunnel status of vpn gateway", func(s *mcclient.ClientSession, opts *options.VpnGatewayActionReportStatusOptions) error {
+		params, err := opts.Params()
+		if err != nil {
+			return err
+		}
+		gw, err := modules.VpnGateways.PerformAction(s, opts.ID, "report-status", params)
+		if err != nil {
+			return err
+		}
+		printObject(gw)
+		return nil
+	})
+	R(&options.VpnGatewayPeerCreateOptions{}, "vpngateway-peer-create", "Create vpn gateway peer", func(s *mcclient.ClientSession, opts *options.VpnGatewayPeerCreateOptions) error {
+		params, err := base_options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		peer, err := modules.VpnGatewayPeers.Create(s, params)
+		if err != nil {
+			return err
+		}
+		printObject(peer)
+		return nil
+	})
+	R(&options.VpnGatewayPeerGetOptions{}, "vpngateway-peer-show", "Show vpn gateway peer", func(s *mcclient.ClientSession, opts *options.VpnGatewayPeerGetOptions) error {
+		peer, err := modules.VpnGatewayPeers.Get(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(peer)
+		return nil
+	})
+	R(&options.VpnGatewayPeerListOptions{}, "vpngateway-peer-list", "List vpn gateway peers", func(s *mcclient.ClientSession, opts *options.VpnGatewayPeerListOptions) error {
+		params, err := base_options.ListStructToParams(opts)
+		if err != nil {
+			return err
+		}
+		result, err := modules.VpnGatewayPeers.List(s, params)
+		if err != nil {
+			return err
+		}
+		printList(result, modules.VpnGatewayPeers.GetColumns(s))
+		return nil
+	})
+	R(&options.VpnGatewayPeerUpdateOptions{}, "vpngateway-peer-update", "Update vpn gateway peer", func(s *mcclient.ClientSession, opts *options.VpnGatewayPeerUpdateOptions) error {
+		params, err := base_options.StructToParams(opts)
+		if err != nil {
+			return err
+		}
+		peer, err := modules.VpnGatewayPeers.Update(s, opts.ID, params)
+		if err != nil {
+			return err
+		}
+		printObject(peer)
+		return nil
+	})
+	R(&options.VpnGatewayPeerDeleteOptions{}, "vpngateway-peer-delete", "Delete vpn gateway peer", func(s *mcclient.ClientSession, opts *options.VpnGatewayPeerDeleteOptions) error {
+		peer, err := modules.VpnGatewayPeers.Delete(s, opts.ID, nil)
+		if err != nil {
+			return err
+		}
+		printObject(peer)
+		return nil
+	})
+}
@@ -39,6 +39,11 @@ func init() {
 	cmd.Perform("purge", &compute.SDnsZoneIdOptions{})
 	cmd.Perform("add-vpcs", &compute.DnsZoneAddVpcsOptions{})
 	cmd.Perform("remove-vpcs", &compute.DnsZoneRemoveVpcsOptions{})
+	cmd.Perform("set-transfer", &compute.DnsZoneSetTransferOptions{})
+	cmd.Perform("enable-dnssec", &compute.DnsZoneEnableDnssecOptions{})
+	cmd.Perform("disable-dnssec", &compute.SDnsZoneIdOptions{})
+	cmd.Perform("dnssec-rollover", &compute.DnsZoneDnssecRolloverOptions{})
+	cmd.Get("ds-records", &compute.SDnsZoneIdOptions{})
 	cmd.GetWithCustomShow("exports", func(result jsonutils.JSONObject) {
 		rr := make(map[string]string)
 		err := result.Unmarshal(&rr)
@@ -0,0 +1,44 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/cmd/climc/shell"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/options/compute"
+)
+
+func init() {
+	cmd := shell.NewResourceCmd(&modules.DrProtectionGroups)
+	cmd.List(&compute.DrProtectionGroupListOptions{})
+	cmd.Show(&compute.DrProtectionGroupIdOptions{})
+	cmd.Create(&compute.DrProtectionGroupCreateOptions{})
+	cmd.Update(&compute.DrProtectionGroupUpdateOptions{})
+	cmd.Delete(&compute.DrProtectionGroupIdOptions{})
+	cmd.Perform("enable", &compute.DrProtectionGroupIdOptions{})
+	cmd.Perform("disable", &compute.DrProtectionGroupIdOptions{})
+	cmd.Perform("add-guests", &compute.DrProtectionGroupGuestsOptions{})
+	cmd.Perform("remove-guests", &compute.DrProtectionGroupGuestsOptions{})
+	cmd.Perform("sync", &compute.DrProtectionGroupIdOptions{})
+	cmd.Perform("failover", &compute.DrProtectionGroupFailoverOptions{})
+	cmd.Perform("test-failover", &compute.DrProtectionGroupFailoverOptions{})
+	cmd.Perform("cleanup-test", &compute.DrProtectionGroupIdOptions{})
+	cmd.Perform("failback", &compute.DrProtectionGroupIdOptions{})
+
+	rpCmd := shell.NewResourceCmd(&modules.DrRecoveryPoints)
+	rpCmd.List(&compute.DrRecoveryPointListOptions{})
+	rpCmd.Show(&compute.DrRecoveryPointIdOptions{})
+	rpCmd.Delete(&compute.DrRecoveryPointIdOptions{})
+}
@@ -57,7 +57,10 @@ func init() {
 
 	R(&options.LoadbalancerListenerCreateOptions{}, "lblistener-create", "Create lblistener", func(s *mcclient.ClientSession, opts *options.LoadbalancerListenerCreateOptions) error {
 		// TODO make a generic one
-		params := jsonutils.Marshal(opts)
+		params, err := opts.Params()
+		if err != nil {
+			return err
+		}
 		lblistener, err := modules.LoadbalancerListeners.Create(s, params)
 		if err != nil {
 			return err
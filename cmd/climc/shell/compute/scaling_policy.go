@@ -73,12 +73,15 @@ func init() {
 	})
 
 	type ScalingAlarm struct {
-		AlarmCumulate  int     `help:"Cumulate times alarm will trigger, for 'alarm' trigger" json:"cumulate"`
-		AlarmCycle     int     `help:"Monitoring cycle for indicators, for 'alarm' trigger" json:"cycle"`
-		AlarmIndicator string  `help:"Indicator for 'alarm' trigger" json:"indicator"`
-		AlarmWrapper   string  `help:"Wrapper for Indicators" choices:"max|min|average" json:"wrapper"`
-		AlarmOperator  string  `help:"Operator between Indicator and Operator" json:"operator"`
-		AlarmValue     float64 `help:"Value of Indicator" json:"value"`
+		AlarmCumulate    int     `help:"Cumulate times alarm will trigger, for 'alarm' trigger" json:"cumulate"`
+		AlarmCycle       int     `help:"Monitoring cycle for indicators, for 'alarm' trigger" json:"cycle"`
+		AlarmIndicator   string  `help:"Indicator for 'alarm' trigger" choices:"cpu|mem|disk_read|disk_write|flow_into|flow_out|custom" json:"indicator"`
+		AlarmDatabase    string  `help:"Tsdb database of custom indicator" json:"database"`
+		AlarmMeasurement string  `help:"Measurement of custom indicator, the series must be tagged with vm_scaling_group_id" json:"measurement"`
+		AlarmField       string  `help:"Field of custom indicator" json:"field"`
+		AlarmWrapper     string  `help:"Wrapper for Indicators" choices:"max|min|average" json:"wrapper"`
+		AlarmOperator    string  `help:"Operator between Indicator and Operator" json:"operator"`
+		AlarmValue       float64 `help:"Value of Indicator" json:"value"`
 	}
 
 	type ScalingPolicyCreateOptions struct {
@@ -133,6 +136,10 @@ func init() {
 					Wrapper:   args.AlarmWrapper,
 					Operator:  args.AlarmOperator,
 					Value:     args.AlarmValue,
+
+					Database:    args.AlarmDatabase,
+					Measurement: args.AlarmMeasurement,
+					Field:       args.AlarmField,
 				},
 				Action:      args.Action,
 				Number:      args.Number,
@@ -15,6 +15,7 @@
 package compute
 
 import (
+	"fmt"
 	"os"
 
 	"yunion.io/x/jsonutils"
@@ -29,12 +30,36 @@ import (
 
 func init() {
 	R(&options.PodCreateOptions{}, "pod-create", "Create a container pod", func(s *mcclient.ClientSession, opts *options.PodCreateOptions) error {
-		params, err := opts.Params()
-		if err != nil {
-			return err
+		var params *computeapi.ServerCreateInput
+		var err error
+		if len(opts.FromFile) > 0 {
+			convertParams, err := opts.ConvertParams()
+			if err != nil {
+				return err
+			}
+			ret, err := modules.Servers.PerformClassAction(s, "convert-pod-manifest", convertParams)
+			if err != nil {
+				return errors.Wrap(err, "convert pod manifest")
+			}
+			output := new(computeapi.PodConvertManifestOutput)
+			if err := ret.Unmarshal(output); err != nil {
+				return errors.Wrap(err, "unmarshal convert output")
+			}
+			for _, field := range output.Unmapped {
+				fmt.Fprintf(os.Stderr, "Warning: %s isn't converted\n", field)
+			}
+			params, err = opts.FromFileParams(output)
+			if err != nil {
+				return err
+			}
+		} else {
+			params, err = opts.Params()
+			if err != nil {
+				return err
+			}
 		}
-		if opts.Count > 1 {
-			results := modules.Servers.BatchCreate(s, params.JSON(params), opts.Count)
+		if params.Count > 1 {
+			results := modules.Servers.BatchCreate(s, params.JSON(params), params.Count)
 			printBatchResults(results, modules.Servers.GetColumns(s))
 		} else {
 			server, err := modules.Servers.Create(s, params.JSON(params))
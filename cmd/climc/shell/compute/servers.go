@@ -16,6 +16,7 @@ package compute
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -130,6 +131,8 @@ func init() {
 	cmd.BatchPerform("sync-os-info", &options.ServerIdsOptions{})
 	cmd.BatchPerform("set-root-disk-matcher", &options.ServerSetRootDiskMatcher{})
 	cmd.Perform("disable-auto-merge-snapshot", &options.ServerDisableAutoMergeSnapshot{})
+	cmd.Perform("attach-virtiofs", &options.ServerAttachVirtiofsOptions{})
+	cmd.Perform("detach-virtiofs", &options.ServerDetachVirtiofsOptions{})
 	cmd.Get("vnc", new(options.ServerVncOptions))
 	cmd.Get("desc", new(options.ServerIdOptions))
 	cmd.Get("status", new(options.ServerIdOptions))
@@ -143,6 +146,24 @@ func init() {
 	cmd.Get("sshport", new(options.ServerIdOptions))
 	cmd.Get("qemu-info", new(options.ServerIdOptions))
 	cmd.Get("hardware-info", new(options.ServerIdOptions))
+	cmd.GetWithCustomShow("serial-log", func(data jsonutils.JSONObject) {
+		content, _ := data.GetString("content")
+		fmt.Print(content)
+	}, new(options.ServerSerialLogOptions))
+	cmd.GetWithCustomOptionShow("screendump", func(data jsonutils.JSONObject, args shell.IGetOpt) {
+		opts := args.(*options.ServerScreenDumpOptions)
+		b64, _ := data.GetString("data")
+		img, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			log.Errorf("decode screendump: %s", err)
+			return
+		}
+		if err := ioutil.WriteFile(opts.Output, img, 0644); err != nil {
+			log.Errorf("write %s: %s", opts.Output, err)
+			return
+		}
+		fmt.Printf("screendump saved to %s\n", opts.Output)
+	}, new(options.ServerScreenDumpOptions))
 
 	cmd.GetProperty(&options.ServerStatusStatisticsOptions{})
 	cmd.GetProperty(&options.ServerProjectStatisticsOptions{})
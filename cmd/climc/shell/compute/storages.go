@@ -17,8 +17,11 @@ package compute
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/printutils"
 
 	"yunion.io/x/onecloud/cmd/climc/shell"
 	"yunion.io/x/onecloud/pkg/mcclient"
@@ -48,6 +51,15 @@ func init() {
 	cmd.Get("hardware-info", &options.BaseIdOptions{})
 	cmd.Perform("set-hardware-info", &compute.StorageSetHardwareInfoOptions{})
 	cmd.Perform("set-commit-bound", &compute.StorageSetCommitBoundOptions{})
+	cmd.GetWithCustomShow("usage-history", func(data jsonutils.JSONObject) {
+		samples, _ := data.GetArray("data")
+		printList(&printutils.ListResult{Data: samples}, nil)
+		growth, _ := data.Float("actual_growth_mb_per_day")
+		fmt.Printf("actual growth: %.2f MB/day\n", growth)
+		if exhaustAt, err := data.GetTime("actual_exhaust_at"); err == nil {
+			fmt.Printf("predicted exhaust at: %s\n", exhaustAt.Format(time.RFC3339))
+		}
+	}, &compute.StorageUsageHistoryOptions{})
 
 	type StorageCephRunOptions struct {
 		ID     string `help:"ID or name of ceph storage"`
@@ -15,6 +15,9 @@
 package image
 
 import (
+	"io"
+	"os"
+
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/pkg/errors"
 
@@ -31,6 +34,7 @@ func init() {
 		ImageNumber int      `help:"common image number of guest image"`
 		Protected   bool     `help:"if guest image is protected"`
 		Image       []string `help:"list of images"`
+		OvaUrl      string   `help:"url of ova appliance to import disks and guest template from"`
 	}
 
 	R(&GuestImageCreateOptions{}, "guest-image-create", "Create guest image's metadata", func(s *mcclient.ClientSession,
@@ -53,6 +57,9 @@ func init() {
 			}
 			params.Add(jsonutils.Marshal(images), "images")
 		}
+		if len(args.OvaUrl) > 0 {
+			params.Add(jsonutils.NewString(args.OvaUrl), "ova_url")
+		}
 		ret, err := modules.GuestImages.Create(s, params)
 		if err != nil {
 			return err
@@ -192,6 +199,46 @@ func init() {
 		return nil
 	})
 
+	R(&GuestImageOptions{}, "guest-image-export-ova", "Export guest image as an ova appliance", func(s *mcclient.ClientSession,
+		args *GuestImageOptions) error {
+
+		result, err := modules.GuestImages.PerformAction(s, args.ID, "export-ova", nil)
+		if err != nil {
+			return err
+		}
+		printObject(result)
+		return nil
+	})
+
+	type GuestImageDownloadOvaOptions struct {
+		ID     string `help:"Guest Image id or name"`
+		Output string `help:"Output file" short-token:"o"`
+	}
+	R(&GuestImageDownloadOvaOptions{}, "guest-image-download-ova", "Download exported ova of guest image to a file or stdout", func(s *mcclient.ClientSession,
+		args *GuestImageDownloadOvaOptions) error {
+
+		id, err := modules.GuestImages.GetId(s, args.ID, nil)
+		if err != nil {
+			return err
+		}
+		src, _, err := modules.GuestImages.DownloadOva(s, id)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		var sink io.Writer = os.Stdout
+		if len(args.Output) > 0 {
+			f, err := os.Create(args.Output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			sink = f
+		}
+		_, err = io.Copy(sink, src)
+		return err
+	})
+
 	type GuestImageOperationOptions struct {
 		ID []string `help:"Guest Image ID or Name"`
 	}
@@ -276,6 +276,9 @@ func init() {
 
 		EncryptKey string `help:"encrypt key id"`
 
+		Signature string `help:"base64 encoded signature of the image checksum"`
+		SignerKey string `help:"ID or name of the trusted signer key"`
+
 		ImageOptionalOptions
 	}
 	R(&ImageUploadOptions{}, "image-upload", "Upload a local image", func(s *mcclient.ClientSession, args *ImageUploadOptions) error {
@@ -284,6 +287,12 @@ func init() {
 		if len(args.EncryptKey) > 0 {
 			params.Add(jsonutils.NewString(args.EncryptKey), "encrypt_key_id")
 		}
+		if len(args.Signature) > 0 {
+			params.Add(jsonutils.NewString(args.Signature), "signature")
+		}
+		if len(args.SignerKey) > 0 {
+			params.Add(jsonutils.NewString(args.SignerKey), "signer_key_id")
+		}
 		err := addImageOptionalOptions(s, params, args.ImageOptionalOptions)
 		if err != nil {
 			return err
@@ -492,4 +501,54 @@ func init() {
 		printObject(img)
 		return nil
 	})
+
+	type ImageScanOptions struct {
+		ID string `help:"ID or name of image to scan"`
+	}
+	R(&ImageScanOptions{}, "image-scan", "Start scanning installed packages and vulnerabilities of image", func(s *mcclient.ClientSession, opts *ImageScanOptions) error {
+		img, err := modules.Images.PerformAction(s, opts.ID, "scan", nil)
+		if err != nil {
+			return err
+		}
+		printObject(img)
+		return nil
+	})
+
+	type ImageSbomOptions struct {
+		ID     string `help:"ID or name of image"`
+		Format string `help:"SBOM format" choices:"spdx|cyclonedx" default:"spdx"`
+		Output string `help:"Save SBOM to file instead of stdout" short-token:"o"`
+	}
+	R(&ImageSbomOptions{}, "image-sbom", "Show software bill of materials of a scanned image", func(s *mcclient.ClientSession, opts *ImageSbomOptions) error {
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(opts.Format), "format")
+		result, err := modules.Images.GetSpecific(s, opts.ID, "sbom", params)
+		if err != nil {
+			return err
+		}
+		if len(opts.Output) > 0 {
+			return os.WriteFile(opts.Output, []byte(result.PrettyString()), 0644)
+		}
+		fmt.Println(result.PrettyString())
+		return nil
+	})
+
+	type ImageVulnerabilitiesOptions struct {
+		ID          string `help:"ID or name of image"`
+		MinSeverity string `help:"Show vulnerabilities at or above the severity" choices:"low|medium|high|critical"`
+	}
+	R(&ImageVulnerabilitiesOptions{}, "image-vulnerabilities", "Show vulnerabilities found by image scan", func(s *mcclient.ClientSession, opts *ImageVulnerabilitiesOptions) error {
+		params := jsonutils.NewDict()
+		if len(opts.MinSeverity) > 0 {
+			params.Add(jsonutils.NewString(opts.MinSeverity), "min_severity")
+		}
+		result, err := modules.Images.GetSpecific(s, opts.ID, "vulnerabilities", params)
+		if err != nil {
+			return err
+		}
+		arrays, _ := result.GetArray("vulnerabilities")
+		listResult := printutils.ListResult{Data: arrays}
+		printList(&listResult, []string{"id", "severity", "package", "version", "fixed_version"})
+		return nil
+	})
 }
@@ -0,0 +1,35 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"yunion.io/x/onecloud/cmd/climc/shell"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
+	"yunion.io/x/onecloud/pkg/mcclient/options/glance"
+)
+
+func init() {
+	cmd := shell.NewResourceCmd(&modules.ImageReplications).WithKeyword("image-replication")
+	cmd.List(&glance.ImageReplicationListOptions{})
+	cmd.Create(&glance.ImageReplicationCreateOptions{})
+	cmd.Show(&glance.ImageReplicationIdOptions{})
+	cmd.Update(&glance.ImageReplicationUpdateOptions{})
+	cmd.Delete(&glance.ImageReplicationIdOptions{})
+	cmd.Perform("enable", &glance.ImageReplicationIdOptions{})
+	cmd.Perform("disable", &glance.ImageReplicationIdOptions{})
+	cmd.Perform("sync", &glance.ImageReplicationSyncOptions{})
+	cmd.Perform("add-target", &glance.ImageReplicationTargetOptions{})
+	cmd.Perform("remove-target", &glance.ImageReplicationTargetOptions{})
+}
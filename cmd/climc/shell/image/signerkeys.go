@@ -0,0 +1,84 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"os"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/cmd/climc/shell"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
+	"yunion.io/x/onecloud/pkg/mcclient/options/glance"
+	"yunion.io/x/onecloud/pkg/util/imagesign"
+)
+
+func init() {
+	cmd := shell.NewResourceCmd(&modules.ImageSignerKeys).WithKeyword("image-signer-key")
+	cmd.List(&glance.ImageSignerKeyListOptions{})
+	cmd.Create(&glance.ImageSignerKeyCreateOptions{})
+	cmd.Show(&glance.ImageSignerKeyIdOptions{})
+	cmd.Delete(&glance.ImageSignerKeyIdOptions{})
+	cmd.Perform("enable", &glance.ImageSignerKeyIdOptions{})
+	cmd.Perform("disable", &glance.ImageSignerKeyIdOptions{})
+
+	type ImageSignOptions struct {
+		ID             string `help:"ID or name of image"`
+		Signature      string `help:"base64 encoded signature of image checksum"`
+		PrivateKeyFile string `help:"PEM encoded ecdsa or ed25519 private key file to sign the image checksum locally"`
+		SignerKey      string `help:"ID or name of the trusted signer key"`
+	}
+	R(&ImageSignOptions{}, "image-sign", "Attach a detached signature of image checksum to an image", func(s *mcclient.ClientSession, args *ImageSignOptions) error {
+		signature := args.Signature
+		if len(signature) == 0 {
+			if len(args.PrivateKeyFile) == 0 {
+				return errors.Wrap(errors.ErrEmpty, "either --signature or --private-key-file is required")
+			}
+			data, err := os.ReadFile(args.PrivateKeyFile)
+			if err != nil {
+				return errors.Wrapf(err, "read %s", args.PrivateKeyFile)
+			}
+			priv, err := imagesign.ParsePrivateKey(string(data))
+			if err != nil {
+				return errors.Wrap(err, "ParsePrivateKey")
+			}
+			img, err := modules.Images.Get(s, args.ID, nil)
+			if err != nil {
+				return err
+			}
+			checksum, _ := img.GetString("checksum")
+			if len(checksum) == 0 {
+				return errors.Wrap(errors.ErrInvalidStatus, "image checksum is not ready")
+			}
+			signature, err = imagesign.Sign(priv, checksum)
+			if err != nil {
+				return err
+			}
+		}
+		params := jsonutils.NewDict()
+		params.Add(jsonutils.NewString(signature), "signature")
+		if len(args.SignerKey) > 0 {
+			params.Add(jsonutils.NewString(args.SignerKey), "signer_key_id")
+		}
+		img, err := modules.Images.PerformAction(s, args.ID, "set-signature", params)
+		if err != nil {
+			return err
+		}
+		printObject(img)
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudnet
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+const (
+	VPN_TUNNEL_TYPE_WIREGUARD = "wireguard"
+	VPN_TUNNEL_TYPE_IPSEC     = "ipsec"
+
+	VPN_PEER_STATUS_INIT = "init"
+	VPN_PEER_STATUS_UP   = "up"
+	VPN_PEER_STATUS_DOWN = "down"
+
+	// wireguard rekeys every 2 minutes when there is traffic, a peer
+	// without handshake for longer than this is considered down
+	VPN_PEER_HANDSHAKE_TIMEOUT_SECONDS = 180
+)
+
+type VpnGatewayUpdateInput struct {
+	apis.StandaloneResourceBaseUpdateInput
+
+	// vpc cidrs announced to remote sites, concatenated by comma
+	LocalCidrs string `json:"local_cidrs"`
+}
+
+type VpnGatewayReportStatusInput struct {
+	// output of "wg show <ifname> dump"
+	WgDump string `json:"wg_dump"`
+
+	// names of established ipsec connections as listed by "swanctl --list-sas"
+	IpsecEstablished []string `json:"ipsec_established"`
+}
+
+type VpnGatewayPeerUpdateInput struct {
+	apis.StatusStandaloneResourceBaseUpdateInput
+
+	Endpoint string `json:"endpoint"`
+
+	// remote site cidrs, concatenated by comma
+	RemoteCidrs string `json:"remote_cidrs"`
+
+	PersistentKeepalive *int `json:"persistent_keepalive"`
+
+	Psk string `json:"psk"`
+
+	RemoteId string `json:"remote_id"`
+}
+
+type VpnGatewayPeerDetails struct {
+	apis.StatusStandaloneResourceDetails
+
+	// seconds since the latest handshake, -1 if there was none
+	HandshakeAge int64 `json:"handshake_age"`
+}
@@ -441,6 +441,23 @@ type ServerConfigs struct {
 	// 主机组列表, 参数可以是主机组名称或ID,建议使用ID
 	InstanceGroupIds []string `json:"groups"`
 
+	// 虚拟机vNUMA节点数量, 0表示按宿主机NUMA分配情况自动生成, 仅KVM生效
+	// required: false
+	NumaNodes int `json:"numa_nodes"`
+
+	// 虚拟机内存后端, 仅KVM生效
+	// enum: hugepages_2m, hugepages_1g
+	// required: false
+	MemoryBacking string `json:"memory_backing"`
+
+	// vNUMA节点内存严格绑定到宿主机NUMA节点
+	// required: false
+	MemoryStrictBind bool `json:"memory_strict_bind"`
+
+	// virtiofs共享目录列表, 仅KVM生效
+	// required: false
+	Virtiofs []*ServerVirtiofsConfig `json:"virtiofs"`
+
 	// DEPRECATE
 	Suggestion bool `json:"suggestion"`
 }
@@ -475,6 +492,11 @@ type ServerCreateInput struct {
 	VmemSize       int  `json:"vmem_size"`
 	EnableMemclean bool `json:"enable_memclean"`
 
+	// 启用virtio-balloon设备,宿主机内存紧张时回收虚拟机空闲内存
+	EnableMemoryBalloon bool `json:"enable_memory_balloon"`
+	// 内存回收的下限,单位Mb,默认由宿主机配置决定
+	BalloonMinMemMb int `json:"balloon_min_mem_mb"`
+
 	// 虚拟机Cpu大小,若未指定instance_type,此参数为必传项
 	// default: 1
 	VcpuCount int `json:"vcpu_count"`
@@ -161,6 +161,8 @@ type DiskBackupPackMetadata struct {
 	// 操作系统类型
 	OsType     string
 	DiskConfig *SBackupDiskConfig
+	// 增量备份, 只含父备份包中同序号磁盘之后写入的数据
+	Incremental bool
 }
 
 type InstanceBackupPackMetadata struct {
@@ -178,6 +180,8 @@ type InstanceBackupPackMetadata struct {
 	EncryptKeyId string
 	// Instance Backup metadata
 	Metadata map[string]string `json:"metadata"`
+	// 增量磁盘所依赖的父备份包, 位于同一备份存储
+	ParentPackageName string
 }
 
 type InstanceBackupManagerSyncstatusInput struct {
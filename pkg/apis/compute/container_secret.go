@@ -0,0 +1,75 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+const (
+	CONTAINER_SECRET_STATUS_READY = apis.STATUS_AVAILABLE
+	CONTAINER_CONFIG_STATUS_READY = apis.STATUS_AVAILABLE
+)
+
+type ContainerSecretCreateInput struct {
+	apis.VirtualResourceCreateInput
+	apis.EncryptedResourceCreateInput
+
+	// Plain text values of the secret, which are encrypted by the encrypt key before saving
+	// required: true
+	Data map[string]string `json:"data"`
+}
+
+type ContainerSecretUpdateInput struct {
+	apis.VirtualResourceBaseUpdateInput
+
+	// Replace all the values of the secret
+	Data map[string]string `json:"data"`
+}
+
+type ContainerSecretListInput struct {
+	apis.VirtualResourceListInput
+}
+
+type ContainerSecretDetails struct {
+	apis.VirtualResourceDetails
+	apis.EncryptedResourceDetails
+
+	// Count of the containers which reference the secret
+	ContainerCount int `json:"container_count"`
+}
+
+type ContainerConfigCreateInput struct {
+	apis.VirtualResourceCreateInput
+
+	// required: true
+	Data map[string]string `json:"data"`
+}
+
+type ContainerConfigUpdateInput struct {
+	apis.VirtualResourceBaseUpdateInput
+
+	// Replace all the values of the config
+	Data map[string]string `json:"data"`
+}
+
+type ContainerConfigListInput struct {
+	apis.VirtualResourceListInput
+}
+
+type ContainerConfigDetails struct {
+	apis.VirtualResourceDetails
+
+	// Count of the containers which reference the config
+	ContainerCount int `json:"container_count"`
+}
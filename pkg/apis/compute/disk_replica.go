@@ -0,0 +1,119 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	// 同步复制, 虚拟机写入在副本完成后才返回
+	DISK_REPLICA_MODE_SYNC = "sync"
+	// 异步复制, 副本可能落后于主盘
+	DISK_REPLICA_MODE_ASYNC = "async"
+
+	DISK_REPLICA_STATUS_PREPARING       = "preparing"
+	DISK_REPLICA_STATUS_PREPARE_FAILED  = "prepare_failed"
+	DISK_REPLICA_STATUS_SYNCING         = "syncing"
+	DISK_REPLICA_STATUS_IN_SYNC         = "in_sync"
+	DISK_REPLICA_STATUS_LAGGING         = "lagging"
+	DISK_REPLICA_STATUS_PAUSED          = "paused"
+	DISK_REPLICA_STATUS_BROKEN          = "broken"
+	DISK_REPLICA_STATUS_FAILING_OVER    = "failing_over"
+	DISK_REPLICA_STATUS_FAILOVER_FAILED = "failover_failed"
+	DISK_REPLICA_STATUS_FAILED_OVER     = "failed_over"
+	DISK_REPLICA_STATUS_DELETING        = "deleting"
+	DISK_REPLICA_STATUS_DELETE_FAILED   = "delete_failed"
+)
+
+var (
+	// replicas in these status hold a complete copy of the disk and
+	// can take over the guest
+	DISK_REPLICA_FAILOVER_STATUS = []string{
+		DISK_REPLICA_STATUS_IN_SYNC,
+		DISK_REPLICA_STATUS_LAGGING,
+		DISK_REPLICA_STATUS_PAUSED,
+	}
+)
+
+type DiskReplicaCreateInput struct {
+	apis.StatusStandaloneResourceCreateInput
+
+	// 复制的本地盘
+	// required: true
+	DiskId string `json:"disk_id"`
+	// 副本所在宿主机
+	// required: true
+	ReplicaHostId string `json:"replica_host_id"`
+	// 副本所在存储, 为空时自动选择副本宿主机上容量足够的本地存储
+	ReplicaStorageId string `json:"replica_storage_id"`
+	// 复制模式, sync或async, 默认async
+	Mode string `json:"mode"`
+	// 异步复制允许的最大延迟, 单位MB, 超过后状态为lagging
+	MaxLagMb int `json:"max_lag_mb"`
+
+	GuestId string `json:"guest_id"`
+	HostId  string `json:"host_id"`
+}
+
+type DiskReplicaListInput struct {
+	apis.StatusStandaloneResourceListInput
+
+	DiskId []string `json:"disk_id"`
+	// 以虚拟机过滤
+	GuestId []string `json:"guest_id"`
+	// 以主盘所在宿主机过滤
+	HostId []string `json:"host_id"`
+	// 以副本所在宿主机过滤
+	ReplicaHostId []string `json:"replica_host_id"`
+	Mode          []string `json:"mode"`
+}
+
+type DiskReplicaUpdateInput struct {
+	apis.StatusStandaloneResourceBaseUpdateInput
+
+	MaxLagMb *int `json:"max_lag_mb"`
+}
+
+type DiskReplicaDetails struct {
+	apis.StatusStandaloneResourceDetails
+
+	SDiskReplica
+
+	Disk           string `json:"disk"`
+	Guest          string `json:"guest"`
+	Host           string `json:"host"`
+	ReplicaHost    string `json:"replica_host"`
+	ReplicaStorage string `json:"replica_storage"`
+}
+
+type DiskReplicaResyncInput struct {
+}
+
+type DiskReplicaFailoverInput struct {
+	// 主宿主机仍在线时也切换
+	Force bool `json:"force"`
+}
+
+// DiskReplicaJobStatus is the mirror job of a replicated disk reported by
+// the primary host
+type DiskReplicaJobStatus struct {
+	DiskId   string `json:"disk_id"`
+	Device   string `json:"device"`
+	Ready    bool   `json:"ready"`
+	Len      int64  `json:"len"`
+	Offset   int64  `json:"offset"`
+	IoStatus string `json:"io_status"`
+}
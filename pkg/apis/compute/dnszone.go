@@ -15,7 +15,12 @@
 package compute
 
 import (
+	"reflect"
+	"time"
+
 	"yunion.io/x/cloudmux/pkg/apis/compute"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
 
 	"yunion.io/x/onecloud/pkg/apis"
 )
@@ -29,6 +34,113 @@ const (
 	DNS_ZONE_STATUS_UNKNOWN       = compute.DNS_ZONE_STATUS_UNKNOWN   // 未知
 )
 
+const (
+	// 每个区域保留的变更记录数量, 超出范围的IXFR请求退化为AXFR
+	DNS_ZONE_CHANGE_JOURNAL_SIZE = 256
+
+	DNS_TSIG_ALGORITHM_HMAC_MD5    = "hmac-md5"
+	DNS_TSIG_ALGORITHM_HMAC_SHA1   = "hmac-sha1"
+	DNS_TSIG_ALGORITHM_HMAC_SHA256 = "hmac-sha256"
+	DNS_TSIG_ALGORITHM_HMAC_SHA512 = "hmac-sha512"
+)
+
+var DNS_TSIG_ALGORITHMS = []string{
+	DNS_TSIG_ALGORITHM_HMAC_MD5,
+	DNS_TSIG_ALGORITHM_HMAC_SHA1,
+	DNS_TSIG_ALGORITHM_HMAC_SHA256,
+	DNS_TSIG_ALGORITHM_HMAC_SHA512,
+}
+
+const (
+	DNSSEC_KEY_TYPE_KSK = "KSK"
+	DNSSEC_KEY_TYPE_ZSK = "ZSK"
+
+	// 已发布但不参与签名, 用于ZSK预发布轮换
+	DNSSEC_KEY_STATE_PUBLISHED = "published"
+	// 参与签名
+	DNSSEC_KEY_STATE_ACTIVE = "active"
+	// 已轮换, 保留发布直至缓存过期或上级区域DS更新
+	DNSSEC_KEY_STATE_RETIRED = "retired"
+
+	DNSSEC_ALGORITHM_RSASHA256       = "RSASHA256"
+	DNSSEC_ALGORITHM_ECDSAP256SHA256 = "ECDSAP256SHA256"
+	DNSSEC_ALGORITHM_ED25519         = "ED25519"
+)
+
+var DNSSEC_ALGORITHMS = []string{
+	DNSSEC_ALGORITHM_RSASHA256,
+	DNSSEC_ALGORITHM_ECDSAP256SHA256,
+	DNSSEC_ALGORITHM_ED25519,
+}
+
+type SDnssecKey struct {
+	// KSK or ZSK
+	KeyType string `json:"key_type"`
+	// published, active or retired
+	State  string `json:"state"`
+	KeyTag uint16 `json:"key_tag"`
+	// DNSKEY记录
+	DnsKey string `json:"dns_key"`
+	// BIND格式私钥, 加密存储
+	PrivateKey string `json:"private_key"`
+
+	CreatedAt time.Time `json:"created_at"`
+	// 进入当前状态的时间
+	StateChangedAt time.Time `json:"state_changed_at"`
+}
+
+type SDnssecKeys struct {
+	Keys []SDnssecKey `json:"keys"`
+}
+
+func (keys *SDnssecKeys) String() string {
+	return jsonutils.Marshal(keys).String()
+}
+
+func (keys *SDnssecKeys) IsZero() bool {
+	return keys == nil || len(keys.Keys) == 0
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&SDnssecKeys{}), func() gotypes.ISerializable {
+		return &SDnssecKeys{}
+	})
+}
+
+type DnsZoneEnableDnssecInput struct {
+	// 签名算法
+	// enum: ["RSASHA256", "ECDSAP256SHA256", "ED25519"]
+	// default: ECDSAP256SHA256
+	Algorithm string `json:"algorithm"`
+}
+
+type DnsZoneDisableDnssecInput struct {
+}
+
+type DnsZoneDnssecRolloverInput struct {
+	// 轮换的密钥类型
+	// ZSK轮换采用预发布方式, 新密钥发布并传播后才参与签名
+	// KSK轮换后新旧密钥同时签名, 旧密钥在上级区域更新DS记录的宽限期后移除
+	// enum: ["KSK", "ZSK"]
+	KeyType string `json:"key_type"`
+}
+
+type DnsZoneDnssecKeyInfo struct {
+	KeyType        string    `json:"key_type"`
+	State          string    `json:"state"`
+	KeyTag         uint16    `json:"key_tag"`
+	DnsKey         string    `json:"dns_key"`
+	CreatedAt      time.Time `json:"created_at"`
+	StateChangedAt time.Time `json:"state_changed_at"`
+}
+
+type DnsZoneDsRecordsOutput struct {
+	// 需要添加到上级区域的DS记录
+	DsRecords []string `json:"ds_records"`
+	// 区域当前的全部密钥
+	Keys []DnsZoneDnssecKeyInfo `json:"keys"`
+}
+
 type DnsZoneFilterListBase struct {
 	DnsZoneId string `json:"dns_zone_id"`
 	ManagedResourceListInput
@@ -51,6 +163,25 @@ type DnsZoneCreateInput struct {
 
 	// VPC id列表, 仅在zone_type为PrivateZone时生效, vpc列表必须属于同一个账号
 	VpcIds []string `json:"vpc_ids"`
+
+	DnsZoneTransferInput
+}
+
+// 区域传送(AXFR/IXFR)及NOTIFY设置, 仅本地区域支持
+// 未设置transfer_allow_ips及tsig_key_name时禁止区域传送
+type DnsZoneTransferInput struct {
+	// 允许区域传送的IP或CIDR列表, 为空时不限制来源地址
+	TransferAllowIps []string `json:"transfer_allow_ips"`
+	// TSIG密钥名称, 设置后区域传送请求必须携带有效的TSIG签名
+	TsigKeyName string `json:"tsig_key_name"`
+	// TSIG算法
+	// enum: ["hmac-md5", "hmac-sha1", "hmac-sha256", "hmac-sha512"]
+	// default: hmac-sha256
+	TsigAlgorithm string `json:"tsig_algorithm"`
+	// TSIG密钥, base64编码
+	TsigSecret string `json:"tsig_secret"`
+	// 辅DNS服务器地址列表, 格式为ip[:port], 区域变更时发送NOTIFY
+	NotifySecondaries []string `json:"notify_secondaries"`
 }
 
 type DnsZoneDetails struct {
@@ -0,0 +1,205 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"reflect"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	DR_PROTECTION_GROUP_STATUS_PROTECTING           = "protecting"
+	DR_PROTECTION_GROUP_STATUS_FAILING_OVER         = "failing_over"
+	DR_PROTECTION_GROUP_STATUS_FAILOVER_FAILED      = "failover_failed"
+	DR_PROTECTION_GROUP_STATUS_FAILED_OVER          = "failed_over"
+	DR_PROTECTION_GROUP_STATUS_FAILING_BACK         = "failing_back"
+	DR_PROTECTION_GROUP_STATUS_FAILBACK_FAILED      = "failback_failed"
+	DR_PROTECTION_GROUP_STATUS_TEST_FAILING_OVER    = "test_failing_over"
+	DR_PROTECTION_GROUP_STATUS_TEST_FAILOVER_FAILED = "test_failover_failed"
+	DR_PROTECTION_GROUP_STATUS_TESTING              = "testing"
+	DR_PROTECTION_GROUP_STATUS_CLEANING_TEST        = "cleaning_test"
+	DR_PROTECTION_GROUP_STATUS_DELETING             = "deleting"
+	DR_PROTECTION_GROUP_STATUS_DELETE_FAILED        = "delete_failed"
+
+	DR_RECOVERY_POINT_STATUS_CREATING      = "creating"
+	DR_RECOVERY_POINT_STATUS_PACKING       = "packing"
+	DR_RECOVERY_POINT_STATUS_READY         = "ready"
+	DR_RECOVERY_POINT_STATUS_CREATE_FAILED = "create_failed"
+	DR_RECOVERY_POINT_STATUS_DELETING      = "deleting"
+	DR_RECOVERY_POINT_STATUS_DELETE_FAILED = "delete_failed"
+
+	// 恢复点默认间隔, 单位分钟
+	DR_PROTECTION_GROUP_DEFAULT_RPO_MINUTES = 5
+	// 每台虚拟机默认保留的恢复点数量
+	DR_PROTECTION_GROUP_DEFAULT_RETAIN_COUNT = 4
+	// 默认连续增量恢复点的最大数量
+	DR_PROTECTION_GROUP_DEFAULT_FULL_INTERVAL = 96
+)
+
+var (
+	// groups in these status can be failed over or test failed over
+	DR_PROTECTION_GROUP_FAILOVER_STATUS = []string{
+		DR_PROTECTION_GROUP_STATUS_PROTECTING,
+		DR_PROTECTION_GROUP_STATUS_FAILOVER_FAILED,
+		DR_PROTECTION_GROUP_STATUS_TEST_FAILOVER_FAILED,
+	}
+)
+
+// DrNetworkMapping maps a network of the protected region to the network
+// the recovered guests attach to in the target region
+type DrNetworkMapping struct {
+	// 源区域网络
+	SourceNetworkId string `json:"source_network_id"`
+	// 目标区域网络
+	TargetNetworkId string `json:"target_network_id"`
+}
+
+type DrNetworkMappings []DrNetworkMapping
+
+func (self DrNetworkMappings) String() string {
+	return jsonutils.Marshal(self).String()
+}
+
+func (self DrNetworkMappings) IsZero() bool {
+	if len(self) == 0 {
+		return true
+	}
+	return false
+}
+
+// Reverse swaps source and target networks, recovering guests back into the
+// protected region uses the reversed mappings
+func (self DrNetworkMappings) Reverse() DrNetworkMappings {
+	ret := make(DrNetworkMappings, len(self))
+	for i := range self {
+		ret[i] = DrNetworkMapping{
+			SourceNetworkId: self[i].TargetNetworkId,
+			TargetNetworkId: self[i].SourceNetworkId,
+		}
+	}
+	return ret
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&DrNetworkMappings{}), func() gotypes.ISerializable {
+		return &DrNetworkMappings{}
+	})
+}
+
+type DrProtectionGroupCreateInput struct {
+	apis.EnabledStatusStandaloneResourceCreateInput
+
+	// 目标区域
+	// required: true
+	TargetRegion string `json:"target_region"`
+	// 本区域备份存储, 恢复点保存于此
+	// required: true
+	BackupStorageId string `json:"backup_storage_id"`
+	// 同一备份存储在目标区域中的Id
+	// required: true
+	TargetBackupStorageId string `json:"target_backup_storage_id"`
+	// 恢复点间隔, 单位分钟, 默认5
+	RpoMinutes int `json:"rpo_minutes"`
+	// 每台虚拟机保留的恢复点数量, 默认4
+	RetainCount int `json:"retain_count"`
+	// 连续增量恢复点的最大数量, 超过后做一次全量, 默认96
+	FullInterval int `json:"full_interval"`
+	// 网络映射
+	NetworkMappings DrNetworkMappings `json:"network_mappings"`
+	// 演练使用的目标区域隔离网络
+	TestNetworkId string `json:"test_network_id"`
+	// 受保护的虚拟机
+	GuestIds []string `json:"guest_ids"`
+}
+
+type DrProtectionGroupListInput struct {
+	apis.EnabledStatusStandaloneResourceListInput
+
+	TargetRegion    []string `json:"target_region"`
+	BackupStorageId []string `json:"backup_storage_id"`
+	// 以受保护的虚拟机过滤
+	GuestId string `json:"guest_id"`
+}
+
+type DrProtectionGroupUpdateInput struct {
+	apis.EnabledStatusStandaloneResourceBaseUpdateInput
+
+	RpoMinutes      *int              `json:"rpo_minutes"`
+	RetainCount     *int              `json:"retain_count"`
+	FullInterval    *int              `json:"full_interval"`
+	NetworkMappings DrNetworkMappings `json:"network_mappings"`
+	TestNetworkId   *string           `json:"test_network_id"`
+}
+
+type DrProtectionGroupMemberDetails struct {
+	GuestId       string `json:"guest_id"`
+	Guest         string `json:"guest"`
+	RemoteGuestId string `json:"remote_guest_id"`
+	TestGuestId   string `json:"test_guest_id"`
+	// 最新可用恢复点时间
+	LastRecoveryPointAt time.Time `json:"last_recovery_point_at"`
+}
+
+type DrProtectionGroupDetails struct {
+	apis.EnabledStatusStandaloneResourceDetails
+
+	SDrProtectionGroup
+
+	BackupStorage string                           `json:"backup_storage"`
+	Members       []DrProtectionGroupMemberDetails `json:"members"`
+}
+
+type DrProtectionGroupGuestsInput struct {
+	GuestIds []string `json:"guest_ids"`
+}
+
+type DrProtectionGroupSyncInput struct {
+}
+
+type DrProtectionGroupFailoverInput struct {
+	// 使用此时间之前的最新恢复点, 默认使用最新恢复点
+	RecoveryPointBefore time.Time `json:"recovery_point_before"`
+}
+
+type DrProtectionGroupTestFailoverInput struct {
+	DrProtectionGroupFailoverInput
+}
+
+type DrProtectionGroupCleanupTestInput struct {
+}
+
+type DrProtectionGroupFailbackInput struct {
+}
+
+type DrRecoveryPointListInput struct {
+	apis.StatusStandaloneResourceListInput
+
+	DrProtectionGroupId string   `json:"dr_protection_group_id"`
+	GuestId             []string `json:"guest_id"`
+}
+
+type DrRecoveryPointDetails struct {
+	apis.StatusStandaloneResourceDetails
+
+	SDrRecoveryPoint
+
+	DrProtectionGroup string `json:"dr_protection_group"`
+	Guest             string `json:"guest"`
+}
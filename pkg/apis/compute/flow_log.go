@@ -0,0 +1,220 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	FLOW_LOG_STATUS_AVAILABLE = "available"
+
+	// 记录所有流量
+	FLOW_LOG_TRAFFIC_TYPE_ALL = "all"
+	// 仅记录被安全组允许的流量
+	FLOW_LOG_TRAFFIC_TYPE_ACCEPT = "accept"
+	// 仅记录被安全组拒绝的流量
+	FLOW_LOG_TRAFFIC_TYPE_REJECT = "reject"
+
+	// 写入时序数据库
+	FLOW_LOG_TARGET_TSDB = "tsdb"
+	// 写入对象存储类型的备份存储
+	FLOW_LOG_TARGET_S3 = "s3"
+
+	FLOW_LOG_DEFAULT_LOG_RATE = 100
+
+	FLOW_LOG_TSDB_DATABASE    = "vpc_flow_log"
+	FLOW_LOG_TSDB_MEASUREMENT = "vpc_flow_log"
+
+	FLOW_LOG_DIRECTION_INGRESS = "ingress"
+	FLOW_LOG_DIRECTION_EGRESS  = "egress"
+
+	// acl without security group rule, e.g. the default in-bound deny rule
+	FLOW_LOG_RULE_DEFAULT = "default"
+
+	flowLogAclNamePrefix = "fl"
+)
+
+var FLOW_LOG_TRAFFIC_TYPES = []string{
+	FLOW_LOG_TRAFFIC_TYPE_ALL,
+	FLOW_LOG_TRAFFIC_TYPE_ACCEPT,
+	FLOW_LOG_TRAFFIC_TYPE_REJECT,
+}
+
+var FLOW_LOG_TARGETS = []string{
+	FLOW_LOG_TARGET_TSDB,
+	FLOW_LOG_TARGET_S3,
+}
+
+// FlowLogAclName returns name of logged ovn acl.  It is carried in every
+// acl_log line of ovn-controller so that host collectors can tell direction
+// and security group rule of the record.  OVN limits acl name to 63 chars
+func FlowLogAclName(direction, ruleId string) string {
+	if ruleId == "" {
+		ruleId = FLOW_LOG_RULE_DEFAULT
+	}
+	return fmt.Sprintf("%s/%s/%s", flowLogAclNamePrefix, direction, ruleId)
+}
+
+// ParseFlowLogAclName is the reverse of FlowLogAclName
+func ParseFlowLogAclName(name string) (direction string, ruleId string, ok bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 || parts[0] != flowLogAclNamePrefix {
+		return "", "", false
+	}
+	switch parts[1] {
+	case FLOW_LOG_DIRECTION_INGRESS, FLOW_LOG_DIRECTION_EGRESS:
+	default:
+		return "", "", false
+	}
+	if parts[2] == FLOW_LOG_RULE_DEFAULT {
+		return parts[1], "", true
+	}
+	return parts[1], parts[2], true
+}
+
+type FlowLogCreateInput struct {
+	apis.EnabledStatusInfrasResourceBaseCreateInput
+
+	// 流日志所属VPC, 仅支持本地IDC的VPC
+	VpcId string `json:"vpc_id"`
+	// 仅记录该网络内的流量
+	NetworkId string `json:"network_id"`
+	// 仅记录该虚拟机网卡的流量, 需要同时指定 mac_addr 以指定网卡
+	ServerId string `json:"server_id"`
+	// 虚拟机网卡MAC地址
+	MacAddr string `json:"mac_addr"`
+	// swagger:ignore
+	GuestId string `json:"guest_id"`
+
+	// 记录的流量类型
+	// enum: all, accept, reject
+	// default: all
+	TrafficType string `json:"traffic_type"`
+	// 每个网卡每秒最多记录的日志条数, 0 表示不限制
+	// default: 100
+	LogRate *int `json:"log_rate"`
+
+	// 日志投递目标
+	// enum: tsdb, s3
+	// default: tsdb
+	Target string `json:"target"`
+	// 投递目标为 s3 时使用的对象存储类型的备份存储
+	BackupStorageId string `json:"backup_storage_id"`
+	// 对象存储中的路径前缀
+	TargetPrefix string `json:"target_prefix"`
+}
+
+type FlowLogUpdateInput struct {
+	apis.EnabledStatusInfrasResourceBaseUpdateInput
+
+	TrafficType string `json:"traffic_type"`
+	LogRate     *int   `json:"log_rate"`
+}
+
+type FlowLogListInput struct {
+	apis.EnabledStatusInfrasResourceBaseListInput
+	VpcFilterListInput
+
+	NetworkId   []string `json:"network_id"`
+	ServerId    []string `json:"server_id"`
+	TrafficType []string `json:"traffic_type"`
+	Target      []string `json:"target"`
+}
+
+type FlowLogDetails struct {
+	apis.EnabledStatusInfrasResourceBaseDetails
+	VpcResourceInfo
+
+	Network       string `json:"network"`
+	Server        string `json:"server"`
+	BackupStorage string `json:"backup_storage"`
+}
+
+// FlowLogTargetInfo is used by host collectors to ship records
+type FlowLogTargetInfo struct {
+	Target       string `json:"target"`
+	TargetPrefix string `json:"target_prefix"`
+
+	SBackupStorageAccessInfo
+}
+
+// FlowLogRecordsInput filters records of flow log stored in tsdb
+type FlowLogRecordsInput struct {
+	// 起始时间, 默认为一小时前
+	StartTime time.Time `json:"start_time"`
+	// 截止时间, 默认为当前时间
+	EndTime time.Time `json:"end_time"`
+
+	SrcIp    string `json:"src_ip"`
+	SrcPort  int    `json:"src_port"`
+	DstIp    string `json:"dst_ip"`
+	DstPort  int    `json:"dst_port"`
+	Protocol string `json:"protocol"`
+
+	// enum: accept, reject
+	Verdict   string `json:"verdict"`
+	Direction string `json:"direction"`
+	ServerId  string `json:"server_id"`
+
+	// 默认 100, 最大 10000
+	Limit int `json:"limit"`
+}
+
+type FlowLogRecordsOutput struct {
+	Data  []SFlowLogRecord `json:"data"`
+	Total int              `json:"total"`
+}
+
+// SFlowLogRecord is one record of flow log.
+//
+// Records shipped to tsdb are stored in measurement vpc_flow_log of database
+// vpc_flow_log, all fields except packets are tags.
+//
+// Records shipped to s3 are stored as json lines, one record per line, in
+// objects with key <target_prefix>/<flow_log_id>/<yyyy>/<mm>/<dd>/<host_id>-<unix_nano>.jsonl
+type SFlowLogRecord struct {
+	// 记录时间
+	Time time.Time `json:"time"`
+
+	FlowLogId string `json:"flow_log_id"`
+	HostId    string `json:"host_id"`
+	VpcId     string `json:"vpc_id"`
+	NetworkId string `json:"network_id"`
+	ServerId  string `json:"server_id"`
+	MacAddr   string `json:"mac_addr"`
+
+	// 命中的安全组及规则, 缺省规则的规则ID为空
+	SecgroupId string `json:"secgroup_id"`
+	RuleId     string `json:"rule_id"`
+
+	// enum: ingress, egress
+	Direction string `json:"direction"`
+	// enum: accept, reject
+	Verdict string `json:"verdict"`
+
+	// tcp, udp, icmp, icmp6 等
+	Protocol string `json:"protocol"`
+	SrcIp    string `json:"src_ip"`
+	SrcPort  int    `json:"src_port"`
+	DstIp    string `json:"dst_ip"`
+	DstPort  int    `json:"dst_port"`
+
+	Packets int `json:"packets"`
+}
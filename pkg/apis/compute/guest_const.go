@@ -333,12 +333,35 @@ const (
 	VM_METADATA_START_VMEM_MB               = "start_vmem_mb"
 	VM_METADATA_START_VCPU_COUNT            = "start_vcpu_count"
 	VM_METADATA_DISABLE_AUTO_MERGE_SNAPSHOT = "disable_auto_merge_snapshot"
+	VM_METADATA_ENABLE_MEMORY_BALLOON       = "enable_memory_balloon"
+	VM_METADATA_BALLOON_MIN_MEM_MB          = "balloon_min_mem_mb"
+	VM_METADATA_BALLOON_ACTUAL_MEM_MB       = "balloon_actual_mem_mb"
+	VM_METADATA_NUMA_NODES                  = "numa_nodes"
+	VM_METADATA_MEMORY_BACKING              = "memory_backing"
+	VM_METADATA_MEMORY_STRICT_BIND          = "memory_strict_bind"
+	VM_METADATA_VIRTIOFS                    = "__virtiofs"
 
 	VM_METADATA_RELEASED_DEVICES = "released_devices"
 
 	VM_METADATA_CPU_NUMA_PIN = "__cpu_numa_pin"
 )
 
+const (
+	GUEST_MEMORY_BACKING_HUGEPAGES_2M = "hugepages_2m"
+	GUEST_MEMORY_BACKING_HUGEPAGES_1G = "hugepages_1g"
+)
+
+// GuestMemoryBackingPageSizeKB returns the hugepage size required by memory backing
+func GuestMemoryBackingPageSizeKB(backing string) int {
+	switch backing {
+	case GUEST_MEMORY_BACKING_HUGEPAGES_2M:
+		return 2048
+	case GUEST_MEMORY_BACKING_HUGEPAGES_1G:
+		return 1024 * 1024
+	}
+	return 0
+}
+
 // windows allow a maximal length of 15
 // http://support.microsoft.com/kb/909264
 const MAX_WINDOWS_COMPUTER_NAME_LENGTH = 15
@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/apis/host"
+)
+
+const (
+	VIRTIOFS_SOURCE_HOST_PATH    = "host_path"
+	VIRTIOFS_SOURCE_CEPHFS       = "cephfs"
+	VIRTIOFS_SOURCE_MOUNT_TARGET = "mount_target"
+)
+
+var VIRTIOFS_SOURCE_TYPES = []string{
+	VIRTIOFS_SOURCE_HOST_PATH,
+	VIRTIOFS_SOURCE_CEPHFS,
+	VIRTIOFS_SOURCE_MOUNT_TARGET,
+}
+
+type ServerVirtiofsConfig struct {
+	// 虚拟机内挂载使用的标签, mount -t virtiofs <tag> /mnt
+	// required: true
+	Tag string `json:"tag"`
+
+	// 共享目录来源
+	// enum: host_path, cephfs, mount_target
+	// required: true
+	SourceType string `json:"source_type"`
+
+	// 宿主机目录, source_type为host_path时必传
+	HostPath string `json:"host_path"`
+
+	// CephFS文件系统Id, source_type为cephfs时必传
+	FilesystemId string `json:"filesystem_id"`
+	// CephFS子卷路径
+	SubPath string `json:"sub_path"`
+
+	// 文件系统挂载点Id, source_type为mount_target时必传
+	MountTargetId string `json:"mount_target_id"`
+
+	// 只读共享
+	ReadOnly bool `json:"read_only"`
+
+	// swagger:ignore
+	// 文件系统所在可用区, 用于调度
+	ZoneId string `json:"zone_id"`
+}
+
+type ServerAttachVirtiofsInput struct {
+	ServerVirtiofsConfig
+}
+
+type ServerDetachVirtiofsInput struct {
+	// 共享目录标签
+	// required: true
+	Tag string `json:"tag"`
+}
+
+type GuestVirtiofsJsonDesc struct {
+	Tag        string `json:"tag"`
+	SourceType string `json:"source_type"`
+	ReadOnly   bool   `json:"read_only"`
+
+	HostPath string `json:"host_path,omitempty"`
+	// NFS export of the filesystem mount target, e.g. 10.0.0.2:/
+	NfsSource string                           `json:"nfs_source,omitempty"`
+	Cephfs    *host.ContainerVolumeMountCephFS `json:"cephfs,omitempty"`
+}
@@ -907,6 +907,8 @@ type GuestJsonDesc struct {
 
 	Floppys []*GuestfloppyJsonDesc `json:"floppys"`
 
+	Virtiofs []*GuestVirtiofsJsonDesc `json:"virtiofs"`
+
 	Tenant        string `json:"tenant"`
 	TenantId      string `json:"tenant_id"`
 	DomainId      string `json:"domain_id"`
@@ -1156,6 +1158,35 @@ type ServerGetHardwareInfoResp struct {
 	GPUs        []*ServerHardwareInfoGPU       `json:"gpu"`
 }
 
+type ServerGetSerialLogInput struct {
+	// 从串口日志的指定字节偏移开始读取, 偏移从最早写入的字节开始计算, 日志轮转后仍然有效, 不指定则读取末尾
+	Offset *int64 `json:"offset"`
+	// 读取末尾的字节数, 默认64KB
+	Tail int64 `json:"tail"`
+	// 从offset开始读取的最大字节数, 默认64KB
+	Limit int64 `json:"limit"`
+}
+
+type ServerSerialLogOutput struct {
+	// 日志内容
+	Content string `json:"content"`
+	// 内容在串口日志中的起始偏移, 用于向前翻页
+	Offset int64 `json:"offset"`
+	// 串口日志累计写入的总大小, 包含已轮转和已清理的日志
+	Size int64 `json:"size"`
+}
+
+type ServerGetScreenDumpInput struct{}
+
+type ServerScreenDumpOutput struct {
+	// 图片格式, 目前为png
+	Format string `json:"format"`
+	// base64编码的图片内容
+	Data   string `json:"data"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
 type ServerMonitorInput struct {
 	COMMAND string
 	QMP     bool
@@ -610,6 +610,16 @@ type SHostStorageStat struct {
 
 	CapacityMb           int64 `json:"capacity_mb"`
 	ActualCapacityUsedMb int64 `json:"actual_capacity_used_mb"`
+
+	// DiskStats reports the space actually consumed by thin provisioned disks
+	DiskStats []SDiskActualUsage `json:"disk_stats"`
+}
+
+// SDiskActualUsage is the space a disk actually holds on its storage,
+// e.g. qemu-img actual size, rbd du used size or thin lv data percent
+type SDiskActualUsage struct {
+	DiskId       string `json:"disk_id"`
+	ActualSizeMb int64  `json:"actual_size_mb"`
 }
 
 type SHostPingInput struct {
@@ -623,6 +633,15 @@ type SHostPingInput struct {
 	StorageStats []SHostStorageStat `json:"storage_stats"`
 
 	QgaRunningGuestIds []string `json:"qga_running_guests"`
+
+	GuestMemoryStats []SGuestMemoryStat `json:"guest_memory_stats"`
+}
+
+// SGuestMemoryStat reports the memory actually hold by guest with balloon device
+type SGuestMemoryStat struct {
+	GuestId       string `json:"guest_id"`
+	ReservedMemMb int64  `json:"reserved_mem_mb"`
+	ActualMemMb   int64  `json:"actual_mem_mb"`
 }
 
 type HostReserveCpusInput struct {
@@ -126,6 +126,8 @@ const (
 const (
 	HOST_METADATA_CPU_USAGE_PERCENT = "cpu_usage_percent"
 	HOST_METADATA_MEMORY_USED_MB    = "memory_used_mb"
+	// memory reclaimed from guests by balloon
+	HOST_METADATA_BALLOON_RECLAIMED_MEM_MB = "balloon_reclaimed_mem_mb"
 )
 
 var HOST_TYPES = []string{
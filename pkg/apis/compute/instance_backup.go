@@ -38,6 +38,9 @@ const (
 	INSTANCE_BACKUP_STATUS_SNAPSHOT_FAILED = "snapshot_failed"
 	INSTANCE_BACKUP_STATUS_SAVING          = "saving"
 	INSTANCE_BACKUP_STATUS_SAVE_FAILED     = "save_failed"
+
+	// 最近一次恢复出的虚拟机
+	INSTANCE_BACKUP_METADATA_RECOVERED_GUEST_ID = "recovered_guest_id"
 )
 
 type InstanceBackupListInput struct {
@@ -100,6 +103,8 @@ type SSimpleBackup struct {
 type InstanceBackupRecoveryInput struct {
 	// description: name of guest
 	Name string
+	// description: networks of guest, defaults to the networks in backup
+	Networks []*NetworkConfig `json:"networks"`
 }
 
 type InstanceBackupPackInput struct {
@@ -430,6 +430,16 @@ var LB_HA_STATES = choices.NewChoices(
 	LB_HA_STATE_UNKNOWN,
 )
 
+const (
+	LB_DATA_PLANE_HAPROXY = "haproxy"
+	LB_DATA_PLANE_ENVOY   = "envoy"
+)
+
+var LB_DATA_PLANES = choices.NewChoices(
+	LB_DATA_PLANE_HAPROXY,
+	LB_DATA_PLANE_ENVOY,
+)
+
 const (
 	LBAGENT_QUERY_ORIG_KEY = "_orig"
 	LBAGENT_QUERY_ORIG_VAL = "lbagent"
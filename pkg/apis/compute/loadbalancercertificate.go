@@ -15,11 +15,59 @@
 package compute
 
 import (
+	"reflect"
 	"time"
 
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+
 	"yunion.io/x/onecloud/pkg/apis"
 )
 
+const (
+	LB_CERT_SOURCE_UPLOAD = "upload"
+	LB_CERT_SOURCE_ACME   = "acme"
+
+	LB_CERT_ACME_CHALLENGE_HTTP01 = "http-01"
+	LB_CERT_ACME_CHALLENGE_DNS01  = "dns-01"
+
+	LB_CERT_STATUS_ISSUING      = "issuing"
+	LB_CERT_STATUS_ISSUE_FAILED = "issue_failed"
+
+	// path prefix of HTTP-01 challenges, served by lbagent
+	LB_CERT_ACME_HTTP01_PATH_PREFIX = "/.well-known/acme-challenge/"
+)
+
+var LB_CERT_ACME_CHALLENGE_TYPES = []string{
+	LB_CERT_ACME_CHALLENGE_HTTP01,
+	LB_CERT_ACME_CHALLENGE_DNS01,
+}
+
+type SAcmeHttpChallenge struct {
+	Token            string `json:"token"`
+	KeyAuthorization string `json:"key_authorization"`
+}
+
+// SAcmeHttpChallenges are the pending HTTP-01 challenges of an acme
+// certificate, answered by lbagent
+type SAcmeHttpChallenges struct {
+	Challenges []SAcmeHttpChallenge `json:"challenges"`
+}
+
+func (c *SAcmeHttpChallenges) String() string {
+	return jsonutils.Marshal(c).String()
+}
+
+func (c *SAcmeHttpChallenges) IsZero() bool {
+	return c == nil || len(c.Challenges) == 0
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&SAcmeHttpChallenges{}), func() gotypes.ISerializable {
+		return &SAcmeHttpChallenges{}
+	})
+}
+
 type LoadbalancerCertificateDetails struct {
 	apis.SharableVirtualResourceDetails
 	SLoadbalancerCertificate
@@ -68,6 +116,24 @@ type LoadbalancerCertificateListInput struct {
 
 	CommonName              []string `json:"common_name"`
 	SubjectAlternativeNames []string `json:"subject_alternative_names"`
+
+	// 证书来源
+	CertSource []string `json:"cert_source"`
+}
+
+type LoadbalancerCertificateAcmeInput struct {
+	// ACME服务目录地址, 默认为region服务配置的acme_directory_url
+	AcmeDirectoryUrl string `json:"acme_directory_url"`
+	// 注册ACME账户的联系邮箱
+	AcmeEmail string `json:"acme_email"`
+	// 域名验证方式, http-01由lbagent应答, dns-01通过本平台dnsrecords添加TXT记录
+	// enum: ["http-01", "dns-01"]
+	AcmeChallengeType string `json:"acme_challenge_type"`
+	// 申请证书的域名列表, 通配符域名仅支持dns-01验证
+	AcmeDomains []string `json:"acme_domains"`
+}
+
+type LoadbalancerCertificateAcmeRenewInput struct {
 }
 
 type LoadbalancerCertificateCreateInput struct {
@@ -76,6 +142,12 @@ type LoadbalancerCertificateCreateInput struct {
 	CloudregionResourceInput
 	CloudproviderResourceInput
 
+	// 证书来源, upload为上传证书, acme为自动从ACME服务签发
+	// enum: ["upload", "acme"]
+	// default: upload
+	CertSource string `json:"cert_source"`
+	LoadbalancerCertificateAcmeInput
+
 	Certificate string `json:"certificate"`
 	PrivateKey  string `json:"private_key"`
 	// swagger:ignore
@@ -164,6 +164,18 @@ type LoadbalancerListenerCreateInput struct {
 
 	HttpRequestRate       int `json:"http_request_rate"`
 	HttpRequestRatePerSrc int `json:"http_request_rate_src"`
+	// requests per second of each client identified by value of http_request_rate_header,
+	// mutually exclusive with http_request_rate_src
+	HttpRequestRatePerHeader int    `json:"http_request_rate_per_header"`
+	HttpRequestRateHeader    string `json:"http_request_rate_header"`
+
+	// add, set or remove headers of requests and responses
+	HttpHeaderRewrites SLoadbalancerHTTPHeaderRewrites `json:"http_header_rewrites"`
+
+	// max concurrent connections of the listener
+	MaxConn int `json:"max_conn"`
+	// max concurrent connections of each source address
+	MaxConnPerSrc int `json:"max_conn_per_src"`
 
 	// default: off
 	// enmu: off, raw
@@ -278,6 +290,15 @@ func (self *LoadbalancerListenerCreateInput) Validate() error {
 	if !utils.IsInStringArray(self.Redirect, []string{LB_REDIRECT_OFF, LB_REDIRECT_RAW}) {
 		return httperrors.NewInputParameterError("invalid redirect %s", self.Redirect)
 	}
+	if err := ValidateHTTPRequestRateLimits(self.HttpRequestRate, self.HttpRequestRatePerSrc, self.HttpRequestRatePerHeader, self.HttpRequestRateHeader); err != nil {
+		return err
+	}
+	if err := self.HttpHeaderRewrites.Validate(); err != nil {
+		return err
+	}
+	if err := ValidateConnLimits(self.MaxConn, self.MaxConnPerSrc); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -346,6 +367,18 @@ type LoadbalancerListenerUpdateInput struct {
 
 	HttpRequestRate       *int `json:"http_request_rate"`
 	HttpRequestRatePerSrc *int `json:"http_request_rate_src"`
+	// requests per second of each client identified by value of http_request_rate_header,
+	// mutually exclusive with http_request_rate_src
+	HttpRequestRatePerHeader *int    `json:"http_request_rate_per_header"`
+	HttpRequestRateHeader    *string `json:"http_request_rate_header"`
+
+	// add, set or remove headers of requests and responses
+	HttpHeaderRewrites *SLoadbalancerHTTPHeaderRewrites `json:"http_header_rewrites"`
+
+	// max concurrent connections of the listener
+	MaxConn *int `json:"max_conn"`
+	// max concurrent connections of each source address
+	MaxConnPerSrc *int `json:"max_conn_per_src"`
 
 	// default: off
 	// enmu: off, raw
@@ -370,6 +403,11 @@ func (self *LoadbalancerListenerUpdateInput) Validate() error {
 			}
 		}
 	}
+	if self.HttpHeaderRewrites != nil {
+		if err := self.HttpHeaderRewrites.Validate(); err != nil {
+			return err
+		}
+	}
 	if self.Scheduler != nil && !utils.IsInStringArray(*self.Scheduler, LB_SCHEDULER_TYPES) {
 		return httperrors.NewInputParameterError("invalid scheduler %v", self.Scheduler)
 	}
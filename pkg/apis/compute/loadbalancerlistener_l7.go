@@ -0,0 +1,211 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"reflect"
+	"regexp"
+	"unicode"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/gotypes"
+	"yunion.io/x/pkg/utils"
+
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+const (
+	LB_HTTP_HEADER_DIRECTION_REQUEST  = "request"
+	LB_HTTP_HEADER_DIRECTION_RESPONSE = "response"
+
+	LB_HTTP_HEADER_ACTION_ADD = "add"
+	LB_HTTP_HEADER_ACTION_SET = "set"
+	LB_HTTP_HEADER_ACTION_DEL = "del"
+
+	LB_HTTP_HEADER_VALUE_MAX_LEN = 1024
+)
+
+var (
+	LB_HTTP_HEADER_DIRECTIONS = []string{
+		LB_HTTP_HEADER_DIRECTION_REQUEST,
+		LB_HTTP_HEADER_DIRECTION_RESPONSE,
+	}
+	LB_HTTP_HEADER_ACTIONS = []string{
+		LB_HTTP_HEADER_ACTION_ADD,
+		LB_HTTP_HEADER_ACTION_SET,
+		LB_HTTP_HEADER_ACTION_DEL,
+	}
+
+	// token defined in rfc7230
+	httpHeaderNameReg = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+	// HTTPHeaderSampleFetchReg matches haproxy sample fetch expressions
+	// like %[src] in header values
+	HTTPHeaderSampleFetchReg = regexp.MustCompile(`%\[([^\]]*)\]`)
+
+	// sample fetches allowed in header values.  Converters are not allowed
+	httpHeaderSampleFetches = []string{
+		"src", "src_port", "dst", "dst_port",
+		"method", "path", "query", "url", "base", "req.ver",
+		"ssl_fc", "ssl_fc_protocol", "ssl_fc_sni", "ssl_c_s_dn",
+		"unique-id", "uuid",
+	}
+	httpHeaderSampleFetchHdrReg = regexp.MustCompile(`^(req\.hdr|res\.hdr|hdr)\([0-9A-Za-z_-]+\)$`)
+)
+
+// SLoadbalancerHTTPHeaderRewrite adds, sets or removes a header of requests
+// to backends or responses to clients
+type SLoadbalancerHTTPHeaderRewrite struct {
+	// enum: request, response
+	Direction string `json:"direction"`
+	// enum: add, set, del
+	Action string `json:"action"`
+	Name   string `json:"name"`
+	// haproxy sample fetches like %[src] and %[req.hdr(host)] are allowed
+	Value string `json:"value"`
+}
+
+type SLoadbalancerHTTPHeaderRewrites []SLoadbalancerHTTPHeaderRewrite
+
+func (self SLoadbalancerHTTPHeaderRewrites) String() string {
+	return jsonutils.Marshal(self).String()
+}
+
+func (self SLoadbalancerHTTPHeaderRewrites) IsZero() bool {
+	return len(self) == 0
+}
+
+func ValidateHTTPHeaderName(name string) error {
+	if !httpHeaderNameReg.MatchString(name) {
+		return httperrors.NewInputParameterError("invalid http header name %q", name)
+	}
+	return nil
+}
+
+// IsHTTPHeaderSampleFetchAllowed reports whether the sample fetch expression
+// inside %[] may be used in header values
+func IsHTTPHeaderSampleFetchAllowed(fetch string) bool {
+	return utils.IsInStringArray(fetch, httpHeaderSampleFetches) || httpHeaderSampleFetchHdrReg.MatchString(fetch)
+}
+
+func (rewrite *SLoadbalancerHTTPHeaderRewrite) Validate() error {
+	if len(rewrite.Direction) == 0 {
+		rewrite.Direction = LB_HTTP_HEADER_DIRECTION_REQUEST
+	}
+	if !utils.IsInStringArray(rewrite.Direction, LB_HTTP_HEADER_DIRECTIONS) {
+		return httperrors.NewInputParameterError("invalid header rewrite direction %s", rewrite.Direction)
+	}
+	if !utils.IsInStringArray(rewrite.Action, LB_HTTP_HEADER_ACTIONS) {
+		return httperrors.NewInputParameterError("invalid header rewrite action %s", rewrite.Action)
+	}
+	if err := ValidateHTTPHeaderName(rewrite.Name); err != nil {
+		return err
+	}
+	switch rewrite.Action {
+	case LB_HTTP_HEADER_ACTION_DEL:
+		if len(rewrite.Value) > 0 {
+			return httperrors.NewInputParameterError("header %s to delete must not have value", rewrite.Name)
+		}
+	default:
+		if len(rewrite.Value) == 0 {
+			return httperrors.NewMissingParameterError("value of header " + rewrite.Name)
+		}
+		if len(rewrite.Value) > LB_HTTP_HEADER_VALUE_MAX_LEN {
+			return httperrors.NewInputParameterError("value of header %s too long (%d>%d)", rewrite.Name, len(rewrite.Value), LB_HTTP_HEADER_VALUE_MAX_LEN)
+		}
+		for _, r := range rewrite.Value {
+			if !unicode.IsPrint(r) {
+				return httperrors.NewInputParameterError("value of header %s contains non-printable char: %v", rewrite.Name, r)
+			}
+		}
+		for _, m := range HTTPHeaderSampleFetchReg.FindAllStringSubmatch(rewrite.Value, -1) {
+			if !IsHTTPHeaderSampleFetchAllowed(m[1]) {
+				return httperrors.NewInputParameterError("value of header %s uses unsupported sample fetch %s", rewrite.Name, m[0])
+			}
+		}
+	}
+	return nil
+}
+
+func (self SLoadbalancerHTTPHeaderRewrites) Validate() error {
+	for i := range self {
+		if err := self[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateHTTPRequestRateLimits checks per client request rate limits.  Each
+// client is identified either by source address or by value of a request
+// header, not both
+func ValidateHTTPRequestRateLimits(rate, ratePerSrc, ratePerHeader int, header string) error {
+	if rate < 0 {
+		return httperrors.NewInputParameterError("invalid http_request_rate %d", rate)
+	}
+	if ratePerSrc < 0 {
+		return httperrors.NewInputParameterError("invalid http_request_rate_per_src %d", ratePerSrc)
+	}
+	if ratePerHeader < 0 {
+		return httperrors.NewInputParameterError("invalid http_request_rate_per_header %d", ratePerHeader)
+	}
+	if ratePerHeader > 0 {
+		if len(header) == 0 {
+			return httperrors.NewMissingParameterError("http_request_rate_header")
+		}
+		if err := ValidateHTTPHeaderName(header); err != nil {
+			return err
+		}
+		if ratePerSrc > 0 {
+			return httperrors.NewConflictError("http_request_rate_per_src and http_request_rate_per_header are mutually exclusive")
+		}
+	}
+	return nil
+}
+
+// ValidateConnLimits checks concurrent connection limits of listeners
+func ValidateConnLimits(maxConn, maxConnPerSrc int) error {
+	if maxConn < 0 {
+		return httperrors.NewInputParameterError("invalid max_conn %d", maxConn)
+	}
+	if maxConnPerSrc < 0 {
+		return httperrors.NewInputParameterError("invalid max_conn_per_src %d", maxConnPerSrc)
+	}
+	if maxConn > 0 && maxConnPerSrc > maxConn {
+		return httperrors.NewInputParameterError("max_conn_per_src %d exceeds max_conn %d", maxConnPerSrc, maxConn)
+	}
+	return nil
+}
+
+// ValidateEnvoyDataPlaneLimits rejects per client limits, which have no
+// counterpart in the envoy data plane
+func ValidateEnvoyDataPlaneLimits(maxConnPerSrc, ratePerSrc, ratePerHeader int) error {
+	if maxConnPerSrc > 0 {
+		return httperrors.NewNotSupportedError("max_conn_per_src is not supported by %s data plane", LB_DATA_PLANE_ENVOY)
+	}
+	if ratePerSrc > 0 {
+		return httperrors.NewNotSupportedError("http_request_rate_per_src is not supported by %s data plane", LB_DATA_PLANE_ENVOY)
+	}
+	if ratePerHeader > 0 {
+		return httperrors.NewNotSupportedError("http_request_rate_per_header is not supported by %s data plane", LB_DATA_PLANE_ENVOY)
+	}
+	return nil
+}
+
+func init() {
+	gotypes.RegisterSerializable(reflect.TypeOf(&SLoadbalancerHTTPHeaderRewrites{}), func() gotypes.ISerializable {
+		return &SLoadbalancerHTTPHeaderRewrites{}
+	})
+}
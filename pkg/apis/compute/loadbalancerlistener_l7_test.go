@@ -0,0 +1,85 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import "testing"
+
+func TestSLoadbalancerHTTPHeaderRewritesValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		rewrite SLoadbalancerHTTPHeaderRewrite
+		wantErr bool
+	}{
+		{"set", SLoadbalancerHTTPHeaderRewrite{Action: "set", Name: "X-Real-IP", Value: "%[src]"}, false},
+		{"del", SLoadbalancerHTTPHeaderRewrite{Direction: "response", Action: "del", Name: "Server"}, false},
+		{"del with value", SLoadbalancerHTTPHeaderRewrite{Action: "del", Name: "Server", Value: "x"}, true},
+		{"add without value", SLoadbalancerHTTPHeaderRewrite{Action: "add", Name: "X-Foo"}, true},
+		{"bad name", SLoadbalancerHTTPHeaderRewrite{Action: "set", Name: "X Foo", Value: "1"}, true},
+		{"bad value", SLoadbalancerHTTPHeaderRewrite{Action: "set", Name: "X-Foo", Value: "a\nb"}, true},
+		{"header fetch", SLoadbalancerHTTPHeaderRewrite{Action: "set", Name: "X-Host", Value: "host=%[req.hdr(host)]"}, false},
+		{"literal percent and dollar", SLoadbalancerHTTPHeaderRewrite{Action: "set", Name: "X-Foo", Value: "100% ${HOME}"}, false},
+		{"converter", SLoadbalancerHTTPHeaderRewrite{Action: "set", Name: "X-Foo", Value: "%[src,ipmask(24)]"}, true},
+		{"unknown fetch", SLoadbalancerHTTPHeaderRewrite{Action: "set", Name: "X-Foo", Value: "%[env(SECRET)]"}, true},
+		{"bad direction", SLoadbalancerHTTPHeaderRewrite{Direction: "both", Action: "set", Name: "X-Foo", Value: "1"}, true},
+		{"bad action", SLoadbalancerHTTPHeaderRewrite{Action: "replace", Name: "X-Foo", Value: "1"}, true},
+	}
+	for _, c := range cases {
+		err := SLoadbalancerHTTPHeaderRewrites{c.rewrite}.Validate()
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: want error %v, got %v", c.name, c.wantErr, err)
+		}
+	}
+}
+
+func TestValidateEnvoyDataPlaneLimits(t *testing.T) {
+	cases := []struct {
+		name                                     string
+		maxConnPerSrc, ratePerSrc, ratePerHeader int
+		wantErr                                  bool
+	}{
+		{"none", 0, 0, 0, false},
+		{"max conn per src", 10, 0, 0, true},
+		{"rate per src", 0, 10, 0, true},
+		{"rate per header", 0, 0, 10, true},
+	}
+	for _, c := range cases {
+		err := ValidateEnvoyDataPlaneLimits(c.maxConnPerSrc, c.ratePerSrc, c.ratePerHeader)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: want error %v, got %v", c.name, c.wantErr, err)
+		}
+	}
+}
+
+func TestValidateHTTPRequestRateLimits(t *testing.T) {
+	cases := []struct {
+		name                            string
+		rate, ratePerSrc, ratePerHeader int
+		header                          string
+		wantErr                         bool
+	}{
+		{"none", 0, 0, 0, "", false},
+		{"per src", 100, 10, 0, "", false},
+		{"per header", 100, 0, 10, "X-Api-Key", false},
+		{"per header without header", 0, 0, 10, "", true},
+		{"both per src and per header", 0, 10, 10, "X-Api-Key", true},
+		{"negative", -1, 0, 0, "", true},
+	}
+	for _, c := range cases {
+		err := ValidateHTTPRequestRateLimits(c.rate, c.ratePerSrc, c.ratePerHeader, c.header)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: want error %v, got %v", c.name, c.wantErr, err)
+		}
+	}
+}
@@ -64,6 +64,12 @@ type LoadbalancerListenerRuleCreateInput struct {
 	Path                 string `json:"path"`
 	HttpRequstRate       int    `json:"http_request_rate"`
 	HttpRequstRatePerSec int    `json:"http_request_rate_per_sec"`
+	// requests per second of each client identified by value of http_request_rate_header
+	HttpRequestRatePerHeader int    `json:"http_request_rate_per_header"`
+	HttpRequestRateHeader    string `json:"http_request_rate_header"`
+
+	// add, set or remove headers of requests and responses matching the rule
+	HttpHeaderRewrites SLoadbalancerHTTPHeaderRewrites `json:"http_header_rewrites"`
 
 	Redirect       string
 	RedirectCode   int64
@@ -92,6 +98,12 @@ func (self *LoadbalancerListenerRuleCreateInput) Validate() error {
 	if self.HttpRequstRatePerSec < 0 {
 		return httperrors.NewInputParameterError("invalid http_request_rate_per_sec %d", self.HttpRequstRatePerSec)
 	}
+	if err := ValidateHTTPRequestRateLimits(self.HttpRequstRate, 0, self.HttpRequestRatePerHeader, self.HttpRequestRateHeader); err != nil {
+		return err
+	}
+	if err := self.HttpHeaderRewrites.Validate(); err != nil {
+		return err
+	}
 	if len(self.Redirect) == 0 {
 		self.Redirect = LB_REDIRECT_OFF
 	}
@@ -133,6 +145,12 @@ type LoadbalancerListenerRuleUpdateInput struct {
 	Path                 string `json:"path"`
 	HttpRequstRate       int    `json:"http_request_rate"`
 	HttpRequstRatePerSec int    `json:"http_request_rate_per_sec"`
+	// requests per second of each client identified by value of http_request_rate_header
+	HttpRequestRatePerHeader *int    `json:"http_request_rate_per_header"`
+	HttpRequestRateHeader    *string `json:"http_request_rate_header"`
+
+	// add, set or remove headers of requests and responses matching the rule
+	HttpHeaderRewrites *SLoadbalancerHTTPHeaderRewrites `json:"http_header_rewrites"`
 
 	Redirect       string
 	RedirectCode   int64
@@ -80,12 +80,48 @@ type PodSecurityContext struct {
 }
 
 type PodCreateInput struct {
-	Containers []*PodContainerCreateInput `json:"containers"`
-	HostIPC    bool                       `json:"host_ipc"`
+	// Init containers are started one by one in order,
+	// each of them must exit successfully before the next one is started.
+	InitContainers []*PodContainerCreateInput `json:"init_containers,omitempty"`
+	Containers     []*PodContainerCreateInput `json:"containers"`
+	HostIPC        bool                       `json:"host_ipc"`
 	//PortMappings    []*PodPortMapping          `json:"port_mappings"`
 	SecurityContext *PodSecurityContext `json:"security_context,omitempty"`
 }
 
+type PodManifestFormat string
+
+const (
+	POD_MANIFEST_FORMAT_KUBERNETES PodManifestFormat = "kubernetes"
+	POD_MANIFEST_FORMAT_COMPOSE    PodManifestFormat = "compose"
+)
+
+type PodConvertManifestInput struct {
+	// Format of the manifest, detected from the content if it's empty
+	// enum: ["kubernetes", "compose"]
+	Format PodManifestFormat `json:"format"`
+	// Content of Kubernetes Pod/Deployment yaml or docker-compose v3 file
+	// required: true
+	Manifest string `json:"manifest"`
+}
+
+type PodConvertManifestOutput struct {
+	Format PodManifestFormat `json:"format"`
+	// Name of the pod, from metadata.name of kubernetes manifest
+	Name string `json:"name"`
+	// Sum of the containers' cpu limits, 0 if not specified
+	VcpuCount int `json:"vcpu_count"`
+	// Sum of the containers' memory limits in MB, 0 if not specified
+	VmemSize int `json:"vmem_size"`
+	// Replicas of the kubernetes deployment
+	Count int             `json:"count"`
+	Pod   *PodCreateInput `json:"pod"`
+	// Port mappings of the containers, which should be set to the network of the pod
+	PortMappings GuestPortMappings `json:"port_mappings"`
+	// Fields of the manifest which can't be converted
+	Unmapped []string `json:"unmapped"`
+}
+
 type PodStartResponse struct {
 	CRIId     string `json:"cri_id"`
 	IsRunning bool   `json:"is_running"`
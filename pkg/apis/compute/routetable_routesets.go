@@ -25,6 +25,8 @@ const (
 	NEXT_HOP_TYPE_VPCPEERING = compute.NEXT_HOP_TYPE_VPCPEERING // vpc对等连接
 
 	NEXT_HOP_TYPE_IP = compute.NEXT_HOP_TYPE_IP
+
+	ROUTE_ENTRY_TYPE_PROPAGATE = compute.ROUTE_ENTRY_TYPE_PROPAGATE // 路由传播
 )
 
 type RouteTableRouteSetCreateInput struct {
@@ -42,6 +42,7 @@ const (
 	INDICATOR_DISK_WRITE = "disk_write" // 磁盘写速率
 	INDICATOR_FLOW_INTO  = "flow_into"  // 网络入流量
 	INDICATOR_FLOW_OUT   = "flow_out"   // 网络出流量
+	INDICATOR_CUSTOM     = "custom"     // 自定义指标
 
 	WRAPPER_MAX  = "max"     // 最大值
 	WRAPPER_MIN  = "min"     //最小值
@@ -64,9 +64,21 @@ type ScalingAlarmCreateInput struct {
 
 	// description: 监控指标
 	// example: cpu
-	// enum: ["cpu","disk_read","disk_write","flow_into","flow_out"]
+	// enum: ["cpu","mem","disk_read","disk_write","flow_into","flow_out","custom"]
 	Indicator string `json:"indicator"`
 
+	// description: 自定义指标所在的时序数据库，仅在 indicator 为 custom 时有效
+	// example: telegraf
+	Database string `json:"database"`
+
+	// description: 自定义指标的 measurement，仅在 indicator 为 custom 时有效，数据需带有 vm_scaling_group_id 标签
+	// example: http_requests
+	Measurement string `json:"measurement"`
+
+	// description: 自定义指标的字段，仅在 indicator 为 custom 时有效
+	// example: rps
+	Field string `json:"field"`
+
 	// description: 监控指标的取值方式(比如最大值，最小值，平均值)
 	// example: max
 	// enum: ["max","min","average"]
@@ -111,6 +123,12 @@ type ScalingAlarmDetails struct {
 	Cycle int `json:"cycle"`
 	// description: 指标
 	Indicator string `json:"indicator"`
+	// description: 指标所在的时序数据库
+	Database string `json:"database"`
+	// description: 指标的 measurement
+	Measurement string `json:"measurement"`
+	// description: 指标的字段
+	Field string `json:"field"`
 	// description: 指标的取值方式，最大值/最小值/平均值
 	Wrapper string `json:"wrapper"`
 	// description: 指标和阈值之间的比较关系，>=/<=
@@ -98,6 +98,10 @@ type SSecgroupRuleCreateInput struct {
 	// 安全组ID
 	// required: true
 	SecgroupId string `json:"secgroup_id"`
+
+	// 对端安全组ID或名称, 规则作用于该安全组内虚拟机的IP地址, 仅支持本地IDC安全组
+	// required: false
+	PeerSecgroupId string `json:"peer_secgroup_id"`
 }
 
 type SSecgroupRuleUpdateInput struct {
@@ -23,4 +23,7 @@ type SecgroupRuleDetails struct {
 	SecurityGroupResourceInfo
 
 	ProjectId string `json:"tenant_id"`
+
+	// 对端安全组名称
+	PeerSecgroup string `json:"peer_secgroup"`
 }
@@ -16,6 +16,7 @@ package compute
 
 import (
 	"strconv"
+	"time"
 
 	"yunion.io/x/jsonutils"
 
@@ -331,3 +332,27 @@ type IscsiStorageConf struct {
 type StorageSetCmtBoundInput struct {
 	Cmtbound *float32
 }
+
+type StorageUsageHistoryInput struct {
+	// 查询最近多少天的用量, 默认为全部保留的采样
+	Days int `json:"days"`
+}
+
+type StorageUsageSample struct {
+	SampledAt time.Time `json:"sampled_at"`
+	// 容量, 单位MB
+	Capacity int64 `json:"capacity"`
+	// 实际使用量, 单位MB
+	ActualCapacityUsed int64 `json:"actual_capacity_used"`
+	// 已分配容量, 单位MB
+	AllocatedCapacity int64 `json:"allocated_capacity"`
+}
+
+type StorageUsageHistoryOutput struct {
+	Data []StorageUsageSample `json:"data"`
+
+	// 实际使用量每天增长, 单位MB
+	ActualGrowthMbPerDay float64 `json:"actual_growth_mb_per_day"`
+	// 按当前增长速度预计存储用尽的时间
+	ActualExhaustAt *time.Time `json:"actual_exhaust_at,omitempty"`
+}
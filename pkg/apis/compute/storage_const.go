@@ -197,6 +197,9 @@ var (
 
 	// supported shared storage types
 	SHARED_STORAGE = []string{STORAGE_NFS, STORAGE_GPFS, STORAGE_RBD, STORAGE_CLVM, STORAGE_SLVM, STORAGE_ISCSI}
+
+	// storages whose actual used space is reported by hosts and may stay far below the allocated size
+	THIN_PROVISIONED_STORAGE = []string{STORAGE_LOCAL, STORAGE_NFS, STORAGE_GPFS, STORAGE_RBD, STORAGE_LVM, STORAGE_CLVM, STORAGE_SLVM}
 )
 
 func IsDiskTypeMatch(t1, t2 string) bool {
@@ -222,6 +225,11 @@ const (
 	STORAGE_METADATA_VENDOR    = "vendor"
 	STORAGE_METADATA_MODEL     = "model"
 	STORAGE_METADATA_BANDWIDTH = "bandwidth"
+
+	// growth of actual used capacity in MB per day, fitted from the usage samples
+	STORAGE_METADATA_ACTUAL_GROWTH_MB_PER_DAY = "actual_growth_mb_per_day"
+	// predicted time the actual used capacity reaches the storage capacity
+	STORAGE_METADATA_ACTUAL_EXHAUST_AT = "actual_exhaust_at"
 )
 
 type StorageResourceInput struct {
@@ -53,10 +53,18 @@ const (
 	VpcInterExtMac2  = "ee:ee:ee:ee:ee:f1"
 )
 
+// transit /30 subnets connecting vpc routers of peering connections
+const (
+	sVpcPeeringTransitCidr = "100.65.64.0/18"
+	VpcPeeringTransitMask  = 30
+)
+
 var (
 	vpcInterCidr   netutils.IPV4Prefix
 	vpcInterExtIP1 netutils.IPV4Addr
 	vpcInterExtIP2 netutils.IPV4Addr
+
+	vpcPeeringTransitCidr netutils.IPV4Prefix
 )
 
 func VpcInterCidr() netutils.IPV4Prefix {
@@ -71,6 +79,10 @@ func VpcInterExtIP2() netutils.IPV4Addr {
 	return vpcInterExtIP2
 }
 
+func VpcPeeringTransitCidr() netutils.IPV4Prefix {
+	return vpcPeeringTransitCidr
+}
+
 const (
 	sVpcMappedCidr      = "100.64.0.0/17"
 	VpcMappedIPMask     = 17
@@ -135,6 +147,8 @@ func init() {
 	vpcInterExtIP1 = mi(netutils.NewIPV4Addr(sVpcInterExtIP1))
 	vpcInterExtIP2 = mi(netutils.NewIPV4Addr(sVpcInterExtIP2))
 
+	vpcPeeringTransitCidr = mp(netutils.NewIPV4Prefix(sVpcPeeringTransitCidr))
+
 	vpcMappedCidr = mp(netutils.NewIPV4Prefix(sVpcMappedCidr))
 	vpcMappedGatewayIP = mi(netutils.NewIPV4Addr(sVpcMappedGatewayIP))
 
@@ -540,6 +540,8 @@ type SDisk struct {
 	// 磁盘大小, 单位Mb
 	// example: 10240
 	DiskSize int `json:"disk_size"`
+	// 磁盘在存储上实际占用的空间, 由宿主机上报, 单位Mb
+	ActualSizeMb int64 `json:"actual_size_mb"`
 	// 磁盘路径
 	AccessPath string `json:"access_path"`
 	// 备份磁盘实例的存储ID
@@ -595,6 +597,35 @@ type SDiskBackup struct {
 	// 操作系统类型
 	OsType     string             `json:"os_type"`
 	DiskConfig *SBackupDiskConfig `json:"disk_config"`
+	// 增量备份所基于的备份, 为空表示全量备份
+	ParentBackupId string `json:"parent_backup_id"`
+	// 保留在磁盘上的快照, 作为下一次增量备份的基础
+	BaseSnapshotId string `json:"base_snapshot_id"`
+}
+
+// SDiskReplica is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDiskReplica.
+type SDiskReplica struct {
+	apis.SStatusStandaloneResourceBase
+	// 复制的本地盘
+	DiskId string `json:"disk_id"`
+	// 所属虚拟机
+	GuestId string `json:"guest_id"`
+	// 主盘所在宿主机
+	HostId string `json:"host_id"`
+	// 副本所在宿主机
+	ReplicaHostId string `json:"replica_host_id"`
+	// 副本所在存储
+	ReplicaStorageId string `json:"replica_storage_id"`
+	// 复制模式, sync或async
+	Mode string `json:"mode"`
+	// 允许的最大延迟, 单位MB
+	MaxLagMb int `json:"max_lag_mb"`
+	// 副本导出的nbd地址
+	NbdUri string `json:"nbd_uri"`
+	// 副本落后主盘的数据量
+	LagBytes int64 `json:"lag_bytes"`
+	// 上次与主盘一致的时间
+	SyncedAt time.Time `json:"synced_at"`
 }
 
 // SDiskResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDiskResourceBase.
@@ -629,6 +660,37 @@ type SDnsZone struct {
 	SManagedResourceBase
 	ZoneType    string `json:"zone_type"`
 	ProductType string `json:"product_type"`
+	// 区域序列号, 本地区域的解析记录变更时递增
+	Serial int64 `json:"serial"`
+	// 允许区域传送的IP或CIDR列表
+	TransferAllowIps []string `json:"transfer_allow_ips"`
+	// 区域传送使用的TSIG密钥名称
+	TsigKeyName string `json:"tsig_key_name"`
+	// TSIG算法
+	TsigAlgorithm string `json:"tsig_algorithm"`
+	// TSIG密钥, base64编码
+	TsigSecret string `json:"tsig_secret"`
+	// 辅DNS服务器地址列表, 区域变更时发送NOTIFY
+	NotifySecondaries []string `json:"notify_secondaries"`
+	// 是否启用DNSSEC
+	DnssecEnabled *bool `json:"dnssec_enabled,omitempty"`
+	// DNSSEC签名算法
+	DnssecAlgorithm string `json:"dnssec_algorithm"`
+	// DNSSEC密钥, 私钥加密存储
+	DnssecKeys *SDnssecKeys `json:"dnssec_keys"`
+}
+
+// SDnsZoneChange is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDnsZoneChange.
+type SDnsZoneChange struct {
+	apis.SResourceBase
+	RowId     int64  `json:"row_id"`
+	DnsZoneId string `json:"dns_zone_id"`
+	// 变更后的区域序列号
+	Serial int64 `json:"serial"`
+	// 删除的记录, 每行一条zone file格式记录
+	Removed string `json:"removed"`
+	// 新增的记录, 每行一条zone file格式记录
+	Added string `json:"added"`
 }
 
 // SDnsZoneResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDnsZoneResourceBase.
@@ -636,6 +698,44 @@ type SDnsZoneResourceBase struct {
 	DnsZoneId string `json:"dns_zone_id"`
 }
 
+// SDrProtectionGroup is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDrProtectionGroup.
+type SDrProtectionGroup struct {
+	apis.SEnabledStatusStandaloneResourceBase
+	// 目标区域
+	TargetRegion string `json:"target_region"`
+	// 本区域备份存储
+	BackupStorageId string `json:"backup_storage_id"`
+	// 备份存储在目标区域中的Id
+	TargetBackupStorageId string `json:"target_backup_storage_id"`
+	// 恢复点间隔, 单位分钟
+	RpoMinutes int `json:"rpo_minutes"`
+	// 每台虚拟机保留的恢复点数量
+	RetainCount int `json:"retain_count"`
+	// 连续增量恢复点的最大数量
+	FullInterval int `json:"full_interval"`
+	// 网络映射
+	NetworkMappings *DrNetworkMappings `json:"network_mappings"`
+	// 演练使用的目标区域隔离网络
+	TestNetworkId string `json:"test_network_id"`
+	// 上次故障切换时间
+	FailedOverAt time.Time `json:"failed_over_at"`
+}
+
+// SDrRecoveryPoint is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDrRecoveryPoint.
+type SDrRecoveryPoint struct {
+	apis.SStatusStandaloneResourceBase
+	// 所属保护组
+	GroupId string `json:"group_id"`
+	// 受保护的虚拟机
+	GuestId string `json:"guest_id"`
+	// 主机备份
+	InstanceBackupId string `json:"instance_backup_id"`
+	// 备份存储上的备份包
+	PackageName string `json:"package_name"`
+	// 增量恢复点所基于的恢复点, 为空表示全量
+	ParentId string `json:"parent_id"`
+}
+
 // SDynamicschedtag is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SDynamicschedtag.
 type SDynamicschedtag struct {
 	apis.SStandaloneResourceBase
@@ -1782,6 +1882,20 @@ type SLoadbalancerCertificate struct {
 	SManagedResourceBase
 	SCloudregionResourceBase
 	apis.SCertificateResourceBase
+	// 证书来源
+	CertSource string `json:"cert_source"`
+	// ACME服务目录地址
+	AcmeDirectoryUrl string `json:"acme_directory_url"`
+	// ACME账户联系邮箱
+	AcmeEmail string `json:"acme_email"`
+	// 域名验证方式
+	AcmeChallengeType string `json:"acme_challenge_type"`
+	// 申请证书的域名列表
+	AcmeDomains []string `json:"acme_domains"`
+	// ACME账户私钥, 加密存储
+	AcmeAccountKey string `json:"acme_account_key"`
+	// 待lbagent应答的HTTP-01验证
+	AcmeHttpChallenges *SAcmeHttpChallenges `json:"acme_http_challenges"`
 }
 
 // SLoadbalancerCertificateResourceBase is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerCertificateResourceBase.
@@ -1796,6 +1910,8 @@ type SLoadbalancerCluster struct {
 	SZoneResourceBase
 	SWireResourceBase
 	Params *SLoadbalancerClusterParams `json:"params"`
+	// 数据面, haproxy或envoy
+	DataPlane string `json:"data_plane"`
 }
 
 // SLoadbalancerClusterParams is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerClusterParams.
@@ -1813,6 +1929,18 @@ type SLoadbalancerClusterResourceBase struct {
 	ClusterId string `json:"cluster_id"`
 }
 
+// SLoadbalancerConnLimiter is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerConnLimiter.
+type SLoadbalancerConnLimiter struct {
+	MaxConn int `json:"max_conn"`
+	// 监听最大并发连接数
+	MaxConnPerSrc int `json:"max_conn_per_src"`
+}
+
+// SLoadbalancerHTTPHeaderRewriter is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerHTTPHeaderRewriter.
+type SLoadbalancerHTTPHeaderRewriter struct {
+	HTTPHeaderRewrites *SLoadbalancerHTTPHeaderRewrites `json:"http_header_rewrites"`
+}
+
 // SLoadbalancerHTTPListener is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerHTTPListener.
 type SLoadbalancerHTTPListener struct {
 	StickySession string `json:"sticky_session"`
@@ -1832,6 +1960,10 @@ type SLoadbalancerHTTPRateLimiter struct {
 	HTTPRequestRate int `json:"http_request_rate"`
 	// 限定监听接收请示速率
 	HTTPRequestRatePerSrc int `json:"http_request_rate_per_src"`
+	// 源IP监听请求最大速率
+	HTTPRequestRatePerHeader int `json:"http_request_rate_per_header"`
+	// 按请求头区分客户端的最大请求速率
+	HTTPRequestRateHeader string `json:"http_request_rate_header"`
 }
 
 // SLoadbalancerHTTPRedirect is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SLoadbalancerHTTPRedirect.
@@ -1898,12 +2030,14 @@ type SLoadbalancerListener struct {
 	AclType            string `json:"acl_type"`
 	SLoadbalancerAclResourceBase
 	SLoadbalancerRateLimiter
+	SLoadbalancerConnLimiter
 	SLoadbalancerTCPListener
 	SLoadbalancerUDPListener
 	SLoadbalancerHTTPListener
 	SLoadbalancerHTTPSListener
 	SLoadbalancerHealthCheck
 	SLoadbalancerHTTPRateLimiter
+	SLoadbalancerHTTPHeaderRewriter
 	SLoadbalancerHTTPRedirect
 }
 
@@ -1927,6 +2061,7 @@ type SLoadbalancerListenerRule struct {
 	SLoadbalancerHealthCheck
 	// 目前只有腾讯云HTTP、HTTPS类型的健康检查是和规则绑定的。
 	SLoadbalancerHTTPRateLimiter
+	SLoadbalancerHTTPHeaderRewriter
 	SLoadbalancerHTTPRedirect
 }
 
@@ -2718,6 +2853,19 @@ type SStorageResourceBase struct {
 	StorageId string `json:"storage_id"`
 }
 
+// SStorageUsageSample is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SStorageUsageSample.
+type SStorageUsageSample struct {
+	apis.SResourceBase
+	RowId     int64  `json:"row_id"`
+	StorageId string `json:"storage_id"`
+	// 容量, 单位MB
+	Capacity int64 `json:"capacity"`
+	// 实际使用量, 单位MB
+	ActualCapacityUsed int64 `json:"actual_capacity_used"`
+	// 已分配容量, 单位MB
+	AllocatedCapacity int64 `json:"allocated_capacity"`
+}
+
 // SStoragecache is an autogenerated struct via yunion.io/x/onecloud/pkg/compute/models.SStoragecache.
 type SStoragecache struct {
 	apis.SStandaloneResourceBase
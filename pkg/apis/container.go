@@ -24,12 +24,38 @@ import (
 type ContainerKeyValue struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
+	// ValueFrom takes the value from the key of container_secret or container_config,
+	// it's resolved to Value by region before sending to host
+	ValueFrom *ContainerValueSource `json:"value_from,omitempty"`
+}
+
+type ContainerObjectKeyRef struct {
+	// Id or name of container_secret or container_config
+	Id  string `json:"id"`
+	Key string `json:"key"`
+}
+
+type ContainerValueSource struct {
+	Secret *ContainerObjectKeyRef `json:"secret,omitempty"`
+	Config *ContainerObjectKeyRef `json:"config,omitempty"`
+}
+
+// GetObjectRef returns the referenced object type and key, secret takes precedence
+func (s *ContainerValueSource) GetObjectRef() (ContainerVolumeMountType, *ContainerObjectKeyRef) {
+	if s.Secret != nil {
+		return CONTAINER_VOLUME_MOUNT_TYPE_SECRET, s.Secret
+	}
+	if s.Config != nil {
+		return CONTAINER_VOLUME_MOUNT_TYPE_CONFIG, s.Config
+	}
+	return "", nil
 }
 
 type ContainerLifecyleHandlerType string
 
 const (
-	ContainerLifecyleHandlerTypeExec ContainerLifecyleHandlerType = "exec"
+	ContainerLifecyleHandlerTypeExec    ContainerLifecyleHandlerType = "exec"
+	ContainerLifecyleHandlerTypeHTTPGet ContainerLifecyleHandlerType = "http_get"
 )
 
 type ContainerLifecyleHandlerExecAction struct {
@@ -37,14 +63,32 @@ type ContainerLifecyleHandlerExecAction struct {
 }
 
 type ContainerLifecyleHandler struct {
-	Type ContainerLifecyleHandlerType        `json:"type"`
-	Exec *ContainerLifecyleHandlerExecAction `json:"exec"`
+	Type    ContainerLifecyleHandlerType        `json:"type"`
+	Exec    *ContainerLifecyleHandlerExecAction `json:"exec"`
+	HTTPGet *ContainerProbeHTTPGetAction        `json:"http_get,omitempty"`
 }
 
 type ContainerLifecyle struct {
 	PostStart *ContainerLifecyleHandler `json:"post_start"`
+	// PreStop is called immediately before a container is stopped,
+	// the container will be killed after the handler completes or the grace period is reached.
+	PreStop *ContainerLifecyleHandler `json:"pre_stop,omitempty"`
+	// Seconds to wait for the PreStop handler and the graceful shutdown of the container,
+	// default to 30 seconds.
+	TerminationGracePeriodSeconds int64 `json:"termination_grace_period_seconds,omitempty"`
+}
+
+func (l *ContainerLifecyle) GetTerminationGracePeriodSeconds() int64 {
+	if l == nil || l.TerminationGracePeriodSeconds <= 0 {
+		return CONTAINER_DEFAULT_TERMINATION_GRACE_PERIOD_SECONDS
+	}
+	return l.TerminationGracePeriodSeconds
 }
 
+const (
+	CONTAINER_DEFAULT_TERMINATION_GRACE_PERIOD_SECONDS = 30
+)
+
 type ContainerProcMountType string
 
 const (
@@ -130,6 +174,11 @@ type ContainerSpec struct {
 	StartupProbe  *ContainerProbe `json:"startup_probe,omitempty"`
 	AlwaysRestart bool            `json:"always_restart"`
 	Primary       bool            `json:"primary"`
+	// Init marks an init container, which must run to completion
+	// before the application containers of the pod are started.
+	Init bool `json:"init,omitempty"`
+	// InitOrder is the starting order of init containers in the pod
+	InitOrder int `json:"init_order,omitempty"`
 }
 
 func (c *ContainerSpec) NeedProbe() bool {
@@ -161,6 +210,8 @@ const (
 	CONTAINER_VOLUME_MOUNT_TYPE_HOST_PATH ContainerVolumeMountType = "host_path"
 	CONTAINER_VOLUME_MOUNT_TYPE_TEXT      ContainerVolumeMountType = "text"
 	CONTAINER_VOLUME_MOUNT_TYPE_CEPHF_FS  ContainerVolumeMountType = "ceph_fs"
+	CONTAINER_VOLUME_MOUNT_TYPE_SECRET    ContainerVolumeMountType = "secret"
+	CONTAINER_VOLUME_MOUNT_TYPE_CONFIG    ContainerVolumeMountType = "config"
 )
 
 type ContainerDeviceType string
@@ -195,6 +246,8 @@ type ContainerVolumeMount struct {
 	HostPath   *ContainerVolumeMountHostPath `json:"host_path"`
 	Text       *ContainerVolumeMountText     `json:"text"`
 	CephFS     *ContainerVolumeMountCephFS   `json:"ceph_fs"`
+	Secret     *ContainerVolumeMountObject   `json:"secret,omitempty"`
+	Config     *ContainerVolumeMountObject   `json:"config,omitempty"`
 	// Mounted read-only if true, read-write otherwise (false or unspecified).
 	ReadOnly bool `json:"read_only"`
 	// Path within the container at which the volume should be mounted.  Must
@@ -331,6 +384,42 @@ type ContainerVolumeMountText struct {
 	Content string `json:"content"`
 }
 
+type ContainerObjectRefreshPolicy string
+
+const (
+	// rewrite the mounted files in place when the object is updated
+	CONTAINER_OBJECT_REFRESH_POLICY_REFRESH ContainerObjectRefreshPolicy = "refresh"
+	// restart the running container when the object is updated
+	CONTAINER_OBJECT_REFRESH_POLICY_RESTART ContainerObjectRefreshPolicy = "restart"
+	// changes take effect at the next start of the container
+	CONTAINER_OBJECT_REFRESH_POLICY_NONE ContainerObjectRefreshPolicy = "none"
+)
+
+type ContainerKeyToPath struct {
+	Key string `json:"key"`
+	// Relative path of the file in the mounted directory
+	Path string `json:"path"`
+}
+
+// ContainerVolumeMountObject mounts the keys of container_secret or container_config as files
+type ContainerVolumeMountObject struct {
+	// Id or name of container_secret or container_config
+	Id string `json:"id"`
+	// Key mounts the value of the key as a single file at mount_path
+	Key string `json:"key,omitempty"`
+	// Items mounts the keys as files in the directory of mount_path, all keys are mounted if both key and items are empty
+	Items []ContainerKeyToPath `json:"items,omitempty"`
+	// RefreshPolicy defaults to refresh
+	RefreshPolicy ContainerObjectRefreshPolicy `json:"refresh_policy,omitempty"`
+}
+
+func (o *ContainerVolumeMountObject) GetRefreshPolicy() ContainerObjectRefreshPolicy {
+	if o.RefreshPolicy == "" {
+		return CONTAINER_OBJECT_REFRESH_POLICY_REFRESH
+	}
+	return o.RefreshPolicy
+}
+
 type ContainerVolumeMountCephFS struct {
 	Id string `json:"id"`
 }
@@ -41,6 +41,19 @@ type ContainerVolumeMountCephFS struct {
 	Name    string `json:"name"`
 }
 
+type ContainerVolumeMountFile struct {
+	// Relative path of the file, empty if the object is mounted as a single file
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+type ContainerVolumeMountObject struct {
+	Id string `json:"id"`
+	// IsFile means the only file is mounted at mount_path, otherwise files are put in the mounted directory
+	IsFile bool                        `json:"is_file"`
+	Files  []*ContainerVolumeMountFile `json:"files"`
+}
+
 type ContainerRootfs struct {
 	Type apis.ContainerVolumeMountType `json:"type"`
 	Disk *ContainerVolumeMountDisk     `json:"disk"`
@@ -55,6 +68,8 @@ type ContainerVolumeMount struct {
 	HostPath   *apis.ContainerVolumeMountHostPath `json:"host_path"`
 	Text       *apis.ContainerVolumeMountText     `json:"text"`
 	CephFS     *ContainerVolumeMountCephFS        `json:"ceph_fs"`
+	Secret     *ContainerVolumeMountObject        `json:"secret,omitempty"`
+	Config     *ContainerVolumeMountObject        `json:"config,omitempty"`
 	// Mounted read-only if true, read-write otherwise (false or unspecified).
 	ReadOnly bool `json:"read_only"`
 	// Path within the container at which the volume should be mounted.  Must
@@ -76,6 +91,39 @@ type ContainerSpec struct {
 	Devices              []*ContainerDevice      `json:"devices"`
 }
 
+// WithoutSecretData returns a copy of the volume mount with the file contents of container_secret removed
+func (vm *ContainerVolumeMount) WithoutSecretData() *ContainerVolumeMount {
+	if vm.Secret == nil {
+		return vm
+	}
+	ret := *vm
+	secret := *vm.Secret
+	secret.Files = make([]*ContainerVolumeMountFile, len(vm.Secret.Files))
+	for i := range vm.Secret.Files {
+		secret.Files[i] = &ContainerVolumeMountFile{Path: vm.Secret.Files[i].Path}
+	}
+	ret.Secret = &secret
+	return &ret
+}
+
+// WithoutSecretData returns a copy of the spec with the values resolved from container_secret removed,
+// it's used for the desc persisted by host.
+func (s *ContainerSpec) WithoutSecretData() *ContainerSpec {
+	ret := *s
+	ret.Envs = make([]*apis.ContainerKeyValue, len(s.Envs))
+	for i, env := range s.Envs {
+		if env.ValueFrom != nil && env.ValueFrom.Secret != nil {
+			env = &apis.ContainerKeyValue{Key: env.Key, ValueFrom: env.ValueFrom}
+		}
+		ret.Envs[i] = env
+	}
+	ret.VolumeMounts = make([]*ContainerVolumeMount, len(s.VolumeMounts))
+	for i, vm := range s.VolumeMounts {
+		ret.VolumeMounts[i] = vm.WithoutSecretData()
+	}
+	return &ret
+}
+
 type ContainerDevice struct {
 	Type           apis.ContainerDeviceType `json:"type"`
 	ContainerPath  string                   `json:"container_path"`
@@ -152,3 +200,8 @@ type ContainerStopInput struct {
 	ContainerName string `json:"container_name"`
 	Force         bool   `json:"force"`
 }
+
+type ContainerRefreshVolumeMountsInput struct {
+	// Secret or config volume mounts whose files should be rewritten
+	VolumeMounts []*ContainerVolumeMount `json:"volume_mounts"`
+}
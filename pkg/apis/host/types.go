@@ -29,6 +29,15 @@ type HostNodeHugepageNr struct {
 	HugepageNr int `json:"hugepage_nr"`
 }
 
+// HostNodeReservedHugepages is the count of the hugepages of a page size
+// reserved on a numa node
+type HostNodeReservedHugepages struct {
+	NodeId int `json:"node_id"`
+	SizeKb int `json:"size_kb"`
+	Total  int `json:"total"`
+	Free   int `json:"free"`
+}
+
 type HostTopology struct {
 	*topology.Info
 }
@@ -74,6 +74,40 @@ const (
 	IMAGE_STATUS_UPDATING = "updating"
 )
 
+const (
+	GUEST_IMAGE_OVA_STATUS_EXPORTING = "exporting"
+	GUEST_IMAGE_OVA_STATUS_READY     = "ready"
+	GUEST_IMAGE_OVA_STATUS_FAILED    = "failed"
+
+	GUEST_IMAGE_FORMAT_OVA = "ova"
+)
+
+const (
+	// image rejected by signature policy, it never becomes active until
+	// a valid signature is attached
+	IMAGE_STATUS_UNTRUSTED = "untrusted"
+
+	IMAGE_SIGNATURE_STATUS_UNSIGNED = ""
+	IMAGE_SIGNATURE_STATUS_VERIFIED = "verified"
+	IMAGE_SIGNATURE_STATUS_INVALID  = "invalid"
+)
+
+const (
+	// image blocked by ImageScanBlockSeverity, it becomes active again
+	// once a rescan finds nothing at or above the severity
+	IMAGE_STATUS_VULNERABLE = "vulnerable"
+	// image failed to be scanned while ImageScanBlockSeverity is set, it
+	// becomes active once a rescan passes
+	IMAGE_STATUS_SCAN_FAILED = "scan_failed"
+
+	IMAGE_SCAN_STATUS_NONE    = ""
+	IMAGE_SCAN_STATUS_SCANNED = "scanned"
+	IMAGE_SCAN_STATUS_FAILED  = "failed"
+
+	IMAGE_SBOM_FORMAT_SPDX      = "spdx"
+	IMAGE_SBOM_FORMAT_CYCLONEDX = "cyclonedx"
+)
+
 var (
 	ImageDeadStatus = []string{IMAGE_STATUS_DEACTIVATED, IMAGE_STATUS_KILLED, IMAGE_STATUS_DELETED, IMAGE_STATUS_PENDING_DELETE}
 )
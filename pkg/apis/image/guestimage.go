@@ -87,4 +87,10 @@ type GuestImageCreateInput struct {
 
 	// 镜像属性
 	Properties map[string]string `json:"properties"`
+
+	// OVA 虚拟设备的下载地址, 指定后从 OVA 导入全部磁盘并创建对应的主机模板
+	OvaUrl string `json:"ova_url"`
+}
+
+type GuestImageExportOvaInput struct {
 }
@@ -58,6 +58,11 @@ type ImageListInput struct {
 	Distributions []string `json:"distributions"`
 	// 发行版精确匹配
 	DistributionPreciseMatch bool `json:"distribution_precise_match`
+
+	// 以镜像内容扫描状态过滤, 可能值为: scanned, failed
+	ScanStatus []string `json:"scan_status"`
+	// 列出存在不低于该等级漏洞的镜像, 可能值为: low, medium, high, critical
+	MinVulnSeverity string `json:"min_vuln_severity"`
 }
 
 type GuestImageListInput struct {
@@ -107,6 +112,11 @@ type ImageCreateInput struct {
 
 	apis.EncryptedResourceCreateInput
 
+	// 镜像校验和的签名, base64编码
+	Signature string `json:"signature"`
+	// 签名密钥Id, 为空时使用所在域内所有启用的签名密钥验证
+	SignerKeyId string `json:"signer_key_id"`
+
 	// 镜像属性
 	Properties map[string]string `json:"properties"`
 }
@@ -141,3 +151,24 @@ type ImageUpdateStatusInput struct {
 
 type PerformProbeInput struct {
 }
+
+type ImageSetSignatureInput struct {
+	// 镜像校验和的签名, base64编码
+	// required: true
+	Signature string `json:"signature"`
+	// 签名密钥Id, 为空时使用所在域内所有启用的签名密钥验证
+	SignerKeyId string `json:"signer_key_id"`
+}
+
+type PerformScanInput struct {
+}
+
+type ImageSbomInput struct {
+	// SBOM格式, 可能值为: spdx, cyclonedx, 默认spdx
+	Format string `json:"format"`
+}
+
+type ImageVulnerabilityListInput struct {
+	// 以漏洞等级过滤, 列出不低于该等级的漏洞
+	MinSeverity string `json:"min_severity"`
+}
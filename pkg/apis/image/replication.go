@@ -0,0 +1,123 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"time"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+const (
+	IMAGE_REPLICATION_TRIGGER_MANUAL    = "manual"
+	IMAGE_REPLICATION_TRIGGER_ON_CHANGE = "on_change"
+	IMAGE_REPLICATION_TRIGGER_SCHEDULE  = "schedule"
+
+	IMAGE_REPLICATION_STATUS_SYNCING     = "syncing"
+	IMAGE_REPLICATION_STATUS_SYNC_FAILED = "sync_failed"
+
+	IMAGE_REPLICATION_TARGET_STATUS_PENDING     = "pending"
+	IMAGE_REPLICATION_TARGET_STATUS_REPLICATING = "replicating"
+	IMAGE_REPLICATION_TARGET_STATUS_READY       = "ready"
+	IMAGE_REPLICATION_TARGET_STATUS_FAILED      = "failed"
+
+	// copy_from of images replicated from the image service of another
+	// region, glance://<region>/<image_id>
+	IMAGE_COPY_FROM_GLANCE_PREFIX = "glance://"
+
+	// property of replicated images recording the source region and image
+	IMAGE_REPLICATED_FROM = "replicated_from"
+)
+
+type ImageReplicationTargetInput struct {
+	// 目标区域
+	// required: true
+	Region string `json:"region"`
+	// 目标项目Id或名称, 为空时与源镜像所在项目相同
+	Project string `json:"project"`
+}
+
+type ImageReplicationCreateInput struct {
+	apis.EnabledStatusDomainLevelResourceCreateInput
+
+	// 源镜像Id或名称
+	// required: true
+	ImageId string `json:"image_id"`
+	// 触发方式, 可能值为: manual, on_change, schedule, 默认manual
+	Trigger string `json:"trigger"`
+	// 定时同步间隔, 单位分钟, trigger为schedule时有效
+	IntervalMinutes int `json:"interval_minutes"`
+	// 同步目标
+	// required: true
+	Targets []ImageReplicationTargetInput `json:"targets"`
+}
+
+type ImageReplicationListInput struct {
+	apis.EnabledStatusDomainLevelResourceListInput
+
+	// 以源镜像过滤
+	ImageId []string `json:"image_id"`
+	// 以触发方式过滤
+	Trigger []string `json:"trigger"`
+}
+
+type ImageReplicationUpdateInput struct {
+	apis.EnabledStatusDomainLevelResourceBaseUpdateInput
+
+	// 触发方式, 可能值为: manual, on_change, schedule
+	Trigger string `json:"trigger"`
+	// 定时同步间隔, 单位分钟
+	IntervalMinutes *int `json:"interval_minutes"`
+}
+
+type ImageReplicationTargetDetails struct {
+	Region    string `json:"region"`
+	ProjectId string `json:"project_id"`
+	DomainId  string `json:"domain_id"`
+	// 目标区域已同步的镜像Id
+	TargetImageId string `json:"target_image_id"`
+	// 目标区域正在同步的镜像Id
+	PendingImageId string `json:"pending_image_id"`
+	// 同步状态, pending, replicating, ready, failed
+	Status string `json:"status"`
+	// 已同步的源镜像校验和
+	SourceChecksum string    `json:"source_checksum"`
+	LastSyncAt     time.Time `json:"last_sync_at"`
+	Reason         string    `json:"reason"`
+}
+
+type ImageReplicationDetails struct {
+	apis.EnabledStatusDomainLevelResourceDetails
+
+	SImageReplication
+
+	// 源镜像名称
+	Image string `json:"image"`
+	// 同步目标及状态
+	Targets []ImageReplicationTargetDetails `json:"targets"`
+}
+
+type ImageReplicationRemoveTargetInput struct {
+	// 目标区域
+	// required: true
+	Region string `json:"region"`
+	// 目标项目Id或名称, 为空时删除该区域所有目标
+	Project string `json:"project"`
+}
+
+type ImageReplicationSyncInput struct {
+	// 重新同步所有目标, 包括已同步的目标
+	Force bool `json:"force"`
+}
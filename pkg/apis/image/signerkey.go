@@ -0,0 +1,44 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import "yunion.io/x/onecloud/pkg/apis"
+
+type ImageSignerKeyCreateInput struct {
+	apis.EnabledStatusDomainLevelResourceCreateInput
+
+	// PEM格式的ecdsa或ed25519公钥
+	// required: true
+	PublicKey string `json:"public_key"`
+}
+
+type ImageSignerKeyListInput struct {
+	apis.EnabledStatusDomainLevelResourceListInput
+
+	// 以签名算法过滤, 可能值为: ecdsa, ed25519
+	Algorithm []string `json:"algorithm"`
+	// 以公钥指纹过滤
+	Fingerprint []string `json:"fingerprint"`
+}
+
+type ImageSignerKeyUpdateInput struct {
+	apis.EnabledStatusDomainLevelResourceBaseUpdateInput
+}
+
+type ImageSignerKeyDetails struct {
+	apis.EnabledStatusDomainLevelResourceDetails
+
+	SImageSignerKey
+}
@@ -15,6 +15,8 @@
 package image
 
 import (
+	"time"
+
 	"yunion.io/x/onecloud/pkg/apis"
 )
 
@@ -24,6 +26,8 @@ type SGuestImage struct {
 	apis.SMultiArchResourceBase
 	apis.SEncryptedResource
 	Protected *bool `json:"protected,omitempty"`
+	// 导出 OVA 的状态
+	OvaStatus string `json:"ova_status"`
 }
 
 // SGuestImageJoint is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SGuestImageJoint.
@@ -66,6 +70,20 @@ type SImage struct {
 	OssChecksum string `json:"oss_checksum"`
 	// 加密状态, "",encrypting,encrypted
 	EncryptStatus string `json:"encrypt_status"`
+	// 镜像校验和的签名, base64编码
+	Signature string `json:"signature"`
+	// 验证签名的签名密钥Id
+	SignerKeyId string `json:"signer_key_id"`
+	// 签名状态, "",verified,invalid
+	SignatureStatus string `json:"signature_status"`
+	// 镜像内容扫描状态, "",scanned,failed
+	ScanStatus string `json:"scan_status"`
+	// 扫描到的已安装软件包数量
+	PackageCount int `json:"package_count"`
+	// 匹配到的漏洞数量
+	VulnerabilityCount int `json:"vulnerability_count"`
+	// 漏洞最高等级, "",low,medium,high,critical
+	MaxVulnSeverity string `json:"max_vuln_severity"`
 }
 
 // SImagePeripheral is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImagePeripheral.
@@ -74,3 +92,29 @@ type SImagePeripheral struct {
 	Id      int    `json:"id"`
 	ImageId string `json:"image_id"`
 }
+
+// SImageReplication is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageReplication.
+type SImageReplication struct {
+	apis.SEnabledStatusDomainLevelResourceBase
+	// 源镜像Id
+	ImageId string `json:"image_id"`
+	// 源区域
+	SourceRegion string `json:"source_region"`
+	// 触发方式, manual, on_change, schedule
+	Trigger string `json:"trigger"`
+	// 定时同步间隔, 单位分钟
+	IntervalMinutes int `json:"interval_minutes"`
+	// 上次触发同步时间
+	LastReplicatedAt time.Time `json:"last_replicated_at"`
+}
+
+// SImageSignerKey is an autogenerated struct via yunion.io/x/onecloud/pkg/image/models.SImageSignerKey.
+type SImageSignerKey struct {
+	apis.SEnabledStatusDomainLevelResourceBase
+	// PEM格式的公钥
+	PublicKey string `json:"public_key"`
+	// 签名算法, ecdsa或ed25519
+	Algorithm string `json:"algorithm"`
+	// 公钥指纹
+	Fingerprint string `json:"fingerprint"`
+}
@@ -15,6 +15,8 @@
 package scheduler
 
 import (
+	"sort"
+
 	"yunion.io/x/jsonutils"
 
 	"yunion.io/x/onecloud/pkg/apis"
@@ -196,6 +198,55 @@ func NodesFreeCpuEnough(nodeCount, vcpuCount int, cpuNumaFree []*SFreeNumaCpuMem
 	return freeCpu >= vcpuCount
 }
 
+// SFreeHugepages is the free memory of the hugepages of a page size reserved
+// on host, besides the native hugepages
+type SFreeHugepages struct {
+	// nodeId: free memSizeMB
+	NodeFreeMB map[int]int
+	// memory of guests not pinned to numa nodes, which may come from any node
+	UnplacedMB int
+}
+
+func (h *SFreeHugepages) totalFreeMB() int {
+	total := -h.UnplacedMB
+	for _, free := range h.NodeFreeMB {
+		total += free
+	}
+	return total
+}
+
+// NodesFit tells whether nodeCount distinct numa nodes can each back
+// nodeMemMB of guest memory
+func (h *SFreeHugepages) NodesFit(nodeCount, nodeMemMB int) bool {
+	if nodeCount <= 0 || nodeCount > len(h.NodeFreeMB) {
+		return false
+	}
+	frees := make([]int, 0, len(h.NodeFreeMB))
+	for _, free := range h.NodeFreeMB {
+		frees = append(frees, free)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(frees)))
+	for i := 0; i < nodeCount; i++ {
+		if frees[i] < nodeMemMB {
+			return false
+		}
+	}
+	return h.totalFreeMB() >= nodeCount*nodeMemMB
+}
+
+// PinnedFit tells whether the numa nodes can back the guest memory pinned
+// to them, nodeId: memSizeMB
+func (h *SFreeHugepages) PinnedFit(nodeMemMB map[int]int) bool {
+	total := 0
+	for nodeId, memMB := range nodeMemMB {
+		if h.NodeFreeMB[nodeId] < memMB {
+			return false
+		}
+		total += memMB
+	}
+	return h.totalFreeMB() >= total
+}
+
 type SCpuPin struct {
 	Vcpu int
 	Pcpu int
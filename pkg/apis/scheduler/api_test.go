@@ -0,0 +1,59 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import "testing"
+
+func TestFreeHugepagesFit(t *testing.T) {
+	free := &SFreeHugepages{
+		NodeFreeMB: map[int]int{0: 4096, 1: 2048, 2: 1024},
+		UnplacedMB: 1024,
+	}
+	cases := []struct {
+		name      string
+		nodeCount int
+		nodeMemMB int
+		want      bool
+	}{
+		{"one node", 1, 4096, true},
+		{"one node too large", 1, 6144, false},
+		{"two nodes", 2, 2048, true},
+		{"two nodes too large", 2, 3072, false},
+		{"three nodes over unplaced", 3, 1024, true},
+		{"unplaced taken", 3, 2048, false},
+		{"more nodes than host", 4, 256, false},
+	}
+	for _, c := range cases {
+		if got := free.NodesFit(c.nodeCount, c.nodeMemMB); got != c.want {
+			t.Errorf("%s: want %v, got %v", c.name, c.want, got)
+		}
+	}
+
+	pinned := []struct {
+		name string
+		mems map[int]int
+		want bool
+	}{
+		{"pinned", map[int]int{0: 2048, 1: 2048}, true},
+		{"pinned node short", map[int]int{2: 2048}, false},
+		{"pinned unknown node", map[int]int{3: 512}, false},
+		{"pinned over unplaced", map[int]int{0: 4096, 1: 2048, 2: 512}, false},
+	}
+	for _, c := range pinned {
+		if got := free.PinnedFit(c.mems); got != c.want {
+			t.Errorf("%s: want %v, got %v", c.name, c.want, got)
+		}
+	}
+}
@@ -20,3 +20,10 @@ const (
 	SERVICE_TYPE    = apis.SERVICE_TYPE_SCHEDULER
 	SERVICE_VERSION = ""
 )
+
+const (
+	// count free capacity of storages on allocated disk size against the overcommit bound
+	STORAGE_SCHEDULE_MODE_ALLOCATED = "allocated"
+	// count free capacity of thin provisioned storages on the space actually used
+	STORAGE_SCHEDULE_MODE_ACTUAL = "actual"
+)
@@ -956,6 +956,12 @@ func GetItemDetails(manager IModelManager, item IModel, ctx context.Context, use
 	return getItemDetails(manager, item, ctx, userCred, jsonutils.NewDict())
 }
 
+// GetItemDetailsWithQuery returns the default details body, for models
+// with customized details body falling back to it
+func GetItemDetailsWithQuery(manager IModelManager, item IModel, ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return getItemDetails(manager, item, ctx, userCred, query)
+}
+
 func getItemDetails(manager IModelManager, item IModel, ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	metaFields, excludeFields := GetDetailFields(manager, userCred)
 	fieldFilter := jsonutils.GetQueryStringArray(query, "field")
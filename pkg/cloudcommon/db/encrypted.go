@@ -64,6 +64,26 @@ func (res *SEncryptedResource) GetEncryptInfo(
 	return ret, nil
 }
 
+// EncryptData encrypts the data with the encrypt key of the resource and returns base64 encoded cipher text
+func (res *SEncryptedResource) EncryptData(ctx context.Context, userCred mcclient.TokenCredential, data []byte) (string, error) {
+	session := auth.GetSession(ctx, userCred, consts.GetRegion())
+	secKey, err := identity_modules.Credentials.GetEncryptKey(session, res.EncryptKeyId)
+	if err != nil {
+		return "", errors.Wrap(err, "GetEncryptKey")
+	}
+	return secKey.EncryptBase64(data)
+}
+
+// DecryptData decrypts the base64 encoded cipher text returned by EncryptData
+func (res *SEncryptedResource) DecryptData(ctx context.Context, userCred mcclient.TokenCredential, cipherText string) ([]byte, error) {
+	session := auth.GetSession(ctx, userCred, consts.GetRegion())
+	secKey, err := identity_modules.Credentials.GetEncryptKey(session, res.EncryptKeyId)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetEncryptKey")
+	}
+	return secKey.DecryptBase64(cipherText)
+}
+
 func (res *SEncryptedResource) ValidateEncryption(ctx context.Context, userCred mcclient.TokenCredential) error {
 	if res.IsEncrypted() {
 		_, err := res.GetEncryptInfo(ctx, userCred)
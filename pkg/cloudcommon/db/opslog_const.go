@@ -343,4 +343,23 @@ const (
 	ACT_REBUILD_FAILED = "rebuild_failed"
 
 	ACT_SET_COMMIT_BOUND = "set_commit_bound"
+
+	ACT_VERIFY_SIGNATURE      = "verify_signature"
+	ACT_VERIFY_SIGNATURE_FAIL = "verify_signature_fail"
+
+	ACT_IMAGE_SCAN      = "image_scan"
+	ACT_IMAGE_SCAN_FAIL = "image_scan_fail"
+
+	ACT_IMAGE_REPLICATE      = "image_replicate"
+	ACT_IMAGE_REPLICATE_FAIL = "image_replicate_fail"
+
+	ACT_DISK_REPLICA_FAILOVER      = "disk_replica_failover"
+	ACT_DISK_REPLICA_FAILOVER_FAIL = "disk_replica_failover_fail"
+
+	ACT_DR_FAILOVER           = "dr_failover"
+	ACT_DR_FAILOVER_FAIL      = "dr_failover_fail"
+	ACT_DR_TEST_FAILOVER      = "dr_test_failover"
+	ACT_DR_TEST_FAILOVER_FAIL = "dr_test_failover_fail"
+	ACT_DR_FAILBACK           = "dr_failback"
+	ACT_DR_FAILBACK_FAIL      = "dr_failback_fail"
 )
@@ -154,6 +154,9 @@ func (router *SRouter) PostUpdate(ctx context.Context, userCred mcclient.TokenCr
 
 func (router *SRouter) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
 	var errs []error
+	if err := VpnGatewayManager.removeByRouter(ctx, userCred, router); err != nil {
+		errs = append(errs, err)
+	}
 	if err := MeshNetworkManager.removeRouter(ctx, userCred, router); err != nil {
 		errs = append(errs, err)
 	}
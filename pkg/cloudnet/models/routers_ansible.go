@@ -365,3 +365,71 @@ func (router *SRouter) playEssential() *ansiblev2.Play {
 	play.Name = "Perform essential steps"
 	return play
 }
+
+func (router *SRouter) playDeployIpsec() (*ansiblev2.Play, error) {
+	conns, err := router.swanctlConns()
+	if err != nil {
+		return nil, err
+	}
+	var tasks []ansiblev2.ITask
+	if len(conns) > 0 {
+		tasks = []ansiblev2.ITask{
+			&ansiblev2.Task{
+				Name:       "Install strongswan",
+				ModuleName: "package",
+				ModuleArgs: map[string]interface{}{
+					"name":  "strongswan",
+					"state": "present",
+				},
+			},
+			&ansiblev2.Task{
+				Name:       "Put swanctl conf",
+				ModuleName: "copy",
+				ModuleArgs: map[string]interface{}{
+					"content": genSwanctlConf(conns),
+					"dest":    swanctlConfPath,
+					"owner":   "root",
+					"group":   "root",
+					"mode":    "0600",
+				},
+				Register: "swanctl_conf",
+			},
+			&ansiblev2.Task{
+				Name:       "Enable strongswan-swanctl service",
+				ModuleName: "service",
+				ModuleArgs: map[string]interface{}{
+					"name":    "strongswan-swanctl",
+					"state":   "started",
+					"enabled": "yes",
+				},
+			},
+			&ansiblev2.ShellTask{
+				Name:   "Load swanctl conf",
+				Script: "swanctl --load-all",
+				When:   "swanctl_conf.changed",
+			},
+		}
+	} else {
+		tasks = []ansiblev2.ITask{
+			&ansiblev2.Task{
+				Name:       "Remove swanctl conf",
+				ModuleName: "file",
+				ModuleArgs: map[string]interface{}{
+					"path":  swanctlConfPath,
+					"state": "absent",
+				},
+				Register: "swanctl_conf",
+			},
+			&ansiblev2.ShellTask{
+				Name:         "Unload swanctl conf",
+				Script:       "swanctl --load-all",
+				IgnoreErrors: true,
+				When:         "swanctl_conf.changed",
+			},
+		}
+	}
+	play := ansiblev2.NewPlay(tasks...)
+	play.Hosts = "all"
+	play.Name = "Configure ipsec connections"
+	return play, nil
+}
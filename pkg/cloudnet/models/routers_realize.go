@@ -45,6 +45,13 @@ func (router *SRouter) realize(ctx context.Context, userCred mcclient.TokenCrede
 			router.playDeployWireguardNetworks(),
 		)
 	}
+	{
+		playIpsec, err := router.playDeployIpsec()
+		if err != nil {
+			return err
+		}
+		plays = append(plays, playIpsec)
+	}
 
 	if router.RealizeRoutes {
 		playRoutes, err := router.playDeployRoutes()
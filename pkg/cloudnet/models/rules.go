@@ -71,6 +71,10 @@ const (
 
 	PROTO_TCP = "tcp"
 	PROTO_UDP = "udp"
+	PROTO_ESP = "esp" // internal use for ipsec rules
+
+	PORT_IKE      = 500
+	PORT_IKE_NATT = 4500
 )
 
 var (
@@ -342,6 +346,45 @@ func (man *SRuleManager) addWireguardIfaceRules(ctx context.Context, userCred mc
 	return err
 }
 
+// addIpsecRules accepts ike and esp traffic on router.  They are shared by
+// all ipsec peers and added only once
+func (man *SRuleManager) addIpsecRules(ctx context.Context, userCred mcclient.TokenCredential, router *SRouter) error {
+	if existing, err := man.getByFilter(map[string]string{
+		"router_id":   router.Id,
+		"match_proto": PROTO_ESP,
+		"action":      ACT_INPUT_ACCEPT,
+	}); err != nil {
+		return err
+	} else if len(existing) > 0 {
+		return nil
+	}
+	rules := []*SRule{
+		&SRule{
+			RouterId:      router.Id,
+			MatchProto:    PROTO_UDP,
+			MatchDestPort: PORT_IKE,
+			Action:        ACT_INPUT_ACCEPT,
+		},
+		&SRule{
+			RouterId:      router.Id,
+			MatchProto:    PROTO_UDP,
+			MatchDestPort: PORT_IKE_NATT,
+			Action:        ACT_INPUT_ACCEPT,
+		},
+		&SRule{
+			RouterId:   router.Id,
+			MatchProto: PROTO_ESP,
+			Action:     ACT_INPUT_ACCEPT,
+		},
+	}
+	for _, r := range rules {
+		r.IsSystem = true
+		r.Name = router.Name + "-allow-ipsec-" + rand.String(4)
+		r.SetModelManager(man, r)
+	}
+	return man.addRules(ctx, userCred, rules)
+}
+
 func (man *SRuleManager) ifaceTCPMSSRules(ctx context.Context, userCred mcclient.TokenCredential, iface *SIface) []*SRule {
 	rules := []*SRule{
 		&SRule{
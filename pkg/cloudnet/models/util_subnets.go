@@ -22,6 +22,18 @@ import (
 
 type Subnets []*netutils.IPV4Prefix
 
+func newSubnetsFromStrList(strs []string) (Subnets, error) {
+	r := make([]*netutils.IPV4Prefix, 0, len(strs))
+	for _, s := range strs {
+		p, err := netutils.NewIPV4Prefix(s)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, &p)
+	}
+	return Subnets(r), nil
+}
+
 func (nets Subnets) StrList() []string {
 	r := make([]string, 0, len(nets))
 	for _, p := range nets {
@@ -0,0 +1,503 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/rand"
+	"yunion.io/x/pkg/util/regutils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	cnutils "yunion.io/x/onecloud/pkg/cloudnet/utils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/choices"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// SVpnGatewayPeer is a remote site connected to vpn gateway.  Wireguard
+// peers are realized as iface peers of the gateway iface.  Ipsec peers are
+// realized as strongswan connections
+type SVpnGatewayPeer struct {
+	db.SStatusStandaloneResourceBase
+
+	VpnGatewayId string `length:"32" nullable:"false" list:"user" create:"required"`
+	RouterId     string `length:"32" nullable:"false" list:"user" create:"required"`
+	TunnelType   string `length:"16" nullable:"false" list:"user" create:"required"`
+
+	Endpoint    string `length:"64" nullable:"false" list:"user" update:"user" create:"optional"`
+	RemoteCidrs string `nullable:"false" list:"user" update:"user" create:"required"`
+
+	PublicKey           string `length:"64" nullable:"false" list:"user" create:"optional"`
+	PersistentKeepalive int    `nullable:"false" list:"user" update:"user" create:"optional"`
+	IfacePeerId         string `length:"32" nullable:"false" list:"user"`
+
+	Psk      string `nullable:"false" update:"user" create:"optional"` // do not allow get, list
+	RemoteId string `nullable:"false" list:"user" update:"user" create:"optional"`
+
+	LastHandshake time.Time `nullable:"true" list:"user"`
+	RxBytes       int64     `nullable:"false" list:"user"`
+	TxBytes       int64     `nullable:"false" list:"user"`
+}
+
+type SVpnGatewayPeerManager struct {
+	db.SStatusStandaloneResourceBaseManager
+}
+
+var VpnGatewayPeerManager *SVpnGatewayPeerManager
+
+var tunnelTypeChoices = choices.NewChoices(
+	api.VPN_TUNNEL_TYPE_WIREGUARD,
+	api.VPN_TUNNEL_TYPE_IPSEC,
+)
+
+func init() {
+	VpnGatewayPeerManager = &SVpnGatewayPeerManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SVpnGatewayPeer{},
+			"vpngateway_peers_tbl",
+			"vpngateway_peer",
+			"vpngateway_peers",
+		),
+	}
+	VpnGatewayPeerManager.SetVirtualObject(VpnGatewayPeerManager)
+}
+
+func validateWireguardEndpoint(endpoint string) error {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return httperrors.NewInputParameterError("invalid endpoint %q: %v", endpoint, err)
+	}
+	if host == "" {
+		return httperrors.NewInputParameterError("invalid endpoint %q: empty host", endpoint)
+	}
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		return httperrors.NewInputParameterError("invalid endpoint %q: bad port", endpoint)
+	}
+	return nil
+}
+
+var ipsecKeyIdReg = regexp.MustCompile(`^@[A-Za-z0-9._-]+$`)
+
+// validateIpsecRemoteId accepts ip address, fqdn or @keyid as ike identity.
+// The charset is kept strict as the id goes into swanctl.conf unquoted
+func validateIpsecRemoteId(id string) error {
+	if net.ParseIP(id) != nil || ipsecKeyIdReg.MatchString(id) {
+		return nil
+	}
+	if regutils.MatchDomainName(id) && strings.Contains(id, ".") {
+		return nil
+	}
+	return httperrors.NewInputParameterError("invalid remote_id %q, want ip address, fqdn or @keyid", id)
+}
+
+// validateData validates tunnel params of peer.  gw is the gateway peer
+// belongs to, peer is nil on create
+func (man *SVpnGatewayPeerManager) validateData(ctx context.Context, data *jsonutils.JSONDict, gw *SVpnGateway, tunnelType string, peer *SVpnGatewayPeer) error {
+	isUpdate := peer != nil
+	remoteCidrsV := newCidrsValidator("remote_cidrs")
+	if isUpdate {
+		remoteCidrsV.Optional(true)
+	}
+	if err := remoteCidrsV.Validate(ctx, data); err != nil {
+		return err
+	}
+	if nets := cidrsValidatorValue(remoteCidrsV); len(nets) > 0 {
+		localNets, err := newSubnetsFromStrList(gw.localCidrsStrList())
+		if err != nil {
+			return httperrors.NewInternalServerError("parse gateway local cidrs: %v", err)
+		}
+		if _, p := localNets.ContainsAnyEx(nets); p != nil {
+			return httperrors.NewInputParameterError("remote cidr %s is also a local cidr of the gateway", p.String())
+		}
+		peers, err := man.getByVpnGateway(gw)
+		if err != nil {
+			return err
+		}
+		for i := range peers {
+			other := &peers[i]
+			if isUpdate && other.Id == peer.Id {
+				continue
+			}
+			if _, p := other.remoteSubnets().ContainsAnyEx(nets); p != nil {
+				return httperrors.NewConflictError("remote cidr %s is already occupied by peer %s(%s)",
+					p.String(), other.Name, other.Id)
+			}
+		}
+	} else if !isUpdate {
+		return httperrors.NewInputParameterError("remote_cidrs must not be empty")
+	}
+
+	switch tunnelType {
+	case api.VPN_TUNNEL_TYPE_WIREGUARD:
+		if !isUpdate {
+			publicKey, _ := data.GetString("public_key")
+			if _, err := cnutils.ParseKeyString(publicKey); err != nil {
+				return httperrors.NewInputParameterError("invalid public_key: %v", err)
+			}
+		}
+		// remote sites behind nat may have no fixed endpoint
+		if endpoint, _ := data.GetString("endpoint"); endpoint != "" {
+			if err := validateWireguardEndpoint(endpoint); err != nil {
+				return err
+			}
+		}
+		keepaliveV := validators.NewRangeValidator("persistent_keepalive", 0, 65535)
+		if err := keepaliveV.Optional(true).Validate(ctx, data); err != nil {
+			return err
+		}
+	case api.VPN_TUNNEL_TYPE_IPSEC:
+		endpointV := validators.NewIPv4AddrValidator("endpoint")
+		pskV := validators.NewStringLenRangeValidator("psk", 8, 128)
+		remoteIdV := validators.NewStringLenRangeValidator("remote_id", 1, 128)
+		vs := []validators.IValidator{
+			endpointV,
+			pskV,
+			remoteIdV.Optional(true),
+		}
+		for _, v := range vs {
+			if isUpdate {
+				v.Optional(true)
+			}
+			if err := v.Validate(ctx, data); err != nil {
+				return err
+			}
+		}
+		if data.Contains("remote_id") {
+			if err := validateIpsecRemoteId(remoteIdV.Value); err != nil {
+				return err
+			}
+		} else if !isUpdate {
+			data.Set("remote_id", jsonutils.NewString(endpointV.IP.String()))
+		}
+	}
+	return nil
+}
+
+func (man *SVpnGatewayPeerManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	input := apis.StatusStandaloneResourceCreateInput{}
+	err := data.Unmarshal(&input)
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("unmarshal StatusStandaloneResourceCreateInput fail %s", err)
+	}
+	input, err = man.SStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input)
+	if err != nil {
+		return nil, err
+	}
+	data.Update(jsonutils.Marshal(input))
+
+	gwV := validators.NewModelIdOrNameValidator("vpn_gateway", "vpngateway", ownerId)
+	tunnelTypeV := validators.NewStringChoicesValidator("tunnel_type", tunnelTypeChoices)
+	vs := []validators.IValidator{
+		gwV,
+		tunnelTypeV.Default(api.VPN_TUNNEL_TYPE_WIREGUARD),
+	}
+	for _, v := range vs {
+		if err := v.Validate(ctx, data); err != nil {
+			return nil, err
+		}
+	}
+	gw := gwV.Model.(*SVpnGateway)
+	if err := man.validateData(ctx, data, gw, tunnelTypeV.Value, nil); err != nil {
+		return nil, err
+	}
+
+	data.Set("router_id", jsonutils.NewString(gw.RouterId))
+	data.Set("status", jsonutils.NewString(api.VPN_PEER_STATUS_INIT))
+	if !data.Contains("name") {
+		data.Set("name", jsonutils.NewString(gw.Name+"-"+rand.String(4)))
+	}
+	return data, nil
+}
+
+// 站点VPN网关对端列表
+func (man *SVpnGatewayPeerManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	input := apis.StatusStandaloneResourceListInput{}
+	err := query.Unmarshal(&input)
+	if err != nil {
+		return nil, errors.Wrap(err, "query.Unmarshal")
+	}
+	q, err = man.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	data := query.(*jsonutils.JSONDict)
+	q, err = validators.ApplyModelFilters(ctx, q, data, []*validators.ModelFilterOptions{
+		{Key: "vpn_gateway", ModelKeyword: "vpngateway", OwnerId: userCred},
+		{Key: "router", ModelKeyword: "router", OwnerId: userCred},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (man *SVpnGatewayPeerManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.VpnGatewayPeerDetails {
+	rows := make([]api.VpnGatewayPeerDetails, len(objs))
+	stdRows := man.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	now := time.Now()
+	for i := range rows {
+		peer := objs[i].(*SVpnGatewayPeer)
+		rows[i] = api.VpnGatewayPeerDetails{
+			StatusStandaloneResourceDetails: stdRows[i],
+			HandshakeAge:                    peer.handshakeAge(now),
+		}
+	}
+	return rows
+}
+
+func (peer *SVpnGatewayPeer) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	if peer.TunnelType == api.VPN_TUNNEL_TYPE_WIREGUARD {
+		gw, err := VpnGatewayManager.getById(peer.VpnGatewayId)
+		if err != nil {
+			return errors.Wrapf(err, "get vpn gateway %s", peer.VpnGatewayId)
+		}
+		ifacePeer := &SIfacePeer{
+			RouterId: gw.RouterId,
+			IfaceId:  gw.IfaceId,
+
+			PublicKey:           peer.PublicKey,
+			AllowedIPs:          peer.RemoteCidrs,
+			Endpoint:            peer.Endpoint,
+			PersistentKeepalive: peer.PersistentKeepalive,
+		}
+		ifacePeer.Name = peer.Name
+		ifacePeer.SetModelManager(IfacePeerManager, ifacePeer)
+		if err := IfacePeerManager.TableSpec().Insert(ctx, ifacePeer); err != nil {
+			return errors.Wrap(err, "insert iface peer")
+		}
+		peer.IfacePeerId = ifacePeer.Id
+	} else if peer.TunnelType == api.VPN_TUNNEL_TYPE_IPSEC {
+		router, err := RouterManager.getById(peer.RouterId)
+		if err != nil {
+			return errors.Wrapf(err, "get router %s", peer.RouterId)
+		}
+		if err := RuleManager.addIpsecRules(ctx, userCred, router); err != nil {
+			return errors.Wrap(err, "add ipsec rules")
+		}
+	}
+	return peer.SStatusStandaloneResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data)
+}
+
+func (peer *SVpnGatewayPeer) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	peer.syncVpcRoutes(ctx, userCred)
+}
+
+func (peer *SVpnGatewayPeer) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.VpnGatewayPeerUpdateInput) (api.VpnGatewayPeerUpdateInput, error) {
+	var err error
+	input.StatusStandaloneResourceBaseUpdateInput, err = peer.SStatusStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.StatusStandaloneResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SStatusStandaloneResourceBase.ValidateUpdateData")
+	}
+	gw, err := VpnGatewayManager.getById(peer.VpnGatewayId)
+	if err != nil {
+		return input, httperrors.NewInternalServerError("get vpn gateway %s: %v", peer.VpnGatewayId, err)
+	}
+	data := jsonutils.Marshal(input).(*jsonutils.JSONDict)
+	if err := VpnGatewayPeerManager.validateData(ctx, data, gw, peer.TunnelType, peer); err != nil {
+		return input, err
+	}
+	if err := data.Unmarshal(&input); err != nil {
+		return input, httperrors.NewInternalServerError("unmarshal VpnGatewayPeerUpdateInput: %v", err)
+	}
+	return input, nil
+}
+
+func (peer *SVpnGatewayPeer) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	if peer.TunnelType == api.VPN_TUNNEL_TYPE_WIREGUARD {
+		if err := peer.updateIfacePeer(ctx); err != nil {
+			log.Errorf("update iface peer of vpn gateway peer %s: %v", peer.Name, err)
+		}
+	}
+	peer.syncVpcRoutes(ctx, userCred)
+}
+
+func (peer *SVpnGatewayPeer) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	if ifacePeer, err := peer.getIfacePeer(); err != nil {
+		if err != sql.ErrNoRows {
+			return err
+		}
+	} else if err := ifacePeer.Delete(ctx, userCred); err != nil {
+		return errors.Wrap(err, "delete iface peer")
+	}
+	return peer.SStatusStandaloneResourceBase.CustomizeDelete(ctx, userCred, query, data)
+}
+
+func (peer *SVpnGatewayPeer) PostDelete(ctx context.Context, userCred mcclient.TokenCredential) {
+	peer.syncVpcRoutes(ctx, userCred)
+}
+
+func (peer *SVpnGatewayPeer) syncVpcRoutes(ctx context.Context, userCred mcclient.TokenCredential) {
+	gw, err := VpnGatewayManager.getById(peer.VpnGatewayId)
+	if err != nil {
+		log.Errorf("get vpn gateway %s: %v", peer.VpnGatewayId, err)
+		return
+	}
+	if err := gw.syncVpcRoutes(ctx, userCred); err != nil {
+		log.Errorf("vpn gateway %s: propagate vpc routes: %v", gw.Name, err)
+	}
+}
+
+func (peer *SVpnGatewayPeer) getIfacePeer() (*SIfacePeer, error) {
+	if peer.IfacePeerId == "" {
+		return nil, sql.ErrNoRows
+	}
+	obj, err := db.FetchById(IfacePeerManager, peer.IfacePeerId)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*SIfacePeer), nil
+}
+
+func (peer *SVpnGatewayPeer) updateIfacePeer(ctx context.Context) error {
+	ifacePeer, err := peer.getIfacePeer()
+	if err != nil {
+		return err
+	}
+	_, err = db.Update(ifacePeer, func() error {
+		ifacePeer.AllowedIPs = peer.RemoteCidrs
+		ifacePeer.Endpoint = peer.Endpoint
+		ifacePeer.PersistentKeepalive = peer.PersistentKeepalive
+		return nil
+	})
+	return err
+}
+
+func (peer *SVpnGatewayPeer) remoteCidrsStrList() []string {
+	return strings.Split(peer.RemoteCidrs, ",")
+}
+
+func (peer *SVpnGatewayPeer) remoteSubnets() Subnets {
+	nets, err := newSubnetsFromStrList(peer.remoteCidrsStrList())
+	if err != nil {
+		log.Errorf("%s: invalid subnet sneaked in: %v", peer.Id, err)
+		return nil
+	}
+	return nets
+}
+
+func (peer *SVpnGatewayPeer) ipsecConnName() string {
+	return peer.Id
+}
+
+func (peer *SVpnGatewayPeer) handshakeAge(now time.Time) int64 {
+	if peer.LastHandshake.IsZero() {
+		return -1
+	}
+	return int64(now.Sub(peer.LastHandshake) / time.Second)
+}
+
+func vpnPeerStatus(lastHandshake, now time.Time) string {
+	if lastHandshake.IsZero() {
+		return api.VPN_PEER_STATUS_DOWN
+	}
+	if now.Sub(lastHandshake) > api.VPN_PEER_HANDSHAKE_TIMEOUT_SECONDS*time.Second {
+		return api.VPN_PEER_STATUS_DOWN
+	}
+	return api.VPN_PEER_STATUS_UP
+}
+
+func (peer *SVpnGatewayPeer) updateWireguardStatus(ctx context.Context, userCred mcclient.TokenCredential, wgPeer *cnutils.WgDumpPeer, now time.Time) error {
+	_, err := db.Update(peer, func() error {
+		if wgPeer != nil {
+			if !wgPeer.LatestHandshake.IsZero() {
+				peer.LastHandshake = wgPeer.LatestHandshake
+			}
+			peer.RxBytes = wgPeer.RxBytes
+			peer.TxBytes = wgPeer.TxBytes
+		}
+		peer.Status = vpnPeerStatus(peer.LastHandshake, now)
+		return nil
+	})
+	return err
+}
+
+// updateIpsecStatus records the time ike sa was last seen established as
+// handshake time
+func (peer *SVpnGatewayPeer) updateIpsecStatus(ctx context.Context, userCred mcclient.TokenCredential, established bool, now time.Time) error {
+	_, err := db.Update(peer, func() error {
+		if established {
+			peer.LastHandshake = now
+			peer.Status = api.VPN_PEER_STATUS_UP
+		} else {
+			peer.Status = api.VPN_PEER_STATUS_DOWN
+		}
+		return nil
+	})
+	return err
+}
+
+func (man *SVpnGatewayPeerManager) getByFilter(filter map[string]string) ([]SVpnGatewayPeer, error) {
+	peers := []SVpnGatewayPeer{}
+	q := man.Query()
+	for key, val := range filter {
+		q = q.Equals(key, val)
+	}
+	if err := db.FetchModelObjects(VpnGatewayPeerManager, q, &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+func (man *SVpnGatewayPeerManager) getByVpnGateway(gw *SVpnGateway) ([]SVpnGatewayPeer, error) {
+	return man.getByFilter(map[string]string{
+		"vpn_gateway_id": gw.Id,
+	})
+}
+
+func (man *SVpnGatewayPeerManager) getByRouterTunnelType(router *SRouter, tunnelType string) ([]SVpnGatewayPeer, error) {
+	return man.getByFilter(map[string]string{
+		"router_id":   router.Id,
+		"tunnel_type": tunnelType,
+	})
+}
+
+// removeByVpnGateway removes peers of the gateway.  Iface peers are left to
+// be removed with the gateway iface
+func (man *SVpnGatewayPeerManager) removeByVpnGateway(ctx context.Context, userCred mcclient.TokenCredential, gw *SVpnGateway) error {
+	peers, err := man.getByVpnGateway(gw)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for j := range peers {
+		if err := peers[j].Delete(ctx, userCred); err != nil {
+			errs = append(errs, fmt.Errorf("delete peer %s: %v", peers[j].Name, err))
+		}
+	}
+	return errors.NewAggregate(errs)
+}
@@ -0,0 +1,344 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/gotypes"
+	"yunion.io/x/pkg/util/netutils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	cnutils "yunion.io/x/onecloud/pkg/cloudnet/utils"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+)
+
+// SVpnGateway terminates site-to-site tunnels of an on-premise vpc on a
+// router.  The router must have an address in the vpc, which will be the
+// next hop of remote site cidrs propagated into vpc route tables
+type SVpnGateway struct {
+	db.SStandaloneResourceBase
+
+	RouterId string `length:"32" nullable:"false" list:"user" create:"required"`
+	VpcId    string `length:"36" nullable:"false" list:"user" create:"required"`
+	VpcIp    string `length:"16" nullable:"false" list:"user" create:"required"`
+
+	LocalCidrs string `nullable:"false" list:"user" update:"user" create:"optional"`
+
+	IfaceId    string `length:"32" nullable:"false" list:"user"`
+	Ifname     string `length:"32" nullable:"false" list:"user"`
+	PublicKey  string `length:"64" nullable:"false" list:"user"`
+	ListenPort int    `nullable:"false" list:"user"`
+}
+
+type SVpnGatewayManager struct {
+	db.SStandaloneResourceBaseManager
+}
+
+var VpnGatewayManager *SVpnGatewayManager
+
+func init() {
+	VpnGatewayManager = &SVpnGatewayManager{
+		SStandaloneResourceBaseManager: db.NewStandaloneResourceBaseManager(
+			SVpnGateway{},
+			"vpngateways_tbl",
+			"vpngateway",
+			"vpngateways",
+		),
+	}
+	VpnGatewayManager.SetVirtualObject(VpnGatewayManager)
+}
+
+func newCidrsValidator(key string) *validators.ValidatorByActor {
+	return validators.NewValidatorByActor(key,
+		validators.NewActorJoinedBy(",", validators.NewActorIPv4Prefix()))
+}
+
+func cidrsValidatorValue(v *validators.ValidatorByActor) Subnets {
+	if v.Value == nil {
+		return nil
+	}
+	nets := gotypes.ConvertSliceElemType(v.Value, (**netutils.IPV4Prefix)(nil)).([]*netutils.IPV4Prefix)
+	return Subnets(nets)
+}
+
+func (man *SVpnGatewayManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data *jsonutils.JSONDict) (*jsonutils.JSONDict, error) {
+	input := apis.StandaloneResourceCreateInput{}
+	err := data.Unmarshal(&input)
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("unmarshal StandaloneResourceCreateInput fail %s", err)
+	}
+	input, err = man.SStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input)
+	if err != nil {
+		return nil, err
+	}
+	data.Update(jsonutils.Marshal(input))
+
+	routerV := validators.NewModelIdOrNameValidator("router", "router", ownerId)
+	vpcV := validators.NewStringNonEmptyValidator("vpc")
+	vpcIpV := validators.NewIPv4AddrValidator("vpc_ip")
+	vs := []validators.IValidator{
+		routerV,
+		vpcV,
+		vpcIpV,
+	}
+	for _, v := range vs {
+		if err := v.Validate(ctx, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var cidrBlock string
+	{
+		s := auth.GetSession(ctx, userCred, "")
+		vpc, err := compute.Vpcs.Get(s, vpcV.Value, nil)
+		if err != nil {
+			return nil, httperrors.NewInputParameterError("get vpc %s: %v", vpcV.Value, err)
+		}
+		if providerId, _ := vpc.GetString("cloudprovider_id"); providerId != "" {
+			return nil, httperrors.NewInputParameterError("vpn gateway only supports on-premise vpc")
+		}
+		vpcId, _ := vpc.GetString("id")
+		data.Set("vpc_id", jsonutils.NewString(vpcId))
+		cidrBlock, _ = vpc.GetString("cidr_block")
+	}
+
+	localCidrsV := newCidrsValidator("local_cidrs")
+	if cidrBlock != "" {
+		localCidrsV.Default(cidrBlock)
+	}
+	if err := localCidrsV.Validate(ctx, data); err != nil {
+		return nil, err
+	}
+	if len(cidrsValidatorValue(localCidrsV)) == 0 {
+		return nil, httperrors.NewInputParameterError("local_cidrs must not be empty")
+	}
+
+	router := routerV.Model.(*SRouter)
+	data.Set("router_id", jsonutils.NewString(router.Id))
+	if !data.Contains("name") {
+		data.Set("name", jsonutils.NewString(router.Name+"-vpn"))
+	}
+	return data, nil
+}
+
+// 站点VPN网关列表
+func (man *SVpnGatewayManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*sqlchemy.SQuery, error) {
+	input := apis.StandaloneResourceListInput{}
+	err := query.Unmarshal(&input)
+	if err != nil {
+		return nil, errors.Wrap(err, "query.Unmarshal")
+	}
+	q, err = man.SStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, input)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStandaloneResourceBaseManager.ListItemFilter")
+	}
+	data := query.(*jsonutils.JSONDict)
+	q, err = validators.ApplyModelFilters(ctx, q, data, []*validators.ModelFilterOptions{
+		{Key: "router", ModelKeyword: "router", OwnerId: userCred},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if vpcId, _ := data.GetString("vpc_id"); vpcId != "" {
+		q = q.Equals("vpc_id", vpcId)
+	}
+	return q, nil
+}
+
+func (gw *SVpnGateway) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	router, err := RouterManager.getById(gw.RouterId)
+	if err != nil {
+		return errors.Wrapf(err, "get router %s", gw.RouterId)
+	}
+	iface, err := IfaceManager.addWireguardIface(ctx, userCred, router, nil)
+	if err != nil {
+		return errors.Wrap(err, "add wireguard iface")
+	}
+	gw.IfaceId = iface.Id
+	gw.Ifname = iface.Ifname
+	gw.PublicKey = iface.PublicKey
+	gw.ListenPort = iface.ListenPort
+	return gw.SStandaloneResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data)
+}
+
+func (gw *SVpnGateway) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.VpnGatewayUpdateInput) (api.VpnGatewayUpdateInput, error) {
+	var err error
+	input.StandaloneResourceBaseUpdateInput, err = gw.SStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.StandaloneResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SStandaloneResourceBase.ValidateUpdateData")
+	}
+	if input.LocalCidrs != "" {
+		data := jsonutils.Marshal(input).(*jsonutils.JSONDict)
+		localCidrsV := newCidrsValidator("local_cidrs")
+		if err := localCidrsV.Validate(ctx, data); err != nil {
+			return input, err
+		}
+		input.LocalCidrs, _ = data.GetString("local_cidrs")
+	}
+	return input, nil
+}
+
+func (gw *SVpnGateway) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	var errs []error
+	if err := VpnGatewayPeerManager.removeByVpnGateway(ctx, userCred, gw); err != nil {
+		errs = append(errs, err)
+	}
+	if err := gw.propagateVpcRoutes(ctx, userCred, nil); err != nil {
+		errs = append(errs, errors.Wrap(err, "withdraw vpc routes"))
+	}
+	if iface, err := gw.getIface(); err != nil {
+		if err != sql.ErrNoRows {
+			errs = append(errs, err)
+		}
+	} else if err := iface.remove(ctx, userCred); err != nil {
+		errs = append(errs, err)
+	}
+	if err := gw.SStandaloneResourceBase.CustomizeDelete(ctx, userCred, query, data); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (gw *SVpnGateway) getIface() (*SIface, error) {
+	obj, err := db.FetchById(IfaceManager, gw.IfaceId)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*SIface), nil
+}
+
+func (gw *SVpnGateway) getRouter() (*SRouter, error) {
+	return RouterManager.getById(gw.RouterId)
+}
+
+func (gw *SVpnGateway) localCidrsStrList() []string {
+	return strings.Split(gw.LocalCidrs, ",")
+}
+
+func (gw *SVpnGateway) PerformRealize(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	router, err := gw.getRouter()
+	if err != nil {
+		return nil, httperrors.NewBadRequestError("get router: %v", err)
+	}
+	if err := router.realize(ctx, userCred); err != nil {
+		return nil, httperrors.NewBadRequestError("realize router %s: %v", router.Name, err)
+	}
+	if err := gw.syncVpcRoutes(ctx, userCred); err != nil {
+		return nil, httperrors.NewBadRequestError("propagate vpc routes: %v", err)
+	}
+	return nil, nil
+}
+
+// PerformReportStatus updates tunnel status of peers with reports from the
+// router
+func (gw *SVpnGateway) PerformReportStatus(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.VpnGatewayReportStatusInput) (jsonutils.JSONObject, error) {
+	wgPeers, err := cnutils.ParseWgDump(input.WgDump)
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("parse wg_dump: %v", err)
+	}
+	wgPeersByKey := map[string]*cnutils.WgDumpPeer{}
+	for i := range wgPeers {
+		wgPeer := &wgPeers[i]
+		if wgPeer.Ifname != "" && wgPeer.Ifname != gw.Ifname {
+			continue
+		}
+		wgPeersByKey[wgPeer.PublicKey] = wgPeer
+	}
+	ipsecEstablished := map[string]struct{}{}
+	for _, name := range input.IpsecEstablished {
+		ipsecEstablished[name] = struct{}{}
+	}
+
+	peers, err := VpnGatewayPeerManager.getByVpnGateway(gw)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var errs []error
+	for i := range peers {
+		peer := &peers[i]
+		var err error
+		switch peer.TunnelType {
+		case api.VPN_TUNNEL_TYPE_WIREGUARD:
+			err = peer.updateWireguardStatus(ctx, userCred, wgPeersByKey[peer.PublicKey], now)
+		case api.VPN_TUNNEL_TYPE_IPSEC:
+			_, ok := ipsecEstablished[peer.ipsecConnName()]
+			err = peer.updateIpsecStatus(ctx, userCred, ok, now)
+		}
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "update status of peer %s", peer.Name))
+		}
+	}
+	return nil, errors.NewAggregate(errs)
+}
+
+func (man *SVpnGatewayManager) getByFilter(filter map[string]string) ([]SVpnGateway, error) {
+	gws := []SVpnGateway{}
+	q := man.Query()
+	for key, val := range filter {
+		q = q.Equals(key, val)
+	}
+	if err := db.FetchModelObjects(VpnGatewayManager, q, &gws); err != nil {
+		return nil, err
+	}
+	return gws, nil
+}
+
+func (man *SVpnGatewayManager) getById(id string) (*SVpnGateway, error) {
+	obj, err := db.FetchById(man, id)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*SVpnGateway), nil
+}
+
+func (man *SVpnGatewayManager) getByRouter(router *SRouter) ([]SVpnGateway, error) {
+	return man.getByFilter(map[string]string{
+		"router_id": router.Id,
+	})
+}
+
+func (man *SVpnGatewayManager) removeByRouter(ctx context.Context, userCred mcclient.TokenCredential, router *SRouter) error {
+	gws, err := man.getByRouter(router)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range gws {
+		gw := &gws[i]
+		if err := gw.CustomizeDelete(ctx, userCred, nil, nil); err != nil {
+			errs = append(errs, fmt.Errorf("remove vpn gateway %s: %v", gw.Name, err))
+			continue
+		}
+		if err := gw.Delete(ctx, userCred); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.NewAggregate(errs)
+}
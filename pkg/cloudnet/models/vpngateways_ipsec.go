@@ -0,0 +1,105 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+)
+
+const swanctlConfPath = "/etc/strongswan/swanctl/conf.d/cloudnet.conf"
+
+type swanctlConn struct {
+	Name       string
+	LocalId    string
+	RemoteAddr string
+	RemoteId   string
+	LocalTs    []string
+	RemoteTs   []string
+	Psk        string
+}
+
+// genSwanctlConf renders ikev2 psk connections in swanctl.conf format.
+// Psk is hex encoded to avoid quoting
+func genSwanctlConf(conns []swanctlConn) string {
+	b := &strings.Builder{}
+	b.WriteString("# Ansible managed\n")
+	b.WriteString("connections {\n")
+	for _, conn := range conns {
+		fmt.Fprintf(b, "\t%s {\n", conn.Name)
+		fmt.Fprintf(b, "\t\tversion = 2\n")
+		fmt.Fprintf(b, "\t\tlocal_addrs = %%any\n")
+		fmt.Fprintf(b, "\t\tremote_addrs = %s\n", conn.RemoteAddr)
+		fmt.Fprintf(b, "\t\tdpd_delay = 30s\n")
+		fmt.Fprintf(b, "\t\tlocal {\n\t\t\tauth = psk\n\t\t\tid = %s\n\t\t}\n", conn.LocalId)
+		fmt.Fprintf(b, "\t\tremote {\n\t\t\tauth = psk\n\t\t\tid = %s\n\t\t}\n", conn.RemoteId)
+		fmt.Fprintf(b, "\t\tchildren {\n")
+		fmt.Fprintf(b, "\t\t\t%s {\n", conn.Name)
+		fmt.Fprintf(b, "\t\t\t\tlocal_ts = %s\n", strings.Join(conn.LocalTs, ","))
+		fmt.Fprintf(b, "\t\t\t\tremote_ts = %s\n", strings.Join(conn.RemoteTs, ","))
+		fmt.Fprintf(b, "\t\t\t\tstart_action = start\n")
+		fmt.Fprintf(b, "\t\t\t\tdpd_action = restart\n")
+		fmt.Fprintf(b, "\t\t\t}\n")
+		fmt.Fprintf(b, "\t\t}\n")
+		fmt.Fprintf(b, "\t}\n")
+	}
+	b.WriteString("}\n")
+	b.WriteString("secrets {\n")
+	for _, conn := range conns {
+		fmt.Fprintf(b, "\tike-%s {\n", conn.Name)
+		fmt.Fprintf(b, "\t\tid-local = %s\n", conn.LocalId)
+		fmt.Fprintf(b, "\t\tid-remote = %s\n", conn.RemoteId)
+		fmt.Fprintf(b, "\t\tsecret = 0x%s\n", hex.EncodeToString([]byte(conn.Psk)))
+		fmt.Fprintf(b, "\t}\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// swanctlConns returns ipsec connections of all vpn gateways on the router
+func (router *SRouter) swanctlConns() ([]swanctlConn, error) {
+	peers, err := VpnGatewayPeerManager.getByRouterTunnelType(router, api.VPN_TUNNEL_TYPE_IPSEC)
+	if err != nil {
+		return nil, err
+	}
+	gws := map[string]*SVpnGateway{}
+	conns := make([]swanctlConn, 0, len(peers))
+	for i := range peers {
+		peer := &peers[i]
+		gw, ok := gws[peer.VpnGatewayId]
+		if !ok {
+			gw, err = VpnGatewayManager.getById(peer.VpnGatewayId)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "get vpn gateway %s", peer.VpnGatewayId)
+			}
+			gws[gw.Id] = gw
+		}
+		conns = append(conns, swanctlConn{
+			Name:       peer.ipsecConnName(),
+			LocalId:    router.endpointIP(),
+			RemoteAddr: peer.Endpoint,
+			RemoteId:   peer.RemoteId,
+			LocalTs:    gw.localCidrsStrList(),
+			RemoteTs:   peer.remoteCidrsStrList(),
+			Psk:        peer.Psk,
+		})
+	}
+	return conns, nil
+}
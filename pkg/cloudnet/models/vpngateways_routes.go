@@ -0,0 +1,135 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"sort"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+)
+
+// remoteCidrs returns sorted remote cidrs of all peers of the gateway
+func (gw *SVpnGateway) remoteCidrs() ([]string, error) {
+	peers, err := VpnGatewayPeerManager.getByVpnGateway(gw)
+	if err != nil {
+		return nil, err
+	}
+	r := []string{}
+	for i := range peers {
+		r = append(r, peers[i].remoteSubnets().StrList()...)
+	}
+	sort.Strings(r)
+	return r, nil
+}
+
+func (gw *SVpnGateway) syncVpcRoutes(ctx context.Context, userCred mcclient.TokenCredential) error {
+	cidrs, err := gw.remoteCidrs()
+	if err != nil {
+		return errors.Wrap(err, "get remote cidrs")
+	}
+	return gw.propagateVpcRoutes(ctx, userCred, cidrs)
+}
+
+// propagateVpcRoutes makes route tables of the vpc contain exactly the
+// specified cidrs as routes propagated by the gateway
+func (gw *SVpnGateway) propagateVpcRoutes(ctx context.Context, userCred mcclient.TokenCredential, cidrs []string) error {
+	s := auth.GetSession(ctx, userCred, "")
+	params := jsonutils.NewDict()
+	params.Set("vpc_id", jsonutils.NewString(gw.VpcId))
+	params.Set("limit", jsonutils.NewInt(0))
+	result, err := compute.RouteTables.List(s, params)
+	if err != nil {
+		return errors.Wrap(err, "list route tables")
+	}
+	if len(result.Data) == 0 {
+		log.Warningf("vpn gateway %s: vpc %s has no route table, remote cidrs not propagated", gw.Name, gw.VpcId)
+		return nil
+	}
+	var errs []error
+	for _, rtObj := range result.Data {
+		rtId, _ := rtObj.GetString("id")
+		routes := computeapi.SRoutes{}
+		if rtObj.Contains("routes") {
+			if err := rtObj.Unmarshal(&routes, "routes"); err != nil {
+				errs = append(errs, errors.Wrapf(err, "unmarshal routes of route table %s", rtId))
+				continue
+			}
+		}
+		adds, dels := vpnGatewayRoutesDiff(routes, gw.VpcIp, cidrs)
+		if len(dels) > 0 {
+			params := jsonutils.NewDict()
+			params.Set("cidrs", jsonutils.NewStringArray(dels))
+			if _, err := compute.RouteTables.PerformAction(s, rtId, "del-routes", params); err != nil {
+				errs = append(errs, errors.Wrapf(err, "del routes of route table %s", rtId))
+			}
+		}
+		if len(adds) > 0 {
+			params := jsonutils.NewDict()
+			params.Set("routes", jsonutils.Marshal(adds))
+			if _, err := compute.RouteTables.PerformAction(s, rtId, "add-routes", params); err != nil {
+				errs = append(errs, errors.Wrapf(err, "add routes to route table %s", rtId))
+			}
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+// vpnGatewayRoutesDiff compares existing routes with wanted cidrs of
+// gateway at nextHop.  Routes propagated by the gateway are identified by
+// type and next hop.  Cidrs already routed elsewhere are left as they are
+func vpnGatewayRoutesDiff(routes computeapi.SRoutes, nextHop string, cidrs []string) (computeapi.SRoutes, []string) {
+	wanted := map[string]struct{}{}
+	for _, cidr := range cidrs {
+		wanted[cidr] = struct{}{}
+	}
+	existing := map[string]struct{}{}
+	dels := []string{}
+	for _, route := range routes {
+		existing[route.Cidr] = struct{}{}
+		if route.Type != computeapi.ROUTE_ENTRY_TYPE_PROPAGATE ||
+			route.NextHopType != computeapi.NEXT_HOP_TYPE_IP ||
+			route.NextHopId != nextHop {
+			if _, ok := wanted[route.Cidr]; ok {
+				log.Warningf("route to %s already exists with next hop %s %s, skip propagating",
+					route.Cidr, route.NextHopType, route.NextHopId)
+			}
+			continue
+		}
+		if _, ok := wanted[route.Cidr]; !ok {
+			dels = append(dels, route.Cidr)
+		}
+	}
+	adds := computeapi.SRoutes{}
+	for _, cidr := range cidrs {
+		if _, ok := existing[cidr]; ok {
+			continue
+		}
+		adds = append(adds, &computeapi.SRoute{
+			Type:        computeapi.ROUTE_ENTRY_TYPE_PROPAGATE,
+			Cidr:        cidr,
+			NextHopType: computeapi.NEXT_HOP_TYPE_IP,
+			NextHopId:   nextHop,
+		})
+	}
+	return adds, dels
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/cloudnet"
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestVpnGatewayRoutesDiff(t *testing.T) {
+	const nextHop = "192.168.0.2"
+	propagated := func(cidr, nextHop string) *computeapi.SRoute {
+		return &computeapi.SRoute{
+			Type:        computeapi.ROUTE_ENTRY_TYPE_PROPAGATE,
+			Cidr:        cidr,
+			NextHopType: computeapi.NEXT_HOP_TYPE_IP,
+			NextHopId:   nextHop,
+		}
+	}
+	routes := computeapi.SRoutes{
+		// stale route of the gateway
+		propagated("10.1.0.0/16", nextHop),
+		// still wanted
+		propagated("10.2.0.0/16", nextHop),
+		// propagated by another gateway
+		propagated("10.3.0.0/16", "192.168.0.3"),
+		// custom route conflicting with wanted cidr
+		{
+			Type:        "custom",
+			Cidr:        "10.4.0.0/16",
+			NextHopType: computeapi.NEXT_HOP_TYPE_IP,
+			NextHopId:   "192.168.0.4",
+		},
+	}
+	cidrs := []string{"10.2.0.0/16", "10.4.0.0/16", "10.5.0.0/16"}
+	adds, dels := vpnGatewayRoutesDiff(routes, nextHop, cidrs)
+	wantAdds := computeapi.SRoutes{propagated("10.5.0.0/16", nextHop)}
+	wantDels := []string{"10.1.0.0/16"}
+	if !reflect.DeepEqual(adds, wantAdds) {
+		t.Errorf("adds: got %s, want %s", adds, wantAdds)
+	}
+	if !reflect.DeepEqual(dels, wantDels) {
+		t.Errorf("dels: got %v, want %v", dels, wantDels)
+	}
+
+	adds, dels = vpnGatewayRoutesDiff(routes, nextHop, nil)
+	if len(adds) != 0 {
+		t.Errorf("withdraw: unexpected adds %s", adds)
+	}
+	if want := []string{"10.1.0.0/16", "10.2.0.0/16"}; !reflect.DeepEqual(dels, want) {
+		t.Errorf("withdraw: got dels %v, want %v", dels, want)
+	}
+}
+
+func TestGenSwanctlConf(t *testing.T) {
+	conf := genSwanctlConf([]swanctlConn{
+		{
+			Name:       "peer0",
+			LocalId:    "1.1.1.1",
+			RemoteAddr: "2.2.2.2",
+			RemoteId:   "site-a",
+			LocalTs:    []string{"192.168.0.0/24"},
+			RemoteTs:   []string{"10.1.0.0/16", "10.2.0.0/16"},
+			Psk:        `pass"word`,
+		},
+	})
+	for _, want := range []string{
+		"\tpeer0 {\n\t\tversion = 2\n",
+		"remote_addrs = 2.2.2.2\n",
+		"\t\tlocal {\n\t\t\tauth = psk\n\t\t\tid = 1.1.1.1\n\t\t}\n",
+		"\t\tremote {\n\t\t\tauth = psk\n\t\t\tid = site-a\n\t\t}\n",
+		"local_ts = 192.168.0.0/24\n",
+		"remote_ts = 10.1.0.0/16,10.2.0.0/16\n",
+		"\tike-peer0 {\n\t\tid-local = 1.1.1.1\n\t\tid-remote = site-a\n",
+		"secret = 0x7061737322776f7264\n",
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("missing %q in\n%s", want, conf)
+		}
+	}
+	if strings.Count(conf, "{") != strings.Count(conf, "}") {
+		t.Errorf("unbalanced braces\n%s", conf)
+	}
+}
+
+func TestValidateIpsecRemoteId(t *testing.T) {
+	cases := []struct {
+		id      string
+		wantErr bool
+	}{
+		{"203.0.113.1", false},
+		{"2001:db8::1", false},
+		{"vpn.example.com", false},
+		{"@branch-01.site", false},
+		{"vpn", true},
+		{"@", true},
+		{"@key id", true},
+		{"a.example.com }\n\tsecret = x", true},
+		{"vpn.example.com#", true},
+	}
+	for _, c := range cases {
+		err := validateIpsecRemoteId(c.id)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%q: want error %v, got %v", c.id, c.wantErr, err)
+		}
+	}
+}
+
+func TestVpnPeerStatus(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	cases := []struct {
+		lastHandshake time.Time
+		want          string
+	}{
+		{time.Time{}, api.VPN_PEER_STATUS_DOWN},
+		{now.Add(-10 * time.Second), api.VPN_PEER_STATUS_UP},
+		{now.Add(-api.VPN_PEER_HANDSHAKE_TIMEOUT_SECONDS * time.Second), api.VPN_PEER_STATUS_UP},
+		{now.Add(-10 * time.Minute), api.VPN_PEER_STATUS_DOWN},
+	}
+	for _, c := range cases {
+		if got := vpnPeerStatus(c.lastHandshake, now); got != c.want {
+			t.Errorf("last handshake %s: got %s, want %s", c.lastHandshake, got, c.want)
+		}
+	}
+}
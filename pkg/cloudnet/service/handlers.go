@@ -49,6 +49,8 @@ func InitHandlers(app *appsrv.Application) {
 		models.MeshNetworkManager,
 		models.RouteManager,
 		models.RuleManager,
+		models.VpnGatewayManager,
+		models.VpnGatewayPeerManager,
 	} {
 		db.RegisterModelManager(manager)
 		handler := db.NewModelHandler(manager)
@@ -33,8 +33,12 @@ func MustNewKeyString() string {
 	return key.String()
 }
 
+func ParseKeyString(k string) (wgtypes.Key, error) {
+	return wgtypes.ParseKey(k)
+}
+
 func MustParseKeyString(k string) wgtypes.Key {
-	key, err := wgtypes.ParseKey(k)
+	key, err := ParseKeyString(k)
 	if err != nil {
 		panic(fmt.Errorf("invalid key: %v", err))
 	}
@@ -0,0 +1,85 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type WgDumpPeer struct {
+	Ifname          string
+	PublicKey       string
+	Endpoint        string
+	AllowedIPs      string
+	LatestHandshake time.Time
+	RxBytes         int64
+	TxBytes         int64
+}
+
+// ParseWgDump parses output of "wg show <ifname> dump" or "wg show all
+// dump".  Interface lines are skipped.  Ifname of peers is only available in
+// the latter form
+func ParseWgDump(s string) ([]WgDumpPeer, error) {
+	r := []WgDumpPeer{}
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		var ifname string
+		switch len(fields) {
+		case 4, 5:
+			// interface line
+			continue
+		case 8:
+		case 9:
+			ifname = fields[0]
+			fields = fields[1:]
+		default:
+			return nil, fmt.Errorf("line %d: unexpected number of fields: %d", i+1, len(fields))
+		}
+		peer := WgDumpPeer{
+			Ifname:     ifname,
+			PublicKey:  fields[0],
+			Endpoint:   fields[2],
+			AllowedIPs: fields[3],
+		}
+		if peer.Endpoint == "(none)" {
+			peer.Endpoint = ""
+		}
+		if peer.AllowedIPs == "(none)" {
+			peer.AllowedIPs = ""
+		}
+		nums := make([]int64, 3)
+		for j := range nums {
+			n, err := strconv.ParseInt(fields[4+j], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: field %d: %v", i+1, 5+j, err)
+			}
+			nums[j] = n
+		}
+		if nums[0] > 0 {
+			peer.LatestHandshake = time.Unix(nums[0], 0)
+		}
+		peer.RxBytes = nums[1]
+		peer.TxBytes = nums[2]
+		r = append(r, peer)
+	}
+	return r, nil
+}
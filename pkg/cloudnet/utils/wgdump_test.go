@@ -0,0 +1,93 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWgDump(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		want    []WgDumpPeer
+		wantErr bool
+	}{
+		{
+			name: "single interface",
+			in: "WJYVsrTtAae1QS9YzefV4OmVM6mkJglR+GEgxQpTs2g=\tmOX0S5AuRqd8lQZWcqTlzOS+veo404gE7NyV4u3xVkg=\t20000\toff\n" +
+				"pubA=\t(none)\t192.168.1.2:51820\t10.1.0.0/16,10.2.0.0/16\t1600000000\t1024\t2048\t25\n" +
+				"pubB=\t(none)\t(none)\t(none)\t0\t0\t0\toff\n",
+			want: []WgDumpPeer{
+				{
+					PublicKey:       "pubA=",
+					Endpoint:        "192.168.1.2:51820",
+					AllowedIPs:      "10.1.0.0/16,10.2.0.0/16",
+					LatestHandshake: time.Unix(1600000000, 0),
+					RxBytes:         1024,
+					TxBytes:         2048,
+				},
+				{
+					PublicKey: "pubB=",
+				},
+			},
+		},
+		{
+			name: "all interfaces",
+			in: "wg0\tpriv=\tpub=\t20000\toff\n" +
+				"wg0\tpubA=\t(none)\t192.168.1.2:51820\t10.1.0.0/16\t1600000000\t1\t2\toff\n",
+			want: []WgDumpPeer{
+				{
+					Ifname:          "wg0",
+					PublicKey:       "pubA=",
+					Endpoint:        "192.168.1.2:51820",
+					AllowedIPs:      "10.1.0.0/16",
+					LatestHandshake: time.Unix(1600000000, 0),
+					RxBytes:         1,
+					TxBytes:         2,
+				},
+			},
+		},
+		{
+			name:    "bad fields",
+			in:      "pubA=\t(none)\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad number",
+			in:      "pubA=\t(none)\t(none)\t(none)\tx\t0\t0\toff\n",
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ParseWgDump(c.in)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expecting error, got %#v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got\n%#v\nwant\n%#v", got, c.want)
+			}
+		})
+	}
+}
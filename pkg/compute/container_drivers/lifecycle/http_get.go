@@ -0,0 +1,56 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+func init() {
+	models.RegisterContainerLifecyleDriver(newHTTPGet())
+}
+
+type httpGetDriver struct{}
+
+func newHTTPGet() models.IContainerLifecyleDriver {
+	return &httpGetDriver{}
+}
+
+func (h httpGetDriver) GetType() apis.ContainerLifecyleHandlerType {
+	return apis.ContainerLifecyleHandlerTypeHTTPGet
+}
+
+func (h httpGetDriver) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, input *apis.ContainerLifecyleHandler) error {
+	if input.HTTPGet == nil {
+		return httperrors.NewNotEmptyError("http_get field")
+	}
+	port := input.HTTPGet.Port
+	if port < 1 || port > 65535 {
+		return httperrors.NewInputParameterError("invalid http port: %d, must between [1,65535]", port)
+	}
+	switch input.HTTPGet.Scheme {
+	case "":
+		input.HTTPGet.Scheme = apis.URISchemeHTTP
+	case apis.URISchemeHTTP, apis.URISchemeHTTPS:
+	default:
+		return httperrors.NewInputParameterError("invalid http scheme %q", input.HTTPGet.Scheme)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package volume_mount
+
+import (
+	"context"
+
+	"yunion.io/x/pkg/util/sets"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+func init() {
+	models.RegisterContainerVolumeMountDriver(newObject(apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET))
+	models.RegisterContainerVolumeMountDriver(newObject(apis.CONTAINER_VOLUME_MOUNT_TYPE_CONFIG))
+}
+
+// object mounts the keys of container_secret or container_config
+type object struct {
+	objType apis.ContainerVolumeMountType
+}
+
+func newObject(objType apis.ContainerVolumeMountType) models.IContainerVolumeMountDriver {
+	return &object{objType: objType}
+}
+
+func (o object) GetType() apis.ContainerVolumeMountType {
+	return o.objType
+}
+
+func (o object) getObject(vm *apis.ContainerVolumeMount) *apis.ContainerVolumeMountObject {
+	if o.objType == apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET {
+		return vm.Secret
+	}
+	return vm.Config
+}
+
+func (o object) validateObject(mo *apis.ContainerVolumeMountObject) ([]string, error) {
+	if mo == nil {
+		return nil, httperrors.NewNotEmptyError("%s is nil", o.objType)
+	}
+	if mo.Id == "" {
+		return nil, httperrors.NewNotEmptyError("%s id", o.objType)
+	}
+	if !sets.NewString(
+		string(apis.CONTAINER_OBJECT_REFRESH_POLICY_REFRESH),
+		string(apis.CONTAINER_OBJECT_REFRESH_POLICY_RESTART),
+		string(apis.CONTAINER_OBJECT_REFRESH_POLICY_NONE)).Has(string(mo.GetRefreshPolicy())) {
+		return nil, httperrors.NewInputParameterError("invalid refresh_policy %s", mo.RefreshPolicy)
+	}
+	if mo.Key != "" {
+		if len(mo.Items) != 0 {
+			return nil, httperrors.NewInputParameterError("key and items can't be set at the same time")
+		}
+		return []string{mo.Key}, nil
+	}
+	keys := make([]string, 0, len(mo.Items))
+	paths := sets.NewString()
+	for _, item := range mo.Items {
+		if item.Key == "" {
+			return nil, httperrors.NewNotEmptyError("key of items")
+		}
+		path := item.Path
+		if path == "" {
+			path = item.Key
+		}
+		if err := models.ValidateContainerObjectFilePath(path); err != nil {
+			return nil, err
+		}
+		if paths.Has(path) {
+			return nil, httperrors.NewDuplicateNameError("path", path)
+		}
+		paths.Insert(path)
+		keys = append(keys, item.Key)
+	}
+	return keys, nil
+}
+
+func (o object) ValidatePodCreateData(ctx context.Context, userCred mcclient.TokenCredential, vm *apis.ContainerVolumeMount, input *api.ServerCreateInput) error {
+	mo := o.getObject(vm)
+	keys, err := o.validateObject(mo)
+	if err != nil {
+		return err
+	}
+	if _, err := models.GetContainerManager().ValidateContainerObjectKey(ctx, userCred, o.objType, mo.Id, keys...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o object) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, pod *models.SGuest, vm *apis.ContainerVolumeMount) (*apis.ContainerVolumeMount, error) {
+	mo := o.getObject(vm)
+	keys, err := o.validateObject(mo)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := models.GetContainerManager().ValidateContainerObjectKey(ctx, userCred, o.objType, mo.Id, keys...)
+	if err != nil {
+		return nil, err
+	}
+	mo.Id = obj.GetId()
+	return vm, nil
+}
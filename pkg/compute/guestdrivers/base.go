@@ -518,6 +518,14 @@ func (drv *SBaseGuestDriver) QgaRequestGetOsInfo(ctx context.Context, userCred m
 	return nil, httperrors.ErrNotImplemented
 }
 
+func (drv *SBaseGuestDriver) RequestGetSerialLog(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest, input *api.ServerGetSerialLogInput) (*api.ServerSerialLogOutput, error) {
+	return nil, httperrors.ErrNotImplemented
+}
+
+func (drv *SBaseGuestDriver) RequestScreenDump(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest) (*api.ServerScreenDumpOutput, error) {
+	return nil, httperrors.ErrNotImplemented
+}
+
 func (drv *SBaseGuestDriver) RequestQgaCommand(ctx context.Context, userCred mcclient.TokenCredential, body jsonutils.JSONObject, host *models.SHost, guest *models.SGuest) (jsonutils.JSONObject, error) {
 	return nil, httperrors.ErrNotImplemented
 }
@@ -1201,6 +1201,36 @@ func (self *SKVMGuestDriver) RequestQgaCommand(ctx context.Context, userCred mcc
 	return res, nil
 }
 
+func (self *SKVMGuestDriver) RequestGetSerialLog(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest, input *api.ServerGetSerialLogInput) (*api.ServerSerialLogOutput, error) {
+	url := fmt.Sprintf("%s/servers/%s/serial-log", host.ManagerUri, guest.Id)
+	httpClient := httputils.GetDefaultClient()
+	header := mcclient.GetTokenHeaders(userCred)
+	_, res, err := httputils.JSONRequest(httpClient, ctx, "POST", url, header, jsonutils.Marshal(input), false)
+	if err != nil {
+		return nil, errors.Wrap(err, "host request")
+	}
+	ret := new(api.ServerSerialLogOutput)
+	if err := res.Unmarshal(ret); err != nil {
+		return nil, errors.Wrap(err, "unmarshal serial log")
+	}
+	return ret, nil
+}
+
+func (self *SKVMGuestDriver) RequestScreenDump(ctx context.Context, userCred mcclient.TokenCredential, host *models.SHost, guest *models.SGuest) (*api.ServerScreenDumpOutput, error) {
+	url := fmt.Sprintf("%s/servers/%s/screendump", host.ManagerUri, guest.Id)
+	httpClient := httputils.GetDefaultClient()
+	header := mcclient.GetTokenHeaders(userCred)
+	_, res, err := httputils.JSONRequest(httpClient, ctx, "POST", url, header, nil, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "host request")
+	}
+	ret := new(api.ServerScreenDumpOutput)
+	if err := res.Unmarshal(ret); err != nil {
+		return nil, errors.Wrap(err, "unmarshal screendump")
+	}
+	return ret, nil
+}
+
 func (self *SKVMGuestDriver) FetchMonitorUrl(ctx context.Context, guest *models.SGuest) string {
 	if options.Options.KvmMonitorAgentUseMetadataService && !guest.IsSriov() {
 		return apis.MetaServiceMonitorAgentUrl
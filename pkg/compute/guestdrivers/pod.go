@@ -89,23 +89,19 @@ func (p *SPodDriver) ValidateCreateData(ctx context.Context, userCred mcclient.T
 
 	ctrNames := sets.NewString()
 	volUniqNames := sets.NewString()
-	for idx, ctr := range input.Pod.Containers {
-		if err := p.validateContainerData(ctx, userCred, idx, input.Name, ctr, input); err != nil {
-			return nil, errors.Wrapf(err, "data of %d container", idx)
+	for idx, ctr := range input.Pod.InitContainers {
+		ctr.Init = true
+		ctr.InitOrder = idx
+		if err := p.validatePodContainer(ctx, userCred, idx, input.Name+"-init", ctr, input, ctrNames, volUniqNames); err != nil {
+			return nil, errors.Wrapf(err, "data of %d init container", idx)
 		}
-		if ctrNames.Has(ctr.Name) {
-			return nil, httperrors.NewDuplicateNameError("same name %s of containers", ctr.Name)
+	}
+	for idx, ctr := range input.Pod.Containers {
+		if ctr.Init {
+			return nil, httperrors.NewInputParameterError("container %d is an init container, it should be specified in init_containers", idx)
 		}
-		ctrNames.Insert(ctr.Name)
-		for volIdx := range ctr.VolumeMounts {
-			vol := ctr.VolumeMounts[volIdx]
-			if vol.UniqueName != "" {
-				if volUniqNames.Has(vol.UniqueName) {
-					return nil, httperrors.NewDuplicateNameError("same volume unique name %s", fmt.Sprintf("container %s volume_mount %d %s", ctr.Name, volIdx, vol.UniqueName))
-				} else {
-					volUniqNames.Insert(vol.UniqueName)
-				}
-			}
+		if err := p.validatePodContainer(ctx, userCred, idx, input.Name, ctr, input, ctrNames, volUniqNames); err != nil {
+			return nil, errors.Wrapf(err, "data of %d container", idx)
 		}
 	}
 
@@ -138,6 +134,27 @@ func (p *SPodDriver) validateHostPortMapping(hostId string, pm *api.PodPortMappi
 	return nil
 }
 
+func (p *SPodDriver) validatePodContainer(ctx context.Context, userCred mcclient.TokenCredential, idx int, defaultNamePrefix string, ctr *api.PodContainerCreateInput, input *api.ServerCreateInput, ctrNames sets.String, volUniqNames sets.String) error {
+	if err := p.validateContainerData(ctx, userCred, idx, defaultNamePrefix, ctr, input); err != nil {
+		return err
+	}
+	if ctrNames.Has(ctr.Name) {
+		return httperrors.NewDuplicateNameError("same name %s of containers", ctr.Name)
+	}
+	ctrNames.Insert(ctr.Name)
+	for volIdx := range ctr.VolumeMounts {
+		vol := ctr.VolumeMounts[volIdx]
+		if vol.UniqueName != "" {
+			if volUniqNames.Has(vol.UniqueName) {
+				return httperrors.NewDuplicateNameError("same volume unique name %s", fmt.Sprintf("container %s volume_mount %d %s", ctr.Name, volIdx, vol.UniqueName))
+			} else {
+				volUniqNames.Insert(vol.UniqueName)
+			}
+		}
+	}
+	return nil
+}
+
 func (p *SPodDriver) validateContainerData(ctx context.Context, userCred mcclient.TokenCredential, idx int, defaultNamePrefix string, ctr *api.PodContainerCreateInput, input *api.ServerCreateInput) error {
 	if ctr.Name == "" {
 		ctr.Name = fmt.Sprintf("%s-%d", defaultNamePrefix, idx)
@@ -386,8 +403,9 @@ func (p *SPodDriver) createContainersOnPod(ctx context.Context, userCred mcclien
 	if err != nil {
 		return errors.Wrap(err, "GetCreateParams")
 	}
-	ctrs := make([]*models.SContainer, len(input.Pod.Containers))
-	for idx, ctr := range input.Pod.Containers {
+	allCtrs := append(append([]*api.PodContainerCreateInput{}, input.Pod.InitContainers...), input.Pod.Containers...)
+	ctrs := make([]*models.SContainer, len(allCtrs))
+	for idx, ctr := range allCtrs {
 		if obj, err := models.GetContainerManager().CreateOnPod(ctx, userCred, guest.GetOwnerId(), guest, ctr); err != nil {
 			return errors.Wrapf(err, "create container on pod: %s", guest.GetName())
 		} else {
@@ -464,6 +482,19 @@ func (p *SPodDriver) RequestStartContainer(ctx context.Context, userCred mcclien
 	return p.performContainerAction(ctx, userCred, task, "start", jsonutils.Marshal(input))
 }
 
+// RequestWaitInitContainerExited asks host to callback the task after the init container exits
+func (p *SPodDriver) RequestWaitInitContainerExited(ctx context.Context, userCred mcclient.TokenCredential, ctr *models.SContainer, task taskman.ITask) error {
+	pod := ctr.GetPod()
+	host, err := pod.GetHost()
+	if err != nil {
+		return errors.Wrap(err, "GetHost")
+	}
+	url := fmt.Sprintf("%s/pods/%s/containers/%s/wait-init-exited", host.ManagerUri, pod.GetId(), ctr.GetId())
+	header := p.getTaskRequestHeader(task)
+	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, header, nil, false)
+	return err
+}
+
 func (p *SPodDriver) RequestStopContainer(ctx context.Context, userCred mcclient.TokenCredential, task models.IContainerTask) error {
 	ctr := task.GetContainer()
 	params := task.GetParams()
@@ -538,6 +569,10 @@ func (p *SPodDriver) RequestSetContainerResourcesLimit(ctx context.Context, user
 	return p.requestContainerSyncAction(ctx, userCred, container, "set-resources-limit", jsonutils.Marshal(limit))
 }
 
+func (p *SPodDriver) RequestRefreshContainerVolumeMounts(ctx context.Context, userCred mcclient.TokenCredential, container *models.SContainer, input *hostapi.ContainerRefreshVolumeMountsInput) (jsonutils.JSONObject, error) {
+	return p.requestContainerSyncAction(ctx, userCred, container, "refresh-volume-mounts", jsonutils.Marshal(input))
+}
+
 func (p *SPodDriver) OnDeleteGuestFinalCleanup(ctx context.Context, guest *models.SGuest, userCred mcclient.TokenCredential) error {
 	// clean disk records in DB
 	return guest.DeleteAllDisksInDB(ctx, userCred)
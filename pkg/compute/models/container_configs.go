@@ -0,0 +1,209 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+var containerConfigManager *SContainerConfigManager
+
+func GetContainerConfigManager() *SContainerConfigManager {
+	if containerConfigManager == nil {
+		containerConfigManager = &SContainerConfigManager{
+			SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+				SContainerConfig{},
+				"container_configs_tbl",
+				"container_config",
+				"container_configs"),
+		}
+		containerConfigManager.SetVirtualObject(containerConfigManager)
+	}
+	return containerConfigManager
+}
+
+func init() {
+	GetContainerConfigManager()
+}
+
+type SContainerConfigManager struct {
+	db.SVirtualResourceBaseManager
+}
+
+// SContainerConfig stores non-sensitive configurations which can be referenced by containers
+type SContainerConfig struct {
+	db.SVirtualResourceBase
+
+	// Keys of the config
+	Keys []string `length:"long" charset:"utf8" nullable:"true" list:"user"`
+	// Data stores the values of the keys
+	Data *jsonutils.JSONDict `length:"long" charset:"utf8" nullable:"true" get:"user"`
+}
+
+func (m *SContainerConfigManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.ContainerConfigCreateInput) (*api.ContainerConfigCreateInput, error) {
+	var err error
+	input.VirtualResourceCreateInput, err = m.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.VirtualResourceCreateInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ValidateCreateData")
+	}
+	if err := validateContainerObjectData(input.Data); err != nil {
+		return nil, err
+	}
+	input.Status = api.CONTAINER_CONFIG_STATUS_READY
+	return input, nil
+}
+
+func (c *SContainerConfig) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	if err := c.SVirtualResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data); err != nil {
+		return errors.Wrap(err, "SVirtualResourceBase.CustomizeCreate")
+	}
+	input := new(api.ContainerConfigCreateInput)
+	if err := data.Unmarshal(input); err != nil {
+		return errors.Wrap(err, "unmarshal to ContainerConfigCreateInput")
+	}
+	c.setData(input.Data)
+	return nil
+}
+
+func (c *SContainerConfig) setData(data map[string]string) {
+	c.Data = jsonutils.Marshal(data).(*jsonutils.JSONDict)
+	c.Keys = getContainerObjectKeys(data)
+}
+
+func (c *SContainerConfig) GetData(ctx context.Context, userCred mcclient.TokenCredential) (map[string]string, error) {
+	ret := make(map[string]string)
+	if c.Data == nil {
+		return ret, nil
+	}
+	if err := c.Data.Unmarshal(&ret); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal data of config %s", c.Name)
+	}
+	return ret, nil
+}
+
+func (c *SContainerConfig) GetKeys() []string {
+	return c.Keys
+}
+
+func (c *SContainerConfig) GetObjectType() apis.ContainerVolumeMountType {
+	return apis.CONTAINER_VOLUME_MOUNT_TYPE_CONFIG
+}
+
+func (c *SContainerConfig) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ContainerConfigUpdateInput) (*api.ContainerConfigUpdateInput, error) {
+	var err error
+	input.VirtualResourceBaseUpdateInput, err = c.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBase.ValidateUpdateData")
+	}
+	if input.Data != nil {
+		if err := validateContainerObjectData(input.Data); err != nil {
+			return nil, err
+		}
+	}
+	return input, nil
+}
+
+func (c *SContainerConfig) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	c.SVirtualResourceBase.PostUpdate(ctx, userCred, query, data)
+	input := new(api.ContainerConfigUpdateInput)
+	if err := data.Unmarshal(input); err != nil || input.Data == nil {
+		return
+	}
+	if _, err := db.Update(c, func() error {
+		c.setData(input.Data)
+		return nil
+	}); err != nil {
+		log.Errorf("update data of container config %s: %v", c.Name, err)
+		return
+	}
+	if err := StartContainerObjectPropagateTask(ctx, userCred, c, ""); err != nil {
+		log.Errorf("start propagate task of container config %s: %v", c.Name, err)
+	}
+}
+
+func (c *SContainerConfig) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	ctrs, err := GetContainerManager().GetContainersByObject(c)
+	if err != nil {
+		return errors.Wrap(err, "GetContainersByObject")
+	}
+	if len(ctrs) > 0 {
+		return httperrors.NewNotEmptyError("config is used by %d containers", len(ctrs))
+	}
+	return c.SVirtualResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (m *SContainerConfigManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query api.ContainerConfigListInput) (*sqlchemy.SQuery, error) {
+	q, err := m.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ListItemFilter")
+	}
+	return q, nil
+}
+
+func (m *SContainerConfigManager) OrderByExtraFields(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query api.ContainerConfigListInput) (*sqlchemy.SQuery, error) {
+	q, err := m.SVirtualResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (m *SContainerConfigManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := m.SVirtualResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (m *SContainerConfigManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ContainerConfigDetails {
+	rows := make([]api.ContainerConfigDetails, len(objs))
+	virtRows := m.SVirtualResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	cObjs := make([]IContainerObject, len(objs))
+	for i := range objs {
+		cObjs[i] = objs[i].(*SContainerConfig)
+	}
+	ctrs, err := GetContainerManager().GetContainersByObjects(cObjs)
+	if err != nil {
+		log.Errorf("get containers by configs: %v", err)
+	}
+	for i := range rows {
+		rows[i] = api.ContainerConfigDetails{
+			VirtualResourceDetails: virtRows[i],
+		}
+		config := objs[i].(*SContainerConfig)
+		rows[i].ContainerCount = len(ctrs[config.Id])
+	}
+	return rows
+}
@@ -0,0 +1,333 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/sets"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// IContainerObject is implemented by container_secret and container_config,
+// whose keys can be referenced by containers as environments or files
+type IContainerObject interface {
+	db.IStandaloneModel
+
+	GetObjectType() apis.ContainerVolumeMountType
+	GetKeys() []string
+	GetData(ctx context.Context, userCred mcclient.TokenCredential) (map[string]string, error)
+}
+
+func getContainerObjectManager(objType apis.ContainerVolumeMountType) (db.IStandaloneModelManager, error) {
+	switch objType {
+	case apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET:
+		return GetContainerSecretManager(), nil
+	case apis.CONTAINER_VOLUME_MOUNT_TYPE_CONFIG:
+		return GetContainerConfigManager(), nil
+	}
+	return nil, errors.Wrapf(errors.ErrNotSupported, "container object type %s", objType)
+}
+
+// FetchContainerObject fetches container_secret or container_config by id or name within the scope of userCred
+func (m *SContainerManager) FetchContainerObject(ctx context.Context, userCred mcclient.TokenCredential, objType apis.ContainerVolumeMountType, idOrName string) (IContainerObject, error) {
+	if idOrName == "" {
+		return nil, httperrors.NewNotEmptyError("%s id", objType)
+	}
+	man, err := getContainerObjectManager(objType)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := man.FetchByIdOrName(ctx, userCred, idOrName)
+	if err != nil {
+		if errors.Cause(err) == errors.ErrNotFound {
+			return nil, httperrors.NewResourceNotFoundError2(man.Keyword(), idOrName)
+		}
+		return nil, errors.Wrapf(err, "fetch %s %s", man.Keyword(), idOrName)
+	}
+	return obj.(IContainerObject), nil
+}
+
+func fetchContainerObjectById(objType apis.ContainerVolumeMountType, id string) (IContainerObject, error) {
+	man, err := getContainerObjectManager(objType)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := man.FetchById(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch %s %s", man.Keyword(), id)
+	}
+	return obj.(IContainerObject), nil
+}
+
+// ValidateContainerObjectKey fetches the object and checks the key exists in it
+func (m *SContainerManager) ValidateContainerObjectKey(ctx context.Context, userCred mcclient.TokenCredential, objType apis.ContainerVolumeMountType, idOrName string, keys ...string) (IContainerObject, error) {
+	obj, err := m.FetchContainerObject(ctx, userCred, objType, idOrName)
+	if err != nil {
+		return nil, err
+	}
+	objKeys := sets.NewString(obj.GetKeys()...)
+	for _, key := range keys {
+		if !objKeys.Has(key) {
+			return nil, httperrors.NewInputParameterError("key %q not found in %s %s", key, objType, obj.GetName())
+		}
+	}
+	return obj, nil
+}
+
+func (m *SContainerManager) ValidateSpecEnvs(ctx context.Context, userCred mcclient.TokenCredential, spec *api.ContainerSpec) error {
+	for _, env := range spec.Envs {
+		if env.ValueFrom == nil {
+			continue
+		}
+		objType, ref := env.ValueFrom.GetObjectRef()
+		if ref == nil || (env.ValueFrom.Secret != nil && env.ValueFrom.Config != nil) {
+			return httperrors.NewInputParameterError("env %s: only one of secret and config should be set in value_from", env.Key)
+		}
+		if ref.Key == "" {
+			return httperrors.NewNotEmptyError("env %s: key of value_from", env.Key)
+		}
+		obj, err := m.ValidateContainerObjectKey(ctx, userCred, objType, ref.Id, ref.Key)
+		if err != nil {
+			return errors.Wrapf(err, "env %s", env.Key)
+		}
+		ref.Id = obj.GetId()
+		env.Value = ""
+	}
+	return nil
+}
+
+// GetContainersByObject returns the containers which reference the object by environments or volume mounts
+func (m *SContainerManager) GetContainersByObject(obj IContainerObject) ([]SContainer, error) {
+	ret, err := m.GetContainersByObjects([]IContainerObject{obj})
+	if err != nil {
+		return nil, err
+	}
+	return ret[obj.GetId()], nil
+}
+
+// GetContainersByObjects fetches the containers referencing any of the objects in one query, keyed by object id
+func (m *SContainerManager) GetContainersByObjects(objs []IContainerObject) (map[string][]SContainer, error) {
+	ret := make(map[string][]SContainer, len(objs))
+	if len(objs) == 0 {
+		return ret, nil
+	}
+	q := m.Query()
+	conds := make([]sqlchemy.ICondition, len(objs))
+	for i := range objs {
+		conds[i] = sqlchemy.Contains(q.Field("spec"), objs[i].GetId())
+	}
+	q = q.Filter(sqlchemy.OR(conds...))
+	ctrs := make([]SContainer, 0)
+	if err := db.FetchModelObjects(m, q, &ctrs); err != nil {
+		return nil, errors.Wrap(err, "db.FetchModelObjects")
+	}
+	for i := range ctrs {
+		for _, obj := range objs {
+			if _, ok := ctrs[i].GetObjectRefreshPolicy(obj); ok {
+				ret[obj.GetId()] = append(ret[obj.GetId()], ctrs[i])
+			}
+		}
+	}
+	return ret, nil
+}
+
+func getVolumeMountObject(vm *apis.ContainerVolumeMount) *apis.ContainerVolumeMountObject {
+	switch vm.Type {
+	case apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET:
+		return vm.Secret
+	case apis.CONTAINER_VOLUME_MOUNT_TYPE_CONFIG:
+		return vm.Config
+	}
+	return nil
+}
+
+// GetObjectRefreshPolicy returns how the container reacts to the change of the object,
+// environments can only be changed by restarting the container.
+func (c *SContainer) GetObjectRefreshPolicy(obj IContainerObject) (apis.ContainerObjectRefreshPolicy, bool) {
+	if c.Spec == nil {
+		return "", false
+	}
+	policies := sets.NewString()
+	for _, env := range c.Spec.Envs {
+		if env.ValueFrom == nil {
+			continue
+		}
+		objType, ref := env.ValueFrom.GetObjectRef()
+		if ref != nil && objType == obj.GetObjectType() && ref.Id == obj.GetId() {
+			policies.Insert(string(apis.CONTAINER_OBJECT_REFRESH_POLICY_RESTART))
+		}
+	}
+	for _, vm := range c.Spec.VolumeMounts {
+		if vm.Type != obj.GetObjectType() {
+			continue
+		}
+		mo := getVolumeMountObject(vm)
+		if mo != nil && mo.Id == obj.GetId() {
+			policies.Insert(string(mo.GetRefreshPolicy()))
+		}
+	}
+	for _, p := range []apis.ContainerObjectRefreshPolicy{
+		apis.CONTAINER_OBJECT_REFRESH_POLICY_RESTART,
+		apis.CONTAINER_OBJECT_REFRESH_POLICY_REFRESH,
+		apis.CONTAINER_OBJECT_REFRESH_POLICY_NONE,
+	} {
+		if policies.Has(string(p)) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// GetObjectVolumeMounts returns the host volume mounts of the object in the container
+func (c *SContainer) GetObjectVolumeMounts(ctx context.Context, userCred mcclient.TokenCredential, obj IContainerObject) ([]*hostapi.ContainerVolumeMount, error) {
+	relations, err := c.GetVolumeMountRelations()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetVolumeMountRelations")
+	}
+	ret := make([]*hostapi.ContainerVolumeMount, 0)
+	for _, r := range relations {
+		if r.VolumeMount.Type != obj.GetObjectType() {
+			continue
+		}
+		mo := getVolumeMountObject(r.VolumeMount)
+		if mo == nil || mo.Id != obj.GetId() {
+			continue
+		}
+		mount, err := r.ToHostMount(ctx, userCred)
+		if err != nil {
+			return nil, errors.Wrapf(err, "ToHostMount %s", r.VolumeMount.MountPath)
+		}
+		ret = append(ret, mount)
+	}
+	return ret, nil
+}
+
+// resolveEnvs returns a copy of the environments whose value_from are replaced by the real values
+func (c *SContainer) resolveEnvs(ctx context.Context, userCred mcclient.TokenCredential) ([]*apis.ContainerKeyValue, error) {
+	if len(c.Spec.Envs) == 0 {
+		return c.Spec.Envs, nil
+	}
+	dataCache := make(map[string]map[string]string)
+	envs := make([]*apis.ContainerKeyValue, len(c.Spec.Envs))
+	for i, env := range c.Spec.Envs {
+		if env.ValueFrom == nil {
+			envs[i] = env
+			continue
+		}
+		objType, ref := env.ValueFrom.GetObjectRef()
+		if ref == nil {
+			return nil, errors.Errorf("invalid value_from of env %s", env.Key)
+		}
+		data, ok := dataCache[ref.Id]
+		if !ok {
+			obj, err := fetchContainerObjectById(objType, ref.Id)
+			if err != nil {
+				return nil, errors.Wrapf(err, "env %s", env.Key)
+			}
+			data, err = obj.GetData(ctx, userCred)
+			if err != nil {
+				return nil, errors.Wrapf(err, "get data of %s %s", objType, obj.GetName())
+			}
+			dataCache[ref.Id] = data
+		}
+		val, ok := data[ref.Key]
+		if !ok {
+			return nil, errors.Wrapf(errors.ErrNotFound, "key %s of %s %s for env %s", ref.Key, objType, ref.Id, env.Key)
+		}
+		envs[i] = &apis.ContainerKeyValue{Key: env.Key, Value: val, ValueFrom: env.ValueFrom}
+	}
+	return envs, nil
+}
+
+func (vm *ContainerVolumeMountRelation) toHostObjectMount(ctx context.Context, userCred mcclient.TokenCredential, mo *apis.ContainerVolumeMountObject) (*hostapi.ContainerVolumeMountObject, error) {
+	obj, err := fetchContainerObjectById(vm.VolumeMount.Type, mo.Id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := obj.GetData(ctx, userCred)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get data of %s %s", vm.VolumeMount.Type, obj.GetName())
+	}
+	getValue := func(key string) (string, error) {
+		val, ok := data[key]
+		if !ok {
+			return "", errors.Wrapf(errors.ErrNotFound, "key %s of %s %s", key, vm.VolumeMount.Type, obj.GetName())
+		}
+		return val, nil
+	}
+	ret := &hostapi.ContainerVolumeMountObject{
+		Id:    obj.GetId(),
+		Files: make([]*hostapi.ContainerVolumeMountFile, 0),
+	}
+	if mo.Key != "" {
+		val, err := getValue(mo.Key)
+		if err != nil {
+			return nil, err
+		}
+		ret.IsFile = true
+		ret.Files = append(ret.Files, &hostapi.ContainerVolumeMountFile{Content: val})
+		return ret, nil
+	}
+	items := mo.Items
+	if len(items) == 0 {
+		for _, key := range obj.GetKeys() {
+			items = append(items, apis.ContainerKeyToPath{Key: key})
+		}
+	}
+	for _, item := range items {
+		val, err := getValue(item.Key)
+		if err != nil {
+			return nil, err
+		}
+		path := item.Path
+		if path == "" {
+			path = item.Key
+		}
+		ret.Files = append(ret.Files, &hostapi.ContainerVolumeMountFile{Path: path, Content: val})
+	}
+	return ret, nil
+}
+
+// ValidateContainerObjectFilePath checks the path is relative and inside the mounted directory
+func ValidateContainerObjectFilePath(path string) error {
+	if filepath.IsAbs(path) {
+		return httperrors.NewInputParameterError("path %q must be relative", path)
+	}
+	cleaned := filepath.Clean(path)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return httperrors.NewInputParameterError("path %q is out of the mounted directory", path)
+	}
+	return nil
+}
+
+func StartContainerObjectPropagateTask(ctx context.Context, userCred mcclient.TokenCredential, obj IContainerObject, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "ContainerObjectPropagateTask", obj, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
@@ -0,0 +1,267 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"sort"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+var containerSecretManager *SContainerSecretManager
+
+func GetContainerSecretManager() *SContainerSecretManager {
+	if containerSecretManager == nil {
+		containerSecretManager = &SContainerSecretManager{
+			SVirtualResourceBaseManager: db.NewVirtualResourceBaseManager(
+				SContainerSecret{},
+				"container_secrets_tbl",
+				"container_secret",
+				"container_secrets"),
+		}
+		containerSecretManager.SetVirtualObject(containerSecretManager)
+	}
+	return containerSecretManager
+}
+
+func init() {
+	GetContainerSecretManager()
+}
+
+type SContainerSecretManager struct {
+	db.SVirtualResourceBaseManager
+	db.SEncryptedResourceManager
+}
+
+// SContainerSecret stores sensitive values which can be referenced by containers,
+// the values are always encrypted by the encrypt key of the resource
+type SContainerSecret struct {
+	db.SVirtualResourceBase
+	db.SEncryptedResource
+
+	// Keys of the secret
+	Keys []string `length:"long" charset:"utf8" nullable:"true" list:"user"`
+	// EncryptedData is the base64 encoded cipher text of the json values
+	EncryptedData string `length:"long" charset:"ascii" nullable:"true"`
+}
+
+func validateContainerObjectData(data map[string]string) error {
+	if len(data) == 0 {
+		return httperrors.NewNotEmptyError("data")
+	}
+	for key := range data {
+		if key == "" {
+			return httperrors.NewInputParameterError("empty key of data")
+		}
+	}
+	return nil
+}
+
+func getContainerObjectKeys(data map[string]string) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *SContainerSecretManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input *api.ContainerSecretCreateInput) (*api.ContainerSecretCreateInput, error) {
+	var err error
+	input.VirtualResourceCreateInput, err = m.SVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.VirtualResourceCreateInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ValidateCreateData")
+	}
+	input.EncryptedResourceCreateInput, err = m.SEncryptedResourceManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EncryptedResourceCreateInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEncryptedResourceManager.ValidateCreateData")
+	}
+	if input.EncryptKeyId == nil || len(*input.EncryptKeyId) == 0 {
+		// secret must be encrypted, create a new key if not specified
+		keyNew := true
+		input.EncryptKeyNew = &keyNew
+		if input.EncryptKeyUserId == nil {
+			userId := userCred.GetUserId()
+			input.EncryptKeyUserId = &userId
+		}
+	}
+	if err := validateContainerObjectData(input.Data); err != nil {
+		return nil, err
+	}
+	input.Status = api.CONTAINER_SECRET_STATUS_READY
+	return input, nil
+}
+
+func (s *SContainerSecret) CustomizeCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	if err := s.SVirtualResourceBase.CustomizeCreate(ctx, userCred, ownerId, query, data); err != nil {
+		return errors.Wrap(err, "SVirtualResourceBase.CustomizeCreate")
+	}
+	if err := s.SEncryptedResource.CustomizeCreate(ctx, userCred, ownerId, data, "container-secret-"+s.Name); err != nil {
+		return errors.Wrap(err, "SEncryptedResource.CustomizeCreate")
+	}
+	input := new(api.ContainerSecretCreateInput)
+	if err := data.Unmarshal(input); err != nil {
+		return errors.Wrap(err, "unmarshal to ContainerSecretCreateInput")
+	}
+	return s.setData(ctx, userCred, input.Data)
+}
+
+func (s *SContainerSecret) setData(ctx context.Context, userCred mcclient.TokenCredential, data map[string]string) error {
+	if !s.IsEncrypted() {
+		return errors.Errorf("encrypt key of secret %s is empty", s.Name)
+	}
+	cipherText, err := s.EncryptData(ctx, userCred, []byte(jsonutils.Marshal(data).String()))
+	if err != nil {
+		return errors.Wrap(err, "EncryptData")
+	}
+	s.EncryptedData = cipherText
+	s.Keys = getContainerObjectKeys(data)
+	return nil
+}
+
+// GetData returns the decrypted values of the secret
+func (s *SContainerSecret) GetData(ctx context.Context, userCred mcclient.TokenCredential) (map[string]string, error) {
+	ret := make(map[string]string)
+	if s.EncryptedData == "" {
+		return ret, nil
+	}
+	plainText, err := s.DecryptData(ctx, userCred, s.EncryptedData)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decrypt data of secret %s", s.Name)
+	}
+	obj, err := jsonutils.Parse(plainText)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse decrypted data")
+	}
+	if err := obj.Unmarshal(&ret); err != nil {
+		return nil, errors.Wrap(err, "unmarshal decrypted data")
+	}
+	return ret, nil
+}
+
+func (s *SContainerSecret) GetKeys() []string {
+	return s.Keys
+}
+
+func (s *SContainerSecret) GetObjectType() apis.ContainerVolumeMountType {
+	return apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET
+}
+
+func (s *SContainerSecret) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ContainerSecretUpdateInput) (*api.ContainerSecretUpdateInput, error) {
+	var err error
+	input.VirtualResourceBaseUpdateInput, err = s.SVirtualResourceBase.ValidateUpdateData(ctx, userCred, query, input.VirtualResourceBaseUpdateInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBase.ValidateUpdateData")
+	}
+	if input.Data != nil {
+		if err := validateContainerObjectData(input.Data); err != nil {
+			return nil, err
+		}
+	}
+	return input, nil
+}
+
+func (s *SContainerSecret) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	s.SVirtualResourceBase.PostUpdate(ctx, userCred, query, data)
+	input := new(api.ContainerSecretUpdateInput)
+	if err := data.Unmarshal(input); err != nil || input.Data == nil {
+		return
+	}
+	if _, err := db.Update(s, func() error {
+		return s.setData(ctx, userCred, input.Data)
+	}); err != nil {
+		log.Errorf("update data of container secret %s: %v", s.Name, err)
+		return
+	}
+	if err := StartContainerObjectPropagateTask(ctx, userCred, s, ""); err != nil {
+		log.Errorf("start propagate task of container secret %s: %v", s.Name, err)
+	}
+}
+
+func (s *SContainerSecret) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	ctrs, err := GetContainerManager().GetContainersByObject(s)
+	if err != nil {
+		return errors.Wrap(err, "GetContainersByObject")
+	}
+	if len(ctrs) > 0 {
+		return httperrors.NewNotEmptyError("secret is used by %d containers", len(ctrs))
+	}
+	return s.SVirtualResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (m *SContainerSecretManager) ListItemFilter(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query api.ContainerSecretListInput) (*sqlchemy.SQuery, error) {
+	q, err := m.SVirtualResourceBaseManager.ListItemFilter(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.ListItemFilter")
+	}
+	return q, nil
+}
+
+func (m *SContainerSecretManager) OrderByExtraFields(ctx context.Context, q *sqlchemy.SQuery, userCred mcclient.TokenCredential, query api.ContainerSecretListInput) (*sqlchemy.SQuery, error) {
+	q, err := m.SVirtualResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VirtualResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVirtualResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (m *SContainerSecretManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := m.SVirtualResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (m *SContainerSecretManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ContainerSecretDetails {
+	rows := make([]api.ContainerSecretDetails, len(objs))
+	virtRows := m.SVirtualResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	encRows := m.SEncryptedResourceManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	cObjs := make([]IContainerObject, len(objs))
+	for i := range objs {
+		cObjs[i] = objs[i].(*SContainerSecret)
+	}
+	ctrs, err := GetContainerManager().GetContainersByObjects(cObjs)
+	if err != nil {
+		log.Errorf("get containers by secrets: %v", err)
+	}
+	for i := range rows {
+		rows[i] = api.ContainerSecretDetails{
+			VirtualResourceDetails:   virtRows[i],
+			EncryptedResourceDetails: encRows[i],
+		}
+		secret := objs[i].(*SContainerSecret)
+		rows[i].ContainerCount = len(ctrs[secret.Id])
+	}
+	return rows
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -148,6 +149,24 @@ func (m *SContainerManager) GetContainersByPod(guestId string) ([]SContainer, er
 	return ctrs, nil
 }
 
+// SplitInitContainers separates the init containers from the application containers,
+// init containers are sorted by their starting order.
+func SplitInitContainers(ctrs []SContainer) ([]SContainer, []SContainer) {
+	initCtrs := make([]SContainer, 0)
+	appCtrs := make([]SContainer, 0)
+	for i := range ctrs {
+		if ctrs[i].Spec != nil && ctrs[i].Spec.Init {
+			initCtrs = append(initCtrs, ctrs[i])
+		} else {
+			appCtrs = append(appCtrs, ctrs[i])
+		}
+	}
+	sort.SliceStable(initCtrs, func(i, j int) bool {
+		return initCtrs[i].Spec.InitOrder < initCtrs[j].Spec.InitOrder
+	})
+	return initCtrs, appCtrs
+}
+
 func (m *SContainerManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, _ jsonutils.JSONObject, input *api.ContainerCreateInput) (*api.ContainerCreateInput, error) {
 	if input.GuestId == "" {
 		return nil, httperrors.NewNotEmptyError("guest_id is required")
@@ -177,6 +196,10 @@ func (m *SContainerManager) ValidateSpec(ctx context.Context, userCred mcclient.
 		}
 	}
 
+	if err := m.ValidateSpecEnvs(ctx, userCred, spec); err != nil {
+		return errors.Wrap(err, "ValidateSpecEnvs")
+	}
+
 	if pod != nil {
 		if err := m.ValidateSpecRootFs(ctx, userCred, pod, spec, ctr); err != nil {
 			return errors.Wrap(err, "ValidateSpecRootFs")
@@ -209,16 +232,37 @@ func (m *SContainerManager) ValidateSpec(ctx context.Context, userCred mcclient.
 }
 
 func (m *SContainerManager) ValidateSpecLifecycle(ctx context.Context, cred mcclient.TokenCredential, spec *api.ContainerSpec) error {
+	if spec.Init {
+		if spec.Lifecyle != nil && (spec.Lifecyle.PostStart != nil || spec.Lifecyle.PreStop != nil) {
+			return httperrors.NewInputParameterError("init container doesn't support lifecycle handlers")
+		}
+		if spec.StartupProbe != nil {
+			return httperrors.NewInputParameterError("init container doesn't support startup probe")
+		}
+		if spec.AlwaysRestart || spec.Primary {
+			return httperrors.NewInputParameterError("init container can't be always_restart or primary")
+		}
+	}
 	if spec.Lifecyle == nil {
 		return nil
 	}
-	if err := m.ValidateSpecLifecyclePostStart(ctx, cred, spec.Lifecyle.PostStart); err != nil {
-		return errors.Wrap(err, "validate post start")
+	if spec.Lifecyle.PostStart != nil {
+		if err := m.ValidateSpecLifecycleHandler(ctx, cred, spec.Lifecyle.PostStart); err != nil {
+			return errors.Wrap(err, "validate post start")
+		}
+	}
+	if spec.Lifecyle.PreStop != nil {
+		if err := m.ValidateSpecLifecycleHandler(ctx, cred, spec.Lifecyle.PreStop); err != nil {
+			return errors.Wrap(err, "validate pre stop")
+		}
+	}
+	if spec.Lifecyle.TerminationGracePeriodSeconds < 0 {
+		return httperrors.NewInputParameterError("termination_grace_period_seconds is negative")
 	}
 	return nil
 }
 
-func (m *SContainerManager) ValidateSpecLifecyclePostStart(ctx context.Context, userCred mcclient.TokenCredential, input *apis.ContainerLifecyleHandler) error {
+func (m *SContainerManager) ValidateSpecLifecycleHandler(ctx context.Context, userCred mcclient.TokenCredential, input *apis.ContainerLifecyleHandler) error {
 	drv, err := GetContainerLifecyleDriverWithError(input.Type)
 	if err != nil {
 		return httperrors.NewInputParameterError("get lifecycle driver: %v", err)
@@ -523,9 +567,13 @@ func (vm *ContainerVolumeMountRelation) toHostDiskMount(disk *apis.ContainerVolu
 }
 
 func (vm *ContainerVolumeMountRelation) toCephFSMount(fs *apis.ContainerVolumeMountCephFS) (*hostapi.ContainerVolumeMountCephFS, error) {
-	fsObj, err := FileSystemManager.FetchById(fs.Id)
+	return getCephFSHostMount(fs.Id)
+}
+
+func getCephFSHostMount(fsId string) (*hostapi.ContainerVolumeMountCephFS, error) {
+	fsObj, err := FileSystemManager.FetchById(fsId)
 	if err != nil {
-		return nil, errors.Errorf("fetch cephfs by id %s", fs.Id)
+		return nil, errors.Errorf("fetch cephfs by id %s", fsId)
 	}
 
 	filesystem := fsObj.(*SFileSystem)
@@ -579,6 +627,20 @@ func (vm *ContainerVolumeMountRelation) ToHostMount(ctx context.Context, userCre
 		}
 		ret.CephFS = fs
 	}
+	if vm.VolumeMount.Secret != nil {
+		obj, err := vm.toHostObjectMount(ctx, userCred, vm.VolumeMount.Secret)
+		if err != nil {
+			return nil, errors.Wrap(err, "toHostObjectMount")
+		}
+		ret.Secret = obj
+	}
+	if vm.VolumeMount.Config != nil {
+		obj, err := vm.toHostObjectMount(ctx, userCred, vm.VolumeMount.Config)
+		if err != nil {
+			return nil, errors.Wrap(err, "toHostObjectMount")
+		}
+		ret.Config = obj
+	}
 	return ret, nil
 }
 
@@ -647,6 +709,14 @@ func (c *SContainer) StartStopTask(ctx context.Context, userCred mcclient.TokenC
 	return task.ScheduleRun(nil)
 }
 
+func (c *SContainer) StartRestartTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "ContainerRestartTask", c, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
 func (c *SContainer) PerformSyncstatus(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	return nil, c.StartSyncStatusTask(ctx, userCred, "")
 }
@@ -802,6 +872,10 @@ func (c *SContainer) ToHostContainerSpec(ctx context.Context, userCred mcclient.
 	}
 
 	spec := c.Spec.ContainerSpec
+	spec.Envs, err = c.resolveEnvs(ctx, userCred)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolveEnvs")
+	}
 	hSpec := &hostapi.ContainerSpec{
 		ContainerSpec: spec,
 		Rootfs:        rootFs,
@@ -824,7 +898,7 @@ func (c *SContainer) GetJsonDescAtHost(ctx context.Context, userCred mcclient.To
 	return &hostapi.ContainerDesc{
 		Id:             c.GetId(),
 		Name:           c.GetName(),
-		Spec:           spec,
+		Spec:           spec.WithoutSecretData(),
 		StartedAt:      c.StartedAt,
 		LastFinishedAt: c.LastFinishedAt,
 		RestartCount:   c.RestartCount,
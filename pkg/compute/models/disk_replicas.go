@@ -0,0 +1,703 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// +onecloud:swagger-gen-model-singular=disk_replica
+// +onecloud:swagger-gen-model-plural=disk_replicas
+type SDiskReplicaManager struct {
+	db.SStatusStandaloneResourceBaseManager
+}
+
+var DiskReplicaManager *SDiskReplicaManager
+
+func init() {
+	DiskReplicaManager = &SDiskReplicaManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SDiskReplica{},
+			"disk_replicas_tbl",
+			"disk_replica",
+			"disk_replicas",
+		),
+	}
+	DiskReplicaManager.SetVirtualObject(DiskReplicaManager)
+}
+
+// SDiskReplica keeps a copy of a local disk on a peer host, the primary host
+// mirrors guest writes into a nbd export of the replica host, and the guest
+// can be restarted on the replica host when the primary host is down
+type SDiskReplica struct {
+	db.SStatusStandaloneResourceBase
+
+	// 复制的本地盘
+	DiskId string `width:"36" charset:"ascii" nullable:"false" create:"required" list:"admin" index:"true"`
+	// 所属虚拟机
+	GuestId string `width:"36" charset:"ascii" nullable:"false" create:"optional" list:"admin" index:"true"`
+	// 主盘所在宿主机
+	HostId string `width:"36" charset:"ascii" nullable:"false" create:"optional" list:"admin" index:"true"`
+	// 副本所在宿主机
+	ReplicaHostId string `width:"36" charset:"ascii" nullable:"false" create:"required" list:"admin" index:"true"`
+	// 副本所在存储
+	ReplicaStorageId string `width:"36" charset:"ascii" nullable:"false" create:"optional" list:"admin"`
+	// 复制模式, sync或async
+	Mode string `width:"8" charset:"ascii" nullable:"false" default:"async" create:"optional" list:"admin"`
+	// 允许的最大延迟, 单位MB
+	MaxLagMb int `nullable:"false" default:"0" create:"optional" list:"admin" update:"admin"`
+	// 副本导出的nbd地址
+	NbdUri string `width:"128" charset:"ascii" nullable:"true" list:"admin"`
+	// 副本落后主盘的数据量
+	LagBytes int64 `nullable:"false" default:"0" list:"admin"`
+	// 上次与主盘一致的时间
+	SyncedAt time.Time `nullable:"true" list:"admin"`
+}
+
+func (manager *SDiskReplicaManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.DiskReplicaCreateInput) (api.DiskReplicaCreateInput, error) {
+	diskObj, err := validators.ValidateModel(ctx, userCred, DiskManager, &input.DiskId)
+	if err != nil {
+		return input, err
+	}
+	disk := diskObj.(*SDisk)
+	if disk.Status != api.DISK_READY {
+		return input, httperrors.NewInvalidStatusError("disk %s status is %s", disk.Name, disk.Status)
+	}
+	if disk.IsEncrypted() {
+		return input, httperrors.NewUnsupportOperationError("encrypted disk %s can not be replicated", disk.Name)
+	}
+	storage, err := disk.GetStorage()
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if !storage.IsLocal() {
+		return input, httperrors.NewUnsupportOperationError("only disks on local storage can be replicated, disk %s is on %s", disk.Name, storage.StorageType)
+	}
+	guest := disk.GetGuest()
+	if guest == nil {
+		return input, httperrors.NewBadRequestError("disk %s is not attached to any server", disk.Name)
+	}
+	if guest.Hypervisor != api.HYPERVISOR_KVM {
+		return input, httperrors.NewUnsupportOperationError("disk replica is not supported for hypervisor %s", guest.Hypervisor)
+	}
+	if len(guest.BackupHostId) > 0 {
+		return input, httperrors.NewBadRequestError("server %s already has a backup guest", guest.Name)
+	}
+	input.GuestId = guest.Id
+	input.HostId = guest.HostId
+
+	if cnt, err := manager.Query().Equals("disk_id", disk.Id).CountWithError(); err != nil {
+		return input, httperrors.NewGeneralError(err)
+	} else if cnt > 0 {
+		return input, httperrors.NewDuplicateResourceError("disk %s already has a replica", disk.Name)
+	}
+
+	hostObj, err := validators.ValidateModel(ctx, userCred, HostManager, &input.ReplicaHostId)
+	if err != nil {
+		return input, err
+	}
+	replicaHost := hostObj.(*SHost)
+	if replicaHost.Id == guest.HostId {
+		return input, httperrors.NewBadRequestError("replica host must be different from the host of server %s", guest.Name)
+	}
+	if replicaHost.HostType != api.HOST_TYPE_HYPERVISOR {
+		return input, httperrors.NewBadRequestError("host %s is not a kvm host", replicaHost.Name)
+	}
+	if replicaHost.HostStatus != api.HOST_ONLINE || !replicaHost.GetEnabled() {
+		return input, httperrors.NewInvalidStatusError("host %s is not online", replicaHost.Name)
+	}
+	// guest can only be restarted on a single host when all its disks are there
+	others, err := manager.GetReplicasByGuest(guest.Id)
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	for i := range others {
+		if others[i].ReplicaHostId != replicaHost.Id {
+			return input, httperrors.NewBadRequestError("disks of server %s must be replicated to the same host %s", guest.Name, others[i].ReplicaHostId)
+		}
+	}
+
+	if len(input.ReplicaStorageId) > 0 {
+		storageObj, err := validators.ValidateModel(ctx, userCred, StorageManager, &input.ReplicaStorageId)
+		if err != nil {
+			return input, err
+		}
+		replicaStorage := storageObj.(*SStorage)
+		if !replicaStorage.IsLocal() || replicaHost.GetHoststorageOfId(replicaStorage.Id) == nil {
+			return input, httperrors.NewBadRequestError("storage %s is not a local storage of host %s", replicaStorage.Name, replicaHost.Name)
+		}
+		if replicaStorage.GetFreeCapacity() < int64(disk.DiskSize) {
+			return input, httperrors.NewOutOfResourceError("storage %s has no enough capacity for disk %s", replicaStorage.Name, disk.Name)
+		}
+	} else {
+		replicaStorage := _getLeastUsedStorage(replicaHost.GetAttachedLocalStorages(), nil)
+		if replicaStorage == nil || replicaStorage.GetFreeCapacity() < int64(disk.DiskSize) {
+			return input, httperrors.NewOutOfResourceError("no local storage of host %s has enough capacity for disk %s", replicaHost.Name, disk.Name)
+		}
+		input.ReplicaStorageId = replicaStorage.Id
+	}
+
+	if len(input.Mode) == 0 {
+		input.Mode = api.DISK_REPLICA_MODE_ASYNC
+	}
+	if !utils.IsInStringArray(input.Mode, []string{api.DISK_REPLICA_MODE_SYNC, api.DISK_REPLICA_MODE_ASYNC}) {
+		return input, httperrors.NewInputParameterError("invalid mode %s", input.Mode)
+	}
+	if input.MaxLagMb < 0 {
+		return input, httperrors.NewInputParameterError("invalid max_lag_mb %d", input.MaxLagMb)
+	}
+	if len(input.Name) == 0 && len(input.GenerateName) == 0 {
+		input.Name = fmt.Sprintf("%s-replica", disk.Name)
+	}
+
+	input.StatusStandaloneResourceCreateInput, err = manager.SStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StatusStandaloneResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	input.Status = api.DISK_REPLICA_STATUS_PREPARING
+	return input, nil
+}
+
+func (self *SDiskReplica) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SStatusStandaloneResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	self.StartSyncTask(ctx, userCred, "")
+}
+
+func (manager *SDiskReplicaManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DiskReplicaListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	for field, values := range map[string][]string{
+		"disk_id":         query.DiskId,
+		"guest_id":        query.GuestId,
+		"host_id":         query.HostId,
+		"replica_host_id": query.ReplicaHostId,
+		"mode":            query.Mode,
+	} {
+		if len(values) > 0 {
+			q = q.In(field, values)
+		}
+	}
+	return q, nil
+}
+
+func (manager *SDiskReplicaManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DiskReplicaListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SDiskReplicaManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SDiskReplicaManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.DiskReplicaDetails {
+	rows := make([]api.DiskReplicaDetails, len(objs))
+	stdRows := manager.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	diskIds := make([]string, len(objs))
+	guestIds := make([]string, len(objs))
+	hostIds := make([]string, 0)
+	storageIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i].StatusStandaloneResourceDetails = stdRows[i]
+		replica := objs[i].(*SDiskReplica)
+		diskIds[i] = replica.DiskId
+		guestIds[i] = replica.GuestId
+		hostIds = append(hostIds, replica.HostId, replica.ReplicaHostId)
+		storageIds[i] = replica.ReplicaStorageId
+	}
+	disks, _ := db.FetchIdNameMap2(DiskManager, diskIds)
+	guests, _ := db.FetchIdNameMap2(GuestManager, guestIds)
+	hosts, _ := db.FetchIdNameMap2(HostManager, hostIds)
+	storages, _ := db.FetchIdNameMap2(StorageManager, storageIds)
+	for i := range rows {
+		replica := objs[i].(*SDiskReplica)
+		rows[i].Disk = disks[replica.DiskId]
+		rows[i].Guest = guests[replica.GuestId]
+		rows[i].Host = hosts[replica.HostId]
+		rows[i].ReplicaHost = hosts[replica.ReplicaHostId]
+		rows[i].ReplicaStorage = storages[replica.ReplicaStorageId]
+	}
+	return rows
+}
+
+func (self *SDiskReplica) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DiskReplicaUpdateInput) (api.DiskReplicaUpdateInput, error) {
+	var err error
+	if input.MaxLagMb != nil && *input.MaxLagMb < 0 {
+		return input, httperrors.NewInputParameterError("invalid max_lag_mb %d", *input.MaxLagMb)
+	}
+	input.StatusStandaloneResourceBaseUpdateInput, err = self.SStatusStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.StatusStandaloneResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SStatusStandaloneResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
+func (self *SDiskReplica) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if utils.IsInStringArray(self.Status, []string{api.DISK_REPLICA_STATUS_DELETING, api.DISK_REPLICA_STATUS_FAILING_OVER}) {
+		return httperrors.NewInvalidStatusError("Cannot delete disk replica in status %s", self.Status)
+	}
+	return self.SStatusStandaloneResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (self *SDiskReplica) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return nil
+}
+
+func (self *SDiskReplica) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return self.SStatusStandaloneResourceBase.Delete(ctx, userCred)
+}
+
+func (self *SDiskReplica) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	return self.StartDeleteTask(ctx, userCred, "")
+}
+
+func (self *SDiskReplica) StartDeleteTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	var err = func() error {
+		task, err := taskman.TaskManager.NewTask(ctx, "DiskReplicaDeleteTask", self, userCred, nil, parentTaskId, "", nil)
+		if err != nil {
+			return errors.Wrapf(err, "NewTask")
+		}
+		return task.ScheduleRun(nil)
+	}()
+	if err != nil {
+		self.SetStatus(ctx, userCred, api.DISK_REPLICA_STATUS_DELETE_FAILED, err.Error())
+		return err
+	}
+	return self.SetStatus(ctx, userCred, api.DISK_REPLICA_STATUS_DELETING, "")
+}
+
+// StartSyncTask (re)exports the replica on the replica host and starts
+// mirroring the disk into it
+func (self *SDiskReplica) StartSyncTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	var err = func() error {
+		task, err := taskman.TaskManager.NewTask(ctx, "DiskReplicaSyncTask", self, userCred, nil, parentTaskId, "", nil)
+		if err != nil {
+			return errors.Wrapf(err, "NewTask")
+		}
+		return task.ScheduleRun(nil)
+	}()
+	if err != nil {
+		self.SetStatus(ctx, userCred, api.DISK_REPLICA_STATUS_PREPARE_FAILED, err.Error())
+		return err
+	}
+	return self.SetStatus(ctx, userCred, api.DISK_REPLICA_STATUS_PREPARING, "")
+}
+
+// 重新同步副本
+func (self *SDiskReplica) PerformResync(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DiskReplicaResyncInput) (jsonutils.JSONObject, error) {
+	if utils.IsInStringArray(self.Status, []string{api.DISK_REPLICA_STATUS_PREPARING, api.DISK_REPLICA_STATUS_DELETING, api.DISK_REPLICA_STATUS_FAILING_OVER}) {
+		return nil, httperrors.NewInvalidStatusError("Cannot resync disk replica in status %s", self.Status)
+	}
+	replicaHost, err := self.GetReplicaHost()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if replicaHost.HostStatus != api.HOST_ONLINE {
+		return nil, httperrors.NewInvalidStatusError("replica host %s is not online", replicaHost.Name)
+	}
+	return nil, self.StartSyncTask(ctx, userCred, "")
+}
+
+// 切换到副本所在宿主机启动虚拟机
+func (self *SDiskReplica) PerformFailover(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DiskReplicaFailoverInput) (jsonutils.JSONObject, error) {
+	guest, err := self.GetGuest()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	host, err := guest.GetHost()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	if host.HostStatus == api.HOST_ONLINE {
+		if !input.Force {
+			return nil, httperrors.NewBadRequestError("host %s of server %s is online, use force to failover", host.Name, guest.Name)
+		}
+		if guest.Status != api.VM_READY {
+			return nil, httperrors.NewInvalidStatusError("server %s must be stopped before failover, current status %s", guest.Name, guest.Status)
+		}
+	}
+	return nil, DiskReplicaManager.StartGuestFailover(ctx, userCred, guest)
+}
+
+func (self *SDiskReplica) GetDisk() (*SDisk, error) {
+	disk, err := DiskManager.FetchById(self.DiskId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "DiskManager.FetchById(%s)", self.DiskId)
+	}
+	return disk.(*SDisk), nil
+}
+
+func (self *SDiskReplica) GetGuest() (*SGuest, error) {
+	guest, err := GuestManager.FetchById(self.GuestId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GuestManager.FetchById(%s)", self.GuestId)
+	}
+	return guest.(*SGuest), nil
+}
+
+func (self *SDiskReplica) GetHost() (*SHost, error) {
+	host, err := HostManager.FetchById(self.HostId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "HostManager.FetchById(%s)", self.HostId)
+	}
+	return host.(*SHost), nil
+}
+
+func (self *SDiskReplica) GetReplicaHost() (*SHost, error) {
+	host, err := HostManager.FetchById(self.ReplicaHostId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "HostManager.FetchById(%s)", self.ReplicaHostId)
+	}
+	return host.(*SHost), nil
+}
+
+func (manager *SDiskReplicaManager) GetReplicasByGuest(guestId string) ([]SDiskReplica, error) {
+	q := manager.Query().Equals("guest_id", guestId)
+	replicas := []SDiskReplica{}
+	err := db.FetchModelObjects(manager, q, &replicas)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return replicas, nil
+}
+
+func (self *SDiskReplica) CanFailover() bool {
+	return utils.IsInStringArray(self.Status, api.DISK_REPLICA_FAILOVER_STATUS) && !self.SyncedAt.IsZero()
+}
+
+// RequestPrepareReplica asks the replica host to create the replica image and
+// export it through nbd, the nbd uri is called back to the task
+func (self *SDiskReplica) RequestPrepareReplica(ctx context.Context, task taskman.ITask) error {
+	disk, err := self.GetDisk()
+	if err != nil {
+		return err
+	}
+	replicaHost, err := self.GetReplicaHost()
+	if err != nil {
+		return err
+	}
+	body := jsonutils.NewDict()
+	body.Set("size_mb", jsonutils.NewInt(int64(disk.DiskSize)))
+	url := fmt.Sprintf("/disks/%s/replica-prepare/%s", self.ReplicaStorageId, self.DiskId)
+	_, err = replicaHost.Request(ctx, task.GetUserCred(), "POST", url, task.GetTaskRequestHeader(), body)
+	return err
+}
+
+// RequestStopReplica stops the nbd export on the replica host, the replica
+// image is removed if deleteDisk is set
+func (self *SDiskReplica) RequestStopReplica(ctx context.Context, userCred mcclient.TokenCredential, deleteDisk bool) error {
+	replicaHost, err := self.GetReplicaHost()
+	if err != nil {
+		return err
+	}
+	body := jsonutils.NewDict()
+	body.Set("delete", jsonutils.NewBool(deleteDisk))
+	url := fmt.Sprintf("/disks/%s/replica-stop/%s", self.ReplicaStorageId, self.DiskId)
+	_, err = replicaHost.Request(ctx, userCred, "POST", url, mcclient.GetTokenHeaders(userCred), body)
+	return err
+}
+
+func (self *SDiskReplica) RequestStartMirror(ctx context.Context, userCred mcclient.TokenCredential, exportName string) error {
+	host, err := self.GetHost()
+	if err != nil {
+		return err
+	}
+	body := jsonutils.NewDict()
+	body.Set("disk_id", jsonutils.NewString(self.DiskId))
+	body.Set("nbd_uri", jsonutils.NewString(self.NbdUri))
+	body.Set("export_name", jsonutils.NewString(exportName))
+	body.Set("mode", jsonutils.NewString(self.Mode))
+	url := fmt.Sprintf("/servers/%s/disk-replica-start", self.GuestId)
+	_, err = host.Request(ctx, userCred, "POST", url, mcclient.GetTokenHeaders(userCred), body)
+	return err
+}
+
+func (self *SDiskReplica) RequestStopMirror(ctx context.Context, userCred mcclient.TokenCredential) error {
+	host, err := self.GetHost()
+	if err != nil {
+		return err
+	}
+	body := jsonutils.NewDict()
+	body.Set("disk_id", jsonutils.NewString(self.DiskId))
+	url := fmt.Sprintf("/servers/%s/disk-replica-stop", self.GuestId)
+	_, err = host.Request(ctx, userCred, "POST", url, mcclient.GetTokenHeaders(userCred), body)
+	return err
+}
+
+// diskReplicaSyncStatus derives replica status and lag from the mirror job
+// reported by the primary host, synced tells the replica holds a complete copy
+func diskReplicaSyncStatus(mode string, maxLagMb int, job *api.DiskReplicaJobStatus, guestRunning bool) (status string, reason string, lagBytes int64, synced bool) {
+	switch {
+	case job == nil && !guestRunning:
+		return api.DISK_REPLICA_STATUS_PAUSED, "server not running", 0, false
+	case job == nil:
+		return api.DISK_REPLICA_STATUS_BROKEN, "mirror job not found", 0, false
+	case len(job.IoStatus) > 0 && job.IoStatus != "ok":
+		return api.DISK_REPLICA_STATUS_BROKEN, fmt.Sprintf("mirror job io status %s", job.IoStatus), 0, false
+	}
+	lagBytes = job.Len - job.Offset
+	if !job.Ready {
+		return api.DISK_REPLICA_STATUS_SYNCING, "", lagBytes, false
+	}
+	if mode == api.DISK_REPLICA_MODE_ASYNC && maxLagMb > 0 && lagBytes > int64(maxLagMb)*1024*1024 {
+		return api.DISK_REPLICA_STATUS_LAGGING, fmt.Sprintf("lag %d bytes", lagBytes), lagBytes, true
+	}
+	return api.DISK_REPLICA_STATUS_IN_SYNC, "", lagBytes, true
+}
+
+func (self *SDiskReplica) updateSyncStatus(ctx context.Context, userCred mcclient.TokenCredential, job *api.DiskReplicaJobStatus, guestRunning bool) {
+	status, reason, lagBytes, synced := diskReplicaSyncStatus(self.Mode, self.MaxLagMb, job, guestRunning)
+	if job == nil {
+		// keep the last known lag while no mirror is running
+		lagBytes = self.LagBytes
+	}
+	syncedAt := self.SyncedAt
+	if synced && (status == api.DISK_REPLICA_STATUS_IN_SYNC || syncedAt.IsZero()) {
+		syncedAt = time.Now().UTC()
+	}
+	if lagBytes != self.LagBytes || !syncedAt.Equal(self.SyncedAt) {
+		db.Update(self, func() error {
+			self.LagBytes = lagBytes
+			self.SyncedAt = syncedAt
+			return nil
+		})
+	}
+	if status != self.Status {
+		self.SetStatus(ctx, userCred, status, reason)
+	}
+}
+
+// CheckDiskReplicas polls mirror jobs of replicated disks from primary hosts,
+// updates replica status and lag, and resyncs replicas whose mirror stopped
+func (manager *SDiskReplicaManager) CheckDiskReplicas(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().In("status", []string{
+		api.DISK_REPLICA_STATUS_SYNCING,
+		api.DISK_REPLICA_STATUS_IN_SYNC,
+		api.DISK_REPLICA_STATUS_LAGGING,
+		api.DISK_REPLICA_STATUS_PAUSED,
+		api.DISK_REPLICA_STATUS_BROKEN,
+	})
+	replicas := []SDiskReplica{}
+	err := db.FetchModelObjects(manager, q, &replicas)
+	if err != nil {
+		log.Errorf("CheckDiskReplicas fetch replicas: %s", err)
+		return
+	}
+	guestReplicas := map[string][]*SDiskReplica{}
+	for i := range replicas {
+		replicas[i].SetModelManager(manager, &replicas[i])
+		guestReplicas[replicas[i].GuestId] = append(guestReplicas[replicas[i].GuestId], &replicas[i])
+	}
+	for guestId, items := range guestReplicas {
+		manager.checkGuestReplicas(ctx, userCred, guestId, items)
+	}
+}
+
+func (manager *SDiskReplicaManager) checkGuestReplicas(ctx context.Context, userCred mcclient.TokenCredential, guestId string, replicas []*SDiskReplica) {
+	guest := GuestManager.FetchGuestById(guestId)
+	active := []*SDiskReplica{}
+	for _, replica := range replicas {
+		disk, _ := replica.GetDisk()
+		if guest == nil || disk == nil || disk.GetGuest() == nil || disk.GetGuest().Id != guestId {
+			log.Infof("disk replica %s lost its disk or server, remove it", replica.Name)
+			replica.StartDeleteTask(ctx, userCred, "")
+			continue
+		}
+		active = append(active, replica)
+	}
+	if len(active) == 0 {
+		return
+	}
+	replicas = active
+	host, err := guest.GetHost()
+	if err != nil || host.HostStatus != api.HOST_ONLINE {
+		// primary host down is handled by host health check
+		return
+	}
+	body := jsonutils.NewDict()
+	diskIds := make([]string, len(replicas))
+	for i := range replicas {
+		diskIds[i] = replicas[i].DiskId
+	}
+	body.Set("disk_ids", jsonutils.NewStringArray(diskIds))
+	url := fmt.Sprintf("/servers/%s/disk-replica-status", guest.Id)
+	ret, err := host.Request(ctx, userCred, "POST", url, mcclient.GetTokenHeaders(userCred), body)
+	if err != nil {
+		log.Errorf("query disk replica status of server %s: %s", guest.Name, err)
+		return
+	}
+	jobs := []api.DiskReplicaJobStatus{}
+	ret.Unmarshal(&jobs, "jobs")
+	running := jsonutils.QueryBoolean(ret, "running", false)
+	for _, replica := range replicas {
+		var job *api.DiskReplicaJobStatus
+		for i := range jobs {
+			if jobs[i].DiskId == replica.DiskId {
+				job = &jobs[i]
+				break
+			}
+		}
+		replica.updateSyncStatus(ctx, userCred, job, running)
+		if replica.Status != api.DISK_REPLICA_STATUS_BROKEN || !running {
+			continue
+		}
+		replicaHost, err := replica.GetReplicaHost()
+		if err != nil || replicaHost.HostStatus != api.HOST_ONLINE {
+			continue
+		}
+		log.Infof("disk replica %s is broken, resync it", replica.Name)
+		replica.StartSyncTask(ctx, userCred, "")
+	}
+}
+
+// StartGuestFailover restarts guest on the replica host of its disks, every
+// disk of the guest must have a replica holding a complete copy
+func (manager *SDiskReplicaManager) StartGuestFailover(ctx context.Context, userCred mcclient.TokenCredential, guest *SGuest) error {
+	lockman.LockObject(ctx, guest)
+	defer lockman.ReleaseObject(ctx, guest)
+
+	replicas, err := manager.GetReplicasByGuest(guest.Id)
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	disks, err := guest.GetDisks()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	replicaHostId := ""
+	for i := range disks {
+		var replica *SDiskReplica
+		for j := range replicas {
+			if replicas[j].DiskId == disks[i].Id {
+				replica = &replicas[j]
+				break
+			}
+		}
+		if replica == nil {
+			return httperrors.NewBadRequestError("disk %s of server %s has no replica", disks[i].Name, guest.Name)
+		}
+		if !replica.CanFailover() {
+			return httperrors.NewInvalidStatusError("replica %s of disk %s is not synced, status %s", replica.Name, disks[i].Name, replica.Status)
+		}
+		replicaHostId = replica.ReplicaHostId
+	}
+	replicaHost := HostManager.FetchHostById(replicaHostId)
+	if replicaHost == nil || replicaHost.HostStatus != api.HOST_ONLINE {
+		return httperrors.NewInvalidStatusError("replica host %s is not online", replicaHostId)
+	}
+	for i := range replicas {
+		replicas[i].SetStatus(ctx, userCred, api.DISK_REPLICA_STATUS_FAILING_OVER, "")
+	}
+	return guest.StartDiskReplicaFailoverTask(ctx, userCred, replicaHostId, "")
+}
+
+func (guest *SGuest) StartDiskReplicaFailoverTask(ctx context.Context, userCred mcclient.TokenCredential, replicaHostId string, parentTaskId string) error {
+	params := jsonutils.NewDict()
+	params.Set("replica_host_id", jsonutils.NewString(replicaHostId))
+	params.Set("guest_status", jsonutils.NewString(guest.Status))
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestDiskReplicaFailoverTask", guest, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	guest.SetStatus(ctx, userCred, api.VM_START_MIGRATE, "disk replica failover")
+	return task.ScheduleRun(nil)
+}
+
+// failoverDiskReplicas restarts guests of the dead host on their replica
+// hosts, and marks replicas kept on the dead host broken
+func (host *SHost) failoverDiskReplicas(ctx context.Context, userCred mcclient.TokenCredential) {
+	replicas := []SDiskReplica{}
+	q := DiskReplicaManager.Query().Equals("replica_host_id", host.Id)
+	if err := db.FetchModelObjects(DiskReplicaManager, q, &replicas); err != nil {
+		log.Errorf("fetch disk replicas on host %s: %s", host.Name, err)
+		return
+	}
+	for i := range replicas {
+		replicas[i].SetStatus(ctx, userCred, api.DISK_REPLICA_STATUS_BROKEN, "replica host down")
+	}
+
+	guestIds := []string{}
+	q = DiskReplicaManager.Query("guest_id").Equals("host_id", host.Id).Distinct()
+	rows, err := q.Rows()
+	if err != nil {
+		log.Errorf("fetch replicated guests on host %s: %s", host.Name, err)
+		return
+	}
+	for rows.Next() {
+		var guestId string
+		if err := rows.Scan(&guestId); err == nil {
+			guestIds = append(guestIds, guestId)
+		}
+	}
+	rows.Close()
+
+	for _, guestId := range guestIds {
+		guest := GuestManager.FetchGuestById(guestId)
+		if guest == nil || guest.HostId != host.Id {
+			continue
+		}
+		err := DiskReplicaManager.StartGuestFailover(ctx, userCred, guest)
+		if err != nil {
+			db.OpsLog.LogEvent(guest, db.ACT_DISK_REPLICA_FAILOVER_FAIL, fmt.Sprintf("failover on host down: %s", err), userCred)
+			logclient.AddSimpleActionLog(guest, logclient.ACT_DISK_REPLICA_FAILOVER, fmt.Sprintf("failover on host down: %s", err), userCred, false)
+		}
+	}
+}
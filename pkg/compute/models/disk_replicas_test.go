@@ -0,0 +1,97 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestDiskReplicaSyncStatus(t *testing.T) {
+	cases := []struct {
+		name     string
+		mode     string
+		maxLagMb int
+		job      *api.DiskReplicaJobStatus
+		running  bool
+		status   string
+		lag      int64
+		synced   bool
+	}{
+		{
+			name:   "server stopped",
+			mode:   api.DISK_REPLICA_MODE_ASYNC,
+			status: api.DISK_REPLICA_STATUS_PAUSED,
+		},
+		{
+			name:    "mirror lost",
+			mode:    api.DISK_REPLICA_MODE_ASYNC,
+			running: true,
+			status:  api.DISK_REPLICA_STATUS_BROKEN,
+		},
+		{
+			name:    "io error",
+			mode:    api.DISK_REPLICA_MODE_SYNC,
+			job:     &api.DiskReplicaJobStatus{Len: 100, Offset: 100, Ready: true, IoStatus: "failed"},
+			running: true,
+			status:  api.DISK_REPLICA_STATUS_BROKEN,
+		},
+		{
+			name:    "full copy in progress",
+			mode:    api.DISK_REPLICA_MODE_ASYNC,
+			job:     &api.DiskReplicaJobStatus{Len: 100, Offset: 40, IoStatus: "ok"},
+			running: true,
+			status:  api.DISK_REPLICA_STATUS_SYNCING,
+			lag:     60,
+		},
+		{
+			name:     "async within lag",
+			mode:     api.DISK_REPLICA_MODE_ASYNC,
+			maxLagMb: 1,
+			job:      &api.DiskReplicaJobStatus{Len: 1024 * 1024, Offset: 1024, Ready: true},
+			running:  true,
+			status:   api.DISK_REPLICA_STATUS_IN_SYNC,
+			lag:      1024*1024 - 1024,
+			synced:   true,
+		},
+		{
+			name:     "async lagging",
+			mode:     api.DISK_REPLICA_MODE_ASYNC,
+			maxLagMb: 1,
+			job:      &api.DiskReplicaJobStatus{Len: 3 * 1024 * 1024, Offset: 1024 * 1024, Ready: true},
+			running:  true,
+			status:   api.DISK_REPLICA_STATUS_LAGGING,
+			lag:      2 * 1024 * 1024,
+			synced:   true,
+		},
+		{
+			name:     "sync mode never lags",
+			mode:     api.DISK_REPLICA_MODE_SYNC,
+			maxLagMb: 1,
+			job:      &api.DiskReplicaJobStatus{Len: 3 * 1024 * 1024, Offset: 1024 * 1024, Ready: true},
+			running:  true,
+			status:   api.DISK_REPLICA_STATUS_IN_SYNC,
+			lag:      2 * 1024 * 1024,
+			synced:   true,
+		},
+	}
+	for _, c := range cases {
+		status, _, lag, synced := diskReplicaSyncStatus(c.mode, c.maxLagMb, c.job, c.running)
+		if status != c.status || lag != c.lag || synced != c.synced {
+			t.Errorf("%s: got status %s lag %d synced %v, want %s %d %v", c.name, status, lag, synced, c.status, c.lag, c.synced)
+		}
+	}
+}
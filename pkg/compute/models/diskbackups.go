@@ -67,6 +67,11 @@ type SDiskBackup struct {
 	// 操作系统类型
 	OsType     string `width:"32" charset:"ascii" nullable:"true" list:"user" create:"optional"`
 	DiskConfig *SBackupDiskConfig
+
+	// 增量备份所基于的备份, 为空表示全量备份
+	ParentBackupId string `width:"36" charset:"ascii" nullable:"true" list:"user"`
+	// 保留在磁盘上的快照, 作为下一次增量备份的基础
+	BaseSnapshotId string `width:"36" charset:"ascii" nullable:"true" list:"user"`
 }
 
 var DiskBackupManager *SDiskBackupManager
@@ -470,6 +475,9 @@ func (self *SDiskBackup) PerformRecovery(ctx context.Context, userCred mcclient.
 	if self.Status != api.BACKUP_STATUS_READY {
 		return nil, errors.Wrapf(httperrors.ErrInvalidStatus, "cannot recover backup in status %s", self.Status)
 	}
+	if self.IsIncremental() {
+		return nil, httperrors.NewUnsupportOperationError("cannot recover incremental backup %s", self.Name)
+	}
 	return nil, self.StartRecoveryTask(ctx, userCred, "", input.Name)
 }
 
@@ -552,7 +560,27 @@ func (self *SDiskBackup) PackMetadata() *api.DiskBackupPackMetadata {
 			DiskConfig: self.DiskConfig.DiskConfig,
 			Name:       self.DiskConfig.Name,
 		},
+		Incremental: self.IsIncremental(),
+	}
+}
+
+// IsIncremental tells the backup only holds the data written after its
+// parent backup, it can't be restored on its own
+func (self *SDiskBackup) IsIncremental() bool {
+	return len(self.ParentBackupId) > 0
+}
+
+// GetBaseSnapshot returns the snapshot kept on the disk for the next
+// incremental backup, nil if there is none
+func (self *SDiskBackup) GetBaseSnapshot() *SSnapshot {
+	if len(self.BaseSnapshotId) == 0 {
+		return nil
+	}
+	snapshot, err := SnapshotManager.FetchById(self.BaseSnapshotId)
+	if err != nil {
+		return nil
 	}
+	return snapshot.(*SSnapshot)
 }
 
 func (manager *SDiskBackupManager) CreateFromPackMetadata(ctx context.Context, owner mcclient.TokenCredential, backupStorageId, id, name string, metadata *api.DiskBackupPackMetadata) (*SDiskBackup, error) {
@@ -97,6 +97,8 @@ type SDisk struct {
 	// 磁盘大小, 单位Mb
 	// example: 10240
 	DiskSize int `nullable:"false" list:"user" json:"disk_size"`
+	// 磁盘在存储上实际占用的空间, 由宿主机上报, 单位Mb
+	ActualSizeMb int64 `nullable:"true" list:"user" json:"actual_size_mb"`
 	// 磁盘路径
 	AccessPath string `width:"256" charset:"utf8" nullable:"true" get:"user" json:"access_path"`
 
@@ -2131,6 +2133,9 @@ func fillDiskConfigByBackup(ctx context.Context, userCred mcclient.TokenCredenti
 		return err
 	}
 	backup := iBakcup.(*SDiskBackup)
+	if backup.IsIncremental() {
+		return httperrors.NewUnsupportOperationError("cannot create disk from incremental backup %s", backup.Name)
+	}
 	if diskConfig.DiskType == "" {
 		diskConfig.DiskType = backup.DiskType
 	}
@@ -3268,6 +3273,9 @@ func (disk *SDisk) resetDiskinfo(
 				}
 			}
 			backup := bkObj.(*SDiskBackup)
+			if backup.IsIncremental() {
+				return httperrors.NewUnsupportOperationError("cannot reset disk from incremental backup %s", backup.Name)
+			}
 			input.BackupId = &backup.Id
 		}
 	}
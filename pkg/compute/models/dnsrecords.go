@@ -86,6 +86,9 @@ type SDnsRecord struct {
 
 	// 目前存储阿里云GTM设置地址及AWS TrafficPolicy端点地址, 仅支持同步
 	ExtraAddresses []string `width:"512" charset:"utf8" nullable:"true" list:"user"`
+
+	// zone lines before update, journaled for zone transfer
+	prevZoneLines []string
 }
 
 func (manager *SDnsRecordManager) EnableGenerateName() bool {
@@ -161,9 +164,30 @@ func (manager *SDnsRecordManager) ValidateCreateData(
 
 func (self *SDnsRecord) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	self.SEnabledStatusStandaloneResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	self.journalChange(ctx, nil, self.zoneLines())
 	self.StartCreateTask(ctx, userCred, "")
 }
 
+// zoneLines returns the lines served by region-dns for this record
+func (self *SDnsRecord) zoneLines() []string {
+	if !self.Enabled.Bool() {
+		return nil
+	}
+	return []string{self.ToZoneLine()}
+}
+
+func (self *SDnsRecord) journalChange(ctx context.Context, removed, added []string) {
+	zone, err := self.GetDnsZone()
+	if err != nil {
+		log.Errorf("GetDnsZone for record %s: %v", self.Id, err)
+		return
+	}
+	err = zone.RecordChanged(ctx, removed, added)
+	if err != nil {
+		log.Errorf("journal change of dns zone %s: %v", zone.Name, err)
+	}
+}
+
 func (self *SDnsRecord) StartCreateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
 	params := jsonutils.NewDict()
 	task, err := taskman.TaskManager.NewTask(ctx, "DnsRecordCreateTask", self, userCred, params, parentTaskId, "", nil)
@@ -241,7 +265,12 @@ func (self *SDnsRecord) Delete(ctx context.Context, userCred mcclient.TokenCrede
 }
 
 func (self *SDnsRecord) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
-	return self.SEnabledStatusStandaloneResourceBase.Delete(ctx, userCred)
+	err := self.SEnabledStatusStandaloneResourceBase.Delete(ctx, userCred)
+	if err != nil {
+		return err
+	}
+	self.journalChange(ctx, self.zoneLines(), nil)
+	return nil
 }
 
 type sRecordUniqValues struct {
@@ -405,8 +434,14 @@ func (self *SDnsRecord) ValidateUpdateData(ctx context.Context, userCred mcclien
 	return input, nil
 }
 
+func (self *SDnsRecord) PreUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	self.SEnabledStatusStandaloneResourceBase.PreUpdate(ctx, userCred, query, data)
+	self.prevZoneLines = self.zoneLines()
+}
+
 func (self *SDnsRecord) PostUpdate(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	self.SEnabledStatusStandaloneResourceBase.PostUpdate(ctx, userCred, query, data)
+	self.journalChange(ctx, self.prevZoneLines, self.zoneLines())
 	self.StartUpdateTask(ctx, userCred, "")
 }
 
@@ -699,10 +734,12 @@ func (self *SDnsRecord) GetCNAME() string {
 
 // 启用
 func (self *SDnsRecord) PerformEnable(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DnsRecordEnableInput) (jsonutils.JSONObject, error) {
+	prev := self.zoneLines()
 	_, err := self.SEnabledStatusStandaloneResourceBase.PerformEnable(ctx, userCred, query, input.PerformEnableInput)
 	if err != nil {
 		return nil, err
 	}
+	self.journalChange(ctx, prev, self.zoneLines())
 	if err != nil {
 		return nil, httperrors.NewGeneralError(errors.Wrapf(err, "GetDnsZone"))
 	}
@@ -721,10 +758,12 @@ func (self *SDnsRecord) StartSetEnabledTask(ctx context.Context, userCred mcclie
 
 // 禁用
 func (self *SDnsRecord) PerformDisable(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DnsRecordDisableInput) (jsonutils.JSONObject, error) {
+	prev := self.zoneLines()
 	_, err := self.SEnabledStatusStandaloneResourceBase.PerformDisable(ctx, userCred, query, input.PerformDisableInput)
 	if err != nil {
 		return nil, err
 	}
+	self.journalChange(ctx, prev, self.zoneLines())
 	if err != nil {
 		return nil, httperrors.NewGeneralError(errors.Wrapf(err, "GetDnsZone"))
 	}
@@ -0,0 +1,329 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/dnssec"
+)
+
+func (self *SDnsZone) IsDnssecEnabled() bool {
+	return self.DnssecEnabled.IsTrue() && self.DnssecKeys != nil
+}
+
+func (self *SDnsZone) GetDnssecKeys() []api.SDnssecKey {
+	if self.DnssecKeys == nil {
+		return nil
+	}
+	return self.DnssecKeys.Keys
+}
+
+// GetDnssecPrivateKey returns the decrypted private key in BIND format
+func (self *SDnsZone) GetDnssecPrivateKey(key *api.SDnssecKey) (string, error) {
+	return utils.DescryptAESBase64(self.Id, key.PrivateKey)
+}
+
+func (self *SDnsZone) newDnssecKey(keyType string, now time.Time) (api.SDnssecKey, error) {
+	flags := uint16(dnssec.FlagZSK)
+	if keyType == api.DNSSEC_KEY_TYPE_KSK {
+		flags = dnssec.FlagKSK
+	}
+	dnskey, priv, err := dnssec.GenerateKey(self.Name, flags, self.DnssecAlgorithm)
+	if err != nil {
+		return api.SDnssecKey{}, errors.Wrapf(err, "GenerateKey %s", keyType)
+	}
+	sec, err := utils.EncryptAESBase64(self.Id, priv)
+	if err != nil {
+		return api.SDnssecKey{}, errors.Wrap(err, "EncryptAESBase64")
+	}
+	return api.SDnssecKey{
+		KeyType:        keyType,
+		State:          api.DNSSEC_KEY_STATE_ACTIVE,
+		KeyTag:         dnskey.KeyTag(),
+		DnsKey:         dnskey.String(),
+		PrivateKey:     sec,
+		CreatedAt:      now,
+		StateChangedAt: now,
+	}, nil
+}
+
+func (self *SDnsZone) setDnssecKeys(keys []api.SDnssecKey) error {
+	_, err := db.Update(self, func() error {
+		self.DnssecKeys = &api.SDnssecKeys{Keys: keys}
+		return nil
+	})
+	return err
+}
+
+// 启用DNSSEC
+func (self *SDnsZone) PerformEnableDnssec(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DnsZoneEnableDnssecInput) (jsonutils.JSONObject, error) {
+	if len(self.ManagerId) > 0 {
+		return nil, httperrors.NewNotSupportedError("dnssec is only supported by local dns zone")
+	}
+	if self.IsDnssecEnabled() {
+		return nil, nil
+	}
+	if len(input.Algorithm) == 0 {
+		input.Algorithm = api.DNSSEC_ALGORITHM_ECDSAP256SHA256
+	}
+	if !utils.IsInStringArray(input.Algorithm, api.DNSSEC_ALGORITHMS) {
+		return nil, httperrors.NewInputParameterError("invalid algorithm %s, supported %s", input.Algorithm, api.DNSSEC_ALGORITHMS)
+	}
+
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	self.DnssecAlgorithm = input.Algorithm
+	now := time.Now().UTC()
+	keys := []api.SDnssecKey{}
+	for _, keyType := range []string{api.DNSSEC_KEY_TYPE_KSK, api.DNSSEC_KEY_TYPE_ZSK} {
+		key, err := self.newDnssecKey(keyType, now)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		keys = append(keys, key)
+	}
+	_, err := db.Update(self, func() error {
+		self.DnssecEnabled = tristate.True
+		self.DnssecKeys = &api.SDnssecKeys{Keys: keys}
+		return nil
+	})
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrap(err, "db.Update"))
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, "enable dnssec", userCred)
+	return nil, nil
+}
+
+// 禁用DNSSEC
+func (self *SDnsZone) PerformDisableDnssec(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DnsZoneDisableDnssecInput) (jsonutils.JSONObject, error) {
+	if !self.DnssecEnabled.IsTrue() {
+		return nil, nil
+	}
+	_, err := db.Update(self, func() error {
+		self.DnssecEnabled = tristate.False
+		self.DnssecAlgorithm = ""
+		self.DnssecKeys = nil
+		return nil
+	})
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrap(err, "db.Update"))
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, "disable dnssec", userCred)
+	return nil, nil
+}
+
+// 轮换DNSSEC密钥
+func (self *SDnsZone) PerformDnssecRollover(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DnsZoneDnssecRolloverInput) (jsonutils.JSONObject, error) {
+	if !self.IsDnssecEnabled() {
+		return nil, httperrors.NewInvalidStatusError("dnssec of zone %s not enabled", self.Name)
+	}
+
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	now := time.Now().UTC()
+	keys := self.GetDnssecKeys()
+	switch input.KeyType {
+	case api.DNSSEC_KEY_TYPE_KSK:
+		key, err := self.newDnssecKey(api.DNSSEC_KEY_TYPE_KSK, now)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		keys = retireDnssecKeys(keys, api.DNSSEC_KEY_TYPE_KSK, now)
+		keys = append(keys, key)
+	case api.DNSSEC_KEY_TYPE_ZSK:
+		if hasDnssecKey(keys, api.DNSSEC_KEY_TYPE_ZSK, api.DNSSEC_KEY_STATE_PUBLISHED) {
+			return nil, httperrors.NewConflictError("zone signing key rollover of zone %s in progress", self.Name)
+		}
+		key, err := self.newDnssecKey(api.DNSSEC_KEY_TYPE_ZSK, now)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		key.State = api.DNSSEC_KEY_STATE_PUBLISHED
+		keys = append(keys, key)
+	default:
+		return nil, httperrors.NewInputParameterError("invalid key_type %s", input.KeyType)
+	}
+	err := self.setDnssecKeys(keys)
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrap(err, "setDnssecKeys"))
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, "rollover dnssec "+input.KeyType, userCred)
+	return nil, nil
+}
+
+// 获取上级区域需要添加的DS记录
+func (self *SDnsZone) GetDetailsDsRecords(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.DnsZoneDsRecordsOutput, error) {
+	if !self.IsDnssecEnabled() {
+		return nil, httperrors.NewInvalidStatusError("dnssec of zone %s not enabled", self.Name)
+	}
+	ret := &api.DnsZoneDsRecordsOutput{
+		DsRecords: []string{},
+		Keys:      []api.DnsZoneDnssecKeyInfo{},
+	}
+	for _, key := range self.GetDnssecKeys() {
+		ret.Keys = append(ret.Keys, api.DnsZoneDnssecKeyInfo{
+			KeyType:        key.KeyType,
+			State:          key.State,
+			KeyTag:         key.KeyTag,
+			DnsKey:         key.DnsKey,
+			CreatedAt:      key.CreatedAt,
+			StateChangedAt: key.StateChangedAt,
+		})
+		if key.KeyType != api.DNSSEC_KEY_TYPE_KSK || key.State != api.DNSSEC_KEY_STATE_ACTIVE {
+			continue
+		}
+		dnskey, _, err := dnssec.ParseKey(key.DnsKey, "")
+		if err != nil {
+			return nil, httperrors.NewGeneralError(errors.Wrapf(err, "parse key %d", key.KeyTag))
+		}
+		ret.DsRecords = append(ret.DsRecords, dnskey.ToDS(dns.SHA256).String())
+	}
+	return ret, nil
+}
+
+func hasDnssecKey(keys []api.SDnssecKey, keyType, state string) bool {
+	for i := range keys {
+		if keys[i].KeyType == keyType && keys[i].State == state {
+			return true
+		}
+	}
+	return false
+}
+
+func retireDnssecKeys(keys []api.SDnssecKey, keyType string, now time.Time) []api.SDnssecKey {
+	ret := make([]api.SDnssecKey, len(keys))
+	copy(ret, keys)
+	for i := range ret {
+		if ret[i].KeyType == keyType && ret[i].State == api.DNSSEC_KEY_STATE_ACTIVE {
+			ret[i].State = api.DNSSEC_KEY_STATE_RETIRED
+			ret[i].StateChangedAt = now
+		}
+	}
+	return ret
+}
+
+type sDnssecRolloverPolicy struct {
+	ZskLifetime time.Duration
+	Propagation time.Duration
+	KskRetire   time.Duration
+}
+
+// rolloverDnssecKeys advances the pre-publish rollover of zone signing keys
+// and drops retired keys.  newZsk is called to generate the key to publish
+// when the active zone signing key expires
+func rolloverDnssecKeys(keys []api.SDnssecKey, now time.Time, policy sDnssecRolloverPolicy, newZsk func() (api.SDnssecKey, error)) ([]api.SDnssecKey, bool, error) {
+	changed := false
+	ret := []api.SDnssecKey{}
+	for _, key := range keys {
+		if key.State == api.DNSSEC_KEY_STATE_RETIRED {
+			keep := policy.Propagation
+			if key.KeyType == api.DNSSEC_KEY_TYPE_KSK {
+				keep = policy.KskRetire
+			}
+			if now.Sub(key.StateChangedAt) >= keep {
+				changed = true
+				continue
+			}
+		}
+		ret = append(ret, key)
+	}
+
+	for i := range ret {
+		if ret[i].KeyType != api.DNSSEC_KEY_TYPE_ZSK || ret[i].State != api.DNSSEC_KEY_STATE_PUBLISHED {
+			continue
+		}
+		if now.Sub(ret[i].StateChangedAt) < policy.Propagation {
+			continue
+		}
+		ret = retireDnssecKeys(ret, api.DNSSEC_KEY_TYPE_ZSK, now)
+		ret[i].State = api.DNSSEC_KEY_STATE_ACTIVE
+		ret[i].StateChangedAt = now
+		changed = true
+		break
+	}
+
+	if hasDnssecKey(ret, api.DNSSEC_KEY_TYPE_ZSK, api.DNSSEC_KEY_STATE_PUBLISHED) {
+		return ret, changed, nil
+	}
+	for i := range ret {
+		if ret[i].KeyType == api.DNSSEC_KEY_TYPE_ZSK && ret[i].State == api.DNSSEC_KEY_STATE_ACTIVE && now.Sub(ret[i].StateChangedAt) >= policy.ZskLifetime {
+			key, err := newZsk()
+			if err != nil {
+				return keys, false, err
+			}
+			key.State = api.DNSSEC_KEY_STATE_PUBLISHED
+			ret = append(ret, key)
+			changed = true
+			break
+		}
+	}
+	return ret, changed, nil
+}
+
+func (self *SDnsZone) rolloverDnssecKeys(ctx context.Context, policy sDnssecRolloverPolicy) error {
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	now := time.Now().UTC()
+	keys, changed, err := rolloverDnssecKeys(self.GetDnssecKeys(), now, policy, func() (api.SDnssecKey, error) {
+		return self.newDnssecKey(api.DNSSEC_KEY_TYPE_ZSK, now)
+	})
+	if err != nil {
+		return errors.Wrap(err, "rolloverDnssecKeys")
+	}
+	if !changed {
+		return nil
+	}
+	return self.setDnssecKeys(keys)
+}
+
+func (manager *SDnsZoneManager) AutoRolloverDnssecKeys(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().IsNullOrEmpty("manager_id").IsTrue("dnssec_enabled")
+	zones := []SDnsZone{}
+	err := db.FetchModelObjects(manager, q, &zones)
+	if err != nil {
+		log.Errorf("fetch dnssec enabled zones: %v", err)
+		return
+	}
+	policy := sDnssecRolloverPolicy{
+		ZskLifetime: time.Duration(options.Options.DnssecZskLifetimeDays) * 24 * time.Hour,
+		Propagation: time.Duration(options.Options.DnssecKeyPropagationHours) * time.Hour,
+		KskRetire:   time.Duration(options.Options.DnssecKskRetireDays) * 24 * time.Hour,
+	}
+	for i := range zones {
+		err := zones[i].rolloverDnssecKeys(ctx, policy)
+		if err != nil {
+			log.Errorf("rollover dnssec keys of zone %s: %v", zones[i].Name, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestRolloverDnssecKeys(t *testing.T) {
+	now := time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	policy := sDnssecRolloverPolicy{
+		ZskLifetime: 30 * day,
+		Propagation: 2 * day,
+		KskRetire:   7 * day,
+	}
+	newZsk := func() (api.SDnssecKey, error) {
+		return api.SDnssecKey{KeyType: api.DNSSEC_KEY_TYPE_ZSK, KeyTag: 100, StateChangedAt: now}, nil
+	}
+	key := func(keyType, state string, tag uint16, age time.Duration) api.SDnssecKey {
+		return api.SDnssecKey{KeyType: keyType, State: state, KeyTag: tag, StateChangedAt: now.Add(-age)}
+	}
+	ksk := key(api.DNSSEC_KEY_TYPE_KSK, api.DNSSEC_KEY_STATE_ACTIVE, 1, 100*day)
+
+	cases := []struct {
+		name    string
+		keys    []api.SDnssecKey
+		changed bool
+		want    map[uint16]string
+	}{
+		{
+			name: "fresh keys",
+			keys: []api.SDnssecKey{ksk, key(api.DNSSEC_KEY_TYPE_ZSK, api.DNSSEC_KEY_STATE_ACTIVE, 2, day)},
+			want: map[uint16]string{1: api.DNSSEC_KEY_STATE_ACTIVE, 2: api.DNSSEC_KEY_STATE_ACTIVE},
+		},
+		{
+			name:    "expired zsk publishes new key",
+			keys:    []api.SDnssecKey{ksk, key(api.DNSSEC_KEY_TYPE_ZSK, api.DNSSEC_KEY_STATE_ACTIVE, 2, 31*day)},
+			changed: true,
+			want:    map[uint16]string{1: api.DNSSEC_KEY_STATE_ACTIVE, 2: api.DNSSEC_KEY_STATE_ACTIVE, 100: api.DNSSEC_KEY_STATE_PUBLISHED},
+		},
+		{
+			name: "published zsk waits for propagation",
+			keys: []api.SDnssecKey{
+				ksk,
+				key(api.DNSSEC_KEY_TYPE_ZSK, api.DNSSEC_KEY_STATE_ACTIVE, 2, 31*day),
+				key(api.DNSSEC_KEY_TYPE_ZSK, api.DNSSEC_KEY_STATE_PUBLISHED, 3, day),
+			},
+			want: map[uint16]string{1: api.DNSSEC_KEY_STATE_ACTIVE, 2: api.DNSSEC_KEY_STATE_ACTIVE, 3: api.DNSSEC_KEY_STATE_PUBLISHED},
+		},
+		{
+			name: "published zsk activated",
+			keys: []api.SDnssecKey{
+				ksk,
+				key(api.DNSSEC_KEY_TYPE_ZSK, api.DNSSEC_KEY_STATE_ACTIVE, 2, 33*day),
+				key(api.DNSSEC_KEY_TYPE_ZSK, api.DNSSEC_KEY_STATE_PUBLISHED, 3, 3*day),
+			},
+			changed: true,
+			want:    map[uint16]string{1: api.DNSSEC_KEY_STATE_ACTIVE, 2: api.DNSSEC_KEY_STATE_RETIRED, 3: api.DNSSEC_KEY_STATE_ACTIVE},
+		},
+		{
+			name: "retired keys removed",
+			keys: []api.SDnssecKey{
+				ksk,
+				key(api.DNSSEC_KEY_TYPE_KSK, api.DNSSEC_KEY_STATE_RETIRED, 4, 8*day),
+				key(api.DNSSEC_KEY_TYPE_KSK, api.DNSSEC_KEY_STATE_RETIRED, 5, 3*day),
+				key(api.DNSSEC_KEY_TYPE_ZSK, api.DNSSEC_KEY_STATE_RETIRED, 2, 3*day),
+				key(api.DNSSEC_KEY_TYPE_ZSK, api.DNSSEC_KEY_STATE_ACTIVE, 3, 3*day),
+			},
+			changed: true,
+			want:    map[uint16]string{1: api.DNSSEC_KEY_STATE_ACTIVE, 5: api.DNSSEC_KEY_STATE_RETIRED, 3: api.DNSSEC_KEY_STATE_ACTIVE},
+		},
+	}
+	for _, c := range cases {
+		keys, changed, err := rolloverDnssecKeys(c.keys, now, policy, newZsk)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if changed != c.changed {
+			t.Errorf("%s: want changed %v, got %v", c.name, c.changed, changed)
+		}
+		got := map[uint16]string{}
+		for _, k := range keys {
+			got[k.KeyTag] = k.State
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: want %v, got %v", c.name, c.want, got)
+			continue
+		}
+		for tag, state := range c.want {
+			if got[tag] != state {
+				t.Errorf("%s: key %d want %s, got %s", c.name, tag, state, got[tag])
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+)
+
+// SDnsZoneChangeManager keeps the change journal of local dns zones, which
+// is used by region-dns to answer incremental zone transfer (IXFR)
+type SDnsZoneChangeManager struct {
+	db.SResourceBaseManager
+}
+
+var DnsZoneChangeManager *SDnsZoneChangeManager
+
+func init() {
+	DnsZoneChangeManager = &SDnsZoneChangeManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SDnsZoneChange{},
+			"dns_zone_changes_tbl",
+			"dns_zone_change",
+			"dns_zone_changes",
+		),
+	}
+	DnsZoneChangeManager.SetVirtualObject(DnsZoneChangeManager)
+}
+
+type SDnsZoneChange struct {
+	db.SResourceBase
+
+	RowId int64 `primary:"true" auto_increment:"true" list:"user"`
+
+	DnsZoneId string `width:"36" charset:"ascii" nullable:"false" index:"true" list:"user"`
+	// 变更后的区域序列号
+	Serial int64 `nullable:"false" list:"user"`
+	// 删除的记录, 每行一条zone file格式记录
+	Removed string `type:"text" nullable:"true" list:"user"`
+	// 新增的记录, 每行一条zone file格式记录
+	Added string `type:"text" nullable:"true" list:"user"`
+}
+
+func (change *SDnsZoneChange) GetRemoved() []string {
+	return splitZoneLines(change.Removed)
+}
+
+func (change *SDnsZoneChange) GetAdded() []string {
+	return splitZoneLines(change.Added)
+}
+
+func splitZoneLines(lines string) []string {
+	ret := []string{}
+	for _, line := range strings.Split(lines, "\n") {
+		if len(line) > 0 {
+			ret = append(ret, line)
+		}
+	}
+	return ret
+}
+
+// dnsZoneNextSerial increases the zone serial, skipping 0 on wrap around
+// as serial arithmetic of RFC 1982 is done with 32 bits
+func dnsZoneNextSerial(serial int64) int64 {
+	serial = (serial + 1) & 0xffffffff
+	if serial == 0 {
+		serial = 1
+	}
+	return serial
+}
+
+// dnsZoneChangesSince returns the journal entries bringing a zone from
+// serial to current.  The second return value is false if the journal does
+// not cover the whole range and a full transfer is needed
+func dnsZoneChangesSince(changes []SDnsZoneChange, serial, current int64) ([]SDnsZoneChange, bool) {
+	if serial == current {
+		return []SDnsZoneChange{}, true
+	}
+	start := -1
+	next := dnsZoneNextSerial(serial)
+	for i := range changes {
+		if changes[i].Serial == next {
+			start = i
+		}
+	}
+	if start < 0 {
+		return nil, false
+	}
+	ret := changes[start:]
+	for i := range ret {
+		if ret[i].Serial != next {
+			return nil, false
+		}
+		next = dnsZoneNextSerial(next)
+	}
+	if ret[len(ret)-1].Serial != current {
+		return nil, false
+	}
+	return ret, true
+}
+
+// GetChangesSince returns the changes applied to zone after serial, ordered
+// from the oldest one
+func (self *SDnsZone) GetChangesSince(serial int64) ([]SDnsZoneChange, bool, error) {
+	q := DnsZoneChangeManager.Query().Equals("dns_zone_id", self.Id).Asc("row_id")
+	changes := []SDnsZoneChange{}
+	err := db.FetchModelObjects(DnsZoneChangeManager, q, &changes)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "FetchModelObjects")
+	}
+	ret, ok := dnsZoneChangesSince(changes, serial, self.Serial)
+	return ret, ok, nil
+}
+
+// RecordChanged bumps the serial of a local zone and journals the removed and
+// added records, given in zone file format
+func (self *SDnsZone) RecordChanged(ctx context.Context, removed, added []string) error {
+	if len(self.ManagerId) > 0 {
+		return nil
+	}
+	if isSameZoneLines(removed, added) {
+		return nil
+	}
+
+	lockman.LockObject(ctx, self)
+	defer lockman.ReleaseObject(ctx, self)
+
+	// serial may have been bumped by others since self was fetched
+	obj, err := DnsZoneManager.FetchById(self.Id)
+	if err != nil {
+		return errors.Wrapf(err, "FetchById %s", self.Id)
+	}
+	zone := obj.(*SDnsZone)
+	_, err = db.Update(zone, func() error {
+		zone.Serial = dnsZoneNextSerial(zone.Serial)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update serial")
+	}
+	self.Serial = zone.Serial
+
+	change := &SDnsZoneChange{
+		DnsZoneId: zone.Id,
+		Serial:    zone.Serial,
+		Removed:   strings.Join(removed, "\n"),
+		Added:     strings.Join(added, "\n"),
+	}
+	change.SetModelManager(DnsZoneChangeManager, change)
+	err = DnsZoneChangeManager.TableSpec().Insert(ctx, change)
+	if err != nil {
+		return errors.Wrap(err, "insert change")
+	}
+	return zone.pruneChanges(ctx)
+}
+
+// pruneChanges keeps the newest DNS_ZONE_CHANGE_JOURNAL_SIZE changes of zone.
+// Row ids are shared by all zones, so the oldest kept row is looked up per zone
+func (self *SDnsZone) pruneChanges(ctx context.Context) error {
+	q := DnsZoneChangeManager.Query("row_id").Equals("dns_zone_id", self.Id).Desc("row_id").
+		Offset(api.DNS_ZONE_CHANGE_JOURNAL_SIZE - 1).Limit(1)
+	var oldest int64
+	err := q.Row().Scan(&oldest)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil
+		}
+		return errors.Wrap(err, "query oldest change")
+	}
+	expired := DnsZoneChangeManager.Query("row_id").Equals("dns_zone_id", self.Id).LT("row_id", oldest)
+	pair := purgePair{manager: DnsZoneChangeManager, key: "row_id", q: expired}
+	return pair.purgeAll(ctx)
+}
+
+func isSameZoneLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+)
+
+func TestDnsZoneNextSerial(t *testing.T) {
+	cases := []struct {
+		in   int64
+		want int64
+	}{
+		{in: 1, want: 2},
+		{in: 0xfffffffe, want: 0xffffffff},
+		{in: 0xffffffff, want: 1},
+	}
+	for _, c := range cases {
+		if got := dnsZoneNextSerial(c.in); got != c.want {
+			t.Errorf("next of %d: want %d, got %d", c.in, c.want, got)
+		}
+	}
+}
+
+func TestDnsZoneChangesSince(t *testing.T) {
+	changes := []SDnsZoneChange{
+		{Serial: 3},
+		{Serial: 4},
+		{Serial: 5},
+	}
+	cases := []struct {
+		name    string
+		serial  int64
+		current int64
+		want    int
+		ok      bool
+	}{
+		{name: "up to date", serial: 5, current: 5, want: 0, ok: true},
+		{name: "whole journal", serial: 2, current: 5, want: 3, ok: true},
+		{name: "partial", serial: 4, current: 5, want: 1, ok: true},
+		{name: "too old", serial: 1, current: 5, want: 0, ok: false},
+		{name: "journal behind", serial: 3, current: 6, want: 0, ok: false},
+		{name: "unknown serial", serial: 10, current: 5, want: 0, ok: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := dnsZoneChangesSince(changes, c.serial, c.current)
+			if ok != c.ok {
+				t.Fatalf("want ok %v, got %v", c.ok, ok)
+			}
+			if len(got) != c.want {
+				t.Fatalf("want %d changes, got %d", c.want, len(got))
+			}
+		})
+	}
+}
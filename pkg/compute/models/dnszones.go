@@ -17,6 +17,8 @@ package models
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"net"
 	"strings"
 
 	"yunion.io/x/cloudmux/pkg/cloudprovider"
@@ -72,6 +74,27 @@ type SDnsZone struct {
 
 	ZoneType    string `width:"32" charset:"ascii" nullable:"false" list:"domain" create:"domain_required"`
 	ProductType string `width:"32" charset:"ascii" nullable:"false" list:"domain" create:"domain_optional"`
+
+	// 区域序列号, 本地区域的解析记录变更时递增
+	Serial int64 `nullable:"false" default:"1" list:"user"`
+
+	// 允许区域传送的IP或CIDR列表
+	TransferAllowIps []string `width:"1024" charset:"ascii" nullable:"true" list:"domain" create:"domain_optional"`
+	// 区域传送使用的TSIG密钥名称
+	TsigKeyName string `width:"128" charset:"ascii" nullable:"true" list:"domain" create:"domain_optional"`
+	// TSIG算法
+	TsigAlgorithm string `width:"32" charset:"ascii" nullable:"true" list:"domain" create:"domain_optional"`
+	// TSIG密钥, base64编码
+	TsigSecret string `width:"256" charset:"ascii" nullable:"true" create:"domain_optional"`
+	// 辅DNS服务器地址列表, 区域变更时发送NOTIFY
+	NotifySecondaries []string `width:"1024" charset:"ascii" nullable:"true" list:"domain" create:"domain_optional"`
+
+	// 是否启用DNSSEC
+	DnssecEnabled tristate.TriState `default:"false" list:"user"`
+	// DNSSEC签名算法
+	DnssecAlgorithm string `width:"32" charset:"ascii" nullable:"true" list:"user"`
+	// DNSSEC密钥, 私钥加密存储
+	DnssecKeys *api.SDnssecKeys `nullable:"true"`
 }
 
 func (self *SDnsZone) GetUniqValues() jsonutils.JSONObject {
@@ -181,6 +204,14 @@ func (manager *SDnsZoneManager) ValidateCreateData(
 		}
 	}
 
+	if len(input.ManagerId) > 0 && (len(input.TransferAllowIps) > 0 || len(input.TsigKeyName) > 0 || len(input.NotifySecondaries) > 0) {
+		return nil, httperrors.NewNotSupportedError("zone transfer is only supported by local dns zone")
+	}
+	input.DnsZoneTransferInput, err = validateDnsZoneTransfer(input.DnsZoneTransferInput)
+	if err != nil {
+		return nil, err
+	}
+
 	quota := &SDomainQuota{
 		SBaseDomainQuotaKeys: quotas.SBaseDomainQuotaKeys{
 			DomainId: ownerId.GetProjectDomainId(),
@@ -382,10 +413,12 @@ func (self *SDnsZone) Delete(ctx context.Context, userCred mcclient.TokenCredent
 func (self *SDnsZone) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
 	dnsVpcs := DnsZoneVpcManager.Query("row_id").Equals("dns_zone_id", self.Id)
 	records := DnsRecordManager.Query("id").Equals("dns_zone_id", self.Id)
+	changes := DnsZoneChangeManager.Query("row_id").Equals("dns_zone_id", self.Id)
 
 	pairs := []purgePair{
 		{manager: DnsZoneVpcManager, key: "row_id", q: dnsVpcs},
 		{manager: DnsRecordManager, key: "id", q: records},
+		{manager: DnsZoneChangeManager, key: "row_id", q: changes},
 	}
 	for i := range pairs {
 		err := pairs[i].purgeAll(ctx)
@@ -656,6 +689,73 @@ func (manager *SDnsZoneManager) GetPropertyCapability(ctx context.Context, userC
 	return jsonutils.Marshal(cloudprovider.GetDnsCapabilities()), nil
 }
 
+func validateDnsZoneTransfer(input api.DnsZoneTransferInput) (api.DnsZoneTransferInput, error) {
+	for _, addr := range input.TransferAllowIps {
+		if !regutils.MatchIPAddr(addr) && !regutils.MatchCIDR(addr) && !regutils.MatchCIDR6(addr) {
+			return input, httperrors.NewInputParameterError("invalid transfer_allow_ips %s", addr)
+		}
+	}
+	for i, addr := range input.NotifySecondaries {
+		host, port := addr, ""
+		if h, p, err := net.SplitHostPort(addr); err == nil {
+			host, port = h, p
+		}
+		if !regutils.MatchIPAddr(host) {
+			return input, httperrors.NewInputParameterError("invalid notify_secondaries %s", addr)
+		}
+		if len(port) == 0 {
+			port = "53"
+		}
+		input.NotifySecondaries[i] = net.JoinHostPort(host, port)
+	}
+	if len(input.TsigKeyName) == 0 {
+		input.TsigAlgorithm, input.TsigSecret = "", ""
+		return input, nil
+	}
+	if !regutils.MatchDomainName(strings.TrimSuffix(input.TsigKeyName, ".")) {
+		return input, httperrors.NewInputParameterError("invalid tsig_key_name %s", input.TsigKeyName)
+	}
+	input.TsigKeyName = strings.ToLower(strings.TrimSuffix(input.TsigKeyName, "."))
+	if len(input.TsigAlgorithm) == 0 {
+		input.TsigAlgorithm = api.DNS_TSIG_ALGORITHM_HMAC_SHA256
+	}
+	if !utils.IsInStringArray(input.TsigAlgorithm, api.DNS_TSIG_ALGORITHMS) {
+		return input, httperrors.NewInputParameterError("invalid tsig_algorithm %s, supported %s", input.TsigAlgorithm, api.DNS_TSIG_ALGORITHMS)
+	}
+	if len(input.TsigSecret) == 0 {
+		return input, httperrors.NewMissingParameterError("tsig_secret")
+	}
+	if _, err := base64.StdEncoding.DecodeString(input.TsigSecret); err != nil {
+		return input, httperrors.NewInputParameterError("tsig_secret should be base64 encoded")
+	}
+	return input, nil
+}
+
+// 设置区域传送
+func (self *SDnsZone) PerformSetTransfer(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DnsZoneTransferInput) (jsonutils.JSONObject, error) {
+	if len(self.ManagerId) > 0 {
+		return nil, httperrors.NewNotSupportedError("zone transfer is only supported by local dns zone")
+	}
+	var err error
+	input, err = validateDnsZoneTransfer(input)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := db.Update(self, func() error {
+		self.TransferAllowIps = input.TransferAllowIps
+		self.TsigKeyName = input.TsigKeyName
+		self.TsigAlgorithm = input.TsigAlgorithm
+		self.TsigSecret = input.TsigSecret
+		self.NotifySecondaries = input.NotifySecondaries
+		return nil
+	})
+	if err != nil {
+		return nil, httperrors.NewGeneralError(errors.Wrap(err, "db.Update"))
+	}
+	db.OpsLog.LogEvent(self, db.ACT_UPDATE, diff, userCred)
+	return nil, nil
+}
+
 func (self *SDnsZone) PerformPurge(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DnsZonePurgeInput) (jsonutils.JSONObject, error) {
 	return nil, self.RealDelete(ctx, userCred)
 }
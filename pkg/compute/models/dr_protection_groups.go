@@ -0,0 +1,1062 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/cloudmux/pkg/cloudprovider"
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/httputils"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// +onecloud:swagger-gen-model-singular=dr_protection_group
+// +onecloud:swagger-gen-model-plural=dr_protection_groups
+type SDrProtectionGroupManager struct {
+	db.SEnabledStatusStandaloneResourceBaseManager
+}
+
+var DrProtectionGroupManager *SDrProtectionGroupManager
+
+func init() {
+	DrProtectionGroupManager = &SDrProtectionGroupManager{
+		SEnabledStatusStandaloneResourceBaseManager: db.NewEnabledStatusStandaloneResourceBaseManager(
+			SDrProtectionGroup{},
+			"dr_protection_groups_tbl",
+			"dr_protection_group",
+			"dr_protection_groups",
+		),
+	}
+	DrProtectionGroupManager.SetVirtualObject(DrProtectionGroupManager)
+}
+
+// SDrProtectionGroup protects kvm guests against the loss of this region.
+// A recovery point of every member guest is taken each RpoMinutes as an
+// instance backup and packed onto a backup storage shared with the target
+// region, failover rebuilds the guests in the target region from the
+// packages through the compute service of that region. The snapshot taken
+// for a recovery point is kept on the disk, so the next one only ships what
+// was written since as an incremental package on top of it. A full copy is
+// taken after FullInterval incremental points or when the chain is broken.
+type SDrProtectionGroup struct {
+	db.SEnabledStatusStandaloneResourceBase
+
+	// 目标区域
+	TargetRegion string `width:"128" charset:"utf8" nullable:"false" create:"required" list:"admin"`
+	// 本区域备份存储
+	BackupStorageId string `width:"36" charset:"ascii" nullable:"false" create:"required" list:"admin" index:"true"`
+	// 备份存储在目标区域中的Id
+	TargetBackupStorageId string `width:"36" charset:"ascii" nullable:"false" create:"required" list:"admin"`
+	// 恢复点间隔, 单位分钟
+	RpoMinutes int `nullable:"false" default:"5" create:"optional" list:"admin" update:"admin"`
+	// 每台虚拟机保留的恢复点数量
+	RetainCount int `nullable:"false" default:"4" create:"optional" list:"admin" update:"admin"`
+	// 连续增量恢复点的最大数量
+	FullInterval int `nullable:"false" default:"96" create:"optional" list:"admin" update:"admin"`
+	// 网络映射
+	NetworkMappings *api.DrNetworkMappings `nullable:"true" create:"optional" list:"admin" update:"admin"`
+	// 演练使用的目标区域隔离网络
+	TestNetworkId string `width:"36" charset:"ascii" nullable:"true" create:"optional" list:"admin" update:"admin"`
+	// 上次故障切换时间
+	FailedOverAt time.Time `nullable:"true" list:"admin"`
+}
+
+// +onecloud:swagger-gen-ignore
+type SDrProtectionGroupMemberManager struct {
+	db.SResourceBaseManager
+}
+
+var DrProtectionGroupMemberManager *SDrProtectionGroupMemberManager
+
+func init() {
+	DrProtectionGroupMemberManager = &SDrProtectionGroupMemberManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SDrProtectionGroupMember{},
+			"dr_protection_group_members_tbl",
+			"dr_protection_group_member",
+			"dr_protection_group_members",
+		),
+	}
+	DrProtectionGroupMemberManager.SetVirtualObject(DrProtectionGroupMemberManager)
+}
+
+// SDrProtectionGroupMember is a guest protected by a group, along with the
+// guests rebuilt from it in the target region
+// +onecloud:swagger-gen-ignore
+type SDrProtectionGroupMember struct {
+	db.SResourceBase
+
+	Id      int    `primary:"true" auto_increment:"true" nullable:"false"`
+	GroupId string `width:"36" charset:"ascii" nullable:"false" index:"true"`
+	GuestId string `width:"36" charset:"ascii" nullable:"false" index:"true"`
+
+	// guest rebuilt by failover and the instance backup it was rebuilt
+	// from, both live in the target region
+	RemoteGuestId  string `width:"36" charset:"ascii" nullable:"true"`
+	RemoteBackupId string `width:"36" charset:"ascii" nullable:"true"`
+	// same as above for test failover
+	TestGuestId  string `width:"36" charset:"ascii" nullable:"true"`
+	TestBackupId string `width:"36" charset:"ascii" nullable:"true"`
+}
+
+func (member *SDrProtectionGroupMember) GetId() string {
+	return strconv.Itoa(member.Id)
+}
+
+// getGuestToStop returns the protected guest when it is running on a host
+// still online, it must be stopped before its copy starts in the target
+// region. A guest on an unreachable host is taken as lost
+func (member *SDrProtectionGroupMember) getGuestToStop() (*SGuest, error) {
+	guest := GuestManager.FetchGuestById(member.GuestId)
+	if guest == nil || guest.Status == api.VM_READY {
+		return nil, nil
+	}
+	host, _ := guest.GetHost()
+	if host == nil || host.HostStatus != api.HOST_ONLINE {
+		return nil, nil
+	}
+	if guest.Status != api.VM_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("server %s is %s, neither running nor stopped", guest.Name, guest.Status)
+	}
+	return guest, nil
+}
+
+// GetMemberGuestToStop returns a protected guest not failed over yet which
+// has to be stopped before failover, nil when there is none
+func (group *SDrProtectionGroup) GetMemberGuestToStop() (*SGuest, error) {
+	members, err := group.GetMembers()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetMembers")
+	}
+	for i := range members {
+		if len(members[i].RemoteGuestId) > 0 {
+			continue
+		}
+		guest, err := members[i].getGuestToStop()
+		if err != nil || guest != nil {
+			return guest, err
+		}
+	}
+	return nil, nil
+}
+
+// drValidateRemote checks a resource exists in the target region and returns
+// its id
+func drValidateRemote(s *mcclient.ClientSession, manager modulebase.Manager, id string) (string, error) {
+	obj, err := manager.Get(s, id, nil)
+	if err != nil {
+		if httputils.ErrorCode(err) == 404 {
+			return "", httperrors.NewResourceNotFoundError2(manager.GetKeyword(), id)
+		}
+		return "", httperrors.NewGeneralError(errors.Wrapf(err, "get %s %s", manager.GetKeyword(), id))
+	}
+	return obj.GetString("id")
+}
+
+func (manager *SDrProtectionGroupManager) validateNetworkMappings(ctx context.Context, userCred mcclient.TokenCredential, s *mcclient.ClientSession, mappings api.DrNetworkMappings) (api.DrNetworkMappings, error) {
+	sources := []string{}
+	for i := range mappings {
+		netObj, err := validators.ValidateModel(ctx, userCred, NetworkManager, &mappings[i].SourceNetworkId)
+		if err != nil {
+			return nil, err
+		}
+		if utils.IsInStringArray(netObj.GetId(), sources) {
+			return nil, httperrors.NewDuplicateResourceError("network %s is mapped more than once", netObj.GetName())
+		}
+		sources = append(sources, netObj.GetId())
+		if len(mappings[i].TargetNetworkId) == 0 {
+			return nil, httperrors.NewMissingParameterError("target_network_id")
+		}
+		mappings[i].TargetNetworkId, err = drValidateRemote(s, &compute.Networks, mappings[i].TargetNetworkId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mappings, nil
+}
+
+// validateGuest checks the guest can join a group, guests already protected
+// by another group are rejected
+func (manager *SDrProtectionGroupManager) validateGuest(ctx context.Context, userCred mcclient.TokenCredential, guestId string) (*SGuest, error) {
+	guestObj, err := validators.ValidateModel(ctx, userCred, GuestManager, &guestId)
+	if err != nil {
+		return nil, err
+	}
+	guest := guestObj.(*SGuest)
+	if guest.Hypervisor != api.HYPERVISOR_KVM {
+		return nil, httperrors.NewUnsupportOperationError("disaster recovery is not supported for hypervisor %s", guest.Hypervisor)
+	}
+	if len(guest.BackupHostId) > 0 {
+		return nil, httperrors.NewBadRequestError("server %s has a backup guest", guest.Name)
+	}
+	if cnt, err := DrProtectionGroupMemberManager.Query().Equals("guest_id", guest.Id).CountWithError(); err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	} else if cnt > 0 {
+		return nil, httperrors.NewDuplicateResourceError("server %s is already protected", guest.Name)
+	}
+	return guest, nil
+}
+
+func (manager *SDrProtectionGroupManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.DrProtectionGroupCreateInput) (api.DrProtectionGroupCreateInput, error) {
+	if len(input.TargetRegion) == 0 {
+		return input, httperrors.NewMissingParameterError("target_region")
+	}
+	if input.TargetRegion == options.Options.Region {
+		return input, httperrors.NewInputParameterError("target region %s is the current region", input.TargetRegion)
+	}
+	s := auth.GetAdminSession(ctx, input.TargetRegion)
+	if _, err := s.GetServiceURL(api.SERVICE_TYPE, ""); err != nil {
+		return input, httperrors.NewInputParameterError("region %s has no compute service: %v", input.TargetRegion, err)
+	}
+
+	bsObj, err := validators.ValidateModel(ctx, userCred, BackupStorageManager, &input.BackupStorageId)
+	if err != nil {
+		return input, err
+	}
+	bs := bsObj.(*SBackupStorage)
+	if bs.Status != api.BACKUPSTORAGE_STATUS_ONLINE {
+		return input, httperrors.NewInvalidStatusError("backup storage %s is %s", bs.Name, bs.Status)
+	}
+	if len(input.TargetBackupStorageId) == 0 {
+		return input, httperrors.NewMissingParameterError("target_backup_storage_id")
+	}
+	input.TargetBackupStorageId, err = drValidateRemote(s, &compute.BackupStorages, input.TargetBackupStorageId)
+	if err != nil {
+		return input, err
+	}
+
+	if input.RpoMinutes == 0 {
+		input.RpoMinutes = api.DR_PROTECTION_GROUP_DEFAULT_RPO_MINUTES
+	}
+	if input.RpoMinutes < 1 {
+		return input, httperrors.NewInputParameterError("invalid rpo_minutes %d", input.RpoMinutes)
+	}
+	if input.RetainCount == 0 {
+		input.RetainCount = api.DR_PROTECTION_GROUP_DEFAULT_RETAIN_COUNT
+	}
+	if input.RetainCount < 1 {
+		return input, httperrors.NewInputParameterError("invalid retain_count %d", input.RetainCount)
+	}
+	if input.FullInterval == 0 {
+		input.FullInterval = api.DR_PROTECTION_GROUP_DEFAULT_FULL_INTERVAL
+	}
+	if input.FullInterval < 1 {
+		return input, httperrors.NewInputParameterError("invalid full_interval %d", input.FullInterval)
+	}
+	input.NetworkMappings, err = manager.validateNetworkMappings(ctx, userCred, s, input.NetworkMappings)
+	if err != nil {
+		return input, err
+	}
+	if len(input.TestNetworkId) > 0 {
+		input.TestNetworkId, err = drValidateRemote(s, &compute.Networks, input.TestNetworkId)
+		if err != nil {
+			return input, err
+		}
+	}
+	for i := range input.GuestIds {
+		guest, err := manager.validateGuest(ctx, userCred, input.GuestIds[i])
+		if err != nil {
+			return input, err
+		}
+		input.GuestIds[i] = guest.Id
+	}
+
+	input.EnabledStatusStandaloneResourceCreateInput, err = manager.SEnabledStatusStandaloneResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusStandaloneResourceCreateInput)
+	if err != nil {
+		return input, err
+	}
+	input.Status = api.DR_PROTECTION_GROUP_STATUS_PROTECTING
+	return input, nil
+}
+
+func (group *SDrProtectionGroup) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	group.SEnabledStatusStandaloneResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	input := api.DrProtectionGroupCreateInput{}
+	data.Unmarshal(&input)
+	for _, guestId := range input.GuestIds {
+		err := group.addMember(ctx, guestId)
+		if err != nil {
+			log.Errorf("add server %s to dr protection group %s: %s", guestId, group.Name, err)
+		}
+	}
+}
+
+func (manager *SDrProtectionGroupManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DrProtectionGroupListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.TargetRegion) > 0 {
+		q = q.In("target_region", query.TargetRegion)
+	}
+	if len(query.BackupStorageId) > 0 {
+		q = q.In("backup_storage_id", query.BackupStorageId)
+	}
+	if len(query.GuestId) > 0 {
+		guestObj, err := validators.ValidateModel(ctx, userCred, GuestManager, &query.GuestId)
+		if err != nil {
+			return nil, err
+		}
+		sq := DrProtectionGroupMemberManager.Query("group_id").Equals("guest_id", guestObj.GetId())
+		q = q.In("id", sq.SubQuery())
+	}
+	return q, nil
+}
+
+func (manager *SDrProtectionGroupManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DrProtectionGroupListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusStandaloneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SDrProtectionGroupManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SDrProtectionGroupManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.DrProtectionGroupDetails {
+	rows := make([]api.DrProtectionGroupDetails, len(objs))
+	stdRows := manager.SEnabledStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	bsIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i].EnabledStatusStandaloneResourceDetails = stdRows[i]
+		bsIds[i] = objs[i].(*SDrProtectionGroup).BackupStorageId
+	}
+	backupStorages, _ := db.FetchIdNameMap2(BackupStorageManager, bsIds)
+	for i := range rows {
+		group := objs[i].(*SDrProtectionGroup)
+		rows[i].BackupStorage = backupStorages[group.BackupStorageId]
+		members, err := group.GetMembers()
+		if err != nil {
+			log.Errorf("GetMembers of dr protection group %s: %s", group.Id, err)
+			continue
+		}
+		guestIds := make([]string, len(members))
+		for j := range members {
+			guestIds[j] = members[j].GuestId
+		}
+		guests, _ := db.FetchIdNameMap2(GuestManager, guestIds)
+		rows[i].Members = make([]api.DrProtectionGroupMemberDetails, len(members))
+		for j := range members {
+			rows[i].Members[j] = api.DrProtectionGroupMemberDetails{
+				GuestId:       members[j].GuestId,
+				Guest:         guests[members[j].GuestId],
+				RemoteGuestId: members[j].RemoteGuestId,
+				TestGuestId:   members[j].TestGuestId,
+			}
+			rp, _ := DrRecoveryPointManager.GetLatestRecoveryPoint(group.Id, members[j].GuestId, time.Time{})
+			if rp != nil {
+				rows[i].Members[j].LastRecoveryPointAt = rp.CreatedAt
+			}
+		}
+	}
+	return rows
+}
+
+func (group *SDrProtectionGroup) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DrProtectionGroupUpdateInput) (api.DrProtectionGroupUpdateInput, error) {
+	var err error
+	if input.RpoMinutes != nil && *input.RpoMinutes < 1 {
+		return input, httperrors.NewInputParameterError("invalid rpo_minutes %d", *input.RpoMinutes)
+	}
+	if input.RetainCount != nil && *input.RetainCount < 1 {
+		return input, httperrors.NewInputParameterError("invalid retain_count %d", *input.RetainCount)
+	}
+	if input.FullInterval != nil && *input.FullInterval < 1 {
+		return input, httperrors.NewInputParameterError("invalid full_interval %d", *input.FullInterval)
+	}
+	s := auth.GetAdminSession(ctx, group.TargetRegion)
+	if len(input.NetworkMappings) > 0 {
+		input.NetworkMappings, err = DrProtectionGroupManager.validateNetworkMappings(ctx, userCred, s, input.NetworkMappings)
+		if err != nil {
+			return input, err
+		}
+	}
+	if input.TestNetworkId != nil && len(*input.TestNetworkId) > 0 {
+		testNetworkId, err := drValidateRemote(s, &compute.Networks, *input.TestNetworkId)
+		if err != nil {
+			return input, err
+		}
+		input.TestNetworkId = &testNetworkId
+	}
+	input.EnabledStatusStandaloneResourceBaseUpdateInput, err = group.SEnabledStatusStandaloneResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusStandaloneResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SEnabledStatusStandaloneResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
+func (group *SDrProtectionGroup) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if utils.IsInStringArray(group.Status, []string{
+		api.DR_PROTECTION_GROUP_STATUS_FAILING_OVER,
+		api.DR_PROTECTION_GROUP_STATUS_FAILING_BACK,
+		api.DR_PROTECTION_GROUP_STATUS_TEST_FAILING_OVER,
+		api.DR_PROTECTION_GROUP_STATUS_TESTING,
+		api.DR_PROTECTION_GROUP_STATUS_CLEANING_TEST,
+		api.DR_PROTECTION_GROUP_STATUS_DELETING,
+	}) {
+		return httperrors.NewInvalidStatusError("Cannot delete dr protection group in status %s", group.Status)
+	}
+	pending, err := DrRecoveryPointManager.Query().Equals("group_id", group.Id).
+		In("status", []string{api.DR_RECOVERY_POINT_STATUS_CREATING, api.DR_RECOVERY_POINT_STATUS_PACKING}).CountWithError()
+	if err != nil {
+		return httperrors.NewGeneralError(err)
+	}
+	if pending > 0 {
+		return httperrors.NewInvalidStatusError("Cannot delete dr protection group while %d recovery points are being taken", pending)
+	}
+	return group.SEnabledStatusStandaloneResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (group *SDrProtectionGroup) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return nil
+}
+
+func (group *SDrProtectionGroup) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	members, err := group.GetMembers()
+	if err != nil {
+		return errors.Wrap(err, "GetMembers")
+	}
+	for i := range members {
+		_, err := db.Update(&members[i], func() error {
+			return members[i].MarkDelete()
+		})
+		if err != nil {
+			return errors.Wrapf(err, "delete member %d", members[i].Id)
+		}
+	}
+	return group.SEnabledStatusStandaloneResourceBase.Delete(ctx, userCred)
+}
+
+func (group *SDrProtectionGroup) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	return group.StartDeleteTask(ctx, userCred, "")
+}
+
+func (group *SDrProtectionGroup) StartDeleteTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "DrProtectionGroupDeleteTask", group, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrapf(err, "NewTask")
+	}
+	group.SetStatus(ctx, userCred, api.DR_PROTECTION_GROUP_STATUS_DELETING, "")
+	return task.ScheduleRun(nil)
+}
+
+func (group *SDrProtectionGroup) GetMembers() ([]SDrProtectionGroupMember, error) {
+	q := DrProtectionGroupMemberManager.Query().Equals("group_id", group.Id).Asc("id")
+	members := make([]SDrProtectionGroupMember, 0)
+	err := db.FetchModelObjects(DrProtectionGroupMemberManager, q, &members)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return members, nil
+}
+
+func (group *SDrProtectionGroup) GetBackupStorage() (*SBackupStorage, error) {
+	bs, err := BackupStorageManager.FetchById(group.BackupStorageId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "BackupStorageManager.FetchById(%s)", group.BackupStorageId)
+	}
+	return bs.(*SBackupStorage), nil
+}
+
+func (group *SDrProtectionGroup) addMember(ctx context.Context, guestId string) error {
+	member := &SDrProtectionGroupMember{
+		GroupId: group.Id,
+		GuestId: guestId,
+	}
+	member.SetModelManager(DrProtectionGroupMemberManager, member)
+	return DrProtectionGroupMemberManager.TableSpec().Insert(ctx, member)
+}
+
+// 添加受保护的虚拟机
+func (group *SDrProtectionGroup) PerformAddGuests(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DrProtectionGroupGuestsInput) (jsonutils.JSONObject, error) {
+	if group.Status != api.DR_PROTECTION_GROUP_STATUS_PROTECTING {
+		return nil, httperrors.NewInvalidStatusError("Cannot add servers to dr protection group in status %s", group.Status)
+	}
+	lockman.LockObject(ctx, group)
+	defer lockman.ReleaseObject(ctx, group)
+
+	for i := range input.GuestIds {
+		guest, err := DrProtectionGroupManager.validateGuest(ctx, userCred, input.GuestIds[i])
+		if err != nil {
+			return nil, err
+		}
+		err = group.addMember(ctx, guest.Id)
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		db.OpsLog.LogEvent(group, db.ACT_ATTACH, guest.GetShortDesc(ctx), userCred)
+	}
+	return nil, nil
+}
+
+// 移除受保护的虚拟机, 其恢复点一并删除
+func (group *SDrProtectionGroup) PerformRemoveGuests(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DrProtectionGroupGuestsInput) (jsonutils.JSONObject, error) {
+	if group.Status != api.DR_PROTECTION_GROUP_STATUS_PROTECTING {
+		return nil, httperrors.NewInvalidStatusError("Cannot remove servers from dr protection group in status %s", group.Status)
+	}
+	lockman.LockObject(ctx, group)
+	defer lockman.ReleaseObject(ctx, group)
+
+	members, err := group.GetMembers()
+	if err != nil {
+		return nil, httperrors.NewGeneralError(err)
+	}
+	for _, guestId := range input.GuestIds {
+		guestObj, err := validators.ValidateModel(ctx, userCred, GuestManager, &guestId)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for i := range members {
+			if members[i].GuestId != guestObj.GetId() {
+				continue
+			}
+			found = true
+			_, err := db.Update(&members[i], func() error {
+				return members[i].MarkDelete()
+			})
+			if err != nil {
+				return nil, httperrors.NewGeneralError(err)
+			}
+		}
+		if !found {
+			return nil, httperrors.NewResourceNotFoundError("server %s is not protected by %s", guestObj.GetName(), group.Name)
+		}
+		rps, err := DrRecoveryPointManager.GetRecoveryPoints(group.Id, guestObj.GetId())
+		if err != nil {
+			return nil, httperrors.NewGeneralError(err)
+		}
+		for i := range rps {
+			rps[i].StartDeleteTask(ctx, userCred, "")
+		}
+		db.OpsLog.LogEvent(group, db.ACT_DETACH, guestObj.GetShortDesc(ctx), userCred)
+	}
+	return nil, nil
+}
+
+// 立即创建恢复点
+func (group *SDrProtectionGroup) PerformSync(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DrProtectionGroupSyncInput) (jsonutils.JSONObject, error) {
+	if group.Status != api.DR_PROTECTION_GROUP_STATUS_PROTECTING {
+		return nil, httperrors.NewInvalidStatusError("Cannot sync dr protection group in status %s", group.Status)
+	}
+	return nil, group.takeRecoveryPoints(ctx, userCred, true)
+}
+
+// 故障切换, 在目标区域用最新的恢复点重建虚拟机
+func (group *SDrProtectionGroup) PerformFailover(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DrProtectionGroupFailoverInput) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(group.Status, api.DR_PROTECTION_GROUP_FAILOVER_STATUS) {
+		return nil, httperrors.NewInvalidStatusError("Cannot failover dr protection group in status %s", group.Status)
+	}
+	return nil, group.StartFailoverTask(ctx, userCred, false, input.RecoveryPointBefore, "")
+}
+
+// 故障演练, 在目标区域的隔离网络中重建虚拟机, 不影响受保护的虚拟机
+func (group *SDrProtectionGroup) PerformTestFailover(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DrProtectionGroupTestFailoverInput) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(group.Status, api.DR_PROTECTION_GROUP_FAILOVER_STATUS) {
+		return nil, httperrors.NewInvalidStatusError("Cannot test failover dr protection group in status %s", group.Status)
+	}
+	if len(group.TestNetworkId) == 0 {
+		return nil, httperrors.NewMissingParameterError("test_network_id")
+	}
+	return nil, group.StartFailoverTask(ctx, userCred, true, input.RecoveryPointBefore, "")
+}
+
+// 清理故障演练创建的虚拟机
+func (group *SDrProtectionGroup) PerformCleanupTest(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DrProtectionGroupCleanupTestInput) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(group.Status, []string{api.DR_PROTECTION_GROUP_STATUS_TESTING, api.DR_PROTECTION_GROUP_STATUS_TEST_FAILOVER_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("Cannot cleanup test of dr protection group in status %s", group.Status)
+	}
+	return nil, group.startTask(ctx, userCred, "DrProtectionGroupCleanupTestTask", nil, api.DR_PROTECTION_GROUP_STATUS_CLEANING_TEST, "")
+}
+
+// 故障恢复, 将目标区域的虚拟机迁回本区域
+func (group *SDrProtectionGroup) PerformFailback(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.DrProtectionGroupFailbackInput) (jsonutils.JSONObject, error) {
+	if !utils.IsInStringArray(group.Status, []string{api.DR_PROTECTION_GROUP_STATUS_FAILED_OVER, api.DR_PROTECTION_GROUP_STATUS_FAILBACK_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("Cannot failback dr protection group in status %s", group.Status)
+	}
+	return nil, group.startTask(ctx, userCred, "DrProtectionGroupFailbackTask", nil, api.DR_PROTECTION_GROUP_STATUS_FAILING_BACK, "")
+}
+
+func (group *SDrProtectionGroup) StartFailoverTask(ctx context.Context, userCred mcclient.TokenCredential, test bool, before time.Time, parentTaskId string) error {
+	params := jsonutils.NewDict()
+	params.Set("test", jsonutils.NewBool(test))
+	if !before.IsZero() {
+		params.Set("recovery_point_before", jsonutils.NewTimeString(before))
+	}
+	status := api.DR_PROTECTION_GROUP_STATUS_FAILING_OVER
+	if test {
+		status = api.DR_PROTECTION_GROUP_STATUS_TEST_FAILING_OVER
+	}
+	return group.startTask(ctx, userCred, "DrProtectionGroupFailoverTask", params, status, parentTaskId)
+}
+
+func (group *SDrProtectionGroup) startTask(ctx context.Context, userCred mcclient.TokenCredential, taskName string, params *jsonutils.JSONDict, status string, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, taskName, group, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrapf(err, "NewTask")
+	}
+	group.SetStatus(ctx, userCred, status, "")
+	return task.ScheduleRun(nil)
+}
+
+// drRemapNetworks converts the networks of a backed up guest to the networks
+// the rebuilt guest attaches to, all nics go to testNetworkId when it is set
+func drRemapNetworks(nets []*api.NetworkConfig, mappings api.DrNetworkMappings, testNetworkId string) ([]*api.NetworkConfig, error) {
+	ret := make([]*api.NetworkConfig, 0, len(nets))
+	for _, net := range nets {
+		target := testNetworkId
+		if len(target) == 0 {
+			for i := range mappings {
+				if mappings[i].SourceNetworkId == net.Network {
+					target = mappings[i].TargetNetworkId
+					break
+				}
+			}
+		}
+		if len(target) == 0 {
+			return nil, errors.Wrapf(httperrors.ErrNotFound, "no network mapping for network %s", net.Network)
+		}
+		ret = append(ret, &api.NetworkConfig{
+			Index:     net.Index,
+			Network:   target,
+			Driver:    net.Driver,
+			BwLimit:   net.BwLimit,
+			NumQueues: net.NumQueues,
+			IsDefault: net.IsDefault,
+		})
+	}
+	return ret, nil
+}
+
+// drWaitStatus polls a resource through the session until its status is
+// one of status, a status containing "fail" aborts the wait
+func drWaitStatus(s *mcclient.ClientSession, manager modulebase.Manager, id string, status []string, timeout time.Duration) (jsonutils.JSONObject, error) {
+	var obj jsonutils.JSONObject
+	err := cloudprovider.Wait(10*time.Second, timeout, func() (bool, error) {
+		var err error
+		obj, err = manager.Get(s, id, nil)
+		if err != nil {
+			return false, errors.Wrapf(err, "get %s %s", manager.GetKeyword(), id)
+		}
+		cur, _ := obj.GetString("status")
+		if utils.IsInStringArray(cur, status) {
+			return true, nil
+		}
+		if strings.Contains(cur, "fail") {
+			return false, errors.Errorf("%s %s is %s", manager.GetKeyword(), id, cur)
+		}
+		return false, nil
+	})
+	return obj, err
+}
+
+// drRecoverGuest imports a packed instance backup into the region of the
+// session and rebuilds a started guest from it, the ids of the imported
+// backup and the guest are returned
+func drRecoverGuest(s *mcclient.ClientSession, backupStorageId, packageName, name, projectId string, networks []*api.NetworkConfig) (string, string, error) {
+	params := jsonutils.NewDict()
+	params.Set("generate_name", jsonutils.NewString(name))
+	params.Set("backup_storage_id", jsonutils.NewString(backupStorageId))
+	params.Set("package_name", jsonutils.NewString(packageName))
+	if len(projectId) > 0 {
+		params.Set("project_id", jsonutils.NewString(projectId))
+	}
+	ret, err := compute.InstanceBackups.Create(s, params)
+	if err != nil {
+		return "", "", errors.Wrap(err, "create instance backup from package")
+	}
+	backupId, _ := ret.GetString("id")
+	_, err = drWaitStatus(s, &compute.InstanceBackups, backupId, []string{api.INSTANCE_BACKUP_STATUS_READY}, time.Hour*6)
+	if err != nil {
+		return backupId, "", errors.Wrap(err, "wait instance backup unpacked")
+	}
+
+	input := api.InstanceBackupRecoveryInput{Name: name, Networks: networks}
+	_, err = compute.InstanceBackups.PerformAction(s, backupId, "recovery", jsonutils.Marshal(input))
+	if err != nil {
+		return backupId, "", errors.Wrap(err, "recovery")
+	}
+	_, err = drWaitStatus(s, &compute.InstanceBackups, backupId, []string{api.INSTANCE_BACKUP_STATUS_READY}, time.Hour)
+	if err != nil {
+		return backupId, "", errors.Wrap(err, "wait instance backup recovery")
+	}
+	meta, err := compute.InstanceBackups.GetMetadata(s, backupId, nil)
+	if err != nil {
+		return backupId, "", errors.Wrap(err, "GetMetadata")
+	}
+	guestId, _ := meta.GetString(api.INSTANCE_BACKUP_METADATA_RECOVERED_GUEST_ID)
+	if len(guestId) == 0 {
+		return backupId, "", errors.Errorf("instance backup %s records no recovered server", backupId)
+	}
+	obj, err := drWaitStatus(s, &compute.Servers, guestId, []string{api.VM_READY, api.VM_RUNNING}, time.Hour*6)
+	if err != nil {
+		return backupId, guestId, errors.Wrap(err, "wait server created")
+	}
+	if status, _ := obj.GetString("status"); status == api.VM_READY {
+		_, err = compute.Servers.PerformAction(s, guestId, "start", nil)
+		if err != nil {
+			return backupId, guestId, errors.Wrap(err, "start server")
+		}
+	}
+	return backupId, guestId, nil
+}
+
+// drServerNetworks returns the networks recorded in the server config of an
+// instance backup
+func drServerNetworks(serverConfig jsonutils.JSONObject) ([]*api.NetworkConfig, error) {
+	if serverConfig == nil {
+		return nil, errors.Wrap(httperrors.ErrNotFound, "no server config")
+	}
+	input := api.ServerCreateInput{}
+	err := serverConfig.Unmarshal(&input)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal server config")
+	}
+	if input.ServerConfigs == nil {
+		return nil, nil
+	}
+	return input.Networks, nil
+}
+
+// FailoverMembers rebuilds every member guest in the target region from its
+// latest recovery point taken before the given time. Guests rebuilt by test
+// failover attach to the isolated test network and leave the protected
+// guests untouched, a real failover stops the protected guests still up.
+func (group *SDrProtectionGroup) FailoverMembers(ctx context.Context, userCred mcclient.TokenCredential, test bool, before time.Time) error {
+	members, err := group.GetMembers()
+	if err != nil {
+		return errors.Wrap(err, "GetMembers")
+	}
+	mappings := api.DrNetworkMappings{}
+	if group.NetworkMappings != nil {
+		mappings = *group.NetworkMappings
+	}
+	testNetworkId := ""
+	if test {
+		testNetworkId = group.TestNetworkId
+	}
+	s := auth.GetAdminSession(ctx, group.TargetRegion)
+	for i := range members {
+		member := &members[i]
+		if (test && len(member.TestGuestId) > 0) || (!test && len(member.RemoteGuestId) > 0) {
+			// rebuilt by a previous attempt
+			continue
+		}
+		rp, err := DrRecoveryPointManager.GetLatestRecoveryPoint(group.Id, member.GuestId, before)
+		if err != nil {
+			return errors.Wrapf(err, "no recovery point for server %s", member.GuestId)
+		}
+		ib, err := rp.GetInstanceBackup()
+		if err != nil {
+			return errors.Wrapf(err, "instance backup of recovery point %s", rp.Name)
+		}
+		nets, err := drServerNetworks(ib.ServerConfig)
+		if err != nil {
+			return errors.Wrapf(err, "networks of recovery point %s", rp.Name)
+		}
+		nets, err = drRemapNetworks(nets, mappings, testNetworkId)
+		if err != nil {
+			return errors.Wrapf(err, "recovery point %s", rp.Name)
+		}
+		name, _ := ib.ServerConfig.GetString("name")
+		if test {
+			name = fmt.Sprintf("%s-drtest", name)
+		} else {
+			// the failover task stops the protected guests beforehand, never
+			// let both copies run
+			guest, err := member.getGuestToStop()
+			if err != nil {
+				return err
+			}
+			if guest != nil {
+				return httperrors.NewInvalidStatusError("server %s is still running", guest.Name)
+			}
+		}
+		backupId, guestId, err := drRecoverGuest(s, group.TargetBackupStorageId, rp.PackageName, name, ib.ProjectId, nets)
+		_, uerr := db.Update(member, func() error {
+			if test {
+				member.TestBackupId = backupId
+				member.TestGuestId = guestId
+			} else {
+				member.RemoteBackupId = backupId
+				member.RemoteGuestId = guestId
+			}
+			return nil
+		})
+		if uerr != nil {
+			return errors.Wrap(uerr, "update member")
+		}
+		if err != nil {
+			return errors.Wrapf(err, "recover server %s from recovery point %s", member.GuestId, rp.Name)
+		}
+	}
+	return nil
+}
+
+func drDeleteRemote(s *mcclient.ClientSession, manager modulebase.Manager, id string) error {
+	if len(id) == 0 {
+		return nil
+	}
+	params := jsonutils.NewDict()
+	params.Set("override_pending_delete", jsonutils.JSONTrue)
+	_, err := manager.Delete(s, id, params)
+	if err != nil && httputils.ErrorCode(err) != 404 {
+		return errors.Wrapf(err, "delete %s %s", manager.GetKeyword(), id)
+	}
+	return nil
+}
+
+// CleanupTestMembers deletes the guests and backups created by test failover
+// in the target region
+func (group *SDrProtectionGroup) CleanupTestMembers(ctx context.Context, userCred mcclient.TokenCredential) error {
+	members, err := group.GetMembers()
+	if err != nil {
+		return errors.Wrap(err, "GetMembers")
+	}
+	s := auth.GetAdminSession(ctx, group.TargetRegion)
+	for i := range members {
+		member := &members[i]
+		err := drDeleteRemote(s, &compute.Servers, member.TestGuestId)
+		if err != nil {
+			return err
+		}
+		err = drDeleteRemote(s, &compute.InstanceBackups, member.TestBackupId)
+		if err != nil {
+			return err
+		}
+		_, err = db.Update(member, func() error {
+			member.TestGuestId = ""
+			member.TestBackupId = ""
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "update member")
+		}
+	}
+	return nil
+}
+
+// FailbackMembers moves the failed over guests back into this region. Each
+// guest is stopped in the target region, backed up and packed onto the
+// shared backup storage, and rebuilt here with the reversed network
+// mappings. The rebuilt guest becomes the protected member and its imported
+// backup its first recovery point, the stopped guest in the target region is
+// left for the operator to remove.
+func (group *SDrProtectionGroup) FailbackMembers(ctx context.Context, userCred mcclient.TokenCredential) error {
+	members, err := group.GetMembers()
+	if err != nil {
+		return errors.Wrap(err, "GetMembers")
+	}
+	mappings := api.DrNetworkMappings{}
+	if group.NetworkMappings != nil {
+		mappings = group.NetworkMappings.Reverse()
+	}
+	remote := auth.GetAdminSession(ctx, group.TargetRegion)
+	local := auth.GetAdminSession(ctx, options.Options.Region)
+	for i := range members {
+		member := &members[i]
+		if len(member.RemoteGuestId) == 0 {
+			continue
+		}
+		obj, err := compute.Servers.Get(remote, member.RemoteGuestId, nil)
+		if err != nil {
+			return errors.Wrapf(err, "get server %s", member.RemoteGuestId)
+		}
+		name, _ := obj.GetString("name")
+		projectId, _ := obj.GetString("tenant_id")
+		if status, _ := obj.GetString("status"); status != api.VM_READY {
+			_, err = compute.Servers.PerformAction(remote, member.RemoteGuestId, "stop", nil)
+			if err != nil {
+				return errors.Wrapf(err, "stop server %s", name)
+			}
+			_, err = drWaitStatus(remote, &compute.Servers, member.RemoteGuestId, []string{api.VM_READY}, time.Hour)
+			if err != nil {
+				return errors.Wrapf(err, "wait server %s stopped", name)
+			}
+		}
+
+		backupName := fmt.Sprintf("%s-failback-%s", name, time.Now().Format("20060102150405"))
+		params := jsonutils.NewDict()
+		params.Set("name", jsonutils.NewString(backupName))
+		params.Set("backup_storage_id", jsonutils.NewString(group.TargetBackupStorageId))
+		_, err = compute.Servers.PerformAction(remote, member.RemoteGuestId, "instance-backup", params)
+		if err != nil {
+			return errors.Wrapf(err, "backup server %s", name)
+		}
+		// instance-backup does not return the backup, find it by name
+		listParams := jsonutils.NewDict()
+		listParams.Set("scope", jsonutils.NewString("system"))
+		backup, err := compute.InstanceBackups.Get(remote, backupName, listParams)
+		if err != nil {
+			return errors.Wrapf(err, "get instance backup %s", backupName)
+		}
+		backupId, _ := backup.GetString("id")
+		backup, err = drWaitStatus(remote, &compute.InstanceBackups, backupId, []string{api.INSTANCE_BACKUP_STATUS_READY}, time.Hour*6)
+		if err != nil {
+			return errors.Wrapf(err, "wait instance backup %s", backupName)
+		}
+		params = jsonutils.NewDict()
+		params.Set("package_name", jsonutils.NewString(backupId))
+		_, err = compute.InstanceBackups.PerformAction(remote, backupId, "pack", params)
+		if err != nil {
+			return errors.Wrapf(err, "pack instance backup %s", backupName)
+		}
+		_, err = drWaitStatus(remote, &compute.InstanceBackups, backupId, []string{api.INSTANCE_BACKUP_STATUS_READY}, time.Hour*6)
+		if err != nil {
+			return errors.Wrapf(err, "wait instance backup %s packed", backupName)
+		}
+
+		serverConfig, _ := backup.Get("server_config")
+		nets, err := drServerNetworks(serverConfig)
+		if err != nil {
+			return errors.Wrapf(err, "networks of instance backup %s", backupName)
+		}
+		nets, err = drRemapNetworks(nets, mappings, "")
+		if err != nil {
+			return errors.Wrapf(err, "instance backup %s", backupName)
+		}
+		packageName := backupId + ".tar"
+		localBackupId, guestId, err := drRecoverGuest(local, group.BackupStorageId, packageName, name, projectId, nets)
+		if err != nil {
+			return errors.Wrapf(err, "recover server %s", name)
+		}
+		_, err = DrRecoveryPointManager.createRecoveryPoint(ctx, group, guestId, localBackupId, packageName, api.DR_RECOVERY_POINT_STATUS_READY)
+		if err != nil {
+			log.Errorf("record failback backup %s as recovery point: %s", localBackupId, err)
+		}
+		err = drDeleteRemote(remote, &compute.InstanceBackups, backupId)
+		if err != nil {
+			log.Errorf("%s", err)
+		}
+		err = drDeleteRemote(remote, &compute.InstanceBackups, member.RemoteBackupId)
+		if err != nil {
+			log.Errorf("%s", err)
+		}
+		_, err = db.Update(member, func() error {
+			member.GuestId = guestId
+			member.RemoteGuestId = ""
+			member.RemoteBackupId = ""
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "update member")
+		}
+	}
+	return nil
+}
+
+// takeRecoveryPoints starts a recovery point for every member guest whose
+// latest one is older than RpoMinutes, or for all members when forced
+func (group *SDrProtectionGroup) takeRecoveryPoints(ctx context.Context, userCred mcclient.TokenCredential, force bool) error {
+	lockman.LockObject(ctx, group)
+	defer lockman.ReleaseObject(ctx, group)
+
+	members, err := group.GetMembers()
+	if err != nil {
+		return errors.Wrap(err, "GetMembers")
+	}
+	now := time.Now().UTC()
+	for i := range members {
+		guest := GuestManager.FetchGuestById(members[i].GuestId)
+		if guest == nil || !utils.IsInStringArray(guest.Status, []string{api.VM_RUNNING, api.VM_READY}) {
+			continue
+		}
+		pending, err := DrRecoveryPointManager.Query().Equals("group_id", group.Id).Equals("guest_id", guest.Id).
+			In("status", []string{api.DR_RECOVERY_POINT_STATUS_CREATING, api.DR_RECOVERY_POINT_STATUS_PACKING}).CountWithError()
+		if err != nil {
+			return errors.Wrap(err, "count pending recovery points")
+		}
+		if pending > 0 {
+			continue
+		}
+		if !force {
+			last, err := DrRecoveryPointManager.GetLatestRecoveryPoint(group.Id, guest.Id, time.Time{})
+			if err == nil && now.Sub(last.CreatedAt) < time.Duration(group.RpoMinutes)*time.Minute {
+				continue
+			}
+		}
+		rp, err := DrRecoveryPointManager.createRecoveryPoint(ctx, group, guest.Id, "", "", api.DR_RECOVERY_POINT_STATUS_CREATING)
+		if err != nil {
+			return errors.Wrapf(err, "create recovery point of server %s", guest.Name)
+		}
+		err = rp.StartCreateTask(ctx, userCred, "")
+		if err != nil {
+			log.Errorf("start creating recovery point %s: %s", rp.Name, err)
+		}
+	}
+	return nil
+}
+
+func (manager *SDrProtectionGroupManager) SyncDrProtectionGroups(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	q := manager.Query().Equals("status", api.DR_PROTECTION_GROUP_STATUS_PROTECTING).IsTrue("enabled")
+	groups := make([]SDrProtectionGroup, 0)
+	err := db.FetchModelObjects(manager, q, &groups)
+	if err != nil {
+		log.Errorf("fetch dr protection groups: %s", err)
+		return
+	}
+	for i := range groups {
+		err := groups[i].takeRecoveryPoints(ctx, userCred, false)
+		if err != nil {
+			log.Errorf("take recovery points of dr protection group %s: %s", groups[i].Name, err)
+		}
+	}
+}
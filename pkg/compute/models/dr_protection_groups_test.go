@@ -0,0 +1,157 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"strings"
+	"testing"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestDrRemapNetworks(t *testing.T) {
+	nets := []*api.NetworkConfig{
+		{Index: 0, Network: "src-a", Mac: "00:22:00:00:00:01", Address: "10.0.0.2", Driver: "virtio"},
+		{Index: 1, Network: "src-b"},
+	}
+	mappings := api.DrNetworkMappings{
+		{SourceNetworkId: "src-a", TargetNetworkId: "dst-a"},
+		{SourceNetworkId: "src-b", TargetNetworkId: "dst-b"},
+	}
+	cases := []struct {
+		name     string
+		nets     []*api.NetworkConfig
+		mappings api.DrNetworkMappings
+		test     string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "mapped",
+			nets:     nets,
+			mappings: mappings,
+			want:     []string{"dst-a", "dst-b"},
+		},
+		{
+			name:     "test network",
+			nets:     nets,
+			mappings: mappings,
+			test:     "isolated",
+			want:     []string{"isolated", "isolated"},
+		},
+		{
+			name:     "reversed",
+			nets:     []*api.NetworkConfig{{Network: "dst-b"}},
+			mappings: mappings.Reverse(),
+			want:     []string{"src-b"},
+		},
+		{
+			name:     "unmapped",
+			nets:     nets,
+			mappings: mappings[:1],
+			wantErr:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := drRemapNetworks(c.nets, c.mappings, c.test)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %d networks", len(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("drRemapNetworks: %s", err)
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("want %d networks, got %d", len(c.want), len(got))
+			}
+			for i := range got {
+				if got[i].Network != c.want[i] {
+					t.Errorf("nic %d: want network %s, got %s", i, c.want[i], got[i].Network)
+				}
+				if got[i].Index != c.nets[i].Index || got[i].Driver != c.nets[i].Driver {
+					t.Errorf("nic %d: index or driver not kept", i)
+				}
+				if len(got[i].Mac) > 0 || len(got[i].Address) > 0 {
+					t.Errorf("nic %d: mac and address of the source network must be dropped", i)
+				}
+			}
+		})
+	}
+}
+
+func TestDrPrunableRecoveryPoints(t *testing.T) {
+	rp := func(id, parentId, status string) SDrRecoveryPoint {
+		ret := SDrRecoveryPoint{ParentId: parentId}
+		ret.Id = id
+		ret.Status = status
+		return ret
+	}
+	const (
+		ready   = api.DR_RECOVERY_POINT_STATUS_READY
+		failed  = api.DR_RECOVERY_POINT_STATUS_CREATE_FAILED
+		packing = api.DR_RECOVERY_POINT_STATUS_PACKING
+	)
+	cases := []struct {
+		name   string
+		rps    []SDrRecoveryPoint
+		retain int
+		want   []string
+	}{
+		{
+			name:   "full copies",
+			rps:    []SDrRecoveryPoint{rp("c", "", ready), rp("b", "", ready), rp("a", "", ready)},
+			retain: 2,
+			want:   []string{"a"},
+		},
+		{
+			name:   "keep chain of kept",
+			rps:    []SDrRecoveryPoint{rp("d", "c", ready), rp("c", "b", ready), rp("b", "a", ready), rp("a", "", ready), rp("z", "", ready)},
+			retain: 1,
+			want:   []string{"z"},
+		},
+		{
+			name:   "keep chain of pending",
+			rps:    []SDrRecoveryPoint{rp("c", "b", packing), rp("b", "", ready), rp("a", "", ready)},
+			retain: 0,
+			want:   []string{"a"},
+		},
+		{
+			name:   "failed",
+			rps:    []SDrRecoveryPoint{rp("c", "b", failed), rp("b", "", ready), rp("a", "", ready)},
+			retain: 1,
+			want:   []string{"c", "a"},
+		},
+		{
+			name:   "new full copy",
+			rps:    []SDrRecoveryPoint{rp("c", "", ready), rp("b", "a", ready), rp("a", "", ready)},
+			retain: 1,
+			want:   []string{"b", "a"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := []string{}
+			for _, i := range drPrunableRecoveryPoints(c.rps, c.retain) {
+				got = append(got, c.rps[i].Id)
+			}
+			if strings.Join(got, ",") != strings.Join(c.want, ",") {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
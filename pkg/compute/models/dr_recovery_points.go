@@ -0,0 +1,580 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/httputils"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+// +onecloud:swagger-gen-model-singular=dr_recovery_point
+// +onecloud:swagger-gen-model-plural=dr_recovery_points
+type SDrRecoveryPointManager struct {
+	db.SStatusStandaloneResourceBaseManager
+}
+
+var DrRecoveryPointManager *SDrRecoveryPointManager
+
+func init() {
+	DrRecoveryPointManager = &SDrRecoveryPointManager{
+		SStatusStandaloneResourceBaseManager: db.NewStatusStandaloneResourceBaseManager(
+			SDrRecoveryPoint{},
+			"dr_recovery_points_tbl",
+			"dr_recovery_point",
+			"dr_recovery_points",
+		),
+	}
+	DrRecoveryPointManager.SetVirtualObject(DrRecoveryPointManager)
+}
+
+// SDrRecoveryPoint is an instance backup of a protected guest packed onto
+// the backup storage of its group, the package is what the target region
+// rebuilds the guest from. The package of an incremental recovery point
+// only holds what was written after its parent, the target region flattens
+// the chain of packages when it unpacks one
+type SDrRecoveryPoint struct {
+	db.SStatusStandaloneResourceBase
+
+	// 所属保护组
+	GroupId string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	// 受保护的虚拟机
+	GuestId string `width:"36" charset:"ascii" nullable:"false" list:"admin" index:"true"`
+	// 主机备份
+	InstanceBackupId string `width:"36" charset:"ascii" nullable:"true" list:"admin"`
+	// 备份存储上的备份包
+	PackageName string `width:"64" charset:"ascii" nullable:"true" list:"admin"`
+	// 增量恢复点所基于的恢复点, 为空表示全量
+	ParentId string `width:"36" charset:"ascii" nullable:"true" list:"admin"`
+}
+
+func (manager *SDrRecoveryPointManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return input, httperrors.NewUnsupportOperationError("recovery points are taken by dr protection groups")
+}
+
+func (manager *SDrRecoveryPointManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DrRecoveryPointListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SStatusStandaloneResourceBaseManager.ListItemFilter(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.ListItemFilter")
+	}
+	if len(query.DrProtectionGroupId) > 0 {
+		groupObj, err := validators.ValidateModel(ctx, userCred, DrProtectionGroupManager, &query.DrProtectionGroupId)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Equals("group_id", groupObj.GetId())
+	}
+	if len(query.GuestId) > 0 {
+		q = q.In("guest_id", query.GuestId)
+	}
+	return q, nil
+}
+
+func (manager *SDrRecoveryPointManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.DrRecoveryPointListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SStatusStandaloneResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.StatusStandaloneResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SStatusStandaloneResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SDrRecoveryPointManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SStatusStandaloneResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SDrRecoveryPointManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.DrRecoveryPointDetails {
+	rows := make([]api.DrRecoveryPointDetails, len(objs))
+	stdRows := manager.SStatusStandaloneResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	groupIds := make([]string, len(objs))
+	guestIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i].StatusStandaloneResourceDetails = stdRows[i]
+		rp := objs[i].(*SDrRecoveryPoint)
+		groupIds[i] = rp.GroupId
+		guestIds[i] = rp.GuestId
+	}
+	groups, _ := db.FetchIdNameMap2(DrProtectionGroupManager, groupIds)
+	guests, _ := db.FetchIdNameMap2(GuestManager, guestIds)
+	for i := range rows {
+		rp := objs[i].(*SDrRecoveryPoint)
+		rows[i].DrProtectionGroup = groups[rp.GroupId]
+		rows[i].Guest = guests[rp.GuestId]
+	}
+	return rows
+}
+
+func (rp *SDrRecoveryPoint) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
+	if utils.IsInStringArray(rp.Status, []string{api.DR_RECOVERY_POINT_STATUS_CREATING, api.DR_RECOVERY_POINT_STATUS_PACKING, api.DR_RECOVERY_POINT_STATUS_DELETING}) {
+		return httperrors.NewInvalidStatusError("Cannot delete recovery point in status %s", rp.Status)
+	}
+	children, err := DrRecoveryPointManager.Query().Equals("parent_id", rp.Id).CountWithError()
+	if err != nil {
+		return errors.Wrap(err, "count incremental recovery points")
+	}
+	if children > 0 {
+		return httperrors.NewNotEmptyError("%d incremental recovery points are based on recovery point %s", children, rp.Name)
+	}
+	return rp.SStatusStandaloneResourceBase.ValidateDeleteCondition(ctx, nil)
+}
+
+func (rp *SDrRecoveryPoint) Delete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return nil
+}
+
+func (rp *SDrRecoveryPoint) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	return rp.SStatusStandaloneResourceBase.Delete(ctx, userCred)
+}
+
+func (rp *SDrRecoveryPoint) CustomizeDelete(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, data jsonutils.JSONObject) error {
+	return rp.StartDeleteTask(ctx, userCred, "")
+}
+
+func (rp *SDrRecoveryPoint) StartDeleteTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "DrRecoveryPointDeleteTask", rp, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrapf(err, "NewTask")
+	}
+	rp.SetStatus(ctx, userCred, api.DR_RECOVERY_POINT_STATUS_DELETING, "")
+	return task.ScheduleRun(nil)
+}
+
+func (rp *SDrRecoveryPoint) StartCreateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "DrRecoveryPointCreateTask", rp, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		rp.SetStatus(ctx, userCred, api.DR_RECOVERY_POINT_STATUS_CREATE_FAILED, err.Error())
+		return errors.Wrapf(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+func (rp *SDrRecoveryPoint) GetGroup() (*SDrProtectionGroup, error) {
+	group, err := DrProtectionGroupManager.FetchById(rp.GroupId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "DrProtectionGroupManager.FetchById(%s)", rp.GroupId)
+	}
+	return group.(*SDrProtectionGroup), nil
+}
+
+func (rp *SDrRecoveryPoint) GetInstanceBackup() (*SInstanceBackup, error) {
+	ib, err := InstanceBackupManager.FetchById(rp.InstanceBackupId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "InstanceBackupManager.FetchById(%s)", rp.InstanceBackupId)
+	}
+	return ib.(*SInstanceBackup), nil
+}
+
+// drIncrementalDiskIds returns the disks of the guest backed up
+// incrementally, their snapshots are kept as the base of the next backup.
+// Only unencrypted disks on local storage have the snapshot overlay as a
+// plain qcow2 file the host can ship alone
+func drIncrementalDiskIds(guest *SGuest) ([]string, error) {
+	disks, err := guest.GetDisks()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetDisks")
+	}
+	ret := []string{}
+	for i := range disks {
+		storage, err := disks[i].GetStorage()
+		if err != nil {
+			return nil, errors.Wrapf(err, "storage of disk %s", disks[i].Name)
+		}
+		if storage.StorageType == api.STORAGE_LOCAL && len(disks[i].EncryptKeyId) == 0 {
+			ret = append(ret, disks[i].Id)
+		}
+	}
+	return ret, nil
+}
+
+// chainDepth returns the number of incremental recovery points up to the
+// full one rp is based on
+func (rp *SDrRecoveryPoint) chainDepth() (int, error) {
+	depth := 0
+	for cur := rp; len(cur.ParentId) > 0; depth++ {
+		parent, err := DrRecoveryPointManager.FetchById(cur.ParentId)
+		if err != nil {
+			return 0, errors.Wrapf(err, "fetch parent recovery point %s", cur.ParentId)
+		}
+		cur = parent.(*SDrRecoveryPoint)
+	}
+	return depth, nil
+}
+
+// GetIncrementalParent returns the recovery point rp can be taken
+// incrementally on, along with the backups of its disks by disk id. It is
+// the latest ready one of the guest when its disks are the same, its
+// snapshots are still kept and the chain is shorter than FullInterval,
+// otherwise nil is returned and rp is a full copy
+func (rp *SDrRecoveryPoint) GetIncrementalParent() (*SDrRecoveryPoint, map[string]string, error) {
+	group, err := rp.GetGroup()
+	if err != nil {
+		return nil, nil, err
+	}
+	guest := GuestManager.FetchGuestById(rp.GuestId)
+	if guest == nil {
+		return nil, nil, errors.Wrapf(httperrors.ErrNotFound, "server %s", rp.GuestId)
+	}
+	last, err := DrRecoveryPointManager.GetLatestRecoveryPoint(group.Id, guest.Id, time.Time{})
+	if err != nil {
+		if errors.Cause(err) == httperrors.ErrNotFound {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	depth, err := last.chainDepth()
+	if err != nil {
+		return nil, nil, err
+	}
+	if depth >= group.FullInterval {
+		return nil, nil, nil
+	}
+	ib, err := last.GetInstanceBackup()
+	if err != nil {
+		return nil, nil, err
+	}
+	backups, err := ib.GetBackups()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "GetBackups")
+	}
+	guestDisks, err := guest.GetGuestDisks()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "GetGuestDisks")
+	}
+	// disks are matched by index in the packages
+	if len(backups) != len(guestDisks) {
+		return nil, nil, nil
+	}
+	diskIds, err := drIncrementalDiskIds(guest)
+	if err != nil {
+		return nil, nil, err
+	}
+	parentBackupIds := map[string]string{}
+	for i := range backups {
+		if backups[i].DiskId != guestDisks[i].DiskId {
+			return nil, nil, nil
+		}
+		if !utils.IsInStringArray(backups[i].DiskId, diskIds) {
+			continue
+		}
+		if snapshot := backups[i].GetBaseSnapshot(); snapshot != nil && snapshot.Status == api.SNAPSHOT_READY {
+			parentBackupIds[backups[i].DiskId] = backups[i].Id
+		}
+	}
+	if len(parentBackupIds) == 0 {
+		return nil, nil, nil
+	}
+	return last, parentBackupIds, nil
+}
+
+// CreateInstanceBackup backs up the guest to the backup storage of the
+// group, the backup task reports to the given task. The disks with a
+// backup in parentBackupIds are backed up incrementally on it
+func (rp *SDrRecoveryPoint) CreateInstanceBackup(ctx context.Context, task taskman.ITask, parentBackupIds map[string]string) error {
+	userCred := task.GetUserCred()
+	group, err := rp.GetGroup()
+	if err != nil {
+		return err
+	}
+	guest := GuestManager.FetchGuestById(rp.GuestId)
+	if guest == nil {
+		return errors.Wrapf(httperrors.ErrNotFound, "server %s", rp.GuestId)
+	}
+	input, err := guest.validateCreateInstanceBackup(ctx, userCred, nil, api.ServerCreateInstanceBackupInput{Name: rp.Name})
+	if err != nil {
+		return err
+	}
+	ib, err := InstanceBackupManager.CreateInstanceBackup(ctx, userCred, guest, input.Name, group.BackupStorageId)
+	if err != nil {
+		return errors.Wrap(err, "CreateInstanceBackup")
+	}
+	err = guest.InheritTo(ctx, userCred, ib)
+	if err != nil {
+		return errors.Wrapf(err, "unable to inherit from guest %s to instance backup %s", guest.Id, ib.Id)
+	}
+	_, err = db.Update(rp, func() error {
+		rp.InstanceBackupId = ib.Id
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update recovery point")
+	}
+	diskIds, err := drIncrementalDiskIds(guest)
+	if err != nil {
+		return err
+	}
+	params := jsonutils.NewDict()
+	params.Set("keep_snapshot_disk_ids", jsonutils.NewStringArray(diskIds))
+	if len(parentBackupIds) > 0 {
+		params.Set("parent_backup_ids", jsonutils.Marshal(parentBackupIds))
+	}
+	guest.SetStatus(ctx, userCred, api.VM_START_INSTANCE_BACKUP, "dr recovery point")
+	return ib.StartCreateInstanceBackupTask(ctx, userCred, params, task.GetTaskId())
+}
+
+// PackInstanceBackup packs the backup onto the backup storage as
+// <recovery point id>.tar, on top of the package of parent when some disk
+// was backed up incrementally
+func (rp *SDrRecoveryPoint) PackInstanceBackup(ctx context.Context, task taskman.ITask, parent *SDrRecoveryPoint) error {
+	ib, err := rp.GetInstanceBackup()
+	if err != nil {
+		return err
+	}
+	parentPackageName := ""
+	if parent != nil {
+		incremental, err := ib.isIncremental()
+		if err != nil {
+			return err
+		}
+		if incremental {
+			parentPackageName = parent.PackageName
+		} else {
+			parent = nil
+		}
+	}
+	_, err = db.Update(rp, func() error {
+		rp.PackageName = rp.Id + ".tar"
+		if parent != nil {
+			rp.ParentId = parent.Id
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update recovery point")
+	}
+	rp.SetStatus(ctx, task.GetUserCred(), api.DR_RECOVERY_POINT_STATUS_PACKING, "")
+	return ib.StartPackTask(ctx, task.GetUserCred(), task.GetTaskId(), rp.Id, parentPackageName)
+}
+
+// ReleaseBaseSnapshots deletes the snapshots kept on the disks by the
+// recovery points of the guest older than rp, the ones of rp are the base
+// of the next incremental backup from now on
+func (rp *SDrRecoveryPoint) ReleaseBaseSnapshots(ctx context.Context, userCred mcclient.TokenCredential) error {
+	rps, err := DrRecoveryPointManager.GetRecoveryPoints(rp.GroupId, rp.GuestId)
+	if err != nil {
+		return err
+	}
+	for i := range rps {
+		if rps[i].Id == rp.Id || rps[i].CreatedAt.After(rp.CreatedAt) {
+			continue
+		}
+		ib, err := rps[i].GetInstanceBackup()
+		if err != nil {
+			continue
+		}
+		backups, err := ib.GetBackups()
+		if err != nil {
+			return errors.Wrapf(err, "backups of recovery point %s", rps[i].Name)
+		}
+		for j := range backups {
+			backup := &backups[j]
+			if len(backup.BaseSnapshotId) == 0 {
+				continue
+			}
+			snapshot := backup.GetBaseSnapshot()
+			_, err := db.Update(backup, func() error {
+				backup.BaseSnapshotId = ""
+				return nil
+			})
+			if err != nil {
+				return errors.Wrapf(err, "update backup %s", backup.Name)
+			}
+			if snapshot == nil || snapshot.Status == api.SNAPSHOT_DELETING {
+				continue
+			}
+			err = snapshot.StartSnapshotDeleteTask(ctx, userCred, false, "", 0, 0)
+			if err != nil {
+				return errors.Wrapf(err, "delete snapshot %s", snapshot.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// RequestDeletePackage removes the package from the backup storage, the
+// host calls back to the task when done
+func (rp *SDrRecoveryPoint) RequestDeletePackage(ctx context.Context, task taskman.ITask) error {
+	group, err := rp.GetGroup()
+	if err != nil {
+		return err
+	}
+	backupStorage, err := group.GetBackupStorage()
+	if err != nil {
+		return err
+	}
+	host, err := HostManager.GetEnabledKvmHostForBackupStorage(backupStorage)
+	if err != nil {
+		return errors.Wrap(err, "GetEnabledKvmHostForBackupStorage")
+	}
+	accessInfo, err := backupStorage.GetAccessInfo()
+	if err != nil {
+		return errors.Wrap(err, "GetAccessInfo")
+	}
+	body := jsonutils.NewDict()
+	body.Set("package_name", jsonutils.NewString(rp.PackageName))
+	body.Set("backup_storage_id", jsonutils.NewString(backupStorage.Id))
+	body.Set("backup_storage_access_info", jsonutils.Marshal(accessInfo))
+	url := fmt.Sprintf("%s/storages/delete-instance-backup-package", host.ManagerUri)
+	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, task.GetTaskRequestHeader(), body, false)
+	return err
+}
+
+func (manager *SDrRecoveryPointManager) createRecoveryPoint(ctx context.Context, group *SDrProtectionGroup, guestId, instanceBackupId, packageName, status string) (*SDrRecoveryPoint, error) {
+	guest := GuestManager.FetchGuestById(guestId)
+	if guest == nil {
+		return nil, errors.Wrapf(httperrors.ErrNotFound, "server %s", guestId)
+	}
+	rp := &SDrRecoveryPoint{
+		GroupId:          group.Id,
+		GuestId:          guest.Id,
+		InstanceBackupId: instanceBackupId,
+		PackageName:      packageName,
+	}
+	rp.Name = fmt.Sprintf("%s-%s", guest.Name, time.Now().UTC().Format("20060102150405"))
+	rp.Status = status
+	rp.SetModelManager(manager, rp)
+	err := manager.TableSpec().Insert(ctx, rp)
+	if err != nil {
+		return nil, errors.Wrap(err, "Insert")
+	}
+	return rp, nil
+}
+
+func (manager *SDrRecoveryPointManager) GetRecoveryPoints(groupId, guestId string) ([]SDrRecoveryPoint, error) {
+	q := manager.Query().Equals("group_id", groupId).Equals("guest_id", guestId).Desc("created_at")
+	rps := make([]SDrRecoveryPoint, 0)
+	err := db.FetchModelObjects(manager, q, &rps)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return rps, nil
+}
+
+// GetLatestRecoveryPoint returns the latest ready recovery point of the
+// guest created before the given time, a zero time means now
+func (manager *SDrRecoveryPointManager) GetLatestRecoveryPoint(groupId, guestId string, before time.Time) (*SDrRecoveryPoint, error) {
+	q := manager.Query().Equals("group_id", groupId).Equals("guest_id", guestId).
+		Equals("status", api.DR_RECOVERY_POINT_STATUS_READY).Desc("created_at")
+	if !before.IsZero() {
+		q = q.LE("created_at", before)
+	}
+	rp := &SDrRecoveryPoint{}
+	rp.SetModelManager(manager, rp)
+	err := q.First(rp)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, errors.Wrapf(httperrors.ErrNotFound, "no ready recovery point")
+		}
+		return nil, errors.Wrap(err, "First")
+	}
+	return rp, nil
+}
+
+// PruneRecoveryPoints keeps the latest RetainCount ready recovery points of
+// the guest along with the ones they are based on, and deletes the older
+// and the failed ones
+func (group *SDrProtectionGroup) PruneRecoveryPoints(ctx context.Context, userCred mcclient.TokenCredential, guestId string) error {
+	rps, err := DrRecoveryPointManager.GetRecoveryPoints(group.Id, guestId)
+	if err != nil {
+		return err
+	}
+	for _, i := range drPrunableRecoveryPoints(rps, group.RetainCount) {
+		err := rps[i].StartDeleteTask(ctx, userCred, "")
+		if err != nil {
+			return errors.Wrapf(err, "delete recovery point %s", rps[i].Name)
+		}
+	}
+	return nil
+}
+
+// drPrunableRecoveryPoints returns the indexes of the recovery points to
+// delete out of rps sorted from the latest, the ready ones beyond the
+// latest retainCount and the failed ones. Recovery points a kept or a
+// pending one is based on are never deleted
+func drPrunableRecoveryPoints(rps []SDrRecoveryPoint, retainCount int) []int {
+	byId := map[string]*SDrRecoveryPoint{}
+	for i := range rps {
+		byId[rps[i].Id] = &rps[i]
+	}
+	based := map[string]bool{}
+	ready := 0
+	for i := range rps {
+		switch rps[i].Status {
+		case api.DR_RECOVERY_POINT_STATUS_READY:
+			ready++
+			if ready > retainCount {
+				continue
+			}
+		case api.DR_RECOVERY_POINT_STATUS_CREATE_FAILED, api.DR_RECOVERY_POINT_STATUS_DELETING:
+			continue
+		}
+		for cur := byId[rps[i].ParentId]; cur != nil && !based[cur.Id]; cur = byId[cur.ParentId] {
+			based[cur.Id] = true
+		}
+	}
+	ret := []int{}
+	ready = 0
+	for i := range rps {
+		switch rps[i].Status {
+		case api.DR_RECOVERY_POINT_STATUS_READY:
+			ready++
+			if ready <= retainCount || based[rps[i].Id] {
+				continue
+			}
+		case api.DR_RECOVERY_POINT_STATUS_CREATE_FAILED:
+		default:
+			continue
+		}
+		ret = append(ret, i)
+	}
+	return ret
+}
@@ -1705,7 +1705,7 @@ func (manager *SElasticipManager) NewEipForVMOnHost(ctx context.Context, userCre
 		panic("unsupported associate type")
 	}
 
-	if (host != nil && host.ManagerId == "") || grp != nil || lb != nil { // kvm
+	if (host != nil && host.ManagerId == "") || grp != nil || lb != nil || (nat != nil && eip.ManagerId == "") { // kvm
 		q := NetworkManager.Query()
 
 		var zoneId string
@@ -1718,6 +1718,15 @@ func (manager *SElasticipManager) NewEipForVMOnHost(ctx context.Context, userCre
 		} else if lb != nil {
 			zone, _ := lb.GetZone()
 			zoneId = zone.Id
+		} else if nat != nil {
+			net, err := nat.GetNetwork()
+			if err != nil {
+				return nil, errors.Wrapf(err, "nat.GetNetwork")
+			}
+			zone, _ := net.GetZone()
+			if zone != nil {
+				zoneId = zone.Id
+			}
 		}
 
 		wireq := WireManager.Query().SubQuery()
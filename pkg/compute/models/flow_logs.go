@@ -0,0 +1,563 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/regutils"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/tsdb"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/util/influxdb"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SFlowLogManager struct {
+	db.SEnabledStatusInfrasResourceBaseManager
+	SVpcResourceBaseManager
+}
+
+var FlowLogManager *SFlowLogManager
+
+func init() {
+	FlowLogManager = &SFlowLogManager{
+		SEnabledStatusInfrasResourceBaseManager: db.NewEnabledStatusInfrasResourceBaseManager(
+			SFlowLog{},
+			"flow_logs_tbl",
+			"flow_log",
+			"flow_logs",
+		),
+	}
+	FlowLogManager.SetVirtualObject(FlowLogManager)
+}
+
+// SFlowLog records traffic of guest nics in an on-premise vpc.  The scope
+// narrows down to a network or a single nic when network_id or
+// guest_id/mac_addr are set
+type SFlowLog struct {
+	db.SEnabledStatusInfrasResourceBase
+
+	SVpcResourceBase
+
+	// 仅记录该网络内的流量
+	NetworkId string `width:"36" charset:"ascii" nullable:"true" list:"domain" create:"optional"`
+	// 仅记录该虚拟机网卡的流量
+	GuestId string `width:"36" charset:"ascii" nullable:"true" list:"domain" create:"optional"`
+	MacAddr string `width:"32" charset:"ascii" nullable:"true" list:"domain" create:"optional"`
+
+	// 记录的流量类型
+	TrafficType string `width:"16" charset:"ascii" nullable:"false" default:"all" list:"domain" create:"optional" update:"domain"`
+	// 每个网卡每秒最多记录的日志条数, 0表示不限制
+	LogRate int `nullable:"false" default:"100" list:"domain" create:"optional" update:"domain"`
+
+	// 日志投递目标
+	Target          string `width:"16" charset:"ascii" nullable:"false" default:"tsdb" list:"domain" create:"optional"`
+	BackupStorageId string `width:"36" charset:"ascii" nullable:"true" list:"domain" create:"optional"`
+	TargetPrefix    string `width:"128" charset:"utf8" nullable:"true" list:"domain" create:"optional"`
+}
+
+func (manager *SFlowLogManager) GetContextManagers() [][]db.IModelManager {
+	return [][]db.IModelManager{
+		{VpcManager},
+	}
+}
+
+func (manager *SFlowLogManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.FlowLogListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.ListItemFilter")
+	}
+	q, err = manager.SVpcResourceBaseManager.ListItemFilter(ctx, q, userCred, query.VpcFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVpcResourceBaseManager.ListItemFilter")
+	}
+	if len(query.NetworkId) > 0 {
+		q = q.In("network_id", query.NetworkId)
+	}
+	if len(query.ServerId) > 0 {
+		q = q.In("guest_id", query.ServerId)
+	}
+	if len(query.TrafficType) > 0 {
+		q = q.In("traffic_type", query.TrafficType)
+	}
+	if len(query.Target) > 0 {
+		q = q.In("target", query.Target)
+	}
+	return q, nil
+}
+
+func (manager *SFlowLogManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.FlowLogListInput,
+) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusInfrasResourceBaseListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.OrderByExtraFields")
+	}
+	q, err = manager.SVpcResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.VpcFilterListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SVpcResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SFlowLogManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	var err error
+	q, err = manager.SEnabledStatusInfrasResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	q, err = manager.SVpcResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func validateFlowLogTrafficType(trafficType string) error {
+	if !utils.IsInStringArray(trafficType, api.FLOW_LOG_TRAFFIC_TYPES) {
+		return httperrors.NewInputParameterError("invalid traffic_type %q, want one of %s", trafficType, strings.Join(api.FLOW_LOG_TRAFFIC_TYPES, ","))
+	}
+	return nil
+}
+
+func validateFlowLogRate(logRate *int) error {
+	if logRate != nil && *logRate < 0 {
+		return httperrors.NewInputParameterError("log_rate must not be negative")
+	}
+	return nil
+}
+
+func (manager *SFlowLogManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.FlowLogCreateInput,
+) (api.FlowLogCreateInput, error) {
+	var err error
+	input.EnabledStatusInfrasResourceBaseCreateInput, err = manager.SEnabledStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusInfrasResourceBaseCreateInput)
+	if err != nil {
+		return input, err
+	}
+
+	if len(input.VpcId) == 0 {
+		return input, httperrors.NewMissingParameterError("vpc_id")
+	}
+	vpcObj, err := VpcManager.FetchByIdOrName(ctx, userCred, input.VpcId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return input, httperrors.NewResourceNotFoundError2("vpc", input.VpcId)
+		}
+		return input, httperrors.NewGeneralError(err)
+	}
+	vpc := vpcObj.(*SVpc)
+	if vpc.ManagerId != "" || vpc.Id == api.DEFAULT_VPC_ID {
+		return input, httperrors.NewNotSupportedError("flow log is only supported for on-premise vpc")
+	}
+	input.VpcId = vpc.Id
+
+	if len(input.NetworkId) > 0 {
+		netObj, err := NetworkManager.FetchByIdOrName(ctx, userCred, input.NetworkId)
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				return input, httperrors.NewResourceNotFoundError2("network", input.NetworkId)
+			}
+			return input, httperrors.NewGeneralError(err)
+		}
+		network := netObj.(*SNetwork)
+		netVpc, err := network.GetVpc()
+		if err != nil {
+			return input, httperrors.NewGeneralError(errors.Wrapf(err, "GetVpc of network %s", network.Id))
+		}
+		if netVpc.Id != vpc.Id {
+			return input, httperrors.NewInputParameterError("network %s is not in vpc %s", network.Name, vpc.Name)
+		}
+		input.NetworkId = network.Id
+	}
+
+	if len(input.ServerId) > 0 {
+		if len(input.MacAddr) == 0 {
+			return input, httperrors.NewMissingParameterError("mac_addr")
+		}
+		guestObj, err := GuestManager.FetchByIdOrName(ctx, userCred, input.ServerId)
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				return input, httperrors.NewResourceNotFoundError2("server", input.ServerId)
+			}
+			return input, httperrors.NewGeneralError(err)
+		}
+		guest := guestObj.(*SGuest)
+		input.MacAddr = strings.ToLower(input.MacAddr)
+		gns, err := GuestnetworkManager.FetchByGuestId(guest.Id)
+		if err != nil {
+			return input, httperrors.NewGeneralError(errors.Wrapf(err, "FetchByGuestId %s", guest.Id))
+		}
+		var gn *SGuestnetwork
+		for i := range gns {
+			if gns[i].MacAddr == input.MacAddr {
+				gn = &gns[i]
+				break
+			}
+		}
+		if gn == nil {
+			return input, httperrors.NewInputParameterError("server %s has no nic with mac %s", guest.Name, input.MacAddr)
+		}
+		if input.NetworkId != "" && gn.NetworkId != input.NetworkId {
+			return input, httperrors.NewInputParameterError("nic %s of server %s is not in network %s", input.MacAddr, guest.Name, input.NetworkId)
+		}
+		network, err := gn.GetNetwork()
+		if err != nil {
+			return input, httperrors.NewGeneralError(errors.Wrapf(err, "GetNetwork of nic %s", input.MacAddr))
+		}
+		netVpc, err := network.GetVpc()
+		if err != nil {
+			return input, httperrors.NewGeneralError(errors.Wrapf(err, "GetVpc of network %s", network.Id))
+		}
+		if netVpc.Id != vpc.Id {
+			return input, httperrors.NewInputParameterError("nic %s of server %s is not in vpc %s", input.MacAddr, guest.Name, vpc.Name)
+		}
+		input.ServerId = guest.Id
+		input.GuestId = guest.Id
+		input.NetworkId = network.Id
+	} else if len(input.MacAddr) > 0 {
+		return input, httperrors.NewMissingParameterError("server_id")
+	}
+
+	if input.TrafficType == "" {
+		input.TrafficType = api.FLOW_LOG_TRAFFIC_TYPE_ALL
+	}
+	if err := validateFlowLogTrafficType(input.TrafficType); err != nil {
+		return input, err
+	}
+	if err := validateFlowLogRate(input.LogRate); err != nil {
+		return input, err
+	}
+	if input.LogRate == nil {
+		logRate := api.FLOW_LOG_DEFAULT_LOG_RATE
+		input.LogRate = &logRate
+	}
+
+	if input.Target == "" {
+		input.Target = api.FLOW_LOG_TARGET_TSDB
+	}
+	switch input.Target {
+	case api.FLOW_LOG_TARGET_TSDB:
+		input.BackupStorageId = ""
+		input.TargetPrefix = ""
+	case api.FLOW_LOG_TARGET_S3:
+		if len(input.BackupStorageId) == 0 {
+			return input, httperrors.NewMissingParameterError("backup_storage_id")
+		}
+		bsObj, err := BackupStorageManager.FetchByIdOrName(ctx, userCred, input.BackupStorageId)
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				return input, httperrors.NewResourceNotFoundError2("backup_storage", input.BackupStorageId)
+			}
+			return input, httperrors.NewGeneralError(err)
+		}
+		bs := bsObj.(*SBackupStorage)
+		if bs.StorageType != api.BACKUPSTORAGE_TYPE_OBJECT_STORAGE {
+			return input, httperrors.NewInputParameterError("backup storage %s is not object storage", bs.Name)
+		}
+		input.BackupStorageId = bs.Id
+		input.TargetPrefix = strings.Trim(input.TargetPrefix, "/")
+	default:
+		return input, httperrors.NewInputParameterError("invalid target %q, want one of %s", input.Target, strings.Join(api.FLOW_LOG_TARGETS, ","))
+	}
+
+	input.Status = api.FLOW_LOG_STATUS_AVAILABLE
+	return input, nil
+}
+
+func (manager *SFlowLogManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.FlowLogDetails {
+	rows := make([]api.FlowLogDetails, len(objs))
+	stdRows := manager.SEnabledStatusInfrasResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	vpcObjs := make([]interface{}, len(objs))
+	netIds := make([]string, len(objs))
+	guestIds := make([]string, len(objs))
+	bsIds := make([]string, len(objs))
+	for i := range rows {
+		rows[i] = api.FlowLogDetails{
+			EnabledStatusInfrasResourceBaseDetails: stdRows[i],
+		}
+		flowLog := objs[i].(*SFlowLog)
+		vpcObjs[i] = &SVpcResourceBase{VpcId: flowLog.VpcId}
+		netIds[i] = flowLog.NetworkId
+		guestIds[i] = flowLog.GuestId
+		bsIds[i] = flowLog.BackupStorageId
+	}
+	vpcRows := manager.SVpcResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, vpcObjs, fields, isList)
+	netMap, _ := db.FetchIdNameMap2(NetworkManager, netIds)
+	guestMap, _ := db.FetchIdNameMap2(GuestManager, guestIds)
+	bsMap, _ := db.FetchIdNameMap2(BackupStorageManager, bsIds)
+	for i := range rows {
+		rows[i].VpcResourceInfo = vpcRows[i]
+		rows[i].Network = netMap[netIds[i]]
+		rows[i].Server = guestMap[guestIds[i]]
+		rows[i].BackupStorage = bsMap[bsIds[i]]
+	}
+	return rows
+}
+
+func (manager *SFlowLogManager) ListItemExportKeys(ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	keys stringutils2.SSortedStrings,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusInfrasResourceBaseManager.ListItemExportKeys(ctx, q, userCred, keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusInfrasResourceBaseManager.ListItemExportKeys")
+	}
+	return q, nil
+}
+
+func (flowLog *SFlowLog) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.FlowLogUpdateInput) (api.FlowLogUpdateInput, error) {
+	var err error
+	input.EnabledStatusInfrasResourceBaseUpdateInput, err = flowLog.SEnabledStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusInfrasResourceBaseUpdateInput)
+	if err != nil {
+		return input, err
+	}
+	if input.TrafficType != "" {
+		if err := validateFlowLogTrafficType(input.TrafficType); err != nil {
+			return input, err
+		}
+	}
+	if err := validateFlowLogRate(input.LogRate); err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+// GetDetailsTargetInfo returns where records should be shipped to, including
+// credentials of object storage, thus only available to system admin
+func (flowLog *SFlowLog) GetDetailsTargetInfo(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (*api.FlowLogTargetInfo, error) {
+	if !userCred.HasSystemAdminPrivilege() {
+		return nil, httperrors.NewForbiddenError("not allow to get target info of flow log")
+	}
+	out := &api.FlowLogTargetInfo{
+		Target:       flowLog.Target,
+		TargetPrefix: flowLog.TargetPrefix,
+	}
+	if flowLog.Target != api.FLOW_LOG_TARGET_S3 {
+		return out, nil
+	}
+	bsObj, err := BackupStorageManager.FetchById(flowLog.BackupStorageId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetch backup storage %s", flowLog.BackupStorageId)
+	}
+	accessInfo, err := bsObj.(*SBackupStorage).GetAccessInfo()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetAccessInfo")
+	}
+	out.SBackupStorageAccessInfo = *accessInfo
+	return out, nil
+}
+
+const (
+	flowLogRecordsDefaultLimit = 100
+	flowLogRecordsMaxLimit     = 10000
+)
+
+var flowLogProtocolReg = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// flowLogRecordsSql builds influxql from validated input.  Tag values are
+// either ip addresses, numbers or enumerated words, no quoting is needed
+func (flowLog *SFlowLog) flowLogRecordsSql(input *api.FlowLogRecordsInput) (string, error) {
+	conds := []string{
+		fmt.Sprintf("flow_log_id = '%s'", flowLog.Id),
+		fmt.Sprintf("time >= '%s'", input.StartTime.UTC().Format(time.RFC3339)),
+		fmt.Sprintf("time <= '%s'", input.EndTime.UTC().Format(time.RFC3339)),
+	}
+	addIp := func(key, ip string) error {
+		if ip == "" {
+			return nil
+		}
+		if !regutils.MatchIPAddr(ip) && !regutils.MatchIP6Addr(ip) {
+			return httperrors.NewInputParameterError("invalid %s %q", key, ip)
+		}
+		conds = append(conds, fmt.Sprintf("%s = '%s'", key, ip))
+		return nil
+	}
+	addPort := func(key string, port int) error {
+		if port == 0 {
+			return nil
+		}
+		if port < 0 || port > 65535 {
+			return httperrors.NewInputParameterError("invalid %s %d", key, port)
+		}
+		conds = append(conds, fmt.Sprintf("%s = '%d'", key, port))
+		return nil
+	}
+	addEnum := func(key, val string, choices []string) error {
+		if val == "" {
+			return nil
+		}
+		if !utils.IsInStringArray(val, choices) {
+			return httperrors.NewInputParameterError("invalid %s %q", key, val)
+		}
+		conds = append(conds, fmt.Sprintf("%s = '%s'", key, val))
+		return nil
+	}
+	if err := addIp("src_ip", input.SrcIp); err != nil {
+		return "", err
+	}
+	if err := addIp("dst_ip", input.DstIp); err != nil {
+		return "", err
+	}
+	if err := addPort("src_port", input.SrcPort); err != nil {
+		return "", err
+	}
+	if err := addPort("dst_port", input.DstPort); err != nil {
+		return "", err
+	}
+	if input.Protocol != "" {
+		proto := strings.ToLower(input.Protocol)
+		if !flowLogProtocolReg.MatchString(proto) {
+			return "", httperrors.NewInputParameterError("invalid protocol %q", input.Protocol)
+		}
+		conds = append(conds, fmt.Sprintf("protocol = '%s'", proto))
+	}
+	if err := addEnum("verdict", input.Verdict, []string{api.FLOW_LOG_TRAFFIC_TYPE_ACCEPT, api.FLOW_LOG_TRAFFIC_TYPE_REJECT}); err != nil {
+		return "", err
+	}
+	if err := addEnum("direction", input.Direction, []string{api.FLOW_LOG_DIRECTION_INGRESS, api.FLOW_LOG_DIRECTION_EGRESS}); err != nil {
+		return "", err
+	}
+	if input.ServerId != "" {
+		if !regutils.MatchUUID(input.ServerId) {
+			return "", httperrors.NewInputParameterError("invalid server_id %q", input.ServerId)
+		}
+		conds = append(conds, fmt.Sprintf("server_id = '%s'", input.ServerId))
+	}
+	return fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY time DESC LIMIT %d",
+		api.FLOW_LOG_TSDB_MEASUREMENT, strings.Join(conds, " AND "), input.Limit), nil
+}
+
+// GetDetailsRecords queries records of flow log shipped to tsdb
+func (flowLog *SFlowLog) GetDetailsRecords(ctx context.Context, userCred mcclient.TokenCredential, input api.FlowLogRecordsInput) (*api.FlowLogRecordsOutput, error) {
+	if flowLog.Target != api.FLOW_LOG_TARGET_TSDB {
+		return nil, httperrors.NewNotSupportedError("records of flow log with target %s can not be queried", flowLog.Target)
+	}
+	now := time.Now()
+	if input.EndTime.IsZero() {
+		input.EndTime = now
+	}
+	if input.StartTime.IsZero() {
+		input.StartTime = input.EndTime.Add(-time.Hour)
+	}
+	if input.StartTime.After(input.EndTime) {
+		return nil, httperrors.NewInputParameterError("start_time is after end_time")
+	}
+	if input.Limit <= 0 {
+		input.Limit = flowLogRecordsDefaultLimit
+	} else if input.Limit > flowLogRecordsMaxLimit {
+		input.Limit = flowLogRecordsMaxLimit
+	}
+	querySql, err := flowLog.flowLogRecordsSql(&input)
+	if err != nil {
+		return nil, err
+	}
+
+	s := auth.GetAdminSession(ctx, options.Options.Region)
+	dbUrl, err := tsdb.GetDefaultServiceSourceURL(s, options.Options.SessionEndpointType)
+	if err != nil {
+		return nil, httperrors.NewNotFoundError("tsdb endpoint not found: %s", err)
+	}
+	dbinst := influxdb.NewInfluxdb(dbUrl)
+	if err := dbinst.SetDatabase(api.FLOW_LOG_TSDB_DATABASE); err != nil {
+		return nil, errors.Wrap(err, "SetDatabase")
+	}
+	queryRes, err := dbinst.Query(querySql)
+	if err != nil {
+		return nil, errors.Wrap(err, "query influxdb")
+	}
+	out := &api.FlowLogRecordsOutput{
+		Data: []api.SFlowLogRecord{},
+	}
+	for _, res := range queryRes {
+		for _, series := range res {
+			for _, values := range series.Values {
+				out.Data = append(out.Data, flowLogRecordFromColumns(series.Columns, values))
+			}
+		}
+	}
+	out.Total = len(out.Data)
+	return out, nil
+}
+
+func flowLogRecordFromColumns(columns []string, values []jsonutils.JSONObject) api.SFlowLogRecord {
+	var (
+		record = api.SFlowLogRecord{}
+		row    = jsonutils.NewDict()
+		ts     time.Time
+	)
+	for i, col := range columns {
+		if i >= len(values) || values[i] == nil || values[i] == jsonutils.JSONNull {
+			continue
+		}
+		switch col {
+		case "time":
+			ms, _ := values[i].Int()
+			ts = time.Unix(0, ms*int64(time.Millisecond)).UTC()
+		case "src_port", "dst_port", "packets":
+			// tags are strings while fields are numbers
+			str, _ := values[i].GetString()
+			n, err := strconv.Atoi(str)
+			if err != nil {
+				n64, _ := values[i].Int()
+				n = int(n64)
+			}
+			row.Set(col, jsonutils.NewInt(int64(n)))
+		default:
+			row.Set(col, values[i])
+		}
+	}
+	row.Unmarshal(&record)
+	record.Time = ts
+	return record
+}
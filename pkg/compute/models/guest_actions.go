@@ -68,6 +68,7 @@ import (
 	"yunion.io/x/onecloud/pkg/util/bitmap"
 	"yunion.io/x/onecloud/pkg/util/logclient"
 	"yunion.io/x/onecloud/pkg/util/netutils2"
+	"yunion.io/x/onecloud/pkg/util/pod/manifest"
 	"yunion.io/x/onecloud/pkg/util/seclib2"
 )
 
@@ -409,6 +410,9 @@ func (self *SGuest) validateMigrate(
 	if err != nil {
 		return errors.Wrapf(err, "GetDriver")
 	}
+	if replicas, _ := DiskReplicaManager.GetReplicasByGuest(self.Id); len(replicas) > 0 {
+		return httperrors.NewUnsupportOperationError("Cannot migrate server with disk replicas, remove them first")
+	}
 
 	if isLiveMigrate {
 		// do live migrate check
@@ -418,6 +422,10 @@ func (self *SGuest) validateMigrate(
 		if err := driver.CheckLiveMigrate(ctx, self, userCred, *liveMigrateInput); err != nil {
 			return err
 		}
+		if vfs, _ := self.GetVirtiofs(ctx); len(vfs) > 0 {
+			// vhost-user-fs device state can't be migrated
+			return httperrors.NewUnsupportOperationError("Cannot live migrate server with virtiofs shared directories")
+		}
 		if utils.IsInStringArray(self.Status, []string{api.VM_RUNNING, api.VM_SUSPEND}) {
 			if len(liveMigrateInput.PreferHostId) > 0 {
 				iHost, _ := HostManager.FetchByIdOrName(ctx, userCred, liveMigrateInput.PreferHostId)
@@ -5537,7 +5545,7 @@ func (self *SGuest) InstaceCreateSnapshot(
 
 func (self *SGuest) InstanceCreateBackup(ctx context.Context, userCred mcclient.TokenCredential, instanceBackup *SInstanceBackup) error {
 	self.SetStatus(ctx, userCred, api.VM_START_INSTANCE_BACKUP, "instance backup")
-	return instanceBackup.StartCreateInstanceBackupTask(ctx, userCred, "")
+	return instanceBackup.StartCreateInstanceBackupTask(ctx, userCred, nil, "")
 }
 
 func (self *SGuest) PerformInstanceSnapshotReset(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.ServerResetInput) (jsonutils.JSONObject, error) {
@@ -6764,3 +6772,18 @@ func (self *SGuest) PerformDisableAutoMergeSnapshots(ctx context.Context, userCr
 	disableAutoMergeSnapshot := jsonutils.QueryBoolean(data, "disable_auto_merge_snapshot", false)
 	return nil, self.SetMetadata(ctx, api.VM_METADATA_DISABLE_AUTO_MERGE_SNAPSHOT, disableAutoMergeSnapshot, userCred)
 }
+
+// PerformConvertPodManifest converts Kubernetes Pod/Deployment manifest or docker-compose file to pod create input
+func (manager *SGuestManager) PerformConvertPodManifest(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.PodConvertManifestInput) (*api.PodConvertManifestOutput, error) {
+	if len(input.Manifest) == 0 {
+		return nil, httperrors.NewMissingParameterError("manifest")
+	}
+	if input.Format != "" && !utils.IsInStringArray(string(input.Format), []string{string(api.POD_MANIFEST_FORMAT_KUBERNETES), string(api.POD_MANIFEST_FORMAT_COMPOSE)}) {
+		return nil, httperrors.NewInputParameterError("unsupported manifest format %s", input.Format)
+	}
+	output, err := manifest.Convert(input.Format, input.Manifest)
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("convert manifest: %v", err)
+	}
+	return output, nil
+}
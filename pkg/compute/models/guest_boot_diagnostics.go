@@ -0,0 +1,58 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// 获取虚拟机串口日志
+func (self *SGuest) GetDetailsSerialLog(ctx context.Context, userCred mcclient.TokenCredential, input *api.ServerGetSerialLogInput) (*api.ServerSerialLogOutput, error) {
+	if input.Offset != nil && *input.Offset < 0 {
+		return nil, httperrors.NewInputParameterError("invalid offset %d", *input.Offset)
+	}
+	if input.Tail < 0 || input.Limit < 0 {
+		return nil, httperrors.NewInputParameterError("tail and limit must not be negative")
+	}
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, httperrors.NewInvalidStatusError("guest has no host: %v", err)
+	}
+	drv, err := self.GetDriver()
+	if err != nil {
+		return nil, err
+	}
+	return drv.RequestGetSerialLog(ctx, userCred, host, self, input)
+}
+
+// 获取虚拟机屏幕截图, 用于启动诊断
+func (self *SGuest) GetDetailsScreendump(ctx context.Context, userCred mcclient.TokenCredential, input *api.ServerGetScreenDumpInput) (*api.ServerScreenDumpOutput, error) {
+	if self.Status != api.VM_RUNNING {
+		return nil, httperrors.NewInvalidStatusError("can't get screendump in status %s", self.Status)
+	}
+	host, err := self.GetHost()
+	if err != nil {
+		return nil, httperrors.NewInvalidStatusError("guest has no host: %v", err)
+	}
+	drv, err := self.GetDriver()
+	if err != nil {
+		return nil, err
+	}
+	return drv.RequestScreenDump(ctx, userCred, host, self)
+}
@@ -164,6 +164,7 @@ var HypervisorBrandMap = map[string]string{
 	computeapis.HYPERVISOR_ZSTACK:    computeapis.CLOUD_PROVIDER_ZSTACK,
 	computeapis.HYPERVISOR_GOOGLE:    computeapis.CLOUD_PROVIDER_GOOGLE,
 	computeapis.HYPERVISOR_CTYUN:     computeapis.CLOUD_PROVIDER_CTYUN,
+	computeapis.HYPERVISOR_POD:       computeapis.CLOUD_PROVIDER_ONECLOUD,
 }
 
 var BrandHypervisorMap = map[string]string{
@@ -0,0 +1,248 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/gotypes"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/validators"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+var virtiofsTagReg = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_\-]{0,35}$`)
+
+func validateVirtiofsConfig(ctx context.Context, userCred mcclient.TokenCredential, conf *api.ServerVirtiofsConfig, exists []*api.ServerVirtiofsConfig) error {
+	if !virtiofsTagReg.MatchString(conf.Tag) {
+		return httperrors.NewInputParameterError("invalid virtiofs tag %q", conf.Tag)
+	}
+	for i := range exists {
+		if exists[i].Tag == conf.Tag {
+			return httperrors.NewDuplicateNameError("tag", conf.Tag)
+		}
+	}
+	conf.ZoneId = ""
+	switch conf.SourceType {
+	case api.VIRTIOFS_SOURCE_HOST_PATH:
+		if !path.IsAbs(conf.HostPath) {
+			return httperrors.NewInputParameterError("host_path %q must be absolute path", conf.HostPath)
+		}
+		conf.HostPath = path.Clean(conf.HostPath)
+		if !userCred.HasSystemAdminPrivilege() && !isVirtiofsHostPathAllowed(conf.HostPath, options.Options.VirtiofsAllowedHostPaths) {
+			return httperrors.NewForbiddenError("host_path %q is not allowed to be shared", conf.HostPath)
+		}
+	case api.VIRTIOFS_SOURCE_CEPHFS:
+		if len(conf.FilesystemId) == 0 {
+			return httperrors.NewMissingParameterError("filesystem_id")
+		}
+		obj, err := validators.ValidateModel(ctx, userCred, FileSystemManager, &conf.FilesystemId)
+		if err != nil {
+			return err
+		}
+		fs := obj.(*SFileSystem)
+		if fs.Status != api.NAS_STATUS_AVAILABLE {
+			return httperrors.NewInvalidStatusError("invalid cephfs status %s", fs.Status)
+		}
+		account := fs.GetCloudaccount()
+		if gotypes.IsNil(account) || account.Provider != api.CLOUD_PROVIDER_CEPHFS {
+			return httperrors.NewInputParameterError("filesystem %s is not cephfs", fs.Name)
+		}
+		if len(conf.SubPath) > 0 {
+			conf.SubPath = path.Clean("/" + conf.SubPath)
+		}
+		conf.ZoneId = fs.ZoneId
+	case api.VIRTIOFS_SOURCE_MOUNT_TARGET:
+		if len(conf.MountTargetId) == 0 {
+			return httperrors.NewMissingParameterError("mount_target_id")
+		}
+		obj, err := validators.ValidateModel(ctx, userCred, MountTargetManager, &conf.MountTargetId)
+		if err != nil {
+			return err
+		}
+		mt := obj.(*SMountTarget)
+		if mt.Status != api.MOUNT_TARGET_STATUS_AVAILABLE {
+			return httperrors.NewInvalidStatusError("invalid mount target status %s", mt.Status)
+		}
+		if len(mt.DomainName) == 0 {
+			return httperrors.NewInputParameterError("mount target %s has no domain name", mt.Name)
+		}
+		fs, err := mt.GetFileSystem()
+		if err != nil {
+			return errors.Wrapf(err, "GetFileSystem of mount target %s", mt.Name)
+		}
+		if fs.Protocol != "NFS" {
+			return httperrors.NewUnsupportOperationError("unsupported filesystem protocol %s", fs.Protocol)
+		}
+		conf.ZoneId = fs.ZoneId
+	default:
+		return httperrors.NewInputParameterError("invalid virtiofs source_type %q, choose from %v", conf.SourceType, api.VIRTIOFS_SOURCE_TYPES)
+	}
+	return nil
+}
+
+// isVirtiofsHostPathAllowed checks the cleaned path is one of or under the allowed host directories
+func isVirtiofsHostPathAllowed(hostPath string, allowed []string) bool {
+	for _, dir := range allowed {
+		dir = path.Clean(dir)
+		if hostPath == dir || strings.HasPrefix(hostPath, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *SGuest) GetVirtiofs(ctx context.Context) ([]*api.ServerVirtiofsConfig, error) {
+	ret := make([]*api.ServerVirtiofsConfig, 0)
+	str := self.GetMetadata(ctx, api.VM_METADATA_VIRTIOFS, nil)
+	if len(str) == 0 {
+		return ret, nil
+	}
+	obj, err := jsonutils.ParseString(str)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse virtiofs metadata")
+	}
+	if err := obj.Unmarshal(&ret); err != nil {
+		return nil, errors.Wrap(err, "unmarshal virtiofs metadata")
+	}
+	return ret, nil
+}
+
+func (self *SGuest) setVirtiofs(ctx context.Context, userCred mcclient.TokenCredential, confs []*api.ServerVirtiofsConfig) error {
+	if len(confs) == 0 {
+		return self.SetMetadata(ctx, api.VM_METADATA_VIRTIOFS, "", userCred)
+	}
+	return self.SetMetadata(ctx, api.VM_METADATA_VIRTIOFS, jsonutils.Marshal(confs), userCred)
+}
+
+func (self *SGuest) FillVirtiofsSchedDesc(desc *api.ServerConfigs) {
+	confs, _ := self.GetVirtiofs(context.Background())
+	desc.Virtiofs = confs
+}
+
+func (self *SGuest) getVirtiofsJsonDesc(ctx context.Context) []*api.GuestVirtiofsJsonDesc {
+	confs, err := self.GetVirtiofs(ctx)
+	if err != nil {
+		return nil
+	}
+	ret := make([]*api.GuestVirtiofsJsonDesc, 0)
+	for _, conf := range confs {
+		desc := &api.GuestVirtiofsJsonDesc{
+			Tag:        conf.Tag,
+			SourceType: conf.SourceType,
+			ReadOnly:   conf.ReadOnly,
+		}
+		switch conf.SourceType {
+		case api.VIRTIOFS_SOURCE_HOST_PATH:
+			desc.HostPath = conf.HostPath
+		case api.VIRTIOFS_SOURCE_CEPHFS:
+			cephfs, err := getCephFSHostMount(conf.FilesystemId)
+			if err != nil {
+				logclient.AddSimpleActionLog(self, logclient.ACT_VM_SYNC_CONF, errors.Wrapf(err, "virtiofs %s", conf.Tag), nil, false)
+				continue
+			}
+			if len(conf.SubPath) > 0 {
+				cephfs.Path = path.Join(cephfs.Path, conf.SubPath)
+			}
+			desc.Cephfs = cephfs
+		case api.VIRTIOFS_SOURCE_MOUNT_TARGET:
+			obj, err := MountTargetManager.FetchById(conf.MountTargetId)
+			if err != nil {
+				logclient.AddSimpleActionLog(self, logclient.ACT_VM_SYNC_CONF, errors.Wrapf(err, "virtiofs %s", conf.Tag), nil, false)
+				continue
+			}
+			desc.NfsSource = fmt.Sprintf("%s:/", obj.(*SMountTarget).DomainName)
+		}
+		ret = append(ret, desc)
+	}
+	return ret
+}
+
+func (self *SGuest) validateVirtiofsAction() error {
+	if self.Hypervisor != api.HYPERVISOR_KVM {
+		return httperrors.NewNotAcceptableError("Not allow for hypervisor %s", self.Hypervisor)
+	}
+	if self.Status != api.VM_READY {
+		return httperrors.NewServerStatusError("virtiofs can only be changed while server is stopped, current status %s", self.Status)
+	}
+	return nil
+}
+
+// 挂载virtiofs共享目录
+func (self *SGuest) PerformAttachVirtiofs(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ServerAttachVirtiofsInput) (jsonutils.JSONObject, error) {
+	if err := self.validateVirtiofsAction(); err != nil {
+		return nil, err
+	}
+	confs, err := self.GetVirtiofs(ctx)
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("%v", err)
+	}
+	conf := input.ServerVirtiofsConfig
+	if err := validateVirtiofsConfig(ctx, userCred, &conf, confs); err != nil {
+		return nil, err
+	}
+	if len(conf.ZoneId) > 0 {
+		host, _ := self.GetHost()
+		if host != nil && host.ZoneId != conf.ZoneId {
+			return nil, httperrors.NewConflictError("filesystem of virtiofs %s is not in the zone of host %s", conf.Tag, host.Name)
+		}
+	}
+	confs = append(confs, &conf)
+	if err := self.setVirtiofs(ctx, userCred, confs); err != nil {
+		return nil, errors.Wrap(err, "setVirtiofs")
+	}
+	db.OpsLog.LogEvent(self, db.ACT_ATTACH, conf, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_ATTACH_VIRTIOFS, conf, userCred, true)
+	return nil, nil
+}
+
+// 卸载virtiofs共享目录
+func (self *SGuest) PerformDetachVirtiofs(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.ServerDetachVirtiofsInput) (jsonutils.JSONObject, error) {
+	if err := self.validateVirtiofsAction(); err != nil {
+		return nil, err
+	}
+	confs, err := self.GetVirtiofs(ctx)
+	if err != nil {
+		return nil, httperrors.NewInternalServerError("%v", err)
+	}
+	idx := -1
+	for i := range confs {
+		if confs[i].Tag == input.Tag {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, httperrors.NewResourceNotFoundError2("virtiofs", input.Tag)
+	}
+	confs = append(confs[:idx], confs[idx+1:]...)
+	if err := self.setVirtiofs(ctx, userCred, confs); err != nil {
+		return nil, errors.Wrap(err, "setVirtiofs")
+	}
+	db.OpsLog.LogEvent(self, db.ACT_DETACH, input, userCred)
+	logclient.AddActionLogWithContext(ctx, self, logclient.ACT_DETACH_VIRTIOFS, input, userCred, true)
+	return nil, nil
+}
@@ -20,6 +20,7 @@ import (
 	"database/sql"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -1898,6 +1899,24 @@ func (manager *SGuestManager) validateCreateData(
 			input.VmemSize = vmemSize
 			input.VcpuCount = vcpuCount
 		}
+		if input.BalloonMinMemMb < 0 || input.BalloonMinMemMb > input.VmemSize {
+			return nil, httperrors.NewInputParameterError("balloon_min_mem_mb %d out of range [0, %d]", input.BalloonMinMemMb, input.VmemSize)
+		}
+		if input.NumaNodes > 0 || len(input.MemoryBacking) > 0 || input.MemoryStrictBind {
+			if err := validateGuestNumaMemoryInput(input); err != nil {
+				return nil, err
+			}
+		}
+		if len(input.Virtiofs) > 0 {
+			if input.Hypervisor != api.HYPERVISOR_KVM {
+				return nil, httperrors.NewNotSupportedError("virtiofs only supported by hypervisor %s", api.HYPERVISOR_KVM)
+			}
+			for i := range input.Virtiofs {
+				if err := validateVirtiofsConfig(ctx, userCred, input.Virtiofs[i], input.Virtiofs[:i]); err != nil {
+					return nil, err
+				}
+			}
+		}
 
 		dataDiskDefs := []*api.DiskConfig{}
 		if sku != nil && sku.AttachedDiskCount > 0 {
@@ -2532,6 +2551,28 @@ func (guest *SGuest) PostCreate(ctx context.Context, userCred mcclient.TokenCred
 	if jsonutils.QueryBoolean(data, api.VM_METADATA_ENABLE_MEMCLEAN, false) {
 		guest.SetMetadata(ctx, api.VM_METADATA_ENABLE_MEMCLEAN, "true", userCred)
 	}
+	if jsonutils.QueryBoolean(data, api.VM_METADATA_ENABLE_MEMORY_BALLOON, false) {
+		guest.SetMetadata(ctx, api.VM_METADATA_ENABLE_MEMORY_BALLOON, "true", userCred)
+		if minMem, _ := data.Int(api.VM_METADATA_BALLOON_MIN_MEM_MB); minMem > 0 {
+			guest.SetMetadata(ctx, api.VM_METADATA_BALLOON_MIN_MEM_MB, minMem, userCred)
+		}
+	}
+	if numaNodes, _ := data.Int(api.VM_METADATA_NUMA_NODES); numaNodes > 0 {
+		guest.SetMetadata(ctx, api.VM_METADATA_NUMA_NODES, numaNodes, userCred)
+	}
+	if backing, _ := data.GetString(api.VM_METADATA_MEMORY_BACKING); len(backing) > 0 {
+		guest.SetMetadata(ctx, api.VM_METADATA_MEMORY_BACKING, backing, userCred)
+	}
+	if jsonutils.QueryBoolean(data, api.VM_METADATA_MEMORY_STRICT_BIND, false) {
+		guest.SetMetadata(ctx, api.VM_METADATA_MEMORY_STRICT_BIND, "true", userCred)
+	}
+	if data.Contains("virtiofs") {
+		virtiofs := make([]*api.ServerVirtiofsConfig, 0)
+		data.Unmarshal(&virtiofs, "virtiofs")
+		if len(virtiofs) > 0 {
+			guest.setVirtiofs(ctx, userCred, virtiofs)
+		}
+	}
 	if jsonutils.QueryBoolean(data, imageapi.IMAGE_DISABLE_USB_KBD, false) {
 		guest.SetMetadata(ctx, imageapi.IMAGE_DISABLE_USB_KBD, "true", userCred)
 	}
@@ -3071,6 +3112,10 @@ func (self *SGuest) getSecurityGroupsRules() string {
 	}
 	rules := []string{}
 	for _, rule := range secrules {
+		if len(rule.PeerSecgroupId) > 0 {
+			// peer secgroup rules only take effect in vpc
+			continue
+		}
 		rules = append(rules, rule.String())
 	}
 	return strings.Join(rules, SECURITY_GROUP_SEPARATOR)
@@ -5336,6 +5381,8 @@ func (self *SGuest) GetJsonDescAtHypervisor(ctx context.Context, host *SHost) *a
 		desc.Floppys = append(desc.Floppys, floppyDesc)
 	}
 
+	desc.Virtiofs = self.getVirtiofsJsonDesc(ctx)
+
 	// tenant
 	tc, _ := self.GetTenantCache(ctx)
 	if tc != nil {
@@ -6296,6 +6343,35 @@ func (self *SGuest) GetApptags() []string {
 	return nil
 }
 
+func validateGuestNumaMemoryInput(input *api.ServerCreateInput) error {
+	if input.Hypervisor != api.HYPERVISOR_KVM {
+		return httperrors.NewNotSupportedError("numa_nodes/memory_backing only supported by hypervisor %s", api.HYPERVISOR_KVM)
+	}
+	if input.NumaNodes < 0 || input.NumaNodes > input.VcpuCount {
+		return httperrors.NewInputParameterError("numa_nodes %d out of range [0, %d]", input.NumaNodes, input.VcpuCount)
+	}
+	if len(input.MemoryBacking) > 0 {
+		pageSizeKB := api.GuestMemoryBackingPageSizeKB(input.MemoryBacking)
+		if pageSizeKB == 0 {
+			return httperrors.NewInputParameterError("invalid memory_backing %q", input.MemoryBacking)
+		}
+		if input.EnableMemoryBalloon {
+			return httperrors.NewConflictError("memory balloon can't work with hugepage memory backing")
+		}
+		nodes := input.NumaNodes
+		if nodes == 0 {
+			nodes = 1
+		}
+		pageSizeMB := pageSizeKB / 1024
+		if input.VmemSize%(pageSizeMB*nodes) != 0 {
+			return httperrors.NewInputParameterError("vmem_size %dMB must be multiple of %dMB for %s with %d numa nodes", input.VmemSize, pageSizeMB*nodes, input.MemoryBacking, nodes)
+		}
+	} else if input.NumaNodes > 0 && input.VmemSize%input.NumaNodes != 0 {
+		return httperrors.NewInputParameterError("vmem_size %dMB can't be evenly divided into %d numa nodes", input.VmemSize, input.NumaNodes)
+	}
+	return nil
+}
+
 func (self *SGuest) ToSchedDesc() *schedapi.ScheduleInput {
 	desc := new(schedapi.ScheduleInput)
 	config := &schedapi.ServerConfig{
@@ -6322,9 +6398,26 @@ func (self *SGuest) ToSchedDesc() *schedapi.ScheduleInput {
 	desc.ServerConfig = *config
 	desc.OsArch = self.OsArch
 	desc.ExtraCpuCount = self.ExtraCpuCount
+	if config.Hypervisor == api.HYPERVISOR_KVM {
+		self.fillNumaMemorySchedDesc(desc.ServerConfigs)
+		self.FillVirtiofsSchedDesc(desc.ServerConfigs)
+	}
 	return desc
 }
 
+func (self *SGuest) fillNumaMemorySchedDesc(desc *api.ServerConfigs) {
+	meta, err := self.GetAllMetadata(context.Background(), nil)
+	if err != nil {
+		log.Errorf("get guest %s metadata: %v", self.Name, err)
+		return
+	}
+	if nodes, ok := meta[api.VM_METADATA_NUMA_NODES]; ok {
+		desc.NumaNodes, _ = strconv.Atoi(nodes)
+	}
+	desc.MemoryBacking = meta[api.VM_METADATA_MEMORY_BACKING]
+	desc.MemoryStrictBind = meta[api.VM_METADATA_MEMORY_STRICT_BIND] == "true"
+}
+
 func (self *SGuest) FillGroupSchedDesc(desc *api.ServerConfigs) {
 	groups := make([]SGroupguest, 0)
 	err := GroupguestManager.Query().Equals("guest_id", self.Id).All(&groups)
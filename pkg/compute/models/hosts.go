@@ -5148,14 +5148,34 @@ func (hh *SHost) PerformPing(ctx context.Context, userCred mcclient.TokenCredent
 				if err != nil {
 					log.Errorf("update storage info error %s", err)
 				}
+				storage.SyncDiskActualUsages(si.DiskStats)
+				if err := storage.RecordUsageSample(ctx, userCred); err != nil {
+					log.Errorf("record usage sample of storage %s error %s", storage.Name, err)
+				}
 			}
 		}
 		hh.SetMetadata(ctx, "root_partition_used_capacity_mb", input.RootPartitionUsedCapacityMb, userCred)
 		hh.SetMetadata(ctx, "memory_used_mb", input.MemoryUsedMb, userCred)
 		hh.SetMetadata(ctx, api.HOST_METADATA_CPU_USAGE_PERCENT, input.CpuUsagePercent, userCred)
 
+		memStats := make(map[string]api.SGuestMemoryStat, len(input.GuestMemoryStats))
+		var reclaimedMemMb int64
+		for _, stat := range input.GuestMemoryStats {
+			memStats[stat.GuestId] = stat
+			if stat.ReservedMemMb > stat.ActualMemMb {
+				reclaimedMemMb += stat.ReservedMemMb - stat.ActualMemMb
+			}
+		}
+		hh.SetMetadata(ctx, api.HOST_METADATA_BALLOON_RECLAIMED_MEM_MB, reclaimedMemMb, userCred)
+
 		guests, _ := hh.GetGuests()
 		for _, guest := range guests {
+			if stat, ok := memStats[guest.Id]; ok {
+				actual := strconv.FormatInt(stat.ActualMemMb, 10)
+				if guest.GetMetadata(ctx, api.VM_METADATA_BALLOON_ACTUAL_MEM_MB, nil) != actual {
+					guest.SetMetadata(ctx, api.VM_METADATA_BALLOON_ACTUAL_MEM_MB, actual, userCred)
+				}
+			}
 			if utils.IsInStringArray(guest.Id, input.QgaRunningGuestIds) {
 				if guest.QgaStatus != api.QGA_STATUS_AVAILABLE {
 					guest.UpdateQgaStatus(api.QGA_STATUS_AVAILABLE)
@@ -6973,6 +6993,7 @@ func (host *SHost) OnHostDown(ctx context.Context, userCred mcclient.TokenCreden
 	logclient.AddActionLogWithContext(ctx, host, logclient.ACT_OFFLINE, map[string]string{"reason": "host down"}, userCred, false)
 	host.SyncCleanSchedDescCache()
 	host.switchWithBackup(ctx, userCred)
+	host.failoverDiskReplicas(ctx, userCred)
 	host.migrateOnHostDown(ctx, userCred)
 }
 
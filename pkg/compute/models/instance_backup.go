@@ -258,9 +258,9 @@ func (manager *SInstanceBackupManager) FetchCustomizeColumns(
 	return rows
 }
 
-func (self *SInstanceBackup) StartCreateInstanceBackupTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+func (self *SInstanceBackup) StartCreateInstanceBackupTask(ctx context.Context, userCred mcclient.TokenCredential, params *jsonutils.JSONDict, parentTaskId string) error {
 	self.SetStatus(ctx, userCred, api.INSTANCE_BACKUP_STATUS_CREATING, "")
-	if task, err := taskman.TaskManager.NewTask(ctx, "InstanceBackupCreateTask", self, userCred, nil, parentTaskId, "", nil); err != nil {
+	if task, err := taskman.TaskManager.NewTask(ctx, "InstanceBackupCreateTask", self, userCred, params, parentTaskId, "", nil); err != nil {
 		return err
 	} else {
 		task.ScheduleRun(nil)
@@ -491,16 +491,39 @@ func (self *SInstanceBackup) GetBackups() ([]SDiskBackup, error) {
 	return backups, nil
 }
 
+// isIncremental tells some disk backups only hold the data written after
+// their parent backups
+func (self *SInstanceBackup) isIncremental() (bool, error) {
+	backups, err := self.GetBackups()
+	if err != nil {
+		return false, err
+	}
+	for i := range backups {
+		if backups[i].IsIncremental() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (self *SInstanceBackup) PerformRecovery(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.InstanceBackupRecoveryInput) (jsonutils.JSONObject, error) {
-	return nil, self.StartRecoveryTask(ctx, userCred, "", input.Name)
+	if incremental, err := self.isIncremental(); err != nil {
+		return nil, err
+	} else if incremental {
+		return nil, httperrors.NewUnsupportOperationError("cannot recover incremental instance backup %s", self.Name)
+	}
+	return nil, self.StartRecoveryTask(ctx, userCred, "", input.Name, input.Networks)
 }
 
-func (self *SInstanceBackup) StartRecoveryTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string, serverName string) error {
+func (self *SInstanceBackup) StartRecoveryTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string, serverName string, networks []*api.NetworkConfig) error {
 	self.SetStatus(ctx, userCred, api.INSTANCE_BACKUP_STATUS_RECOVERY, "")
 	params := jsonutils.NewDict()
 	if serverName != "" {
 		params.Set("server_name", jsonutils.NewString(serverName))
 	}
+	if len(networks) > 0 {
+		params.Set("networks", jsonutils.Marshal(networks))
+	}
 	task, err := taskman.TaskManager.NewTask(ctx, "InstanceBackupRecoveryTask", self, userCred, params, parentTaskId, "", nil)
 	if err != nil {
 		return err
@@ -514,16 +537,30 @@ func (self *SInstanceBackup) PerformPack(ctx context.Context, userCred mcclient.
 	if input.PackageName == "" {
 		return nil, httperrors.NewMissingParameterError("miss package_name")
 	}
+	if incremental, err := self.isIncremental(); err != nil {
+		return nil, err
+	} else if incremental {
+		return nil, httperrors.NewUnsupportOperationError("incremental instance backup %s is packed by its dr protection group", self.Name)
+	}
+	return nil, self.StartPackTask(ctx, userCred, "", input.PackageName, "")
+}
+
+// StartPackTask packs the backup as packageName, incremental disk backups
+// are packed on top of the package parentPackageName
+func (self *SInstanceBackup) StartPackTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string, packageName, parentPackageName string) error {
 	self.SetStatus(ctx, userCred, api.INSTANCE_BACKUP_STATUS_PACK, "")
 	params := jsonutils.NewDict()
-	params.Set("package_name", jsonutils.NewString(input.PackageName))
-	task, err := taskman.TaskManager.NewTask(ctx, "InstanceBackupPackTask", self, userCred, params, "", "", nil)
+	params.Set("package_name", jsonutils.NewString(packageName))
+	if len(parentPackageName) > 0 {
+		params.Set("parent_package_name", jsonutils.NewString(parentPackageName))
+	}
+	task, err := taskman.TaskManager.NewTask(ctx, "InstanceBackupPackTask", self, userCred, params, parentTaskId, "", nil)
 	if err != nil {
-		return nil, err
+		return err
 	} else {
 		task.ScheduleRun(nil)
 	}
-	return nil, nil
+	return nil
 }
 
 func (manager *SInstanceBackupManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, input api.InstanceBackupManagerCreateFromPackageInput) (api.InstanceBackupManagerCreateFromPackageInput, error) {
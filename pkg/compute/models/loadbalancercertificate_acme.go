@@ -0,0 +1,498 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/eggsampler/acme/v3"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/regutils"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+const (
+	acmeIssueTimeout  = 10 * time.Minute
+	acmeDnsRecordTTL  = 60
+	acmeDnsRecordName = "_acme-challenge"
+)
+
+func (lbcert *SLoadbalancerCertificate) IsAcme() bool {
+	return lbcert.CertSource == api.LB_CERT_SOURCE_ACME
+}
+
+func validateAcmeInput(input api.LoadbalancerCertificateAcmeInput) (api.LoadbalancerCertificateAcmeInput, error) {
+	if len(input.AcmeDirectoryUrl) == 0 {
+		input.AcmeDirectoryUrl = options.Options.AcmeDirectoryUrl
+	}
+	u, err := url.Parse(input.AcmeDirectoryUrl)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || len(u.Host) == 0 {
+		return input, httperrors.NewInputParameterError("invalid acme_directory_url %s", input.AcmeDirectoryUrl)
+	}
+	if len(input.AcmeEmail) > 0 && !regutils.MatchEmail(input.AcmeEmail) {
+		return input, httperrors.NewInputParameterError("invalid acme_email %s", input.AcmeEmail)
+	}
+	if len(input.AcmeChallengeType) == 0 {
+		input.AcmeChallengeType = api.LB_CERT_ACME_CHALLENGE_HTTP01
+	}
+	if !utils.IsInStringArray(input.AcmeChallengeType, api.LB_CERT_ACME_CHALLENGE_TYPES) {
+		return input, httperrors.NewInputParameterError("invalid acme_challenge_type %s, supported %s", input.AcmeChallengeType, api.LB_CERT_ACME_CHALLENGE_TYPES)
+	}
+	domains := []string{}
+	for _, domain := range input.AcmeDomains {
+		domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if len(domain) == 0 || utils.IsInStringArray(domain, domains) {
+			continue
+		}
+		if strings.HasPrefix(domain, "*.") {
+			if input.AcmeChallengeType != api.LB_CERT_ACME_CHALLENGE_DNS01 {
+				return input, httperrors.NewInputParameterError("wildcard domain %s requires %s challenge", domain, api.LB_CERT_ACME_CHALLENGE_DNS01)
+			}
+		}
+		if !regutils.MatchDomainName(strings.TrimPrefix(domain, "*.")) {
+			return input, httperrors.NewInputParameterError("invalid domain %s", domain)
+		}
+		domains = append(domains, domain)
+	}
+	if len(domains) == 0 {
+		return input, httperrors.NewMissingParameterError("acme_domains")
+	}
+	input.AcmeDomains = domains
+	return input, nil
+}
+
+func (lbcert *SLoadbalancerCertificate) StartAcmeIssueTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "LoadbalancerCertificateAcmeIssueTask", lbcert, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrapf(err, "NewTask")
+	}
+	lbcert.SetStatus(ctx, userCred, api.LB_CERT_STATUS_ISSUING, "")
+	return task.ScheduleRun(nil)
+}
+
+// 立即续签ACME证书
+func (lbcert *SLoadbalancerCertificate) PerformAcmeRenew(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.LoadbalancerCertificateAcmeRenewInput) (jsonutils.JSONObject, error) {
+	if !lbcert.IsAcme() {
+		return nil, httperrors.NewUnsupportOperationError("certificate %s is not issued by acme", lbcert.Name)
+	}
+	if !utils.IsInStringArray(lbcert.Status, []string{apis.STATUS_AVAILABLE, api.LB_CERT_STATUS_ISSUE_FAILED}) {
+		return nil, httperrors.NewInvalidStatusError("can not renew certificate in status %s", lbcert.Status)
+	}
+	return nil, lbcert.StartAcmeIssueTask(ctx, userCred, "")
+}
+
+// AutoRenewAcmeCertificates renews acme certificates expiring in
+// AcmeRenewBeforeDays, retrying those failed to be issued
+func (manager *SLoadbalancerCertificateManager) AutoRenewAcmeCertificates(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	renewBefore := time.Now().Add(time.Duration(options.Options.AcmeRenewBeforeDays) * 24 * time.Hour)
+	q := manager.Query().Equals("cert_source", api.LB_CERT_SOURCE_ACME)
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.AND(
+			sqlchemy.Equals(q.Field("status"), apis.STATUS_AVAILABLE),
+			sqlchemy.LT(q.Field("not_after"), renewBefore),
+		),
+		sqlchemy.Equals(q.Field("status"), api.LB_CERT_STATUS_ISSUE_FAILED),
+	))
+	certs := []SLoadbalancerCertificate{}
+	err := db.FetchModelObjects(manager, q, &certs)
+	if err != nil {
+		log.Errorf("fetch acme certificates to renew: %v", err)
+		return
+	}
+	for i := range certs {
+		err := certs[i].StartAcmeIssueTask(ctx, userCred, "")
+		if err != nil {
+			log.Errorf("start renewing acme certificate %s: %v", certs[i].Name, err)
+		}
+	}
+}
+
+func acmeHttpClient() (*http.Client, error) {
+	if len(options.Options.AcmeCaBundle) == 0 {
+		return http.DefaultClient, nil
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	data, err := ioutil.ReadFile(options.Options.AcmeCaBundle)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", options.Options.AcmeCaBundle)
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.Wrapf(errors.ErrInvalidFormat, "no certificate found in %s", options.Options.AcmeCaBundle)
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		},
+	}, nil
+}
+
+func encodeEcPrivateKey(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", errors.Wrap(err, "MarshalECPrivateKey")
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+}
+
+func encodeCertificateChain(certs []*x509.Certificate) string {
+	buf := []byte{}
+	for _, cert := range certs {
+		buf = append(buf, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return string(buf)
+}
+
+// getAcmeAccountKey returns the account key of the certificate, generating
+// one on first use
+func (lbcert *SLoadbalancerCertificate) getAcmeAccountKey() (crypto.Signer, error) {
+	if len(lbcert.AcmeAccountKey) > 0 {
+		data, err := utils.DescryptAESBase64(lbcert.Id, lbcert.AcmeAccountKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "decrypt account key")
+		}
+		p, _ := pem.Decode([]byte(data))
+		if p == nil {
+			return nil, errors.Wrap(errors.ErrInvalidFormat, "account key")
+		}
+		return x509.ParseECPrivateKey(p.Bytes)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "GenerateKey")
+	}
+	data, err := encodeEcPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	sec, err := utils.EncryptAESBase64(lbcert.Id, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "encrypt account key")
+	}
+	_, err = db.Update(lbcert, func() error {
+		lbcert.AcmeAccountKey = sec
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "save account key")
+	}
+	return key, nil
+}
+
+func (lbcert *SLoadbalancerCertificate) acmeClient() (acme.Client, acme.Account, error) {
+	key, err := lbcert.getAcmeAccountKey()
+	if err != nil {
+		return acme.Client{}, acme.Account{}, err
+	}
+	httpClient, err := acmeHttpClient()
+	if err != nil {
+		return acme.Client{}, acme.Account{}, err
+	}
+	client, err := acme.NewClient(lbcert.AcmeDirectoryUrl, acme.WithHTTPClient(httpClient), acme.WithUserAgentSuffix("cloudpods-region"))
+	if err != nil {
+		return acme.Client{}, acme.Account{}, errors.Wrapf(err, "NewClient %s", lbcert.AcmeDirectoryUrl)
+	}
+	client.PollTimeout = acmeIssueTimeout
+	contacts := []string{}
+	if len(lbcert.AcmeEmail) > 0 {
+		contacts = append(contacts, "mailto:"+lbcert.AcmeEmail)
+	}
+	account, err := client.NewAccount(key, false, true, contacts...)
+	if err != nil {
+		return acme.Client{}, acme.Account{}, errors.Wrap(err, "NewAccount")
+	}
+	return client, account, nil
+}
+
+type sAcmeChallenge struct {
+	challenge acme.Challenge
+	domain    string
+	// HTTP-01 key authorization or DNS-01 TXT record value
+	response string
+}
+
+// AcmeIssue orders a certificate from the ACME server, answering the
+// challenges by lbagent or dns records, and replaces the certificate and
+// private key on success.  lbagent picks up the updated certificate and
+// reloads haproxy with it
+func (lbcert *SLoadbalancerCertificate) AcmeIssue(ctx context.Context, userCred mcclient.TokenCredential) error {
+	ctx, cancel := context.WithTimeout(ctx, acmeIssueTimeout)
+	defer cancel()
+
+	client, account, err := lbcert.acmeClient()
+	if err != nil {
+		return err
+	}
+	publish := func(challenges []sAcmeChallenge) (func(), error) {
+		return lbcert.publishAcmeChallenges(ctx, userCred, challenges)
+	}
+	propagation := time.Duration(options.Options.AcmeChallengePropagationSeconds) * time.Second
+	certificate, privateKey, err := acmeObtainCertificate(ctx, client, account, lbcert.AcmeDomains, lbcert.AcmeChallengeType, propagation, publish)
+	if err != nil {
+		return err
+	}
+	return lbcert.setIssuedCertificate(ctx, userCred, certificate, privateKey)
+}
+
+// acmeObtainCertificate runs an ACME order of domains to completion and
+// returns the PEM encoded certificate chain and private key.  publish makes
+// the challenge responses available to the ACME server and returns the
+// function withdrawing them
+func acmeObtainCertificate(
+	ctx context.Context,
+	client acme.Client,
+	account acme.Account,
+	domains []string,
+	challengeType string,
+	propagation time.Duration,
+	publish func([]sAcmeChallenge) (func(), error),
+) (string, string, error) {
+	ids := []acme.Identifier{}
+	for _, domain := range domains {
+		ids = append(ids, acme.Identifier{Type: "dns", Value: domain})
+	}
+	order, err := client.NewOrder(account, ids)
+	if err != nil {
+		return "", "", errors.Wrap(err, "NewOrder")
+	}
+
+	challenges := []sAcmeChallenge{}
+	for _, authzURL := range order.Authorizations {
+		authz, err := client.FetchAuthorization(account, authzURL)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "FetchAuthorization %s", authzURL)
+		}
+		if authz.Status == "valid" {
+			continue
+		}
+		chal, ok := authz.ChallengeMap[challengeType]
+		if !ok {
+			return "", "", errors.Wrapf(errors.ErrNotSupported, "no %s challenge offered for %s", challengeType, authz.Identifier.Value)
+		}
+		response := chal.KeyAuthorization
+		if challengeType == api.LB_CERT_ACME_CHALLENGE_DNS01 {
+			response = acme.EncodeDNS01KeyAuthorization(chal.KeyAuthorization)
+		}
+		challenges = append(challenges, sAcmeChallenge{
+			challenge: chal,
+			domain:    authz.Identifier.Value,
+			response:  response,
+		})
+	}
+
+	if len(challenges) > 0 {
+		cleanup, err := publish(challenges)
+		defer cleanup()
+		if err != nil {
+			return "", "", errors.Wrap(err, "publish challenges")
+		}
+		select {
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		case <-time.After(propagation):
+		}
+	}
+	for _, chal := range challenges {
+		_, err := client.UpdateChallenge(account, chal.challenge)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "challenge for %s", chal.domain)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", errors.Wrap(err, "GenerateKey")
+	}
+	csrDer, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return "", "", errors.Wrap(err, "CreateCertificateRequest")
+	}
+	csr, err := x509.ParseCertificateRequest(csrDer)
+	if err != nil {
+		return "", "", errors.Wrap(err, "ParseCertificateRequest")
+	}
+	order, err = client.FinalizeOrder(account, order, csr)
+	if err != nil {
+		return "", "", errors.Wrap(err, "FinalizeOrder")
+	}
+	certs, err := client.FetchCertificates(account, order.Certificate)
+	if err != nil {
+		return "", "", errors.Wrap(err, "FetchCertificates")
+	}
+	if len(certs) == 0 {
+		return "", "", errors.Wrap(errors.ErrEmpty, "no certificate issued")
+	}
+	privateKey, err := encodeEcPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return encodeCertificateChain(certs), privateKey, nil
+}
+
+func (lbcert *SLoadbalancerCertificate) setIssuedCertificate(ctx context.Context, userCred mcclient.TokenCredential, certificate, privateKey string) error {
+	info, err := parseCertificate(certificate, privateKey)
+	if err != nil {
+		return errors.Wrap(err, "parseCertificate")
+	}
+	diff, err := db.Update(lbcert, func() error {
+		lbcert.SCertificateResourceBase = *info
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "db.Update")
+	}
+	db.OpsLog.LogEvent(lbcert, db.ACT_UPDATE, diff, userCred)
+	return nil
+}
+
+// publishAcmeChallenges makes the challenge responses available to the ACME
+// server.  The returned function withdraws them and is always non nil
+func (lbcert *SLoadbalancerCertificate) publishAcmeChallenges(ctx context.Context, userCred mcclient.TokenCredential, challenges []sAcmeChallenge) (func(), error) {
+	switch lbcert.AcmeChallengeType {
+	case api.LB_CERT_ACME_CHALLENGE_HTTP01:
+		return lbcert.publishAcmeHttpChallenges(challenges)
+	default:
+		return lbcert.publishAcmeDnsChallenges(ctx, userCred, challenges)
+	}
+}
+
+func (lbcert *SLoadbalancerCertificate) setAcmeHttpChallenges(challenges *api.SAcmeHttpChallenges) error {
+	_, err := db.Update(lbcert, func() error {
+		lbcert.AcmeHttpChallenges = challenges
+		return nil
+	})
+	return err
+}
+
+func (lbcert *SLoadbalancerCertificate) publishAcmeHttpChallenges(challenges []sAcmeChallenge) (func(), error) {
+	httpChallenges := &api.SAcmeHttpChallenges{}
+	for _, chal := range challenges {
+		httpChallenges.Challenges = append(httpChallenges.Challenges, api.SAcmeHttpChallenge{
+			Token:            chal.challenge.Token,
+			KeyAuthorization: chal.response,
+		})
+	}
+	cleanup := func() {
+		err := lbcert.setAcmeHttpChallenges(nil)
+		if err != nil {
+			log.Errorf("clear http challenges of certificate %s: %v", lbcert.Name, err)
+		}
+	}
+	return cleanup, lbcert.setAcmeHttpChallenges(httpChallenges)
+}
+
+func (lbcert *SLoadbalancerCertificate) publishAcmeDnsChallenges(ctx context.Context, userCred mcclient.TokenCredential, challenges []sAcmeChallenge) (func(), error) {
+	records := []*SDnsRecord{}
+	cleanup := func() {
+		for _, record := range records {
+			err := record.StartDeleteTask(ctx, userCred, "")
+			if err != nil {
+				log.Errorf("delete acme challenge record %s: %v", record.Name, err)
+			}
+		}
+	}
+	for _, chal := range challenges {
+		zone, err := lbcert.findAcmeDnsZone(chal.domain)
+		if err != nil {
+			return cleanup, errors.Wrapf(err, "find dns zone of %s", chal.domain)
+		}
+		input := api.DnsRecordCreateInput{
+			DnsZoneId: zone.Id,
+			DnsType:   "TXT",
+			DnsValue:  chal.response,
+			TTL:       acmeDnsRecordTTL,
+		}
+		input.Name = acmeChallengeRecordName(chal.domain, zone.Name)
+		data := jsonutils.Marshal(input)
+		obj, err := db.DoCreate(DnsRecordManager, ctx, userCred, nil, data, lbcert.GetOwnerId())
+		if err != nil {
+			return cleanup, errors.Wrapf(err, "create record %s in zone %s", input.Name, zone.Name)
+		}
+		record := obj.(*SDnsRecord)
+		record.PostCreate(ctx, userCred, lbcert.GetOwnerId(), nil, data)
+		records = append(records, record)
+	}
+	return cleanup, nil
+}
+
+// findAcmeDnsZone returns the closest dns zone of domain visible to the
+// project of the certificate
+func (lbcert *SLoadbalancerCertificate) findAcmeDnsZone(domain string) (*SDnsZone, error) {
+	labels := strings.Split(domain, ".")
+	candidates := []string{}
+	for i := range labels {
+		candidates = append(candidates, strings.Join(labels[i:], "."))
+	}
+	q := DnsZoneManager.Query().IsTrue("enabled").In("name", candidates)
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.IsTrue(q.Field("is_public")),
+		sqlchemy.Equals(q.Field("tenant_id"), lbcert.ProjectId),
+	))
+	zones := []SDnsZone{}
+	err := db.FetchModelObjects(DnsZoneManager, q, &zones)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	var ret *SDnsZone
+	for i := range zones {
+		if ret == nil || len(zones[i].Name) > len(ret.Name) {
+			ret = &zones[i]
+		}
+	}
+	if ret == nil {
+		return nil, errors.Wrapf(errors.ErrNotFound, "no dns zone for %s", domain)
+	}
+	return ret, nil
+}
+
+// acmeChallengeRecordName returns the name of the DNS-01 TXT record of domain,
+// relative to zone
+func acmeChallengeRecordName(domain, zone string) string {
+	if domain == zone {
+		return acmeDnsRecordName
+	}
+	return acmeDnsRecordName + "." + strings.TrimSuffix(domain, "."+zone)
+}
@@ -0,0 +1,220 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/eggsampler/acme/v3"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func freeLocalPort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// startPebble runs a pebble ACME test server accepting all challenges and
+// reusing valid authorizations, and returns its directory url and the http
+// client trusting its certificate
+func startPebble(t *testing.T) (string, *http.Client) {
+	bin, err := exec.LookPath("pebble")
+	if err != nil {
+		t.Skipf("looking for pebble: %v", err)
+	}
+	dir := t.TempDir()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	keyPEM, err := encodeEcPrivateKey(key)
+	if err != nil {
+		t.Fatalf("encodeEcPrivateKey: %v", err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("write %s: %v", certFile, err)
+	}
+	if err := ioutil.WriteFile(keyFile, []byte(keyPEM), 0600); err != nil {
+		t.Fatalf("write %s: %v", keyFile, err)
+	}
+
+	listenAddr := fmt.Sprintf("127.0.0.1:%d", freeLocalPort(t))
+	conf := map[string]interface{}{
+		"pebble": map[string]interface{}{
+			"listenAddress":                  listenAddr,
+			"managementListenAddress":        fmt.Sprintf("127.0.0.1:%d", freeLocalPort(t)),
+			"certificate":                    certFile,
+			"privateKey":                     keyFile,
+			"httpPort":                       freeLocalPort(t),
+			"tlsPort":                        freeLocalPort(t),
+			"ocspResponderURL":               "",
+			"externalAccountBindingRequired": false,
+		},
+	}
+	data, _ := json.Marshal(conf)
+	confFile := filepath.Join(dir, "pebble-config.json")
+	if err := ioutil.WriteFile(confFile, data, 0600); err != nil {
+		t.Fatalf("write %s: %v", confFile, err)
+	}
+
+	cmd := exec.Command(bin, "-config", confFile)
+	cmd.Env = append(os.Environ(),
+		"PEBBLE_VA_ALWAYS_VALID=1",
+		"PEBBLE_VA_NOSLEEP=1",
+		"PEBBLE_WFE_NONCEREJECT=0",
+		"PEBBLE_AUTHZREUSE=100",
+	)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start pebble: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	httpClient := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+	dirUrl := fmt.Sprintf("https://%s/dir", listenAddr)
+	for i := 0; ; i++ {
+		resp, err := httpClient.Get(dirUrl)
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i >= 50 {
+			t.Fatalf("pebble not ready: %v", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return dirUrl, httpClient
+}
+
+func TestAcmeObtainCertificatePebble(t *testing.T) {
+	dirUrl, httpClient := startPebble(t)
+
+	client, err := acme.NewClient(dirUrl, acme.WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	client.PollInterval = 100 * time.Millisecond
+	client.PollTimeout = 30 * time.Second
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	account, err := client.NewAccount(key, false, true)
+	if err != nil {
+		t.Fatalf("NewAccount: %v", err)
+	}
+
+	domains := []string{"example.com", "www.example.com"}
+	issue := func() (int, error) {
+		published := 0
+		publish := func(challenges []sAcmeChallenge) (func(), error) {
+			published += len(challenges)
+			return func() {}, nil
+		}
+		certificate, privateKey, err := acmeObtainCertificate(context.Background(), client, account, domains, api.LB_CERT_ACME_CHALLENGE_HTTP01, 0, publish)
+		if err != nil {
+			return published, err
+		}
+		pair, err := tls.X509KeyPair([]byte(certificate), []byte(privateKey))
+		if err != nil {
+			return published, err
+		}
+		leaf, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return published, err
+		}
+		sort.Strings(leaf.DNSNames)
+		if !reflect.DeepEqual(leaf.DNSNames, domains) {
+			t.Errorf("dns names: want %v, got %v", domains, leaf.DNSNames)
+		}
+		return published, nil
+	}
+
+	published, err := issue()
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if published != len(domains) {
+		t.Errorf("issue: want %d challenges published, got %d", len(domains), published)
+	}
+	// renewing reuses the valid authorizations of the account
+	published, err = issue()
+	if err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	if published != 0 {
+		t.Errorf("renew: want no challenge published, got %d", published)
+	}
+
+	_, _, err = acmeObtainCertificate(context.Background(), client, account, []string{"invalid_domain"}, api.LB_CERT_ACME_CHALLENGE_HTTP01, 0, func([]sAcmeChallenge) (func(), error) {
+		return func() {}, nil
+	})
+	if err == nil {
+		t.Errorf("expect order of invalid domain rejected")
+	}
+}
@@ -0,0 +1,568 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eggsampler/acme/v3"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestValidateAcmeInput(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   api.LoadbalancerCertificateAcmeInput
+		domains []string
+		wantErr bool
+	}{
+		{
+			name: "normalize domains",
+			input: api.LoadbalancerCertificateAcmeInput{
+				AcmeDirectoryUrl: "https://localhost:14000/dir",
+				AcmeDomains:      []string{"WWW.Example.com.", "www.example.com", " example.com "},
+			},
+			domains: []string{"www.example.com", "example.com"},
+		},
+		{
+			name: "wildcard with dns-01",
+			input: api.LoadbalancerCertificateAcmeInput{
+				AcmeDirectoryUrl:  "https://localhost:14000/dir",
+				AcmeChallengeType: api.LB_CERT_ACME_CHALLENGE_DNS01,
+				AcmeDomains:       []string{"*.example.com"},
+			},
+			domains: []string{"*.example.com"},
+		},
+		{
+			name: "wildcard with http-01",
+			input: api.LoadbalancerCertificateAcmeInput{
+				AcmeDirectoryUrl: "https://localhost:14000/dir",
+				AcmeDomains:      []string{"*.example.com"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no domains",
+			input: api.LoadbalancerCertificateAcmeInput{
+				AcmeDirectoryUrl: "https://localhost:14000/dir",
+			},
+			wantErr: true,
+		},
+		{
+			name: "bad directory url",
+			input: api.LoadbalancerCertificateAcmeInput{
+				AcmeDirectoryUrl: "localhost:14000/dir",
+				AcmeDomains:      []string{"example.com"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "bad challenge type",
+			input: api.LoadbalancerCertificateAcmeInput{
+				AcmeDirectoryUrl:  "https://localhost:14000/dir",
+				AcmeChallengeType: "tls-alpn-01",
+				AcmeDomains:       []string{"example.com"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			input, err := validateAcmeInput(c.input)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expect error, got %#v", input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateAcmeInput: %v", err)
+			}
+			if !reflect.DeepEqual(input.AcmeDomains, c.domains) {
+				t.Errorf("domains: want %v, got %v", c.domains, input.AcmeDomains)
+			}
+			if input.AcmeChallengeType == "" {
+				t.Errorf("challenge type not defaulted")
+			}
+		})
+	}
+}
+
+func TestAcmeChallengeRecordName(t *testing.T) {
+	cases := []struct {
+		domain string
+		zone   string
+		want   string
+	}{
+		{"example.com", "example.com", "_acme-challenge"},
+		{"www.example.com", "example.com", "_acme-challenge.www"},
+		{"a.b.example.com", "example.com", "_acme-challenge.a.b"},
+	}
+	for _, c := range cases {
+		got := acmeChallengeRecordName(c.domain, c.zone)
+		if got != c.want {
+			t.Errorf("%s in %s: want %s, got %s", c.domain, c.zone, c.want, got)
+		}
+	}
+}
+
+// sMockAcmeServer is a minimal RFC 8555 server validating challenges against
+// the responses published by the client
+type sMockAcmeServer struct {
+	*httptest.Server
+
+	t          *testing.T
+	lock       sync.Mutex
+	thumbprint string
+	caKey      *ecdsa.PrivateKey
+	caCert     *x509.Certificate
+	domains    []string
+	valid      map[int]bool
+	published  map[string]string
+	certPEM    []byte
+
+	// failures injected to the order
+	orderProblem     *acme.Problem
+	challengeInvalid bool
+	finalizeInvalid  bool
+}
+
+func newMockAcmeServer(t *testing.T) *sMockAcmeServer {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mock acme ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	srv := &sMockAcmeServer{
+		t:         t,
+		caKey:     caKey,
+		caCert:    caCert,
+		valid:     map[int]bool{},
+		published: map[string]string{},
+	}
+	srv.Server = httptest.NewServer(http.HandlerFunc(srv.serve))
+	return srv
+}
+
+func (srv *sMockAcmeServer) token(i int) string {
+	return fmt.Sprintf("token-%d", i)
+}
+
+func (srv *sMockAcmeServer) authz(i int) map[string]interface{} {
+	status := "pending"
+	if srv.valid[i] {
+		status = "valid"
+	}
+	challenges := []map[string]string{}
+	for _, typ := range api.LB_CERT_ACME_CHALLENGE_TYPES {
+		challenges = append(challenges, map[string]string{
+			"type":   typ,
+			"url":    fmt.Sprintf("%s/chal/%d/%s", srv.URL, i, typ),
+			"token":  srv.token(i),
+			"status": status,
+		})
+	}
+	return map[string]interface{}{
+		"status":     status,
+		"identifier": acme.Identifier{Type: "dns", Value: srv.domains[i]},
+		"challenges": challenges,
+	}
+}
+
+func (srv *sMockAcmeServer) order() map[string]interface{} {
+	authzs := []string{}
+	ids := []acme.Identifier{}
+	for i, domain := range srv.domains {
+		authzs = append(authzs, fmt.Sprintf("%s/authz/%d", srv.URL, i))
+		ids = append(ids, acme.Identifier{Type: "dns", Value: domain})
+	}
+	status := "ready"
+	if len(srv.certPEM) > 0 {
+		status = "valid"
+	}
+	return map[string]interface{}{
+		"status":         status,
+		"identifiers":    ids,
+		"authorizations": authzs,
+		"finalize":       srv.URL + "/finalize",
+		"certificate":    srv.URL + "/cert",
+	}
+}
+
+func (srv *sMockAcmeServer) serve(w http.ResponseWriter, r *http.Request) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+	if r.URL.Path == "/dir" {
+		json.NewEncoder(w).Encode(acme.Directory{
+			NewNonce:   srv.URL + "/nonce",
+			NewAccount: srv.URL + "/account",
+			NewOrder:   srv.URL + "/order",
+		})
+		return
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	jws := struct {
+		Payload string `json:"payload"`
+	}{}
+	err := json.NewDecoder(r.Body).Decode(&jws)
+	if err != nil {
+		srv.t.Errorf("decode jws of %s: %v", r.URL.Path, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+
+	var i int
+	switch {
+	case r.URL.Path == "/account":
+		w.Header().Set("Location", srv.URL+"/account/1")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"status": "valid"})
+	case r.URL.Path == "/order" && len(payload) > 0 && srv.orderProblem != nil:
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(srv.orderProblem.Status)
+		json.NewEncoder(w).Encode(srv.orderProblem)
+	case r.URL.Path == "/order" && len(payload) > 0:
+		req := struct {
+			Identifiers []acme.Identifier `json:"identifiers"`
+		}{}
+		json.Unmarshal(payload, &req)
+		for _, id := range req.Identifiers {
+			srv.domains = append(srv.domains, id.Value)
+		}
+		w.Header().Set("Location", srv.URL+"/order")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(srv.order())
+	case r.URL.Path == "/order":
+		json.NewEncoder(w).Encode(srv.order())
+	case strings.HasPrefix(r.URL.Path, "/authz/"):
+		fmt.Sscanf(r.URL.Path, "/authz/%d", &i)
+		json.NewEncoder(w).Encode(srv.authz(i))
+	case strings.HasPrefix(r.URL.Path, "/chal/"):
+		parts := strings.Split(r.URL.Path, "/")
+		fmt.Sscanf(parts[2], "%d", &i)
+		typ := parts[3]
+		keyAuth := srv.token(i) + "." + srv.thumbprint
+		want := keyAuth
+		if typ == api.LB_CERT_ACME_CHALLENGE_DNS01 {
+			want = acme.EncodeDNS01KeyAuthorization(keyAuth)
+		}
+		chal := map[string]interface{}{
+			"type":   typ,
+			"url":    srv.URL + r.URL.Path,
+			"token":  srv.token(i),
+			"status": "valid",
+		}
+		if srv.challengeInvalid || srv.published[srv.domains[i]] != want {
+			chal["status"] = "invalid"
+			chal["error"] = acme.Problem{Type: "urn:ietf:params:acme:error:unauthorized", Detail: "response mismatch"}
+		} else {
+			srv.valid[i] = true
+		}
+		json.NewEncoder(w).Encode(chal)
+	case r.URL.Path == "/finalize":
+		req := struct {
+			Csr string `json:"csr"`
+		}{}
+		json.Unmarshal(payload, &req)
+		der, _ := base64.RawURLEncoding.DecodeString(req.Csr)
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			srv.t.Errorf("ParseCertificateRequest: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if srv.finalizeInvalid {
+			order := srv.order()
+			order["status"] = "invalid"
+			order["error"] = acme.Problem{Type: "urn:ietf:params:acme:error:badCSR", Detail: "csr rejected"}
+			w.Header().Set("Location", srv.URL+"/order")
+			json.NewEncoder(w).Encode(order)
+			return
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      csr.Subject,
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(24 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		der, err = x509.CreateCertificate(rand.Reader, tmpl, srv.caCert, csr.PublicKey, srv.caKey)
+		if err != nil {
+			srv.t.Errorf("CreateCertificate: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		srv.certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+		srv.certPEM = append(srv.certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.caCert.Raw})...)
+		w.Header().Set("Location", srv.URL+"/order")
+		json.NewEncoder(w).Encode(srv.order())
+	case r.URL.Path == "/cert":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(srv.certPEM)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestAcmeObtainCertificate(t *testing.T) {
+	for _, challengeType := range api.LB_CERT_ACME_CHALLENGE_TYPES {
+		t.Run(challengeType, func(t *testing.T) {
+			srv := newMockAcmeServer(t)
+			defer srv.Close()
+
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("GenerateKey: %v", err)
+			}
+			srv.thumbprint, err = acme.JWKThumbprint(key.Public())
+			if err != nil {
+				t.Fatalf("JWKThumbprint: %v", err)
+			}
+			client, err := acme.NewClient(srv.URL + "/dir")
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			client.PollInterval = 10 * time.Millisecond
+			client.PollTimeout = time.Second
+			account, err := client.NewAccount(key, false, true)
+			if err != nil {
+				t.Fatalf("NewAccount: %v", err)
+			}
+
+			withdrawn := false
+			publish := func(challenges []sAcmeChallenge) (func(), error) {
+				srv.lock.Lock()
+				defer srv.lock.Unlock()
+				for _, chal := range challenges {
+					srv.published[chal.domain] = chal.response
+				}
+				return func() { withdrawn = true }, nil
+			}
+			domains := []string{"example.com", "www.example.com"}
+			certificate, privateKey, err := acmeObtainCertificate(context.Background(), client, account, domains, challengeType, 0, publish)
+			if err != nil {
+				t.Fatalf("acmeObtainCertificate: %v", err)
+			}
+			if !withdrawn {
+				t.Errorf("challenges not withdrawn")
+			}
+			pair, err := tls.X509KeyPair([]byte(certificate), []byte(privateKey))
+			if err != nil {
+				t.Fatalf("X509KeyPair: %v", err)
+			}
+			if len(pair.Certificate) != 2 {
+				t.Errorf("want certificate chain of 2, got %d", len(pair.Certificate))
+			}
+			leaf, err := x509.ParseCertificate(pair.Certificate[0])
+			if err != nil {
+				t.Fatalf("ParseCertificate: %v", err)
+			}
+			if !reflect.DeepEqual(leaf.DNSNames, domains) {
+				t.Errorf("dns names: want %v, got %v", domains, leaf.DNSNames)
+			}
+		})
+	}
+}
+
+func TestAcmeObtainCertificateWrongResponse(t *testing.T) {
+	srv := newMockAcmeServer(t)
+	defer srv.Close()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	srv.thumbprint, _ = acme.JWKThumbprint(key.Public())
+	client, err := acme.NewClient(srv.URL + "/dir")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	account, err := client.NewAccount(key, false, true)
+	if err != nil {
+		t.Fatalf("NewAccount: %v", err)
+	}
+	publish := func(challenges []sAcmeChallenge) (func(), error) {
+		srv.lock.Lock()
+		defer srv.lock.Unlock()
+		for _, chal := range challenges {
+			srv.published[chal.domain] = "bogus"
+		}
+		return func() {}, nil
+	}
+	_, _, err = acmeObtainCertificate(context.Background(), client, account, []string{"example.com"}, api.LB_CERT_ACME_CHALLENGE_HTTP01, 0, publish)
+	if err == nil {
+		t.Fatalf("expect error of invalid challenge")
+	}
+}
+
+func newMockAcmeAccount(t *testing.T, srv *sMockAcmeServer) (acme.Client, acme.Account) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	srv.thumbprint, err = acme.JWKThumbprint(key.Public())
+	if err != nil {
+		t.Fatalf("JWKThumbprint: %v", err)
+	}
+	client, err := acme.NewClient(srv.URL + "/dir")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	client.PollInterval = 10 * time.Millisecond
+	client.PollTimeout = time.Second
+	account, err := client.NewAccount(key, false, true)
+	if err != nil {
+		t.Fatalf("NewAccount: %v", err)
+	}
+	return client, account
+}
+
+func TestAcmeObtainCertificateFailures(t *testing.T) {
+	cases := []struct {
+		name       string
+		setup      func(srv *sMockAcmeServer)
+		publishErr error
+		// whether the challenges are published before the failure
+		published bool
+		errMsg    string
+	}{
+		{
+			name: "order rejected",
+			setup: func(srv *sMockAcmeServer) {
+				srv.orderProblem = &acme.Problem{
+					Type:   "urn:ietf:params:acme:error:rejectedIdentifier",
+					Detail: "domain forbidden by policy",
+					Status: http.StatusBadRequest,
+				}
+			},
+			errMsg: "domain forbidden by policy",
+		},
+		{
+			name: "challenge invalid",
+			setup: func(srv *sMockAcmeServer) {
+				srv.challengeInvalid = true
+			},
+			published: true,
+			errMsg:    "response mismatch",
+		},
+		{
+			name: "finalize invalid",
+			setup: func(srv *sMockAcmeServer) {
+				srv.finalizeInvalid = true
+			},
+			published: true,
+			errMsg:    "csr rejected",
+		},
+		{
+			name:       "publish failed",
+			publishErr: fmt.Errorf("no dns zone"),
+			published:  true,
+			errMsg:     "no dns zone",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			srv := newMockAcmeServer(t)
+			defer srv.Close()
+			if c.setup != nil {
+				c.setup(srv)
+			}
+			client, account := newMockAcmeAccount(t, srv)
+
+			published, withdrawn := false, false
+			publish := func(challenges []sAcmeChallenge) (func(), error) {
+				srv.lock.Lock()
+				defer srv.lock.Unlock()
+				published = true
+				for _, chal := range challenges {
+					srv.published[chal.domain] = chal.response
+				}
+				return func() { withdrawn = true }, c.publishErr
+			}
+			_, _, err := acmeObtainCertificate(context.Background(), client, account, []string{"example.com"}, api.LB_CERT_ACME_CHALLENGE_DNS01, 0, publish)
+			if err == nil {
+				t.Fatalf("expect error")
+			}
+			if !strings.Contains(err.Error(), c.errMsg) {
+				t.Errorf("want error containing %q, got %v", c.errMsg, err)
+			}
+			if published != c.published {
+				t.Errorf("published: want %v, got %v", c.published, published)
+			}
+			if published && !withdrawn {
+				t.Errorf("challenges not withdrawn")
+			}
+		})
+	}
+}
+
+// renewing with valid authorizations shouldn't publish any challenge
+func TestAcmeObtainCertificateValidAuthz(t *testing.T) {
+	srv := newMockAcmeServer(t)
+	defer srv.Close()
+	srv.valid[0] = true
+	client, account := newMockAcmeAccount(t, srv)
+
+	publish := func(challenges []sAcmeChallenge) (func(), error) {
+		t.Errorf("unexpected challenges published: %d", len(challenges))
+		return func() {}, nil
+	}
+	certificate, _, err := acmeObtainCertificate(context.Background(), client, account, []string{"example.com"}, api.LB_CERT_ACME_CHALLENGE_HTTP01, 0, publish)
+	if err != nil {
+		t.Fatalf("acmeObtainCertificate: %v", err)
+	}
+	if len(certificate) == 0 {
+		t.Errorf("empty certificate")
+	}
+}
@@ -77,6 +77,21 @@ type SLoadbalancerCertificate struct {
 	SCloudregionResourceBase
 
 	db.SCertificateResourceBase
+
+	// 证书来源
+	CertSource string `width:"16" charset:"ascii" nullable:"false" default:"upload" list:"user" create:"optional"`
+	// ACME服务目录地址
+	AcmeDirectoryUrl string `width:"256" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// ACME账户联系邮箱
+	AcmeEmail string `width:"128" charset:"utf8" nullable:"true" list:"user" create:"optional"`
+	// 域名验证方式
+	AcmeChallengeType string `width:"16" charset:"ascii" nullable:"true" list:"user" create:"optional"`
+	// 申请证书的域名列表
+	AcmeDomains []string `width:"1024" charset:"utf8" nullable:"true" list:"user" create:"optional"`
+	// ACME账户私钥, 加密存储
+	AcmeAccountKey string `type:"text" nullable:"true"`
+	// 待lbagent应答的HTTP-01验证
+	AcmeHttpChallenges *api.SAcmeHttpChallenges `length:"long" nullable:"true" list:"admin"`
 }
 
 func (lbcert *SLoadbalancerCertificate) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.LoadbalancerCertificateUpdateInput) (*api.LoadbalancerCertificateUpdateInput, error) {
@@ -246,6 +261,9 @@ func (man *SLoadbalancerCertificateManager) ListItemFilter(
 	if len(query.SubjectAlternativeNames) > 0 {
 		q = q.In("subject_alternative_names", query.SubjectAlternativeNames)
 	}
+	if len(query.CertSource) > 0 {
+		q = q.In("cert_source", query.CertSource)
+	}
 
 	return q, nil
 }
@@ -358,33 +376,42 @@ func (man *SLoadbalancerCertificateManager) ValidateCreateData(
 	query jsonutils.JSONObject,
 	input *api.LoadbalancerCertificateCreateInput,
 ) (*api.LoadbalancerCertificateCreateInput, error) {
-	if len(input.Certificate) == 0 {
-		return nil, httperrors.NewMissingParameterError("certificate")
-	}
-	if len(input.PrivateKey) == 0 {
-		return nil, httperrors.NewMissingParameterError("private_key")
-	}
-	_, err := tls.X509KeyPair([]byte(input.Certificate), []byte(input.PrivateKey))
-	if err != nil {
-		return nil, err
-	}
-	p, _ := pem.Decode([]byte(input.Certificate))
-	c, err := x509.ParseCertificate(p.Bytes)
-	if err != nil {
-		return nil, err
+	if len(input.CertSource) == 0 {
+		input.CertSource = api.LB_CERT_SOURCE_UPLOAD
 	}
-	input.SubjectAlternativeNames = strings.Join(c.DNSNames, " ")
-	input.SignatureAlgorithm = c.SignatureAlgorithm.String()
-	d := sha256.Sum256(c.Raw)
-	input.Fingerprint = api.LB_TLS_CERT_FINGERPRINT_ALGO_SHA256 + ":" + hex.EncodeToString(d[:])
-	input.CommonName = c.Subject.CommonName
-	input.NotBefore = c.NotBefore
-	input.NotAfter = c.NotAfter
-	switch pub := c.PublicKey.(type) {
-	case *rsa.PublicKey:
-		input.PublicKeyBitLen = pub.N.BitLen()
-	case *ecdsa.PublicKey:
-		input.PublicKeyBitLen = pub.X.BitLen()
+	var err error
+	switch input.CertSource {
+	case api.LB_CERT_SOURCE_UPLOAD:
+		if len(input.Certificate) == 0 {
+			return nil, httperrors.NewMissingParameterError("certificate")
+		}
+		if len(input.PrivateKey) == 0 {
+			return nil, httperrors.NewMissingParameterError("private_key")
+		}
+		info, err := parseCertificate(input.Certificate, input.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		input.SubjectAlternativeNames = info.SubjectAlternativeNames
+		input.SignatureAlgorithm = info.SignatureAlgorithm
+		input.Fingerprint = info.Fingerprint
+		input.CommonName = info.CommonName
+		input.NotBefore = info.NotBefore
+		input.NotAfter = info.NotAfter
+		input.PublicKeyBitLen = info.PublicKeyBitLen
+	case api.LB_CERT_SOURCE_ACME:
+		if len(input.CloudproviderId) > 0 {
+			return nil, httperrors.NewNotSupportedError("acme certificate is only supported by on-premise loadbalancer")
+		}
+		input.LoadbalancerCertificateAcmeInput, err = validateAcmeInput(input.LoadbalancerCertificateAcmeInput)
+		if err != nil {
+			return nil, err
+		}
+		input.Certificate, input.PrivateKey = "", ""
+		input.CommonName = input.AcmeDomains[0]
+		input.SubjectAlternativeNames = strings.Join(input.AcmeDomains, " ")
+	default:
+		return nil, httperrors.NewInputParameterError("invalid cert_source %s", input.CertSource)
 	}
 	input.SharableVirtualResourceCreateInput, err = man.SSharableVirtualResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.SharableVirtualResourceCreateInput)
 	if err != nil {
@@ -400,6 +427,9 @@ func (man *SLoadbalancerCertificateManager) ValidateCreateData(
 		return nil, err
 	}
 	region := regionObj.(*SCloudregion)
+	if input.CertSource == api.LB_CERT_SOURCE_ACME && region.Provider != api.CLOUD_PROVIDER_ONECLOUD {
+		return nil, httperrors.NewNotSupportedError("acme certificate is only supported by on-premise loadbalancer")
+	}
 	if len(input.CloudproviderId) > 0 {
 		providerObj, err := validators.ValidateModel(ctx, userCred, CloudproviderManager, &input.CloudproviderId)
 		if err != nil {
@@ -417,9 +447,45 @@ func (man *SLoadbalancerCertificateManager) ValidateCreateData(
 
 func (self *SLoadbalancerCertificate) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	self.SSharableVirtualResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+	if self.IsAcme() {
+		self.StartAcmeIssueTask(ctx, userCred, "")
+		return
+	}
 	self.StartCreateTask(ctx, userCred, "")
 }
 
+// parseCertificate validates the key pair and extracts the derived
+// attributes of the certificate
+func parseCertificate(certificate, privateKey string) (*db.SCertificateResourceBase, error) {
+	_, err := tls.X509KeyPair([]byte(certificate), []byte(privateKey))
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("invalid key pair: %v", err)
+	}
+	p, _ := pem.Decode([]byte(certificate))
+	c, err := x509.ParseCertificate(p.Bytes)
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("invalid certificate: %v", err)
+	}
+	d := sha256.Sum256(c.Raw)
+	info := &db.SCertificateResourceBase{
+		Certificate:             certificate,
+		PrivateKey:              privateKey,
+		SubjectAlternativeNames: strings.Join(c.DNSNames, " "),
+		SignatureAlgorithm:      c.SignatureAlgorithm.String(),
+		Fingerprint:             api.LB_TLS_CERT_FINGERPRINT_ALGO_SHA256 + ":" + hex.EncodeToString(d[:]),
+		CommonName:              c.Subject.CommonName,
+		NotBefore:               c.NotBefore,
+		NotAfter:                c.NotAfter,
+	}
+	switch pub := c.PublicKey.(type) {
+	case *rsa.PublicKey:
+		info.PublicKeyBitLen = pub.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.PublicKeyBitLen = pub.X.BitLen()
+	}
+	return info, nil
+}
+
 func (lbcert *SLoadbalancerCertificate) StartCreateTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
 	task, err := taskman.TaskManager.NewTask(ctx, "LoadbalancerCertificateCreateTask", lbcert, userCred, nil, parentTaskId, "", nil)
 	if err != nil {
@@ -66,6 +66,8 @@ type SLoadbalancerCluster struct {
 	SWireResourceBase `width:"36" charset:"ascii" nullable:"true" list:"admin" create:"optional" update:"admin"`
 
 	Params *SLoadbalancerClusterParams `nullable:"true" create:"optional" list:"admin" get:"admin"`
+	// 数据面, haproxy或envoy
+	DataPlane string `width:"16" charset:"ascii" nullable:"false" default:"haproxy" list:"admin" create:"optional" update:"admin"`
 }
 
 // 负载均衡集群列表
@@ -151,9 +153,11 @@ func (man *SLoadbalancerClusterManager) ValidateCreateData(
 ) (*jsonutils.JSONDict, error) {
 	zoneV := validators.NewModelIdOrNameValidator("zone", "zone", ownerId)
 	wireV := validators.NewModelIdOrNameValidator("wire", "wire", ownerId)
+	dataPlaneV := validators.NewStringChoicesValidator("data_plane", api.LB_DATA_PLANES)
 	vs := []validators.IValidator{
 		zoneV,
 		wireV.Optional(true),
+		dataPlaneV.Default(api.LB_DATA_PLANE_HAPROXY),
 	}
 	for _, v := range vs {
 		if err := v.Validate(ctx, data); err != nil {
@@ -212,6 +216,20 @@ func (lbc *SLoadbalancerCluster) ValidateUpdateData(
 	if err := wireV.Validate(ctx, data); err != nil {
 		return nil, err
 	}
+	dataPlaneV := validators.NewStringChoicesValidator("data_plane", api.LB_DATA_PLANES)
+	dataPlaneV.Optional(true)
+	if err := dataPlaneV.Validate(ctx, data); err != nil {
+		return nil, err
+	}
+	if dataPlaneV.Value != "" && dataPlaneV.Value != lbc.DataPlane {
+		if dataPlaneV.Value == api.LB_DATA_PLANE_ENVOY {
+			if err := lbc.validateEnvoyDataPlane(); err != nil {
+				return nil, err
+			}
+		}
+		log.Infof("changing data plane of lbcluster %s(%s) from %s to %s",
+			lbc.Name, lbc.Id, lbc.DataPlane, dataPlaneV.Value)
+	}
 	if wireV.Model != nil {
 		wire := wireV.Model.(*SWire)
 		if wire.ZoneId != lbc.ZoneId {
@@ -239,6 +257,40 @@ func (lbc *SLoadbalancerCluster) ValidateUpdateData(
 	return data, nil
 }
 
+func (lbc *SLoadbalancerCluster) IsEnvoyDataPlane() bool {
+	return lbc.DataPlane == api.LB_DATA_PLANE_ENVOY
+}
+
+// validateEnvoyDataPlane checks that no listener or listener rule of
+// loadbalancers in the cluster uses per client limits, which envoy data plane
+// does not support
+func (lbc *SLoadbalancerCluster) validateEnvoyDataPlane() error {
+	lbs := LoadbalancerManager.Query("id").Equals("cluster_id", lbc.Id).SubQuery()
+	listeners := LoadbalancerListenerManager.Query().In("loadbalancer_id", lbs)
+	listeners = listeners.Filter(sqlchemy.OR(
+		sqlchemy.GT(listeners.Field("max_conn_per_src"), 0),
+		sqlchemy.GT(listeners.Field("http_request_rate_per_src"), 0),
+		sqlchemy.GT(listeners.Field("http_request_rate_per_header"), 0),
+	))
+	cnt, err := listeners.CountWithError()
+	if err != nil {
+		return errors.Wrap(err, "count listeners with per client limits")
+	}
+	if cnt > 0 {
+		return httperrors.NewNotSupportedError("%d listener(s) use per client limits not supported by %s data plane", cnt, api.LB_DATA_PLANE_ENVOY)
+	}
+	listenerIds := LoadbalancerListenerManager.Query("id").In("loadbalancer_id", lbs).SubQuery()
+	rules := LoadbalancerListenerRuleManager.Query().In("listener_id", listenerIds).GT("http_request_rate_per_header", 0)
+	cnt, err = rules.CountWithError()
+	if err != nil {
+		return errors.Wrap(err, "count listener rules with per client limits")
+	}
+	if cnt > 0 {
+		return httperrors.NewNotSupportedError("%d listener rule(s) use per client limits not supported by %s data plane", cnt, api.LB_DATA_PLANE_ENVOY)
+	}
+	return nil
+}
+
 func (lbc *SLoadbalancerCluster) ValidateDeleteCondition(ctx context.Context, info jsonutils.JSONObject) error {
 	refCnts, err := lbc.refCounts()
 	if err != nil {
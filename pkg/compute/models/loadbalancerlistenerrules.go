@@ -77,6 +77,7 @@ type SLoadbalancerListenerRule struct {
 
 	SLoadbalancerHealthCheck // 目前只有腾讯云HTTP、HTTPS类型的健康检查是和规则绑定的。
 	SLoadbalancerHTTPRateLimiter
+	SLoadbalancerHTTPHeaderRewriter
 	SLoadbalancerHTTPRedirect
 }
 
@@ -537,6 +538,11 @@ func (man *SLoadbalancerListenerRuleManager) ValidateCreateData(ctx context.Cont
 	if err != nil {
 		return nil, errors.Wrap(err, "listener.GetRegion")
 	}
+	if input.HttpRequestRatePerHeader > 0 && listener.isEnvoyDataPlane() {
+		if err := api.ValidateEnvoyDataPlaneLimits(0, 0, input.HttpRequestRatePerHeader); err != nil {
+			return nil, err
+		}
+	}
 	if region.GetDriver().IsSupportLoadbalancerListenerRuleRedirect() {
 		_, err := validators.ValidateModel(ctx, userCred, LoadbalancerBackendGroupManager, &input.BackendGroupId)
 		if err != nil {
@@ -590,6 +596,17 @@ func (lbr *SLoadbalancerListenerRule) ValidateUpdateData(ctx context.Context, us
 	if err != nil {
 		return nil, errors.Wrap(err, "SStatusStandaloneResourceBase.ValidateUpdateData")
 	}
+	if input.HttpRequestRatePerHeader != nil && *input.HttpRequestRatePerHeader > 0 {
+		listener, err := lbr.GetLoadbalancerListener()
+		if err != nil {
+			return nil, err
+		}
+		if listener.isEnvoyDataPlane() {
+			if err := api.ValidateEnvoyDataPlaneLimits(0, 0, *input.HttpRequestRatePerHeader); err != nil {
+				return nil, err
+			}
+		}
+	}
 	region, err := lbr.GetRegion()
 	if err != nil {
 		return nil, err
@@ -66,8 +66,19 @@ func init() {
 }
 
 type SLoadbalancerHTTPRateLimiter struct {
-	HTTPRequestRate       int `nullable:"true" list:"user" create:"optional" update:"user"` // 限定监听接收请示速率
-	HTTPRequestRatePerSrc int `nullable:"true" list:"user" create:"optional" update:"user"` // 源IP监听请求最大速率
+	HTTPRequestRate          int    `nullable:"true" list:"user" create:"optional" update:"user"`                            // 限定监听接收请示速率
+	HTTPRequestRatePerSrc    int    `nullable:"true" list:"user" create:"optional" update:"user"`                            // 源IP监听请求最大速率
+	HTTPRequestRatePerHeader int    `nullable:"true" list:"user" create:"optional" update:"user"`                            // 按请求头区分客户端的最大请求速率
+	HTTPRequestRateHeader    string `width:"64" charset:"ascii" nullable:"true" list:"user" create:"optional" update:"user"` // 区分客户端的请求头
+}
+
+type SLoadbalancerHTTPHeaderRewriter struct {
+	HTTPHeaderRewrites *api.SLoadbalancerHTTPHeaderRewrites `nullable:"true" list:"user" create:"optional" update:"user"` // 请求及响应头改写
+}
+
+type SLoadbalancerConnLimiter struct {
+	MaxConn       int `nullable:"true" list:"user" create:"optional" update:"user"` // 监听最大并发连接数
+	MaxConnPerSrc int `nullable:"true" list:"user" create:"optional" update:"user"` // 源IP最大并发连接数
 }
 
 type SLoadbalancerRateLimiter struct {
@@ -151,6 +162,7 @@ type SLoadbalancerListener struct {
 	SLoadbalancerAclResourceBase `width:"36" charset:"ascii" nullable:"true" list:"user" create:"optional"`
 
 	SLoadbalancerRateLimiter
+	SLoadbalancerConnLimiter
 
 	SLoadbalancerTCPListener
 	SLoadbalancerUDPListener
@@ -159,6 +171,7 @@ type SLoadbalancerListener struct {
 
 	SLoadbalancerHealthCheck
 	SLoadbalancerHTTPRateLimiter
+	SLoadbalancerHTTPHeaderRewriter
 	SLoadbalancerHTTPRedirect
 }
 
@@ -716,6 +729,16 @@ func (lblis *SLoadbalancerListener) GetLoadbalancer() (*SLoadbalancer, error) {
 	return lbObj.(*SLoadbalancer), nil
 }
 
+// isEnvoyDataPlane reports whether the listener is served by envoy data
+// plane of its loadbalancer cluster
+func (lblis *SLoadbalancerListener) isEnvoyDataPlane() bool {
+	lb, err := lblis.GetLoadbalancer()
+	if err != nil {
+		return false
+	}
+	return lb.IsEnvoyDataPlane()
+}
+
 func (lblis *SLoadbalancerListener) GetRegion() (*SCloudregion, error) {
 	loadbalancer, err := lblis.GetLoadbalancer()
 	if err != nil {
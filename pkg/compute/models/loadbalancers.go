@@ -621,6 +621,16 @@ func (lb *SLoadbalancer) GetCloudproviderId() string {
 	return lb.SManagedResourceBase.GetCloudproviderId()
 }
 
+// IsEnvoyDataPlane reports whether the loadbalancer belongs to a cluster
+// with envoy data plane
+func (lb *SLoadbalancer) IsEnvoyDataPlane() bool {
+	if len(lb.ClusterId) == 0 {
+		return false
+	}
+	cluster := lb.GetLoadbalancerCluster()
+	return cluster != nil && cluster.IsEnvoyDataPlane()
+}
+
 func (lb *SLoadbalancer) GetRegion() (*SCloudregion, error) {
 	return lb.SCloudregionResourceBase.GetRegion()
 }
@@ -750,6 +750,19 @@ func (self *SNatGateway) GetVpc() (*SVpc, error) {
 	return vpc.(*SVpc), nil
 }
 
+func (self *SNatGateway) GetNetwork() (*SNetwork, error) {
+	network, err := NetworkManager.FetchById(self.NetworkId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Fetch network by ID %s failed", self.NetworkId)
+	}
+	return network.(*SNetwork), nil
+}
+
+// IsManaged returns false for natgateways of onecloud vpc, which are realized by vpcagent as ovn nat rules
+func (self *SNatGateway) IsManaged() bool {
+	return len(self.GetCloudproviderId()) > 0
+}
+
 func (self *SNatGateway) GetINatGateway(ctx context.Context) (cloudprovider.ICloudNatGateway, error) {
 	if len(self.ExternalId) == 0 {
 		return nil, errors.Wrapf(cloudprovider.ErrNotFound, "empty external id")
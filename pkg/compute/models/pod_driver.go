@@ -21,6 +21,7 @@ import (
 
 	"yunion.io/x/onecloud/pkg/apis"
 	"yunion.io/x/onecloud/pkg/apis/compute"
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/mcclient"
 )
@@ -37,6 +38,7 @@ type IPodDriver interface {
 
 	RequestCreateContainer(ctx context.Context, userCred mcclient.TokenCredential, task IContainerTask) error
 	RequestStartContainer(ctx context.Context, userCred mcclient.TokenCredential, task IContainerTask) error
+	RequestWaitInitContainerExited(ctx context.Context, userCred mcclient.TokenCredential, ctr *SContainer, task taskman.ITask) error
 	RequestStopContainer(ctx context.Context, userCred mcclient.TokenCredential, task IContainerTask) error
 	RequestDeleteContainer(ctx context.Context, userCred mcclient.TokenCredential, task IContainerTask) error
 	RequestSyncContainerStatus(ctx context.Context, userCred mcclient.TokenCredential, task IContainerTask) error
@@ -45,6 +47,7 @@ type IPodDriver interface {
 	RequestSaveVolumeMountImage(ctx context.Context, userCred mcclient.TokenCredential, task IContainerTask) error
 	RequestExecSyncContainer(ctx context.Context, userCred mcclient.TokenCredential, ctr *SContainer, input *compute.ContainerExecSyncInput) (jsonutils.JSONObject, error)
 	RequestSetContainerResourcesLimit(ctx context.Context, userCred mcclient.TokenCredential, c *SContainer, limit *apis.ContainerResources) (jsonutils.JSONObject, error)
+	RequestRefreshContainerVolumeMounts(ctx context.Context, userCred mcclient.TokenCredential, c *SContainer, input *hostapi.ContainerRefreshVolumeMountsInput) (jsonutils.JSONObject, error)
 
 	RequestAddVolumeMountPostOverlay(ctx context.Context, userCred mcclient.TokenCredential, task IContainerTask) error
 	RequestRemoveVolumeMountPostOverlay(ctx context.Context, userCred mcclient.TokenCredential, task IContainerTask) error
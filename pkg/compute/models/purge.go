@@ -589,7 +589,7 @@ func (self *purgePair) purgeAll(ctx context.Context) error {
 				"delete from %s where %s in (%s)",
 				self.manager.TableSpec().Name(), self.key, placeholder,
 			)
-		case SecurityGroupRuleManager.Keyword():
+		case SecurityGroupRuleManager.Keyword(), DnsZoneChangeManager.Keyword():
 			sql = fmt.Sprintf(
 				"delete from %s where %s in (%s)",
 				self.manager.TableSpec().Name(), self.key, placeholder,
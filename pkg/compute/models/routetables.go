@@ -144,6 +144,25 @@ func (man *SRouteTableManager) validateRoutes(ctx context.Context, data *jsonuti
 	return data, nil
 }
 
+// validateRouteNextHops resolves next hop of peering routes, which must be a
+// vpc peering connection of the vpc
+func (man *SRouteTableManager) validateRouteNextHops(ctx context.Context, userCred mcclient.TokenCredential, vpcId string, routes api.SRoutes) error {
+	for _, route := range routes {
+		if route.NextHopType != api.NEXT_HOP_TYPE_VPCPEERING {
+			continue
+		}
+		_peer, err := validators.ValidateModel(ctx, userCred, VpcPeeringConnectionManager, &route.NextHopId)
+		if err != nil {
+			return err
+		}
+		peer := _peer.(*SVpcPeeringConnection)
+		if peer.VpcId != vpcId && peer.PeerVpcId != vpcId {
+			return httperrors.NewInputParameterError("vpc peering connection %s does not belong to vpc %s", peer.Name, vpcId)
+		}
+	}
+	return nil
+}
+
 func (man *SRouteTableManager) ValidateCreateData(
 	ctx context.Context,
 	userCred mcclient.TokenCredential,
@@ -159,6 +178,12 @@ func (man *SRouteTableManager) ValidateCreateData(
 	if err != nil {
 		return input, err
 	}
+	if input.Routes != nil {
+		err = man.validateRouteNextHops(ctx, userCred, input.VpcId, *input.Routes)
+		if err != nil {
+			return input, err
+		}
+	}
 	input.StatusInfrasResourceBaseCreateInput, err = man.SStatusInfrasResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.StatusInfrasResourceBaseCreateInput)
 	if err != nil {
 		return input, errors.Wrap(err, "SStatusInfrasResourceBaseManager.ValidateCreateData")
@@ -217,6 +242,12 @@ func (rt *SRouteTable) ValidateUpdateData(
 	if err != nil {
 		return input, errors.Wrap(err, "RouteTableManager.validateRoutes")
 	}
+	if input.Routes != nil {
+		err = RouteTableManager.validateRouteNextHops(ctx, userCred, rt.VpcId, *input.Routes)
+		if err != nil {
+			return input, err
+		}
+	}
 	input.StatusInfrasResourceBaseUpdateInput, err = rt.SStatusInfrasResourceBase.ValidateUpdateData(ctx, userCred, query, input.StatusInfrasResourceBaseUpdateInput)
 	if err != nil {
 		return input, errors.Wrap(err, "SStatusInfrasResourceBase.ValidateUpdateData")
@@ -240,6 +271,10 @@ func (rt *SRouteTable) PerformAddRoutes(ctx context.Context, userCred mcclient.T
 		if err != nil {
 			return nil, err
 		}
+		err = RouteTableManager.validateRouteNextHops(ctx, userCred, rt.VpcId, adds)
+		if err != nil {
+			return nil, err
+		}
 		for _, add := range adds {
 			found := false
 			for _, route := range routes {
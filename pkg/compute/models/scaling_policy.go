@@ -234,7 +234,7 @@ func (spm *SScalingPolicyManager) ValidateCreateData(ctx context.Context, userCr
 	if err != nil {
 		return input, errors.Wrap(err, "ScalingPolicyManager.Trigger")
 	}
-	input, err = trigger.ValidateCreateData(input)
+	input, err = trigger.ValidateCreateData(model.(*SScalingGroup), input)
 	if err != nil {
 		return input, httperrors.NewInputParameterError("%v", err)
 	}
@@ -337,6 +337,9 @@ func (sp *SScalingPolicy) Trigger(input *api.ScalingPolicyCreateInput) (IScaling
 				Cumulate:           input.Alarm.Cumulate,
 				Cycle:              input.Alarm.Cycle,
 				Indicator:          input.Alarm.Indicator,
+				Database:           input.Alarm.Database,
+				Measurement:        input.Alarm.Measurement,
+				Field:              input.Alarm.Field,
 				Wrapper:            input.Alarm.Wrapper,
 				Operator:           input.Alarm.Operator,
 				Value:              input.Alarm.Value,
@@ -41,8 +41,8 @@ type IScalingTriggerDesc interface {
 type IScalingTrigger interface {
 	IScalingTriggerDesc
 
-	// ValidateCreateData check and verify the input when creating SScalingPolicy
-	ValidateCreateData(input api.ScalingPolicyCreateInput) (api.ScalingPolicyCreateInput, error)
+	// ValidateCreateData check and verify the input when creating SScalingPolicy of the scaling group
+	ValidateCreateData(sg *SScalingGroup, input api.ScalingPolicyCreateInput) (api.ScalingPolicyCreateInput, error)
 
 	// Register
 	Register(ctx context.Context, userCred mcclient.TokenCredential) error
@@ -115,6 +115,13 @@ type SScalingAlarm struct {
 	Cycle     int
 	Indicator string `width:"32" charset:"ascii"`
 
+	// Database, Measurement and Field locate the metric in tsdb,
+	// they are resolved from Indicator and the hypervisor of scaling group
+	// or given explicitly for custom indicator
+	Database    string `width:"64" charset:"ascii"`
+	Measurement string `width:"64" charset:"ascii"`
+	Field       string `width:"64" charset:"ascii"`
+
 	// Wrapper instruct how to calculate collective data based on individual data
 	Wrapper  string `width:"16" charset:"ascii"`
 	Operator string `width:"2" charset:"ascii"`
@@ -162,10 +169,14 @@ func (sa *SScalingAlarm) AlarmDetails() api.ScalingAlarmDetails {
 		Wrapper:   sa.Wrapper,
 		Operator:  sa.Operator,
 		Value:     sa.Value,
+
+		Database:    sa.Database,
+		Measurement: sa.Measurement,
+		Field:       sa.Field,
 	}
 }
 
-func (st *SScalingTimer) ValidateCreateData(input api.ScalingPolicyCreateInput) (api.ScalingPolicyCreateInput, error) {
+func (st *SScalingTimer) ValidateCreateData(sg *SScalingGroup, input api.ScalingPolicyCreateInput) (api.ScalingPolicyCreateInput, error) {
 	var err error
 	if input.TriggerType == api.TRIGGER_TIMING {
 		input.Timer, err = checkTimerCreateInput(input.Timer)
@@ -216,7 +227,7 @@ func (st *SScalingTimer) IsTrigger() bool {
 	return true
 }
 
-func (sa *SScalingAlarm) ValidateCreateData(input api.ScalingPolicyCreateInput) (api.ScalingPolicyCreateInput, error) {
+func (sa *SScalingAlarm) ValidateCreateData(sg *SScalingGroup, input api.ScalingPolicyCreateInput) (api.ScalingPolicyCreateInput, error) {
 	if len(input.Alarm.Operator) == 0 {
 		input.Alarm.Operator = api.OPERATOR_GT
 	}
@@ -226,9 +237,25 @@ func (sa *SScalingAlarm) ValidateCreateData(input api.ScalingPolicyCreateInput)
 	if !utils.IsInStringArray(input.Alarm.Operator, []string{api.OPERATOR_GT, api.OPERATOR_LT}) {
 		return input, httperrors.NewInputParameterError("unkown operator in alarm %s", input.Alarm.Operator)
 	}
-	if !utils.IsInStringArray(input.Alarm.Indicator, []string{api.INDICATOR_CPU, api.INDICATOR_DISK_READ,
-		api.INDICATOR_DISK_WRITE, api.INDICATOR_FLOW_INTO, api.INDICATOR_FLOW_OUT}) {
-		return input, httperrors.NewInputParameterError("unkown indicator in alarm %s", input.Alarm.Indicator)
+	hypervisor := ""
+	if sg != nil {
+		hypervisor = sg.Hypervisor
+	}
+	if input.Alarm.Indicator == api.INDICATOR_CUSTOM {
+		if len(input.Alarm.Measurement) == 0 || len(input.Alarm.Field) == 0 {
+			return input, httperrors.NewMissingParameterError("measurement and field are required for custom indicator")
+		}
+		if len(input.Alarm.Database) == 0 {
+			input.Alarm.Database = alarmDefaultDatabase
+		}
+	} else {
+		tf, ok := getIndicatorTableField(hypervisor, input.Alarm.Indicator)
+		if !ok {
+			return input, httperrors.NewInputParameterError("unkown indicator in alarm %s", input.Alarm.Indicator)
+		}
+		input.Alarm.Database = alarmDefaultDatabase
+		input.Alarm.Measurement = tf.Table
+		input.Alarm.Field = tf.Field
 	}
 	if !utils.IsInStringArray(input.Alarm.Wrapper, []string{api.WRAPPER_MIN, api.WRAPPER_MAX, api.WRAPPER_AVER}) {
 		return input, httperrors.NewInputParameterError("unkown wrapper in alarm %s", input.Alarm.Wrapper)
@@ -318,14 +345,49 @@ type sTableField struct {
 	Field string
 }
 
+const alarmDefaultDatabase = "telegraf"
+
 var indicatorMap = map[string]sTableField{
 	api.INDICATOR_CPU:        {"vm_cpu", "usage_active"},
+	api.INDICATOR_MEM:        {"vm_mem", "used_percent"},
 	api.INDICATOR_DISK_WRITE: {"vm_diskio", "write_bps"},
 	api.INDICATOR_DISK_READ:  {"vm_diskio", "read_bps"},
 	api.INDICATOR_FLOW_INTO:  {"vm_netio", "bps_recv"},
 	api.INDICATOR_FLOW_OUT:   {"vm_netio", "bps_sent"},
 }
 
+// podIndicatorMap maps indicators to the container metrics reported by host for pods
+var podIndicatorMap = map[string]sTableField{
+	api.INDICATOR_CPU:        {"pod_cpu", "usage_rate"},
+	api.INDICATOR_MEM:        {"pod_mem", "usage_rate"},
+	api.INDICATOR_DISK_WRITE: {"pod_diskio", "write_Bps"},
+	api.INDICATOR_DISK_READ:  {"pod_diskio", "read_Bps"},
+	api.INDICATOR_FLOW_INTO:  {"pod_netio", "bps_recv"},
+	api.INDICATOR_FLOW_OUT:   {"pod_netio", "bps_sent"},
+}
+
+func getIndicatorTableField(hypervisor, indicator string) (sTableField, bool) {
+	if hypervisor == api.HYPERVISOR_POD {
+		tf, ok := podIndicatorMap[indicator]
+		return tf, ok
+	}
+	tf, ok := indicatorMap[indicator]
+	return tf, ok
+}
+
+// metric return the database, measurement and field of alarm,
+// alarms created before they were stored fall back to the vm indicators
+func (sa *SScalingAlarm) metric() (string, sTableField) {
+	if len(sa.Measurement) > 0 && len(sa.Field) > 0 {
+		database := sa.Database
+		if len(database) == 0 {
+			database = alarmDefaultDatabase
+		}
+		return database, sTableField{sa.Measurement, sa.Field}
+	}
+	return alarmDefaultDatabase, indicatorMap[sa.Indicator]
+}
+
 var alertConfigUsedBy = "scaling_group"
 
 func (sa *SScalingAlarm) generateAlertConfig(sp *SScalingPolicy) (*monitor.AlertConfig, error) {
@@ -334,7 +396,8 @@ func (sa *SScalingAlarm) generateAlertConfig(sp *SScalingPolicy) (*monitor.Alert
 		return nil, err
 	}
 	config.UsedBy = alertConfigUsedBy
-	cond := config.Condition("telegraf", indicatorMap[sa.Indicator].Table).Avg()
+	database, tf := sa.metric()
+	cond := config.Condition(database, tf.Table).Avg()
 	log.Debugf("alarm: %#v", sa)
 
 	switch sa.Operator {
@@ -344,7 +407,7 @@ func (sa *SScalingAlarm) generateAlertConfig(sp *SScalingPolicy) (*monitor.Alert
 		cond = cond.GT(sa.Value)
 	}
 	q := cond.Query().From("1h")
-	sel := q.Selects().Select(indicatorMap[sa.Indicator].Field)
+	sel := q.Selects().Select(tf.Field)
 	switch sa.Wrapper {
 	case api.WRAPPER_AVER:
 		sel = sel.MEAN()
@@ -381,9 +444,13 @@ func (sa *SScalingAlarm) TriggerDescription() string {
 	if sp != nil {
 		name = sp.Name
 	}
+	indicator := descs[sa.Indicator]
+	if sa.Indicator == api.INDICATOR_CUSTOM {
+		indicator = fmt.Sprintf("%s %s.%s", indicator, sa.Measurement, sa.Field)
+	}
 	return fmt.Sprintf(
 		`Alarm task(the %s %s of the instance is %s than %f%s) execute scaling policy "%s"`,
-		descs[sa.Wrapper], descs[sa.Indicator], descs[sa.Operator],
+		descs[sa.Wrapper], indicator, descs[sa.Operator],
 		sa.Value, units[sa.Indicator], name,
 	)
 }
@@ -420,6 +487,7 @@ var descs = map[string]string{
 	api.INDICATOR_DISK_WRITE: "disk write rate",
 	api.INDICATOR_FLOW_INTO:  "network inflow rate",
 	api.INDICATOR_FLOW_OUT:   "network outflow rate",
+	api.INDICATOR_CUSTOM:     "custom metric",
 	api.WRAPPER_MAX:          "maximum",
 	api.WRAPPER_MIN:          "minimum",
 	api.WRAPPER_AVER:         "average",
@@ -106,3 +106,76 @@ func TestSTimer_Update(t *testing.T) {
 		}
 	}
 }
+
+func TestSScalingAlarm_ValidateCreateData(t *testing.T) {
+	cases := []struct {
+		name     string
+		sg       *SScalingGroup
+		alarm    compute.ScalingAlarmCreateInput
+		database string
+		want     sTableField
+		wantErr  bool
+	}{
+		{
+			name:     "vm cpu",
+			sg:       &SScalingGroup{Hypervisor: compute.HYPERVISOR_KVM},
+			alarm:    compute.ScalingAlarmCreateInput{Indicator: compute.INDICATOR_CPU, Wrapper: compute.WRAPPER_AVER},
+			database: "telegraf",
+			want:     sTableField{"vm_cpu", "usage_active"},
+		},
+		{
+			name:     "pod memory",
+			sg:       &SScalingGroup{Hypervisor: compute.HYPERVISOR_POD},
+			alarm:    compute.ScalingAlarmCreateInput{Indicator: compute.INDICATOR_MEM, Wrapper: compute.WRAPPER_MAX},
+			database: "telegraf",
+			want:     sTableField{"pod_mem", "usage_rate"},
+		},
+		{
+			name: "custom",
+			sg:   &SScalingGroup{Hypervisor: compute.HYPERVISOR_POD},
+			alarm: compute.ScalingAlarmCreateInput{
+				Indicator:   compute.INDICATOR_CUSTOM,
+				Wrapper:     compute.WRAPPER_AVER,
+				Database:    "app",
+				Measurement: "http_requests",
+				Field:       "rps",
+			},
+			database: "app",
+			want:     sTableField{"http_requests", "rps"},
+		},
+		{
+			name:    "custom without measurement",
+			sg:      &SScalingGroup{Hypervisor: compute.HYPERVISOR_POD},
+			alarm:   compute.ScalingAlarmCreateInput{Indicator: compute.INDICATOR_CUSTOM, Wrapper: compute.WRAPPER_AVER},
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		input, err := new(SScalingAlarm).ValidateCreateData(c.sg, compute.ScalingPolicyCreateInput{Alarm: c.alarm})
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: ValidateCreateData: %v", c.name, err)
+		}
+		sa := SScalingAlarm{
+			Indicator:   input.Alarm.Indicator,
+			Database:    input.Alarm.Database,
+			Measurement: input.Alarm.Measurement,
+			Field:       input.Alarm.Field,
+		}
+		database, tf := sa.metric()
+		if database != c.database || tf != c.want {
+			t.Errorf("%s: want %s %#v, got %s %#v", c.name, c.database, c.want, database, tf)
+		}
+	}
+
+	// alarms created before the metric was stored
+	database, tf := (&SScalingAlarm{Indicator: compute.INDICATOR_CPU}).metric()
+	if database != "telegraf" || tf != (sTableField{"vm_cpu", "usage_active"}) {
+		t.Errorf("legacy alarm: got %s %#v", database, tf)
+	}
+}
@@ -79,6 +79,9 @@ type SSecurityGroupRule struct {
 	CIDR        string `width:"256" charset:"ascii" list:"user" update:"user" create:"optional"`
 	Action      string `width:"5" charset:"ascii" nullable:"false" list:"user" update:"user" create:"required"`
 	Description string `width:"256" charset:"utf8" list:"user" update:"user" create:"optional"`
+
+	// 对端安全组, 规则作用于该安全组内虚拟机的IP地址, 指定后CIDR不生效
+	PeerSecgroupId string `width:"128" charset:"ascii" list:"user" create:"optional"`
 }
 
 func (self *SSecurityGroupRule) GetId() string {
@@ -186,6 +189,7 @@ func (manager *SSecurityGroupRuleManager) FetchCustomizeColumns(
 	bRows := manager.SResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
 	secRows := manager.SSecurityGroupResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
 	secIds := make([]string, len(objs))
+	peerIds := make([]string, len(objs))
 	for i := range rows {
 		rows[i] = api.SecgroupRuleDetails{
 			ResourceBaseDetails:       bRows[i],
@@ -193,10 +197,11 @@ func (manager *SSecurityGroupRuleManager) FetchCustomizeColumns(
 		}
 		rule := objs[i].(*SSecurityGroupRule)
 		secIds[i] = rule.SecgroupId
+		peerIds[i] = rule.PeerSecgroupId
 	}
 
 	secgroups := make(map[string]SSecurityGroup)
-	err := db.FetchStandaloneObjectsByIds(SecurityGroupManager, secIds, &secgroups)
+	err := db.FetchStandaloneObjectsByIds(SecurityGroupManager, append(secIds, peerIds...), &secgroups)
 	if err != nil {
 		log.Errorf("FetchStandaloneObjectsByIds fail: %v", err)
 		return rows
@@ -208,6 +213,9 @@ func (manager *SSecurityGroupRuleManager) FetchCustomizeColumns(
 			virObjs[i] = &secgroup
 			rows[i].ProjectId = secgroup.ProjectId
 		}
+		if peer, ok := secgroups[peerIds[i]]; ok {
+			rows[i].PeerSecgroup = peer.Name
+		}
 	}
 
 	projRows := SecurityGroupManager.SProjectizedResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, virObjs, fields, isList)
@@ -277,6 +285,14 @@ func (manager *SSecurityGroupRuleManager) ValidateCreateData(ctx context.Context
 
 	secgroup := _secgroup.(*SSecurityGroup)
 
+	err = manager.validatePeerSecgroup(ctx, userCred, secgroup, &input.PeerSecgroupId)
+	if err != nil {
+		return input, err
+	}
+	if len(input.PeerSecgroupId) > 0 {
+		input.CIDR = ""
+	}
+
 	driver, err := secgroup.GetRegionDriver()
 	if err != nil {
 		return nil, err
@@ -300,6 +316,26 @@ func (manager *SSecurityGroupRuleManager) ValidateCreateData(ctx context.Context
 	return input, nil
 }
 
+// validatePeerSecgroup resolves peerId to the id of an on-premise security
+// group. Member addresses of peer secgroup are only maintained by vpcagent
+func (manager *SSecurityGroupRuleManager) validatePeerSecgroup(ctx context.Context, userCred mcclient.TokenCredential, secgroup *SSecurityGroup, peerId *string) error {
+	if len(*peerId) == 0 {
+		return nil
+	}
+	if len(secgroup.ManagerId) > 0 {
+		return httperrors.NewUnsupportOperationError("peer security group rule is not supported for managed security group %s", secgroup.Name)
+	}
+	_peer, err := validators.ValidateModel(ctx, userCred, SecurityGroupManager, peerId)
+	if err != nil {
+		return err
+	}
+	peer := _peer.(*SSecurityGroup)
+	if len(peer.ManagerId) > 0 {
+		return httperrors.NewInputParameterError("peer security group %s is a managed security group", peer.Name)
+	}
+	return nil
+}
+
 func (self *SSecurityGroupRule) ValidateUpdateData(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input *api.SSecgroupRuleUpdateInput) (*api.SSecgroupRuleUpdateInput, error) {
 	secgrp, err := self.GetSecGroup()
 	if err != nil {
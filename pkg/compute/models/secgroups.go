@@ -565,6 +565,10 @@ func (self *SSecurityGroup) getSecurityRuleString() (string, error) {
 	}
 	var rules []string
 	for _, rule := range secgrouprules {
+		if len(rule.PeerSecgroupId) > 0 {
+			// peer secgroup rules only take effect in vpc
+			continue
+		}
 		rules = append(rules, rule.String())
 	}
 	return strings.Join(rules, SECURITY_GROUP_SEPARATOR), nil
@@ -629,7 +633,12 @@ func (self *SSecurityGroup) PerformAddRule(ctx context.Context, userCred mcclien
 	if err := data.Unmarshal(secgrouprule); err != nil {
 		return nil, err
 	}
-	if len(secgrouprule.CIDR) > 0 {
+	if err := SecurityGroupRuleManager.validatePeerSecgroup(ctx, userCred, self, &secgrouprule.PeerSecgroupId); err != nil {
+		return nil, err
+	}
+	if len(secgrouprule.PeerSecgroupId) > 0 {
+		secgrouprule.CIDR = ""
+	} else if len(secgrouprule.CIDR) > 0 {
 		if !regutils.MatchCIDR(secgrouprule.CIDR) && !regutils.MatchIPAddr(secgrouprule.CIDR) {
 			return nil, httperrors.NewInputParameterError("invalid ip address: %s", secgrouprule.CIDR)
 		}
@@ -711,6 +720,7 @@ func (self *SSecurityGroup) PerformClone(ctx context.Context, userCred mcclient.
 		secgrouprule.Ports = rule.Ports
 		secgrouprule.Direction = rule.Direction
 		secgrouprule.CIDR = rule.CIDR
+		secgrouprule.PeerSecgroupId = rule.PeerSecgroupId
 		secgrouprule.Action = rule.Action
 		secgrouprule.Description = rule.Description
 		secgrouprule.SecgroupId = secgroup.Id
@@ -1056,6 +1066,13 @@ func (self *SSecurityGroup) ValidateDeleteCondition(ctx context.Context, info ap
 	if info.TotalCnt > 0 {
 		return httperrors.NewNotEmptyError("the security group %s is in use cnt: %d", self.Id, info.TotalCnt)
 	}
+	peerCnt, err := SecurityGroupRuleManager.Query().Equals("peer_secgroup_id", self.Id).NotEquals("secgroup_id", self.Id).CountWithError()
+	if err != nil {
+		return httperrors.NewGeneralError(errors.Wrapf(err, "count peer rules"))
+	}
+	if peerCnt > 0 {
+		return httperrors.NewNotEmptyError("the security group %s is referenced by %d rules of other security groups", self.Id, peerCnt)
+	}
 	return self.SSharableVirtualResourceBase.ValidateDeleteCondition(ctx, nil)
 }
 
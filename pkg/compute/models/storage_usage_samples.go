@@ -0,0 +1,226 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/tristate"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+)
+
+// SStorageUsageSampleManager keeps the time series of storage capacity and
+// actual usage reported by hosts, which is used to predict when a thin
+// provisioned storage runs out of space
+type SStorageUsageSampleManager struct {
+	db.SResourceBaseManager
+}
+
+var StorageUsageSampleManager *SStorageUsageSampleManager
+
+func init() {
+	StorageUsageSampleManager = &SStorageUsageSampleManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SStorageUsageSample{},
+			"storage_usage_samples_tbl",
+			"storage_usage_sample",
+			"storage_usage_samples",
+		),
+	}
+	StorageUsageSampleManager.SetVirtualObject(StorageUsageSampleManager)
+}
+
+type SStorageUsageSample struct {
+	db.SResourceBase
+
+	RowId int64 `primary:"true" auto_increment:"true" list:"user"`
+
+	StorageId string `width:"36" charset:"ascii" nullable:"false" index:"true" list:"user"`
+	// 容量, 单位MB
+	Capacity int64 `nullable:"false" list:"user"`
+	// 实际使用量, 单位MB
+	ActualCapacityUsed int64 `nullable:"false" list:"user"`
+	// 已分配容量, 单位MB
+	AllocatedCapacity int64 `nullable:"false" list:"user"`
+}
+
+func (self *SStorage) getUsageSamples(since time.Time) ([]SStorageUsageSample, error) {
+	q := StorageUsageSampleManager.Query().Equals("storage_id", self.Id)
+	if !since.IsZero() {
+		q = q.GE("created_at", since)
+	}
+	q = q.Asc("row_id")
+	samples := []SStorageUsageSample{}
+	err := db.FetchModelObjects(StorageUsageSampleManager, q, &samples)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return samples, nil
+}
+
+// storageUsageForecast fits the actual usage of samples against time with
+// least squares and returns the growth per day.  The exhaust time is only
+// returned if usage is growing
+func storageUsageForecast(samples []SStorageUsageSample) (float64, *time.Time) {
+	if len(samples) < 2 {
+		return 0, nil
+	}
+	first := samples[0].CreatedAt
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range samples {
+		x := samples[i].CreatedAt.Sub(first).Hours() / 24
+		y := float64(samples[i].ActualCapacityUsed)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(samples))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0, nil
+	}
+	growth := (n*sumXY - sumX*sumY) / denom
+	if growth <= 0 {
+		return growth, nil
+	}
+	last := samples[len(samples)-1]
+	exhaustAt := last.CreatedAt
+	if free := last.Capacity - last.ActualCapacityUsed; free > 0 {
+		days := float64(free) / growth
+		// beyond 100 years nothing meaningful can be predicted
+		if days > 36500 {
+			return growth, nil
+		}
+		exhaustAt = exhaustAt.Add(time.Duration(days * 24 * float64(time.Hour)))
+	}
+	return growth, &exhaustAt
+}
+
+// RecordUsageSample appends the current usage of storage to its time series
+// at most once every StorageUsageSampleIntervalMinutes, purges the samples
+// out of retention and refreshes the predicted exhaust time
+func (self *SStorage) RecordUsageSample(ctx context.Context, userCred mcclient.TokenCredential) error {
+	latest := SStorageUsageSample{}
+	err := StorageUsageSampleManager.Query().Equals("storage_id", self.Id).Desc("row_id").First(&latest)
+	if err == nil && time.Since(latest.CreatedAt) < time.Duration(options.Options.StorageUsageSampleIntervalMinutes)*time.Minute {
+		return nil
+	}
+
+	sample := &SStorageUsageSample{
+		StorageId:          self.Id,
+		Capacity:           self.Capacity,
+		ActualCapacityUsed: self.ActualCapacityUsed,
+		AllocatedCapacity:  self.GetUsedCapacity(tristate.None),
+	}
+	sample.SetModelManager(StorageUsageSampleManager, sample)
+	err = StorageUsageSampleManager.TableSpec().Insert(ctx, sample)
+	if err != nil {
+		return errors.Wrap(err, "insert sample")
+	}
+
+	retention := time.Now().AddDate(0, 0, -options.Options.StorageUsageHistoryDays)
+	expired := StorageUsageSampleManager.Query("row_id").Equals("storage_id", self.Id).LT("created_at", retention)
+	pair := purgePair{manager: StorageUsageSampleManager, key: "row_id", q: expired}
+	err = pair.purgeAll(ctx)
+	if err != nil {
+		return errors.Wrap(err, "purge expired samples")
+	}
+
+	samples, err := self.getUsageSamples(time.Time{})
+	if err != nil {
+		return errors.Wrap(err, "getUsageSamples")
+	}
+	growth, exhaustAt := storageUsageForecast(samples)
+	meta := map[string]interface{}{
+		api.STORAGE_METADATA_ACTUAL_GROWTH_MB_PER_DAY: fmt.Sprintf("%.2f", growth),
+		api.STORAGE_METADATA_ACTUAL_EXHAUST_AT:        "none",
+	}
+	if exhaustAt != nil {
+		meta[api.STORAGE_METADATA_ACTUAL_EXHAUST_AT] = exhaustAt.UTC().Format(time.RFC3339)
+	}
+	return self.SetAllMetadata(ctx, meta, userCred)
+}
+
+// 获取存储容量和实际用量的历史采样及耗尽预测
+func (self *SStorage) GetDetailsUsageHistory(ctx context.Context, userCred mcclient.TokenCredential, input api.StorageUsageHistoryInput) (*api.StorageUsageHistoryOutput, error) {
+	since := time.Time{}
+	if input.Days > 0 {
+		since = time.Now().AddDate(0, 0, -input.Days)
+	}
+	samples, err := self.getUsageSamples(since)
+	if err != nil {
+		return nil, err
+	}
+	ret := &api.StorageUsageHistoryOutput{
+		Data: []api.StorageUsageSample{},
+	}
+	for i := range samples {
+		ret.Data = append(ret.Data, api.StorageUsageSample{
+			SampledAt:          samples[i].CreatedAt,
+			Capacity:           samples[i].Capacity,
+			ActualCapacityUsed: samples[i].ActualCapacityUsed,
+			AllocatedCapacity:  samples[i].AllocatedCapacity,
+		})
+	}
+	growth, exhaustAt := storageUsageForecast(samples)
+	ret.ActualGrowthMbPerDay = math.Round(growth*100) / 100
+	ret.ActualExhaustAt = exhaustAt
+	return ret, nil
+}
+
+// SyncDiskActualUsages saves the space actually consumed by disks on storage
+// as reported by host
+func (self *SStorage) SyncDiskActualUsages(stats []api.SDiskActualUsage) {
+	if len(stats) == 0 {
+		return
+	}
+	usages := make(map[string]int64, len(stats))
+	ids := make([]string, 0, len(stats))
+	for _, stat := range stats {
+		usages[stat.DiskId] = stat.ActualSizeMb
+		ids = append(ids, stat.DiskId)
+	}
+	q := DiskManager.Query().Equals("storage_id", self.Id).In("id", ids)
+	disks := []SDisk{}
+	err := db.FetchModelObjects(DiskManager, q, &disks)
+	if err != nil {
+		log.Errorf("fetch disks of storage %s: %s", self.Name, err)
+		return
+	}
+	for i := range disks {
+		disk := &disks[i]
+		actual := usages[disk.Id]
+		if disk.ActualSizeMb == actual {
+			continue
+		}
+		_, err := db.Update(disk, func() error {
+			disk.ActualSizeMb = actual
+			return nil
+		})
+		if err != nil {
+			log.Errorf("update actual size of disk %s: %s", disk.Name, err)
+		}
+	}
+}
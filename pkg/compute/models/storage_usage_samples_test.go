@@ -0,0 +1,72 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+)
+
+func newUsageSample(at time.Time, capacity, used int64) SStorageUsageSample {
+	return SStorageUsageSample{
+		SResourceBase:      db.SResourceBase{CreatedAt: at},
+		Capacity:           capacity,
+		ActualCapacityUsed: used,
+	}
+}
+
+func TestStorageUsageForecast(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	growth, exhaustAt := storageUsageForecast([]SStorageUsageSample{newUsageSample(start, 1000, 100)})
+	if growth != 0 || exhaustAt != nil {
+		t.Errorf("single sample: want no forecast, got %f %v", growth, exhaustAt)
+	}
+
+	growing := []SStorageUsageSample{
+		newUsageSample(start, 1000, 100),
+		newUsageSample(start.Add(day), 1000, 200),
+		newUsageSample(start.Add(2*day), 1000, 300),
+	}
+	growth, exhaustAt = storageUsageForecast(growing)
+	if growth < 99.99 || growth > 100.01 {
+		t.Errorf("growing: want growth 100, got %f", growth)
+	}
+	want := start.Add(9 * day)
+	if exhaustAt == nil || exhaustAt.Sub(want).Abs() > time.Minute {
+		t.Errorf("growing: want exhaust at %s, got %v", want, exhaustAt)
+	}
+
+	shrinking := []SStorageUsageSample{
+		newUsageSample(start, 1000, 300),
+		newUsageSample(start.Add(day), 1000, 200),
+	}
+	growth, exhaustAt = storageUsageForecast(shrinking)
+	if growth >= 0 || exhaustAt != nil {
+		t.Errorf("shrinking: want negative growth and no exhaust, got %f %v", growth, exhaustAt)
+	}
+
+	full := []SStorageUsageSample{
+		newUsageSample(start, 1000, 900),
+		newUsageSample(start.Add(day), 1000, 1000),
+	}
+	_, exhaustAt = storageUsageForecast(full)
+	if exhaustAt == nil || !exhaustAt.Equal(start.Add(day)) {
+		t.Errorf("full: want exhausted at last sample, got %v", exhaustAt)
+	}
+}
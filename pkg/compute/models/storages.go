@@ -858,6 +858,24 @@ func (self *SStorage) GetCapacity() int64 {
 	return self.Capacity - self.GetReserved()
 }
 
+// GetActualUnreportedCapacity returns the allocated size of disks on the
+// storage whose actual size is not reported by the host yet
+func (self *SStorage) GetActualUnreportedCapacity() int64 {
+	disks := DiskManager.Query().SubQuery()
+	q := disks.Query(sqlchemy.SUM("sum", disks.Field("disk_size"))).Equals("storage_id", self.Id)
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.IsNull(disks.Field("actual_size_mb")),
+		sqlchemy.Equals(disks.Field("actual_size_mb"), 0),
+	))
+	var sum sql.NullInt64
+	err := q.Row().Scan(&sum)
+	if err != nil {
+		log.Errorf("GetActualUnreportedCapacity fail: %s", err)
+		return 0
+	}
+	return sum.Int64
+}
+
 func (self *SStorage) GetFreeCapacity() int64 {
 	return int64(float32(self.GetCapacity())*self.GetOvercommitBound()) - self.GetUsedCapacity(tristate.None)
 }
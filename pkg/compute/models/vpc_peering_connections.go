@@ -17,6 +17,7 @@ package models
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"strings"
 
 	"yunion.io/x/cloudmux/pkg/cloudprovider"
@@ -27,6 +28,7 @@ import (
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/httperrors"
 	"yunion.io/x/onecloud/pkg/mcclient"
@@ -63,6 +65,9 @@ type SVpcPeeringConnection struct {
 	PeerVpcId        string `width:"36" charset:"ascii" nullable:"true" list:"domain" create:"required" json:"peer_vpc_id"`
 	PeerAccountId    string `width:"36" charset:"ascii" nullable:"true" list:"domain"`
 	Bandwidth        int    `nullable:"false" default:"0" list:"user" create:"optional"`
+
+	// 本地IDC vpc对等连接互联网段
+	TransitCidr string `width:"32" charset:"ascii" nullable:"true" list:"domain"`
 }
 
 func (manager *SVpcPeeringConnectionManager) GetContextManagers() [][]db.IModelManager {
@@ -143,8 +148,11 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 	}
 	peerVpc := _peerVpc.(*SVpc)
 
+	if len(vpc.ManagerId) == 0 && len(peerVpc.ManagerId) == 0 {
+		return manager.validateOnPremiseCreateData(vpc, peerVpc, input)
+	}
 	if len(vpc.ManagerId) == 0 || len(peerVpc.ManagerId) == 0 {
-		return input, httperrors.NewInputParameterError("Only public cloud support vpcpeering")
+		return input, httperrors.NewInputParameterError("vpc peering between on-premise and public cloud vpc is not supported")
 	}
 
 	// get account,providerFactory
@@ -161,31 +169,8 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 
 	// check vpc ip range overlap
 	if !factory.IsSupportVpcPeeringVpcCidrOverlap() {
-		vpcIpv4Ranges := []netutils.IPV4AddrRange{}
-		peervpcIpv4Ranges := []netutils.IPV4AddrRange{}
-		vpcCidrBlocks := strings.Split(vpc.CidrBlock, ",")
-		peervpcCidrBlocks := strings.Split(peerVpc.CidrBlock, ",")
-		for i := range vpcCidrBlocks {
-			vpcIpv4Range, err := netutils.NewIPV4Prefix(vpcCidrBlocks[i])
-			if err != nil {
-				return input, httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", vpcCidrBlocks[i]))
-			}
-			vpcIpv4Ranges = append(vpcIpv4Ranges, vpcIpv4Range.ToIPRange())
-		}
-
-		for i := range peervpcCidrBlocks {
-			peervpcIpv4Range, err := netutils.NewIPV4Prefix(peervpcCidrBlocks[i])
-			if err != nil {
-				return input, httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", peervpcCidrBlocks[i]))
-			}
-			peervpcIpv4Ranges = append(peervpcIpv4Ranges, peervpcIpv4Range.ToIPRange())
-		}
-		for i := range vpcIpv4Ranges {
-			for j := range peervpcIpv4Ranges {
-				if vpcIpv4Ranges[i].IsOverlap(peervpcIpv4Ranges[j]) {
-					return input, httperrors.NewNotSupportedError("ipv4 range overlap")
-				}
-			}
+		if err := checkVpcCidrOverlap(vpc, peerVpc); err != nil {
+			return input, err
 		}
 	}
 
@@ -218,6 +203,111 @@ func (manager *SVpcPeeringConnectionManager) ValidateCreateData(
 	return input, nil
 }
 
+func checkVpcCidrOverlap(vpc, peerVpc *SVpc) error {
+	vpcIpv4Ranges := []netutils.IPV4AddrRange{}
+	peervpcIpv4Ranges := []netutils.IPV4AddrRange{}
+	vpcCidrBlocks := strings.Split(vpc.CidrBlock, ",")
+	peervpcCidrBlocks := strings.Split(peerVpc.CidrBlock, ",")
+	for i := range vpcCidrBlocks {
+		vpcIpv4Range, err := netutils.NewIPV4Prefix(vpcCidrBlocks[i])
+		if err != nil {
+			return httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", vpcCidrBlocks[i]))
+		}
+		vpcIpv4Ranges = append(vpcIpv4Ranges, vpcIpv4Range.ToIPRange())
+	}
+
+	for i := range peervpcCidrBlocks {
+		peervpcIpv4Range, err := netutils.NewIPV4Prefix(peervpcCidrBlocks[i])
+		if err != nil {
+			return httperrors.NewGeneralError(errors.Wrapf(err, "convert vpc cidr %s to ipv4range error", peervpcCidrBlocks[i]))
+		}
+		peervpcIpv4Ranges = append(peervpcIpv4Ranges, peervpcIpv4Range.ToIPRange())
+	}
+	for i := range vpcIpv4Ranges {
+		for j := range peervpcIpv4Ranges {
+			if vpcIpv4Ranges[i].IsOverlap(peervpcIpv4Ranges[j]) {
+				return httperrors.NewNotSupportedError("ipv4 range overlap")
+			}
+		}
+	}
+	return nil
+}
+
+// validateOnPremiseCreateData validates peering of two onecloud vpcs, which is
+// realized by vpcagent connecting their logical routers
+func (manager *SVpcPeeringConnectionManager) validateOnPremiseCreateData(vpc, peerVpc *SVpc, input api.VpcPeeringConnectionCreateInput) (api.VpcPeeringConnectionCreateInput, error) {
+	if vpc.Id == api.DEFAULT_VPC_ID || peerVpc.Id == api.DEFAULT_VPC_ID {
+		return input, httperrors.NewInputParameterError("default vpc does not support vpc peering")
+	}
+	if vpc.Id == peerVpc.Id {
+		return input, httperrors.NewInputParameterError("vpc can not peer with itself")
+	}
+	if vpc.CloudregionId != peerVpc.CloudregionId {
+		return input, httperrors.NewNotSupportedError("on-premise vpc peering across regions is not supported")
+	}
+	if err := checkVpcCidrOverlap(vpc, peerVpc); err != nil {
+		return input, err
+	}
+
+	q := manager.Query()
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.AND(sqlchemy.Equals(q.Field("vpc_id"), vpc.Id), sqlchemy.Equals(q.Field("peer_vpc_id"), peerVpc.Id)),
+		sqlchemy.AND(sqlchemy.Equals(q.Field("vpc_id"), peerVpc.Id), sqlchemy.Equals(q.Field("peer_vpc_id"), vpc.Id)),
+	))
+	cnt, err := q.CountWithError()
+	if err != nil {
+		return input, httperrors.NewGeneralError(err)
+	}
+	if cnt > 0 {
+		return input, httperrors.NewNotSupportedError("vpc %s and vpc %s have already connected", vpc.Name, peerVpc.Name)
+	}
+	input.VpcId = vpc.Id
+	input.PeerVpcId = peerVpc.Id
+	input.Bandwidth = 0
+	return input, nil
+}
+
+// AllocateTransitCidr assigns a free /30 subnet for linking logical routers of
+// on-premise vpc peering
+func (self *SVpcPeeringConnection) AllocateTransitCidr(ctx context.Context) error {
+	if len(self.TransitCidr) > 0 {
+		return nil
+	}
+	lockman.LockClass(ctx, VpcPeeringConnectionManager, "")
+	defer lockman.ReleaseClass(ctx, VpcPeeringConnectionManager, "")
+
+	q := VpcPeeringConnectionManager.Query("transit_cidr").IsNotEmpty("transit_cidr")
+	rows, err := q.Rows()
+	if err != nil {
+		return errors.Wrap(err, "query transit cidrs")
+	}
+	defer rows.Close()
+	used := map[string]struct{}{}
+	for rows.Next() {
+		var cidr string
+		if err := rows.Scan(&cidr); err != nil {
+			return errors.Wrap(err, "scan transit cidr")
+		}
+		used[cidr] = struct{}{}
+	}
+
+	transit := api.VpcPeeringTransitCidr()
+	ipRange := transit.ToIPRange()
+	step := netutils.IPV4Addr(1 << (32 - api.VpcPeeringTransitMask))
+	for addr := ipRange.StartIp(); addr < ipRange.EndIp(); addr += step {
+		cidr := fmt.Sprintf("%s/%d", addr.String(), api.VpcPeeringTransitMask)
+		if _, ok := used[cidr]; ok {
+			continue
+		}
+		_, err := db.Update(self, func() error {
+			self.TransitCidr = cidr
+			return nil
+		})
+		return err
+	}
+	return errors.Wrap(httperrors.ErrOutOfResource, "no free transit cidr")
+}
+
 func (self *SVpcPeeringConnection) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
 	params := jsonutils.NewDict()
 	task, err := taskman.TaskManager.NewTask(ctx, "VpcPeeringConnectionCreateTask", self, userCred, params, "", "", nil)
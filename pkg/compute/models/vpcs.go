@@ -258,6 +258,16 @@ func (svpc *SVpc) ValidateDeleteCondition(ctx context.Context, info *api.VpcDeta
 	if info.RequestVpcPeerCount > 0 {
 		return httperrors.NewNotEmptyError("VPC not empty, please delete vpc peering first")
 	}
+	if len(svpc.ManagerId) == 0 && info.AcceptVpcPeerCount > 0 {
+		return httperrors.NewNotEmptyError("VPC not empty, please delete vpc peering first")
+	}
+	flowLogCnt, err := FlowLogManager.Query().Equals("vpc_id", svpc.Id).CountWithError()
+	if err != nil {
+		return httperrors.NewGeneralError(errors.Wrap(err, "count flow logs"))
+	}
+	if flowLogCnt > 0 {
+		return httperrors.NewNotEmptyError("VPC not empty, please delete flow log first")
+	}
 
 	return svpc.SEnabledStatusInfrasResourceBase.ValidateDeleteCondition(ctx, nil)
 }
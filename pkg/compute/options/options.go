@@ -127,6 +127,17 @@ type ComputeOptions struct {
 	// cloud image sync
 	CloudImagesSyncIntervalHours int `default:"3" help:"Interval to sync public cloud image, defualt is 3 hour"`
 
+	// dnssec key rollover
+	DnssecZskLifetimeDays     int `default:"30" help:"Days a zone signing key is used before being rolled over, default is 30 days"`
+	DnssecKeyPropagationHours int `default:"48" help:"Hours a newly published or retired dnssec key is kept in the DNSKEY set to let caches expire, default is 48 hours"`
+	DnssecKskRetireDays       int `default:"7" help:"Days a retired key signing key is kept to let the DS record of parent zone be updated, default is 7 days"`
+
+	// acme certificates of loadbalancer
+	AcmeDirectoryUrl                string `default:"https://acme-v02.api.letsencrypt.org/directory" help:"Default ACME directory url of loadbalancer certificates"`
+	AcmeCaBundle                    string `help:"Path of extra CA certificates to trust when talking to the ACME server, e.g. the root of a local Pebble server"`
+	AcmeRenewBeforeDays             int    `default:"30" help:"Days before expiry to renew acme certificates, default is 30 days"`
+	AcmeChallengePropagationSeconds int    `default:"30" help:"Seconds to wait for challenges to be propagated to lbagent or dns servers before asking the ACME server to validate them"`
+
 	// 由云管(Cloudpods)负责分配IP地址，默认为false。默认是由对应具备IPAM能力的云平台自主分配IP地址
 	EnablePreAllocateIpAddr bool `help:"Enable private and public cloud private ip pre allocate, default false" default:"false"`
 
@@ -164,11 +175,18 @@ type ComputeOptions struct {
 	GuestTemplateCheckInterval int `help:"interval between two consecutive inspections of Guest Template in hour unit" default:"12"`
 
 	ReconcileGuestBackupIntervalSeconds int `help:"interval reconcile guest bakcups" default:"30"`
+	DiskReplicaCheckIntervalSeconds     int `help:"interval of checking status and lag of disk replicas" default:"30"`
+
+	DrProtectionGroupSyncIntervalSeconds int `help:"interval of taking due recovery points of dr protection groups" default:"60"`
 
 	EnableAutoRenameProject bool `help:"when it set true, auto create project will rename when cloud project name changed" default:"false"`
 
 	SyncStorageCapacityUsedIntervalMinutes int  `help:"interval sync storage capacity used" default:"20"`
 	LockStorageFromCachedimage             bool `help:"must use storage in where selected cachedimage when creating vm"`
+	StorageUsageSampleIntervalMinutes      int  `help:"interval of recording storage usage samples used to predict storage exhaustion" default:"60"`
+	StorageUsageHistoryDays                int  `help:"days of storage usage samples kept" default:"30"`
+
+	VirtiofsAllowedHostPaths []string `help:"host directories which can be shared to guests through virtiofs by non system admin users"`
 
 	AutoReconcileBackupServers   bool `help:"auto reconcile backup servers" default:"false"`
 	SetKVMServerAsDaemonOnCreate bool `help:"set kvm guest as daemon server on create" default:"false"`
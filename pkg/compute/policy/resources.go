@@ -35,6 +35,9 @@ var (
 		// "reservedips",
 		"policy_definitions",
 		"schedtags",
+		"disk_replicas",
+		"dr_protection_groups",
+		"dr_recovery_points",
 	}
 	computeDomainResources = []string{
 		"cloudaccounts",
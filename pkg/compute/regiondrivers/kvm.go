@@ -201,21 +201,125 @@ func (self *SKVMRegionDriver) IsSupportLoadbalancerListenerRuleRedirect() bool {
 }
 
 func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerRuleData(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, input *api.LoadbalancerListenerRuleCreateInput) (*api.LoadbalancerListenerRuleCreateInput, error) {
+	err := api.ValidateHTTPRequestRateLimits(input.HttpRequstRate, 0, input.HttpRequestRatePerHeader, input.HttpRequestRateHeader)
+	if err != nil {
+		return nil, err
+	}
+	if err := input.HttpHeaderRewrites.Validate(); err != nil {
+		return nil, err
+	}
 	return input, nil
 }
 
 func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerRuleData(ctx context.Context, userCred mcclient.TokenCredential, input *api.LoadbalancerListenerRuleUpdateInput) (*api.LoadbalancerListenerRuleUpdateInput, error) {
+	if input.HttpRequestRateHeader != nil && len(*input.HttpRequestRateHeader) > 0 {
+		if err := api.ValidateHTTPHeaderName(*input.HttpRequestRateHeader); err != nil {
+			return nil, err
+		}
+	}
+	if input.HttpRequestRatePerHeader != nil && *input.HttpRequestRatePerHeader < 0 {
+		return nil, httperrors.NewInputParameterError("invalid http_request_rate_per_header %d", *input.HttpRequestRatePerHeader)
+	}
+	if input.HttpHeaderRewrites != nil {
+		if err := input.HttpHeaderRewrites.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	return input, nil
 }
 
+// validateLoadbalancerListenerL7 checks that rate limits and header rewrites
+// are only set on http, https listeners, and connection limits not on udp
+// listeners which are served by gobetween instead of haproxy
+func validateLoadbalancerListenerL7(listenerType string, ratePerHeader int, rewrites api.SLoadbalancerHTTPHeaderRewrites, maxConn, maxConnPerSrc int) error {
+	switch listenerType {
+	case api.LB_LISTENER_TYPE_HTTP, api.LB_LISTENER_TYPE_HTTPS:
+	default:
+		if ratePerHeader > 0 {
+			return httperrors.NewInputParameterError("http_request_rate_per_header is not supported by %s listener", listenerType)
+		}
+		if len(rewrites) > 0 {
+			return httperrors.NewInputParameterError("http_header_rewrites is not supported by %s listener", listenerType)
+		}
+	}
+	if listenerType == api.LB_LISTENER_TYPE_UDP && (maxConn > 0 || maxConnPerSrc > 0) {
+		return httperrors.NewInputParameterError("connection limits are not supported by %s listener", listenerType)
+	}
+	return nil
+}
+
 func (self *SKVMRegionDriver) ValidateCreateLoadbalancerListenerData(ctx context.Context, userCred mcclient.TokenCredential,
 	ownerId mcclient.IIdentityProvider, input *api.LoadbalancerListenerCreateInput,
 	lb *models.SLoadbalancer, lbbg *models.SLoadbalancerBackendGroup) (*api.LoadbalancerListenerCreateInput, error) {
+	err := validateLoadbalancerListenerL7(input.ListenerType, input.HttpRequestRatePerHeader, input.HttpHeaderRewrites, input.MaxConn, input.MaxConnPerSrc)
+	if err != nil {
+		return nil, err
+	}
+	if lb.IsEnvoyDataPlane() {
+		err := api.ValidateEnvoyDataPlaneLimits(input.MaxConnPerSrc, input.HttpRequestRatePerSrc, input.HttpRequestRatePerHeader)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return input, nil
 }
 
 func (self *SKVMRegionDriver) ValidateUpdateLoadbalancerListenerData(ctx context.Context, userCred mcclient.TokenCredential,
 	lblis *models.SLoadbalancerListener, input *api.LoadbalancerListenerUpdateInput) (*api.LoadbalancerListenerUpdateInput, error) {
+	{
+		// check against current settings of the listener
+		var (
+			rate          = lblis.HTTPRequestRate
+			ratePerSrc    = lblis.HTTPRequestRatePerSrc
+			ratePerHeader = lblis.HTTPRequestRatePerHeader
+			rateHeader    = lblis.HTTPRequestRateHeader
+			maxConn       = lblis.MaxConn
+			maxConnPerSrc = lblis.MaxConnPerSrc
+			rewrites      api.SLoadbalancerHTTPHeaderRewrites
+		)
+		if lblis.HTTPHeaderRewrites != nil {
+			rewrites = *lblis.HTTPHeaderRewrites
+		}
+		if input.HttpRequestRate != nil {
+			rate = *input.HttpRequestRate
+		}
+		if input.HttpRequestRatePerSrc != nil {
+			ratePerSrc = *input.HttpRequestRatePerSrc
+		}
+		if input.HttpRequestRatePerHeader != nil {
+			ratePerHeader = *input.HttpRequestRatePerHeader
+		}
+		if input.HttpRequestRateHeader != nil {
+			rateHeader = *input.HttpRequestRateHeader
+		}
+		if input.MaxConn != nil {
+			maxConn = *input.MaxConn
+		}
+		if input.MaxConnPerSrc != nil {
+			maxConnPerSrc = *input.MaxConnPerSrc
+		}
+		if input.HttpHeaderRewrites != nil {
+			rewrites = *input.HttpHeaderRewrites
+		}
+		if err := api.ValidateHTTPRequestRateLimits(rate, ratePerSrc, ratePerHeader, rateHeader); err != nil {
+			return nil, err
+		}
+		if err := api.ValidateConnLimits(maxConn, maxConnPerSrc); err != nil {
+			return nil, err
+		}
+		if err := validateLoadbalancerListenerL7(lblis.ListenerType, ratePerHeader, rewrites, maxConn, maxConnPerSrc); err != nil {
+			return nil, err
+		}
+		lb, err := lblis.GetLoadbalancer()
+		if err != nil {
+			return nil, errors.Wrap(err, "GetLoadbalancer")
+		}
+		if lb.IsEnvoyDataPlane() {
+			if err := api.ValidateEnvoyDataPlaneLimits(maxConnPerSrc, ratePerSrc, ratePerHeader); err != nil {
+				return nil, err
+			}
+		}
+	}
 	/*
 
 		certV := validators.NewModelIdOrNameValidator("certificate", "loadbalancercertificate", ownerId)
@@ -964,6 +1068,12 @@ func (self *SKVMRegionDriver) RequestPackInstanceBackup(ctx context.Context, ib
 	if err != nil {
 		return errors.Wrap(err, "unable to PackMetadata")
 	}
+	metadata.ParentPackageName, _ = task.GetParams().GetString("parent_package_name")
+	for i := range metadata.DiskMetadatas {
+		if metadata.DiskMetadatas[i].Incremental && len(metadata.ParentPackageName) == 0 {
+			return errors.Errorf("incremental disk backup %s without parent package", backupIds[i])
+		}
+	}
 	url := fmt.Sprintf("%s/storages/pack-instance-backup", host.ManagerUri)
 	body := jsonutils.NewDict()
 	body.Set("package_name", jsonutils.NewString(packageName))
@@ -1160,8 +1270,44 @@ func (self *SKVMRegionDriver) RequestSyncSnapshotStatus(ctx context.Context, use
 	return nil
 }
 
+func (self *SKVMRegionDriver) IsSupportedNatGateway() bool {
+	return true
+}
+
+func (self *SKVMRegionDriver) ValidateCreateNatGateway(ctx context.Context, userCred mcclient.TokenCredential, input api.NatgatewayCreateInput) (api.NatgatewayCreateInput, error) {
+	if input.VpcId == api.DEFAULT_VPC_ID {
+		return input, httperrors.NewUnsupportOperationError("natgateway is not supported in default vpc")
+	}
+	if len(input.Duration) > 0 {
+		return input, httperrors.NewInputParameterError("onecloud natgateway does not support billing cycle")
+	}
+	_vpc, err := models.VpcManager.FetchById(input.VpcId)
+	if err != nil {
+		return input, errors.Wrapf(err, "fetch vpc %s", input.VpcId)
+	}
+	vpc := _vpc.(*models.SVpc)
+	if !utils.IsInStringArray(vpc.ExternalAccessMode, []string{api.VPC_EXTERNAL_ACCESS_MODE_EIP, api.VPC_EXTERNAL_ACCESS_MODE_EIP_DISTGW}) {
+		return input, httperrors.NewInputParameterError("natgateway requires eipgw of vpc %s, but external access mode is %s", vpc.Name, vpc.ExternalAccessMode)
+	}
+	return input, nil
+}
+
+// RequestAssociateEipForNAT binds eip to natgateway, an eip can be shared by multiple snat and dnat entries of the same natgateway
 func (self *SKVMRegionDriver) RequestAssociateEipForNAT(ctx context.Context, userCred mcclient.TokenCredential, nat *models.SNatGateway, eip *models.SElasticip, task taskman.ITask) error {
-	return errors.Wrapf(cloudprovider.ErrNotSupported, "RequestAssociateEipForNAT")
+	if err := eip.AssociateInstance(ctx, userCred, api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY, nat); err != nil {
+		return errors.Wrapf(err, "associate eip %s(%s) to natgateway %s(%s)", eip.Name, eip.Id, nat.Name, nat.Id)
+	}
+	if err := eip.SetStatus(ctx, userCred, api.EIP_STATUS_READY, api.EIP_STATUS_ASSOCIATE); err != nil {
+		return errors.Wrapf(err, "set eip status to %s", api.EIP_STATUS_READY)
+	}
+	return task.ScheduleRun(nil)
+}
+
+func (self *SKVMRegionDriver) RequestSyncNatGatewayStatus(ctx context.Context, userCred mcclient.TokenCredential, natgateway *models.SNatGateway, task taskman.ITask) error {
+	taskman.LocalTaskRun(task, func() (jsonutils.JSONObject, error) {
+		return nil, natgateway.SetStatus(ctx, userCred, api.NAT_STAUTS_AVAILABLE, "syncstatus")
+	})
+	return nil
 }
 
 func (self *SKVMRegionDriver) ValidateCacheSecgroup(ctx context.Context, userCred mcclient.TokenCredential, secgroup *models.SSecurityGroup, vpc *models.SVpc, classic bool) error {
@@ -1367,6 +1513,17 @@ func (self *SKVMRegionDriver) RequestCreateBackup(ctx context.Context, backup *m
 		return errors.Wrap(err, "GetAccessInfo")
 	}
 	body.Set("backup_storage_access_info", jsonutils.Marshal(accessInfo))
+	if parentBackupId, _ := task.GetParams().GetString("parent_backup_id"); len(parentBackupId) > 0 {
+		// the host ships the snapshot overlay alone when it sits right on
+		// the snapshot kept by the parent backup
+		parent, err := models.DiskBackupManager.FetchById(parentBackupId)
+		if err != nil {
+			return errors.Wrapf(err, "fetch parent backup %s", parentBackupId)
+		}
+		if baseSnapshotId := parent.(*models.SDiskBackup).BaseSnapshotId; len(baseSnapshotId) > 0 {
+			body.Set("parent_snapshot_id", jsonutils.NewString(baseSnapshotId))
+		}
+	}
 	if len(backup.EncryptKeyId) > 0 {
 		body.Set("encrypt_key_id", jsonutils.NewString(backup.EncryptKeyId))
 	}
@@ -1399,6 +1556,8 @@ func (self *SKVMRegionDriver) RequestAssociateEip(ctx context.Context, userCred
 			if err != nil {
 				return nil, err
 			}
+		case api.EIP_ASSOCIATE_TYPE_NAT_GATEWAY:
+			// realized by vpcagent as snat and dnat rules of natgateway
 		default:
 			return nil, errors.Wrapf(cloudprovider.ErrNotSupported, "instance type %s", input.InstanceType)
 		}
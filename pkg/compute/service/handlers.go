@@ -108,6 +108,9 @@ func InitHandlers(app *appsrv.Application) {
 		models.LoadbalancerSecurityGroupManager,
 
 		models.HostFileJointsManager,
+		models.DnsZoneChangeManager,
+		models.StorageUsageSampleManager,
+		models.DrProtectionGroupMemberManager,
 	} {
 		db.RegisterModelManager(manager)
 	}
@@ -132,6 +135,8 @@ func InitHandlers(app *appsrv.Application) {
 		models.SchedtagManager,
 		models.GuestManager,
 		models.GetContainerManager(),
+		models.GetContainerSecretManager(),
+		models.GetContainerConfigManager(),
 		models.GroupManager,
 		models.DiskManager,
 		models.NetworkManager,
@@ -201,6 +206,7 @@ func InitHandlers(app *appsrv.Application) {
 		models.DnsRecordManager,
 
 		models.VpcPeeringConnectionManager,
+		models.FlowLogManager,
 		models.InterVpcNetworkManager,
 
 		models.NatSkuManager,
@@ -236,6 +242,9 @@ func InitHandlers(app *appsrv.Application) {
 		models.BackupStorageManager,
 		models.DiskBackupManager,
 		models.InstanceBackupManager,
+		models.DiskReplicaManager,
+		models.DrProtectionGroupManager,
+		models.DrRecoveryPointManager,
 
 		models.IPv6GatewayManager,
 		models.TablestoreManager,
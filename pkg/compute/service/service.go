@@ -180,6 +180,8 @@ func StartServiceWithJobsAndApp(jobs func(cron *cronman.SCronJobManager), appCll
 		cron.AddJobAtIntervals("CleanExpiredPostpaidNas", time.Duration(opts.PrepaidExpireCheckSeconds)*time.Second, models.FileSystemManager.DeleteExpiredPostpaids)
 
 		cron.AddJobAtIntervals("StartHostPingDetectionTask", time.Duration(opts.HostOfflineDetectionInterval)*time.Second, models.HostManager.PingDetectionTask)
+		cron.AddJobAtIntervals("CheckDiskReplicas", time.Duration(opts.DiskReplicaCheckIntervalSeconds)*time.Second, models.DiskReplicaManager.CheckDiskReplicas)
+		cron.AddJobAtIntervals("SyncDrProtectionGroups", time.Duration(opts.DrProtectionGroupSyncIntervalSeconds)*time.Second, models.DrProtectionGroupManager.SyncDrProtectionGroups)
 
 		cron.AddJobAtIntervals("RefreshCloudproviderHostStatus", time.Duration(opts.ManagedHostSyncStatusIntervalSeconds)*time.Second, models.RefreshCloudproviderHostStatus)
 
@@ -199,6 +201,9 @@ func StartServiceWithJobsAndApp(jobs func(cron *cronman.SCronJobManager), appCll
 
 		cron.AddJobEveryFewHour("AutoCleanImageCache", 1, 5, 0, models.CachedimageManager.AutoCleanImageCaches, false)
 
+		cron.AddJobEveryFewHour("AutoRolloverDnssecKeys", 1, 15, 0, models.DnsZoneManager.AutoRolloverDnssecKeys, false)
+		cron.AddJobEveryFewHour("AutoRenewAcmeCertificates", 1, 25, 0, models.LoadbalancerCertificateManager.AutoRenewAcmeCertificates, false)
+
 		cron.AddJobAtIntervalsWithStartRun("SyncSkus", time.Duration(opts.ServerSkuSyncIntervalMinutes)*time.Minute, models.SyncServerSkus, true)
 
 		cron.AddJobEveryFewDays("SyncDBInstanceSkus", opts.SyncSkusDay, opts.SyncSkusHour, 0, 0, models.SyncDBInstanceSkus, true)
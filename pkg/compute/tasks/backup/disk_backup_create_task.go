@@ -129,10 +129,23 @@ func (self *DiskBackupCreateTask) OnSave(ctx context.Context, backup *models.SDi
 	}
 	log.Infof("data from RequestCreateBackup: %s", data)
 	sizeMb, _ := data.Int("size_mb")
+	incremental := jsonutils.QueryBoolean(data, "incremental", false)
+	parentBackupId, _ := self.Params.GetString("parent_backup_id")
+	keepSnapshot := jsonutils.QueryBoolean(self.Params, "keep_snapshot", false)
 	db.Update(backup, func() error {
 		backup.SizeMb = int(sizeMb)
+		if incremental {
+			backup.ParentBackupId = parentBackupId
+		}
+		if keepSnapshot {
+			backup.BaseSnapshotId = snapshotId
+		}
 		return nil
 	})
+	if keepSnapshot {
+		self.taksSuccess(ctx, backup, nil)
+		return
+	}
 	snapshot := snapshotModel.(*models.SSnapshot)
 	err = snapshot.StartSnapshotDeleteTask(ctx, self.UserCred, false, self.GetId(), 0, 0)
 	if err != nil {
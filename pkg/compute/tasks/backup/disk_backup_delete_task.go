@@ -62,19 +62,44 @@ func (self *DiskBackupDeleteTask) OnInit(ctx context.Context, obj db.IStandalone
 }
 
 func (self *DiskBackupDeleteTask) OnDelete(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	self.deleteBaseSnapshot(ctx, backup)
+}
+
+// deleteBaseSnapshot removes the snapshot kept on the disk for the next
+// incremental backup
+func (self *DiskBackupDeleteTask) deleteBaseSnapshot(ctx context.Context, backup *models.SDiskBackup) {
+	snapshot := backup.GetBaseSnapshot()
+	if snapshot == nil || snapshot.Status == api.SNAPSHOT_DELETING {
+		self.taskSuccess(ctx, backup, nil)
+		return
+	}
+	self.SetStage("OnBaseSnapshotDeleted", nil)
+	err := snapshot.StartSnapshotDeleteTask(ctx, self.UserCred, false, self.GetTaskId(), 0, 0)
+	if err != nil {
+		self.taskFailed(ctx, backup, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *DiskBackupDeleteTask) OnBaseSnapshotDeleted(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
 	self.taskSuccess(ctx, backup, nil)
 }
 
+func (self *DiskBackupDeleteTask) OnBaseSnapshotDeletedFailed(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, backup, data)
+}
+
 func (self *DiskBackupDeleteTask) OnDeleteFailed(ctx context.Context, backup *models.SDiskBackup, data jsonutils.JSONObject) {
 	log.Infof("params: %s", self.Params)
 	log.Infof("OnDeleteFailed data: %s", data)
 	if forceDelete := jsonutils.QueryBoolean(self.Params, "force_delete", false); !forceDelete {
 		self.taskFailed(ctx, backup, data)
+		return
 	}
 	reason, _ := data.GetString("__reason__")
 	if !strings.Contains(reason, api.BackupStorageOffline) {
 		self.taskFailed(ctx, backup, data)
+		return
 	}
 	log.Infof("delete backup %s failed, force delete", backup.GetId())
-	self.taskSuccess(ctx, backup, nil)
+	self.deleteBaseSnapshot(ctx, backup)
 }
@@ -0,0 +1,53 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type DrProtectionGroupCleanupTestTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DrProtectionGroupCleanupTestTask{})
+}
+
+func (self *DrProtectionGroupCleanupTestTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	group := obj.(*models.SDrProtectionGroup)
+	self.SetStage("OnCleanupComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, group.CleanupTestMembers(ctx, self.GetUserCred())
+	})
+}
+
+func (self *DrProtectionGroupCleanupTestTask) OnCleanupComplete(ctx context.Context, group *models.SDrProtectionGroup, data jsonutils.JSONObject) {
+	group.SetStatus(ctx, self.GetUserCred(), api.DR_PROTECTION_GROUP_STATUS_PROTECTING, "")
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DrProtectionGroupCleanupTestTask) OnCleanupCompleteFailed(ctx context.Context, group *models.SDrProtectionGroup, data jsonutils.JSONObject) {
+	// the test guests left behind can be cleaned up again
+	group.SetStatus(ctx, self.GetUserCred(), api.DR_PROTECTION_GROUP_STATUS_TEST_FAILOVER_FAILED, data.String())
+	self.SetStageFailed(ctx, data)
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type DrProtectionGroupDeleteTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DrProtectionGroupDeleteTask{})
+}
+
+func (self *DrProtectionGroupDeleteTask) taskFailed(ctx context.Context, group *models.SDrProtectionGroup, reason jsonutils.JSONObject) {
+	group.SetStatus(ctx, self.GetUserCred(), api.DR_PROTECTION_GROUP_STATUS_DELETE_FAILED, reason.String())
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *DrProtectionGroupDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	group := obj.(*models.SDrProtectionGroup)
+	rps := []models.SDrRecoveryPoint{}
+	q := models.DrRecoveryPointManager.Query().Equals("group_id", group.Id)
+	err := db.FetchModelObjects(models.DrRecoveryPointManager, q, &rps)
+	if err != nil {
+		self.taskFailed(ctx, group, jsonutils.NewString(err.Error()))
+		return
+	}
+	self.SetStage("OnRecoveryPointsDeleted", nil)
+	started := 0
+	for i := range rps {
+		if rps[i].Status == api.DR_RECOVERY_POINT_STATUS_DELETING {
+			continue
+		}
+		err := rps[i].StartDeleteTask(ctx, self.GetUserCred(), self.GetTaskId())
+		if err != nil {
+			self.taskFailed(ctx, group, jsonutils.NewString(err.Error()))
+			return
+		}
+		started++
+	}
+	if started == 0 {
+		self.OnRecoveryPointsDeleted(ctx, group, nil)
+	}
+}
+
+func (self *DrProtectionGroupDeleteTask) OnRecoveryPointsDeleted(ctx context.Context, group *models.SDrProtectionGroup, data jsonutils.JSONObject) {
+	err := group.RealDelete(ctx, self.GetUserCred())
+	if err != nil {
+		self.taskFailed(ctx, group, jsonutils.NewString(err.Error()))
+		return
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DrProtectionGroupDeleteTask) OnRecoveryPointsDeletedFailed(ctx context.Context, group *models.SDrProtectionGroup, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, group, data)
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type DrProtectionGroupFailbackTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DrProtectionGroupFailbackTask{})
+}
+
+func (self *DrProtectionGroupFailbackTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	group := obj.(*models.SDrProtectionGroup)
+	self.SetStage("OnFailbackComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, group.FailbackMembers(ctx, self.GetUserCred())
+	})
+}
+
+func (self *DrProtectionGroupFailbackTask) OnFailbackComplete(ctx context.Context, group *models.SDrProtectionGroup, data jsonutils.JSONObject) {
+	group.SetStatus(ctx, self.GetUserCred(), api.DR_PROTECTION_GROUP_STATUS_PROTECTING, "")
+	db.OpsLog.LogEvent(group, db.ACT_DR_FAILBACK, group.GetShortDesc(ctx), self.GetUserCred())
+	logclient.AddActionLogWithStartable(self, group, logclient.ACT_DR_FAILBACK, nil, self.GetUserCred(), true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DrProtectionGroupFailbackTask) OnFailbackCompleteFailed(ctx context.Context, group *models.SDrProtectionGroup, data jsonutils.JSONObject) {
+	group.SetStatus(ctx, self.GetUserCred(), api.DR_PROTECTION_GROUP_STATUS_FAILBACK_FAILED, data.String())
+	db.OpsLog.LogEvent(group, db.ACT_DR_FAILBACK_FAIL, data, self.GetUserCred())
+	logclient.AddActionLogWithStartable(self, group, logclient.ACT_DR_FAILBACK, data, self.GetUserCred(), false)
+	self.SetStageFailed(ctx, data)
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// DrProtectionGroupFailoverTask rebuilds the member guests of a group in
+// its target region, into the isolated test network when test is set
+type DrProtectionGroupFailoverTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DrProtectionGroupFailoverTask{})
+}
+
+func (self *DrProtectionGroupFailoverTask) isTest() bool {
+	return jsonutils.QueryBoolean(self.Params, "test", false)
+}
+
+func (self *DrProtectionGroupFailoverTask) taskFailed(ctx context.Context, group *models.SDrProtectionGroup, reason jsonutils.JSONObject) {
+	status, action, event := api.DR_PROTECTION_GROUP_STATUS_FAILOVER_FAILED, logclient.ACT_DR_FAILOVER, db.ACT_DR_FAILOVER_FAIL
+	if self.isTest() {
+		status, action, event = api.DR_PROTECTION_GROUP_STATUS_TEST_FAILOVER_FAILED, logclient.ACT_DR_TEST_FAILOVER, db.ACT_DR_TEST_FAILOVER_FAIL
+	}
+	group.SetStatus(ctx, self.GetUserCred(), status, reason.String())
+	db.OpsLog.LogEvent(group, event, reason, self.GetUserCred())
+	logclient.AddActionLogWithStartable(self, group, action, reason, self.GetUserCred(), false)
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *DrProtectionGroupFailoverTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	group := obj.(*models.SDrProtectionGroup)
+	if self.isTest() {
+		self.failoverMembers(ctx, group)
+		return
+	}
+	self.stopNextGuest(ctx, group)
+}
+
+// stopNextGuest stops the protected guests one by one, the copies are
+// rebuilt only after all of them stopped
+func (self *DrProtectionGroupFailoverTask) stopNextGuest(ctx context.Context, group *models.SDrProtectionGroup) {
+	guest, err := group.GetMemberGuestToStop()
+	if err != nil {
+		self.taskFailed(ctx, group, jsonutils.NewString(err.Error()))
+		return
+	}
+	if guest == nil {
+		self.failoverMembers(ctx, group)
+		return
+	}
+	params := jsonutils.NewDict()
+	params.Set("stopping_guest_id", jsonutils.NewString(guest.Id))
+	self.SetStage("OnGuestStopped", params)
+	err = guest.StartGuestStopTask(ctx, self.GetUserCred(), 60, false, false, self.GetTaskId())
+	if err != nil {
+		self.taskFailed(ctx, group, jsonutils.NewString(errors.Wrapf(err, "stop server %s", guest.Name).Error()))
+	}
+}
+
+func (self *DrProtectionGroupFailoverTask) OnGuestStopped(ctx context.Context, group *models.SDrProtectionGroup, data jsonutils.JSONObject) {
+	guestId, _ := self.Params.GetString("stopping_guest_id")
+	guest := models.GuestManager.FetchGuestById(guestId)
+	if guest != nil && guest.Status == api.VM_RUNNING {
+		self.taskFailed(ctx, group, jsonutils.NewString(fmt.Sprintf("server %s still running after stop", guest.Name)))
+		return
+	}
+	self.stopNextGuest(ctx, group)
+}
+
+func (self *DrProtectionGroupFailoverTask) OnGuestStoppedFailed(ctx context.Context, group *models.SDrProtectionGroup, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, group, data)
+}
+
+func (self *DrProtectionGroupFailoverTask) failoverMembers(ctx context.Context, group *models.SDrProtectionGroup) {
+	before := time.Time{}
+	if self.Params.Contains("recovery_point_before") {
+		before, _ = self.Params.GetTime("recovery_point_before")
+	}
+	test := self.isTest()
+	self.SetStage("OnFailoverComplete", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, group.FailoverMembers(ctx, self.GetUserCred(), test, before)
+	})
+}
+
+func (self *DrProtectionGroupFailoverTask) OnFailoverComplete(ctx context.Context, group *models.SDrProtectionGroup, data jsonutils.JSONObject) {
+	if self.isTest() {
+		group.SetStatus(ctx, self.GetUserCred(), api.DR_PROTECTION_GROUP_STATUS_TESTING, "")
+		db.OpsLog.LogEvent(group, db.ACT_DR_TEST_FAILOVER, group.GetShortDesc(ctx), self.GetUserCred())
+		logclient.AddActionLogWithStartable(self, group, logclient.ACT_DR_TEST_FAILOVER, nil, self.GetUserCred(), true)
+	} else {
+		db.Update(group, func() error {
+			group.FailedOverAt = time.Now().UTC()
+			return nil
+		})
+		group.SetStatus(ctx, self.GetUserCred(), api.DR_PROTECTION_GROUP_STATUS_FAILED_OVER, "")
+		db.OpsLog.LogEvent(group, db.ACT_DR_FAILOVER, group.GetShortDesc(ctx), self.GetUserCred())
+		logclient.AddActionLogWithStartable(self, group, logclient.ACT_DR_FAILOVER, nil, self.GetUserCred(), true)
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DrProtectionGroupFailoverTask) OnFailoverCompleteFailed(ctx context.Context, group *models.SDrProtectionGroup, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, group, data)
+}
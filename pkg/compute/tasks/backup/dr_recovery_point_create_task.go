@@ -0,0 +1,100 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type DrRecoveryPointCreateTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DrRecoveryPointCreateTask{})
+}
+
+func (self *DrRecoveryPointCreateTask) taskFailed(ctx context.Context, rp *models.SDrRecoveryPoint, reason jsonutils.JSONObject) {
+	rp.SetStatus(ctx, self.GetUserCred(), api.DR_RECOVERY_POINT_STATUS_CREATE_FAILED, reason.String())
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *DrRecoveryPointCreateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	rp := obj.(*models.SDrRecoveryPoint)
+	parent, parentBackupIds, err := rp.GetIncrementalParent()
+	if err != nil {
+		self.taskFailed(ctx, rp, jsonutils.NewString(err.Error()))
+		return
+	}
+	params := jsonutils.NewDict()
+	if parent != nil {
+		params.Set("parent_recovery_point_id", jsonutils.NewString(parent.Id))
+	}
+	self.SetStage("OnInstanceBackupCreated", params)
+	err = rp.CreateInstanceBackup(ctx, self, parentBackupIds)
+	if err != nil {
+		self.taskFailed(ctx, rp, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *DrRecoveryPointCreateTask) OnInstanceBackupCreated(ctx context.Context, rp *models.SDrRecoveryPoint, data jsonutils.JSONObject) {
+	var parent *models.SDrRecoveryPoint
+	if parentId, _ := self.GetParams().GetString("parent_recovery_point_id"); len(parentId) > 0 {
+		obj, err := models.DrRecoveryPointManager.FetchById(parentId)
+		if err != nil {
+			self.taskFailed(ctx, rp, jsonutils.NewString(errors.Wrapf(err, "fetch parent recovery point %s", parentId).Error()))
+			return
+		}
+		parent = obj.(*models.SDrRecoveryPoint)
+	}
+	self.SetStage("OnPacked", nil)
+	err := rp.PackInstanceBackup(ctx, self, parent)
+	if err != nil {
+		self.taskFailed(ctx, rp, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *DrRecoveryPointCreateTask) OnInstanceBackupCreatedFailed(ctx context.Context, rp *models.SDrRecoveryPoint, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, rp, data)
+}
+
+func (self *DrRecoveryPointCreateTask) OnPacked(ctx context.Context, rp *models.SDrRecoveryPoint, data jsonutils.JSONObject) {
+	rp.SetStatus(ctx, self.GetUserCred(), api.DR_RECOVERY_POINT_STATUS_READY, "")
+	err := rp.ReleaseBaseSnapshots(ctx, self.GetUserCred())
+	if err != nil {
+		log.Errorf("release base snapshots of server %s: %s", rp.GuestId, err)
+	}
+	group, err := rp.GetGroup()
+	if err == nil {
+		err = group.PruneRecoveryPoints(ctx, self.GetUserCred(), rp.GuestId)
+	}
+	if err != nil {
+		log.Errorf("prune recovery points of server %s: %s", rp.GuestId, err)
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DrRecoveryPointCreateTask) OnPackedFailed(ctx context.Context, rp *models.SDrRecoveryPoint, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, rp, data)
+}
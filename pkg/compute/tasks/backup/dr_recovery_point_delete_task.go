@@ -0,0 +1,83 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type DrRecoveryPointDeleteTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DrRecoveryPointDeleteTask{})
+}
+
+func (self *DrRecoveryPointDeleteTask) taskFailed(ctx context.Context, rp *models.SDrRecoveryPoint, reason jsonutils.JSONObject) {
+	rp.SetStatus(ctx, self.GetUserCred(), api.DR_RECOVERY_POINT_STATUS_DELETE_FAILED, reason.String())
+	self.SetStageFailed(ctx, reason)
+}
+
+func (self *DrRecoveryPointDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	rp := obj.(*models.SDrRecoveryPoint)
+	if len(rp.PackageName) == 0 {
+		self.OnPackageDeleted(ctx, rp, nil)
+		return
+	}
+	self.SetStage("OnPackageDeleted", nil)
+	err := rp.RequestDeletePackage(ctx, self)
+	if err != nil {
+		self.taskFailed(ctx, rp, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *DrRecoveryPointDeleteTask) OnPackageDeleted(ctx context.Context, rp *models.SDrRecoveryPoint, data jsonutils.JSONObject) {
+	ib, err := rp.GetInstanceBackup()
+	if err != nil {
+		// never created or already deleted
+		self.OnInstanceBackupDeleted(ctx, rp, nil)
+		return
+	}
+	self.SetStage("OnInstanceBackupDeleted", nil)
+	err = ib.StartInstanceBackupDeleteTask(ctx, self.GetUserCred(), self.GetTaskId(), false)
+	if err != nil {
+		self.taskFailed(ctx, rp, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *DrRecoveryPointDeleteTask) OnPackageDeletedFailed(ctx context.Context, rp *models.SDrRecoveryPoint, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, rp, data)
+}
+
+func (self *DrRecoveryPointDeleteTask) OnInstanceBackupDeleted(ctx context.Context, rp *models.SDrRecoveryPoint, data jsonutils.JSONObject) {
+	err := rp.RealDelete(ctx, self.GetUserCred())
+	if err != nil {
+		self.taskFailed(ctx, rp, jsonutils.NewString(err.Error()))
+		return
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DrRecoveryPointDeleteTask) OnInstanceBackupDeletedFailed(ctx context.Context, rp *models.SDrRecoveryPoint, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, rp, data)
+}
@@ -20,6 +20,7 @@ import (
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/utils"
 
 	"yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
@@ -93,6 +94,13 @@ func (self *InstanceBackupCreateTask) OnKvmDisksSnapshot(ctx context.Context, ib
 		backup := ibackup.(*models.SDiskBackup)
 		params := jsonutils.NewDict()
 		params.Set("snapshot_id", jsonutils.NewString(snapshotId))
+		// the snapshot kept for dr is the base of the next incremental backup
+		if keepDiskIds, _ := jsonutils.GetStringArray(self.Params, "keep_snapshot_disk_ids"); utils.IsInStringArray(backup.DiskId, keepDiskIds) {
+			params.Set("keep_snapshot", jsonutils.JSONTrue)
+		}
+		if parentBackupId, _ := self.Params.GetString("parent_backup_ids", backup.DiskId); len(parentBackupId) > 0 {
+			params.Set("parent_backup_id", jsonutils.NewString(parentBackupId))
+		}
 		if err := backup.StartBackupCreateTask(ctx, self.UserCred, params, self.Id); err != nil {
 			self.taskFailed(ctx, ib, guest, jsonutils.NewString(err.Error()), compute.INSTANCE_BACKUP_STATUS_SAVE_FAILED)
 			return
@@ -65,6 +65,9 @@ func (self *InstanceBackupRecoveryTask) OnInit(ctx context.Context, obj db.IStan
 	sourceInput.GenerateName = serverName
 	sourceInput.Description = fmt.Sprintf("recovered from instance backup %s", ib.GetName())
 	sourceInput.InstanceBackupId = ib.GetId()
+	if self.Params.Contains("networks") {
+		self.Params.Unmarshal(&sourceInput.Networks, "networks")
+	}
 
 	// PANIC ?????
 	// sourceInput.Hypervisor = compute.HYPERVISOR_KVM
@@ -95,6 +98,8 @@ func (self *InstanceBackupRecoveryTask) OnInit(ctx context.Context, obj db.IStan
 		return
 	}
 	guest := guestObj.(*models.SGuest)
+	// lets callers in other regions find the guest rebuilt from the backup
+	ib.SetMetadata(ctx, compute.INSTANCE_BACKUP_METADATA_RECOVERED_GUEST_ID, guest.Id, self.UserCred)
 
 	func() {
 		lockman.LockObject(ctx, guest)
@@ -0,0 +1,86 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+// ContainerObjectPropagateTask applies the change of container_secret or container_config
+// to the running containers which reference it
+type ContainerObjectPropagateTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(ContainerObjectPropagateTask{})
+}
+
+func (t *ContainerObjectPropagateTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	cObj := obj.(models.IContainerObject)
+	ctrs, err := models.GetContainerManager().GetContainersByObject(cObj)
+	if err != nil {
+		t.SetStageFailed(ctx, jsonutils.NewString(errors.Wrap(err, "GetContainersByObject").Error()))
+		return
+	}
+	failed := make([]string, 0)
+	for i := range ctrs {
+		ctr := &ctrs[i]
+		if !api.ContainerRunningStatus.Has(ctr.Status) {
+			continue
+		}
+		if err := t.propagate(ctx, cObj, ctr); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", ctr.GetName(), err))
+		}
+	}
+	if len(failed) > 0 {
+		t.SetStageFailed(ctx, jsonutils.Marshal(failed))
+		return
+	}
+	t.SetStageComplete(ctx, nil)
+}
+
+func (t *ContainerObjectPropagateTask) propagate(ctx context.Context, obj models.IContainerObject, ctr *models.SContainer) error {
+	policy, _ := ctr.GetObjectRefreshPolicy(obj)
+	switch policy {
+	case apis.CONTAINER_OBJECT_REFRESH_POLICY_RESTART:
+		return ctr.StartRestartTask(ctx, t.GetUserCred(), "")
+	case apis.CONTAINER_OBJECT_REFRESH_POLICY_REFRESH:
+		mounts, err := ctr.GetObjectVolumeMounts(ctx, t.GetUserCred(), obj)
+		if err != nil {
+			return errors.Wrap(err, "GetObjectVolumeMounts")
+		}
+		drv := ctr.GetPodDriver()
+		if drv == nil {
+			return errors.Errorf("pod driver of container %s not found", ctr.GetName())
+		}
+		input := &hostapi.ContainerRefreshVolumeMountsInput{VolumeMounts: mounts}
+		if _, err := drv.RequestRefreshContainerVolumeMounts(ctx, t.GetUserCred(), ctr, input); err != nil {
+			return errors.Wrap(err, "RequestRefreshContainerVolumeMounts")
+		}
+	}
+	return nil
+}
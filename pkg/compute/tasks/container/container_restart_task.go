@@ -0,0 +1,61 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type ContainerRestartTask struct {
+	ContainerBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(ContainerRestartTask{})
+}
+
+func (t *ContainerRestartTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body jsonutils.JSONObject) {
+	ctr := obj.(*models.SContainer)
+	t.SetStage("OnStopped", nil)
+	if err := ctr.StartStopTask(ctx, t.GetUserCred(), &api.ContainerStopInput{}, t.GetTaskId()); err != nil {
+		t.OnStoppedFailed(ctx, ctr, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (t *ContainerRestartTask) OnStopped(ctx context.Context, ctr *models.SContainer, data jsonutils.JSONObject) {
+	t.SetStage("OnStarted", nil)
+	if err := ctr.StartStartTask(ctx, t.GetUserCred(), t.GetTaskId()); err != nil {
+		t.OnStartedFailed(ctx, ctr, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (t *ContainerRestartTask) OnStoppedFailed(ctx context.Context, ctr *models.SContainer, reason jsonutils.JSONObject) {
+	t.SetStageFailed(ctx, reason)
+}
+
+func (t *ContainerRestartTask) OnStarted(ctx context.Context, ctr *models.SContainer, data jsonutils.JSONObject) {
+	t.SetStageComplete(ctx, nil)
+}
+
+func (t *ContainerRestartTask) OnStartedFailed(ctx context.Context, ctr *models.SContainer, reason jsonutils.JSONObject) {
+	t.SetStageFailed(ctx, reason)
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type DiskReplicaDeleteTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DiskReplicaDeleteTask{})
+}
+
+func (self *DiskReplicaDeleteTask) taskFailed(ctx context.Context, replica *models.SDiskReplica, err error) {
+	replica.SetStatus(ctx, self.GetUserCred(), api.DISK_REPLICA_STATUS_DELETE_FAILED, err.Error())
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *DiskReplicaDeleteTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	replica := obj.(*models.SDiskReplica)
+
+	if host, err := replica.GetHost(); err == nil && host.HostStatus == api.HOST_ONLINE {
+		if _, err := replica.GetGuest(); err == nil {
+			if err := replica.RequestStopMirror(ctx, self.GetUserCred()); err != nil {
+				self.taskFailed(ctx, replica, errors.Wrap(err, "RequestStopMirror"))
+				return
+			}
+		}
+	}
+
+	replicaHost, err := replica.GetReplicaHost()
+	if err == nil && replicaHost.HostStatus == api.HOST_ONLINE {
+		if err := replica.RequestStopReplica(ctx, self.GetUserCred(), true); err != nil {
+			self.taskFailed(ctx, replica, errors.Wrap(err, "RequestStopReplica"))
+			return
+		}
+	} else {
+		log.Warningf("replica host %s of disk replica %s is not online, replica image is left", replica.ReplicaHostId, replica.Name)
+	}
+
+	if err := replica.RealDelete(ctx, self.GetUserCred()); err != nil {
+		self.taskFailed(ctx, replica, errors.Wrap(err, "RealDelete"))
+		return
+	}
+	self.SetStageComplete(ctx, nil)
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"context"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+type DiskReplicaSyncTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(DiskReplicaSyncTask{})
+}
+
+func (self *DiskReplicaSyncTask) taskFailed(ctx context.Context, replica *models.SDiskReplica, status string, err error) {
+	replica.SetStatus(ctx, self.GetUserCred(), status, err.Error())
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *DiskReplicaSyncTask) isGuestRunning(replica *models.SDiskReplica) bool {
+	guest, err := replica.GetGuest()
+	if err != nil || guest.Status != api.VM_RUNNING {
+		return false
+	}
+	host, err := guest.GetHost()
+	return err == nil && host.HostStatus == api.HOST_ONLINE
+}
+
+func (self *DiskReplicaSyncTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	replica := obj.(*models.SDiskReplica)
+	if self.isGuestRunning(replica) {
+		// mirror job left by a former sync must be cancelled before mirroring again
+		if err := replica.RequestStopMirror(ctx, self.GetUserCred()); err != nil {
+			self.taskFailed(ctx, replica, api.DISK_REPLICA_STATUS_PREPARE_FAILED, errors.Wrap(err, "RequestStopMirror"))
+			return
+		}
+	}
+	self.SetStage("OnReplicaPrepared", nil)
+	if err := replica.RequestPrepareReplica(ctx, self); err != nil {
+		self.taskFailed(ctx, replica, api.DISK_REPLICA_STATUS_PREPARE_FAILED, errors.Wrap(err, "RequestPrepareReplica"))
+	}
+}
+
+func (self *DiskReplicaSyncTask) OnReplicaPrepared(ctx context.Context, replica *models.SDiskReplica, data jsonutils.JSONObject) {
+	nbdUri, _ := data.GetString("nbd_uri")
+	exportName, _ := data.GetString("export_name")
+	if len(nbdUri) == 0 || len(exportName) == 0 {
+		self.taskFailed(ctx, replica, api.DISK_REPLICA_STATUS_PREPARE_FAILED, errors.Errorf("invalid replica export %s", data))
+		return
+	}
+	_, err := db.Update(replica, func() error {
+		replica.NbdUri = nbdUri
+		return nil
+	})
+	if err != nil {
+		self.taskFailed(ctx, replica, api.DISK_REPLICA_STATUS_PREPARE_FAILED, errors.Wrap(err, "update nbd uri"))
+		return
+	}
+	if !self.isGuestRunning(replica) {
+		// mirror starts when the server is running again
+		replica.SetStatus(ctx, self.GetUserCred(), api.DISK_REPLICA_STATUS_PAUSED, "server not running")
+		self.SetStageComplete(ctx, nil)
+		return
+	}
+	// replica is inconsistent until the full mirror is ready
+	db.Update(replica, func() error {
+		replica.SyncedAt = time.Time{}
+		replica.LagBytes = 0
+		return nil
+	})
+	if err := replica.RequestStartMirror(ctx, self.GetUserCred(), exportName); err != nil {
+		self.taskFailed(ctx, replica, api.DISK_REPLICA_STATUS_BROKEN, errors.Wrap(err, "RequestStartMirror"))
+		return
+	}
+	replica.SetStatus(ctx, self.GetUserCred(), api.DISK_REPLICA_STATUS_SYNCING, "")
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *DiskReplicaSyncTask) OnReplicaPreparedFailed(ctx context.Context, replica *models.SDiskReplica, data jsonutils.JSONObject) {
+	self.taskFailed(ctx, replica, api.DISK_REPLICA_STATUS_PREPARE_FAILED, errors.Error(data.String()))
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/httputils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// GuestDiskReplicaFailoverTask restarts a guest on the replica host of its
+// local disks, the replica images take the place of the disks
+type GuestDiskReplicaFailoverTask struct {
+	SGuestBaseTask
+}
+
+func init() {
+	taskman.RegisterTask(GuestDiskReplicaFailoverTask{})
+}
+
+func (self *GuestDiskReplicaFailoverTask) taskFailed(ctx context.Context, guest *models.SGuest, err error) {
+	replicas, _ := models.DiskReplicaManager.GetReplicasByGuest(guest.Id)
+	for i := range replicas {
+		replicas[i].SetStatus(ctx, self.GetUserCred(), api.DISK_REPLICA_STATUS_FAILOVER_FAILED, err.Error())
+	}
+	guest.SetStatus(ctx, self.GetUserCred(), api.VM_MIGRATE_FAILED, err.Error())
+	db.OpsLog.LogEvent(guest, db.ACT_DISK_REPLICA_FAILOVER_FAIL, err.Error(), self.GetUserCred())
+	logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_DISK_REPLICA_FAILOVER, err.Error(), self.GetUserCred(), false)
+	self.SetStageFailed(ctx, jsonutils.NewString(err.Error()))
+}
+
+func (self *GuestDiskReplicaFailoverTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guest := obj.(*models.SGuest)
+	replicaHostId, _ := self.GetParams().GetString("replica_host_id")
+	replicaHost := models.HostManager.FetchHostById(replicaHostId)
+	if replicaHost == nil {
+		self.taskFailed(ctx, guest, errors.Errorf("replica host %s not found", replicaHostId))
+		return
+	}
+	replicas, err := models.DiskReplicaManager.GetReplicasByGuest(guest.Id)
+	if err != nil {
+		self.taskFailed(ctx, guest, errors.Wrap(err, "GetReplicasByGuest"))
+		return
+	}
+	disks, err := guest.GetDisks()
+	if err != nil {
+		self.taskFailed(ctx, guest, errors.Wrap(err, "GetDisks"))
+		return
+	}
+
+	// the replica images are going to be opened by the guest
+	for i := range replicas {
+		if err := replicas[i].RequestStopReplica(ctx, self.GetUserCred(), false); err != nil {
+			self.taskFailed(ctx, guest, errors.Wrapf(err, "stop replica %s", replicas[i].Name))
+			return
+		}
+	}
+
+	oldStorages := jsonutils.NewDict()
+	for i := range disks {
+		disk := &disks[i]
+		for j := range replicas {
+			if replicas[j].DiskId != disk.Id {
+				continue
+			}
+			oldStorages.Set(disk.Id, jsonutils.NewString(disk.StorageId))
+			_, err := db.Update(disk, func() error {
+				disk.StorageId = replicas[j].ReplicaStorageId
+				return nil
+			})
+			if err != nil {
+				self.taskFailed(ctx, guest, errors.Wrapf(err, "switch storage of disk %s", disk.Name))
+				return
+			}
+		}
+	}
+	params := jsonutils.NewDict()
+	params.Set("old_storages", oldStorages)
+	self.SetStage("OnDestPrepareComplete", params)
+
+	body := jsonutils.NewDict()
+	body.Set("is_local_storage", jsonutils.JSONFalse)
+	body.Set("qemu_version", jsonutils.NewString(guest.GetQemuVersion(self.GetUserCred())))
+	body.Set("desc", jsonutils.Marshal(guest.GetJsonDescAtHypervisor(ctx, replicaHost)))
+	url := fmt.Sprintf("%s/servers/%s/dest-prepare-migrate", replicaHost.ManagerUri, guest.Id)
+	_, _, err = httputils.JSONRequest(httputils.GetDefaultClient(), ctx, "POST", url, self.GetTaskRequestHeader(), body, false)
+	if err != nil {
+		self.OnDestPrepareCompleteFailed(ctx, guest, jsonutils.NewString(err.Error()))
+	}
+}
+
+func (self *GuestDiskReplicaFailoverTask) OnDestPrepareComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	replicaHostId, _ := self.GetParams().GetString("replica_host_id")
+	if err := guest.SetHostId(self.GetUserCred(), replicaHostId); err != nil {
+		self.taskFailed(ctx, guest, errors.Wrap(err, "SetHostId"))
+		return
+	}
+	replicas, _ := models.DiskReplicaManager.GetReplicasByGuest(guest.Id)
+	for i := range replicas {
+		replicas[i].SetStatus(ctx, self.GetUserCred(), api.DISK_REPLICA_STATUS_FAILED_OVER, "")
+		replicas[i].RealDelete(ctx, self.GetUserCred())
+	}
+	db.OpsLog.LogEvent(guest, db.ACT_DISK_REPLICA_FAILOVER, replicaHostId, self.GetUserCred())
+	logclient.AddActionLogWithContext(ctx, guest, logclient.ACT_DISK_REPLICA_FAILOVER, replicaHostId, self.GetUserCred(), true)
+
+	guest.SetStatus(ctx, self.GetUserCred(), api.VM_READY, "disk replica failover")
+	guestStatus, _ := self.GetParams().GetString("guest_status")
+	if guestStatus == api.VM_READY {
+		self.SetStageComplete(ctx, nil)
+		return
+	}
+	self.SetStage("OnGuestStartComplete", nil)
+	guest.StartGueststartTask(ctx, self.GetUserCred(), nil, self.GetTaskId())
+}
+
+func (self *GuestDiskReplicaFailoverTask) OnDestPrepareCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	// switch disks back, the replicas are still intact
+	oldStorages, _ := self.GetParams().GetMap("old_storages")
+	disks, _ := guest.GetDisks()
+	for i := range disks {
+		disk := &disks[i]
+		if storageId, ok := oldStorages[disk.Id]; ok {
+			db.Update(disk, func() error {
+				disk.StorageId, _ = storageId.GetString()
+				return nil
+			})
+		}
+	}
+	self.taskFailed(ctx, guest, errors.Errorf("dest prepare failed: %s", data))
+}
+
+func (self *GuestDiskReplicaFailoverTask) OnGuestStartComplete(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *GuestDiskReplicaFailoverTask) OnGuestStartCompleteFailed(ctx context.Context, guest *models.SGuest, data jsonutils.JSONObject) {
+	self.SetStageFailed(ctx, data)
+}
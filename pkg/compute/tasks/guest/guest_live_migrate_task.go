@@ -519,6 +519,8 @@ func (task *GuestMigrateTask) sharedStorageMigrateConf(ctx context.Context, gues
 	if err := task.setBodyMemorySnapshotParams(guest, sourceHost, body); err != nil {
 		return nil, errors.Wrap(err, "setBodyMemorySnapshotParams")
 	}
+	// serial console log moves along with guest
+	body.Set("serial_log_uri", jsonutils.NewString(fmt.Sprintf("%s/download/serial_logs", sourceHost.ManagerUri)))
 	return body, nil
 }
 
@@ -562,6 +564,8 @@ func (task *GuestMigrateTask) localStorageMigrateConf(ctx context.Context,
 	if err := task.setBodyMemorySnapshotParams(guest, sourceHost, body); err != nil {
 		return nil, errors.Wrap(err, "setBodyMemorySnapshotParams")
 	}
+	// serial console log moves along with guest
+	body.Set("serial_log_uri", jsonutils.NewString(fmt.Sprintf("%s/download/serial_logs", sourceHost.ManagerUri)))
 
 	targetDesc := guest.GetJsonDescAtHypervisor(ctx, targetHost)
 	if len(targetDesc.Disks) == 0 {
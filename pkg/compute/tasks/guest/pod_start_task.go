@@ -42,18 +42,69 @@ func (t *PodStartTask) OnInit(ctx context.Context, obj db.IStandaloneModel, body
 
 func (t *PodStartTask) OnPodStarted(ctx context.Context, pod *models.SGuest, _ jsonutils.JSONObject) {
 	pod.SetStatus(ctx, t.GetUserCred(), api.POD_STATUS_STARTING_CONTAINER, "")
+	t.startInitContainer(ctx, pod, 0)
+}
+
+// startInitContainer starts the init containers one by one,
+// the application containers are started after all of them exit successfully.
+func (t *PodStartTask) startInitContainer(ctx context.Context, pod *models.SGuest, idx int) {
 	ctrs, err := models.GetContainerManager().GetContainersByPod(pod.GetId())
 	if err != nil {
 		t.OnContainerStartedFailed(ctx, pod, jsonutils.NewString(errors.Wrap(err, "GetContainersByPod").Error()))
 		return
 	}
+	initCtrs, appCtrs := models.SplitInitContainers(ctrs)
+	if idx < len(initCtrs) {
+		ctr := &initCtrs[idx]
+		params := jsonutils.NewDict()
+		params.Set("init_index", jsonutils.NewInt(int64(idx)))
+		t.SetStage("OnInitContainerStarted", params)
+		if err := ctr.StartStartTask(ctx, t.GetUserCred(), t.GetTaskId()); err != nil {
+			t.OnContainerStartedFailed(ctx, pod, jsonutils.NewString(errors.Wrapf(err, "start init container %s", ctr.GetName()).Error()))
+		}
+		return
+	}
 	t.SetStage("OnContainerStarted", nil)
-	if err := models.GetContainerManager().StartBatchStartTask(ctx, t.GetUserCred(), ctrs, t.GetId()); err != nil {
+	if err := models.GetContainerManager().StartBatchStartTask(ctx, t.GetUserCred(), appCtrs, t.GetId()); err != nil {
 		t.OnContainerStartedFailed(ctx, pod, jsonutils.NewString(err.Error()))
 		return
 	}
 }
 
+// OnInitContainerStarted waits the init container to exit,
+// the host calls back asynchronously when it completes.
+func (t *PodStartTask) OnInitContainerStarted(ctx context.Context, pod *models.SGuest, data jsonutils.JSONObject) {
+	idx, _ := t.GetParams().Int("init_index")
+	ctrs, err := models.GetContainerManager().GetContainersByPod(pod.GetId())
+	if err != nil {
+		t.OnContainerStartedFailed(ctx, pod, jsonutils.NewString(errors.Wrap(err, "GetContainersByPod").Error()))
+		return
+	}
+	initCtrs, _ := models.SplitInitContainers(ctrs)
+	if int(idx) >= len(initCtrs) {
+		t.OnContainerStartedFailed(ctx, pod, jsonutils.NewString(errors.Errorf("init container index %d out of range", idx).Error()))
+		return
+	}
+	ctr := &initCtrs[idx]
+	t.SetStage("OnInitContainerExited", nil)
+	if err := ctr.GetPodDriver().RequestWaitInitContainerExited(ctx, t.GetUserCred(), ctr, t); err != nil {
+		t.OnContainerStartedFailed(ctx, pod, jsonutils.NewString(errors.Wrapf(err, "wait init container %s", ctr.GetName()).Error()))
+	}
+}
+
+func (t *PodStartTask) OnInitContainerExited(ctx context.Context, pod *models.SGuest, data jsonutils.JSONObject) {
+	idx, _ := t.GetParams().Int("init_index")
+	t.startInitContainer(ctx, pod, int(idx)+1)
+}
+
+func (t *PodStartTask) OnInitContainerExitedFailed(ctx context.Context, pod *models.SGuest, data jsonutils.JSONObject) {
+	t.OnContainerStartedFailed(ctx, pod, data)
+}
+
+func (t *PodStartTask) OnInitContainerStartedFailed(ctx context.Context, pod *models.SGuest, data jsonutils.JSONObject) {
+	t.OnContainerStartedFailed(ctx, pod, data)
+}
+
 func (t *PodStartTask) OnPodStartedFailed(ctx context.Context, pod *models.SGuest, reason jsonutils.JSONObject) {
 	pod.SetStatus(ctx, t.GetUserCred(), api.VM_START_FAILED, reason.String())
 	t.SetStageFailed(ctx, reason)
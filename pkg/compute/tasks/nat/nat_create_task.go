@@ -61,6 +61,13 @@ func (self *NatGatewayCreateTask) OnInit(ctx context.Context, obj db.IStandalone
 		return
 	}
 
+	if !vpc.IsManaged() {
+		// onecloud vpc natgateway is realized by vpcagent, nothing to create
+		self.SetStage("OnCreateNatGatewayCreateComplete", nil)
+		self.OnCreateNatGatewayCreateComplete(ctx, nat, nil)
+		return
+	}
+
 	opts.VpcId = vpc.ExternalId
 
 	if len(nat.NetworkId) > 0 {
@@ -87,6 +87,12 @@ func (self *SNatSEntryCreateTask) OnAssociateEipComplete(ctx context.Context, sn
 		self.taskFailed(ctx, snat, errors.Wrapf(err, "snat.GetNatgateway"))
 		return
 	}
+	if !nat.IsManaged() {
+		snat.SetStatus(ctx, self.UserCred, api.NAT_STAUTS_AVAILABLE, "")
+		logclient.AddActionLogWithStartable(self, nat, logclient.ACT_NAT_CREATE_SNAT, nil, self.UserCred, true)
+		self.SetStageComplete(ctx, nil)
+		return
+	}
 	iNat, err := nat.GetINatGateway(ctx)
 	if err != nil {
 		self.taskFailed(ctx, snat, errors.Wrapf(err, "nat.GetINatGateway"))
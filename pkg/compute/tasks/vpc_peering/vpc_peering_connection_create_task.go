@@ -58,6 +58,18 @@ func (self *VpcPeeringConnectionCreateTask) OnInit(ctx context.Context, obj db.I
 		return
 	}
 
+	if len(vpc.ManagerId) == 0 {
+		// on-premise vpc peering is realized by vpcagent
+		err = peer.AllocateTransitCidr(ctx)
+		if err != nil {
+			self.taskFailed(ctx, peer, errors.Wrapf(err, "AllocateTransitCidr"))
+			return
+		}
+		peer.SetStatus(ctx, self.UserCred, api.VPC_PEERING_CONNECTION_STATUS_ACTIVE, "")
+		self.taskComplete(ctx, peer)
+		return
+	}
+
 	iVpc, err := vpc.GetIVpc(ctx)
 	if err != nil {
 		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetIVpc"))
@@ -58,6 +58,11 @@ func (self *VpcPeeringConnectionDeleteTask) OnInit(ctx context.Context, obj db.I
 		return
 	}
 
+	if len(vpc.ManagerId) == 0 {
+		self.taskComplete(ctx, peer)
+		return
+	}
+
 	iVpc, err := vpc.GetIVpc(ctx)
 	if err != nil {
 		self.taskFailed(ctx, peer, errors.Wrapf(err, "GetIVpc"))
@@ -51,6 +51,14 @@ func (self *VpcPeeringConnectionSyncstatusTask) OnInit(ctx context.Context, obj
 		return
 	}
 
+	if len(svpc.ManagerId) == 0 {
+		if len(peer.TransitCidr) > 0 {
+			peer.SetStatus(ctx, self.UserCred, api.VPC_PEERING_CONNECTION_STATUS_ACTIVE, "")
+		}
+		self.SetStageComplete(ctx, nil)
+		return
+	}
+
 	extVpc, err := svpc.GetIVpc(ctx)
 	if err != nil {
 		self.taskFail(ctx, peer, errors.Wrap(err, "svpc.GetIVpc()"))
@@ -20,7 +20,9 @@ import (
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/util/sets"
+	"yunion.io/x/pkg/utils"
 	"yunion.io/x/sqlchemy"
 
 	apis "yunion.io/x/onecloud/pkg/apis/compute"
@@ -34,6 +36,42 @@ import (
 var UnhealthStatus = []string{
 	apis.VM_UNKNOWN, apis.VM_SCHEDULE_FAILED, apis.VM_NETWORK_FAILED, apis.VM_DEVICE_FAILED, apis.VM_DISK_FAILED,
 	apis.VM_DEPLOY_FAILED, apis.VM_READY, apis.VM_START_FAILED,
+	apis.POD_STATUS_CREATE_CONTAINER_FAILED, apis.POD_STATUS_START_CONTAINER_FAILED,
+	apis.POD_STATUS_CRASH_LOOP_BACK_OFF, apis.POD_STATUS_CONTAINER_EXITED,
+}
+
+// UnhealthContainerStatus make the pod which the container belongs to unhealthy,
+// even though the pod itself is still running
+var UnhealthContainerStatus = []string{
+	apis.CONTAINER_STATUS_PROBE_FAILED, apis.CONTAINER_STATUS_CRASH_LOOP_BACK_OFF,
+	apis.CONTAINER_STATUS_EXITED, apis.CONTAINER_STATUS_START_FAILED,
+}
+
+// IsContainerUnhealthy checks whether the container makes its pod unhealthy,
+// init containers are expected to exit after running to completion
+func IsContainerUnhealthy(ctr *models.SContainer) bool {
+	if !utils.IsInStringArray(ctr.Status, UnhealthContainerStatus) {
+		return false
+	}
+	if ctr.Status == apis.CONTAINER_STATUS_EXITED && ctr.Spec != nil && ctr.Spec.Init {
+		return false
+	}
+	return true
+}
+
+func unhealthPodIds() ([]string, error) {
+	q := models.GetContainerManager().Query().In("status", UnhealthContainerStatus)
+	ctrs := make([]models.SContainer, 0)
+	if err := db.FetchModelObjects(models.GetContainerManager(), q, &ctrs); err != nil {
+		return nil, errors.Wrap(err, "db.FetchModelObjects")
+	}
+	podIds := sets.NewString()
+	for i := range ctrs {
+		if IsContainerUnhealthy(&ctrs[i]) {
+			podIds.Insert(ctrs[i].GuestId)
+		}
+	}
+	return podIds.UnsortedList(), nil
 }
 
 type sUnnormalGuest struct {
@@ -43,12 +81,19 @@ type sUnnormalGuest struct {
 	CreateCompleteTime time.Time `json:"create_complete_time"`
 }
 
-func (asc *SASController) HealthCheckSql() *sqlchemy.SQuery {
+func (asc *SASController) HealthCheckSql(podIds []string) *sqlchemy.SQuery {
 	now := time.Now()
 	sgSubQ := models.ScalingGroupManager.Query("id").IsTrue("enabled").LT("next_check_time", now).SubQuery()
 	sggQ := models.ScalingGroupGuestManager.Query("guest_id", "scaling_group_id", "updated_at").Equals("guest_status", apis.SG_GUEST_STATUS_READY)
 	sggSubQ := sggQ.Join(sgSubQ, sqlchemy.Equals(sgSubQ.Field("id"), sggQ.Field("scaling_group_id"))).SubQuery()
-	q := models.GuestManager.Query("id", "status").In("status", UnhealthStatus)
+	q := models.GuestManager.Query("id", "status")
+	q = q.Filter(sqlchemy.OR(
+		sqlchemy.In(q.Field("status"), UnhealthStatus),
+		sqlchemy.AND(
+			sqlchemy.Equals(q.Field("hypervisor"), apis.HYPERVISOR_POD),
+			sqlchemy.In(q.Field("id"), podIds),
+		),
+	))
 	q = q.Join(sggSubQ, sqlchemy.Equals(q.Field("id"), sggSubQ.Field("guest_id")))
 	q = q.AppendField(sggSubQ.Field("scaling_group_id"), sggSubQ.Field("updated_at", "create_complete_time"))
 	return q
@@ -58,7 +103,12 @@ func (asc *SASController) CheckInstanceHealth(ctx context.Context, userCred mccl
 	// Fetch all unhealth status instace
 	unnormalGuests := make([]sUnnormalGuest, 0, 5)
 	scalingGroupIdSet := sets.NewString()
-	rows, err := asc.HealthCheckSql().Rows()
+	podIds, err := unhealthPodIds()
+	if err != nil {
+		log.Errorf("unable to fetch unhealth pods: %v", err)
+		return
+	}
+	rows, err := asc.HealthCheckSql(podIds).Rows()
 	if err != nil {
 		log.Errorf("GuestManager's SQuery.Rows: %s", err.Error())
 		return
@@ -0,0 +1,49 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoscaling
+
+import (
+	"testing"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+func TestIsContainerUnhealthy(t *testing.T) {
+	newContainer := func(status string, init bool) *models.SContainer {
+		ctr := &models.SContainer{
+			Spec: &apis.ContainerSpec{},
+		}
+		ctr.Status = status
+		ctr.Spec.Init = init
+		return ctr
+	}
+	cases := []struct {
+		name string
+		ctr  *models.SContainer
+		want bool
+	}{
+		{"running", newContainer(apis.CONTAINER_STATUS_RUNNING, false), false},
+		{"exited", newContainer(apis.CONTAINER_STATUS_EXITED, false), true},
+		{"init container completed", newContainer(apis.CONTAINER_STATUS_EXITED, true), false},
+		{"init container crash loop", newContainer(apis.CONTAINER_STATUS_CRASH_LOOP_BACK_OFF, true), true},
+		{"probe failed", newContainer(apis.CONTAINER_STATUS_PROBE_FAILED, false), true},
+	}
+	for _, c := range cases {
+		if got := IsContainerUnhealthy(c.ctr); got != c.want {
+			t.Errorf("%s: want %v, got %v", c.name, c.want, got)
+		}
+	}
+}
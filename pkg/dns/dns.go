@@ -86,6 +86,9 @@ type SRegionDNS struct {
 
 	InCloudOnly bool
 
+	// interval to check zone serials for sending NOTIFY to secondaries
+	NotifyIntervalSeconds int
+
 	// K8sSkip bool
 
 	// K8sManager *k8s.SKubeClusterManager
@@ -143,12 +146,28 @@ func (r *SRegionDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, rmsg *d
 	var (
 		records []dns.RR
 		extra   []dns.RR
+		signer  *sZoneSigner
 		err     error
 	)
 
 	opt := plugin.Options{}
 	state := request.Request{W: w, Req: rmsg, Context: ctx}
 	zone := plugin.Zones(r.Zones).Matches(state.Name())
+	if state.Do() || state.QType() == dns.TypeDNSKEY {
+		signer, err = r.getZoneSigner(state.Name())
+		if err != nil {
+			log.Errorf("get signer of %s: %v", state.Name(), err)
+			return dns.RcodeServerFailure, nil
+		}
+		if signer != nil {
+			if records := signer.answerApex(state); len(records) > 0 {
+				return signer.writeSigned(state, records, nil)
+			}
+			if !state.Do() {
+				signer = nil
+			}
+		}
+	}
 	switch state.QType() {
 	case dns.TypeA:
 		records, err = plugin.A(r, zone, state, nil, opt)
@@ -166,6 +185,8 @@ func (r *SRegionDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, rmsg *d
 		records, extra, err = plugin.SRV(r, zone, state, opt)
 	case dns.TypeSOA:
 		records, err = plugin.SOA(r, zone, state, opt)
+	case dns.TypeAXFR, dns.TypeIXFR:
+		return r.Transfer(ctx, state)
 	case dns.TypeNS:
 		if state.Name() == zone {
 			records, extra, err = plugin.NS(r, zone, state, opt)
@@ -178,6 +199,12 @@ func (r *SRegionDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, rmsg *d
 		_, err = plugin.A(r, zone, state, nil, opt)
 	}
 
+	if signer != nil && err != errRefused {
+		// names of signed zones are answered authoritatively, missing
+		// records are denied by NSEC instead of NXDOMAIN
+		return signer.writeSigned(state, records, extra)
+	}
+
 	if err == errCallNext {
 		if r.Fall.Through(state.Name()) {
 			return plugin.NextOrFailure(r.Name(), r.Next, ctx, w, rmsg)
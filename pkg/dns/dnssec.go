@@ -0,0 +1,225 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/request"
+	"github.com/miekg/dns"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	"yunion.io/x/onecloud/pkg/util/dnssec"
+)
+
+// sZoneSigner holds the parsed keys of a dnssec enabled local zone
+type sZoneSigner struct {
+	zone      *models.SDnsZone
+	updatedAt time.Time
+
+	// all published keys, answered to DNSKEY queries
+	dnskeys []dns.RR
+	// active and retired key signing keys, signing the DNSKEY rrset
+	ksks []*dnssec.SKey
+	// active zone signing key, signing everything else
+	zsks []*dnssec.SKey
+}
+
+var (
+	zoneSigners     = map[string]*sZoneSigner{}
+	zoneSignersLock = &sync.Mutex{}
+)
+
+func newZoneSigner(zone *models.SDnsZone) (*sZoneSigner, error) {
+	signer := &sZoneSigner{
+		zone:      zone,
+		updatedAt: zone.UpdatedAt,
+	}
+	for _, key := range zone.GetDnssecKeys() {
+		priv, err := zone.GetDnssecPrivateKey(&key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decrypt key %d", key.KeyTag)
+		}
+		dnskey, sig, err := dnssec.ParseKey(key.DnsKey, priv)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse key %d", key.KeyTag)
+		}
+		signer.dnskeys = append(signer.dnskeys, dnskey)
+		skey := &dnssec.SKey{DnsKey: dnskey, Signer: sig}
+		switch {
+		case key.KeyType == api.DNSSEC_KEY_TYPE_KSK:
+			signer.ksks = append(signer.ksks, skey)
+		case key.State == api.DNSSEC_KEY_STATE_ACTIVE:
+			signer.zsks = append(signer.zsks, skey)
+		}
+	}
+	if len(signer.ksks) == 0 || len(signer.zsks) == 0 {
+		return nil, errors.Wrapf(errors.ErrNotFound, "zone %s has no active signing keys", zone.Name)
+	}
+	return signer, nil
+}
+
+// getZoneSigner returns the signer of the signed local zone covering name,
+// nil if there is no such zone.  Parsed keys are cached until the zone is
+// updated
+func (r *SRegionDNS) getZoneSigner(name string) (*sZoneSigner, error) {
+	zone, err := r.findLocalZone(name)
+	if err != nil {
+		return nil, err
+	}
+	if zone == nil || !zone.IsDnssecEnabled() {
+		return nil, nil
+	}
+
+	zoneSignersLock.Lock()
+	defer zoneSignersLock.Unlock()
+
+	if signer, ok := zoneSigners[zone.Id]; ok && signer.updatedAt.Equal(zone.UpdatedAt) {
+		return signer, nil
+	}
+	signer, err := newZoneSigner(zone)
+	if err != nil {
+		return nil, err
+	}
+	zoneSigners[zone.Id] = signer
+	return signer, nil
+}
+
+func (s *sZoneSigner) origin() string {
+	return dns.Fqdn(strings.ToLower(s.zone.Name))
+}
+
+func (s *sZoneSigner) isApex(name string) bool {
+	return dns.Fqdn(strings.ToLower(name)) == s.origin()
+}
+
+// sign appends signatures to the rrsets owned by names of the zone.  Records
+// outside the zone, e.g. targets of CNAME resolved elsewhere, are left
+// unsigned
+func (s *sZoneSigner) sign(rrs []dns.RR, now time.Time) ([]dns.RR, error) {
+	ret := make([]dns.RR, 0, len(rrs)*2)
+	for _, rrset := range dnssec.SplitRRSets(rrs) {
+		if !dns.IsSubDomain(s.origin(), rrset[0].Header().Name) {
+			ret = append(ret, rrset...)
+			continue
+		}
+		keys := s.zsks
+		if rrset[0].Header().Rrtype == dns.TypeDNSKEY {
+			keys = s.ksks
+			ret = append(ret, rrset...)
+			for _, key := range keys {
+				sig, err := key.Sign(rrset, now)
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, sig)
+			}
+			continue
+		}
+		signed, err := dnssec.SignSection(rrset, keys, now)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, signed...)
+	}
+	return ret, nil
+}
+
+// nameTypes returns the types of enabled records owned by name
+func (s *sZoneSigner) nameTypes(name string) ([]uint16, error) {
+	types := []uint16{}
+	rname := "@"
+	if !s.isApex(name) {
+		rname = strings.TrimSuffix(strings.ToLower(dns.Fqdn(name)), "."+s.origin())
+	} else {
+		types = append(types, dns.TypeSOA, dns.TypeNS, dns.TypeDNSKEY)
+	}
+	q := models.DnsRecordManager.Query().Equals("dns_zone_id", s.zone.Id).IsTrue("enabled").In("name", []string{rname, "*." + rname})
+	records := []models.SDnsRecord{}
+	err := db.FetchModelObjects(models.DnsRecordManager, q, &records)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	for i := range records {
+		if t, ok := dns.StringToType[records[i].DnsType]; ok {
+			types = append(types, t)
+		}
+	}
+	return types, nil
+}
+
+// answerApex answers SOA and DNSKEY queries at the apex of a signed zone
+func (s *sZoneSigner) answerApex(state request.Request) []dns.RR {
+	if !s.isApex(state.Name()) {
+		return nil
+	}
+	switch state.QType() {
+	case dns.TypeDNSKEY:
+		return s.dnskeys
+	case dns.TypeSOA:
+		return []dns.RR{zoneSOA(s.zone)}
+	}
+	return nil
+}
+
+// writeSigned writes the answer, signed if the client asks for DNSSEC
+// records.  Empty answers are turned into NODATA proved by a minimally
+// covering NSEC record, so that the denial can be computed online without
+// walking the zone
+func (s *sZoneSigner) writeSigned(state request.Request, answer, extra []dns.RR) (int, error) {
+	now := time.Now()
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative, m.RecursionAvailable = true, true
+	m.Extra = extra
+	if len(answer) > 0 {
+		m.Answer = answer
+	} else {
+		types, err := s.nameTypes(state.Name())
+		if err != nil {
+			log.Errorf("query types of %s: %v", state.Name(), err)
+			return dns.RcodeServerFailure, nil
+		}
+		soa := zoneSOA(s.zone)
+		m.Ns = []dns.RR{soa, dnssec.DenialNSEC(state.Name(), soa.Minttl, types)}
+	}
+
+	if !state.Do() {
+		return s.write(state, m)
+	}
+	var err error
+	for _, section := range []*[]dns.RR{&m.Answer, &m.Ns, &m.Extra} {
+		*section, err = s.sign(*section, now)
+		if err != nil {
+			log.Errorf("sign answer of %s: %v", state.Name(), err)
+			return dns.RcodeServerFailure, nil
+		}
+	}
+	return s.write(state, m)
+}
+
+func (s *sZoneSigner) write(state request.Request, m *dns.Msg) (int, error) {
+	state.SizeAndDo(m)
+	m = state.Scrub(m)
+	state.W.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
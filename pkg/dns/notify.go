@@ -0,0 +1,99 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"time"
+
+	"github.com/miekg/dns"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+const defaultNotifyIntervalSeconds = 30
+
+// startNotifier polls the serial of local zones having secondaries and
+// sends DNS NOTIFY (RFC 1996) to them once the serial changes
+func (r *SRegionDNS) startNotifier(stop <-chan struct{}) {
+	interval := r.NotifyIntervalSeconds
+	if interval <= 0 {
+		interval = defaultNotifyIntervalSeconds
+	}
+	notified := map[string]int64{}
+	go func() {
+		tick := time.NewTicker(time.Duration(interval) * time.Second)
+		defer tick.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-tick.C:
+				r.notifySecondaries(notified)
+			}
+		}
+	}()
+}
+
+func (r *SRegionDNS) notifySecondaries(notified map[string]int64) {
+	q := models.DnsZoneManager.Query().IsNullOrEmpty("manager_id").IsTrue("enabled").IsNotEmpty("notify_secondaries")
+	zones := []models.SDnsZone{}
+	err := db.FetchModelObjects(models.DnsZoneManager, q, &zones)
+	if err != nil {
+		log.Errorf("fetch dns zones to notify: %v", err)
+		return
+	}
+	for i := range zones {
+		zone := &zones[i]
+		if serial, ok := notified[zone.Id]; ok && serial == zone.Serial {
+			continue
+		}
+		allDone := true
+		for _, addr := range zone.NotifySecondaries {
+			err := sendNotify(zone, addr)
+			if err != nil {
+				log.Warningf("notify %s of zone %s serial %d: %v", addr, zone.Name, zone.Serial, err)
+				allDone = false
+			}
+		}
+		if allDone {
+			notified[zone.Id] = zone.Serial
+		}
+	}
+}
+
+func sendNotify(zone *models.SDnsZone, addr string) error {
+	m := new(dns.Msg)
+	m.SetNotify(dns.Fqdn(zone.Name))
+	m.Authoritative = true
+	m.Answer = []dns.RR{zoneSOA(zone)}
+
+	c := new(dns.Client)
+	if key := zoneTsigKey(zone); key != nil {
+		c.TsigSecret = map[string]string{key.Name: key.Secret}
+		m.SetTsig(key.Name, key.Algorithm, defaultTsigFudge, time.Now().Unix())
+	}
+	resp, _, err := c.Exchange(m, addr)
+	if err != nil {
+		return errors.Wrap(err, "Exchange")
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return errors.Errorf("rcode %s", dns.RcodeToString[resp.Rcode])
+	}
+	return nil
+}
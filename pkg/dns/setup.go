@@ -16,6 +16,7 @@ package dns
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -73,6 +74,13 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error(PluginName, err)
 	}
 
+	stopNotifier := make(chan struct{})
+	rDNS.startNotifier(stopNotifier)
+	c.OnShutdown(func() error {
+		close(stopNotifier)
+		return nil
+	})
+
 	/*if !rDNS.K8sSkip {
 		go rDNS.initK8s()
 	}*/
@@ -160,6 +168,15 @@ func parseConfig(c *caddy.Controller) (*SRegionDNS, error) {
 					rDNS.Upstream = u
 				case "in_cloud_only":
 					rDNS.InCloudOnly = true
+				case "notify_interval":
+					if !c.NextArg() {
+						return nil, c.ArgErr()
+					}
+					interval, err := strconv.Atoi(c.Val())
+					if err != nil {
+						return nil, c.Errf("invalid notify_interval %q", c.Val())
+					}
+					rDNS.NotifyIntervalSeconds = interval
 				// case "k8s_skip":
 				//	rDNS.K8sSkip = true
 				default:
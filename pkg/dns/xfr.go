@@ -16,15 +16,38 @@ package dns
 
 import (
 	"context"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/coredns/coredns/request"
 	"github.com/miekg/dns"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+const (
+	// max resource records carried by one message of a zone transfer
+	xfrEnvelopeSize = 100
+
+	defaultTsigFudge = 300
 )
 
 // Serial implements the Transferer interface
+//
+// For names inside a local dns zone the zone serial is returned, which is
+// increased on every record change.  Other names are answered by guest and
+// host records which are not journaled, so a time based serial is used
 func (r *SRegionDNS) Serial(state request.Request) uint32 {
-	return uint32(time.Now().Unix())
+	zone, err := r.findLocalZone(state.Name())
+	if err != nil || zone == nil {
+		return uint32(time.Now().Unix())
+	}
+	return uint32(zone.Serial)
 }
 
 // MinTTL implements the Transferer interface
@@ -33,6 +56,292 @@ func (r *SRegionDNS) MinTTL(state request.Request) uint32 {
 }
 
 // Transferer implements the Transferer interface
+//
+// AXFR and IXFR of local dns zones are served from dnszones and dnsrecords,
+// guarded by the transfer allowlist and TSIG key of the zone
 func (r *SRegionDNS) Transfer(ctx context.Context, state request.Request) (int, error) {
-	return dns.RcodeServerFailure, nil
+	zone, err := r.findLocalZone(state.Name())
+	if err != nil {
+		log.Errorf("find zone %s: %v", state.Name(), err)
+		return dns.RcodeServerFailure, nil
+	}
+	if zone == nil || dns.Fqdn(zone.Name) != strings.ToLower(state.Name()) {
+		return dns.RcodeRefused, nil
+	}
+	tsig, err := checkTransferAcl(zone, state)
+	if err != nil {
+		log.Warningf("refuse %s of zone %s from %s: %v", state.Type(), zone.Name, state.IP(), err)
+		return dns.RcodeRefused, nil
+	}
+
+	soa := zoneSOA(zone)
+	var rrs []dns.RR
+	if state.QType() == dns.TypeIXFR {
+		rrs, err = r.ixfrRecords(zone, soa, state)
+	} else if state.Proto() != "tcp" {
+		// AXFR must be carried over TCP
+		return dns.RcodeRefused, nil
+	} else {
+		rrs, err = r.axfrRecords(zone, soa)
+	}
+	if err != nil {
+		log.Errorf("%s of zone %s: %v", state.Type(), zone.Name, err)
+		return dns.RcodeServerFailure, nil
+	}
+
+	err = writeTransfer(state.W, state.Req, tsig, rrs)
+	if err != nil {
+		log.Errorf("write %s of zone %s: %v", state.Type(), zone.Name, err)
+		return dns.RcodeServerFailure, nil
+	}
+	log.Infof("%s of zone %s serial %d to %s: %d records", state.Type(), zone.Name, soa.Serial, state.IP(), len(rrs))
+	return dns.RcodeSuccess, nil
+}
+
+// findLocalZone returns the enabled local dns zone closest to name
+func (r *SRegionDNS) findLocalZone(name string) (*models.SDnsZone, error) {
+	labels := dns.SplitDomainName(strings.ToLower(name))
+	candidates := make([]string, 0, len(labels))
+	for i := range labels {
+		candidates = append(candidates, strings.Join(labels[i:], "."))
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	q := models.DnsZoneManager.Query().IsNullOrEmpty("manager_id").IsTrue("enabled").In("name", candidates)
+	zones := []models.SDnsZone{}
+	err := db.FetchModelObjects(models.DnsZoneManager, q, &zones)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	var ret *models.SDnsZone
+	for i := range zones {
+		if ret == nil || len(zones[i].Name) > len(ret.Name) {
+			ret = &zones[i]
+		}
+	}
+	return ret, nil
+}
+
+func (r *SRegionDNS) axfrRecords(zone *models.SDnsZone, soa *dns.SOA) ([]dns.RR, error) {
+	records, err := zone.GetDnsRecords()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetDnsRecords")
+	}
+	lines := []string{}
+	for i := range records {
+		if records[i].Enabled.Bool() {
+			lines = append(lines, records[i].ToZoneLine())
+		}
+	}
+	body, err := parseZoneLines(zone.Name, lines)
+	if err != nil {
+		return nil, err
+	}
+	rrs := []dns.RR{soa}
+	rrs = append(rrs, body...)
+	return append(rrs, soa), nil
+}
+
+// ixfrRecords answers IXFR as described by RFC 1995.  Falls back to AXFR
+// style answer when the journal does not cover the serial of the client
+func (r *SRegionDNS) ixfrRecords(zone *models.SDnsZone, soa *dns.SOA, state request.Request) ([]dns.RR, error) {
+	var clientSOA *dns.SOA
+	for _, rr := range state.Req.Ns {
+		if s, ok := rr.(*dns.SOA); ok {
+			clientSOA = s
+			break
+		}
+	}
+	if clientSOA == nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "IXFR without SOA in authority section")
+	}
+	if clientSOA.Serial == soa.Serial || state.Proto() != "tcp" {
+		// up to date, or ask the client to retry with TCP
+		return []dns.RR{soa}, nil
+	}
+	changes, ok, err := zone.GetChangesSince(int64(clientSOA.Serial))
+	if err != nil {
+		return nil, errors.Wrap(err, "GetChangesSince")
+	}
+	if !ok {
+		return r.axfrRecords(zone, soa)
+	}
+	return buildIxfr(zone.Name, soa, clientSOA.Serial, changes)
+}
+
+func buildIxfr(zoneName string, soa *dns.SOA, serial uint32, changes []models.SDnsZoneChange) ([]dns.RR, error) {
+	rrs := []dns.RR{soa}
+	for i := range changes {
+		removed, err := parseZoneLines(zoneName, changes[i].GetRemoved())
+		if err != nil {
+			return nil, errors.Wrapf(err, "removed records of serial %d", changes[i].Serial)
+		}
+		added, err := parseZoneLines(zoneName, changes[i].GetAdded())
+		if err != nil {
+			return nil, errors.Wrapf(err, "added records of serial %d", changes[i].Serial)
+		}
+		from := dns.Copy(soa).(*dns.SOA)
+		from.Serial = serial
+		rrs = append(rrs, from)
+		rrs = append(rrs, removed...)
+		to := dns.Copy(soa).(*dns.SOA)
+		to.Serial = uint32(changes[i].Serial)
+		rrs = append(rrs, to)
+		rrs = append(rrs, added...)
+		serial = to.Serial
+	}
+	return append(rrs, soa), nil
+}
+
+// parseZoneLines converts records in zone file format to resource records
+func parseZoneLines(zoneName string, lines []string) ([]dns.RR, error) {
+	rrs := []dns.RR{}
+	if len(lines) == 0 {
+		return rrs, nil
+	}
+	zp := dns.NewZoneParser(strings.NewReader(strings.Join(lines, "\n")), dns.Fqdn(zoneName), "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if rr.Header().Ttl == 0 {
+			rr.Header().Ttl = defaultTTL
+		}
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		return nil, errors.Wrapf(err, "parse zone %s", zoneName)
+	}
+	return rrs, nil
+}
+
+func zoneSOA(zone *models.SDnsZone) *dns.SOA {
+	origin := dns.Fqdn(zone.Name)
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 30},
+		Ns:      defaultNSName + origin,
+		Mbox:    "hostmaster." + origin,
+		Serial:  uint32(zone.Serial),
+		Refresh: 7200,
+		Retry:   1800,
+		Expire:  86400,
+		Minttl:  30,
+	}
+}
+
+type sTsigKey struct {
+	Name      string
+	Algorithm string
+	Secret    string
+}
+
+func zoneTsigKey(zone *models.SDnsZone) *sTsigKey {
+	if len(zone.TsigKeyName) == 0 {
+		return nil
+	}
+	return &sTsigKey{
+		Name:      dns.Fqdn(strings.ToLower(zone.TsigKeyName)),
+		Algorithm: dns.Fqdn(zone.TsigAlgorithm),
+		Secret:    zone.TsigSecret,
+	}
+}
+
+// checkTransferAcl validates the source address and TSIG signature of a
+// transfer request.  Transfer is denied if the zone has neither allowlist
+// nor TSIG key configured
+func checkTransferAcl(zone *models.SDnsZone, state request.Request) (*sTsigKey, error) {
+	key := zoneTsigKey(zone)
+	if len(zone.TransferAllowIps) == 0 && key == nil {
+		return nil, errors.Wrap(errRefused, "zone transfer not enabled")
+	}
+	if len(zone.TransferAllowIps) > 0 && !isIpAllowed(state.IP(), zone.TransferAllowIps) {
+		return nil, errors.Wrapf(errRefused, "%s not in transfer allowlist", state.IP())
+	}
+	if key == nil {
+		return nil, nil
+	}
+	err := verifyTsig(state.Req, key)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func isIpAllowed(addr string, allows []string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, allow := range allows {
+		if _, cidr, err := net.ParseCIDR(allow); err == nil {
+			if cidr.Contains(ip) {
+				return true
+			}
+		} else if allowIp := net.ParseIP(allow); allowIp != nil && allowIp.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyTsig checks the TSIG signature of the request.  The server of
+// coredns does not keep the wire format of requests, so the request is
+// packed again, with and without name compression, for verification
+func verifyTsig(req *dns.Msg, key *sTsigKey) error {
+	t := req.IsTsig()
+	if t == nil {
+		return errors.Wrap(dns.ErrSig, "request not signed")
+	}
+	if strings.ToLower(t.Hdr.Name) != key.Name || strings.ToLower(t.Algorithm) != key.Algorithm {
+		return errors.Wrapf(dns.ErrSecret, "unknown key %s %s", t.Hdr.Name, t.Algorithm)
+	}
+	var lastErr error
+	for _, compress := range []bool{false, true} {
+		msg := req.Copy()
+		msg.Compress = compress
+		buf, err := msg.Pack()
+		if err != nil {
+			return errors.Wrap(err, "Pack")
+		}
+		lastErr = dns.TsigVerify(buf, key.Secret, "", false)
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return errors.Wrap(lastErr, "TsigVerify")
+}
+
+// writeTransfer sends rrs in envelopes, signing each of them when the
+// request is authenticated by TSIG
+func writeTransfer(w dns.ResponseWriter, req *dns.Msg, key *sTsigKey, rrs []dns.RR) error {
+	requestMAC := ""
+	if key != nil {
+		requestMAC = req.IsTsig().MAC
+	}
+	timersOnly := false
+	for start := 0; start < len(rrs); start += xfrEnvelopeSize {
+		end := start + xfrEnvelopeSize
+		if end > len(rrs) {
+			end = len(rrs)
+		}
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Authoritative = true
+		m.Compress = true
+		m.Answer = rrs[start:end]
+		if key == nil {
+			if err := w.WriteMsg(m); err != nil {
+				return errors.Wrap(err, "WriteMsg")
+			}
+			continue
+		}
+		m.SetTsig(key.Name, key.Algorithm, defaultTsigFudge, time.Now().Unix())
+		buf, mac, err := dns.TsigGenerate(m, key.Secret, requestMAC, timersOnly)
+		if err != nil {
+			return errors.Wrap(err, "TsigGenerate")
+		}
+		if _, err := w.Write(buf); err != nil {
+			return errors.Wrap(err, "Write")
+		}
+		requestMAC, timersOnly = mac, true
+	}
+	return nil
 }
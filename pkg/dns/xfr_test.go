@@ -0,0 +1,122 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"yunion.io/x/onecloud/pkg/compute/models"
+)
+
+func TestParseZoneLines(t *testing.T) {
+	lines := []string{
+		"www\t600\tIN\tA\t10.0.0.1",
+		"@\t0\tIN\tMX\t10\tmail.example.com.",
+		"_sip._tcp\t300\tIN\tSRV\t10 60 5060 sip.example.com.",
+	}
+	rrs, err := parseZoneLines("example.com", lines)
+	if err != nil {
+		t.Fatalf("parseZoneLines: %v", err)
+	}
+	if len(rrs) != 3 {
+		t.Fatalf("want 3 records, got %d", len(rrs))
+	}
+	if rrs[0].Header().Name != "www.example.com." || rrs[0].Header().Ttl != 600 {
+		t.Errorf("unexpected record %s", rrs[0])
+	}
+	if rrs[1].Header().Name != "example.com." || rrs[1].Header().Ttl != defaultTTL {
+		t.Errorf("unexpected record %s", rrs[1])
+	}
+	if srv, ok := rrs[2].(*dns.SRV); !ok || srv.Port != 5060 {
+		t.Errorf("unexpected record %s", rrs[2])
+	}
+}
+
+func TestBuildIxfr(t *testing.T) {
+	zone := &models.SDnsZone{}
+	zone.Name = "example.com"
+	zone.Serial = 5
+	soa := zoneSOA(zone)
+	changes := []models.SDnsZoneChange{
+		{Serial: 4, Added: "www\t600\tIN\tA\t10.0.0.1"},
+		{Serial: 5, Removed: "www\t600\tIN\tA\t10.0.0.1", Added: "www\t600\tIN\tA\t10.0.0.2"},
+	}
+	rrs, err := buildIxfr(zone.Name, soa, 3, changes)
+	if err != nil {
+		t.Fatalf("buildIxfr: %v", err)
+	}
+	serials := []uint32{}
+	for _, rr := range rrs {
+		if s, ok := rr.(*dns.SOA); ok {
+			serials = append(serials, s.Serial)
+		}
+	}
+	want := []uint32{5, 3, 4, 4, 5, 5}
+	if len(rrs) != 9 || len(serials) != len(want) {
+		t.Fatalf("unexpected ixfr %v", rrs)
+	}
+	for i := range want {
+		if serials[i] != want[i] {
+			t.Fatalf("want soa serials %v, got %v", want, serials)
+		}
+	}
+}
+
+func TestIsIpAllowed(t *testing.T) {
+	allows := []string{"10.0.0.0/24", "192.168.1.2", "fd00::/64"}
+	cases := map[string]bool{
+		"10.0.0.8":    true,
+		"10.0.1.8":    false,
+		"192.168.1.2": true,
+		"192.168.1.3": false,
+		"fd00::1":     true,
+		"invalid":     false,
+	}
+	for ip, want := range cases {
+		if got := isIpAllowed(ip, allows); got != want {
+			t.Errorf("%s: want %v, got %v", ip, want, got)
+		}
+	}
+}
+
+func TestVerifyTsig(t *testing.T) {
+	key := &sTsigKey{
+		Name:      "xfr-key.",
+		Algorithm: dns.HmacSHA256,
+		Secret:    "c2VjcmV0LWtleS1mb3ItdGVzdA==",
+	}
+	m := new(dns.Msg)
+	m.SetAxfr("example.com.")
+	m.SetTsig(key.Name, key.Algorithm, defaultTsigFudge, time.Now().Unix())
+	buf, _, err := dns.TsigGenerate(m, key.Secret, "", false)
+	if err != nil {
+		t.Fatalf("TsigGenerate: %v", err)
+	}
+	req := new(dns.Msg)
+	if err := req.Unpack(buf); err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+	if err := verifyTsig(req, key); err != nil {
+		t.Errorf("verifyTsig: %v", err)
+	}
+	wrong := *key
+	wrong.Secret = "d3Jvbmc="
+	if err := verifyTsig(req, &wrong); err == nil {
+		t.Errorf("verifyTsig with wrong secret should fail")
+	}
+}
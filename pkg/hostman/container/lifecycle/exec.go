@@ -38,7 +38,7 @@ func (e execDriver) GetType() apis.ContainerLifecyleHandlerType {
 	return apis.ContainerLifecyleHandlerTypeExec
 }
 
-func (e execDriver) Run(ctx context.Context, input *apis.ContainerLifecyleHandler, cri pod.CRI, id string) error {
+func (e execDriver) Run(ctx context.Context, input *apis.ContainerLifecyleHandler, cri pod.CRI, id string, _ string) error {
 	cfg := input.Exec
 	resp, err := cri.ExecSync(ctx, id, cfg.Command, 0)
 	if err != nil {
@@ -0,0 +1,114 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	"yunion.io/x/onecloud/pkg/util/pod"
+)
+
+func init() {
+	RegisterDriver(newHTTPGet())
+}
+
+type httpGetDriver struct {
+	client *http.Client
+}
+
+func newHTTPGet() ILifecycle {
+	return &httpGetDriver{
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+				DisableKeepAlives: true,
+			},
+			// don't follow redirects, same as kubelet's lifecycle handler
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (h httpGetDriver) GetType() apis.ContainerLifecyleHandlerType {
+	return apis.ContainerLifecyleHandlerTypeHTTPGet
+}
+
+func GetHTTPGetURL(input *apis.ContainerProbeHTTPGetAction, podIp string) (*url.URL, error) {
+	host := input.Host
+	if host == "" {
+		host = podIp
+	}
+	if host == "" {
+		return nil, errors.Errorf("not found host of http_get handler")
+	}
+	scheme := strings.ToLower(string(input.Scheme))
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := input.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse path %q", input.Path)
+	}
+	u.Scheme = scheme
+	u.Host = net.JoinHostPort(host, strconv.Itoa(input.Port))
+	return u, nil
+}
+
+func (h httpGetDriver) Run(ctx context.Context, input *apis.ContainerLifecyleHandler, _ pod.CRI, _ string, podIp string) error {
+	cfg := input.HTTPGet
+	u, err := GetHTTPGetURL(cfg, podIp)
+	if err != nil {
+		return errors.Wrap(err, "get http_get url")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return errors.Wrapf(err, "new request %s", u)
+	}
+	for _, header := range cfg.HTTPHeaders {
+		if strings.EqualFold(header.Name, "Host") {
+			req.Host = header.Value
+			continue
+		}
+		req.Header.Add(header.Name, header.Value)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "request %s", u)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 10*1024))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("request %s status code %d: %s", u, resp.StatusCode, body)
+	}
+	log.Infof("request %s: status code %d", u, resp.StatusCode)
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lifecycle
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/apis"
+)
+
+func TestGetHTTPGetURL(t *testing.T) {
+	cases := []struct {
+		input *apis.ContainerProbeHTTPGetAction
+		podIp string
+		want  string
+	}{
+		{
+			input: &apis.ContainerProbeHTTPGetAction{Port: 8080, Path: "shutdown"},
+			podIp: "10.0.0.2",
+			want:  "http://10.0.0.2:8080/shutdown",
+		},
+		{
+			input: &apis.ContainerProbeHTTPGetAction{Port: 443, Path: "/stop?now=1", Scheme: apis.URISchemeHTTPS, Host: "127.0.0.1"},
+			podIp: "10.0.0.2",
+			want:  "https://127.0.0.1:443/stop?now=1",
+		},
+	}
+	for _, c := range cases {
+		u, err := GetHTTPGetURL(c.input, c.podIp)
+		if err != nil {
+			t.Fatalf("GetHTTPGetURL %#v: %v", c.input, err)
+		}
+		if u.String() != c.want {
+			t.Errorf("want %s, got %s", c.want, u.String())
+		}
+	}
+	if _, err := GetHTTPGetURL(&apis.ContainerProbeHTTPGetAction{Port: 80}, ""); err == nil {
+		t.Errorf("empty host should return error")
+	}
+}
+
+func TestHTTPGetDriverRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	drv := newHTTPGet()
+	for path, wantErr := range map[string]bool{"/ok": false, "/fail": true} {
+		err := drv.Run(context.Background(), &apis.ContainerLifecyleHandler{
+			Type:    apis.ContainerLifecyleHandlerTypeHTTPGet,
+			HTTPGet: &apis.ContainerProbeHTTPGetAction{Port: port, Path: path},
+		}, nil, "", host)
+		if (err != nil) != wantErr {
+			t.Errorf("path %s: want error %v, got %v", path, wantErr, err)
+		}
+	}
+}
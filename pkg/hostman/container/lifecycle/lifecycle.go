@@ -40,5 +40,7 @@ func GetDriver(typ apis.ContainerLifecyleHandlerType) ILifecycle {
 
 type ILifecycle interface {
 	GetType() apis.ContainerLifecyleHandlerType
-	Run(ctx context.Context, input *apis.ContainerLifecyleHandler, cri pod.CRI, id string) error
+	// Run executes the handler against the container, podIp is used as the default host
+	// by the handlers connecting to the container through network.
+	Run(ctx context.Context, input *apis.ContainerLifecyleHandler, cri pod.CRI, id string, podIp string) error
 }
@@ -0,0 +1,156 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package volume_mount
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	hostapi "yunion.io/x/onecloud/pkg/apis/host"
+	"yunion.io/x/onecloud/pkg/httperrors"
+)
+
+func init() {
+	RegisterDriver(newObject(apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET))
+	RegisterDriver(newObject(apis.CONTAINER_VOLUME_MOUNT_TYPE_CONFIG))
+}
+
+// IRefreshableVolumeMount rewrites the content of the volume mount while the container is running
+type IRefreshableVolumeMount interface {
+	IVolumeMount
+
+	Refresh(pod IPodInfo, ctrId string, vm *hostapi.ContainerVolumeMount) error
+}
+
+// object writes the keys of container_secret or container_config resolved by region to files
+type object struct {
+	objType apis.ContainerVolumeMountType
+}
+
+func newObject(objType apis.ContainerVolumeMountType) IRefreshableVolumeMount {
+	return &object{objType: objType}
+}
+
+func (o object) GetType() apis.ContainerVolumeMountType {
+	return o.objType
+}
+
+func (o object) getObject(vm *hostapi.ContainerVolumeMount) (*hostapi.ContainerVolumeMountObject, error) {
+	obj := vm.Config
+	if o.objType == apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET {
+		obj = vm.Secret
+	}
+	if obj == nil {
+		return nil, httperrors.NewNotEmptyError("%s is nil", o.objType)
+	}
+	return obj, nil
+}
+
+func (o object) getHostPath(pod IPodInfo, ctrId string, vm *hostapi.ContainerVolumeMount) string {
+	return filepath.Join(pod.GetVolumesDir(), fmt.Sprintf("%s-%s", ctrId, strings.ReplaceAll(vm.MountPath, "/", "_")))
+}
+
+func (o object) GetRuntimeMountHostPath(pod IPodInfo, ctrId string, vm *hostapi.ContainerVolumeMount) (string, error) {
+	if err := EnsureDir(pod.GetVolumesDir()); err != nil {
+		return "", errors.Wrapf(err, "mkdir %s", pod.GetVolumesDir())
+	}
+	mntPath := o.getHostPath(pod, ctrId, vm)
+	if err := o.writeFiles(pod.GetVolumesDir(), vm, mntPath); err != nil {
+		return "", err
+	}
+	return mntPath, nil
+}
+
+func (o object) writeFiles(volumesDir string, vm *hostapi.ContainerVolumeMount, mntPath string) error {
+	obj, err := o.getObject(vm)
+	if err != nil {
+		return err
+	}
+	if obj.IsFile {
+		if len(obj.Files) != 1 {
+			return errors.Errorf("%s %s should contain only one file", o.objType, obj.Id)
+		}
+		return o.writeFile(volumesDir, mntPath, obj.Files[0].Content)
+	}
+	if err := EnsureDir(mntPath); err != nil {
+		return errors.Wrapf(err, "mkdir %s", mntPath)
+	}
+	for _, f := range obj.Files {
+		fp := filepath.Join(mntPath, f.Path)
+		if !strings.HasPrefix(fp, mntPath+"/") {
+			return errors.Errorf("path %s is out of %s", f.Path, mntPath)
+		}
+		if err := EnsureDir(filepath.Dir(fp)); err != nil {
+			return errors.Wrapf(err, "mkdir %s", filepath.Dir(fp))
+		}
+		if err := o.writeFile(volumesDir, fp, f.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile truncates and rewrites the file in place,
+// so the inode bound into the container is kept when refreshing
+func (o object) writeFile(volumesDir string, path string, content string) error {
+	if !isPathInDir(volumesDir, path) {
+		return errors.Errorf("path %s is out of volumes dir %s", path, volumesDir)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return errors.Wrapf(err, "write content to %s", path)
+	}
+	return nil
+}
+
+// isPathInDir checks the cleaned path is under dir and isn't redirected out of it by symbolic link
+func isPathInDir(dir string, path string) bool {
+	dir = filepath.Clean(dir)
+	path = filepath.Clean(path)
+	if !strings.HasPrefix(path, dir+"/") {
+		return false
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	realParent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	if realParent != realDir && !strings.HasPrefix(realParent, realDir+"/") {
+		return false
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		return false
+	}
+	return true
+}
+
+func (o object) Refresh(pod IPodInfo, ctrId string, vm *hostapi.ContainerVolumeMount) error {
+	return o.writeFiles(pod.GetVolumesDir(), vm, o.getHostPath(pod, ctrId, vm))
+}
+
+func (o object) Mount(pod IPodInfo, ctrId string, vm *hostapi.ContainerVolumeMount) error {
+	return nil
+}
+
+func (o object) Unmount(pod IPodInfo, ctrId string, vm *hostapi.ContainerVolumeMount) error {
+	return nil
+}
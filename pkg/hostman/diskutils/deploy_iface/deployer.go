@@ -39,4 +39,5 @@ type IDeployer interface {
 	FormatFs(req *apis.FormatFsParams) (*apis.Empty, error)
 	SaveToGlance(req *apis.SaveToGlanceParams) (*apis.SaveToGlanceResponse, error)
 	ProbeImageInfo(req *apis.ProbeImageInfoPramas) (*apis.ImageInfo, error)
+	ScanImagePackages(req *apis.ProbeImageInfoPramas) (*apis.ImagePackages, error)
 }
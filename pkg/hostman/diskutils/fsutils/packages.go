@@ -0,0 +1,131 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsutils
+
+import (
+	"os"
+	"path"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/cloudcommon/consts"
+	"yunion.io/x/onecloud/pkg/hostman/diskutils/deploy_iface"
+	"yunion.io/x/onecloud/pkg/hostman/guestfs/fsdriver"
+	"yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/sbomutils"
+	"yunion.io/x/onecloud/pkg/util/winutils"
+)
+
+var rpmDbPaths = []string{
+	"/usr/lib/sysimage/rpm",
+	"/var/lib/rpm",
+}
+
+func scanRpmPackages(part fsdriver.IDiskPartition) ([]sbomutils.SPackage, error) {
+	for _, dbPath := range rpmDbPaths {
+		if !part.Exists(dbPath, false) || len(part.ListDir(dbPath, false)) == 0 {
+			continue
+		}
+		// the rootfs is mounted readonly, rpm needs a writable db path
+		tmpDir, err := os.MkdirTemp(consts.DeployTempDir(), "rpmdb")
+		if err != nil {
+			return nil, errors.Wrap(err, "MkdirTemp")
+		}
+		defer os.RemoveAll(tmpDir)
+		tmpDbPath := path.Join(tmpDir, "rpm")
+		if out, err := procutils.NewCommand("cp", "-r", part.GetLocalPath(dbPath, false), tmpDbPath).Output(); err != nil {
+			return nil, errors.Wrapf(err, "copy rpm db %s: %s", dbPath, out)
+		}
+		out, err := procutils.NewCommand("rpm", "--dbpath", tmpDbPath, "-qa", "--qf", sbomutils.RPM_QUERY_FORMAT).Output()
+		if err != nil {
+			return nil, errors.Wrapf(err, "rpm query %s: %s", dbPath, out)
+		}
+		return sbomutils.ParseRpmQueryOutput(out), nil
+	}
+	return nil, nil
+}
+
+func scanLinuxPackages(part fsdriver.IDiskPartition) ([]sbomutils.SPackage, error) {
+	pkgs := []sbomutils.SPackage{}
+	if data, err := part.FileGetContents("/var/lib/dpkg/status", false); err == nil {
+		pkgs = append(pkgs, sbomutils.ParseDpkgStatus(data)...)
+	}
+	if data, err := part.FileGetContents("/lib/apk/db/installed", false); err == nil {
+		pkgs = append(pkgs, sbomutils.ParseApkInstalled(data)...)
+	}
+	rpms, err := scanRpmPackages(part)
+	if err != nil {
+		return nil, err
+	}
+	return append(pkgs, rpms...), nil
+}
+
+func scanWindowsPackages(part fsdriver.IDiskPartition) ([]sbomutils.SPackage, error) {
+	tool := winutils.NewWinRegTool(part.GetLocalPath("/windows/system32/config", true))
+	if !tool.CheckPath() {
+		return nil, errors.Wrap(errors.ErrNotFound, "windows registry")
+	}
+	pkgs := []sbomutils.SPackage{}
+	for _, prog := range tool.GetInstalledPrograms() {
+		pkgs = append(pkgs, sbomutils.SPackage{
+			Name:    prog.Name,
+			Version: prog.Version,
+			Arch:    prog.Arch,
+			Type:    sbomutils.PACKAGE_TYPE_WINDOWS,
+		})
+	}
+	sbomutils.SortPackages(pkgs)
+	return pkgs, nil
+}
+
+// ScanImagePackages mounts the rootfs readonly and enumerates installed
+// packages from rpm, dpkg and apk databases or windows registry hives
+func ScanImagePackages(d deploy_iface.IDeployer) (*apis.ImagePackages, error) {
+	rootfs, err := d.MountRootfs(true)
+	if err != nil {
+		return new(apis.ImagePackages), errors.Wrapf(err, "MountRootfs")
+	}
+	defer d.UmountRootfs(rootfs)
+
+	part := rootfs.GetPartition()
+	ret := &apis.ImagePackages{
+		OsType: rootfs.GetOs(),
+		OsInfo: rootfs.GetReleaseInfo(part),
+	}
+	var pkgs []sbomutils.SPackage
+	switch ret.OsType {
+	case "Windows":
+		pkgs, err = scanWindowsPackages(part)
+	case "Linux":
+		pkgs, err = scanLinuxPackages(part)
+	default:
+		return ret, errors.Wrapf(errors.ErrNotSupported, "scan packages of %s", ret.OsType)
+	}
+	if err != nil {
+		return ret, errors.Wrapf(err, "scan %s packages", ret.OsType)
+	}
+	for _, pkg := range pkgs {
+		ret.Packages = append(ret.Packages, &apis.ImagePackage{
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			Arch:    pkg.Arch,
+			Type:    pkg.Type,
+		})
+	}
+	log.Infof("ScanImagePackages found %d packages of %s", len(ret.Packages), ret.OsType)
+	return ret, nil
+}
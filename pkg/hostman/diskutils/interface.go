@@ -33,6 +33,7 @@ type IDisk interface {
 	FormatFs(req *apis.FormatFsParams) (*apis.Empty, error)
 	SaveToGlance(req *apis.SaveToGlanceParams) (*apis.SaveToGlanceResponse, error)
 	ProbeImageInfo(req *apis.ProbeImageInfoPramas) (*apis.ImageInfo, error)
+	ScanImagePackages(req *apis.ProbeImageInfoPramas) (*apis.ImagePackages, error)
 }
 
 type DiskParams struct {
@@ -159,3 +159,7 @@ func (d *SKVMGuestDisk) SaveToGlance(req *apis.SaveToGlanceParams) (*apis.SaveTo
 func (d *SKVMGuestDisk) ProbeImageInfo(req *apis.ProbeImageInfoPramas) (*apis.ImageInfo, error) {
 	return d.deployer.ProbeImageInfo(req)
 }
+
+func (d *SKVMGuestDisk) ScanImagePackages(req *apis.ProbeImageInfoPramas) (*apis.ImagePackages, error) {
+	return d.deployer.ScanImagePackages(req)
+}
@@ -286,3 +286,7 @@ func (d *SLibguestfsDriver) FormatFs(req *apis.FormatFsParams) (*apis.Empty, err
 func (d *SLibguestfsDriver) ProbeImageInfo(req *apis.ProbeImageInfoPramas) (*apis.ImageInfo, error) {
 	return fsutils.ProbeImageInfo(d)
 }
+
+func (d *SLibguestfsDriver) ScanImagePackages(req *apis.ProbeImageInfoPramas) (*apis.ImagePackages, error) {
+	return fsutils.ScanImagePackages(d)
+}
@@ -298,6 +298,10 @@ func (d *NBDDriver) ProbeImageInfo(req *apis.ProbeImageInfoPramas) (*apis.ImageI
 	return fsutils.ProbeImageInfo(d)
 }
 
+func (d *NBDDriver) ScanImagePackages(req *apis.ProbeImageInfoPramas) (*apis.ImagePackages, error) {
+	return fsutils.ScanImagePackages(d)
+}
+
 func getQemuNbdVersion() (string, error) {
 	output, err := procutils.NewRemoteCommandAsFarAsPossible(qemutils.GetQemuNbd(), "--version").Output()
 	if err != nil {
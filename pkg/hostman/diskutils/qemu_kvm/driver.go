@@ -570,6 +570,43 @@ func (d *QemuKvmDriver) ProbeImageInfo(req *apis.ProbeImageInfoPramas) (*apis.Im
 	return res, retErr
 }
 
+func (d *QemuKvmDriver) ScanImagePackages(req *apis.ProbeImageInfoPramas) (*apis.ImagePackages, error) {
+	defer func() {
+		logStr, _ := d.sshRun("test -f /log && cat /log")
+		log.Infof("ScanImagePackages log: %v", strings.Join(logStr, "\n"))
+	}()
+
+	params, _ := json.Marshal(req)
+	cmd := fmt.Sprintf("%s --deploy-action scan_image_packages --deploy-params '%s'", DEPLOYER_BIN, params)
+	out, err := d.sshRun(cmd)
+	if err != nil {
+		return nil, errors.Wrapf(err, "run scan_image_packages failed %s", out)
+	}
+	log.Infof("ScanImagePackages log: %s", strings.Join(out, "\n"))
+
+	responseStrs, err := d.sshRun("test -f /response && cat /response || true")
+	if err != nil {
+		return nil, errors.Wrapf(err, "ssh gather errors failed")
+	}
+	var res = new(apis.ImagePackages)
+	if len(responseStrs[0]) > 0 {
+		err := json.Unmarshal([]byte(responseStrs[0]), res)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed unmarshal deploy response %s", responseStrs[0])
+		}
+	}
+
+	errStrs, err := d.sshRun("test -f /error && cat /error || true")
+	if err != nil {
+		return nil, errors.Wrapf(err, "ssh gather errors failed")
+	}
+	var retErr error = nil
+	if len(errStrs[0]) > 0 {
+		retErr = errors.Errorf(errStrs[0])
+	}
+	return res, retErr
+}
+
 // wrap strings
 func __(v string, vs ...interface{}) string {
 	return fmt.Sprintf(" "+v, vs...)
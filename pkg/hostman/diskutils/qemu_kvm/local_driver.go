@@ -138,3 +138,7 @@ func (d *LocalDiskDriver) FormatFs(req *apis.FormatFsParams) (*apis.Empty, error
 func (d *LocalDiskDriver) ProbeImageInfo(req *apis.ProbeImageInfoPramas) (*apis.ImageInfo, error) {
 	return fsutils.ProbeImageInfo(d)
 }
+
+func (d *LocalDiskDriver) ScanImagePackages(req *apis.ProbeImageInfoPramas) (*apis.ImagePackages, error) {
+	return fsutils.ScanImagePackages(d)
+}
@@ -467,6 +467,10 @@ func (d *VDDKDisk) ProbeImageInfo(req *apis.ProbeImageInfoPramas) (*apis.ImageIn
 	return d.kvmDisk.ProbeImageInfo(req)
 }
 
+func (d *VDDKDisk) ScanImagePackages(req *apis.ProbeImageInfoPramas) (*apis.ImagePackages, error) {
+	return d.kvmDisk.ScanImagePackages(req)
+}
+
 type VDDKPartition struct {
 	*kvmpart.SLocalGuestFS
 }
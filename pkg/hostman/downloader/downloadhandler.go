@@ -62,6 +62,10 @@ func AddDownloadHandler(prefix string, app *appsrv.Application) {
 			nil, "memory_snapshot_download", nil)
 		customizeHandlerInfo(hi)
 
+		hi = app.AddHandler2("GET", fmt.Sprintf("%s/%s/serial_logs/<serverId>/<fileName>",
+			prefix, kerword), auth.Authenticate(serialLogDownload), nil, "serial_log_download", nil)
+		customizeHandlerInfo(hi)
+
 		hi = app.AddHandler2("HEAD", fmt.Sprintf("%s/%s/disks/<storageId>/<diskId>",
 			prefix, kerword), auth.Authenticate(diskHead),
 			nil, "head_disk_download", nil)
@@ -134,6 +138,27 @@ func download(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func serialLogDownload(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var (
+		params, _, _ = appsrv.FetchEnv(ctx, w, r)
+		serverId     = params["<serverId>"]
+		fileName     = params["<fileName>"]
+	)
+	logPath, err := guestman.GetGuestManager().GetSerialLogFile(serverId, fileName)
+	if err != nil {
+		hostutils.Response(ctx, w, err)
+		return
+	}
+	if !fileutils2.Exists(logPath) {
+		httperrors.NotFoundError(ctx, w, "Guest %s serial log %s not found", serverId, fileName)
+		return
+	}
+	hand := NewSnapshotDownloadProvider(w, isCompress(r), false, options.HostOptions.BandwidthLimit, logPath)
+	if err := hand.Start(); err != nil {
+		hostutils.Response(ctx, w, err)
+	}
+}
+
 func diskPrecheck(
 	ctx context.Context, w http.ResponseWriter, r *http.Request,
 ) (storageman.IDisk, error) {
@@ -0,0 +1,257 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/shirou/gopsutil/mem"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/monitor"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+)
+
+type balloonPressure int
+
+const (
+	balloonRelax   balloonPressure = -1
+	balloonKeep    balloonPressure = 0
+	balloonReclaim balloonPressure = 1
+
+	balloonMonitorTimeout = 10 * time.Second
+)
+
+// computeBalloonTarget returns the memory size guest should be ballooned to,
+// guestAvailMb < 0 means guest doesn't report its available memory
+func computeBalloonTarget(pressure balloonPressure, actualMb, minMb, maxMb, stepMb, guestAvailMb int64) int64 {
+	switch pressure {
+	case balloonReclaim:
+		reclaimable := actualMb - minMb
+		if guestAvailMb >= 0 && guestAvailMb < reclaimable {
+			reclaimable = guestAvailMb
+		}
+		if stepMb < reclaimable {
+			reclaimable = stepMb
+		}
+		if reclaimable <= 0 {
+			return actualMb
+		}
+		return actualMb - reclaimable
+	case balloonRelax:
+		target := actualMb + stepMb
+		if target > maxMb {
+			target = maxMb
+		}
+		if target < actualMb {
+			return actualMb
+		}
+		return target
+	default:
+		return actualMb
+	}
+}
+
+func getHostBalloonPressure() (balloonPressure, error) {
+	info, err := mem.VirtualMemory()
+	if err != nil {
+		return balloonKeep, errors.Wrap(err, "get host memory")
+	}
+	if info.Total == 0 {
+		return balloonKeep, nil
+	}
+	availPercent := int(info.Available * 100 / info.Total)
+	switch {
+	case availPercent < options.HostOptions.MemoryBalloonPressurePercent:
+		return balloonReclaim, nil
+	case availPercent > options.HostOptions.MemoryBalloonRelaxPercent:
+		return balloonRelax, nil
+	default:
+		return balloonKeep, nil
+	}
+}
+
+func (m *SGuestManager) StartMemoryBalloonController() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				debug.PrintStack()
+				log.Errorf("Memory balloon controller failed %s", r)
+			}
+		}()
+		for {
+			interval := options.HostOptions.MemoryBalloonIntervalSeconds
+			if interval <= 0 {
+				interval = 30
+			}
+			time.Sleep(time.Duration(interval) * time.Second)
+			m.balloonAdjust()
+		}
+	}()
+}
+
+func (m *SGuestManager) balloonAdjust() {
+	pressure, err := getHostBalloonPressure()
+	if err != nil {
+		log.Errorf("balloon controller: %s", err)
+		return
+	}
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest, ok := v.(*SKVMGuestInstance)
+		if !ok || guest.Desc.Balloon == nil || !guest.IsRunning() || guest.Monitor == nil {
+			return true
+		}
+		if err := guest.balloonAdjust(pressure); err != nil {
+			log.Errorf("guest %s balloon adjust: %s", guest.GetName(), err)
+		}
+		return true
+	})
+}
+
+// GetGuestMemoryStats returns reserved and actual memory of guests with balloon device
+func (m *SGuestManager) GetGuestMemoryStats() []compute.SGuestMemoryStat {
+	stats := []compute.SGuestMemoryStat{}
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest, ok := v.(*SKVMGuestInstance)
+		if !ok || guest.Desc.Balloon == nil || !guest.IsRunning() || guest.balloonActualMb <= 0 {
+			return true
+		}
+		stats = append(stats, compute.SGuestMemoryStat{
+			GuestId:       guest.Id,
+			ReservedMemMb: guest.Desc.Mem,
+			ActualMemMb:   guest.balloonActualMb,
+		})
+		return true
+	})
+	return stats
+}
+
+func (s *SKVMGuestInstance) getBalloonMinMemMb() int64 {
+	if minMem, _ := strconv.ParseInt(s.Desc.Metadata[compute.VM_METADATA_BALLOON_MIN_MEM_MB], 10, 64); minMem > 0 {
+		return minMem
+	}
+	return s.Desc.Mem * int64(options.HostOptions.MemoryBalloonMinPercent) / 100
+}
+
+func (s *SKVMGuestInstance) balloonAdjust(pressure balloonPressure) error {
+	if !s.balloonStatsPolling {
+		if err := s.setBalloonStatsPolling(options.HostOptions.MemoryBalloonStatsPollingSeconds); err != nil {
+			return errors.Wrap(err, "set balloon stats polling")
+		}
+		s.balloonStatsPolling = true
+	}
+	actualMb, err := s.queryBalloonActualMb()
+	if err != nil {
+		return errors.Wrap(err, "query balloon")
+	}
+	s.balloonActualMb = actualMb
+
+	guestAvailMb := int64(-1)
+	if pressure == balloonReclaim {
+		if stats, err := s.getBalloonStats(); err != nil {
+			log.Warningf("guest %s get balloon stats: %s", s.GetName(), err)
+		} else if avail, ok := stats.Stats[monitor.BALLOON_STAT_AVAILABLE_MEMORY]; ok && avail >= 0 {
+			guestAvailMb = avail / 1024 / 1024
+		}
+	}
+	stepMb := s.Desc.Mem * int64(options.HostOptions.MemoryBalloonStepPercent) / 100
+	target := computeBalloonTarget(pressure, actualMb, s.getBalloonMinMemMb(), s.Desc.Mem, stepMb, guestAvailMb)
+	if target == actualMb {
+		return nil
+	}
+	log.Infof("guest %s balloon from %dMB to %dMB", s.GetName(), actualMb, target)
+	return s.setBalloon(target)
+}
+
+func (s *SKVMGuestInstance) setBalloon(targetMb int64) error {
+	errCh := make(chan error, 1)
+	s.Monitor.SetBalloon(targetMb, func(res string) {
+		if len(res) > 0 {
+			errCh <- errors.Error(res)
+		} else {
+			errCh <- nil
+		}
+	})
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(balloonMonitorTimeout):
+		return errors.ErrTimeout
+	}
+}
+
+func (s *SKVMGuestInstance) setBalloonStatsPolling(intervalSec int) error {
+	errCh := make(chan error, 1)
+	s.Monitor.SetBalloonStatsPolling(s.Desc.Balloon.Id, intervalSec, func(res string) {
+		if len(res) > 0 {
+			errCh <- errors.Error(res)
+		} else {
+			errCh <- nil
+		}
+	})
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(balloonMonitorTimeout):
+		return errors.ErrTimeout
+	}
+}
+
+func (s *SKVMGuestInstance) queryBalloonActualMb() (int64, error) {
+	type result struct {
+		actual int64
+		err    error
+	}
+	resCh := make(chan result, 1)
+	s.Monitor.QueryBalloon(func(info *monitor.BalloonInfo, err string) {
+		if len(err) > 0 {
+			resCh <- result{err: errors.Error(err)}
+		} else {
+			resCh <- result{actual: info.Actual / 1024 / 1024}
+		}
+	})
+	select {
+	case res := <-resCh:
+		return res.actual, res.err
+	case <-time.After(balloonMonitorTimeout):
+		return 0, errors.ErrTimeout
+	}
+}
+
+func (s *SKVMGuestInstance) getBalloonStats() (*monitor.BalloonStats, error) {
+	type result struct {
+		stats *monitor.BalloonStats
+		err   error
+	}
+	resCh := make(chan result, 1)
+	s.Monitor.GetBalloonStats(s.Desc.Balloon.Id, func(stats *monitor.BalloonStats, err string) {
+		if len(err) > 0 {
+			resCh <- result{err: errors.Error(err)}
+		} else {
+			resCh <- result{stats: stats}
+		}
+	})
+	select {
+	case res := <-resCh:
+		return res.stats, res.err
+	case <-time.After(balloonMonitorTimeout):
+		return nil, errors.ErrTimeout
+	}
+}
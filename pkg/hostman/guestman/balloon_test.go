@@ -0,0 +1,41 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import "testing"
+
+func TestComputeBalloonTarget(t *testing.T) {
+	cases := []struct {
+		name     string
+		pressure balloonPressure
+		actual   int64
+		avail    int64
+		want     int64
+	}{
+		{"keep", balloonKeep, 4096, -1, 4096},
+		{"reclaim one step", balloonReclaim, 4096, -1, 3686},
+		{"reclaim limited by guest available", balloonReclaim, 4096, 100, 3996},
+		{"reclaim limited by min", balloonReclaim, 2100, -1, 2048},
+		{"reclaim at min", balloonReclaim, 2048, -1, 2048},
+		{"relax one step", balloonRelax, 2048, -1, 2458},
+		{"relax limited by max", balloonRelax, 4000, -1, 4096},
+	}
+	for _, c := range cases {
+		got := computeBalloonTarget(c.pressure, c.actual, 2048, 4096, 410, c.avail)
+		if got != c.want {
+			t.Errorf("%s: want %d got %d", c.name, c.want, got)
+		}
+	}
+}
@@ -90,11 +90,19 @@ type SGuestMem struct {
 	SizeMB int64
 
 	Mem *SMemsDesc `json:",omitempty"`
+	// distances between guest numa nodes, mirrors host placement
+	NumaDistances []SNumaDistance `json:",omitempty"`
 
 	// hotplug mem devices
 	MemSlots []*SMemSlot `json:",omitempty"`
 }
 
+type SNumaDistance struct {
+	Src uint16
+	Dst uint16
+	Val int
+}
+
 type SGuestHardwareDesc struct {
 	Cpu     int64
 	CpuDesc *SGuestCpu `json:",omitempty"`
@@ -131,9 +139,11 @@ type SGuestHardwareDesc struct {
 	Disks           []*SGuestDisk           `json:",omitempty"`
 	Nics            []*SGuestNetwork        `json:",omitempty"`
 	IsolatedDevices []*SGuestIsolatedDevice `json:",omitempty"`
+	Virtiofs        []*SGuestVirtiofs       `json:"virtiofs,omitempty"`
 
 	// Random Number Generator Device
 	Rng       *SGuestRng       `json:",omitempty"`
+	Balloon   *SGuestBalloon   `json:",omitempty"`
 	Qga       *SGuestQga       `json:",omitempty"`
 	Pvpanic   *SGuestPvpanic   `json:",omitempty"`
 	IsaSerial *SGuestIsaSerial `json:",omitempty"`
@@ -253,6 +263,29 @@ type SGuestRng struct {
 	RngRandom *Object
 }
 
+type SGuestBalloon struct {
+	*PCIDevice `json:",omitempty"`
+
+	// report free pages to host, require qemu 5.1 and later
+	FreePageReporting bool
+}
+
+// vhost-user-fs-pci device backed by a virtiofsd daemon
+type SGuestVirtiofs struct {
+	*PCIDevice `json:",omitempty"`
+
+	Tag        string `json:"tag"`
+	SourceType string `json:"source_type"`
+	ReadOnly   bool   `json:"read_only"`
+
+	HostPath  string                           `json:"host_path,omitempty"`
+	NfsSource string                           `json:"nfs_source,omitempty"`
+	Cephfs    *host.ContainerVolumeMountCephFS `json:"cephfs,omitempty"`
+
+	// unix socket between qemu and virtiofsd
+	Chardev *CharDev `json:",omitempty"`
+}
+
 type SoundCard struct {
 	*PCIDevice `json:",omitempty"`
 	Codec      *Codec
@@ -0,0 +1,293 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/regutils"
+	"yunion.io/x/pkg/utils"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/monitor"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+	"yunion.io/x/onecloud/pkg/util/qemutils"
+)
+
+type SDiskReplicaPrepare struct {
+	DiskId   string
+	DiskPath string
+	SizeMb   int
+}
+
+func (m *SGuestManager) diskReplicaPidFile(diskId string) string {
+	return path.Join(options.HostOptions.DiskReplicaRunPath, diskId+".pid")
+}
+
+func (m *SGuestManager) diskReplicaPortFile(diskId string) string {
+	return path.Join(options.HostOptions.DiskReplicaRunPath, diskId+".port")
+}
+
+// getDiskReplicaPid returns the pid of qemu-nbd exporting the replica of diskId,
+// or -1 if no export is running
+func (m *SGuestManager) getDiskReplicaPid(diskId string) int {
+	pidFile := m.diskReplicaPidFile(diskId)
+	if !fileutils2.Exists(pidFile) {
+		return -1
+	}
+	pidStr, err := fileutils2.FileGetContents(pidFile)
+	if err != nil {
+		log.Errorf("read disk replica pid file %s: %s", pidFile, err)
+		return -1
+	}
+	pidStr = strings.TrimSpace(pidStr)
+	if !regutils.MatchInteger(pidStr) {
+		return -1
+	}
+	cmdline, err := fileutils2.FileGetContents(fmt.Sprintf("/proc/%s/cmdline", pidStr))
+	if err != nil || !strings.Contains(cmdline, diskId) {
+		return -1
+	}
+	pid, _ := strconv.Atoi(pidStr)
+	return pid
+}
+
+func (m *SGuestManager) diskReplicaNbdUri(port int) string {
+	return fmt.Sprintf("nbd:%s:%d", m.host.GetMasterIp(), port)
+}
+
+// DiskReplicaPrepare creates the replica image of a local disk and exports it
+// through qemu-nbd, so that the primary host can mirror the disk into it
+func (m *SGuestManager) DiskReplicaPrepare(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	input, ok := params.(*SDiskReplicaPrepare)
+	if !ok {
+		return nil, hostutils.ParamsError
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("export_name", jsonutils.NewString(input.DiskId))
+
+	if pid := m.getDiskReplicaPid(input.DiskId); pid > 0 {
+		portStr, err := fileutils2.FileGetContents(m.diskReplicaPortFile(input.DiskId))
+		if err == nil {
+			port, _ := strconv.Atoi(strings.TrimSpace(portStr))
+			if port > 0 {
+				ret.Set("nbd_uri", jsonutils.NewString(m.diskReplicaNbdUri(port)))
+				return ret, nil
+			}
+		}
+		if err := m.stopDiskReplicaExport(input.DiskId); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := procutils.NewCommand("mkdir", "-p", options.HostOptions.DiskReplicaRunPath).Run(); err != nil {
+		return nil, errors.Wrapf(err, "mkdir %s", options.HostOptions.DiskReplicaRunPath)
+	}
+	img, err := qemuimg.NewQemuImage(input.DiskPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "NewQemuImage %s", input.DiskPath)
+	}
+	if !img.IsValid() {
+		if err := img.CreateQcow2(input.SizeMb, false, "", "", "", ""); err != nil {
+			return nil, errors.Wrapf(err, "create replica image %s", input.DiskPath)
+		}
+	} else if img.GetSizeMB() < input.SizeMb {
+		if err := img.Resize(input.SizeMb); err != nil {
+			return nil, errors.Wrapf(err, "resize replica image %s", input.DiskPath)
+		}
+	}
+
+	port := m.GetNBDServerFreePort()
+	pidFile := m.diskReplicaPidFile(input.DiskId)
+	output, err := procutils.NewRemoteCommandAsFarAsPossible(qemutils.GetQemuNbd(),
+		"--fork", "--persistent", "--pid-file", pidFile,
+		"-f", "qcow2", "-x", input.DiskId,
+		"-b", m.host.GetMasterIp(), "-p", strconv.Itoa(port),
+		input.DiskPath,
+	).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "start qemu-nbd for disk %s: %s", input.DiskId, output)
+	}
+	if err := fileutils2.FilePutContents(m.diskReplicaPortFile(input.DiskId), strconv.Itoa(port), false); err != nil {
+		return nil, errors.Wrap(err, "save disk replica port")
+	}
+	ret.Set("nbd_uri", jsonutils.NewString(m.diskReplicaNbdUri(port)))
+	return ret, nil
+}
+
+func (m *SGuestManager) stopDiskReplicaExport(diskId string) error {
+	if pid := m.getDiskReplicaPid(diskId); pid > 0 {
+		output, err := procutils.NewCommand("kill", strconv.Itoa(pid)).Output()
+		if err != nil {
+			return errors.Wrapf(err, "kill qemu-nbd %d: %s", pid, output)
+		}
+	}
+	for _, f := range []string{m.diskReplicaPidFile(diskId), m.diskReplicaPortFile(diskId)} {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "remove %s", f)
+		}
+	}
+	return nil
+}
+
+// DiskReplicaStop stops the nbd export of a replica, the replica image is
+// kept unless deleteDisk is set, e.g. when it is about to take over the guest
+func (m *SGuestManager) DiskReplicaStop(diskId, diskPath string, deleteDisk bool) error {
+	if err := m.stopDiskReplicaExport(diskId); err != nil {
+		return err
+	}
+	if deleteDisk && len(diskPath) > 0 {
+		if err := os.Remove(diskPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "remove replica image %s", diskPath)
+		}
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) getDiskReplicaDrive(diskId string) (string, error) {
+	for i := range s.Desc.Disks {
+		if s.Desc.Disks[i].DiskId == diskId {
+			return fmt.Sprintf("drive_%d", s.Desc.Disks[i].Index), nil
+		}
+	}
+	return "", errors.Wrapf(errors.ErrNotFound, "disk %s of guest %s", diskId, s.Id)
+}
+
+func (s *SKVMGuestInstance) getBlockJobs() ([]monitor.BlockJob, error) {
+	res := make(chan []monitor.BlockJob)
+	s.Monitor.GetBlockJobs(func(jobs []monitor.BlockJob) {
+		res <- jobs
+	})
+	select {
+	case <-time.After(time.Second * 30):
+		return nil, errors.Wrap(errors.ErrTimeout, "get block jobs")
+	case jobs := <-res:
+		return jobs, nil
+	}
+}
+
+func (s *SKVMGuestInstance) waitMonitorResult(f func(monitor.StringCallback)) error {
+	res := make(chan string)
+	f(func(r string) {
+		res <- r
+	})
+	select {
+	case <-time.After(time.Second * 30):
+		return errors.ErrTimeout
+	case r := <-res:
+		if len(r) > 0 {
+			return errors.Error(r)
+		}
+		return nil
+	}
+}
+
+// StartDiskReplica mirrors the whole disk into the replica export, the mirror
+// job is left running after it is ready so that later writes keep replicated
+func (s *SKVMGuestInstance) StartDiskReplica(diskId, nbdUri, exportName, mode string, speed int64) error {
+	if !s.IsRunning() || !s.IsMonitorAlive() {
+		return errors.Errorf("guest %s not running", s.Id)
+	}
+	drive, err := s.getDiskReplicaDrive(diskId)
+	if err != nil {
+		return err
+	}
+	jobs, err := s.getBlockJobs()
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		if jobs[i].Device == drive {
+			return errors.Errorf("drive %s is busy with block job %s", drive, jobs[i].Type)
+		}
+	}
+	target := fmt.Sprintf("%s:exportname=%s", nbdUri, exportName)
+	log.Infof("guest %s start disk replica %s -> %s mode %s", s.Id, drive, target, mode)
+	return s.waitMonitorResult(func(cb monitor.StringCallback) {
+		if mode == api.DISK_REPLICA_MODE_SYNC {
+			s.Monitor.DriveMirrorWithCopyMode(cb, drive, target, "full", "write-blocking", speed)
+		} else {
+			s.Monitor.DriveMirror(cb, drive, target, "full", "", true, false, speed)
+		}
+	})
+}
+
+func (s *SKVMGuestInstance) StopDiskReplica(diskId string) error {
+	if !s.IsRunning() || !s.IsMonitorAlive() {
+		return nil
+	}
+	drive, err := s.getDiskReplicaDrive(diskId)
+	if err != nil {
+		return err
+	}
+	jobs, err := s.getBlockJobs()
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		if jobs[i].Device == drive && jobs[i].Type == "mirror" {
+			return s.waitMonitorResult(func(cb monitor.StringCallback) {
+				s.Monitor.CancelBlockJob(drive, false, cb)
+			})
+		}
+	}
+	return nil
+}
+
+// GetDiskReplicaJobs reports the mirror jobs of replicated disks, disks
+// without a running mirror job are left out
+func (s *SKVMGuestInstance) GetDiskReplicaJobs(diskIds []string) ([]api.DiskReplicaJobStatus, error) {
+	ret := []api.DiskReplicaJobStatus{}
+	if !s.IsRunning() || !s.IsMonitorAlive() {
+		return ret, nil
+	}
+	jobs, err := s.getBlockJobs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range s.Desc.Disks {
+		if len(diskIds) > 0 && !utils.IsInStringArray(s.Desc.Disks[i].DiskId, diskIds) {
+			continue
+		}
+		drive := fmt.Sprintf("drive_%d", s.Desc.Disks[i].Index)
+		for j := range jobs {
+			if jobs[j].Device != drive || jobs[j].Type != "mirror" {
+				continue
+			}
+			ret = append(ret, api.DiskReplicaJobStatus{
+				DiskId:   s.Desc.Disks[i].DiskId,
+				Device:   drive,
+				Ready:    jobs[j].Ready,
+				Len:      jobs[j].Len,
+				Offset:   jobs[j].Offset,
+				IoStatus: jobs[j].IoStatus,
+			})
+		}
+	}
+	return ret, nil
+}
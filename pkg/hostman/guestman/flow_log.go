@@ -0,0 +1,563 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/cloudcommon/tsdb"
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/backupstorage"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/util/influxdb"
+)
+
+const (
+	flowLogAclLogTag = "|acl_log("
+
+	// max bytes read from ovn-controller log at each flush
+	flowLogMaxReadBytes = 64 * 1024 * 1024
+)
+
+// sFlowLogAclLog is an acl log line of ovn-controller, e.g.
+//
+//	2024-05-17T09:31:36.052Z|00013|acl_log(ovn_pinctrl0)|INFO|name="fl/ingress/default", verdict=drop, severity=info, direction=to-lport: icmp,vlan_tci=0x0000,dl_src=00:22:00:00:00:01,dl_dst=00:22:00:00:00:02,nw_src=10.0.0.1,nw_dst=10.0.0.2,nw_tos=0,nw_ecn=0,nw_ttl=64,icmp_type=8,icmp_code=0
+type sFlowLogAclLog struct {
+	Time      time.Time
+	Direction string
+	RuleId    string
+	Verdict   string
+	Protocol  string
+	SrcMac    string
+	DstMac    string
+	SrcIp     string
+	DstIp     string
+	SrcPort   int
+	DstPort   int
+}
+
+// GuestMac returns mac address of the guest nic the acl is attached to
+func (l *sFlowLogAclLog) GuestMac() string {
+	if l.Direction == api.FLOW_LOG_DIRECTION_INGRESS {
+		return l.DstMac
+	}
+	return l.SrcMac
+}
+
+func parseFlowLogAclLog(line string) (*sFlowLogAclLog, bool) {
+	if !strings.Contains(line, flowLogAclLogTag) {
+		return nil, false
+	}
+	parts := strings.SplitN(line, "|", 5)
+	if len(parts) != 5 {
+		return nil, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, false
+	}
+	i := strings.Index(parts[4], ": ")
+	if i < 0 {
+		return nil, false
+	}
+	meta, flow := parts[4][:i], parts[4][i+2:]
+
+	r := &sFlowLogAclLog{
+		Time: ts.UTC(),
+	}
+	var ok bool
+	for _, kv := range strings.Split(meta, ", ") {
+		k, v, found := strings.Cut(kv, "=")
+		if !found {
+			continue
+		}
+		switch k {
+		case "name":
+			r.Direction, r.RuleId, ok = api.ParseFlowLogAclName(strings.Trim(v, `"`))
+		case "verdict":
+			if v == "allow" {
+				r.Verdict = api.FLOW_LOG_TRAFFIC_TYPE_ACCEPT
+			} else {
+				r.Verdict = api.FLOW_LOG_TRAFFIC_TYPE_REJECT
+			}
+		}
+	}
+	if !ok || r.Verdict == "" {
+		// not logged for flow log
+		return nil, false
+	}
+
+	for i, kv := range strings.Split(flow, ",") {
+		if i == 0 {
+			switch kv {
+			case "tcp6", "udp6", "sctp6":
+				r.Protocol = strings.TrimSuffix(kv, "6")
+			default:
+				r.Protocol = kv
+			}
+			continue
+		}
+		k, v, found := strings.Cut(kv, "=")
+		if !found {
+			continue
+		}
+		switch k {
+		case "dl_src":
+			r.SrcMac = v
+		case "dl_dst":
+			r.DstMac = v
+		case "nw_src", "ipv6_src":
+			r.SrcIp = v
+		case "nw_dst", "ipv6_dst":
+			r.DstIp = v
+		case "tp_src":
+			r.SrcPort, _ = strconv.Atoi(v)
+		case "tp_dst":
+			r.DstPort, _ = strconv.Atoi(v)
+		}
+	}
+	return r, true
+}
+
+type sFlowLogNic struct {
+	GuestId   string
+	NetworkId string
+	VpcId     string
+	MacAddr   string
+}
+
+type sFlowLog struct {
+	Id              string `json:"id"`
+	VpcId           string `json:"vpc_id"`
+	NetworkId       string `json:"network_id"`
+	GuestId         string `json:"guest_id"`
+	MacAddr         string `json:"mac_addr"`
+	Target          string `json:"target"`
+	BackupStorageId string `json:"backup_storage_id"`
+	TargetPrefix    string `json:"target_prefix"`
+}
+
+// matchFlowLog returns the most specific flow log covering the nic, it must
+// agree with what vpcagent does when turning on logging of acls
+func matchFlowLog(flowLogs []sFlowLog, nic *sFlowLogNic) *sFlowLog {
+	var (
+		found  *sFlowLog
+		fScore = -1
+	)
+	for i := range flowLogs {
+		flowLog := &flowLogs[i]
+		if flowLog.VpcId != nic.VpcId {
+			continue
+		}
+		score := 0
+		if flowLog.GuestId != "" {
+			if flowLog.GuestId != nic.GuestId || flowLog.MacAddr != nic.MacAddr {
+				continue
+			}
+			score = 2
+		} else if flowLog.NetworkId != "" {
+			if flowLog.NetworkId != nic.NetworkId {
+				continue
+			}
+			score = 1
+		}
+		if score > fScore || (score == fScore && flowLog.Id < found.Id) {
+			found = flowLog
+			fScore = score
+		}
+	}
+	return found
+}
+
+type sFlowLogCollector struct {
+	m *SGuestManager
+
+	logPath string
+	inode   uint64
+	offset  int64
+
+	// rule id => secgroup id
+	ruleSecgroups map[string]string
+}
+
+func (m *SGuestManager) StartFlowLogCollector() {
+	if !options.HostOptions.EnableFlowLog {
+		return
+	}
+	c := &sFlowLogCollector{
+		m:             m,
+		logPath:       options.HostOptions.FlowLogOvnControllerLogPath,
+		offset:        -1,
+		ruleSecgroups: map[string]string{},
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				debug.PrintStack()
+				log.Errorf("Flow log collector failed %s", r)
+			}
+		}()
+		for {
+			interval := options.HostOptions.FlowLogFlushIntervalSeconds
+			if interval <= 0 {
+				interval = 60
+			}
+			time.Sleep(time.Duration(interval) * time.Second)
+			if err := c.collect(context.Background()); err != nil {
+				log.Errorf("flow log collector: %s", err)
+			}
+		}
+	}()
+}
+
+// readLines returns lines appended to ovn-controller log since last read
+// with the position after them.  The position is committed by caller after
+// the lines are shipped.  Lines present at the first read are skipped
+func (c *sFlowLogCollector) readLines() ([]string, uint64, int64, error) {
+	f, err := os.Open(c.logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, c.inode, c.offset, nil
+		}
+		return nil, 0, 0, errors.Wrapf(err, "open %s", c.logPath)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, 0, 0, errors.Wrapf(err, "stat %s", c.logPath)
+	}
+	var inode uint64
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		inode = st.Ino
+	}
+	size := fi.Size()
+	if c.offset < 0 {
+		return nil, inode, size, nil
+	}
+	offset := c.offset
+	if inode != c.inode || size < offset {
+		// rotated
+		offset = 0
+	}
+	if size-offset > flowLogMaxReadBytes {
+		log.Warningf("flow log collector: skip %d bytes of %s", size-flowLogMaxReadBytes-offset, c.logPath)
+		offset = size - flowLogMaxReadBytes
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, 0, errors.Wrapf(err, "seek %s", c.logPath)
+	}
+	data, err := io.ReadAll(io.LimitReader(f, size-offset))
+	if err != nil {
+		return nil, 0, 0, errors.Wrapf(err, "read %s", c.logPath)
+	}
+	// leave incomplete last line to next read
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[:i+1]
+	} else {
+		data = data[:0]
+	}
+	offset += int64(len(data))
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.Contains(line, flowLogAclLogTag) {
+			lines = append(lines, line)
+		}
+	}
+	return lines, inode, offset, nil
+}
+
+func (c *sFlowLogCollector) localNics() map[string]*sFlowLogNic {
+	nics := map[string]*sFlowLogNic{}
+	c.m.Servers.Range(func(k, v interface{}) bool {
+		guest := v.(GuestRuntimeInstance)
+		guestDesc := guest.GetDesc()
+		if guestDesc == nil {
+			return true
+		}
+		for _, nic := range guestDesc.Nics {
+			if nic.Vpc.Provider != api.VPC_PROVIDER_OVN {
+				continue
+			}
+			nics[nic.Mac] = &sFlowLogNic{
+				GuestId:   guest.GetId(),
+				NetworkId: nic.NetId,
+				VpcId:     nic.Vpc.Id,
+				MacAddr:   nic.Mac,
+			}
+		}
+		return true
+	})
+	return nics
+}
+
+func (c *sFlowLogCollector) listFlowLogs(s *mcclient.ClientSession) ([]sFlowLog, error) {
+	params := jsonutils.NewDict()
+	params.Set("enabled", jsonutils.JSONTrue)
+	params.Set("scope", jsonutils.NewString("system"))
+	params.Set("limit", jsonutils.NewInt(0))
+	ret, err := modules.FlowLogs.List(s, params)
+	if err != nil {
+		return nil, errors.Wrap(err, "list flow logs")
+	}
+	flowLogs := make([]sFlowLog, 0, len(ret.Data))
+	for _, data := range ret.Data {
+		flowLog := sFlowLog{}
+		if err := data.Unmarshal(&flowLog); err != nil {
+			return nil, errors.Wrap(err, "unmarshal flow log")
+		}
+		flowLogs = append(flowLogs, flowLog)
+	}
+	return flowLogs, nil
+}
+
+func (c *sFlowLogCollector) ruleSecgroupId(s *mcclient.ClientSession, ruleId string) string {
+	if ruleId == "" {
+		return ""
+	}
+	if secgroupId, ok := c.ruleSecgroups[ruleId]; ok {
+		return secgroupId
+	}
+	ret, err := modules.SecGroupRules.Get(s, ruleId, nil)
+	if err != nil {
+		log.Warningf("flow log collector: get secgroup rule %s: %s", ruleId, err)
+		return ""
+	}
+	secgroupId, _ := ret.GetString("secgroup_id")
+	c.ruleSecgroups[ruleId] = secgroupId
+	return secgroupId
+}
+
+// pruneRuleSecgroups drops cached rules not seen in the latest flush, so
+// that removed rules do not stay in the cache forever
+func (c *sFlowLogCollector) pruneRuleSecgroups(seen map[string]struct{}) {
+	for ruleId := range c.ruleSecgroups {
+		if _, ok := seen[ruleId]; !ok {
+			delete(c.ruleSecgroups, ruleId)
+		}
+	}
+}
+
+// collect ships lines appended since last collect.  The read position only
+// moves forward after the records are shipped, records failed to ship are
+// read again on next collect
+func (c *sFlowLogCollector) collect(ctx context.Context) error {
+	lines, inode, offset, err := c.readLines()
+	if err != nil {
+		return err
+	}
+	if err := c.ship(ctx, lines); err != nil {
+		return err
+	}
+	c.inode, c.offset = inode, offset
+	return nil
+}
+
+func (c *sFlowLogCollector) ship(ctx context.Context, lines []string) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	s := hostutils.GetComputeSession(ctx)
+	flowLogs, err := c.listFlowLogs(s)
+	if err != nil {
+		return err
+	}
+	if len(flowLogs) == 0 {
+		return nil
+	}
+
+	var (
+		hostId = c.m.host.GetHostId()
+		nics   = c.localNics()
+		keys   []string
+		// records of the same flow are merged within a flush
+		records   = map[string]*api.SFlowLogRecord{}
+		targets   = map[string]*sFlowLog{}
+		flowLogOf = map[string]string{}
+		seenRules = map[string]struct{}{}
+	)
+	for _, line := range lines {
+		aclLog, ok := parseFlowLogAclLog(line)
+		if !ok {
+			continue
+		}
+		if aclLog.RuleId != "" {
+			seenRules[aclLog.RuleId] = struct{}{}
+		}
+		nic, ok := nics[aclLog.GuestMac()]
+		if !ok {
+			continue
+		}
+		flowLog := matchFlowLog(flowLogs, nic)
+		if flowLog == nil {
+			continue
+		}
+		key := strings.Join([]string{
+			flowLog.Id, nic.MacAddr, aclLog.Direction, aclLog.Verdict, aclLog.RuleId, aclLog.Protocol,
+			aclLog.SrcIp, strconv.Itoa(aclLog.SrcPort), aclLog.DstIp, strconv.Itoa(aclLog.DstPort),
+		}, "/")
+		if record, ok := records[key]; ok {
+			record.Packets += 1
+			continue
+		}
+		records[key] = &api.SFlowLogRecord{
+			Time:       aclLog.Time,
+			FlowLogId:  flowLog.Id,
+			HostId:     hostId,
+			VpcId:      nic.VpcId,
+			NetworkId:  nic.NetworkId,
+			ServerId:   nic.GuestId,
+			MacAddr:    nic.MacAddr,
+			SecgroupId: c.ruleSecgroupId(s, aclLog.RuleId),
+			RuleId:     aclLog.RuleId,
+			Direction:  aclLog.Direction,
+			Verdict:    aclLog.Verdict,
+			Protocol:   aclLog.Protocol,
+			SrcIp:      aclLog.SrcIp,
+			SrcPort:    aclLog.SrcPort,
+			DstIp:      aclLog.DstIp,
+			DstPort:    aclLog.DstPort,
+			Packets:    1,
+		}
+		keys = append(keys, key)
+		targets[flowLog.Id] = flowLog
+		flowLogOf[key] = flowLog.Id
+	}
+	c.pruneRuleSecgroups(seenRules)
+
+	tsdbRecords := []api.SFlowLogRecord{}
+	s3Records := map[string][]api.SFlowLogRecord{}
+	for _, key := range keys {
+		flowLog := targets[flowLogOf[key]]
+		switch flowLog.Target {
+		case api.FLOW_LOG_TARGET_S3:
+			s3Records[flowLog.Id] = append(s3Records[flowLog.Id], *records[key])
+		default:
+			tsdbRecords = append(tsdbRecords, *records[key])
+		}
+	}
+	var errs []error
+	if len(tsdbRecords) > 0 {
+		if err := c.shipTsdb(s, tsdbRecords); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for flowLogId, records := range s3Records {
+		if err := c.shipS3(ctx, s, targets[flowLogId], hostId, records); err != nil {
+			errs = append(errs, errors.Wrapf(err, "flow log %s", flowLogId))
+		}
+	}
+	return errors.NewAggregate(errs)
+}
+
+func flowLogRecordMetric(record *api.SFlowLogRecord) influxdb.SMetricData {
+	tag := func(k, v string) influxdb.SKeyValue {
+		return influxdb.SKeyValue{Key: k, Value: v}
+	}
+	return influxdb.SMetricData{
+		Name: api.FLOW_LOG_TSDB_MEASUREMENT,
+		Tags: []influxdb.SKeyValue{
+			tag("flow_log_id", record.FlowLogId),
+			tag("host_id", record.HostId),
+			tag("vpc_id", record.VpcId),
+			tag("network_id", record.NetworkId),
+			tag("server_id", record.ServerId),
+			tag("mac_addr", record.MacAddr),
+			tag("secgroup_id", record.SecgroupId),
+			tag("rule_id", record.RuleId),
+			tag("direction", record.Direction),
+			tag("verdict", record.Verdict),
+			tag("protocol", record.Protocol),
+			tag("src_ip", record.SrcIp),
+			tag("src_port", strconv.Itoa(record.SrcPort)),
+			tag("dst_ip", record.DstIp),
+			tag("dst_port", strconv.Itoa(record.DstPort)),
+		},
+		Metrics: []influxdb.SKeyValue{
+			{Key: "packets", Value: fmt.Sprintf("%di", record.Packets)},
+		},
+		Timestamp: record.Time,
+	}
+}
+
+func (c *sFlowLogCollector) shipTsdb(s *mcclient.ClientSession, records []api.SFlowLogRecord) error {
+	src, err := tsdb.GetDefaultServiceSource(s, options.HostOptions.SessionEndpointType)
+	if err != nil {
+		return errors.Wrap(err, "get tsdb source")
+	}
+	metrics := make([]influxdb.SMetricData, len(records))
+	for i := range records {
+		metrics[i] = flowLogRecordMetric(&records[i])
+	}
+	if err := influxdb.BatchSendMetrics(src.URLs, api.FLOW_LOG_TSDB_DATABASE, metrics, false); err != nil {
+		return errors.Wrap(err, "send flow log records")
+	}
+	return nil
+}
+
+// flowLogObjectKey follows the format documented at api.SFlowLogRecord
+func flowLogObjectKey(prefix, flowLogId, hostId string, now time.Time) string {
+	key := fmt.Sprintf("%s/%s/%s-%d.jsonl", flowLogId, now.UTC().Format("2006/01/02"), hostId, now.UnixNano())
+	if prefix != "" {
+		key = prefix + "/" + key
+	}
+	return key
+}
+
+func (c *sFlowLogCollector) shipS3(ctx context.Context, s *mcclient.ClientSession, flowLog *sFlowLog, hostId string, records []api.SFlowLogRecord) error {
+	ret, err := modules.FlowLogs.GetSpecific(s, flowLog.Id, "target-info", nil)
+	if err != nil {
+		return errors.Wrap(err, "get target info")
+	}
+	info := api.FlowLogTargetInfo{}
+	if err := ret.Unmarshal(&info); err != nil {
+		return errors.Wrap(err, "unmarshal target info")
+	}
+	accessInfo := jsonutils.Marshal(info.SBackupStorageAccessInfo).(*jsonutils.JSONDict)
+	bs, err := backupstorage.GetBackupStorage(flowLog.BackupStorageId, accessInfo)
+	if err != nil {
+		return errors.Wrap(err, "GetBackupStorage")
+	}
+	saver, ok := bs.(backupstorage.IObjectSaver)
+	if !ok {
+		return errors.Wrapf(errors.ErrNotSupported, "backup storage %s can not save objects", flowLog.BackupStorageId)
+	}
+	var buf bytes.Buffer
+	for i := range records {
+		buf.WriteString(jsonutils.Marshal(&records[i]).String())
+		buf.WriteByte('\n')
+	}
+	key := flowLogObjectKey(info.TargetPrefix, flowLog.Id, hostId, time.Now())
+	return saver.SaveObject(ctx, key, buf.Bytes())
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func TestParseFlowLogAclLog(t *testing.T) {
+	cases := []struct {
+		line string
+		want *sFlowLogAclLog
+		mac  string
+	}{
+		{
+			line: `2024-05-17T09:31:36.052Z|00013|acl_log(ovn_pinctrl0)|INFO|name="fl/ingress/default", verdict=drop, severity=info, direction=to-lport: icmp,vlan_tci=0x0000,dl_src=00:22:00:00:00:01,dl_dst=00:22:00:00:00:02,nw_src=10.0.0.1,nw_dst=10.0.0.2,nw_tos=0,nw_ecn=0,nw_ttl=64,icmp_type=8,icmp_code=0`,
+			want: &sFlowLogAclLog{
+				Time:      time.Date(2024, 5, 17, 9, 31, 36, 52000000, time.UTC),
+				Direction: api.FLOW_LOG_DIRECTION_INGRESS,
+				Verdict:   api.FLOW_LOG_TRAFFIC_TYPE_REJECT,
+				Protocol:  "icmp",
+				SrcMac:    "00:22:00:00:00:01",
+				DstMac:    "00:22:00:00:00:02",
+				SrcIp:     "10.0.0.1",
+				DstIp:     "10.0.0.2",
+			},
+			mac: "00:22:00:00:00:02",
+		},
+		{
+			line: `2024-05-17T09:31:37.100Z|00014|acl_log(ovn_pinctrl0)|INFO|name="fl/egress/rule0", verdict=allow, severity=info: tcp6,vlan_tci=0x0000,dl_src=00:22:00:00:00:01,dl_dst=00:22:00:00:00:02,ipv6_src=fd00::1,ipv6_dst=fd00::2,ipv6_label=0x00000,nw_tos=0,nw_ecn=0,nw_ttl=64,tp_src=43210,tp_dst=443,tcp_flags=syn`,
+			want: &sFlowLogAclLog{
+				Time:      time.Date(2024, 5, 17, 9, 31, 37, 100000000, time.UTC),
+				Direction: api.FLOW_LOG_DIRECTION_EGRESS,
+				RuleId:    "rule0",
+				Verdict:   api.FLOW_LOG_TRAFFIC_TYPE_ACCEPT,
+				Protocol:  "tcp",
+				SrcMac:    "00:22:00:00:00:01",
+				DstMac:    "00:22:00:00:00:02",
+				SrcIp:     "fd00::1",
+				DstIp:     "fd00::2",
+				SrcPort:   43210,
+				DstPort:   443,
+			},
+			mac: "00:22:00:00:00:01",
+		},
+		{
+			// acl not logged for flow log
+			line: `2024-05-17T09:31:37.100Z|00015|acl_log(ovn_pinctrl0)|INFO|name="other", verdict=allow, severity=info: tcp,dl_src=00:22:00:00:00:01`,
+		},
+		{
+			line: `2024-05-17T09:31:37.100Z|00016|binding|INFO|Claiming lport iface-xx-eth0 for this chassis.`,
+		},
+	}
+	for _, c := range cases {
+		got, ok := parseFlowLogAclLog(c.line)
+		if c.want == nil {
+			if ok {
+				t.Errorf("want not parsed: %s", c.line)
+			}
+			continue
+		}
+		if !ok || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("want %#v, got %#v", c.want, got)
+			continue
+		}
+		if got.GuestMac() != c.mac {
+			t.Errorf("want guest mac %s, got %s", c.mac, got.GuestMac())
+		}
+	}
+}
+
+func TestMatchFlowLog(t *testing.T) {
+	flowLogs := []sFlowLog{
+		{Id: "fl-vpc", VpcId: "vpc0"},
+		{Id: "fl-net", VpcId: "vpc0", NetworkId: "net0"},
+		{Id: "fl-nic", VpcId: "vpc0", NetworkId: "net0", GuestId: "guest0", MacAddr: "00:22:00:00:00:01"},
+		{Id: "fl-vpc1", VpcId: "vpc1"},
+	}
+	cases := []struct {
+		nic  sFlowLogNic
+		want string
+	}{
+		{sFlowLogNic{GuestId: "guest0", NetworkId: "net0", VpcId: "vpc0", MacAddr: "00:22:00:00:00:01"}, "fl-nic"},
+		{sFlowLogNic{GuestId: "guest0", NetworkId: "net0", VpcId: "vpc0", MacAddr: "00:22:00:00:00:02"}, "fl-net"},
+		{sFlowLogNic{GuestId: "guest1", NetworkId: "net1", VpcId: "vpc0", MacAddr: "00:22:00:00:00:03"}, "fl-vpc"},
+		{sFlowLogNic{GuestId: "guest2", NetworkId: "net2", VpcId: "vpc2", MacAddr: "00:22:00:00:00:04"}, ""},
+	}
+	for _, c := range cases {
+		got := matchFlowLog(flowLogs, &c.nic)
+		gotId := ""
+		if got != nil {
+			gotId = got.Id
+		}
+		if gotId != c.want {
+			t.Errorf("nic %#v: want %q, got %q", c.nic, c.want, gotId)
+		}
+	}
+}
+
+func TestFlowLogObjectKey(t *testing.T) {
+	now := time.Date(2024, 5, 17, 9, 31, 36, 0, time.UTC)
+	if got, want := flowLogObjectKey("logs", "fl0", "host0", now), "logs/fl0/2024/05/17/host0-1715938296000000000.jsonl"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	if got, want := flowLogObjectKey("", "fl0", "host0", now), "fl0/2024/05/17/host0-1715938296000000000.jsonl"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestFlowLogCollectorReadLines(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "ovn-controller.log")
+	write := func(content string) {
+		fd, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fd.Close()
+		fd.WriteString(content)
+	}
+	c := &sFlowLogCollector{logPath: logPath, offset: -1}
+
+	write("old|acl_log(ovn_pinctrl0)|INFO\n")
+	lines, inode, offset, err := c.readLines()
+	if err != nil || len(lines) != 0 {
+		t.Fatalf("lines at the first read should be skipped, got %v %v", lines, err)
+	}
+	c.inode, c.offset = inode, offset
+
+	write("a|acl_log(ovn_pinctrl0)|INFO\nb|vconn|INFO\nc|acl_log(ovn_pinctrl0)|INFO")
+	lines, _, offset, err = c.readLines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, []string{"a|acl_log(ovn_pinctrl0)|INFO"}) {
+		t.Errorf("unexpected lines %v", lines)
+	}
+	// not shipped, the same lines are read again
+	lines, _, offset2, err := c.readLines()
+	if err != nil || len(lines) != 1 || offset2 != offset {
+		t.Errorf("uncommitted lines should be read again, got %v at %d", lines, offset2)
+	}
+	c.offset = offset
+
+	write("\n")
+	lines, _, _, err = c.readLines()
+	if err != nil || !reflect.DeepEqual(lines, []string{"c|acl_log(ovn_pinctrl0)|INFO"}) {
+		t.Errorf("unexpected lines after commit %v %v", lines, err)
+	}
+}
+
+func TestFlowLogCollectorPruneRuleSecgroups(t *testing.T) {
+	c := &sFlowLogCollector{
+		ruleSecgroups: map[string]string{"r0": "sg0", "r1": "sg1"},
+	}
+	c.pruneRuleSecgroups(map[string]struct{}{"r1": {}})
+	if !reflect.DeepEqual(c.ruleSecgroups, map[string]string{"r1": "sg1"}) {
+		t.Errorf("unexpected rule secgroups %v", c.ruleSecgroups)
+	}
+}
@@ -90,6 +90,9 @@ func AddGuestTaskHandler(prefix string, app *appsrv.Application) {
 			"hotplug-cpu-mem":          guestHotplugCpuMem,
 			"cancel-block-jobs":        guestCancelBlockJobs,
 			"cancel-block-replication": guestCancelBlockReplication,
+			"disk-replica-start":       guestDiskReplicaStart,
+			"disk-replica-stop":        guestDiskReplicaStop,
+			"disk-replica-status":      guestDiskReplicaStatus,
 			"create-from-libvirt":      guestCreateFromLibvirt,
 			"create-form-esxi":         guestCreateFromEsxi,
 			"create-from-cloudpods":    guestCreateFromCloudpods,
@@ -112,6 +115,8 @@ func AddGuestTaskHandler(prefix string, app *appsrv.Application) {
 			"qga-set-network":          qgaSetNetwork,
 			"qga-get-os-info":          qgaGetOsInfo,
 			"start-rescue":             guestStartRescue,
+			"serial-log":               guestSerialLog,
+			"screendump":               guestScreenDump,
 		} {
 			app.AddHandler("POST",
 				fmt.Sprintf("%s/%s/<sid>/%s", prefix, keyWord, action),
@@ -440,6 +445,7 @@ func guestDestPrepareMigrateInternal(ctx context.Context, userCred mcclient.Toke
 	params.MemorySnapshotsUri = msUri
 	msIds, _ := jsonutils.GetStringArray(body, "src_memory_snapshots")
 	params.SrcMemorySnapshots = msIds
+	params.SerialLogUri, _ = body.GetString("serial_log_uri")
 
 	params.UserCred = userCred
 
@@ -576,6 +582,57 @@ func guestCancelBlockReplication(
 	return nil, nil
 }
 
+func guestDiskReplicaStart(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	guest, ok := guestman.GetGuestManager().GetKVMServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
+	}
+	diskId, err := body.GetString("disk_id")
+	if err != nil {
+		return nil, httperrors.NewMissingParameterError("disk_id")
+	}
+	nbdUri, err := body.GetString("nbd_uri")
+	if err != nil {
+		return nil, httperrors.NewMissingParameterError("nbd_uri")
+	}
+	exportName, err := body.GetString("export_name")
+	if err != nil {
+		return nil, httperrors.NewMissingParameterError("export_name")
+	}
+	mode, _ := body.GetString("mode")
+	speed, _ := body.Int("speed")
+	return nil, guest.StartDiskReplica(diskId, nbdUri, exportName, mode, speed)
+}
+
+func guestDiskReplicaStop(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	guest, ok := guestman.GetGuestManager().GetKVMServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
+	}
+	diskId, err := body.GetString("disk_id")
+	if err != nil {
+		return nil, httperrors.NewMissingParameterError("disk_id")
+	}
+	return nil, guest.StopDiskReplica(diskId)
+}
+
+func guestDiskReplicaStatus(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	guest, ok := guestman.GetGuestManager().GetKVMServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
+	}
+	diskIds := []string{}
+	body.Unmarshal(&diskIds, "disk_ids")
+	jobs, err := guest.GetDiskReplicaJobs(diskIds)
+	if err != nil {
+		return nil, err
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("running", jsonutils.NewBool(guest.IsRunning()))
+	ret.Set("jobs", jsonutils.Marshal(jobs))
+	return ret, nil
+}
+
 func guestHotplugCpuMem(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
 	if !guestman.GetGuestManager().IsGuestExist(sid) {
 		return nil, httperrors.NewNotFoundError("Guest %s not found", sid)
@@ -976,6 +1033,26 @@ func qgaCommand(ctx context.Context, userCred mcclient.TokenCredential, sid stri
 	return gm.QgaCommand(qgaCmd, sid, input.Timeout)
 }
 
+func guestSerialLog(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	guest, ok := guestman.GetGuestManager().GetKVMServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Not found guest by id %s", sid)
+	}
+	input := new(computeapi.ServerGetSerialLogInput)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, httperrors.NewInputParameterError("unmarshal input to ServerGetSerialLogInput: %s", err.Error())
+	}
+	return guest.GetSerialLog(input)
+}
+
+func guestScreenDump(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
+	guest, ok := guestman.GetGuestManager().GetKVMServer(sid)
+	if !ok {
+		return nil, httperrors.NewNotFoundError("Not found guest by id %s", sid)
+	}
+	return guest.ScreenDump()
+}
+
 func qgaGuestInfoTask(ctx context.Context, userCred mcclient.TokenCredential, sid string, body jsonutils.JSONObject) (interface{}, error) {
 	gm := guestman.GetGuestManager()
 	return gm.QgaGuestInfoTask(sid)
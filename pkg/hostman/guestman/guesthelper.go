@@ -79,6 +79,7 @@ type SDestPrepareMigrate struct {
 
 	MemorySnapshotsUri string
 	SrcMemorySnapshots []string
+	SerialLogUri       string
 
 	UserCred mcclient.TokenCredential
 }
@@ -487,6 +487,9 @@ func (m *SGuestManager) OnLoadExistingGuestsComplete() {
 		m.ClenaupCpuset()
 	}
 	m.startContainerSyncLoop()
+	m.StartMemoryBalloonController()
+	m.StartSerialLogRotator()
+	m.StartFlowLogCollector()
 }
 
 func (m *SGuestManager) verifyDirtyServers() {
@@ -1267,6 +1270,13 @@ func (m *SGuestManager) DestPrepareMigrate(ctx context.Context, params interface
 		body.Add(jsonutils.Marshal(preparedMs), "dest_prepared_memory_snapshots")
 	}
 
+	if len(migParams.SerialLogUri) > 0 {
+		if err := guest.fetchMigrateSerialLog(ctx, migParams.SerialLogUri); err != nil {
+			// serial log is diagnostic only, don't block migration
+			log.Errorf("guest %s fetch serial log from %s: %s", guest.GetName(), migParams.SerialLogUri, err)
+		}
+	}
+
 	if migParams.LiveMigrate {
 		startParams := jsonutils.NewDict()
 		startParams.Set("qemu_version", jsonutils.NewString(migParams.QemuVersion))
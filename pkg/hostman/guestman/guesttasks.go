@@ -2699,7 +2699,7 @@ func (task *SGuestHotplugCpuMemTask) onGetSlotIndex(index int) {
 	var id = fmt.Sprintf("mem%d", *task.memSlotNewIndex)
 	var opts map[string]string
 
-	if task.manager.host.IsHugepagesEnabled() {
+	if task.isHugepagesBacked() {
 		memPath := fmt.Sprintf("/dev/hugepages/%s-%d", task.GetId(), index)
 
 		err := procutils.NewRemoteCommandAsFarAsPossible("mkdir", "-p", memPath).Run()
@@ -2710,7 +2710,7 @@ func (task *SGuestHotplugCpuMemTask) onGetSlotIndex(index int) {
 			return
 		}
 		err = procutils.NewRemoteCommandAsFarAsPossible("mount", "-t", "hugetlbfs", "-o",
-			fmt.Sprintf("pagesize=%dK,size=%dM", task.manager.host.HugepageSizeKb(), addMemSize),
+			fmt.Sprintf("pagesize=%dK,size=%dM", task.hugepageSizeKb(), addMemSize),
 			fmt.Sprintf("hugetlbfs-%s-%d", task.GetId(), index),
 			memPath,
 		).Run()
@@ -96,6 +96,8 @@ func (s *SKVMGuestInstance) initGuestDevicesDesc(pciRoot, pciBridge *desc.PCICon
 	s.initIsolatedDevices(pciRoot, pciBridge)
 	s.initUsbController(pciRoot)
 	s.initRandomDevice(pciRoot, options.HostOptions.EnableVirtioRngDevice)
+	s.initBalloonDevice(pciRoot, s.isMemoryBalloonEnabled())
+	s.initVirtiofsDevices(pciRoot)
 	s.initQgaDesc()
 	s.initPvpanicDesc()
 	s.initIsaSerialDesc()
@@ -278,6 +280,34 @@ func (s *SKVMGuestInstance) initRandomDevice(pciRoot *desc.PCIController, enable
 	}
 }
 
+func (s *SKVMGuestInstance) initBalloonDevice(pciRoot *desc.PCIController, enableBalloon bool) {
+	if !enableBalloon {
+		return
+	}
+	s.Desc.Balloon = &desc.SGuestBalloon{
+		PCIDevice:         desc.NewPCIDevice(pciRoot.CType, "virtio-balloon-pci", "balloon0"),
+		FreePageReporting: options.HostOptions.MemoryBalloonFreePageReporting,
+	}
+}
+
+func (s *SKVMGuestInstance) initVirtiofsDevices(pciRoot *desc.PCIController) {
+	for i := range s.Desc.Virtiofs {
+		s.initVirtiofsDevice(pciRoot, i)
+	}
+}
+
+func (s *SKVMGuestInstance) initVirtiofsDevice(pciRoot *desc.PCIController, idx int) {
+	vfs := s.Desc.Virtiofs[idx]
+	vfs.PCIDevice = desc.NewPCIDevice(pciRoot.CType, "vhost-user-fs-pci", fmt.Sprintf("vfs%d", idx))
+	vfs.Chardev = &desc.CharDev{
+		Backend: "socket",
+		Id:      fmt.Sprintf("char-vfs%d", idx),
+		Options: map[string]string{
+			"path": s.getVirtiofsSocketPath(idx),
+		},
+	}
+}
+
 func (s *SKVMGuestInstance) initUsbController(pciRoot *desc.PCIController) {
 	contType := s.getUsbControllerType()
 	s.Desc.Usb = &desc.UsbController{
@@ -744,6 +774,20 @@ func (s *SKVMGuestInstance) ensurePciAddresses() error {
 		}
 	}
 
+	if s.Desc.Balloon != nil {
+		err = s.ensureDevicePciAddress(s.Desc.Balloon.PCIDevice, -1, nil)
+		if err != nil {
+			return errors.Wrap(err, "ensure balloon device pci address")
+		}
+	}
+
+	for i := 0; i < len(s.Desc.Virtiofs); i++ {
+		err = s.ensureDevicePciAddress(s.Desc.Virtiofs[i].PCIDevice, -1, nil)
+		if err != nil {
+			return errors.Wrapf(err, "ensure virtiofs %s pci address", s.Desc.Virtiofs[i].Tag)
+		}
+	}
+
 	anonymousPCIDevs := s.Desc.AnonymousPCIDevs[:0]
 	for i := 0; i < len(s.Desc.AnonymousPCIDevs); i++ {
 		if s.isMachineDefaultAddress(s.Desc.AnonymousPCIDevs[i].PCIAddr) {
@@ -910,6 +954,16 @@ func (s *SKVMGuestInstance) initGuestDescFromExistingGuest(
 			if err != nil {
 				return errors.Wrap(err, "ensure random device pci address")
 			}
+		case "balloon0":
+			if s.Desc.Balloon == nil {
+				// in case balloon device disable by host options
+				s.initBalloonDevice(pciRoot, true)
+			}
+			s.Desc.Balloon.PCIAddr = pciAddr
+			err = s.ensureDevicePciAddress(s.Desc.Balloon.PCIDevice, -1, nil)
+			if err != nil {
+				return errors.Wrap(err, "ensure balloon device pci address")
+			}
 		case "usb":
 			if s.Desc.Usb == nil {
 				s.initUsbController(pciRoot)
@@ -1032,6 +1086,26 @@ func (s *SKVMGuestInstance) initGuestDescFromExistingGuest(
 						}
 					}
 				}
+			case strings.HasPrefix(pciInfoList[0].Devices[i].QdevID, "vfs"):
+				index, err := strconv.Atoi(strings.TrimPrefix(pciInfoList[0].Devices[i].QdevID, "vfs"))
+				if err != nil {
+					log.Errorf("failed parse virtiofs device index %s", pciInfoList[0].Devices[i].QdevID)
+					unknownDevices = append(unknownDevices, pciInfoList[0].Devices[i])
+					continue
+				}
+				if index >= len(s.Desc.Virtiofs) {
+					log.Errorf("virtiofs device %s not found in guest desc", pciInfoList[0].Devices[i].QdevID)
+					unknownDevices = append(unknownDevices, pciInfoList[0].Devices[i])
+					continue
+				}
+				if s.Desc.Virtiofs[index].PCIDevice == nil {
+					s.initVirtiofsDevice(pciRoot, index)
+				}
+				s.Desc.Virtiofs[index].PCIAddr = pciAddr
+				err = s.ensureDevicePciAddress(s.Desc.Virtiofs[index].PCIDevice, -1, nil)
+				if err != nil {
+					return errors.Wrapf(err, "ensure virtiofs %s pci address", s.Desc.Virtiofs[index].Tag)
+				}
 			case strings.HasPrefix(pciInfoList[0].Devices[i].QdevID, "netdev-"):
 				ifname := strings.TrimPrefix(pciInfoList[0].Devices[i].QdevID, "netdev-")
 				for i := 0; i < len(s.Desc.Nics); i++ {
@@ -24,6 +24,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -33,6 +34,7 @@ import (
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
+	"yunion.io/x/pkg/appctx"
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/util/sets"
 	"yunion.io/x/pkg/utils"
@@ -72,6 +74,11 @@ import (
 	"yunion.io/x/onecloud/pkg/util/procutils"
 )
 
+const (
+	// INIT_CONTAINER_WAIT_TIMEOUT is the max duration to wait an init container run to completion
+	INIT_CONTAINER_WAIT_TIMEOUT = 30 * time.Minute
+)
+
 func (m *SGuestManager) startContainerProbeManager() {
 	livenessManager := proberesults.NewManager()
 	startupManager := proberesults.NewManager()
@@ -118,6 +125,7 @@ type PodInstance interface {
 	CreateContainer(ctx context.Context, userCred mcclient.TokenCredential, id string, input *hostapi.ContainerCreateInput) (jsonutils.JSONObject, error)
 	StartContainer(ctx context.Context, userCred mcclient.TokenCredential, ctrId string, input *hostapi.ContainerCreateInput) (jsonutils.JSONObject, error)
 	StartLocalContainer(ctx context.Context, userCred mcclient.TokenCredential, ctrId string) (jsonutils.JSONObject, error)
+	WaitInitContainerExited(ctx context.Context, userCred mcclient.TokenCredential, ctrId string) (jsonutils.JSONObject, error)
 	DeleteContainer(ctx context.Context, cred mcclient.TokenCredential, id string) (jsonutils.JSONObject, error)
 	SyncStatus(reason string)
 	SyncContainerStatus(ctx context.Context, cred mcclient.TokenCredential, ctrId string) (jsonutils.JSONObject, error)
@@ -130,6 +138,7 @@ type PodInstance interface {
 	ExecContainer(ctx context.Context, userCred mcclient.TokenCredential, ctrId string, input *computeapi.ContainerExecInput) (*url.URL, error)
 	ContainerExecSync(ctx context.Context, userCred mcclient.TokenCredential, ctrId string, input *computeapi.ContainerExecSyncInput) (jsonutils.JSONObject, error)
 	SetContainerResourceLimit(ctrId string, limit *apis.ContainerResources) (jsonutils.JSONObject, error)
+	RefreshContainerVolumeMounts(ctx context.Context, userCred mcclient.TokenCredential, ctrId string, input *hostapi.ContainerRefreshVolumeMountsInput) (jsonutils.JSONObject, error)
 	CommitContainer(ctx context.Context, userCred mcclient.TokenCredential, ctrId string, input *hostapi.ContainerCommitInput) (jsonutils.JSONObject, error)
 	AddContainerVolumeMountPostOverlay(ctx context.Context, userCred mcclient.TokenCredential, ctrId string, input *computeapi.ContainerVolumeMountAddPostOverlayInput) error
 	RemoveContainerVolumeMountPostOverlay(ctx context.Context, userCred mcclient.TokenCredential, ctrId string, input *computeapi.ContainerVolumeMountRemovePostOverlayInput) error
@@ -410,6 +419,10 @@ func (s *sPodGuestInstance) GetUploadStatus(ctx context.Context, reason string)
 			}
 		}
 		cStatuss[c.Id] = ctrStatusInput
+		if s.IsInitContainer(c.Id) && cStatus == computeapi.CONTAINER_STATUS_EXITED {
+			// init container exited successfully doesn't affect pod status
+			continue
+		}
 		status = GetPodStatusByContainerStatus(status, cStatus, s.IsPrimaryContainer(c.Id))
 	}
 	if len(errs) > 0 {
@@ -837,9 +850,11 @@ func (s *sPodGuestInstance) GetDiskMountPointById(diskId string) string {
 }
 
 func (s *sPodGuestInstance) getPodPrivilegedMode(input *computeapi.PodCreateInput) bool {
-	for _, ctr := range input.Containers {
-		if ctr.Privileged {
-			return true
+	for _, ctrs := range [][]*computeapi.PodContainerCreateInput{input.InitContainers, input.Containers} {
+		for _, ctr := range ctrs {
+			if ctr.Privileged {
+				return true
+			}
 		}
 	}
 	return false
@@ -888,7 +903,7 @@ func (t *localDirtyPodStartTask) Run() {
 			log.Errorf("start dirty pod(%s/%s) err: %s", t.pod.GetId(), t.pod.GetName(), err.Error())
 		}
 	}*/
-	for _, ctr := range t.pod.GetContainers() {
+	for _, ctr := range t.pod.getOrderedContainers() {
 		if t.pod.isContainerDirtyShutdown(ctr.Id) {
 			if !t.pod.IsRunning() {
 				log.Infof("start dirty pod locally (%s/%s)", t.pod.Id, t.pod.GetName())
@@ -915,7 +930,7 @@ func (s *sPodGuestInstance) StartLocalDirtyPod(ctx context.Context, userCred mcc
 }
 
 func (s *sPodGuestInstance) ShouldRestartPodOnCrash() bool {
-	if len(s.GetContainers()) <= 1 {
+	if s.getAppContainerCount() <= 1 {
 		return true
 	}
 	return false
@@ -926,12 +941,51 @@ func (s *sPodGuestInstance) IsPrimaryContainer(ctrId string) bool {
 	if ctr == nil {
 		return false
 	}
-	if len(s.containers) == 1 {
+	if ctr.Spec.Init {
+		return false
+	}
+	if s.getAppContainerCount() == 1 {
 		return true
 	}
 	return ctr.Spec.Primary
 }
 
+func (s *sPodGuestInstance) IsInitContainer(ctrId string) bool {
+	ctr := s.GetContainerById(ctrId)
+	if ctr == nil {
+		return false
+	}
+	return ctr.Spec.Init
+}
+
+func (s *sPodGuestInstance) getAppContainerCount() int {
+	cnt := 0
+	for _, ctr := range s.GetContainers() {
+		if !ctr.Spec.Init {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// getOrderedContainers returns the init containers sorted by their starting order
+// followed by the application containers.
+func (s *sPodGuestInstance) getOrderedContainers() []*hostapi.ContainerDesc {
+	initCtrs := make([]*hostapi.ContainerDesc, 0)
+	appCtrs := make([]*hostapi.ContainerDesc, 0)
+	for _, ctr := range s.GetContainers() {
+		if ctr.Spec.Init {
+			initCtrs = append(initCtrs, ctr)
+		} else {
+			appCtrs = append(appCtrs, ctr)
+		}
+	}
+	sort.SliceStable(initCtrs, func(i, j int) bool {
+		return initCtrs[i].Spec.InitOrder < initCtrs[j].Spec.InitOrder
+	})
+	return append(initCtrs, appCtrs...)
+}
+
 func (s *sPodGuestInstance) startPod(ctx context.Context, userCred mcclient.TokenCredential) (*computeapi.PodStartResponse, error) {
 	s.startPodLock.Lock()
 	defer s.startPodLock.Unlock()
@@ -1316,8 +1370,11 @@ func (s *sPodGuestInstance) StartContainer(ctx context.Context, userCred mcclien
 	if err := s.doContainerStartPostLifecycle(ctx, criId, input); err != nil {
 		return nil, errors.Wrap(err, "do container lifecycle")
 	}
-	if err := s.startStat.CreateContainerFile(ctrId); err != nil {
-		return nil, errors.Wrapf(err, "create container startup stat file %s", ctrId)
+	if !input.Spec.Init {
+		// completed init container shouldn't be recovered as dirty shutdown
+		if err := s.startStat.CreateContainerFile(ctrId); err != nil {
+			return nil, errors.Wrapf(err, "create container startup stat file %s", ctrId)
+		}
 	}
 	if input.Spec.ResourcesLimit != nil {
 		if err := s.setContainerResourcesLimit(criId, input.Spec.ResourcesLimit); err != nil {
@@ -1417,12 +1474,103 @@ func (s *sPodGuestInstance) doContainerStartPostLifecycle(ctx context.Context, c
 		return nil
 	}
 	drv := lifecycle.GetDriver(ls.PostStart.Type)
-	if err := drv.Run(ctx, ls.PostStart, s.getCRI(), criId); err != nil {
+	if err := drv.Run(ctx, ls.PostStart, s.getCRI(), criId, s.getPodIp()); err != nil {
 		return errors.Wrapf(err, "run %s", ls.PostStart.Type)
 	}
 	return nil
 }
 
+// minimum seconds left to stop the container after pre stop handler used up
+// the grace period, the same as kubelet
+const minimumGracePeriodSeconds = 2
+
+// doContainerPreStopLifecycle runs the pre stop handler within gracePeriod
+// seconds and returns the grace period left for stopping the container
+func (s *sPodGuestInstance) doContainerPreStopLifecycle(ctx context.Context, criId string, ctr *hostapi.ContainerDesc, gracePeriod int64) int64 {
+	if ctr == nil || ctr.Spec == nil || ctr.Spec.Lifecyle == nil || ctr.Spec.Lifecyle.PreStop == nil {
+		return gracePeriod
+	}
+	ls := ctr.Spec.Lifecyle
+	if isRunning, err := s.IsContainerRunning(ctx, ctr.Id); err != nil || !isRunning {
+		return gracePeriod
+	}
+	start := time.Now()
+	preStopCtx, cancel := context.WithTimeout(ctx, time.Duration(gracePeriod)*time.Second)
+	defer cancel()
+	drv := lifecycle.GetDriver(ls.PreStop.Type)
+	// the container is always stopped even the pre stop handler failed
+	if err := drv.Run(preStopCtx, ls.PreStop, s.getCRI(), criId, s.getPodIp()); err != nil {
+		log.Warningf("run pre stop %s of container %s(%s): %v", ls.PreStop.Type, ctr.Name, ctr.Id, err)
+	}
+	return preStopRemainingGracePeriod(gracePeriod, time.Since(start))
+}
+
+func preStopRemainingGracePeriod(gracePeriod int64, used time.Duration) int64 {
+	left := gracePeriod - int64(used.Seconds())
+	if left < minimumGracePeriodSeconds {
+		left = minimumGracePeriodSeconds
+	}
+	return left
+}
+
+// WaitInitContainerExited returns immediately and reports the exit of the init container
+// to the task of the request asynchronously, so no worker is held while it's running.
+func (s *sPodGuestInstance) WaitInitContainerExited(ctx context.Context, userCred mcclient.TokenCredential, ctrId string) (jsonutils.JSONObject, error) {
+	ctr := s.GetContainerById(ctrId)
+	if ctr == nil {
+		return nil, errors.Wrapf(errors.ErrNotFound, "container %s", ctrId)
+	}
+	criId, err := s.getContainerCRIId(ctrId)
+	if err != nil {
+		return nil, errors.Wrap(err, "get container cri id")
+	}
+	taskCtx := context.WithValue(context.Background(), appctx.APP_CONTEXT_KEY_TASK_ID, ctx.Value(appctx.APP_CONTEXT_KEY_TASK_ID))
+	go func() {
+		if err := s.waitInitContainerExited(taskCtx, criId, ctr.Name); err != nil {
+			hostutils.TaskFailed(taskCtx, err.Error())
+			return
+		}
+		hostutils.TaskComplete(taskCtx, nil)
+	}()
+	return nil, nil
+}
+
+// waitInitContainerExited waits the init container to run to completion,
+// an error is returned if the container exits with non-zero code.
+func (s *sPodGuestInstance) waitInitContainerExited(ctx context.Context, criId string, ctrName string) error {
+	ctx, cancel := context.WithTimeout(ctx, INIT_CONTAINER_WAIT_TIMEOUT)
+	defer cancel()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		resp, err := s.getCRI().ContainerStatus(ctx, criId)
+		if err != nil {
+			return errors.Wrapf(err, "get init container %s status", ctrName)
+		}
+		if resp.Status.State == runtimeapi.ContainerState_CONTAINER_EXITED {
+			if resp.Status.ExitCode != 0 {
+				return errors.Errorf("init container %s exited with code %d: %s", ctrName, resp.Status.ExitCode, resp.Status.Message)
+			}
+			log.Infof("init container %s of pod %s completed", ctrName, s.GetName())
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "wait init container %s exited", ctrName)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *sPodGuestInstance) getPodIp() string {
+	for _, nic := range s.GetDesc().Nics {
+		if nic.Ip != "" {
+			return nic.Ip
+		}
+	}
+	return ""
+}
+
 func (s *sPodGuestInstance) StopAll(ctx context.Context) error {
 	ctrs := s.GetContainers()
 	userCred := hostutils.GetComputeSession(ctx).GetToken()
@@ -1455,8 +1603,14 @@ func (s *sPodGuestInstance) StopContainer(ctx context.Context, userCred mcclient
 
 	s.expectedStatus.SetContainerStatus(criId, ctrId, computeapi.CONTAINER_STATUS_EXITED)
 
+	ctr := s.GetContainerById(ctrId)
 	if input.Timeout != 0 {
 		timeout = input.Timeout
+	} else if ctr != nil && ctr.Spec != nil && ctr.Spec.Lifecyle != nil {
+		timeout = ctr.Spec.Lifecyle.GetTerminationGracePeriodSeconds()
+	}
+	if !input.Force {
+		timeout = s.doContainerPreStopLifecycle(ctx, criId, ctr, timeout)
 	}
 	shmSizeMB := input.ShmSizeMB
 
@@ -1710,6 +1864,32 @@ func (s *sPodGuestInstance) SetContainerResourceLimit(ctrId string, limit *apis.
 	return nil, s.setContainerResourcesLimit(criId, limit)
 }
 
+// RefreshContainerVolumeMounts rewrites the files of the secret or config volume mounts of a running container
+func (s *sPodGuestInstance) RefreshContainerVolumeMounts(ctx context.Context, userCred mcclient.TokenCredential, ctrId string, input *hostapi.ContainerRefreshVolumeMountsInput) (jsonutils.JSONObject, error) {
+	ctr := s.GetContainerById(ctrId)
+	if ctr == nil {
+		return nil, errors.Wrapf(errors.ErrNotFound, "container %s", ctrId)
+	}
+	for _, vm := range input.VolumeMounts {
+		drv, ok := volume_mount.GetDriver(vm.Type).(volume_mount.IRefreshableVolumeMount)
+		if !ok {
+			return nil, errors.Wrapf(errors.ErrNotSupported, "refresh volume mount type %s", vm.Type)
+		}
+		if err := drv.Refresh(s, ctrId, vm); err != nil {
+			return nil, errors.Wrapf(err, "refresh volume mount %s", vm.MountPath)
+		}
+		for i := range ctr.Spec.VolumeMounts {
+			if ctr.Spec.VolumeMounts[i].MountPath == vm.MountPath {
+				ctr.Spec.VolumeMounts[i] = vm.WithoutSecretData()
+			}
+		}
+	}
+	if err := s.SaveContainerDesc(ctr); err != nil {
+		return nil, errors.Wrap(err, "SaveContainerDesc")
+	}
+	return nil, nil
+}
+
 func (s *sPodGuestInstance) setContainerResourcesLimit(ctrId string, limit *apis.ContainerResources) error {
 	cgUtil := s.getCGUtil()
 	/*if limit.MemoryLimitMB != nil {
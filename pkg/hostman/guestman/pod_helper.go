@@ -219,10 +219,14 @@ func (t *localPodRestartTask) Run() {
 		log.Errorf("start pod(%s/%s) err: %s", t.pod.GetId(), t.pod.GetName(), err.Error())
 		return
 	}
-	for _, ctr := range t.pod.GetContainers() {
+	for _, ctr := range t.pod.getOrderedContainers() {
 		log.Infof("start container locally (%s/%s/%s/%s)", t.pod.Id, t.pod.GetName(), ctr.Id, ctr.Name)
 		if _, err := t.pod.StartLocalContainer(t.ctx, t.userCred, ctr.Id); err != nil {
 			log.Errorf("start container %s err: %s", ctr.Id, err.Error())
+			if ctr.Spec.Init {
+				// application containers can't be started when init container failed
+				break
+			}
 		}
 	}
 	t.pod.SyncStatus("sync status after pod and containers restart locally")
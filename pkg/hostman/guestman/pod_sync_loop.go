@@ -78,6 +78,10 @@ func (m *SGuestManager) reconcileContainer(obj *sPodGuestInstance, cache runtime
 			// container is deleted
 			continue
 		}
+		if ctr.Spec.Init {
+			// init container is only started along with the pod
+			continue
+		}
 		if cs.State == runtime.ContainerStateExited && cs.ExitCode != 0 {
 			if err := m.startContainer(obj, ctr, cs); err != nil {
 				errs = append(errs, errors.Wrapf(err, "start container %s", ctr.Name))
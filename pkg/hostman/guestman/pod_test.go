@@ -0,0 +1,39 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPreStopRemainingGracePeriod(t *testing.T) {
+	cases := []struct {
+		name  string
+		grace int64
+		used  time.Duration
+		want  int64
+	}{
+		{"pre stop returns at once", 30, 0, 30},
+		{"pre stop used part of grace", 30, 10 * time.Second, 20},
+		{"pre stop used up grace", 30, 30 * time.Second, minimumGracePeriodSeconds},
+		{"pre stop timed out", 30, 45 * time.Second, minimumGracePeriodSeconds},
+	}
+	for _, c := range cases {
+		if got := preStopRemainingGracePeriod(c.grace, c.used); got != c.want {
+			t.Errorf("%s: want %d, got %d", c.name, c.want, got)
+		}
+	}
+}
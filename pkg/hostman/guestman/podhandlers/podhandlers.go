@@ -154,6 +154,8 @@ func AddPodHandlers(prefix string, app *appsrv.Application) {
 
 	syncWorker := appsrv.NewWorkerManager("container-sync-action-worker", 16, appsrv.DEFAULT_BACKLOG, false)
 	app.AddHandler3(newContainerWorkerHandler("POST", fmt.Sprintf("%s/pods/%s/containers/%s/set-resources-limit", prefix, POD_ID, CONTAINER_ID), syncWorker, containerSyncActionHandler(containerSetResourcesLimit)))
+	app.AddHandler3(newContainerWorkerHandler("POST", fmt.Sprintf("%s/pods/%s/containers/%s/refresh-volume-mounts", prefix, POD_ID, CONTAINER_ID), syncWorker, containerSyncActionHandler(containerRefreshVolumeMounts)))
+	app.AddHandler3(newContainerWorkerHandler("POST", fmt.Sprintf("%s/pods/%s/containers/%s/wait-init-exited", prefix, POD_ID, CONTAINER_ID), syncWorker, containerSyncActionHandler(waitInitContainerExited)))
 }
 
 func pullImage(ctx context.Context, userCred mcclient.TokenCredential, pod guestman.PodInstance, ctrId string, body jsonutils.JSONObject) (jsonutils.JSONObject, error) {
@@ -180,6 +182,10 @@ func startContainer(ctx context.Context, userCred mcclient.TokenCredential, pod
 	return pod.StartContainer(ctx, userCred, containerId, input)
 }
 
+func waitInitContainerExited(ctx context.Context, userCred mcclient.TokenCredential, pod guestman.PodInstance, ctrId string, body jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	return pod.WaitInitContainerExited(ctx, userCred, ctrId)
+}
+
 func stopContainer(ctx context.Context, userCred mcclient.TokenCredential, pod guestman.PodInstance, ctrId string, body jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	input := new(hostapi.ContainerStopInput)
 	if err := body.Unmarshal(input); err != nil {
@@ -318,6 +324,14 @@ func containerSetResourcesLimit(ctx context.Context, userCred mcclient.TokenCred
 	return pod.SetContainerResourceLimit(containerId, input)
 }
 
+func containerRefreshVolumeMounts(ctx context.Context, userCred mcclient.TokenCredential, pod guestman.PodInstance, containerId string, body jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	input := new(hostapi.ContainerRefreshVolumeMountsInput)
+	if err := body.Unmarshal(input); err != nil {
+		return nil, errors.Wrap(err, "unmarshal to ContainerRefreshVolumeMountsInput")
+	}
+	return pod.RefreshContainerVolumeMounts(ctx, userCred, containerId, input)
+}
+
 func commitContainer(ctx context.Context, userCred mcclient.TokenCredential, pod guestman.PodInstance, ctrId string, body jsonutils.JSONObject) (jsonutils.JSONObject, error) {
 	input := new(hostapi.ContainerCommitInput)
 	if err := body.Unmarshal(input); err != nil {
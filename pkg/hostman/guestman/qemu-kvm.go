@@ -104,6 +104,10 @@ type SKVMInstanceRuntime struct {
 
 	pciUninitialized bool
 	pciAddrs         *desc.SGuestPCIAddresses
+
+	// memory size of guest after ballooning, 0 means unknown
+	balloonActualMb     int64
+	balloonStatsPolling bool
 }
 
 type SKVMGuestInstance struct {
@@ -777,6 +781,9 @@ func (s *SKVMGuestInstance) asyncScriptStart(ctx context.Context, params interfa
 			}
 		}
 
+		if err = s.mountVirtiofsSources(); err != nil {
+			goto finally
+		}
 		err = s.saveScripts(data)
 		if err != nil {
 			goto finally
@@ -1230,6 +1237,7 @@ func (s *SKVMGuestInstance) SyncMirrorJobFailed(reason string) {
 
 func (s *SKVMGuestInstance) onMonitorConnected(ctx context.Context) {
 	log.Infof("Monitor connected ...")
+	s.balloonStatsPolling = false
 	s.Monitor.GetVersion(func(v string) {
 		s.onGetQemuVersion(ctx, v)
 	})
@@ -34,6 +34,7 @@ import (
 
 	"yunion.io/x/onecloud/pkg/apis"
 	api "yunion.io/x/onecloud/pkg/apis/compute"
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 	"yunion.io/x/onecloud/pkg/hostman/guestman/qemu"
 	qemucerts "yunion.io/x/onecloud/pkg/hostman/guestman/qemu/certs"
@@ -46,6 +47,7 @@ import (
 	"yunion.io/x/onecloud/pkg/util/fileutils2"
 	"yunion.io/x/onecloud/pkg/util/procutils"
 	"yunion.io/x/onecloud/pkg/util/qemutils"
+	"yunion.io/x/onecloud/pkg/util/sysutils"
 )
 
 const (
@@ -237,6 +239,129 @@ func (s *SKVMGuestInstance) isMemcleanEnabled() bool {
 	return s.Desc.Metadata[api.VM_METADATA_ENABLE_MEMCLEAN] == "true"
 }
 
+// vhost-user devices like virtiofs require guest memory shared with the backend daemon
+func (s *SKVMGuestInstance) isSharedMemoryRequired() bool {
+	return s.isMemcleanEnabled() || len(s.Desc.Virtiofs) > 0
+}
+
+func (s *SKVMGuestInstance) isMemoryBalloonEnabled() bool {
+	if s.isHugepagesBacked() {
+		// hugepages can't be reclaimed by balloon
+		return false
+	}
+	return options.HostOptions.EnableMemoryBalloon || s.Desc.Metadata[api.VM_METADATA_ENABLE_MEMORY_BALLOON] == "true"
+}
+
+func (s *SKVMGuestInstance) getNumaNodes() int {
+	nodes, _ := strconv.Atoi(s.Desc.Metadata[api.VM_METADATA_NUMA_NODES])
+	return nodes
+}
+
+func (s *SKVMGuestInstance) isMemoryStrictBind() bool {
+	return s.Desc.Metadata[api.VM_METADATA_MEMORY_STRICT_BIND] == "true"
+}
+
+// isHugepagesBacked means guest memory is backed by the hugetlbfs mounted for the guest,
+// required either by the hugepages option of host or by the memory backing of guest
+func (s *SKVMGuestInstance) isHugepagesBacked() bool {
+	return s.manager.host.IsHugepagesEnabled() || len(s.Desc.Metadata[api.VM_METADATA_MEMORY_BACKING]) > 0
+}
+
+// hugepageSizeKb returns the page size of the hugetlbfs mounted for the guest
+func (s *SKVMGuestInstance) hugepageSizeKb() int {
+	if pageSizeKB := api.GuestMemoryBackingPageSizeKB(s.Desc.Metadata[api.VM_METADATA_MEMORY_BACKING]); pageSizeKB > 0 {
+		return pageSizeKB
+	}
+	return s.manager.host.HugepageSizeKb()
+}
+
+func (s *SKVMGuestInstance) checkMemoryBacking() error {
+	if s.isMemoryStrictBind() {
+		if len(s.Desc.CpuNumaPin) == 0 {
+			return errors.Errorf("memory strict bind requires guest memory pinned to host numa nodes")
+		}
+		for i := range s.Desc.CpuNumaPin {
+			if s.Desc.CpuNumaPin[i].NodeId == nil {
+				return errors.Errorf("memory strict bind requires guest memory pinned to host numa nodes, cpu numa pin %d has no node", i)
+			}
+		}
+	}
+	backing := s.Desc.Metadata[api.VM_METADATA_MEMORY_BACKING]
+	if len(backing) == 0 {
+		return nil
+	}
+	pageSizeKB := api.GuestMemoryBackingPageSizeKB(backing)
+	if pageSizeKB == 0 {
+		return errors.Errorf("unknown memory backing %s", backing)
+	}
+	if !s.manager.host.IsHugepagesEnabled() || s.manager.host.HugepageSizeKb() != pageSizeKB {
+		hp, err := sysutils.GetHugepages()
+		if err != nil {
+			return errors.Wrap(err, "GetHugepages")
+		}
+		if !utils.IsInArray(pageSizeKB, hp.PageSizes()) {
+			return errors.Errorf("memory backing %s requires %dKB hugepages reserved on host", backing, pageSizeKB)
+		}
+	}
+	return s.checkFreeHugepages(backing, pageSizeKB)
+}
+
+// checkFreeHugepages makes sure the free hugepages of each host numa node
+// can back the guest memory placed on it, the memory pinned to host numa
+// nodes must fit on them, otherwise the guest numa nodes must fit on
+// distinct host numa nodes
+func (s *SKVMGuestInstance) checkFreeHugepages(backing string, pageSizeKB int) error {
+	topo := s.manager.host.GetHostTopology()
+	if topo == nil || topo.Info == nil {
+		return errors.Errorf("host numa topology unknown, can't check free hugepages of memory backing %s", backing)
+	}
+	free := &schedapi.SFreeHugepages{NodeFreeMB: map[int]int{}}
+	for _, node := range topo.Nodes {
+		hp, err := sysutils.GetNodeHugepages(node.ID)
+		if err != nil {
+			return errors.Wrapf(err, "GetNodeHugepages of node %d", node.ID)
+		}
+		for i := range hp {
+			if hp[i].SizeKb == pageSizeKB {
+				free.NodeFreeMB[node.ID] = int(int64(hp[i].Free) * int64(pageSizeKB) / 1024)
+			}
+		}
+	}
+
+	pinned := map[int]int{}
+	for i := range s.Desc.CpuNumaPin {
+		if s.Desc.CpuNumaPin[i].NodeId != nil && s.Desc.CpuNumaPin[i].SizeMB > 0 {
+			pinned[int(*s.Desc.CpuNumaPin[i].NodeId)] += int(s.Desc.CpuNumaPin[i].SizeMB)
+		}
+	}
+	if len(pinned) > 0 {
+		for nodeId, memMB := range pinned {
+			if free.NodeFreeMB[nodeId] < memMB {
+				return errors.Errorf("memory backing %s requires %dMB of %dKB hugepages on host numa node %d, only %dMB free",
+					backing, memMB, pageSizeKB, nodeId, free.NodeFreeMB[nodeId])
+			}
+		}
+		return nil
+	}
+	if nodes := s.getNumaNodes(); nodes > 1 {
+		nodeMemMB := int(s.Desc.Mem) / nodes
+		if !free.NodesFit(nodes, nodeMemMB) {
+			return errors.Errorf("memory backing %s requires %d host numa nodes with %dMB of %dKB hugepages free each, free %v",
+				backing, nodes, nodeMemMB, pageSizeKB, free.NodeFreeMB)
+		}
+		return nil
+	}
+	totalFree := 0
+	for _, nodeFree := range free.NodeFreeMB {
+		totalFree += nodeFree
+	}
+	if totalFree < int(s.Desc.Mem) {
+		return errors.Errorf("memory backing %s requires %dMB of %dKB hugepages, only %dMB free on host numa nodes %v",
+			backing, s.Desc.Mem, pageSizeKB, totalFree, free.NodeFreeMB)
+	}
+	return nil
+}
+
 func (s *SKVMGuestInstance) isDisableAutoMergeSnapshots() bool {
 	return s.Desc.Metadata[api.VM_METADATA_DISABLE_AUTO_MERGE_SNAPSHOT] == "true"
 }
@@ -351,14 +476,18 @@ func (s *SKVMGuestInstance) extraOptions() string {
 }
 
 func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (string, error) {
+	if err := s.checkMemoryBacking(); err != nil {
+		return "", err
+	}
+
 	// initial data
 	var input = &qemu.GenerateStartOptionsInput{
 		GuestDesc:            s.Desc,
 		OsName:               s.GetOsName(),
 		OVNIntegrationBridge: options.HostOptions.OvnIntegrationBridge,
 		HomeDir:              s.HomeDir(),
-		HugepagesEnabled:     s.manager.host.IsHugepagesEnabled(),
-		EnableMemfd:          s.isMemcleanEnabled(),
+		HugepagesEnabled:     s.isHugepagesBacked(),
+		EnableMemfd:          s.isSharedMemoryRequired(),
 		PidFilePath:          s.GetPidFilePath(),
 	}
 
@@ -408,7 +537,7 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 	if input.HugepagesEnabled {
 		cmd += fmt.Sprintf("mkdir -p /dev/hugepages/%s\n", s.Desc.Uuid)
 		cmd += fmt.Sprintf("mount -t hugetlbfs -o pagesize=%dK,size=%dM hugetlbfs-%s /dev/hugepages/%s\n",
-			s.manager.host.HugepageSizeKb(), s.Desc.Mem, s.Desc.Uuid, s.Desc.Uuid)
+			s.hugepageSizeKb(), s.Desc.Mem, s.Desc.Uuid, s.Desc.Uuid)
 	}
 
 	cmd += "sleep 1\n"
@@ -426,6 +555,12 @@ func (s *SKVMGuestInstance) generateStartScript(data *jsonutils.JSONDict) (strin
 	}
 	cmd += sriovInitScripts
 
+	virtiofsScripts, err := s.generateVirtiofsStartScripts()
+	if err != nil {
+		return "", errors.Wrap(err, "generateVirtiofsStartScripts")
+	}
+	cmd += virtiofsScripts
+
 	// cmd += fmt.Sprintf("STATE_FILE=`ls -d %s* | head -n 1`\n", s.getStateFilePathRootPrefix())
 	cmd += fmt.Sprintf("PID_FILE=%s\n", input.PidFilePath)
 
@@ -707,6 +842,8 @@ func (s *SKVMGuestInstance) generateStopScript(data *jsonutils.JSONDict) string
 	cmd += "  rm -f $PID_FILE\n"
 	cmd += "fi\n"
 
+	cmd += s.generateVirtiofsStopScripts()
+
 	cmd += fmt.Sprintf("for d in $(ls -d /dev/hugepages/%s*)\n", uuid)
 	cmd += "do\n"
 	cmd += "  if [ -d $d ]; then\n"
@@ -912,9 +1049,9 @@ func (s *SKVMGuestInstance) initMemDesc(memSizeMB int64) error {
 }
 
 func (s *SKVMGuestInstance) memObjectType() string {
-	if s.manager.host.IsHugepagesEnabled() {
+	if s.isHugepagesBacked() {
 		return "memory-backend-file"
-	} else if s.isMemcleanEnabled() {
+	} else if s.isSharedMemoryRequired() {
 		return "memory-backend-memfd"
 	} else {
 		return "memory-backend-ram"
@@ -922,8 +1059,13 @@ func (s *SKVMGuestInstance) memObjectType() string {
 }
 
 func (s *SKVMGuestInstance) initGuestMemObjects(memSizeMB int64) error {
+	s.Desc.MemDesc.NumaDistances = nil
 	if len(s.Desc.CpuNumaPin) == 0 {
-		s.initDefaultMemObject(memSizeMB)
+		if nodes := s.getNumaNodes(); nodes > 1 {
+			s.initVirtualNumaMemObjects(memSizeMB, nodes)
+		} else {
+			s.initDefaultMemObject(memSizeMB)
+		}
 		return nil
 	}
 
@@ -972,12 +1114,93 @@ func (s *SKVMGuestInstance) initGuestMemObjects(memSizeMB int64) error {
 	}
 
 	s.Desc.MemDesc.Mem = desc.NewMemsDesc(mems[0], mems[1:])
+	s.Desc.MemDesc.NumaDistances = s.getGuestNumaDistances()
 	return nil
 }
 
+// initVirtualNumaMemObjects splits guest memory and vcpus evenly into
+// nodes virtual numa nodes which are not bound to host numa nodes
+func (s *SKVMGuestInstance) initVirtualNumaMemObjects(memSizeMB int64, nodes int) {
+	var nodeMem = memSizeMB / int64(nodes)
+	var leastMem = memSizeMB % int64(nodes)
+	var numaCpus = int(s.Desc.CpuDesc.MaxCpus) / nodes
+	var leastCpus = int(s.Desc.CpuDesc.MaxCpus) % nodes
+	var cpuStart = 0
+
+	var mems = make([]desc.SMemDesc, 0, nodes)
+	for i := 0; i < nodes; i++ {
+		memId := "mem"
+		nodeId := uint16(i)
+		if i > 0 {
+			memId += strconv.Itoa(i - 1)
+		}
+		cpuEnd := cpuStart + numaCpus - 1
+		memSize := nodeMem
+		if i == 0 {
+			cpuEnd += leastCpus
+			memSize += leastMem
+		}
+		vcpus := fmt.Sprintf("%d-%d", cpuStart, cpuEnd)
+		cpuStart = cpuEnd + 1
+
+		memDesc := desc.NewMemDesc(s.memObjectType(), memId, &nodeId, &vcpus)
+		memDesc.Options = s.getMemObjectOptions(memSize, s.Desc.Uuid, nil)
+		mems = append(mems, *memDesc)
+	}
+	s.Desc.MemDesc.Mem = desc.NewMemsDesc(mems[0], mems[1:])
+}
+
+// getGuestNumaDistances maps host numa distances of pinned nodes to guest numa nodes
+func (s *SKVMGuestInstance) getGuestNumaDistances() []desc.SNumaDistance {
+	topo := s.manager.host.GetHostTopology()
+	if topo == nil || topo.Info == nil {
+		return nil
+	}
+	hostDistances := map[uint16][]int{}
+	for _, node := range topo.Nodes {
+		hostDistances[uint16(node.ID)] = node.Distances
+	}
+
+	hostNodes := make([]uint16, 0)
+	for i := range s.Desc.CpuNumaPin {
+		if s.Desc.CpuNumaPin[i].SizeMB <= 0 || s.Desc.CpuNumaPin[i].NodeId == nil {
+			continue
+		}
+		if s.Desc.CpuNumaPin[i].Unregular {
+			continue
+		}
+		hostNodes = append(hostNodes, *s.Desc.CpuNumaPin[i].NodeId)
+	}
+	if len(hostNodes) < 2 {
+		return nil
+	}
+
+	res := make([]desc.SNumaDistance, 0)
+	for i := range hostNodes {
+		distances, ok := hostDistances[hostNodes[i]]
+		if !ok {
+			return nil
+		}
+		for j := range hostNodes {
+			if i == j {
+				continue
+			}
+			if int(hostNodes[j]) >= len(distances) {
+				return nil
+			}
+			res = append(res, desc.SNumaDistance{
+				Src: uint16(i),
+				Dst: uint16(j),
+				Val: distances[hostNodes[j]],
+			})
+		}
+	}
+	return res
+}
+
 func (s *SKVMGuestInstance) getMemObjectOptions(memSizeMB int64, memPathSuffix string, hostNodes *uint16) map[string]string {
 	var opts map[string]string
-	if s.manager.host.IsHugepagesEnabled() {
+	if s.isHugepagesBacked() {
 		opts = map[string]string{
 			"mem-path": fmt.Sprintf("/dev/hugepages/%s", memPathSuffix),
 			"size":     fmt.Sprintf("%dM", memSizeMB),
@@ -987,7 +1210,7 @@ func (s *SKVMGuestInstance) getMemObjectOptions(memSizeMB int64, memPathSuffix s
 			opts["host-nodes"] = fmt.Sprintf("%d", *hostNodes)
 			opts["policy"] = "bind"
 		}
-	} else if s.isMemcleanEnabled() {
+	} else if s.isSharedMemoryRequired() {
 		opts = map[string]string{
 			"size":  fmt.Sprintf("%dM", memSizeMB),
 			"share": "on", "prealloc": "on",
@@ -997,6 +1220,10 @@ func (s *SKVMGuestInstance) getMemObjectOptions(memSizeMB int64, memPathSuffix s
 			"size": fmt.Sprintf("%dM", memSizeMB),
 		}
 	}
+	if hostNodes != nil && s.isMemoryStrictBind() {
+		opts["host-nodes"] = fmt.Sprintf("%d", *hostNodes)
+		opts["policy"] = "bind"
+	}
 	return opts
 }
 
@@ -1089,6 +1316,13 @@ func (s *SKVMGuestInstance) initPvpanicDesc() {
 func (s *SKVMGuestInstance) initIsaSerialDesc() {
 	if !s.disableIsaSerialDev() {
 		s.Desc.IsaSerial = s.archMan.GenerateIsaSerialDesc()
+		if s.Desc.IsaSerial != nil && options.HostOptions.EnableSerialLog {
+			// tee serial output into log, kept across guest restarts
+			s.Desc.IsaSerial.Pty.Options = map[string]string{
+				"logfile":   s.getSerialLogPath(),
+				"logappend": "on",
+			}
+		}
 	}
 }
 
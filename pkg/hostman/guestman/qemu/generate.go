@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/version"
 	"yunion.io/x/pkg/utils"
 
 	api "yunion.io/x/onecloud/pkg/apis/compute"
@@ -168,6 +169,10 @@ func generateMemoryOption(memDesc *desc.SGuestMem) string {
 		for i := range memDesc.Mem.Mems {
 			cmds = append(cmds, generateMemObjectWithNumaOptions(&memDesc.Mem.Mems[i]))
 		}
+		for i := range memDesc.NumaDistances {
+			dist := memDesc.NumaDistances[i]
+			cmds = append(cmds, fmt.Sprintf("-numa dist,src=%d,dst=%d,val=%d", dist.Src, dist.Dst, dist.Val))
+		}
 	}
 	for i := 0; i < len(memDesc.MemSlots); i++ {
 		memDev := memDesc.MemSlots[i].MemDev
@@ -617,6 +622,26 @@ func getRNGRandomOptions(rng *desc.SGuestRng) []string {
 	}
 }
 
+func generateBalloonOption(qemuVersion Version, balloon *desc.SGuestBalloon) string {
+	cmd := generatePCIDeviceOption(balloon.PCIDevice)
+	// empty version means the latest qemu
+	if balloon.FreePageReporting && (len(qemuVersion) == 0 || version.GE(string(qemuVersion), "5.1.0")) {
+		cmd += ",free-page-reporting=on"
+	}
+	return cmd
+}
+
+func generateVirtiofsOptions(vfses []*desc.SGuestVirtiofs) []string {
+	opts := make([]string, 0)
+	for _, vfs := range vfses {
+		opts = append(opts, chardevOption(vfs.Chardev))
+		cmd := generatePCIDeviceOption(vfs.PCIDevice)
+		cmd += fmt.Sprintf(",chardev=%s,tag=%s,queue-size=1024", vfs.Chardev.Id, vfs.Tag)
+		opts = append(opts, cmd)
+	}
+	return opts
+}
+
 func generateQgaOptions(guestDesc *desc.SGuestDesc) []string {
 	opts := make([]string, 0)
 	opts = append(opts, chardevOption(guestDesc.Qga.Socket))
@@ -874,6 +899,16 @@ func GenerateStartOptions(
 		opts = append(opts, getRNGRandomOptions(input.GuestDesc.Rng)...)
 	}
 
+	// memory balloon device
+	if input.GuestDesc.Balloon != nil {
+		opts = append(opts, generateBalloonOption(input.QemuVersion, input.GuestDesc.Balloon))
+	}
+
+	// virtiofs shared directories
+	if len(input.GuestDesc.Virtiofs) > 0 {
+		opts = append(opts, generateVirtiofsOptions(input.GuestDesc.Virtiofs)...)
+	}
+
 	// serial device
 	if input.GuestDesc.IsaSerial != nil {
 		opts = append(opts, generateISASerialOptions(input.GuestDesc.IsaSerial)...)
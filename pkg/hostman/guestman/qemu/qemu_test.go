@@ -18,6 +18,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
 )
 
 func Test_baseOptions(t *testing.T) {
@@ -44,3 +46,45 @@ func Test_baseOptions(t *testing.T) {
 	assert.Equal("-vnc :5900,password", opt.VNC(5900, true))
 	assert.Equal("-vnc :5900", opt.VNC(5900, false))
 }
+
+func Test_generateMemoryOption(t *testing.T) {
+	node0, node1 := uint16(0), uint16(1)
+	cpus0, cpus1 := "0-1", "2-3"
+	mem0 := desc.NewMemDesc("memory-backend-ram", "mem", &node0, &cpus0)
+	mem0.Options = map[string]string{"size": "1024M"}
+	mem1 := desc.NewMemDesc("memory-backend-ram", "mem0", &node1, &cpus1)
+	mem1.Options = map[string]string{"size": "1024M"}
+
+	memDesc := &desc.SGuestMem{
+		Slots:  4,
+		MaxMem: 8192,
+		SizeMB: 2048,
+		Mem:    desc.NewMemsDesc(*mem0, []desc.SMemDesc{*mem1}),
+		NumaDistances: []desc.SNumaDistance{
+			{Src: 0, Dst: 1, Val: 21},
+			{Src: 1, Dst: 0, Val: 21},
+		},
+	}
+	assert.Equal(t, "-m 2048M,slots=4,maxmem=8192M"+
+		" -object memory-backend-ram,id=mem,size=1024M -numa node,memdev=mem,nodeid=0,cpus=0-1"+
+		" -object memory-backend-ram,id=mem0,size=1024M -numa node,memdev=mem0,nodeid=1,cpus=2-3"+
+		" -numa dist,src=0,dst=1,val=21 -numa dist,src=1,dst=0,val=21",
+		generateMemoryOption(memDesc))
+}
+
+func Test_generateVirtiofsOptions(t *testing.T) {
+	vfs := &desc.SGuestVirtiofs{
+		PCIDevice: desc.NewPCIDevice(desc.CONTROLLER_TYPE_PCI_ROOT, "vhost-user-fs-pci", "vfs0"),
+		Tag:       "data",
+		Chardev: &desc.CharDev{
+			Backend: "socket",
+			Id:      "char-vfs0",
+			Options: map[string]string{"path": "/tmp/vfs0.sock"},
+		},
+	}
+	vfs.PCIAddr = &desc.PCIAddr{Bus: 0, Slot: 5}
+	assert.Equal(t, []string{
+		"-chardev socket,id=char-vfs0,path=/tmp/vfs0.sock",
+		"-device vhost-user-fs-pci,id=vfs0,bus=pci.0,addr=0x05,chardev=char-vfs0,tag=data,queue-size=1024",
+	}, generateVirtiofsOptions([]*desc.SGuestVirtiofs{vfs}))
+}
@@ -0,0 +1,397 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/hostman/storageman/remotefile"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+const (
+	serialLogName = "serial.log"
+	// bytes of serial log dropped by rotation, offsets of serial log count from the first byte ever written
+	serialLogOffsetName = "serial.log.offset"
+
+	serialLogDefaultReadBytes = 64 * 1024
+	serialLogMaxReadBytes     = 1024 * 1024
+
+	serialLogRotateInterval = time.Minute
+)
+
+func (s *SKVMGuestInstance) getSerialLogPath() string {
+	return path.Join(s.HomeDir(), serialLogName)
+}
+
+// GetSerialLogFile returns the path of the current, rotated or offset serial log file of guest
+func (m *SGuestManager) GetSerialLogFile(sid, name string) (string, error) {
+	s, ok := m.GetServer(sid)
+	if !ok {
+		return "", httperrors.NewNotFoundError("guest %s not found", sid)
+	}
+	guest, ok := s.(*SKVMGuestInstance)
+	if !ok {
+		return "", httperrors.NewUnsupportOperationError("guest %s has no serial log", sid)
+	}
+	names := append(serialLogFiles(serialLogName, options.HostOptions.SerialLogRotateCount), serialLogOffsetName)
+	for _, n := range names {
+		if n == name {
+			return path.Join(guest.HomeDir(), name), nil
+		}
+	}
+	return "", httperrors.NewInputParameterError("invalid serial log file %s", name)
+}
+
+func serialLogOffsetPath(logPath string) string {
+	return path.Join(path.Dir(logPath), serialLogOffsetName)
+}
+
+func readSerialLogOffset(logPath string) (int64, error) {
+	content, err := os.ReadFile(serialLogOffsetPath(logPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+}
+
+// addSerialLogOffset records the size of serial log dropped by rotation
+func addSerialLogOffset(logPath string, size int64) error {
+	offset, err := readSerialLogOffset(logPath)
+	if err != nil {
+		return errors.Wrap(err, "read serial log offset")
+	}
+	return os.WriteFile(serialLogOffsetPath(logPath), []byte(strconv.FormatInt(offset+size, 10)), 0644)
+}
+
+func (s *SKVMGuestInstance) getScreenDumpPath() string {
+	return path.Join(s.HomeDir(), "screendump.ppm")
+}
+
+// serialLogFiles returns serial log files from the oldest rotated one to the current one
+func serialLogFiles(logPath string, rotateCount int) []string {
+	files := make([]string, 0, rotateCount+1)
+	for i := rotateCount; i > 0; i-- {
+		files = append(files, fmt.Sprintf("%s.%d", logPath, i))
+	}
+	return append(files, logPath)
+}
+
+// rotateSerialLog copies the log to logPath.1 and truncates it in place,
+// qemu opens the log with O_APPEND so it keeps writing from the new end.
+func rotateSerialLog(logPath string, maxBytes int64, rotateCount int) error {
+	fi, err := os.Stat(logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "stat %s", logPath)
+	}
+	if fi.Size() <= maxBytes {
+		return nil
+	}
+	if rotateCount <= 0 {
+		if err := addSerialLogOffset(logPath, fi.Size()); err != nil {
+			return errors.Wrap(err, "add serial log offset")
+		}
+		return os.Truncate(logPath, 0)
+	}
+	oldest := fmt.Sprintf("%s.%d", logPath, rotateCount)
+	if ofi, err := os.Stat(oldest); err == nil {
+		if err := addSerialLogOffset(logPath, ofi.Size()); err != nil {
+			return errors.Wrap(err, "add serial log offset")
+		}
+		if err := os.Remove(oldest); err != nil {
+			return errors.Wrapf(err, "remove %s", oldest)
+		}
+	}
+	for i := rotateCount - 1; i > 0; i-- {
+		src := fmt.Sprintf("%s.%d", logPath, i)
+		if !fileutils2.Exists(src) {
+			continue
+		}
+		if err := os.Rename(src, fmt.Sprintf("%s.%d", logPath, i+1)); err != nil {
+			return errors.Wrapf(err, "rename %s", src)
+		}
+	}
+	if err := copySerialLog(logPath, logPath+".1"); err != nil {
+		return errors.Wrap(err, "copy serial log")
+	}
+	return os.Truncate(logPath, 0)
+}
+
+func copySerialLog(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// readSerialLog reads the concatenation of rotated and current serial logs,
+// base is the bytes dropped by rotation before the oldest file, offset counts
+// from the first byte ever written and offset < 0 means reading the last tail bytes.
+func readSerialLog(files []string, base, offset, tail, limit int64) (*api.ServerSerialLogOutput, error) {
+	sizes := make([]int64, len(files))
+	var total int64
+	for i, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "stat %s", f)
+		}
+		sizes[i] = fi.Size()
+		total += fi.Size()
+	}
+	end := base + total
+	if offset < 0 {
+		if tail <= 0 {
+			tail = serialLogDefaultReadBytes
+		} else if tail > serialLogMaxReadBytes {
+			tail = serialLogMaxReadBytes
+		}
+		offset = end - tail
+		if offset < base {
+			offset = base
+		}
+		limit = end - offset
+	}
+	if limit <= 0 {
+		limit = serialLogDefaultReadBytes
+	}
+	if limit > serialLogMaxReadBytes {
+		limit = serialLogMaxReadBytes
+	}
+	// content before base has been dropped by rotation
+	if offset < base {
+		offset = base
+	}
+	if offset > end {
+		offset = end
+	}
+	rel := offset - base
+
+	buf := bytes.NewBuffer(nil)
+	var start int64
+	for i, f := range files {
+		if sizes[i] > 0 && rel < start+sizes[i] && int64(buf.Len()) < limit {
+			if err := func() error {
+				fd, err := os.Open(f)
+				if err != nil {
+					return err
+				}
+				defer fd.Close()
+				pos := int64(0)
+				if rel > start {
+					pos = rel - start
+				}
+				if _, err := fd.Seek(pos, io.SeekStart); err != nil {
+					return err
+				}
+				_, err = io.CopyN(buf, fd, limit-int64(buf.Len()))
+				if err != nil && err != io.EOF {
+					return err
+				}
+				return nil
+			}(); err != nil {
+				return nil, errors.Wrapf(err, "read %s", f)
+			}
+		}
+		start += sizes[i]
+	}
+	return &api.ServerSerialLogOutput{
+		Content: buf.String(),
+		Offset:  offset,
+		Size:    end,
+	}, nil
+}
+
+func (s *SKVMGuestInstance) GetSerialLog(input *api.ServerGetSerialLogInput) (*api.ServerSerialLogOutput, error) {
+	offset := int64(-1)
+	if input.Offset != nil {
+		offset = *input.Offset
+	}
+	logPath := s.getSerialLogPath()
+	base, err := readSerialLogOffset(logPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "read serial log offset")
+	}
+	files := serialLogFiles(logPath, options.HostOptions.SerialLogRotateCount)
+	return readSerialLog(files, base, offset, input.Tail, input.Limit)
+}
+
+// fetchMigrateSerialLog copies the current and rotated serial logs with the
+// offset file from source host, so offsets stay valid after migration
+func (s *SKVMGuestInstance) fetchMigrateSerialLog(ctx context.Context, uri string) error {
+	if err := procutils.NewRemoteCommandAsFarAsPossible("mkdir", "-p", s.HomeDir()).Run(); err != nil {
+		return errors.Wrapf(err, "mkdir -p %s", s.HomeDir())
+	}
+	fetch := func(name string) error {
+		url := fmt.Sprintf("%s/%s/%s", uri, s.Id, name)
+		rf := remotefile.NewRemoteFile(ctx, url, path.Join(s.HomeDir(), name), false, "", -1, nil, "", "")
+		return rf.Fetch(nil)
+	}
+	if err := fetch(serialLogName); err != nil {
+		return errors.Wrap(err, "fetch serial log")
+	}
+	// rotated logs are numbered continuously from 1, stop at the first missing one
+	for i := 1; i <= options.HostOptions.SerialLogRotateCount; i++ {
+		if err := fetch(fmt.Sprintf("%s.%d", serialLogName, i)); err != nil {
+			break
+		}
+	}
+	// offset file only exists after rotated logs were dropped
+	if err := fetch(serialLogOffsetName); err != nil {
+		log.Debugf("guest %s fetch serial log offset: %s", s.GetName(), err)
+	}
+	return nil
+}
+
+// ScreenDump captures guest display as boot diagnostics
+func (s *SKVMGuestInstance) ScreenDump() (*api.ServerScreenDumpOutput, error) {
+	if !s.IsRunning() || s.Monitor == nil {
+		return nil, errors.Errorf("guest %s is not running", s.GetName())
+	}
+	dumpPath := s.getScreenDumpPath()
+	defer os.Remove(dumpPath)
+
+	errChan := make(chan string, 1)
+	s.Monitor.ScreenDump(dumpPath, func(res string) {
+		errChan <- res
+	})
+	select {
+	case res := <-errChan:
+		if len(res) > 0 {
+			return nil, errors.Errorf("screendump: %s", res)
+		}
+	case <-time.After(30 * time.Second):
+		return nil, errors.Errorf("screendump timeout")
+	}
+
+	fd, err := os.Open(dumpPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "open screendump")
+	}
+	defer fd.Close()
+	img, err := decodePPM(bufio.NewReader(fd))
+	if err != nil {
+		return nil, errors.Wrap(err, "decode screendump")
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := png.Encode(buf, img); err != nil {
+		return nil, errors.Wrap(err, "encode png")
+	}
+	return &api.ServerScreenDumpOutput{
+		Format: "png",
+		Data:   base64.StdEncoding.EncodeToString(buf.Bytes()),
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}, nil
+}
+
+// decodePPM decodes the binary P6 pixmap written by qemu screendump
+func decodePPM(r *bufio.Reader) (image.Image, error) {
+	var magic string
+	var width, height, maxVal int
+	if _, err := fmt.Fscan(r, &magic, &width, &height, &maxVal); err != nil {
+		return nil, errors.Wrap(err, "read ppm header")
+	}
+	if magic != "P6" || maxVal != 255 || width <= 0 || height <= 0 {
+		return nil, errors.Errorf("unsupported ppm %s %dx%d max %d", magic, width, height, maxVal)
+	}
+	// single whitespace after header
+	if _, err := r.ReadByte(); err != nil {
+		return nil, errors.Wrap(err, "read ppm header")
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	pix := make([]byte, 3)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if _, err := io.ReadFull(r, pix); err != nil {
+				return nil, errors.Wrap(err, "read ppm pixels")
+			}
+			img.Set(x, y, color.RGBA{R: pix[0], G: pix[1], B: pix[2], A: 255})
+		}
+	}
+	return img, nil
+}
+
+func (m *SGuestManager) StartSerialLogRotator() {
+	if !options.HostOptions.EnableSerialLog {
+		return
+	}
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				debug.PrintStack()
+				log.Errorf("Serial log rotator failed %s", r)
+			}
+		}()
+		for {
+			time.Sleep(serialLogRotateInterval)
+			m.rotateSerialLogs()
+		}
+	}()
+}
+
+func (m *SGuestManager) rotateSerialLogs() {
+	maxBytes := int64(options.HostOptions.SerialLogMaxSizeMb) * 1024 * 1024
+	if maxBytes <= 0 {
+		return
+	}
+	m.Servers.Range(func(k, v interface{}) bool {
+		guest, ok := v.(*SKVMGuestInstance)
+		if !ok {
+			return true
+		}
+		if err := rotateSerialLog(guest.getSerialLogPath(), maxBytes, options.HostOptions.SerialLogRotateCount); err != nil {
+			log.Errorf("rotate guest %s serial log: %s", guest.GetName(), err)
+		}
+		return true
+	})
+}
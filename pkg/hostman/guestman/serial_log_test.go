@@ -0,0 +1,130 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSerialLogRotateAndRead(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), serialLogName)
+	write := func(content string) {
+		fd, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fd.Close()
+		fd.WriteString(content)
+	}
+
+	write("aaaa")
+	if err := rotateSerialLog(logPath, 8, 2); err != nil {
+		t.Fatal(err)
+	}
+	write("bbbbbbbbbb")
+	if err := rotateSerialLog(logPath, 8, 2); err != nil {
+		t.Fatal(err)
+	}
+	write("cccccccccc")
+	if err := rotateSerialLog(logPath, 8, 2); err != nil {
+		t.Fatal(err)
+	}
+	write("dd")
+	if err := rotateSerialLog(logPath, 8, 2); err != nil {
+		t.Fatal(err)
+	}
+	write("eeeeeeeee")
+	if err := rotateSerialLog(logPath, 8, 2); err != nil {
+		t.Fatal(err)
+	}
+	write("ff")
+
+	files := serialLogFiles(logPath, 2)
+	base, err := readSerialLogOffset(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first rotated "aaaabbbbbbbbbb" is dropped by rotate count
+	if base != 14 {
+		t.Errorf("unexpected serial log offset %d", base)
+	}
+	out, err := readSerialLog(files, base, -1, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Content != "ccccccccccddeeeeeeeeeff" || out.Size != 37 || out.Offset != 14 {
+		t.Errorf("unexpected tail read %#v", out)
+	}
+
+	out, err = readSerialLog(files, base, -1, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Content != "eeff" || out.Offset != 33 {
+		t.Errorf("unexpected tail 4 read %#v", out)
+	}
+
+	out, err = readSerialLog(files, base, 22, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Content != "ccd" || out.Offset != 22 {
+		t.Errorf("unexpected offset read %#v", out)
+	}
+
+	// offset of dropped content starts from the oldest kept log
+	out, err = readSerialLog(files, base, 8, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Content != "ccc" || out.Offset != 14 {
+		t.Errorf("unexpected dropped offset read %#v", out)
+	}
+
+	// offsets stay valid after another rotation
+	write("gggggggg")
+	if err := rotateSerialLog(logPath, 8, 2); err != nil {
+		t.Fatal(err)
+	}
+	base, err = readSerialLogOffset(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = readSerialLog(files, base, 33, 0, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Content != "eeffgg" || out.Offset != 33 {
+		t.Errorf("unexpected read after rotation %#v", out)
+	}
+}
+
+func TestDecodePPM(t *testing.T) {
+	data := append([]byte("P6\n2 1\n255\n"), 255, 0, 0, 0, 0, 255)
+	img, err := decodePPM(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 2 || img.Bounds().Dy() != 1 {
+		t.Fatalf("unexpected bounds %v", img.Bounds())
+	}
+	if r, _, b, _ := img.At(1, 0).RGBA(); r != 0 || b != 0xffff {
+		t.Errorf("unexpected pixel %v", img.At(1, 0))
+	}
+}
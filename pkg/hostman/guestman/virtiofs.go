@@ -0,0 +1,158 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guestman
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/hostman/guestman/desc"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/mountutils"
+	"yunion.io/x/onecloud/pkg/util/procutils"
+)
+
+func (s *SKVMGuestInstance) getVirtiofsDir() string {
+	return path.Join(s.HomeDir(), "virtiofs")
+}
+
+// unix socket path is limited to 108 bytes, so name it with device index instead of tag
+func (s *SKVMGuestInstance) getVirtiofsSocketPath(idx int) string {
+	return path.Join(s.getVirtiofsDir(), fmt.Sprintf("vfs%d.sock", idx))
+}
+
+func (s *SKVMGuestInstance) getVirtiofsPidPath(idx int) string {
+	return path.Join(s.getVirtiofsDir(), fmt.Sprintf("vfs%d.pid", idx))
+}
+
+func (s *SKVMGuestInstance) getVirtiofsMountPoint(vfs *desc.SGuestVirtiofs) string {
+	return path.Join(s.getVirtiofsDir(), vfs.Tag)
+}
+
+// getVirtiofsSharedDir returns the host directory exported by virtiofsd
+func (s *SKVMGuestInstance) getVirtiofsSharedDir(vfs *desc.SGuestVirtiofs) string {
+	if vfs.SourceType == api.VIRTIOFS_SOURCE_HOST_PATH {
+		return vfs.HostPath
+	}
+	return s.getVirtiofsMountPoint(vfs)
+}
+
+// the secret is passed to mount.ceph by file to keep it out of the process arguments
+func (s *SKVMGuestInstance) getVirtiofsSecretPath(vfs *desc.SGuestVirtiofs) string {
+	return path.Join(s.getVirtiofsDir(), fmt.Sprintf("%s.secret", vfs.Tag))
+}
+
+// mountVirtiofsSources mounts the remote filesystems shared by virtiofs before the guest starts
+func (s *SKVMGuestInstance) mountVirtiofsSources() error {
+	if len(s.Desc.Virtiofs) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(s.getVirtiofsDir(), 0700); err != nil {
+		return errors.Wrapf(err, "mkdir %s", s.getVirtiofsDir())
+	}
+	for _, vfs := range s.Desc.Virtiofs {
+		if err := s.mountVirtiofsSource(vfs); err != nil {
+			return errors.Wrapf(err, "mount virtiofs %s source", vfs.Tag)
+		}
+	}
+	return nil
+}
+
+func (s *SKVMGuestInstance) mountVirtiofsSource(vfs *desc.SGuestVirtiofs) error {
+	if vfs.SourceType == api.VIRTIOFS_SOURCE_HOST_PATH {
+		if !fileutils2.Exists(vfs.HostPath) {
+			return errors.Wrapf(errors.ErrNotFound, "host path %s", vfs.HostPath)
+		}
+		return nil
+	}
+	mountPoint := s.getVirtiofsMountPoint(vfs)
+	if out, err := procutils.NewRemoteCommandAsFarAsPossible("mkdir", "-p", mountPoint).Output(); err != nil {
+		return errors.Wrapf(err, "mkdir %s: %s", mountPoint, out)
+	}
+	switch vfs.SourceType {
+	case api.VIRTIOFS_SOURCE_CEPHFS:
+		if vfs.Cephfs == nil {
+			return errors.Errorf("virtiofs %s missing cephfs source", vfs.Tag)
+		}
+		secretPath := s.getVirtiofsSecretPath(vfs)
+		if err := os.WriteFile(secretPath, []byte(vfs.Cephfs.Secret), 0600); err != nil {
+			return errors.Wrapf(err, "write secret file %s", secretPath)
+		}
+		defer os.Remove(secretPath)
+		opts := fmt.Sprintf("name=%s,secretfile=%s", vfs.Cephfs.Name, secretPath)
+		if vfs.ReadOnly {
+			opts += ",ro"
+		}
+		return mountutils.MountWithParams(fmt.Sprintf("%s:%s", vfs.Cephfs.MonHost, vfs.Cephfs.Path), mountPoint, "ceph", []string{"-o", opts})
+	case api.VIRTIOFS_SOURCE_MOUNT_TARGET:
+		if len(vfs.NfsSource) == 0 {
+			return errors.Errorf("virtiofs %s missing nfs source", vfs.Tag)
+		}
+		opts := "vers=4,hard"
+		if vfs.ReadOnly {
+			opts += ",ro"
+		}
+		return mountutils.MountWithParams(vfs.NfsSource, mountPoint, "nfs", []string{"-o", opts})
+	default:
+		return errors.Errorf("unknown virtiofs source type %s", vfs.SourceType)
+	}
+}
+
+// generateVirtiofsStartScripts spawns one virtiofsd daemon per shared directory,
+// qemu connects to their sockets. The remote filesystems are mounted by mountVirtiofsSources.
+func (s *SKVMGuestInstance) generateVirtiofsStartScripts() (string, error) {
+	if len(s.Desc.Virtiofs) == 0 {
+		return "", nil
+	}
+	if !fileutils2.Exists(options.HostOptions.VirtiofsdPath) {
+		return "", errors.Errorf("virtiofsd %s not found", options.HostOptions.VirtiofsdPath)
+	}
+	cmd := fmt.Sprintf("mkdir -p %s\n", s.getVirtiofsDir())
+	for i, vfs := range s.Desc.Virtiofs {
+		sock := s.getVirtiofsSocketPath(i)
+		pidFile := s.getVirtiofsPidPath(i)
+		args := fmt.Sprintf("--socket-path=%s --shared-dir=%s --cache=auto", sock, s.getVirtiofsSharedDir(vfs))
+		if vfs.ReadOnly {
+			args += " --readonly"
+		}
+		cmd += fmt.Sprintf("if [ -f %s ]; then kill -9 $(cat %s) > /dev/null 2>&1; fi\n", pidFile, pidFile)
+		cmd += fmt.Sprintf("rm -f %s\n", sock)
+		cmd += fmt.Sprintf("nohup %s %s > %s.log 2>&1 &\n", options.HostOptions.VirtiofsdPath, args, sock)
+		cmd += fmt.Sprintf("echo $! > %s\n", pidFile)
+		cmd += fmt.Sprintf("for i in $(seq 1 10); do [ -S %s ] && break; sleep 0.5; done\n", sock)
+	}
+	return cmd, nil
+}
+
+func (s *SKVMGuestInstance) generateVirtiofsStopScripts() string {
+	cmd := ""
+	for i, vfs := range s.Desc.Virtiofs {
+		pidFile := s.getVirtiofsPidPath(i)
+		cmd += fmt.Sprintf("if [ -f %s ]; then\n", pidFile)
+		cmd += fmt.Sprintf("  kill -9 $(cat %s) > /dev/null 2>&1\n", pidFile)
+		cmd += fmt.Sprintf("  rm -f %s %s\n", pidFile, s.getVirtiofsSocketPath(i))
+		cmd += "fi\n"
+		if vfs.SourceType != api.VIRTIOFS_SOURCE_HOST_PATH {
+			mountPoint := s.getVirtiofsMountPoint(vfs)
+			cmd += fmt.Sprintf("if mountpoint -q %s; then umount -l %s; fi\n", mountPoint, mountPoint)
+		}
+	}
+	return cmd
+}
@@ -26,6 +26,7 @@ type DeployAgentClient interface {
 	ConnectEsxiDisks(ctx context.Context, in *ConnectEsxiDisksParams, opts ...grpc.CallOption) (*EsxiDisksConnectionInfo, error)
 	DisconnectEsxiDisks(ctx context.Context, in *EsxiDisksConnectionInfo, opts ...grpc.CallOption) (*Empty, error)
 	SetOvmfBootOrder(ctx context.Context, in *OvmfBootOrderParams, opts ...grpc.CallOption) (*Empty, error)
+	ScanImagePackages(ctx context.Context, in *ProbeImageInfoPramas, opts ...grpc.CallOption) (*ImagePackages, error)
 }
 
 type deployAgentClient struct {
@@ -108,6 +109,15 @@ func (c *deployAgentClient) SetOvmfBootOrder(ctx context.Context, in *OvmfBootOr
 	return out, nil
 }
 
+func (c *deployAgentClient) ScanImagePackages(ctx context.Context, in *ProbeImageInfoPramas, opts ...grpc.CallOption) (*ImagePackages, error) {
+	out := new(ImagePackages)
+	err := c.cc.Invoke(ctx, "/apis.DeployAgent/ScanImagePackages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DeployAgentServer is the server API for DeployAgent service.
 // All implementations must embed UnimplementedDeployAgentServer
 // for forward compatibility
@@ -120,6 +130,7 @@ type DeployAgentServer interface {
 	ConnectEsxiDisks(context.Context, *ConnectEsxiDisksParams) (*EsxiDisksConnectionInfo, error)
 	DisconnectEsxiDisks(context.Context, *EsxiDisksConnectionInfo) (*Empty, error)
 	SetOvmfBootOrder(context.Context, *OvmfBootOrderParams) (*Empty, error)
+	ScanImagePackages(context.Context, *ProbeImageInfoPramas) (*ImagePackages, error)
 	mustEmbedUnimplementedDeployAgentServer()
 }
 
@@ -151,6 +162,9 @@ func (UnimplementedDeployAgentServer) DisconnectEsxiDisks(context.Context, *Esxi
 func (UnimplementedDeployAgentServer) SetOvmfBootOrder(context.Context, *OvmfBootOrderParams) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetOvmfBootOrder not implemented")
 }
+func (UnimplementedDeployAgentServer) ScanImagePackages(context.Context, *ProbeImageInfoPramas) (*ImagePackages, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScanImagePackages not implemented")
+}
 func (UnimplementedDeployAgentServer) mustEmbedUnimplementedDeployAgentServer() {}
 
 // UnsafeDeployAgentServer may be embedded to opt out of forward compatibility for this service.
@@ -308,6 +322,24 @@ func _DeployAgent_SetOvmfBootOrder_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DeployAgent_ScanImagePackages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProbeImageInfoPramas)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeployAgentServer).ScanImagePackages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/apis.DeployAgent/ScanImagePackages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeployAgentServer).ScanImagePackages(ctx, req.(*ProbeImageInfoPramas))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _DeployAgent_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "apis.DeployAgent",
 	HandlerType: (*DeployAgentServer)(nil),
@@ -344,6 +376,10 @@ var _DeployAgent_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SetOvmfBootOrder",
 			Handler:    _DeployAgent_SetOvmfBootOrder_Handler,
 		},
+		{
+			MethodName: "ScanImagePackages",
+			Handler:    _DeployAgent_ScanImagePackages_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "deploy.proto",
@@ -104,6 +104,16 @@ func (c *DeployClient) ProbeImageInfo(ctx context.Context, in *deployapi.ProbeIm
 	return client.ProbeImageInfo(ctx, in, opts...)
 }
 
+func (c *DeployClient) ScanImagePackages(ctx context.Context, in *deployapi.ProbeImageInfoPramas, opts ...grpc.CallOption) (*deployapi.ImagePackages, error) {
+	conn, err := grcpDialWithUnixSocket(ctx, c.socketPath)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	client := deployapi.NewDeployAgentClient(conn)
+	return client.ScanImagePackages(ctx, in, opts...)
+}
+
 func (c *DeployClient) ConnectEsxiDisks(
 	ctx context.Context, in *deployapi.ConnectEsxiDisksParams, opts ...grpc.CallOption,
 ) (*deployapi.EsxiDisksConnectionInfo, error) {
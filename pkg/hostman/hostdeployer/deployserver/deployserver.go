@@ -211,6 +211,23 @@ func (s *DeployerServer) ProbeImageInfo(ctx context.Context, req *deployapi.Prob
 	return kvmDisk.ProbeImageInfo(req)
 }
 
+func (s *DeployerServer) ScanImagePackages(ctx context.Context, req *deployapi.ProbeImageInfoPramas) (*deployapi.ImagePackages, error) {
+	log.Infof("********* %#v scan image packages", apiDiskInfo(req.DiskInfo))
+	kvmDisk, err := diskutils.NewKVMGuestDisk(apiDiskInfo(req.GetDiskInfo()), DeployOption.ImageDeployDriver, true)
+	if err != nil {
+		return new(deployapi.ImagePackages), errors.Wrap(err, "NewKVMGuestDisk")
+	}
+	defer kvmDisk.Cleanup()
+
+	if err := kvmDisk.Connect(nil); err != nil {
+		log.Errorf("Failed to connect kvm disk %#v: %s", apiDiskInfo(req.DiskInfo), err)
+		return new(deployapi.ImagePackages), errors.Wrap(err, "Disk connector failed to connect image")
+	}
+	defer kvmDisk.Disconnect()
+
+	return kvmDisk.ScanImagePackages(req)
+}
+
 var connectedEsxiDisks = map[string]*diskutils.VDDKDisk{}
 
 func (*DeployerServer) ConnectEsxiDisks(
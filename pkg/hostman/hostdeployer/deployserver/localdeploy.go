@@ -93,6 +93,18 @@ func (d *LocalDeploy) ProbeImageInfo(req *deployapi.ProbeImageInfoPramas) (*depl
 	return localDisk.ProbeImageInfo(req)
 }
 
+func (d *LocalDeploy) ScanImagePackages(req *deployapi.ProbeImageInfoPramas) (*deployapi.ImagePackages, error) {
+	localDisk, err := diskutils.NewKVMGuestDisk(qemuimg.SImageInfo{}, consts.DEPLOY_DRIVER_LOCAL_DISK, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "new local disk")
+	}
+	if err := localDisk.Connect(nil); err != nil {
+		return nil, errors.Wrapf(err, "local disk connect")
+	}
+	defer localDisk.Disconnect()
+	return localDisk.ScanImagePackages(req)
+}
+
 func LocalInitEnv() error {
 	f, _ := os.OpenFile("/log", os.O_CREATE|os.O_WRONLY, 0777)
 	logrus.SetOutput(f)
@@ -162,6 +174,12 @@ func StartLocalDeploy(deployAction string) (interface{}, error) {
 			return nil, errors.Wrap(err, "unmarshal params")
 		}
 		return localDeployer.ProbeImageInfo(params)
+	case "scan_image_packages":
+		params := new(deployapi.ProbeImageInfoPramas)
+		if err := unmarshalDeployParams(params); err != nil {
+			return nil, errors.Wrap(err, "unmarshal params")
+		}
+		return localDeployer.ScanImagePackages(params)
 	default:
 		return nil, errors.Errorf("unknown deploy action")
 	}
@@ -560,6 +560,11 @@ func (h *SHostInfo) prepareEnv() error {
 	}
 
 	h.sysinfo.HugepagesOption = options.HostOptions.HugepagesOption
+	if hp, err := h.Mem.GetHugepages(); err != nil {
+		log.Warningf("MEM.GetHugepages: %v", err)
+	} else {
+		h.sysinfo.HugepageSizesKb = hp.PageSizes()
+	}
 
 	h.PreventArpFlux()
 	h.tuneSystem()
@@ -615,6 +620,7 @@ func (h *SHostInfo) detectHostInfo() error {
 	if err = h.GetNodeHugepages(); err != nil {
 		return errors.Wrap(err, "GetNodeHugepages")
 	}
+	h.getNodeReservedHugepages()
 	system_service.Init()
 	if options.HostOptions.CheckSystemServices {
 		if err := h.checkSystemServices(); err != nil {
@@ -731,6 +737,33 @@ func (h *SHostInfo) GetNodeHugepages() error {
 	return nil
 }
 
+// getNodeReservedHugepages collects the hugepages of every page size
+// reserved on each numa node, which back the memory of guests requiring a
+// page size other than the native one
+func (h *SHostInfo) getNodeReservedHugepages() {
+	nodeHugepages := make([]hostapi.HostNodeReservedHugepages, 0)
+	for i := range h.sysinfo.Topology.Nodes {
+		nodeId := h.sysinfo.Topology.Nodes[i].ID
+		hp, err := sysutils.GetNodeHugepages(nodeId)
+		if err != nil {
+			log.Warningf("GetNodeHugepages of node %d: %v", nodeId, err)
+			continue
+		}
+		for j := range hp {
+			if hp[j].Total == 0 {
+				continue
+			}
+			nodeHugepages = append(nodeHugepages, hostapi.HostNodeReservedHugepages{
+				NodeId: nodeId,
+				SizeKb: hp[j].SizeKb,
+				Total:  hp[j].Total,
+				Free:   hp[j].Free,
+			})
+		}
+	}
+	h.sysinfo.NodeReservedHugepages = nodeHugepages
+}
+
 func (h *SHostInfo) GetMemory() int {
 	return h.Mem.Total
 }
@@ -942,6 +975,7 @@ func (h *SHostInfo) detectSyssoftwareInfo() error {
 			log.Errorf("detect qemu version: %s", err.Error())
 			h.AppendHostError(fmt.Sprintf("detect qemu version: %s", err.Error()))
 		}
+		h.detectVirtiofsSources()
 	}
 	h.detectOvsVersion()
 	if err := h.detectOvsKOVersion(); err != nil {
@@ -1124,6 +1158,30 @@ func (h *SHostInfo) detectOvsVersion() {
 	}
 }
 
+func (h *SHostInfo) detectVirtiofsSources() {
+	sources := make([]string, 0)
+	if fileutils2.Exists(options.HostOptions.VirtiofsdPath) {
+		sources = append(sources, api.VIRTIOFS_SOURCE_HOST_PATH)
+		// kernel modules required to mount the filesystem of virtiofs sources
+		for _, src := range []struct {
+			source string
+			module string
+		}{
+			{api.VIRTIOFS_SOURCE_CEPHFS, "ceph"},
+			{api.VIRTIOFS_SOURCE_MOUNT_TARGET, "nfs"},
+		} {
+			if err := procutils.NewRemoteCommandAsFarAsPossible("modinfo", src.module).Run(); err != nil {
+				log.Warningf("kernel module %s not found, virtiofs source %s unavailable", src.module, src.source)
+				continue
+			}
+			sources = append(sources, src.source)
+		}
+	} else {
+		log.Warningf("virtiofsd %s not found, virtiofs disabled", options.HostOptions.VirtiofsdPath)
+	}
+	h.sysinfo.VirtiofsSources = sources
+}
+
 func (h *SHostInfo) detectOvsKOVersion() error {
 	output, err := procutils.NewRemoteCommandAsFarAsPossible("modinfo", "openvswitch").Output()
 	if err != nil {
@@ -408,11 +408,18 @@ type SSysInfo struct {
 
 	StorageType string `json:"storage_type"`
 
-	HugepagesOption string                       `json:"hugepages_option"`
-	HugepageSizeKb  int                          `json:"hugepage_size_kb"`
-	HugepageNr      *int                         `json:"hugepage_nr"`
+	HugepagesOption string `json:"hugepages_option"`
+	HugepageSizeKb  int    `json:"hugepage_size_kb"`
+	HugepageNr      *int   `json:"hugepage_nr"`
+	// page sizes of reserved hugepages, which can back guest memory
+	HugepageSizesKb []int                        `json:"hugepage_sizes_kb"`
 	NodeHugepages   []hostapi.HostNodeHugepageNr `json:"node_hugepages"`
-	EnableKsm       bool                         `json:"enable_ksm"`
+	// reserved hugepages of each page size on each numa node
+	NodeReservedHugepages []hostapi.HostNodeReservedHugepages `json:"node_reserved_hugepages"`
+	EnableKsm             bool                                `json:"enable_ksm"`
+
+	// filesystem sources can be shared to guests through virtiofs
+	VirtiofsSources []string `json:"virtiofs_sources"`
 
 	Topology        *hostapi.HostTopology `json:"topology"`
 	CPUInfo         *hostapi.HostCPUInfo  `json:"cpu_info"`
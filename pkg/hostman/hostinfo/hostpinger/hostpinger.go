@@ -43,6 +43,7 @@ type SHostPingTask struct {
 	// masterHostStorages for shared storages
 	masterHostStorages []string
 	lastStatAt         time.Time
+	lastDiskStatAt     time.Time
 }
 
 type SEndpoint struct {
@@ -109,9 +110,14 @@ func (p *SHostPingTask) payload() api.SHostPingInput {
 	}
 
 	p.lastStatAt = now
-	data = storageman.GatherHostStorageStats(p.masterHostStorages)
+	withDiskStats := now.After(p.lastDiskStatAt.Add(time.Duration(options.HostOptions.SyncDiskActualSizeDurationSecond) * time.Second))
+	if withDiskStats {
+		p.lastDiskStatAt = now
+	}
+	data = storageman.GatherHostStorageStats(p.masterHostStorages, withDiskStats)
 	data.WithData = true
 	data.QgaRunningGuestIds = guestman.GetGuestManager().GetQgaRunningGuests()
+	data.GuestMemoryStats = guestman.GetGuestManager().GetGuestMemoryStats()
 	info, err := mem.VirtualMemory()
 	if err != nil {
 		return data
@@ -422,6 +422,10 @@ func (m *HmpMonitor) DriveMirror(callback StringCallback, drive, target, syncMod
 	m.Query(cmd, callback)
 }
 
+func (m *HmpMonitor) DriveMirrorWithCopyMode(callback StringCallback, drive, target, syncMode, copyMode string, speed int64) {
+	go callback("hmp unsupport drive mirror copy mode")
+}
+
 func (m *HmpMonitor) DriveBackup(callback StringCallback, drive, target, syncMode, format string) {
 	cmd := "drive_backup -n"
 	if syncMode == "full" {
@@ -518,6 +522,26 @@ func (m *HmpMonitor) GetMemdevList(callback MemdevListCallback) {
 	go callback(nil, "hmp unsupport get memdev list")
 }
 
+func (m *HmpMonitor) SetBalloon(targetMB int64, callback StringCallback) {
+	m.Query(fmt.Sprintf("balloon %d", targetMB), callback)
+}
+
+func (m *HmpMonitor) QueryBalloon(callback QueryBalloonCallback) {
+	go callback(nil, "hmp unsupport query balloon")
+}
+
+func (m *HmpMonitor) SetBalloonStatsPolling(id string, intervalSec int, callback StringCallback) {
+	m.Query(fmt.Sprintf("qom-set /machine/peripheral/%s guest-stats-polling-interval %d", id, intervalSec), callback)
+}
+
+func (m *HmpMonitor) ScreenDump(filename string, callback StringCallback) {
+	m.Query(fmt.Sprintf("screendump %s", filename), callback)
+}
+
+func (m *HmpMonitor) GetBalloonStats(id string, callback BalloonStatsCallback) {
+	go callback(nil, "hmp unsupport get balloon stats")
+}
+
 func (m *HmpMonitor) ObjectAdd(objectType string, params map[string]string, callback StringCallback) {
 	var paramsKvs = []string{}
 	for k, v := range params {
@@ -209,6 +209,13 @@ type Monitor interface {
 	GetMemoryDevicesInfo(QueryMemoryDevicesCallback)
 	GetMemdevList(MemdevListCallback)
 
+	SetBalloon(targetMB int64, callback StringCallback)
+	QueryBalloon(callback QueryBalloonCallback)
+	SetBalloonStatsPolling(id string, intervalSec int, callback StringCallback)
+	GetBalloonStats(id string, callback BalloonStatsCallback)
+
+	ScreenDump(filename string, callback StringCallback)
+
 	GetBlocks(callback func([]QemuBlock))
 	EjectCdrom(dev string, callback StringCallback)
 	ChangeCdrom(dev string, path string, callback StringCallback)
@@ -224,6 +231,7 @@ type Monitor interface {
 	XBlockdevChange(parent, node, child string, callback StringCallback)
 	BlockStream(drive string, callback StringCallback)
 	DriveMirror(callback StringCallback, drive, target, syncMode, format string, unmap, blockReplication bool, speed int64)
+	DriveMirrorWithCopyMode(callback StringCallback, drive, target, syncMode, copyMode string, speed int64)
 	DriveBackup(callback StringCallback, drive, target, syncMode, format string)
 	BlockJobComplete(drive string, cb StringCallback)
 	BlockReopenImage(drive, newImagePath, format string, cb StringCallback)
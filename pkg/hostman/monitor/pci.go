@@ -139,6 +139,29 @@ type Memdev struct {
 
 type MemdevListCallback func(res []Memdev, err string)
 
+// BalloonInfo implements the "BalloonInfo" QMP API type.
+type BalloonInfo struct {
+	// actual memory size of guest in bytes
+	Actual int64 `json:"actual"`
+}
+
+type QueryBalloonCallback func(info *BalloonInfo, err string)
+
+// BalloonStats is the guest-stats property of virtio-balloon device,
+// stat value -1 means not reported by guest
+type BalloonStats struct {
+	Stats      map[string]int64 `json:"stats"`
+	LastUpdate int64            `json:"last-update"`
+}
+
+const (
+	BALLOON_STAT_FREE_MEMORY      = "stat-free-memory"
+	BALLOON_STAT_AVAILABLE_MEMORY = "stat-available-memory"
+	BALLOON_STAT_TOTAL_MEMORY     = "stat-total-memory"
+)
+
+type BalloonStatsCallback func(stats *BalloonStats, err string)
+
 // CpuInstanceProperties -> CPUInstanceProperties (struct)
 
 // CPUInstanceProperties implements the "CpuInstanceProperties" QMP API type.
@@ -798,6 +798,32 @@ func (m *QmpMonitor) DriveMirror(callback StringCallback, drive, target, syncMod
 	m.Query(cmd, cb)
 }
 
+// DriveMirrorWithCopyMode mirrors drive to an existing target, copyMode
+// write-blocking makes guest writes return only after the target is written
+func (m *QmpMonitor) DriveMirrorWithCopyMode(callback StringCallback, drive, target, syncMode, copyMode string, speed int64) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		args = map[string]interface{}{
+			"device":    drive,
+			"target":    target,
+			"mode":      "existing",
+			"sync":      syncMode,
+			"copy-mode": copyMode,
+		}
+	)
+	if speed > 0 {
+		args["speed"] = speed
+	}
+	cmd := &Command{
+		Execute: "drive-mirror",
+		Args:    args,
+	}
+
+	m.Query(cmd, cb)
+}
+
 func (m *QmpMonitor) DriveBackup(callback StringCallback, drive, target, syncMode, format string) {
 	var (
 		cb = func(res *Response) {
@@ -971,6 +997,100 @@ func (m *QmpMonitor) GetMemdevList(callback MemdevListCallback) {
 	m.Query(cmd, cb)
 }
 
+func (m *QmpMonitor) SetBalloon(targetMB int64, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "balloon",
+			Args: map[string]interface{}{
+				"value": targetMB * 1024 * 1024,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) QueryBalloon(callback QueryBalloonCallback) {
+	var (
+		cb = func(res *Response) {
+			if res.ErrorVal != nil {
+				callback(nil, res.ErrorVal.Error())
+				return
+			}
+			info := new(BalloonInfo)
+			if err := json.Unmarshal(res.Return, info); err != nil {
+				callback(nil, err.Error())
+				return
+			}
+			callback(info, "")
+		}
+		cmd = &Command{
+			Execute: "query-balloon",
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) SetBalloonStatsPolling(id string, intervalSec int, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "qom-set",
+			Args: map[string]interface{}{
+				"path":     fmt.Sprintf("/machine/peripheral/%s", id),
+				"property": "guest-stats-polling-interval",
+				"value":    intervalSec,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+// ScreenDump writes guest display into filename in PPM format
+func (m *QmpMonitor) ScreenDump(filename string, callback StringCallback) {
+	var (
+		cb = func(res *Response) {
+			callback(m.actionResult(res))
+		}
+		cmd = &Command{
+			Execute: "screendump",
+			Args: map[string]interface{}{
+				"filename": filename,
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
+func (m *QmpMonitor) GetBalloonStats(id string, callback BalloonStatsCallback) {
+	var (
+		cb = func(res *Response) {
+			if res.ErrorVal != nil {
+				callback(nil, res.ErrorVal.Error())
+				return
+			}
+			stats := new(BalloonStats)
+			if err := json.Unmarshal(res.Return, stats); err != nil {
+				callback(nil, err.Error())
+				return
+			}
+			callback(stats, "")
+		}
+		cmd = &Command{
+			Execute: "qom-get",
+			Args: map[string]interface{}{
+				"path":     fmt.Sprintf("/machine/peripheral/%s", id),
+				"property": "guest-stats",
+			},
+		}
+	)
+	m.Query(cmd, cb)
+}
+
 func (m *QmpMonitor) BlockIoThrottle(driveName string, bps, iops int64, callback StringCallback) {
 	cmd := fmt.Sprintf("block_set_io_throttle %s %d 0 0 %d 0 0", driveName, bps, iops)
 	m.HumanMonitorCommand(cmd, callback)
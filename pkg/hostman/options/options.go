@@ -59,6 +59,8 @@ type SHostBaseOptions struct {
 	ImageCacheCleanupOnStartup  bool `help:"Cleanup image cache on host startup" default:"false"`
 	ImageCacheCleanupDryRun     bool `help:"Dry run cleanup image cache" default:"false"`
 
+	ImageSignaturePolicy string `help:"Policy of image signature verification when caching an image" default:"none" choices:"none|warn|enforce"`
+
 	TelegrafKafkaOutputTopic         string `json:"telegraf_kafka_output_topic" help:"telegraf kafka output topic"`
 	TelegrafKafkaOutputSaslUsername  string `json:"telegraf_kafka_output_sasl_username" help:"telegraf kafka output sasl_username"`
 	TelegrafKafkaOutputSaslPassword  string `json:"telegraf_kafka_output_sasl_password" help:"telegraf kafka output sasl_password"`
@@ -195,7 +197,8 @@ type SHostOptions struct {
 	HostHealthTimeout int `help:"host health timeout" default:"30"`
 	HostLeaseTimeout  int `help:"lease timeout" default:"10"`
 
-	SyncStorageInfoDurationSecond int `help:"sync storage size duration, unit is second, default is every 2 minutes" default:"120"`
+	SyncStorageInfoDurationSecond    int `help:"sync storage size duration, unit is second, default is every 2 minutes" default:"120"`
+	SyncDiskActualSizeDurationSecond int `help:"report actual size of thin provisioned disks duration, unit is second, default is every 10 minutes" default:"600"`
 
 	DisableProbeKubelet bool   `help:"Disable probe kubelet config" default:"false"`
 	KubeletRunDirectory string `help:"Kubelet config file path" default:"/var/lib/kubelet"`
@@ -220,6 +223,25 @@ type SHostOptions struct {
 
 	EnableVirtioRngDevice bool `help:"enable qemu virtio-rng device" default:"true"`
 
+	EnableMemoryBalloon              bool `help:"enable virtio-balloon device for all guests, guest with metadata enable_memory_balloon always enabled" default:"false"`
+	MemoryBalloonFreePageReporting   bool `help:"enable free page reporting of virtio-balloon device" default:"true"`
+	MemoryBalloonIntervalSeconds     int  `help:"interval seconds of memory balloon controller adjusting guests" default:"30"`
+	MemoryBalloonPressurePercent     int  `help:"host is under memory pressure when available memory percent is lower than this" default:"10"`
+	MemoryBalloonRelaxPercent        int  `help:"balloon controller gives memory back to guests when host available memory percent is higher than this" default:"25"`
+	MemoryBalloonMinPercent          int  `help:"default min percent of guest memory that balloon controller can shrink to" default:"50"`
+	MemoryBalloonStepPercent         int  `help:"percent of guest memory adjusted by balloon controller at each step" default:"10"`
+	MemoryBalloonStatsPollingSeconds int  `help:"guest stats polling interval of virtio-balloon device" default:"10"`
+
+	VirtiofsdPath string `help:"path of virtiofsd binary serving guest virtiofs shared directories" default:"/usr/libexec/virtiofsd"`
+
+	EnableSerialLog      bool `help:"persist guest serial console output into log file" default:"true"`
+	SerialLogMaxSizeMb   int  `help:"max size in MB of guest serial log before rotated" default:"10"`
+	SerialLogRotateCount int  `help:"count of rotated guest serial logs to keep" default:"3"`
+
+	EnableFlowLog               bool   `help:"collect vpc flow logs from acl logs of ovn-controller" default:"true"`
+	FlowLogOvnControllerLogPath string `help:"path of ovn-controller log file containing acl logs" default:"/var/log/openvswitch/ovn-controller.log"`
+	FlowLogFlushIntervalSeconds int    `help:"interval seconds of shipping collected flow log records" default:"60"`
+
 	RestrictQemuImgConvertWorker bool `help:"restrict qemu-img convert worker" default:"false"`
 
 	DefaultLiveMigrateDowntime float32 `help:"allow downtime in seconds for live migrate" default:"5.0"`
@@ -227,6 +249,8 @@ type SHostOptions struct {
 	LocalBackupStoragePath string `help:"path for mounting backup nfs storage" default:"/opt/cloud/workspace/backupstorage"`
 	LocalBackupTempPath    string `help:"the local temporary directory for backup" default:"/opt/cloud/workspace/run/backups"`
 
+	DiskReplicaRunPath string `help:"the directory for pid files of disk replica nbd exports" default:"/opt/cloud/workspace/run/disk-replicas"`
+
 	BinaryMemcleanPath string `help:"execute binary memclean path" default:"/opt/yunion/bin/memclean"`
 
 	MaxHotplugVCpuCount int    `help:"maximal possible vCPU count that the platform kvm supports"`
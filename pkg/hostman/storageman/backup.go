@@ -15,9 +15,12 @@
 package storageman
 
 import (
+	"archive/tar"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"path"
 	"strings"
 
@@ -68,43 +71,56 @@ func isTarSnapshot(path string) bool {
 	return strings.HasSuffix(path, ".tar")
 }
 
-func doBackupDisk(ctx context.Context, snapshotPath string, diskBackup *SDiskBackup) (int, error) {
+// doBackupDisk saves the snapshot to the backup storage and returns the size
+// of the backup in MB. When the snapshot is a qcow2 overlay right on top of
+// parentPath, only the overlay is saved and the backup is incremental
+func doBackupDisk(ctx context.Context, snapshotPath, parentPath string, diskBackup *SDiskBackup) (int, bool, error) {
 	backupTmpDir, err := EnsureBackupDir()
 	if err != nil {
-		return 0, errors.Wrap(err, "EnsureBackupDir")
+		return 0, false, errors.Wrap(err, "EnsureBackupDir")
 	}
 	defer CleanupDirOrFile(backupTmpDir)
 
 	backupPath := path.Join(backupTmpDir, diskBackup.BackupId)
 	var newImageSizeMb int
+	incremental := false
 
 	if isTarSnapshot(snapshotPath) {
 		backupPath = snapshotPath
 		fileSize := fileutils2.FileSize(backupPath)
 		if fileSize <= 0 {
-			return 0, errors.Errorf("get snapshot path %s size failed", snapshotPath)
+			return 0, false, errors.Errorf("get snapshot path %s size failed", snapshotPath)
 		}
 		newImageSizeMb = int(fileSize / 1024 / 1024)
 	} else {
 		img, err := qemuimg.NewQemuImage(snapshotPath)
 		if err != nil {
-			return 0, errors.Wrap(err, "NewQemuImage snapshot")
+			return 0, false, errors.Wrap(err, "NewQemuImage snapshot")
 		}
-		encKey := ""
-		if len(diskBackup.EncryptKeyId) > 0 {
-			session := auth.GetSession(ctx, diskBackup.UserCred, consts.GetRegion())
-			secKey, err := identity_modules.Credentials.GetEncryptKey(session, diskBackup.EncryptKeyId)
+		var newImage *qemuimg.SQemuImage
+		if len(parentPath) > 0 && len(diskBackup.EncryptKeyId) == 0 && img.BackFilePath == parentPath {
+			newImage, err = backupSnapshotOverlay(img, backupPath)
 			if err != nil {
-				return 0, errors.Wrap(err, "GetEncryptKey")
+				return 0, false, errors.Wrap(err, "backupSnapshotOverlay")
+			}
+			incremental = true
+		} else {
+			encKey := ""
+			if len(diskBackup.EncryptKeyId) > 0 {
+				session := auth.GetSession(ctx, diskBackup.UserCred, consts.GetRegion())
+				secKey, err := identity_modules.Credentials.GetEncryptKey(session, diskBackup.EncryptKeyId)
+				if err != nil {
+					return 0, false, errors.Wrap(err, "GetEncryptKey")
+				}
+				encKey = secKey.Key
+			}
+			if len(encKey) > 0 {
+				img.SetPassword(encKey)
+			}
+			newImage, err = img.Clone(backupPath, qemuimgfmt.QCOW2, true)
+			if err != nil {
+				return 0, false, errors.Wrap(err, "unable to backup snapshot")
 			}
-			encKey = secKey.Key
-		}
-		if len(encKey) > 0 {
-			img.SetPassword(encKey)
-		}
-		newImage, err := img.Clone(backupPath, qemuimgfmt.QCOW2, true)
-		if err != nil {
-			return 0, errors.Wrap(err, "unable to backup snapshot")
 		}
 
 		newImageSizeMb = newImage.GetActualSizeMB()
@@ -112,15 +128,30 @@ func doBackupDisk(ctx context.Context, snapshotPath string, diskBackup *SDiskBac
 
 	backupStorage, err := backupstorage.GetBackupStorage(diskBackup.BackupStorageId, diskBackup.BackupStorageAccessInfo)
 	if err != nil {
-		return 0, errors.Wrap(err, "GetBackupStorage")
+		return 0, false, errors.Wrap(err, "GetBackupStorage")
 	}
 
 	err = backupStorage.SaveBackupFrom(ctx, backupPath, diskBackup.BackupId)
 	if err != nil {
-		return 0, errors.Wrap(err, "SaveBackupFrom")
+		return 0, false, errors.Wrap(err, "SaveBackupFrom")
 	}
 
-	return newImageSizeMb, nil
+	return newImageSizeMb, incremental, nil
+}
+
+// backupSnapshotOverlay copies the clusters of the snapshot overlay alone,
+// the copy reads zero wherever the snapshot reads from its backing file
+// until it is rebased back onto a copy of the parent backup
+func backupSnapshotOverlay(img *qemuimg.SQemuImage, backupPath string) (*qemuimg.SQemuImage, error) {
+	newImage, err := img.Copy(backupPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to copy snapshot")
+	}
+	err = newImage.Rebase("", true)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to drop backing file")
+	}
+	return newImage, nil
 }
 
 type IDiskCreator interface {
@@ -311,72 +342,219 @@ func DoInstancePackBackup(ctx context.Context, backupInfo SStoragePackInstanceBa
 	return finalPackageName, nil
 }
 
-func DoInstanceUnpackBackup(ctx context.Context, backupInfo SStorageUnpackInstanceBackup) ([]string, *api.InstanceBackupPackMetadata, error) {
-	backupTmpDir, err := EnsureBackupDir()
+// backupPackageDirName returns the top directory in the package tarball and
+// the member name it is stored as. Packages are saved as <name>.tar, or
+// <name>-<n>.tar on conflict, while the tarball always holds the <name>
+// directory, the ./ entry and files at the root are skipped
+func backupPackageDirName(packageFilename string) (string, string, error) {
+	f, err := os.Open(packageFilename)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "EnsureBackupDir")
+		return "", "", errors.Wrap(err, "open")
 	}
-	defer CleanupDirOrFile(backupTmpDir)
-
-	packageName := backupInfo.PackageName
-	metadataOnly := false
-	if backupInfo.MetadataOnly != nil && *backupInfo.MetadataOnly {
-		metadataOnly = true
+	defer f.Close()
+	var dirName, member string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", errors.Wrap(err, "read tar header")
+		}
+		prefix := ""
+		if strings.HasPrefix(hdr.Name, "./") {
+			prefix = "./"
+		}
+		name := path.Clean(hdr.Name)
+		if name == "." {
+			continue
+		}
+		pos := strings.IndexByte(name, '/')
+		if pos < 0 && hdr.Typeflag != tar.TypeDir {
+			continue
+		}
+		if pos >= 0 {
+			name = name[:pos]
+		}
+		if name == "" || name == ".." {
+			return "", "", errors.Wrapf(errors.ErrInvalidFormat, "tar entry %s", hdr.Name)
+		}
+		if len(dirName) == 0 {
+			dirName = name
+			member = prefix + name
+		} else if dirName != name {
+			return "", "", errors.Wrapf(errors.ErrInvalidFormat, "multiple top directories %s and %s", dirName, name)
+		}
 	}
+	if len(dirName) == 0 {
+		return "", "", errors.Wrap(errors.ErrInvalidFormat, "no top directory")
+	}
+	return dirName, member, nil
+}
 
-	backupStorage, err := backupstorage.GetBackupStorage(backupInfo.BackupStorageId, backupInfo.BackupStorageAccessInfo)
+// unpackBackupPackage restores the package from the backup storage and
+// untars it under its own directory in tmpDir, the package directory and
+// the metadata are returned
+func unpackBackupPackage(ctx context.Context, backupStorage backupstorage.IBackupStorage, tmpDir, packageFilename string, metadataOnly bool) (string, *api.InstanceBackupPackMetadata, error) {
+	unpackDir, err := ioutil.TempDir(tmpDir, "package*")
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "GetBackupStorage")
+		return "", nil, errors.Wrap(err, "ioutil.TempDir")
 	}
-
-	packageFilename := path.Join(backupTmpDir, packageName+".tar")
-	err = backupStorage.RestoreBackupInstanceTo(ctx, packageFilename, backupInfo.PackageName)
+	packageFilePath := path.Join(unpackDir, packageFilename)
+	if !strings.HasSuffix(packageFilePath, ".tar") {
+		packageFilePath += ".tar"
+	}
+	err = backupStorage.RestoreBackupInstanceTo(ctx, packageFilePath, packageFilename)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "RestoreBackupInstanceTo")
+	}
+	packageName, packageMember, err := backupPackageDirName(packageFilePath)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "RestoreBackupInstanceTo")
+		return "", nil, errors.Wrapf(err, "backupPackageDirName %s", packageFilePath)
 	}
 
 	// untar to temp dir
-	packagePath := path.Join(backupTmpDir, packageName)
+	packagePath := path.Join(unpackDir, packageName)
 	log.Infof("unpack to %s", packagePath)
 	untarArgs := []string{
-		"-xf", packageFilename, "-C", backupTmpDir,
+		"-xf", packageFilePath, "-C", unpackDir,
 	}
 	if metadataOnly {
-		untarArgs = append(untarArgs, fmt.Sprintf("%s/metadata", packageName))
+		untarArgs = append(untarArgs, fmt.Sprintf("%s/metadata", packageMember))
 	} else {
-		untarArgs = append(untarArgs, packageName)
+		untarArgs = append(untarArgs, packageMember)
 	}
 	if output, err := procutils.NewCommand("tar", untarArgs...).Output(); err != nil {
-		log.Errorf("unable to 'tar -xf %s -C %s %s': %s", packageFilename, backupTmpDir, packageName, output)
-		return nil, nil, errors.Wrap(err, "unable to untar")
+		log.Errorf("unable to 'tar -xf %s -C %s %s': %s", packageFilePath, unpackDir, packageMember, output)
+		return "", nil, errors.Wrap(err, "unable to untar")
 	}
+	// the tarball is no longer needed once untarred
+	os.Remove(packageFilePath)
 
 	// unpack metadata
 	packageMetadataPath := path.Join(packagePath, PackageMetadataFilename)
 	metadataBytes, err := ioutil.ReadFile(packageMetadataPath)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "unable to read metadata file")
+		return "", nil, errors.Wrap(err, "unable to read metadata file")
 	}
 	metadataJson, err := jsonutils.Parse(metadataBytes)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "unable to parse string to json")
+		return "", nil, errors.Wrap(err, "unable to parse string to json")
 	}
 	metadata := &api.InstanceBackupPackMetadata{}
 	err = metadataJson.Unmarshal(metadata)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "unmarshal backup metadata")
+		return "", nil, errors.Wrap(err, "unmarshal backup metadata")
+	}
+	return packagePath, metadata, nil
+}
+
+type sUnpackedBackupPackage struct {
+	path     string
+	metadata *api.InstanceBackupPackMetadata
+}
+
+func packageDiskPath(packagePath string, index int) string {
+	return path.Join(packagePath, fmt.Sprintf("%s_%d", PackageDiskFilename, index))
+}
+
+// flattenIncrementalDisk rebuilds the full disk at index out of the chain of
+// parent packages of an incremental disk. Each incremental disk file is
+// rebased onto the disk file at the same index of its parent package and the
+// top one is converted into a standalone image. The parent packages are
+// unpacked once and kept in the given cache
+func flattenIncrementalDisk(ctx context.Context, backupStorage backupstorage.IBackupStorage, tmpDir string, top *sUnpackedBackupPackage, index int, parents map[string]*sUnpackedBackupPackage) (*qemuimg.SQemuImage, error) {
+	chain := []string{packageDiskPath(top.path, index)}
+	cur := top
+	for cur.metadata.DiskMetadatas[index].Incremental {
+		parentName := cur.metadata.ParentPackageName
+		if len(parentName) == 0 {
+			return nil, errors.Wrapf(errors.ErrInvalidFormat, "incremental disk %d without parent package", index)
+		}
+		parent, ok := parents[parentName]
+		if !ok {
+			parentPath, metadata, err := unpackBackupPackage(ctx, backupStorage, tmpDir, parentName, false)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unpack parent package %s", parentName)
+			}
+			parent = &sUnpackedBackupPackage{path: parentPath, metadata: metadata}
+			parents[parentName] = parent
+		}
+		if index >= len(parent.metadata.DiskMetadatas) {
+			return nil, errors.Wrapf(errors.ErrInvalidFormat, "parent package %s has no disk %d", parentName, index)
+		}
+		chain = append(chain, packageDiskPath(parent.path, index))
+		cur = parent
+	}
+	for i := 0; i < len(chain)-1; i++ {
+		img, err := qemuimg.NewQemuImage(chain[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "NewQemuImage %s", chain[i])
+		}
+		if img.BackFilePath == chain[i+1] {
+			continue
+		}
+		err = img.Rebase(chain[i+1], true)
+		if err != nil {
+			return nil, errors.Wrapf(err, "rebase %s onto %s", chain[i], chain[i+1])
+		}
+	}
+	img, err := qemuimg.NewQemuImage(chain[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "NewQemuImage %s", chain[0])
+	}
+	flatImg, err := img.Clone(chain[0]+".flat", qemuimgfmt.QCOW2, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "flatten %s", chain[0])
 	}
+	return flatImg, nil
+}
 
-	// copy disk files only if !metadataOnly
+func DoInstanceUnpackBackup(ctx context.Context, backupInfo SStorageUnpackInstanceBackup) ([]string, *api.InstanceBackupPackMetadata, error) {
+	backupTmpDir, err := EnsureBackupDir()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "EnsureBackupDir")
+	}
+	defer CleanupDirOrFile(backupTmpDir)
+
+	metadataOnly := false
+	if backupInfo.MetadataOnly != nil && *backupInfo.MetadataOnly {
+		metadataOnly = true
+	}
+
+	backupStorage, err := backupstorage.GetBackupStorage(backupInfo.BackupStorageId, backupInfo.BackupStorageAccessInfo)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "GetBackupStorage")
+	}
+
+	packagePath, metadata, err := unpackBackupPackage(ctx, backupStorage, backupTmpDir, backupInfo.PackageName, metadataOnly)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unpackBackupPackage %s", backupInfo.PackageName)
+	}
+
+	// copy disk files only if !metadataOnly, incremental disks are
+	// flattened with their parent packages into full backups
 	backupIds := make([]string, len(metadata.DiskMetadatas))
 	if !metadataOnly {
+		top := &sUnpackedBackupPackage{path: packagePath, metadata: metadata}
+		parents := map[string]*sUnpackedBackupPackage{}
 		for i := 0; i < len(metadata.DiskMetadatas); i++ {
 			backupId := db.DefaultUUIDGenerator()
 			backupIds[i] = backupId
-			packageDiskPath := path.Join(packagePath, fmt.Sprintf("%s_%d", PackageDiskFilename, i))
-			err := backupStorage.SaveBackupFrom(ctx, packageDiskPath, backupId)
+			diskPath := packageDiskPath(packagePath, i)
+			if metadata.DiskMetadatas[i].Incremental {
+				img, err := flattenIncrementalDisk(ctx, backupStorage, backupTmpDir, top, i, parents)
+				if err != nil {
+					return nil, nil, errors.Wrapf(err, "flatten disk %d", i)
+				}
+				diskPath = img.Path
+				metadata.DiskMetadatas[i].SizeMb = img.GetActualSizeMB()
+				metadata.DiskMetadatas[i].Incremental = false
+			}
+			err := backupStorage.SaveBackupFrom(ctx, diskPath, backupId)
 			if err != nil {
-				return nil, nil, errors.Wrapf(err, "SaveBackupFrom %s %s", packageDiskPath, backupId)
+				return nil, nil, errors.Wrapf(err, "SaveBackupFrom %s %s", diskPath, backupId)
 			}
 		}
 	}
@@ -0,0 +1,80 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupPackageDirName(t *testing.T) {
+	dir := t.TempDir()
+	writeTar := func(fn string, names ...string) string {
+		fp := filepath.Join(dir, fn)
+		f, err := os.Create(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		tw := tar.NewWriter(f)
+		for _, name := range names {
+			hdr := &tar.Header{Name: name, Mode: 0644}
+			if name[len(name)-1] == '/' {
+				hdr.Typeflag = tar.TypeDir
+				hdr.Mode = 0755
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return fp
+	}
+	cases := []struct {
+		fn      string
+		names   []string
+		want    string
+		member  string
+		wantErr bool
+	}{
+		{"pkg.tar", []string{"pkg/", "pkg/metadata", "pkg/disk_0"}, "pkg", "pkg", false},
+		{"pkg-1.tar", []string{"pkg/", "pkg/metadata"}, "pkg", "pkg", false},
+		{"dotted.tar", []string{"./pkg/metadata"}, "pkg", "./pkg", false},
+		{"dotdir.tar", []string{"./", "./pkg/", "./pkg/metadata"}, "pkg", "./pkg", false},
+		{"rootfile.tar", []string{"README", "pkg/", "pkg/metadata"}, "pkg", "pkg", false},
+		{"empty.tar", nil, "", "", true},
+		{"onlyfiles.tar", []string{"metadata", "disk_0"}, "", "", true},
+		{"parent.tar", []string{"../metadata"}, "", "", true},
+		{"multiple.tar", []string{"pkg/metadata", "other/metadata"}, "", "", true},
+	}
+	for _, c := range cases {
+		got, member, err := backupPackageDirName(writeTar(c.fn, c.names...))
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: expect error, got %s", c.fn, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.fn, err)
+		} else if got != c.want || member != c.member {
+			t.Errorf("%s: want %s(%s), got %s(%s)", c.fn, c.want, c.member, got, member)
+		}
+	}
+}
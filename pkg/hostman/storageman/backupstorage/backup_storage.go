@@ -54,6 +54,12 @@ type IBackupStorage interface {
 	IsOnline() (bool, string, error)
 }
 
+// IObjectSaver is implemented by backup storages able to save arbitrary
+// objects, e.g. object storage receiving vpc flow logs
+type IObjectSaver interface {
+	SaveObject(ctx context.Context, key string, content []byte) error
+}
+
 var factories []IBackupStorageFactory
 var backupStoragePool map[string]IBackupStorage
 var backupStorageLock *sync.Mutex
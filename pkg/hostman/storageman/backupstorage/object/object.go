@@ -15,6 +15,7 @@
 package object
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/url"
@@ -129,6 +130,18 @@ func (s *SObjectBackupStorage) saveObject(ctx context.Context, srcFilename strin
 	return nil
 }
 
+func (s *SObjectBackupStorage) SaveObject(ctx context.Context, key string, content []byte) error {
+	bucket, err := s.getBucket()
+	if err != nil {
+		return errors.Wrap(err, "getBucket")
+	}
+	err = cloudprovider.UploadObject(ctx, bucket, key, 0, bytes.NewReader(content), int64(len(content)), cloudprovider.ACLPrivate, "", nil, false)
+	if err != nil {
+		return errors.Wrapf(err, "UploadObject %s", key)
+	}
+	return nil
+}
+
 func (s *SObjectBackupStorage) RestoreBackupTo(ctx context.Context, targetFilename string, backupId string) error {
 	return s.restoreObject(ctx, targetFilename, backupId, s.getBackupKey)
 }
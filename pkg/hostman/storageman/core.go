@@ -497,7 +497,7 @@ func CleanImageCachefiles(ctx context.Context, userCred mcclient.TokenCredential
 	// }
 }
 
-func GatherHostStorageStats(reportSharedStorages []string) api.SHostPingInput {
+func GatherHostStorageStats(reportSharedStorages []string, withDiskStats bool) api.SHostPingInput {
 	stats := api.SHostPingInput{}
 	stats.RootPartitionUsedCapacityMb = GetRootPartUsedCapacity()
 	manager := GetManager()
@@ -515,6 +515,12 @@ func GatherHostStorageStats(reportSharedStorages []string) api.SHostPingInput {
 			log.Errorf("sync storage %s size failed: %s", iS.GetStorageName(), err)
 		} else {
 			stat.StorageId = iS.GetId()
+			if diskUsage, ok := iS.(IDiskUsageStorage); ok && withDiskStats {
+				stat.DiskStats, err = diskUsage.GetDisksActualUsage()
+				if err != nil {
+					log.Errorf("get disks actual usage of storage %s failed: %s", iS.GetStorageName(), err)
+				}
+			}
 			stats.StorageStats = append(stats.StorageStats, stat)
 		}
 	}
@@ -471,13 +471,21 @@ func (d *SLocalDisk) DiskBackup(ctx context.Context, params interface{}) (jsonut
 		snapshotPath = diskBackup.SnapshotLocation
 	}
 
-	size, err := doBackupDisk(ctx, snapshotPath, diskBackup)
+	parentPath := ""
+	if len(diskBackup.ParentSnapshotId) > 0 {
+		parentPath = path.Join(snapshotDir, diskBackup.ParentSnapshotId)
+	}
+
+	size, incremental, err := doBackupDisk(ctx, snapshotPath, parentPath, diskBackup)
 	if err != nil {
 		return nil, errors.Wrap(err, "doBackupDisk")
 	}
 
 	data := jsonutils.NewDict()
 	data.Set("size_mb", jsonutils.NewInt(int64(size)))
+	if incremental {
+		data.Set("incremental", jsonutils.JSONTrue)
+	}
 	return data, nil
 }
 
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"path"
 
 	"yunion.io/x/cloudmux/pkg/cloudprovider"
 	"yunion.io/x/jsonutils"
@@ -52,6 +53,8 @@ var (
 		"backup":              diskBackup,
 		"src-migrate-prepare": diskSrcMigratePrepare,
 		"migrate":             diskMigrate,
+		"replica-prepare":     diskReplicaPrepare,
+		"replica-stop":        diskReplicaStop,
 	}
 )
 
@@ -263,7 +266,7 @@ func performDiskActions(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	var err error
 
 	rebuild, _ := body.Bool("disk", "rebuild")
-	if !utils.IsInStringArray(action, []string{"create", "migrate"}) || rebuild {
+	if !utils.IsInStringArray(action, []string{"create", "migrate", "replica-prepare", "replica-stop"}) || rebuild {
 		disk, err = storage.GetDiskById(diskId)
 		if err != nil {
 			if errors.Cause(err) != cloudprovider.ErrNotFound || action != "delete" {
@@ -417,6 +420,42 @@ func diskMigrate(ctx context.Context, userCred mcclient.TokenCredential, storage
 	return nil, nil
 }
 
+func diskReplicaPrepare(ctx context.Context, userCred mcclient.TokenCredential, storage storageman.IStorage, diskId string, disk storageman.IDisk, body jsonutils.JSONObject) (interface{}, error) {
+	if !utils.IsInStringArray(storage.StorageType(), compute.STORAGE_LOCAL_TYPES) {
+		return nil, httperrors.NewBadRequestError("storage %s is not local storage", storage.GetId())
+	}
+	sizeMb, _ := body.Int("size_mb")
+	if sizeMb <= 0 {
+		return nil, httperrors.NewMissingParameterError("size_mb")
+	}
+	// a replica left by a former sync is reused
+	disk, err := storage.GetDiskById(diskId)
+	if err != nil {
+		disk = storage.CreateDisk(diskId)
+	}
+	params := &guestman.SDiskReplicaPrepare{
+		DiskId:   diskId,
+		DiskPath: disk.GetPath(),
+		SizeMb:   int(sizeMb),
+	}
+	hostutils.DelayTask(ctx, guestman.GetGuestManager().DiskReplicaPrepare, params)
+	return nil, nil
+}
+
+func diskReplicaStop(ctx context.Context, userCred mcclient.TokenCredential, storage storageman.IStorage, diskId string, disk storageman.IDisk, body jsonutils.JSONObject) (interface{}, error) {
+	deleteDisk := jsonutils.QueryBoolean(body, "delete", false)
+	diskPath := path.Join(storage.GetPath(), diskId)
+	if deleteDisk {
+		if disk, err := storage.GetDiskById(diskId); err == nil {
+			storage.RemoveDisk(disk)
+		}
+	}
+	if err := guestman.GetGuestManager().DiskReplicaStop(diskId, diskPath, deleteDisk); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
 func diskSnapshot(ctx context.Context, userCred mcclient.TokenCredential, storage storageman.IStorage, diskId string, disk storageman.IDisk, body jsonutils.JSONObject) (interface{}, error) {
 	snapshotId, err := body.GetString("snapshot_id")
 	if err != nil {
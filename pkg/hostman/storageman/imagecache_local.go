@@ -223,6 +223,21 @@ func (l *SLocalImageCache) fetch(ctx context.Context, input api.CacheImageInput,
 		l.cond.L.Unlock()
 	}()
 	var _fetch = func() error {
+		desc, err := l.remoteFile.GetInfo()
+		if err != nil {
+			return errors.Wrapf(err, "remoteFile.GetInfo")
+		}
+		chksum := desc.Chksum
+		if len(chksum) == 0 {
+			chksum = input.Checksum
+		}
+		err = verifyImageSignature(ctx, l.imageId, input.Format, l.GetPath(), chksum)
+		if err != nil {
+			if rmErr := os.Remove(l.GetPath()); rmErr != nil {
+				log.Errorf("remove untrusted image cache %s: %s", l.GetPath(), rmErr)
+			}
+			return err
+		}
 		if len(l.Manager.GetId()) > 0 {
 			_, err := hostutils.RemoteStoragecacheCacheImage(ctx,
 				l.Manager.GetId(), l.imageId, "active", l.GetPath())
@@ -233,11 +248,7 @@ func (l *SLocalImageCache) fetch(ctx context.Context, input api.CacheImageInput,
 		l.cond.L.Lock()
 		defer l.cond.L.Unlock()
 
-		var err error
-		l.Desc, err = l.remoteFile.GetInfo()
-		if err != nil {
-			return errors.Wrapf(err, "remoteFile.GetInfo")
-		}
+		l.Desc = desc
 		fi := l.getFileInfo()
 		if fi != nil {
 			l.Size = fi.Size() / 1024 / 1024
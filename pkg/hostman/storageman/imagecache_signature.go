@@ -0,0 +1,101 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storageman
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/hostman/hostutils"
+	"yunion.io/x/onecloud/pkg/hostman/options"
+	image_modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
+	"yunion.io/x/onecloud/pkg/util/fileutils2"
+	"yunion.io/x/onecloud/pkg/util/imagesign"
+)
+
+// verifyImageSignature checks the signature of a fetched image against
+// its trusted signer key before the image cache becomes usable.  The
+// signature covers the checksum of the original image, so the local file
+// must match it when the original format is cached.  Subformats are
+// converted from the verified original by the image service
+func verifyImageSignature(ctx context.Context, imageId, format, localPath, chksum string) error {
+	policy := options.HostOptions.ImageSignaturePolicy
+	if len(policy) == 0 || policy == imagesign.POLICY_NONE {
+		return nil
+	}
+	err := func() error {
+		s := hostutils.GetImageSession(ctx)
+		query := jsonutils.NewDict()
+		query.Set("scope", jsonutils.NewString("system"))
+		meta, err := image_modules.Images.GetById(s, imageId, query)
+		if err != nil {
+			return errors.Wrap(err, "get image")
+		}
+		signature, _ := meta.GetString("signature")
+		if len(signature) == 0 {
+			return errors.Wrap(errors.ErrNotFound, "image is not signed")
+		}
+		keyId, _ := meta.GetString("signer_key_id")
+		if len(keyId) == 0 {
+			return errors.Wrap(errors.ErrNotFound, "image has no signer key")
+		}
+		key, err := image_modules.ImageSignerKeys.Get(s, keyId, query)
+		if err != nil {
+			return errors.Wrapf(err, "get signer key %s", keyId)
+		}
+		if !jsonutils.QueryBoolean(key, "enabled", false) {
+			return errors.Wrapf(errors.ErrInvalidStatus, "signer key %s is disabled", keyId)
+		}
+		domainId, _ := meta.GetString("domain_id")
+		keyDomainId, _ := key.GetString("domain_id")
+		if domainId != keyDomainId {
+			return errors.Wrapf(errors.ErrInvalidStatus, "signer key %s is not trusted in domain %s", keyId, domainId)
+		}
+		pemStr, _ := key.GetString("public_key")
+		pub, err := imagesign.ParsePublicKey(pemStr)
+		if err != nil {
+			return errors.Wrapf(err, "parse public key of signer key %s", keyId)
+		}
+		checksum, _ := meta.GetString("checksum")
+		if err := pub.Verify(checksum, signature); err != nil {
+			return err
+		}
+		diskFormat, _ := meta.GetString("disk_format")
+		if format != diskFormat {
+			return nil
+		}
+		if len(chksum) == 0 {
+			chksum, err = fileutils2.MD5(localPath)
+			if err != nil {
+				return errors.Wrapf(err, "md5 %s", localPath)
+			}
+		}
+		if chksum != checksum {
+			return errors.Wrapf(imagesign.ErrSignatureInvalid, "local checksum %s != signed checksum %s", chksum, checksum)
+		}
+		return nil
+	}()
+	if err == nil {
+		return nil
+	}
+	if policy == imagesign.POLICY_WARN {
+		log.Warningf("image %s signature verification fail: %s", imageId, err)
+		return nil
+	}
+	return errors.Wrapf(err, "verify signature of image %s", imageId)
+}
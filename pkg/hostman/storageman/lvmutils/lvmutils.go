@@ -190,6 +190,88 @@ func GetVgProps(vg string) (*VgProps, error) {
 	}
 }
 
+type LvUsage struct {
+	Name        string
+	SegType     string
+	SizeBytes   int64
+	DataPercent float64
+}
+
+// ActualSizeBytes returns the space the lv really takes, thin lvs and thin
+// pools only hold the data percent of their size
+func (lv LvUsage) ActualSizeBytes() int64 {
+	if lv.SegType == "thin" || lv.SegType == "thin-pool" {
+		return int64(float64(lv.SizeBytes) * lv.DataPercent / 100)
+	}
+	return lv.SizeBytes
+}
+
+type LvUsageReports struct {
+	Report []struct {
+		LV []struct {
+			LvName      string `json:"lv_name"`
+			SegType     string `json:"segtype"`
+			LvSize      string `json:"lv_size"`
+			DataPercent string `json:"data_percent"`
+		} `json:"lv"`
+	} `json:"report"`
+}
+
+func GetLvUsages(vg string) ([]LvUsage, error) {
+	cmd := fmt.Sprintf("lvm lvs --reportformat json -o lv_name,segtype,lv_size,data_percent --units=B %s 2>/dev/null", vg)
+	out, err := procutils.NewRemoteCommandAsFarAsPossible("bash", "-c", cmd).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "exec lvm command %s: %s", cmd, out)
+	}
+	var reports LvUsageReports
+	err = json.Unmarshal(out, &reports)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unmarshal lvs %s", out)
+	}
+	if len(reports.Report) != 1 {
+		return nil, errors.Errorf("unexpect res %v", reports)
+	}
+	ret := make([]LvUsage, 0, len(reports.Report[0].LV))
+	for _, lv := range reports.Report[0].LV {
+		usage := LvUsage{
+			Name:    lv.LvName,
+			SegType: lv.SegType,
+		}
+		usage.SizeBytes, err = strconv.ParseInt(strings.TrimSuffix(lv.LvSize, "B"), 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse size %s of lv %s", lv.LvSize, lv.LvName)
+		}
+		if len(lv.DataPercent) > 0 {
+			usage.DataPercent, err = strconv.ParseFloat(lv.DataPercent, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse data percent %s of lv %s", lv.DataPercent, lv.LvName)
+			}
+		}
+		ret = append(ret, usage)
+	}
+	return ret, nil
+}
+
+// GetVgActualUsedSize returns the used size of vg in bytes, the unused part
+// of thin pools is not counted as used
+func GetVgActualUsedSize(vg string) (int64, error) {
+	vgProps, err := GetVgProps(vg)
+	if err != nil {
+		return -1, err
+	}
+	lvs, err := GetLvUsages(vg)
+	if err != nil {
+		return -1, err
+	}
+	used := vgProps.VgSize - vgProps.VgFree
+	for _, lv := range lvs {
+		if lv.SegType == "thin-pool" {
+			used -= lv.SizeBytes - lv.ActualSizeBytes()
+		}
+	}
+	return used, nil
+}
+
 func ExtendLvSize(vg string, size int64) (int64, error) {
 	vgProps, err := GetVgProps(vg)
 	if err != nil {
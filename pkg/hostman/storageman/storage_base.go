@@ -152,6 +152,12 @@ type IStorage interface {
 	CleanRecycleDiskfiles(ctx context.Context)
 }
 
+// IDiskUsageStorage is implemented by thin provisioned storages able to
+// report the space actually consumed by each disk
+type IDiskUsageStorage interface {
+	GetDisksActualUsage() ([]api.SDiskActualUsage, error)
+}
+
 type SBaseStorage struct {
 	Manager          *SStorageManager
 	StorageId        string
@@ -278,6 +278,28 @@ func (s *SLocalStorage) getHardwareInfo() (*api.StorageHardwareInfo, error) {
 	return ret, errors.NewAggregate(errs)
 }
 
+// GetDisksActualUsage reports the space allocated by each disk file, i.e.
+// the actual size of qemu-img info
+func (s *SLocalStorage) GetDisksActualUsage() ([]api.SDiskActualUsage, error) {
+	disksPath, err := s.GetDisksPath()
+	if err != nil {
+		return nil, errors.Wrap(err, "GetDisksPath")
+	}
+	ret := make([]api.SDiskActualUsage, 0, len(disksPath))
+	for _, diskPath := range disksPath {
+		img, err := qemuimg.NewQemuImage(diskPath)
+		if err != nil {
+			log.Errorf("open disk %s failed: %s", diskPath, err)
+			continue
+		}
+		ret = append(ret, api.SDiskActualUsage{
+			DiskId:       path.Base(diskPath),
+			ActualSizeMb: int64(img.GetActualSizeMB()),
+		})
+	}
+	return ret, nil
+}
+
 func (s *SLocalStorage) SyncStorageInfo() (jsonutils.JSONObject, error) {
 	content := jsonutils.NewDict()
 	name := s.GetName(s.GetComposedName)
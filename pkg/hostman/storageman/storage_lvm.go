@@ -117,10 +117,34 @@ func (s *SLVMStorage) SyncStorageSize() (api.SHostStorageStat, error) {
 		return stat, err
 	}
 	stat.CapacityMb = sizeMb
-	stat.ActualCapacityUsedMb = 0
+	usedSize, err := lvmutils.GetVgActualUsedSize(s.GetPath())
+	if err != nil {
+		return stat, err
+	}
+	stat.ActualCapacityUsedMb = usedSize / 1024 / 1024
 	return stat, nil
 }
 
+// GetDisksActualUsage reports the size of disk lvs, thin lvs only count
+// the data they really hold in the thin pool
+func (s *SLVMStorage) GetDisksActualUsage() ([]api.SDiskActualUsage, error) {
+	lvs, err := lvmutils.GetLvUsages(s.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]api.SDiskActualUsage, 0, len(lvs))
+	for _, lv := range lvs {
+		if !regutils.MatchUUIDExact(lv.Name) {
+			continue
+		}
+		ret = append(ret, api.SDiskActualUsage{
+			DiskId:       lv.Name,
+			ActualSizeMb: lv.ActualSizeBytes() / 1024 / 1024,
+		})
+	}
+	return ret, nil
+}
+
 func (s *SLVMStorage) SyncStorageInfo() (jsonutils.JSONObject, error) {
 	content := jsonutils.NewDict()
 	name := s.GetName(s.GetComposedName)
@@ -25,6 +25,7 @@ import (
 	"yunion.io/x/pkg/errors"
 	"yunion.io/x/pkg/gotypes"
 	"yunion.io/x/pkg/util/qemuimgfmt"
+	"yunion.io/x/pkg/util/regutils"
 	"yunion.io/x/pkg/utils"
 
 	apis2 "yunion.io/x/onecloud/pkg/apis"
@@ -332,7 +333,7 @@ func (s *SRbdStorage) createBackup(ctx context.Context, diskId string, diskBacku
 
 	srcPath := fmt.Sprintf("rbd:%s%s", backupImg.GetName(), s.getStorageConfString())
 
-	size, err := doBackupDisk(ctx, srcPath, diskBackup)
+	size, _, err := doBackupDisk(ctx, srcPath, "", diskBackup)
 	if err != nil {
 		return 0, errors.Wrap(err, "doBackupDisk")
 	}
@@ -390,6 +391,31 @@ func (s *SRbdStorage) SyncStorageSize() (api.SHostStorageStat, error) {
 	return stat, nil
 }
 
+// GetDisksActualUsage reports the space used by disk images and their
+// snapshots as shown by rbd du
+func (s *SRbdStorage) GetDisksActualUsage() ([]api.SDiskActualUsage, error) {
+	client, err := s.getClient()
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetClient")
+	}
+	defer client.Close()
+	usages, err := client.DiskUsage()
+	if err != nil {
+		return nil, errors.Wrapf(err, "DiskUsage")
+	}
+	ret := make([]api.SDiskActualUsage, 0, len(usages))
+	for name, usage := range usages {
+		if !regutils.MatchUUIDExact(name) {
+			continue
+		}
+		ret = append(ret, api.SDiskActualUsage{
+			DiskId:       name,
+			ActualSizeMb: usage.UsedSizeByte / 1024 / 1024,
+		})
+	}
+	return ret, nil
+}
+
 func (s *SRbdStorage) GetCapacityMb() int {
 	capa, err := s.getRbdCapacity()
 	if err != nil {
@@ -81,6 +81,9 @@ func AddStorageHandler(prefix string, app *appsrv.Application) {
 		app.AddHandler("POST",
 			fmt.Sprintf("%s/%s/unpack-instance-backup", prefix, keyWords),
 			auth.Authenticate(storageUnpackInstanceBackup))
+		app.AddHandler("POST",
+			fmt.Sprintf("%s/%s/delete-instance-backup-package", prefix, keyWords),
+			auth.Authenticate(storageDeleteInstanceBackupPackage))
 		app.AddHandler("POST",
 			fmt.Sprintf("%s/%s/sync-backup-storage", prefix, keyWords),
 			auth.Authenticate(storageSyncBackupStorage))
@@ -376,6 +379,42 @@ func packInstanceBackup(ctx context.Context, params interface{}) (jsonutils.JSON
 	return ret, nil
 }
 
+func storageDeleteInstanceBackupPackage(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	_, _, body := appsrv.FetchEnv(ctx, w, r)
+	if !checkOptions(ctx, w, body, "package_name", "backup_storage_id", "backup_storage_access_info") {
+		return
+	}
+	pb := storageman.SStorageDeleteInstanceBackupPackage{}
+	err := body.Unmarshal(&pb)
+	if err != nil {
+		hostutils.Response(ctx, w, httperrors.NewInputParameterError(err.Error()))
+		return
+	}
+
+	hostutils.DelayTask(ctx, deleteInstanceBackupPackage, &pb)
+	hostutils.ResponseOk(ctx, w)
+}
+
+func deleteInstanceBackupPackage(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
+	sbParams := params.(*storageman.SStorageDeleteInstanceBackupPackage)
+	backupStorage, err := backupstorage.GetBackupStorage(sbParams.BackupStorageId, sbParams.BackupStorageAccessInfo)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := backupStorage.IsBackupInstanceExists(sbParams.PackageName)
+	if err != nil {
+		return nil, errors.Wrap(err, "IsBackupInstanceExists")
+	}
+	if !exists {
+		return nil, nil
+	}
+	err = backupStorage.RemoveBackupInstance(ctx, sbParams.PackageName)
+	if err != nil {
+		return nil, errors.Wrap(err, "RemoveBackupInstance")
+	}
+	return nil, nil
+}
+
 func unpackInstanceBackup(ctx context.Context, params interface{}) (jsonutils.JSONObject, error) {
 	sbParams := params.(*storageman.SStorageUnpackInstanceBackup)
 
@@ -84,6 +84,11 @@ type SDiskBackup struct {
 
 	EncryptKeyId string `json:"encrypt_key_id"`
 
+	// ParentSnapshotId is the snapshot the previous backup of the disk was
+	// taken from, only the data written after it is backed up when the
+	// snapshot is still right on top of it
+	ParentSnapshotId string `json:"parent_snapshot_id"`
+
 	UserCred mcclient.TokenCredential
 }
 
@@ -117,6 +122,12 @@ type SStorageUnpackInstanceBackup struct {
 	MetadataOnly            *bool
 }
 
+type SStorageDeleteInstanceBackupPackage struct {
+	PackageName             string
+	BackupStorageId         string
+	BackupStorageAccessInfo *jsonutils.JSONDict
+}
+
 type SStorageSaveToGlanceInfo struct {
 	UserCred mcclient.TokenCredential
 	DiskInfo *jsonutils.JSONDict
@@ -74,6 +74,9 @@ type SGuestImage struct {
 	db.SEncryptedResource
 
 	Protected tristate.TriState `default:"true" list:"user" get:"user" create:"optional" update:"user"`
+
+	// 导出 OVA 的状态
+	OvaStatus string `width:"16" charset:"ascii" nullable:"true" list:"user" get:"user"`
 }
 
 func (manager *SGuestImageManager) ValidateCreateData(ctx context.Context, userCred mcclient.TokenCredential,
@@ -88,6 +91,18 @@ func (manager *SGuestImageManager) ValidateCreateData(ctx context.Context, userC
 		return input, errors.Wrap(err, "SEncryptedResourceManager.ValidateCreateData")
 	}
 
+	if len(input.OvaUrl) > 0 {
+		if len(input.Images) > 0 {
+			return input, httperrors.NewConflictError("images and ova_url are mutually exclusive")
+		}
+		if input.NeedEncrypt() {
+			return input, httperrors.NewNotSupportedError("encrypted guest image imported from ova")
+		}
+		// pending quota of sub images is checked when the ova descriptor is read
+		input.DiskFormat = string(qemuimgfmt.QCOW2)
+		return input, nil
+	}
+
 	imageNum := len(input.Images)
 	if imageNum == 0 {
 		return input, httperrors.NewMissingParameterError("images")
@@ -159,6 +174,13 @@ func (gi *SGuestImage) PostCreate(ctx context.Context, userCred mcclient.TokenCr
 	kwargs.Remove("image_number")
 	kwargs.Remove("name")
 	kwargs.Remove("generate_name")
+	if ovaUrl, _ := kwargs.GetString("ova_url"); len(ovaUrl) > 0 {
+		err := gi.startImportOvaTask(ctx, userCred, ovaUrl)
+		if err != nil {
+			gi.SetStatus(ctx, userCred, api.IMAGE_STATUS_KILLED, fmt.Sprintf("start import ova task fail %s", err))
+		}
+		return
+	}
 	if !kwargs.Contains("images") {
 		return
 	}
@@ -270,6 +292,7 @@ func (gi *SGuestImage) RealDelete(ctx context.Context, userCred mcclient.TokenCr
 	for i := range guestJoints {
 		guestJoints[i].Delete(ctx, userCred)
 	}
+	gi.removeOva()
 	return gi.SSharableVirtualResourceBase.Delete(ctx, userCred)
 }
 
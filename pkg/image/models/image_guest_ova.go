@@ -0,0 +1,462 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/qemuimgfmt"
+	"yunion.io/x/pkg/util/streamutils"
+	"yunion.io/x/pkg/utils"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	"yunion.io/x/onecloud/pkg/mcclient/modules/compute"
+	"yunion.io/x/onecloud/pkg/util/ovfutils"
+)
+
+func (gi *SGuestImage) GetOvaPath() string {
+	return filepath.Join(options.Options.FilesystemStoreDatadir, gi.Id+"."+api.GUEST_IMAGE_FORMAT_OVA)
+}
+
+func (gi *SGuestImage) startImportOvaTask(ctx context.Context, userCred mcclient.TokenCredential, ovaUrl string) error {
+	params := jsonutils.NewDict()
+	params.Set("ova_url", jsonutils.NewString(ovaUrl))
+	gi.SetStatus(ctx, userCred, api.IMAGE_STATUS_SAVING, "import from ova")
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestImageImportOvaTask", gi, userCred, params, "", "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+// ImportOva creates sub images of guest image from disks of the ova read
+// from reader, and returns the parsed ovf descriptor
+func (gi *SGuestImage) ImportOva(ctx context.Context, userCred mcclient.TokenCredential, reader io.Reader) (*ovfutils.SVirtualAppliance, error) {
+	var (
+		app    *ovfutils.SVirtualAppliance
+		images map[string]*SImage
+	)
+	err := ovfutils.ReadOva(reader, func(desc *ovfutils.SVirtualAppliance) error {
+		app = desc
+		var err error
+		images, err = gi.createOvaSubImages(ctx, userCred, desc)
+		return err
+	}, func(disk *ovfutils.SDisk, r io.Reader, size int64, verify func() error) error {
+		image := images[disk.Id]
+		image.SetStatus(ctx, userCred, api.IMAGE_STATUS_SAVING, "import from ova")
+		err := image.SaveImageFromStream(r, size, false)
+		if err == nil {
+			err = verify()
+		}
+		if err == nil {
+			err = image.convertToQcow2()
+		}
+		if err != nil {
+			image.OnSaveFailed(ctx, userCred, jsonutils.NewString(fmt.Sprintf("import %s from ova fail %s", disk.FileHref, err)))
+			return errors.Wrapf(err, "import %s", disk.FileHref)
+		}
+		image.OnSaveSuccess(ctx, userCred, "import from ova success")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, disk := range app.Disks {
+		if err := images[disk.Id].Pipeline(ctx, userCred, false); err != nil {
+			return nil, errors.Wrapf(err, "pipeline of %s", images[disk.Id].Name)
+		}
+	}
+	return app, gi.checkStatus(ctx, userCred)
+}
+
+// createOvaSubImages creates a sub image for each disk of the appliance,
+// the first disk becomes the root image
+func (gi *SGuestImage) createOvaSubImages(ctx context.Context, userCred mcclient.TokenCredential, app *ovfutils.SVirtualAppliance) (map[string]*SImage, error) {
+	ownerId := gi.GetOwnerId()
+	pendingUsage := SQuota{Image: len(app.Disks)}
+	keys := imageCreateInput2QuotaKeys(string(qemuimgfmt.QCOW2), ownerId)
+	pendingUsage.SetKeys(keys)
+	if err := quotas.CheckSetPendingQuota(ctx, userCred, &pendingUsage); err != nil {
+		return nil, httperrors.NewOutOfQuotaError("%s", err)
+	}
+	defer quotas.CancelPendingUsage(ctx, userCred, &pendingUsage, &pendingUsage, true)
+
+	images := map[string]*SImage{}
+	for i, disk := range app.Disks {
+		isData := i > 0
+		input := api.ImageCreateInput{
+			DiskFormat:   string(qemuimgfmt.VMDK),
+			IsGuestImage: &[]bool{true}[0],
+			IsData:       &isData,
+			Properties:   ovaImageProperties(app, disk),
+		}
+		input.GenerateName = fmt.Sprintf("%s-%s", gi.Name, "root")
+		if isData {
+			input.GenerateName = fmt.Sprintf("%s-%s-%d", gi.Name, "data", i)
+		} else if app.MemoryMB > 0 {
+			minRam := int32(app.MemoryMB)
+			input.MinRamMB = &minRam
+		}
+		model, err := db.DoCreate(ImageManager, ctx, userCred, nil, jsonutils.Marshal(input), ownerId)
+		if err != nil {
+			return nil, errors.Wrapf(err, "create image of disk %s", disk.Id)
+		}
+		image := model.(*SImage)
+		err = ImagePropertyManager.SaveProperties(ctx, userCred, image.Id, jsonutils.Marshal(input.Properties))
+		if err != nil {
+			log.Warningf("save properties of %s error %s", image.Name, err)
+		}
+		if _, err := GuestImageJointManager.CreateGuestImageJoint(ctx, gi.Id, image.Id); err != nil {
+			image.OnJointFailed(ctx, userCred)
+			return nil, errors.Wrapf(err, "join image %s", image.Name)
+		}
+		images[disk.Id] = image
+	}
+	return images, nil
+}
+
+func ovaImageProperties(app *ovfutils.SVirtualAppliance, disk ovfutils.SDisk) map[string]string {
+	props := map[string]string{}
+	if len(app.OsType) > 0 {
+		props[api.IMAGE_OS_TYPE] = app.OsType
+	}
+	if app.Firmware == ovfutils.FIRMWARE_EFI {
+		props[api.IMAGE_UEFI_SUPPORT] = "true"
+	}
+	if driver := ovaDiskDriver(disk); len(driver) > 0 {
+		props["disk_driver"] = driver
+	}
+	if len(app.Nics) > 0 {
+		if driver := ovaNetDriver(app.Nics[0]); len(driver) > 0 {
+			props["net_driver"] = driver
+		}
+	}
+	return props
+}
+
+func ovaDiskDriver(disk ovfutils.SDisk) string {
+	switch disk.Controller {
+	case ovfutils.CONTROLLER_IDE:
+		return computeapi.DISK_DRIVER_IDE
+	case ovfutils.CONTROLLER_SATA:
+		return computeapi.DISK_DRIVER_SATA
+	case ovfutils.CONTROLLER_SCSI:
+		if strings.EqualFold(disk.ControllerModel, "VirtualSCSI") {
+			return computeapi.DISK_DRIVER_PVSCSI
+		}
+		return computeapi.DISK_DRIVER_SCSI
+	}
+	return ""
+}
+
+func ovaNetDriver(nic ovfutils.SNic) string {
+	model := strings.ToLower(nic.Model)
+	switch {
+	case strings.HasPrefix(model, "e1000"):
+		return computeapi.NETWORK_DRIVER_E1000
+	case strings.HasPrefix(model, "vmxnet"):
+		return computeapi.NETWORK_DRIVER_VMXNET3
+	case strings.Contains(model, "virtio"):
+		return computeapi.NETWORK_DRIVER_VIRTIO
+	}
+	return ""
+}
+
+// convertToQcow2 converts the saved image file in place, as sub images of
+// guest image are not converted by the image pipeline
+func (self *SImage) convertToQcow2() error {
+	img, err := self.getQemuImage()
+	if err != nil {
+		return errors.Wrap(err, "getQemuImage")
+	}
+	if img.Format == qemuimgfmt.QCOW2 {
+		return nil
+	}
+	if err := img.Convert2Qcow2(true, "", "", ""); err != nil {
+		return errors.Wrap(err, "Convert2Qcow2")
+	}
+	fi, err := os.Stat(img.Path)
+	if err != nil {
+		return errors.Wrap(err, "stat")
+	}
+	_, err = db.Update(self, func() error {
+		self.DiskFormat = string(qemuimgfmt.QCOW2)
+		self.Size = fi.Size()
+		self.MinDiskMB = int32(math.Ceil(float64(img.SizeBytes) / 1024 / 1024))
+		return nil
+	})
+	return err
+}
+
+// CreateGuestTemplate creates a server template booting from the guest
+// image with the hardware described by the appliance
+func (gi *SGuestImage) CreateGuestTemplate(ctx context.Context, userCred mcclient.TokenCredential, app *ovfutils.SVirtualAppliance) error {
+	input := computeapi.ServerCreateInput{
+		ServerConfigs: &computeapi.ServerConfigs{
+			Hypervisor: computeapi.HYPERVISOR_KVM,
+		},
+		VcpuCount:    app.CpuCount,
+		VmemSize:     app.MemoryMB,
+		OsType:       app.OsType,
+		GuestImageID: gi.Id,
+	}
+	if app.Firmware == ovfutils.FIRMWARE_EFI {
+		input.Bios = "UEFI"
+	}
+	for _, disk := range app.Disks {
+		input.Disks = append(input.Disks, &computeapi.DiskConfig{
+			SizeMb: int(math.Ceil(float64(disk.CapacityBytes) / 1024 / 1024)),
+			Driver: ovaDiskDriver(disk),
+		})
+	}
+	for _, nic := range app.Nics {
+		input.Networks = append(input.Networks, &computeapi.NetworkConfig{
+			Driver: ovaNetDriver(nic),
+		})
+	}
+	params := jsonutils.NewDict()
+	params.Set("generate_name", jsonutils.NewString(gi.Name))
+	params.Set("description", jsonutils.NewString(fmt.Sprintf("imported from ova %s", app.Name)))
+	params.Set("content", jsonutils.Marshal(input))
+	s := auth.GetSession(ctx, userCred, options.Options.Region)
+	_, err := compute.GuestTemplate.Create(s, params)
+	if err != nil {
+		return errors.Wrap(err, "create guest template")
+	}
+	return nil
+}
+
+func (gi *SGuestImage) PerformExportOva(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject, input api.GuestImageExportOvaInput) (jsonutils.JSONObject, error) {
+	if gi.Status != api.IMAGE_STATUS_ACTIVE {
+		return nil, httperrors.NewInvalidStatusError("cannot export guest image in status %s", gi.Status)
+	}
+	if gi.OvaStatus == api.GUEST_IMAGE_OVA_STATUS_EXPORTING {
+		return nil, httperrors.NewInvalidStatusError("guest image is being exported")
+	}
+	images, err := gi.getOrderedSubImages()
+	if err != nil {
+		return nil, errors.Wrap(err, "getOrderedSubImages")
+	}
+	for i := range images {
+		if images[i].isEncrypted() {
+			return nil, httperrors.NewNotSupportedError("export encrypted image %s to ova", images[i].Name)
+		}
+	}
+	return nil, gi.startExportOvaTask(ctx, userCred)
+}
+
+func (gi *SGuestImage) startExportOvaTask(ctx context.Context, userCred mcclient.TokenCredential) error {
+	gi.SetOvaStatus(ctx, userCred, api.GUEST_IMAGE_OVA_STATUS_EXPORTING, "")
+	task, err := taskman.TaskManager.NewTask(ctx, "GuestImageExportOvaTask", gi, userCred, nil, "", "", nil)
+	if err != nil {
+		return err
+	}
+	task.ScheduleRun(nil)
+	return nil
+}
+
+func (gi *SGuestImage) SetOvaStatus(ctx context.Context, userCred mcclient.TokenCredential, status string, reason string) error {
+	_, err := db.Update(gi, func() error {
+		gi.OvaStatus = status
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	db.OpsLog.LogEvent(gi, db.ACT_UPDATE_STATUS, fmt.Sprintf("ova %s %s", status, reason), userCred)
+	return nil
+}
+
+// getOrderedSubImages returns the root image followed by data images
+func (gi *SGuestImage) getOrderedSubImages() ([]SImage, error) {
+	joints, err := GuestImageJointManager.GetByGuestImageId(gi.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "GetByGuestImageId")
+	}
+	var root *SImage
+	datas := []SImage{}
+	for i := range joints {
+		image, err := joints[i].GetImage()
+		if err != nil {
+			return nil, errors.Wrapf(err, "GetImage of joint %d", i)
+		}
+		if !image.IsData.IsTrue() && root == nil {
+			root = image
+			continue
+		}
+		datas = append(datas, *image)
+	}
+	if root == nil {
+		return nil, errors.Wrap(errors.ErrNotFound, "root image")
+	}
+	sort.Slice(datas, func(i, j int) bool {
+		return datas[i].Name < datas[j].Name
+	})
+	return append([]SImage{*root}, datas...), nil
+}
+
+// ExportOva converts sub images to streamOptimized vmdk and packs them
+// together with an ovf descriptor as an ova at GetOvaPath()
+func (gi *SGuestImage) ExportOva(ctx context.Context, userCred mcclient.TokenCredential) error {
+	images, err := gi.getOrderedSubImages()
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(options.Options.FilesystemStoreDatadir, gi.Id+"-ova-")
+	if err != nil {
+		return errors.Wrap(err, "MkdirTemp")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	props, err := ImagePropertyManager.GetProperties(images[0].Id)
+	if err != nil {
+		return errors.Wrap(err, "GetProperties")
+	}
+	app := &ovfutils.SVirtualAppliance{
+		Name:     gi.Name,
+		OsType:   props[api.IMAGE_OS_TYPE],
+		OsDesc:   strings.TrimSpace(props[api.IMAGE_OS_DISTRO] + " " + props[api.IMAGE_OS_VERSION]),
+		CpuCount: 1,
+		MemoryMB: int(images[0].MinRamMB),
+		Firmware: ovfutils.FIRMWARE_BIOS,
+		Nics:     []ovfutils.SNic{{Model: ovaNicModel(props["net_driver"])}},
+	}
+	if app.MemoryMB <= 0 {
+		app.MemoryMB = 1024
+	}
+	if uefi, _ := strconv.ParseBool(props[api.IMAGE_UEFI_SUPPORT]); uefi {
+		app.Firmware = ovfutils.FIRMWARE_EFI
+	}
+	diskPaths := make([]string, len(images))
+	for i := range images {
+		src, err := images[i].getQemuImage()
+		if err != nil {
+			return errors.Wrapf(err, "open %s", images[i].Name)
+		}
+		href := fmt.Sprintf("%s-disk%d.vmdk", gi.Id, i+1)
+		diskPaths[i] = filepath.Join(tmpDir, href)
+		if _, err := src.CloneVmdk(diskPaths[i], true); err != nil {
+			return errors.Wrapf(err, "convert %s to vmdk", images[i].Name)
+		}
+		disk := ovfutils.SDisk{
+			Id:            fmt.Sprintf("vmdisk%d", i+1),
+			FileHref:      href,
+			CapacityBytes: src.SizeBytes,
+			Controller:    ovfutils.CONTROLLER_SCSI,
+		}
+		if i == 0 && utils.IsInStringArray(props["disk_driver"], []string{computeapi.DISK_DRIVER_IDE, computeapi.DISK_DRIVER_SATA}) {
+			disk.Controller = props["disk_driver"]
+		}
+		app.Disks = append(app.Disks, disk)
+	}
+
+	ovaPath := gi.GetOvaPath()
+	tmpOva := filepath.Join(tmpDir, filepath.Base(ovaPath))
+	f, err := os.Create(tmpOva)
+	if err != nil {
+		return errors.Wrap(err, "create ova")
+	}
+	err = ovfutils.WriteOva(f, app, diskPaths)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return errors.Wrap(err, "WriteOva")
+	}
+	return os.Rename(tmpOva, ovaPath)
+}
+
+func ovaNicModel(netDriver string) string {
+	switch netDriver {
+	case computeapi.NETWORK_DRIVER_VMXNET3:
+		return "VmxNet3"
+	case computeapi.NETWORK_DRIVER_VIRTIO:
+		return "virtio"
+	}
+	return "E1000"
+}
+
+func (gi *SGuestImage) removeOva() {
+	ovaPath := gi.GetOvaPath()
+	if err := os.Remove(ovaPath); err != nil && !os.IsNotExist(err) {
+		log.Errorf("remove %s fail %s", ovaPath, err)
+	}
+}
+
+func (manager *SGuestImageManager) CustomizeHandlerInfo(info *appsrv.SHandlerInfo) {
+	manager.SSharableVirtualResourceBaseManager.CustomizeHandlerInfo(info)
+
+	switch info.GetName(nil) {
+	case "get_details":
+		info.SetProcessTimeout(time.Hour * 4).SetWorkerManager(imgStreamingWorkerMan)
+	}
+}
+
+func (manager *SGuestImageManager) IsCustomizedGetDetailsBody() bool {
+	return true
+}
+
+// CustomizedGetDetailsBody downloads the exported ova when format is ova,
+// otherwise returns details as usual
+func (gi *SGuestImage) CustomizedGetDetailsBody(ctx context.Context, userCred mcclient.TokenCredential, query jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	formatStr := jsonutils.GetAnyString(query, []string{"format", "disk_format"})
+	if formatStr != api.GUEST_IMAGE_FORMAT_OVA {
+		return db.GetItemDetailsWithQuery(GuestImageManager, gi, ctx, userCred, query)
+	}
+	err := gi.streamOva(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// streamOva writes the exported ova to the response
+func (gi *SGuestImage) streamOva(ctx context.Context) error {
+	if gi.OvaStatus != api.GUEST_IMAGE_OVA_STATUS_READY {
+		return httperrors.NewInvalidStatusError("cannot download ova in status %q, export it first", gi.OvaStatus)
+	}
+	size, rc, err := GetImage(ctx, gi.GetOvaPath())
+	if err != nil {
+		return errors.Wrap(err, "open ova")
+	}
+	defer rc.Close()
+	appParams := appsrv.AppContextGetParams(ctx)
+	appParams.Response.Header().Set("Content-Type", "application/x-tar")
+	appParams.Response.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	_, err = streamutils.StreamPipe(rc, appParams.Response, false, nil)
+	return err
+}
@@ -0,0 +1,838 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/httputils"
+	"yunion.io/x/pkg/utils"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SImageReplicationManager struct {
+	db.SEnabledStatusDomainLevelResourceBaseManager
+}
+
+var ImageReplicationManager *SImageReplicationManager
+
+func init() {
+	ImageReplicationManager = &SImageReplicationManager{
+		SEnabledStatusDomainLevelResourceBaseManager: db.NewEnabledStatusDomainLevelResourceBaseManager(
+			SImageReplication{},
+			"image_replications_tbl",
+			"image_replication",
+			"image_replications",
+		),
+	}
+	ImageReplicationManager.SetVirtualObject(ImageReplicationManager)
+}
+
+// SImageReplication is a replication policy which copies an image of this
+// region to the image services of other regions or projects
+type SImageReplication struct {
+	db.SEnabledStatusDomainLevelResourceBase
+
+	// 源镜像Id
+	ImageId string `width:"36" charset:"ascii" nullable:"false" index:"true" list:"domain" create:"domain_required"`
+	// 源区域
+	SourceRegion string `width:"128" charset:"utf8" nullable:"false" list:"domain"`
+	// 触发方式, manual, on_change, schedule
+	Trigger string `width:"16" charset:"ascii" nullable:"false" default:"manual" list:"domain" create:"domain_optional" update:"domain"`
+	// 定时同步间隔, 单位分钟
+	IntervalMinutes int `nullable:"false" default:"0" list:"domain" create:"domain_optional" update:"domain"`
+	// 上次触发同步时间
+	LastReplicatedAt time.Time `nullable:"true" list:"domain"`
+}
+
+// +onecloud:swagger-gen-ignore
+type SImageReplicationTargetManager struct {
+	db.SResourceBaseManager
+}
+
+var ImageReplicationTargetManager *SImageReplicationTargetManager
+
+func init() {
+	ImageReplicationTargetManager = &SImageReplicationTargetManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SImageReplicationTarget{},
+			"image_replication_targets_tbl",
+			"image_replication_target",
+			"image_replication_targets",
+		),
+	}
+	ImageReplicationTargetManager.SetVirtualObject(ImageReplicationTargetManager)
+}
+
+// SImageReplicationTarget tracks the replicated image of a policy in one
+// target region and project
+// +onecloud:swagger-gen-ignore
+type SImageReplicationTarget struct {
+	db.SResourceBase
+
+	Id            int    `primary:"true" auto_increment:"true" nullable:"false"`
+	ReplicationId string `width:"36" charset:"ascii" nullable:"false" index:"true"`
+
+	Region    string `width:"128" charset:"utf8" nullable:"false"`
+	ProjectId string `width:"128" charset:"ascii" nullable:"false"`
+	DomainId  string `width:"128" charset:"ascii" nullable:"false"`
+
+	// image in the target region matching SourceChecksum
+	TargetImageId string `width:"36" charset:"ascii" nullable:"true"`
+	// image being copied in the target region, replaces TargetImageId
+	// once it becomes active
+	PendingImageId string `width:"36" charset:"ascii" nullable:"true"`
+
+	Status         string    `width:"36" charset:"ascii" nullable:"false" default:"pending"`
+	SourceChecksum string    `width:"32" charset:"ascii" nullable:"true"`
+	LastSyncAt     time.Time `nullable:"true"`
+	Reason         string    `type:"text" nullable:"true"`
+}
+
+func (target *SImageReplicationTarget) GetId() string {
+	return strconv.Itoa(target.Id)
+}
+
+func (manager *SImageReplicationManager) validateTrigger(trigger string, intervalMinutes int) error {
+	if !utils.IsInStringArray(trigger, []string{
+		api.IMAGE_REPLICATION_TRIGGER_MANUAL,
+		api.IMAGE_REPLICATION_TRIGGER_ON_CHANGE,
+		api.IMAGE_REPLICATION_TRIGGER_SCHEDULE,
+	}) {
+		return httperrors.NewInputParameterError("invalid trigger %q", trigger)
+	}
+	if intervalMinutes < 0 {
+		return httperrors.NewInputParameterError("invalid interval_minutes %d", intervalMinutes)
+	}
+	if trigger == api.IMAGE_REPLICATION_TRIGGER_SCHEDULE && intervalMinutes == 0 {
+		return httperrors.NewMissingParameterError("interval_minutes")
+	}
+	return nil
+}
+
+// validateTarget checks the target region has an image service and
+// resolves the target project, which defaults to the project of the image
+func (manager *SImageReplicationManager) validateTarget(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	img *SImage,
+	input api.ImageReplicationTargetInput,
+) (*SImageReplicationTarget, error) {
+	if len(input.Region) == 0 {
+		return nil, httperrors.NewMissingParameterError("region")
+	}
+	s := auth.GetAdminSession(ctx, input.Region)
+	_, err := s.GetServiceURL(api.SERVICE_TYPE, "")
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("region %s has no image service: %v", input.Region, err)
+	}
+	target := &SImageReplicationTarget{
+		Region:    input.Region,
+		ProjectId: img.ProjectId,
+		DomainId:  img.DomainId,
+		Status:    api.IMAGE_REPLICATION_TARGET_STATUS_PENDING,
+	}
+	if len(input.Project) > 0 {
+		tenant, err := db.TenantCacheManager.FetchTenantByIdOrNameInDomain(ctx, input.Project, "")
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows {
+				return nil, httperrors.NewResourceNotFoundError2("project", input.Project)
+			}
+			return nil, errors.Wrap(err, "FetchTenantByIdOrNameInDomain")
+		}
+		if tenant.DomainId != img.DomainId && db.IsAdminAllowCreate(userCred, manager).Result.IsDeny() {
+			return nil, httperrors.NewForbiddenError("replicating image to project %s of another domain requires admin", tenant.Name)
+		}
+		target.ProjectId = tenant.Id
+		target.DomainId = tenant.DomainId
+	}
+	if target.Region == options.Options.Region && target.ProjectId == img.ProjectId {
+		return nil, httperrors.NewInputParameterError("target %s/%s is the source of the image", target.Region, target.ProjectId)
+	}
+	return target, nil
+}
+
+func (manager *SImageReplicationManager) fetchSourceImage(ctx context.Context, userCred mcclient.TokenCredential, imageId string) (*SImage, error) {
+	imgObj, err := ImageManager.FetchByIdOrName(ctx, userCred, imageId)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, httperrors.NewResourceNotFoundError2(ImageManager.Keyword(), imageId)
+		}
+		return nil, errors.Wrap(err, "ImageManager.FetchByIdOrName")
+	}
+	img := imgObj.(*SImage)
+	if img.IsGuestImage.IsTrue() {
+		return nil, httperrors.NewUnsupportOperationError("image %s is a part of guest image", img.Name)
+	}
+	if len(img.EncryptKeyId) > 0 {
+		return nil, httperrors.NewUnsupportOperationError("encrypted image %s can not be replicated", img.Name)
+	}
+	if utils.IsInStringArray(img.Status, api.ImageDeadStatus) {
+		return nil, httperrors.NewInvalidStatusError("image %s is in status %s", img.Name, img.Status)
+	}
+	return img, nil
+}
+
+func (manager *SImageReplicationManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.ImageReplicationCreateInput,
+) (*jsonutils.JSONDict, error) {
+	var err error
+	input.EnabledStatusDomainLevelResourceCreateInput, err = manager.SEnabledStatusDomainLevelResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusDomainLevelResourceCreateInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusDomainLevelResourceBaseManager.ValidateCreateData")
+	}
+	if len(input.ImageId) == 0 {
+		return nil, httperrors.NewMissingParameterError("image_id")
+	}
+	img, err := manager.fetchSourceImage(ctx, userCred, input.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	if img.DomainId != ownerId.GetProjectDomainId() {
+		return nil, httperrors.NewForbiddenError("image %s does not belong to domain %s", img.Name, ownerId.GetProjectDomainId())
+	}
+	input.ImageId = img.Id
+	if len(input.Trigger) == 0 {
+		input.Trigger = api.IMAGE_REPLICATION_TRIGGER_MANUAL
+	}
+	err = manager.validateTrigger(input.Trigger, input.IntervalMinutes)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.Targets) == 0 {
+		return nil, httperrors.NewMissingParameterError("targets")
+	}
+	keys := map[string]bool{}
+	for i := range input.Targets {
+		target, err := manager.validateTarget(ctx, userCred, img, input.Targets[i])
+		if err != nil {
+			return nil, err
+		}
+		key := target.Region + "/" + target.ProjectId
+		if keys[key] {
+			return nil, httperrors.NewDuplicateResourceError("duplicate target %s", key)
+		}
+		keys[key] = true
+		input.Targets[i] = api.ImageReplicationTargetInput{Region: target.Region, Project: target.ProjectId}
+	}
+	input.Status = api.IMAGE_STATUS_ACTIVE
+	if input.Disabled == nil || !*input.Disabled {
+		input.SetEnabled()
+	}
+	data := input.JSON(input)
+	data.Set("source_region", jsonutils.NewString(options.Options.Region))
+	return data, nil
+}
+
+func (rep *SImageReplication) PostCreate(ctx context.Context, userCred mcclient.TokenCredential, ownerId mcclient.IIdentityProvider, query jsonutils.JSONObject, data jsonutils.JSONObject) {
+	rep.SEnabledStatusDomainLevelResourceBase.PostCreate(ctx, userCred, ownerId, query, data)
+
+	input := api.ImageReplicationCreateInput{}
+	data.Unmarshal(&input)
+	img, err := rep.GetImage()
+	if err != nil {
+		log.Errorf("fetch source image of replication %s: %s", rep.Name, err)
+		return
+	}
+	for i := range input.Targets {
+		target, err := ImageReplicationManager.validateTarget(ctx, userCred, img, input.Targets[i])
+		if err != nil {
+			log.Errorf("validate target %s of replication %s: %s", input.Targets[i].Region, rep.Name, err)
+			continue
+		}
+		err = rep.addTarget(ctx, target)
+		if err != nil {
+			log.Errorf("add target %s of replication %s: %s", target.Region, rep.Name, err)
+		}
+	}
+	if rep.GetEnabled() {
+		rep.StartImageReplicationTask(ctx, userCred, false, "")
+	}
+}
+
+func (manager *SImageReplicationManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ImageReplicationListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusDomainLevelResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusDomainLevelResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusDomainLevelResourceBaseManager.ListItemFilter")
+	}
+	if len(query.ImageId) > 0 {
+		images := ImageManager.Query("id").Filter(sqlchemy.OR(
+			sqlchemy.In(ImageManager.Query().Field("id"), query.ImageId),
+			sqlchemy.In(ImageManager.Query().Field("name"), query.ImageId),
+		)).SubQuery()
+		q = q.In("image_id", images)
+	}
+	if len(query.Trigger) > 0 {
+		q = q.In("trigger", query.Trigger)
+	}
+	return q, nil
+}
+
+func (manager *SImageReplicationManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ImageReplicationListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusDomainLevelResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusDomainLevelResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusDomainLevelResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SImageReplicationManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusDomainLevelResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SImageReplicationManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ImageReplicationDetails {
+	rows := make([]api.ImageReplicationDetails, len(objs))
+	baseRows := manager.SEnabledStatusDomainLevelResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	imageIds := make([]string, len(objs))
+	repIds := make([]string, len(objs))
+	for i := range rows {
+		rep := objs[i].(*SImageReplication)
+		rows[i] = api.ImageReplicationDetails{
+			EnabledStatusDomainLevelResourceDetails: baseRows[i],
+		}
+		imageIds[i] = rep.ImageId
+		repIds[i] = rep.Id
+	}
+	imageNames, err := db.FetchIdNameMap2(ImageManager, imageIds)
+	if err != nil {
+		log.Errorf("FetchIdNameMap2 images: %s", err)
+	}
+	targets := []SImageReplicationTarget{}
+	q := ImageReplicationTargetManager.Query().In("replication_id", repIds).Asc("id")
+	err = db.FetchModelObjects(ImageReplicationTargetManager, q, &targets)
+	if err != nil {
+		log.Errorf("FetchModelObjects image replication targets: %s", err)
+	}
+	targetMap := map[string][]api.ImageReplicationTargetDetails{}
+	for i := range targets {
+		targetMap[targets[i].ReplicationId] = append(targetMap[targets[i].ReplicationId], targets[i].getDetails())
+	}
+	for i := range rows {
+		rows[i].Image = imageNames[imageIds[i]]
+		rows[i].Targets = targetMap[repIds[i]]
+	}
+	return rows
+}
+
+func (target *SImageReplicationTarget) getDetails() api.ImageReplicationTargetDetails {
+	return api.ImageReplicationTargetDetails{
+		Region:         target.Region,
+		ProjectId:      target.ProjectId,
+		DomainId:       target.DomainId,
+		TargetImageId:  target.TargetImageId,
+		PendingImageId: target.PendingImageId,
+		Status:         target.Status,
+		SourceChecksum: target.SourceChecksum,
+		LastSyncAt:     target.LastSyncAt,
+		Reason:         target.Reason,
+	}
+}
+
+func (rep *SImageReplication) ValidateUpdateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.ImageReplicationUpdateInput,
+) (api.ImageReplicationUpdateInput, error) {
+	var err error
+	input.EnabledStatusDomainLevelResourceBaseUpdateInput, err = rep.SEnabledStatusDomainLevelResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusDomainLevelResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SEnabledStatusDomainLevelResourceBase.ValidateUpdateData")
+	}
+	trigger, interval := rep.Trigger, rep.IntervalMinutes
+	if len(input.Trigger) > 0 {
+		trigger = input.Trigger
+	}
+	if input.IntervalMinutes != nil {
+		interval = *input.IntervalMinutes
+	}
+	err = ImageReplicationManager.validateTrigger(trigger, interval)
+	if err != nil {
+		return input, err
+	}
+	return input, nil
+}
+
+func (rep *SImageReplication) PostDelete(ctx context.Context, userCred mcclient.TokenCredential) {
+	rep.SEnabledStatusDomainLevelResourceBase.PostDelete(ctx, userCred)
+
+	// replicated images are kept in the target regions
+	targets, err := rep.GetTargets()
+	if err != nil {
+		log.Errorf("fetch targets of image replication %s: %s", rep.Name, err)
+		return
+	}
+	for i := range targets {
+		_, err := db.Update(&targets[i], func() error {
+			return targets[i].MarkDelete()
+		})
+		if err != nil {
+			log.Errorf("delete target %s of image replication %s: %s", targets[i].Region, rep.Name, err)
+		}
+	}
+}
+
+func (rep *SImageReplication) GetImage() (*SImage, error) {
+	imgObj, err := ImageManager.FetchById(rep.ImageId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "ImageManager.FetchById %s", rep.ImageId)
+	}
+	return imgObj.(*SImage), nil
+}
+
+func (rep *SImageReplication) GetTargets() ([]SImageReplicationTarget, error) {
+	q := ImageReplicationTargetManager.Query().Equals("replication_id", rep.Id).Asc("id")
+	targets := make([]SImageReplicationTarget, 0)
+	err := db.FetchModelObjects(ImageReplicationTargetManager, q, &targets)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return targets, nil
+}
+
+func (rep *SImageReplication) addTarget(ctx context.Context, target *SImageReplicationTarget) error {
+	cnt, err := ImageReplicationTargetManager.Query().Equals("replication_id", rep.Id).
+		Equals("region", target.Region).Equals("project_id", target.ProjectId).CountWithError()
+	if err != nil {
+		return errors.Wrap(err, "CountWithError")
+	}
+	if cnt > 0 {
+		return httperrors.NewDuplicateResourceError("target %s/%s already exists", target.Region, target.ProjectId)
+	}
+	target.ReplicationId = rep.Id
+	target.SetModelManager(ImageReplicationTargetManager, target)
+	return ImageReplicationTargetManager.TableSpec().Insert(ctx, target)
+}
+
+// source reference recorded in the replicated_from property of target images
+func (rep *SImageReplication) sourceRef() string {
+	return fmt.Sprintf("%s/%s", rep.SourceRegion, rep.ImageId)
+}
+
+func (rep *SImageReplication) PerformAddTarget(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.ImageReplicationTargetInput,
+) (jsonutils.JSONObject, error) {
+	img, err := ImageReplicationManager.fetchSourceImage(ctx, userCred, rep.ImageId)
+	if err != nil {
+		return nil, err
+	}
+	target, err := ImageReplicationManager.validateTarget(ctx, userCred, img, input)
+	if err != nil {
+		return nil, err
+	}
+	err = rep.addTarget(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	db.OpsLog.LogEvent(rep, db.ACT_UPDATE, fmt.Sprintf("add target %s/%s", target.Region, target.ProjectId), userCred)
+	if rep.GetEnabled() && rep.Status != api.IMAGE_REPLICATION_STATUS_SYNCING {
+		return nil, rep.StartImageReplicationTask(ctx, userCred, false, "")
+	}
+	return nil, nil
+}
+
+func (rep *SImageReplication) PerformRemoveTarget(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.ImageReplicationRemoveTargetInput,
+) (jsonutils.JSONObject, error) {
+	if len(input.Region) == 0 {
+		return nil, httperrors.NewMissingParameterError("region")
+	}
+	q := ImageReplicationTargetManager.Query().Equals("replication_id", rep.Id).Equals("region", input.Region)
+	if len(input.Project) > 0 {
+		tenant, err := db.TenantCacheManager.FetchTenantByIdOrNameInDomain(ctx, input.Project, "")
+		if err != nil {
+			return nil, httperrors.NewResourceNotFoundError2("project", input.Project)
+		}
+		q = q.Equals("project_id", tenant.Id)
+	}
+	targets := make([]SImageReplicationTarget, 0)
+	err := db.FetchModelObjects(ImageReplicationTargetManager, q, &targets)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	if len(targets) == 0 {
+		return nil, httperrors.NewResourceNotFoundError("no target in region %s", input.Region)
+	}
+	for i := range targets {
+		_, err := db.Update(&targets[i], func() error {
+			return targets[i].MarkDelete()
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "MarkDelete")
+		}
+		db.OpsLog.LogEvent(rep, db.ACT_UPDATE, fmt.Sprintf("remove target %s/%s", targets[i].Region, targets[i].ProjectId), userCred)
+	}
+	return nil, nil
+}
+
+func (rep *SImageReplication) PerformSync(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.ImageReplicationSyncInput,
+) (jsonutils.JSONObject, error) {
+	if !rep.GetEnabled() {
+		return nil, httperrors.NewInvalidStatusError("image replication %s is disabled", rep.Name)
+	}
+	if rep.Status == api.IMAGE_REPLICATION_STATUS_SYNCING {
+		return nil, httperrors.NewInvalidStatusError("image replication %s is syncing", rep.Name)
+	}
+	return nil, rep.StartImageReplicationTask(ctx, userCred, input.Force, "")
+}
+
+func (rep *SImageReplication) StartImageReplicationTask(ctx context.Context, userCred mcclient.TokenCredential, force bool, parentTaskId string) error {
+	params := jsonutils.NewDict()
+	if force {
+		params.Set("force", jsonutils.JSONTrue)
+	}
+	task, err := taskman.TaskManager.NewTask(ctx, "ImageReplicationTask", rep, userCred, params, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	rep.SetStatus(ctx, userCred, api.IMAGE_REPLICATION_STATUS_SYNCING, "")
+	return task.ScheduleRun(nil)
+}
+
+// Replicate copies the source image to the targets whose image is missing
+// or stale, targets already holding the same checksum only get the
+// properties updated. Copies finish asynchronously and are settled by
+// SyncImageReplications
+func (rep *SImageReplication) Replicate(ctx context.Context, userCred mcclient.TokenCredential, force bool) error {
+	img, err := rep.GetImage()
+	if err != nil {
+		return errors.Wrap(err, "GetImage")
+	}
+	if img.Status != api.IMAGE_STATUS_ACTIVE {
+		return errors.Wrapf(httperrors.ErrInvalidStatus, "source image %s is in status %s", img.Name, img.Status)
+	}
+	props, err := ImagePropertyManager.GetProperties(img.Id)
+	if err != nil {
+		return errors.Wrap(err, "GetProperties")
+	}
+	props[api.IMAGE_REPLICATED_FROM] = rep.sourceRef()
+	targets, err := rep.GetTargets()
+	if err != nil {
+		return errors.Wrap(err, "GetTargets")
+	}
+	errs := []error{}
+	for i := range targets {
+		err := targets[i].replicate(ctx, rep, img, props, force)
+		if err != nil {
+			err = errors.Wrapf(err, "target %s/%s", targets[i].Region, targets[i].ProjectId)
+			targets[i].setStatus(api.IMAGE_REPLICATION_TARGET_STATUS_FAILED, err.Error())
+			errs = append(errs, err)
+		}
+	}
+	_, err = db.Update(rep, func() error {
+		rep.LastReplicatedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "update last_replicated_at")
+	}
+	return errors.NewAggregate(errs)
+}
+
+func (target *SImageReplicationTarget) setStatus(status, reason string) error {
+	_, err := db.Update(target, func() error {
+		target.Status = status
+		target.Reason = reason
+		if status == api.IMAGE_REPLICATION_TARGET_STATUS_READY {
+			target.LastSyncAt = time.Now().UTC()
+		}
+		return nil
+	})
+	return err
+}
+
+func (target *SImageReplicationTarget) replicate(ctx context.Context, rep *SImageReplication, img *SImage, props map[string]string, force bool) error {
+	if target.Status == api.IMAGE_REPLICATION_TARGET_STATUS_REPLICATING && !force {
+		return nil
+	}
+	s := auth.GetAdminSession(ctx, target.Region)
+	if !force && len(target.TargetImageId) > 0 && target.SourceChecksum == img.Checksum {
+		meta, err := modules.Images.GetById(s, target.TargetImageId, nil)
+		if err == nil {
+			status, _ := meta.GetString("status")
+			if status == api.IMAGE_STATUS_ACTIVE {
+				params := jsonutils.NewDict()
+				params.Set("description", jsonutils.NewString(img.Description))
+				params.Set("properties", jsonutils.Marshal(props))
+				_, err = modules.Images.Update(s, target.TargetImageId, params)
+				if err != nil {
+					return errors.Wrap(err, "update target image")
+				}
+				return target.setStatus(api.IMAGE_REPLICATION_TARGET_STATUS_READY, "")
+			}
+		} else if httputils.ErrorCode(err) != 404 {
+			return errors.Wrap(err, "fetch target image")
+		}
+		// target image is gone or broken, copy it again
+	}
+	if len(target.PendingImageId) > 0 {
+		// a forced copy supersedes the one in progress
+		_, err := modules.Images.Delete(s, target.PendingImageId, nil)
+		if err != nil && httputils.ErrorCode(err) != 404 {
+			log.Warningf("delete superseded image %s in region %s: %s", target.PendingImageId, target.Region, err)
+		}
+	}
+	params := jsonutils.NewDict()
+	params.Set("generate_name", jsonutils.NewString(img.Name))
+	params.Set("description", jsonutils.NewString(img.Description))
+	params.Set("disk_format", jsonutils.NewString(img.DiskFormat))
+	params.Set("min_disk", jsonutils.NewString(strconv.Itoa(int(img.MinDiskMB))))
+	params.Set("min_ram", jsonutils.NewString(strconv.Itoa(int(img.MinRamMB))))
+	if len(img.OsArch) > 0 {
+		params.Set("os_arch", jsonutils.NewString(img.OsArch))
+	}
+	if len(img.Signature) > 0 {
+		// signer keys are trusted per domain, let the target verify
+		// the signature with its own keys
+		params.Set("signature", jsonutils.NewString(img.Signature))
+	}
+	params.Set("project_id", jsonutils.NewString(target.ProjectId))
+	params.Set("properties", jsonutils.Marshal(props))
+	params.Set("copy_from", jsonutils.NewString(api.IMAGE_COPY_FROM_GLANCE_PREFIX+rep.sourceRef()))
+	ret, err := modules.Images.Create(s, params)
+	if err != nil {
+		return errors.Wrap(err, "create target image")
+	}
+	pendingId, _ := ret.GetString("id")
+	_, err = db.Update(target, func() error {
+		target.PendingImageId = pendingId
+		target.SourceChecksum = img.Checksum
+		target.Status = api.IMAGE_REPLICATION_TARGET_STATUS_REPLICATING
+		target.Reason = ""
+		return nil
+	})
+	return err
+}
+
+// settle checks the pending image of a replicating target, an active
+// pending image replaces the previous target image
+func (target *SImageReplicationTarget) settle(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if len(target.PendingImageId) == 0 {
+		return target.setStatus(api.IMAGE_REPLICATION_TARGET_STATUS_FAILED, "no pending image")
+	}
+	s := auth.GetAdminSession(ctx, target.Region)
+	meta, err := modules.Images.GetById(s, target.PendingImageId, nil)
+	if err != nil {
+		if httputils.ErrorCode(err) == 404 {
+			return target.setStatus(api.IMAGE_REPLICATION_TARGET_STATUS_FAILED, fmt.Sprintf("pending image %s not found", target.PendingImageId))
+		}
+		return errors.Wrap(err, "fetch pending image")
+	}
+	status, _ := meta.GetString("status")
+	switch {
+	case status == api.IMAGE_STATUS_ACTIVE:
+		oldId := target.TargetImageId
+		_, err := db.Update(target, func() error {
+			target.TargetImageId = target.PendingImageId
+			target.PendingImageId = ""
+			target.Status = api.IMAGE_REPLICATION_TARGET_STATUS_READY
+			target.Reason = ""
+			target.LastSyncAt = time.Now().UTC()
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "update target")
+		}
+		if len(oldId) > 0 && oldId != target.TargetImageId {
+			_, err := modules.Images.Delete(s, oldId, nil)
+			if err != nil && httputils.ErrorCode(err) != 404 {
+				log.Warningf("delete replaced image %s in region %s: %s", oldId, target.Region, err)
+			}
+		}
+		return nil
+	case status == api.IMAGE_STATUS_SAVE_FAIL || status == api.IMAGE_STATUS_UNTRUSTED ||
+		status == api.IMAGE_STATUS_VULNERABLE || status == api.IMAGE_STATUS_SCAN_FAILED ||
+		utils.IsInStringArray(status, api.ImageDeadStatus):
+		reason := fmt.Sprintf("pending image %s is %s", target.PendingImageId, status)
+		_, err := modules.Images.Delete(s, target.PendingImageId, nil)
+		if err != nil && httputils.ErrorCode(err) != 404 {
+			log.Warningf("delete failed image %s in region %s: %s", target.PendingImageId, target.Region, err)
+		}
+		_, err = db.Update(target, func() error {
+			target.PendingImageId = ""
+			target.Status = api.IMAGE_REPLICATION_TARGET_STATUS_FAILED
+			target.Reason = reason
+			return nil
+		})
+		return err
+	}
+	// still copying
+	return nil
+}
+
+func (manager *SImageReplicationTargetManager) settleReplicatingTargets(ctx context.Context, userCred mcclient.TokenCredential) {
+	q := manager.Query().Equals("status", api.IMAGE_REPLICATION_TARGET_STATUS_REPLICATING)
+	targets := make([]SImageReplicationTarget, 0)
+	err := db.FetchModelObjects(manager, q, &targets)
+	if err != nil {
+		log.Errorf("fetch replicating image replication targets: %s", err)
+		return
+	}
+	for i := range targets {
+		err := targets[i].settle(ctx, userCred)
+		if err != nil {
+			log.Errorf("settle image replication target %s/%s: %s", targets[i].Region, targets[i].ProjectId, err)
+			continue
+		}
+		repObj, err := ImageReplicationManager.FetchById(targets[i].ReplicationId)
+		if err != nil {
+			continue
+		}
+		rep := repObj.(*SImageReplication)
+		switch targets[i].Status {
+		case api.IMAGE_REPLICATION_TARGET_STATUS_READY:
+			notes := fmt.Sprintf("image %s replicated to %s/%s", targets[i].TargetImageId, targets[i].Region, targets[i].ProjectId)
+			db.OpsLog.LogEvent(rep, db.ACT_IMAGE_REPLICATE, notes, userCred)
+			logclient.AddSimpleActionLog(rep, logclient.ACT_IMAGE_REPLICATE, notes, userCred, true)
+		case api.IMAGE_REPLICATION_TARGET_STATUS_FAILED:
+			db.OpsLog.LogEvent(rep, db.ACT_IMAGE_REPLICATE_FAIL, targets[i].Reason, userCred)
+			logclient.AddSimpleActionLog(rep, logclient.ACT_IMAGE_REPLICATE, targets[i].Reason, userCred, false)
+		}
+	}
+}
+
+// isDue tells whether a schedule or on_change policy should be replicated
+func (rep *SImageReplication) isDue(now time.Time) bool {
+	if !rep.GetEnabled() || rep.Status == api.IMAGE_REPLICATION_STATUS_SYNCING {
+		return false
+	}
+	switch rep.Trigger {
+	case api.IMAGE_REPLICATION_TRIGGER_SCHEDULE:
+		interval := time.Duration(rep.IntervalMinutes) * time.Minute
+		return interval > 0 && !rep.LastReplicatedAt.Add(interval).After(now)
+	case api.IMAGE_REPLICATION_TRIGGER_ON_CHANGE:
+		img, err := rep.GetImage()
+		if err != nil || img.Status != api.IMAGE_STATUS_ACTIVE {
+			return false
+		}
+		return img.UpdatedAt.After(rep.LastReplicatedAt)
+	}
+	return false
+}
+
+// SyncImageReplications settles the copies in progress and starts the
+// replication of due schedule and on_change policies
+func (manager *SImageReplicationManager) SyncImageReplications(ctx context.Context, userCred mcclient.TokenCredential, isStart bool) {
+	ImageReplicationTargetManager.settleReplicatingTargets(ctx, userCred)
+
+	q := manager.Query().IsTrue("enabled").In("trigger", []string{
+		api.IMAGE_REPLICATION_TRIGGER_SCHEDULE,
+		api.IMAGE_REPLICATION_TRIGGER_ON_CHANGE,
+	})
+	reps := make([]SImageReplication, 0)
+	err := db.FetchModelObjects(manager, q, &reps)
+	if err != nil {
+		log.Errorf("fetch image replications: %s", err)
+		return
+	}
+	now := time.Now().UTC()
+	for i := range reps {
+		if !reps[i].isDue(now) {
+			continue
+		}
+		err := reps[i].StartImageReplicationTask(ctx, userCred, false, "")
+		if err != nil {
+			log.Errorf("start replication %s: %s", reps[i].Name, err)
+		}
+	}
+}
+
+// onImageChanged starts the on_change policies of an image right after it
+// becomes active instead of waiting for SyncImageReplications
+func (manager *SImageReplicationManager) onImageChanged(ctx context.Context, userCred mcclient.TokenCredential, imageId string) {
+	q := manager.Query().Equals("image_id", imageId).IsTrue("enabled").Equals("trigger", api.IMAGE_REPLICATION_TRIGGER_ON_CHANGE)
+	reps := make([]SImageReplication, 0)
+	err := db.FetchModelObjects(manager, q, &reps)
+	if err != nil {
+		log.Errorf("fetch image replications of %s: %s", imageId, err)
+		return
+	}
+	for i := range reps {
+		if reps[i].Status == api.IMAGE_REPLICATION_STATUS_SYNCING {
+			continue
+		}
+		err := reps[i].StartImageReplicationTask(ctx, userCred, false, "")
+		if err != nil {
+			log.Errorf("start replication %s: %s", reps[i].Name, err)
+		}
+	}
+}
+
+// ParseGlanceCopyFrom splits glance://<region>/<image_id>
+func ParseGlanceCopyFrom(copyFrom string) (string, string, error) {
+	if !strings.HasPrefix(copyFrom, api.IMAGE_COPY_FROM_GLANCE_PREFIX) {
+		return "", "", errors.Wrapf(errors.ErrInvalidFormat, "%s", copyFrom)
+	}
+	ref := strings.TrimPrefix(copyFrom, api.IMAGE_COPY_FROM_GLANCE_PREFIX)
+	pos := strings.LastIndexByte(ref, '/')
+	if pos <= 0 || pos == len(ref)-1 {
+		return "", "", errors.Wrapf(errors.ErrInvalidFormat, "%s", copyFrom)
+	}
+	return ref[:pos], ref[pos+1:], nil
+}
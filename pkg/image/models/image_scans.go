@@ -0,0 +1,365 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	deployapi "yunion.io/x/onecloud/pkg/hostman/hostdeployer/apis"
+	"yunion.io/x/onecloud/pkg/hostman/hostdeployer/deployclient"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/sbomutils"
+)
+
+var errImageVulnerable = errors.Error("image vulnerable")
+
+// +onecloud:swagger-gen-ignore
+type SImageScanManager struct {
+	db.SResourceBaseManager
+}
+
+var ImageScanManager *SImageScanManager
+
+func init() {
+	ImageScanManager = &SImageScanManager{
+		SResourceBaseManager: db.NewResourceBaseManager(
+			SImageScan{},
+			"image_scans_tbl",
+			"image_scan",
+			"image_scans",
+		),
+	}
+	ImageScanManager.SetVirtualObject(ImageScanManager)
+}
+
+// SImageScan keeps the latest package inventory and vulnerability
+// findings of an image, summaries are kept on the image for filtering
+// +onecloud:swagger-gen-ignore
+type SImageScan struct {
+	SImagePeripheral
+
+	OsType  string `width:"32" charset:"ascii" nullable:"true"`
+	Distro  string `width:"64" charset:"utf8" nullable:"true"`
+	Version string `width:"64" charset:"utf8" nullable:"true"`
+
+	// []sbomutils.SPackage
+	Packages jsonutils.JSONObject `length:"long" nullable:"true"`
+	// []sbomutils.SFinding
+	Findings jsonutils.JSONObject `length:"long" nullable:"true"`
+
+	ScannedAt time.Time `nullable:"true"`
+}
+
+func (manager *SImageScanManager) FetchByImageId(imageId string) (*SImageScan, error) {
+	q := manager.Query().Equals("image_id", imageId)
+	scan := SImageScan{}
+	scan.SetModelManager(manager, &scan)
+	err := q.First(&scan)
+	if err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, errors.Wrapf(errors.ErrNotFound, "scan of image %s", imageId)
+		}
+		return nil, err
+	}
+	return &scan, nil
+}
+
+func (manager *SImageScanManager) saveScan(
+	ctx context.Context,
+	imageId string,
+	result *deployapi.ImagePackages,
+	pkgs []sbomutils.SPackage,
+	findings []sbomutils.SFinding,
+) error {
+	var distro, version string
+	if result.OsInfo != nil {
+		distro, version = result.OsInfo.Distro, result.OsInfo.Version
+	}
+	scan, err := manager.FetchByImageId(imageId)
+	if err != nil {
+		if errors.Cause(err) != errors.ErrNotFound {
+			return err
+		}
+		scan = &SImageScan{}
+		scan.SetModelManager(manager, scan)
+		scan.ImageId = imageId
+		scan.OsType = result.OsType
+		scan.Distro = distro
+		scan.Version = version
+		scan.Packages = jsonutils.Marshal(pkgs)
+		scan.Findings = jsonutils.Marshal(findings)
+		scan.ScannedAt = time.Now().UTC()
+		return manager.TableSpec().Insert(ctx, scan)
+	}
+	_, err = db.Update(scan, func() error {
+		scan.OsType = result.OsType
+		scan.Distro = distro
+		scan.Version = version
+		scan.Packages = jsonutils.Marshal(pkgs)
+		scan.Findings = jsonutils.Marshal(findings)
+		scan.ScannedAt = time.Now().UTC()
+		return nil
+	})
+	return err
+}
+
+func (manager *SImageScanManager) deleteByImageId(imageId string) error {
+	scan, err := manager.FetchByImageId(imageId)
+	if err != nil {
+		if errors.Cause(err) == errors.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	_, err = db.Update(scan, func() error {
+		return scan.MarkDelete()
+	})
+	return err
+}
+
+func (scan *SImageScan) GetPackages() []sbomutils.SPackage {
+	pkgs := []sbomutils.SPackage{}
+	if scan.Packages != nil {
+		scan.Packages.Unmarshal(&pkgs)
+	}
+	return pkgs
+}
+
+func (scan *SImageScan) GetFindings() []sbomutils.SFinding {
+	findings := []sbomutils.SFinding{}
+	if scan.Findings != nil {
+		scan.Findings.Unmarshal(&findings)
+	}
+	return findings
+}
+
+func (img *SImage) scanPackages(ctx context.Context, userCred mcclient.TokenCredential) (*deployapi.ImagePackages, error) {
+	diskPath := img.GetLocalLocation()
+	if len(diskPath) == 0 {
+		return nil, errors.Wrap(httperrors.ErrNotFound, "disk file not found")
+	}
+	if deployclient.GetDeployClient() == nil {
+		return nil, fmt.Errorf("deploy client not init")
+	}
+	diskInfo := &deployapi.DiskInfo{
+		Path: diskPath,
+	}
+	if img.IsEncrypted() {
+		key, err := img.GetEncryptInfo(ctx, userCred)
+		if err != nil {
+			return nil, errors.Wrap(err, "GetEncryptInfo")
+		}
+		diskInfo.EncryptPassword = key.Key
+		diskInfo.EncryptAlg = string(key.Alg)
+	}
+	return deployclient.GetDeployClient().ScanImagePackages(ctx, &deployapi.ProbeImageInfoPramas{DiskInfo: diskInfo})
+}
+
+func (img *SImage) setScanResult(status string, packageCount int, findings []sbomutils.SFinding) error {
+	_, err := db.Update(img, func() error {
+		img.ScanStatus = status
+		img.PackageCount = packageCount
+		img.VulnerabilityCount = len(findings)
+		img.MaxVulnSeverity = sbomutils.MaxSeverity(findings)
+		return nil
+	})
+	return err
+}
+
+func (img *SImage) scanFailed(userCred mcclient.TokenCredential, err error) error {
+	if e := img.setScanResult(api.IMAGE_SCAN_STATUS_FAILED, 0, nil); e != nil {
+		log.Errorf("image %s set scan status: %s", img.Id, e)
+	}
+	db.OpsLog.LogEvent(img, db.ACT_IMAGE_SCAN_FAIL, err, userCred)
+	logclient.AddSimpleActionLog(img, logclient.ACT_IMAGE_SCAN, err, userCred, false)
+	return err
+}
+
+// Scan enumerates installed packages of the image and matches them against
+// the vulnerability feed, an image with findings at or above
+// ImageScanBlockSeverity is set vulnerable and errImageVulnerable returned
+func (img *SImage) Scan(ctx context.Context, userCred mcclient.TokenCredential) error {
+	result, err := img.scanPackages(ctx, userCred)
+	if err != nil {
+		return img.scanFailed(userCred, errors.Wrap(err, "ScanImagePackages"))
+	}
+	pkgs := make([]sbomutils.SPackage, 0, len(result.Packages))
+	for _, pkg := range result.Packages {
+		pkgs = append(pkgs, sbomutils.SPackage{
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			Arch:    pkg.Arch,
+			Type:    pkg.Type,
+		})
+	}
+	findings := []sbomutils.SFinding{}
+	if feedPath := options.Options.ImageVulnerabilityFeedPath; len(feedPath) > 0 {
+		feed, err := sbomutils.LoadVulnerabilityFeed(feedPath)
+		if err != nil {
+			return img.scanFailed(userCred, errors.Wrap(err, "LoadVulnerabilityFeed"))
+		}
+		findings = feed.Match(pkgs)
+	}
+	if err := ImageScanManager.saveScan(ctx, img.Id, result, pkgs, findings); err != nil {
+		return img.scanFailed(userCred, errors.Wrap(err, "saveScan"))
+	}
+	if err := img.setScanResult(api.IMAGE_SCAN_STATUS_SCANNED, len(pkgs), findings); err != nil {
+		return errors.Wrap(err, "setScanResult")
+	}
+	reason := fmt.Sprintf("%d packages, %d vulnerabilities", len(pkgs), len(findings))
+	db.OpsLog.LogEvent(img, db.ACT_IMAGE_SCAN, reason, userCred)
+	logclient.AddSimpleActionLog(img, logclient.ACT_IMAGE_SCAN, reason, userCred, true)
+
+	blockRank := sbomutils.SeverityRank(options.Options.ImageScanBlockSeverity)
+	if blockRank > 0 && sbomutils.SeverityRank(img.MaxVulnSeverity) >= blockRank {
+		reason := fmt.Sprintf("%s vulnerabilities found", img.MaxVulnSeverity)
+		img.SetStatus(ctx, userCred, api.IMAGE_STATUS_VULNERABLE, reason)
+		return errors.Wrap(errImageVulnerable, reason)
+	}
+	if img.Status == api.IMAGE_STATUS_VULNERABLE || img.Status == api.IMAGE_STATUS_SCAN_FAILED {
+		img.SetStatus(ctx, userCred, api.IMAGE_STATUS_SAVED, "scan passed")
+	}
+	return nil
+}
+
+func (img *SImage) isScannable() bool {
+	return !img.IsIso() && !img.IsData.IsTrue()
+}
+
+// doScan scans the image in pipeline if image scan is enabled.  Vulnerable
+// images stop the pipeline, so do scan failures when ImageScanBlockSeverity
+// is set, otherwise scan failures are only logged
+func (img *SImage) doScan(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if !options.Options.EnableImageScan || !img.isScannable() {
+		return nil
+	}
+	err := img.Scan(ctx, userCred)
+	if err != nil {
+		if errors.Cause(err) == errImageVulnerable {
+			return err
+		}
+		if sbomutils.SeverityRank(options.Options.ImageScanBlockSeverity) > 0 {
+			img.SetStatus(ctx, userCred, api.IMAGE_STATUS_SCAN_FAILED, err.Error())
+			return err
+		}
+		log.Errorf("image %s(%s) scan fail: %s", img.Name, img.Id, err)
+	}
+	return nil
+}
+
+// 扫描镜像内已安装的软件包及漏洞
+func (img *SImage) PerformScan(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.PerformScanInput,
+) (jsonutils.JSONObject, error) {
+	if !img.isScannable() {
+		return nil, httperrors.NewUnsupportOperationError("cannot scan iso or data image")
+	}
+	switch img.Status {
+	case api.IMAGE_STATUS_VULNERABLE, api.IMAGE_STATUS_SCAN_FAILED:
+		// rescan in the pipeline which activates the image once it passes
+		return nil, img.StartImagePipeline(ctx, userCred, true)
+	case api.IMAGE_STATUS_ACTIVE:
+		return nil, img.StartImageScanTask(ctx, userCred, "")
+	default:
+		return nil, httperrors.NewInvalidStatusError("cannot scan in status %s", img.Status)
+	}
+}
+
+func (img *SImage) StartImageScanTask(ctx context.Context, userCred mcclient.TokenCredential, parentTaskId string) error {
+	task, err := taskman.TaskManager.NewTask(ctx, "ImageScanTask", img, userCred, nil, parentTaskId, "", nil)
+	if err != nil {
+		return errors.Wrap(err, "NewTask")
+	}
+	return task.ScheduleRun(nil)
+}
+
+func (img *SImage) getScan() (*SImageScan, error) {
+	scan, err := ImageScanManager.FetchByImageId(img.Id)
+	if err != nil {
+		if errors.Cause(err) == errors.ErrNotFound {
+			return nil, httperrors.NewResourceNotFoundError("image %s has not been scanned", img.Name)
+		}
+		return nil, errors.Wrap(err, "FetchByImageId")
+	}
+	return scan, nil
+}
+
+// 获取镜像的软件物料清单(SBOM)
+func (img *SImage) GetDetailsSbom(ctx context.Context, userCred mcclient.TokenCredential, query api.ImageSbomInput) (jsonutils.JSONObject, error) {
+	scan, err := img.getScan()
+	if err != nil {
+		return nil, err
+	}
+	doc := &sbomutils.SDocument{
+		Id:       img.Id,
+		Name:     img.Name,
+		Distro:   scan.Distro,
+		Version:  scan.Version,
+		Created:  scan.ScannedAt,
+		Packages: scan.GetPackages(),
+	}
+	var data []byte
+	switch query.Format {
+	case "", api.IMAGE_SBOM_FORMAT_SPDX:
+		data, err = doc.SPDX()
+	case api.IMAGE_SBOM_FORMAT_CYCLONEDX:
+		data, err = doc.CycloneDX()
+	default:
+		return nil, httperrors.NewInputParameterError("invalid sbom format %s", query.Format)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "generate sbom")
+	}
+	return jsonutils.Parse(data)
+}
+
+// 获取镜像的漏洞列表
+func (img *SImage) GetDetailsVulnerabilities(ctx context.Context, userCred mcclient.TokenCredential, query api.ImageVulnerabilityListInput) (jsonutils.JSONObject, error) {
+	if len(query.MinSeverity) > 0 && !sbomutils.IsValidSeverity(query.MinSeverity) {
+		return nil, httperrors.NewInputParameterError("invalid min_severity %s", query.MinSeverity)
+	}
+	scan, err := img.getScan()
+	if err != nil {
+		return nil, err
+	}
+	minRank := sbomutils.SeverityRank(query.MinSeverity)
+	findings := []sbomutils.SFinding{}
+	for _, f := range scan.GetFindings() {
+		if sbomutils.SeverityRank(f.Severity) >= minRank {
+			findings = append(findings, f)
+		}
+	}
+	ret := jsonutils.NewDict()
+	ret.Set("scanned_at", jsonutils.NewTimeString(scan.ScannedAt))
+	ret.Set("package_count", jsonutils.NewInt(int64(img.PackageCount)))
+	ret.Set("vulnerabilities", jsonutils.Marshal(findings))
+	return ret, nil
+}
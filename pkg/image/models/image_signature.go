@@ -0,0 +1,128 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/imagesign"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+// verifySignature checks the detached signature of the image checksum
+// against the trusted signer keys of the image domain
+func (img *SImage) verifySignature() (string, string, error) {
+	if len(img.Signature) == 0 {
+		return api.IMAGE_SIGNATURE_STATUS_UNSIGNED, img.SignerKeyId, errors.Wrap(errors.ErrNotFound, "image is not signed")
+	}
+	if len(img.Checksum) == 0 {
+		return api.IMAGE_SIGNATURE_STATUS_INVALID, img.SignerKeyId, errors.Wrap(errors.ErrInvalidStatus, "image has no checksum")
+	}
+	key, err := ImageSignerKeyManager.VerifySignature(img.DomainId, img.SignerKeyId, img.Checksum, img.Signature)
+	if err != nil {
+		return api.IMAGE_SIGNATURE_STATUS_INVALID, img.SignerKeyId, err
+	}
+	return api.IMAGE_SIGNATURE_STATUS_VERIFIED, key.Id, nil
+}
+
+func (img *SImage) setSignatureStatus(status, keyId string) error {
+	if img.SignatureStatus == status && img.SignerKeyId == keyId {
+		return nil
+	}
+	_, err := db.Update(img, func() error {
+		img.SignatureStatus = status
+		img.SignerKeyId = keyId
+		return nil
+	})
+	return err
+}
+
+// doVerifySignature enforces ImageSignaturePolicy before the image
+// becomes active, an image rejected by the policy is left untrusted
+func (img *SImage) doVerifySignature(ctx context.Context, userCred mcclient.TokenCredential) error {
+	policy := options.Options.ImageSignaturePolicy
+	if len(policy) == 0 || policy == imagesign.POLICY_NONE {
+		return nil
+	}
+	status, keyId, verifyErr := img.verifySignature()
+	if err := img.setSignatureStatus(status, keyId); err != nil {
+		return errors.Wrap(err, "setSignatureStatus")
+	}
+	if verifyErr == nil {
+		if img.Status == api.IMAGE_STATUS_UNTRUSTED {
+			img.SetStatus(ctx, userCred, api.IMAGE_STATUS_SAVED, "signature verified")
+		}
+		return nil
+	}
+	if policy == imagesign.POLICY_WARN {
+		log.Warningf("image %s(%s) signature verification fail: %s", img.Name, img.Id, verifyErr)
+		return nil
+	}
+	reason := fmt.Sprintf("signature verification fail: %s", verifyErr)
+	db.OpsLog.LogEvent(img, db.ACT_VERIFY_SIGNATURE_FAIL, reason, userCred)
+	logclient.AddSimpleActionLog(img, logclient.ACT_VERIFY_SIGNATURE, reason, userCred, false)
+	img.SetStatus(ctx, userCred, api.IMAGE_STATUS_UNTRUSTED, reason)
+	return errors.Wrap(verifyErr, "verifySignature")
+}
+
+// 设置镜像签名
+func (img *SImage) PerformSetSignature(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.ImageSetSignatureInput,
+) (jsonutils.JSONObject, error) {
+	if len(input.Signature) == 0 {
+		return nil, httperrors.NewMissingParameterError("signature")
+	}
+	if len(img.Checksum) == 0 {
+		return nil, httperrors.NewInvalidStatusError("image checksum is not ready, status %s", img.Status)
+	}
+	key, err := ImageSignerKeyManager.VerifySignature(img.DomainId, input.SignerKeyId, img.Checksum, input.Signature)
+	if err != nil {
+		logclient.AddSimpleActionLog(img, logclient.ACT_VERIFY_SIGNATURE, err, userCred, false)
+		if errors.Cause(err) == errors.ErrNotFound {
+			return nil, httperrors.NewResourceNotFoundError("%v", err)
+		}
+		return nil, httperrors.NewInputParameterError("%v", err)
+	}
+	_, err = db.Update(img, func() error {
+		img.Signature = input.Signature
+		img.SignerKeyId = key.Id
+		img.SignatureStatus = api.IMAGE_SIGNATURE_STATUS_VERIFIED
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "update signature")
+	}
+	reason := fmt.Sprintf("signed by %s(%s)", key.Name, key.Fingerprint)
+	db.OpsLog.LogEvent(img, db.ACT_VERIFY_SIGNATURE, reason, userCred)
+	logclient.AddSimpleActionLog(img, logclient.ACT_VERIFY_SIGNATURE, reason, userCred, true)
+	if img.Status == api.IMAGE_STATUS_UNTRUSTED {
+		// resume the pipeline rejected by signature policy
+		return nil, img.StartImagePipeline(ctx, userCred, true)
+	}
+	return nil, nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/sqlchemy"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/httperrors"
+	"yunion.io/x/onecloud/pkg/mcclient"
+	"yunion.io/x/onecloud/pkg/util/imagesign"
+	"yunion.io/x/onecloud/pkg/util/stringutils2"
+)
+
+type SImageSignerKeyManager struct {
+	db.SEnabledStatusDomainLevelResourceBaseManager
+}
+
+var ImageSignerKeyManager *SImageSignerKeyManager
+
+func init() {
+	ImageSignerKeyManager = &SImageSignerKeyManager{
+		SEnabledStatusDomainLevelResourceBaseManager: db.NewEnabledStatusDomainLevelResourceBaseManager(
+			SImageSignerKey{},
+			"image_signer_keys_tbl",
+			"image_signer_key",
+			"image_signer_keys",
+		),
+	}
+	ImageSignerKeyManager.SetVirtualObject(ImageSignerKeyManager)
+}
+
+// SImageSignerKey is a trusted public key of a domain, images of the
+// domain are verified against its enabled keys
+type SImageSignerKey struct {
+	db.SEnabledStatusDomainLevelResourceBase
+
+	// PEM格式的公钥
+	PublicKey string `type:"text" nullable:"false" list:"domain" create:"domain_required"`
+	// 签名算法, ecdsa或ed25519
+	Algorithm string `width:"16" charset:"ascii" nullable:"false" list:"domain"`
+	// 公钥指纹
+	Fingerprint string `width:"64" charset:"ascii" nullable:"false" list:"domain" index:"true"`
+}
+
+func (manager *SImageSignerKeyManager) ValidateCreateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	ownerId mcclient.IIdentityProvider,
+	query jsonutils.JSONObject,
+	input api.ImageSignerKeyCreateInput,
+) (*jsonutils.JSONDict, error) {
+	var err error
+	input.EnabledStatusDomainLevelResourceCreateInput, err = manager.SEnabledStatusDomainLevelResourceBaseManager.ValidateCreateData(ctx, userCred, ownerId, query, input.EnabledStatusDomainLevelResourceCreateInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusDomainLevelResourceBaseManager.ValidateCreateData")
+	}
+	pub, err := imagesign.ParsePublicKey(input.PublicKey)
+	if err != nil {
+		return nil, httperrors.NewInputParameterError("invalid public_key: %v", err)
+	}
+	cnt, err := manager.Query().Equals("domain_id", ownerId.GetProjectDomainId()).Equals("fingerprint", pub.Fingerprint).CountWithError()
+	if err != nil {
+		return nil, errors.Wrap(err, "CountWithError")
+	}
+	if cnt > 0 {
+		return nil, httperrors.NewDuplicateResourceError("public key %s already trusted", pub.Fingerprint)
+	}
+	input.Status = api.IMAGE_STATUS_ACTIVE
+	if input.Disabled == nil || !*input.Disabled {
+		input.SetEnabled()
+	}
+	data := input.JSON(input)
+	data.Set("algorithm", jsonutils.NewString(pub.Algorithm))
+	data.Set("fingerprint", jsonutils.NewString(pub.Fingerprint))
+	return data, nil
+}
+
+func (manager *SImageSignerKeyManager) ListItemFilter(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ImageSignerKeyListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusDomainLevelResourceBaseManager.ListItemFilter(ctx, q, userCred, query.EnabledStatusDomainLevelResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusDomainLevelResourceBaseManager.ListItemFilter")
+	}
+	if len(query.Algorithm) > 0 {
+		q = q.In("algorithm", query.Algorithm)
+	}
+	if len(query.Fingerprint) > 0 {
+		q = q.In("fingerprint", query.Fingerprint)
+	}
+	return q, nil
+}
+
+func (manager *SImageSignerKeyManager) OrderByExtraFields(
+	ctx context.Context,
+	q *sqlchemy.SQuery,
+	userCred mcclient.TokenCredential,
+	query api.ImageSignerKeyListInput,
+) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusDomainLevelResourceBaseManager.OrderByExtraFields(ctx, q, userCred, query.EnabledStatusDomainLevelResourceListInput)
+	if err != nil {
+		return nil, errors.Wrap(err, "SEnabledStatusDomainLevelResourceBaseManager.OrderByExtraFields")
+	}
+	return q, nil
+}
+
+func (manager *SImageSignerKeyManager) QueryDistinctExtraField(q *sqlchemy.SQuery, field string) (*sqlchemy.SQuery, error) {
+	q, err := manager.SEnabledStatusDomainLevelResourceBaseManager.QueryDistinctExtraField(q, field)
+	if err == nil {
+		return q, nil
+	}
+	return q, httperrors.ErrNotFound
+}
+
+func (manager *SImageSignerKeyManager) FetchCustomizeColumns(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	objs []interface{},
+	fields stringutils2.SSortedStrings,
+	isList bool,
+) []api.ImageSignerKeyDetails {
+	rows := make([]api.ImageSignerKeyDetails, len(objs))
+	baseRows := manager.SEnabledStatusDomainLevelResourceBaseManager.FetchCustomizeColumns(ctx, userCred, query, objs, fields, isList)
+	for i := range rows {
+		rows[i] = api.ImageSignerKeyDetails{
+			EnabledStatusDomainLevelResourceDetails: baseRows[i],
+		}
+	}
+	return rows
+}
+
+func (key *SImageSignerKey) ValidateUpdateData(
+	ctx context.Context,
+	userCred mcclient.TokenCredential,
+	query jsonutils.JSONObject,
+	input api.ImageSignerKeyUpdateInput,
+) (api.ImageSignerKeyUpdateInput, error) {
+	var err error
+	input.EnabledStatusDomainLevelResourceBaseUpdateInput, err = key.SEnabledStatusDomainLevelResourceBase.ValidateUpdateData(ctx, userCred, query, input.EnabledStatusDomainLevelResourceBaseUpdateInput)
+	if err != nil {
+		return input, errors.Wrap(err, "SEnabledStatusDomainLevelResourceBase.ValidateUpdateData")
+	}
+	return input, nil
+}
+
+func (key *SImageSignerKey) GetPublicKey() (*imagesign.SPublicKey, error) {
+	return imagesign.ParsePublicKey(key.PublicKey)
+}
+
+// getTrustedSignerKeys returns the enabled keys of the domain, or only
+// keyId if it is given
+func (manager *SImageSignerKeyManager) getTrustedSignerKeys(domainId string, keyId string) ([]SImageSignerKey, error) {
+	q := manager.Query().Equals("domain_id", domainId).IsTrue("enabled")
+	if len(keyId) > 0 {
+		q = q.Filter(sqlchemy.OR(sqlchemy.Equals(q.Field("id"), keyId), sqlchemy.Equals(q.Field("name"), keyId)))
+	}
+	keys := make([]SImageSignerKey, 0)
+	err := db.FetchModelObjects(manager, q, &keys)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchModelObjects")
+	}
+	return keys, nil
+}
+
+// VerifySignature verifies signature of digest with the trusted keys of
+// the domain and returns the key that signed it
+func (manager *SImageSignerKeyManager) VerifySignature(domainId, keyId, digest, signature string) (*SImageSignerKey, error) {
+	keys, err := manager.getTrustedSignerKeys(domainId, keyId)
+	if err != nil {
+		return nil, errors.Wrap(err, "getTrustedSignerKeys")
+	}
+	if len(keys) == 0 {
+		return nil, errors.Wrapf(errors.ErrNotFound, "no trusted signer key %s in domain %s", keyId, domainId)
+	}
+	for i := range keys {
+		pub, err := keys[i].GetPublicKey()
+		if err != nil {
+			log.Errorf("parse public key of signer key %s: %s", keys[i].Id, err)
+			continue
+		}
+		if pub.Verify(digest, signature) == nil {
+			return &keys[i], nil
+		}
+	}
+	return nil, errors.Wrapf(imagesign.ErrSignatureInvalid, "no trusted signer key in domain %s verifies digest %s", domainId, digest)
+}
@@ -68,6 +68,7 @@ import (
 	"yunion.io/x/onecloud/pkg/util/qemuimg"
 	"yunion.io/x/onecloud/pkg/util/qemutils"
 	"yunion.io/x/onecloud/pkg/util/rbacutils"
+	"yunion.io/x/onecloud/pkg/util/sbomutils"
 	"yunion.io/x/onecloud/pkg/util/stringutils2"
 )
 
@@ -164,6 +165,22 @@ type SImage struct {
 
 	// 加密状态, "",encrypting,encrypted
 	EncryptStatus string `width:"16" charset:"ascii" nullable:"true" get:"user" list:"user"`
+
+	// 镜像校验和的签名, base64编码
+	Signature string `width:"256" charset:"ascii" nullable:"true" get:"user" list:"user" create:"optional"`
+	// 验证签名的签名密钥Id
+	SignerKeyId string `width:"36" charset:"ascii" nullable:"true" get:"user" list:"user" create:"optional"`
+	// 签名状态, "",verified,invalid
+	SignatureStatus string `width:"16" charset:"ascii" nullable:"true" get:"user" list:"user"`
+
+	// 镜像内容扫描状态, "",scanned,failed
+	ScanStatus string `width:"16" charset:"ascii" nullable:"true" get:"user" list:"user"`
+	// 扫描到的已安装软件包数量
+	PackageCount int `nullable:"true" get:"user" list:"user"`
+	// 匹配到的漏洞数量
+	VulnerabilityCount int `nullable:"true" get:"user" list:"user"`
+	// 漏洞最高等级, "",low,medium,high,critical
+	MaxVulnSeverity string `width:"16" charset:"ascii" nullable:"true" get:"user" list:"user"`
 }
 
 func (manager *SImageManager) CustomizeHandlerInfo(info *appsrv.SHandlerInfo) {
@@ -370,6 +387,12 @@ func (self *SImage) GetExtraDetailsHeaders(ctx context.Context, userCred mcclien
 		}
 	}
 
+	// signature covers the checksum of the original image, subformats
+	// are converted from it by the image service
+	if len(self.Signature) > 0 {
+		headers[fmt.Sprintf("%s%s", modules.IMAGE_META, "signed_checksum")] = self.Checksum
+	}
+
 	// none of subimage business
 	var ossChksum = self.OssChecksum
 	if len(self.OssChecksum) == 0 {
@@ -418,6 +441,13 @@ func (manager *SImageManager) ValidateCreateData(
 		return input, errors.Wrap(err, "SEncryptedResourceManager.ValidateCreateData")
 	}
 
+	if appParams := appsrv.AppContextGetParams(ctx); appParams != nil {
+		copyFrom := appParams.Request.Header.Get(modules.IMAGE_META_COPY_FROM)
+		if err := ValidateImageCopyFrom(userCred, copyFrom); err != nil {
+			return input, err
+		}
+	}
+
 	// If this image is the part of guest image (contains "guest_image_id"),
 	// we do not need to check and set pending quota
 	// because that pending quota has been checked and set in SGuestImage.ValidateCreateData
@@ -732,6 +762,9 @@ func (img *SImage) ValidateUpdateData(
 				copyFrom := appParams.Request.Header.Get(modules.IMAGE_META_COPY_FROM)
 				compress := appParams.Request.Header.Get(modules.IMAGE_META_COMPRESS_FORMAT)
 				if len(copyFrom) > 0 {
+					if err := ValidateImageCopyFrom(userCred, copyFrom); err != nil {
+						return input, err
+					}
 					err := img.startImageCopyFromUrlTask(ctx, userCred, copyFrom, compress, "")
 					if err != nil {
 						img.OnSaveFailed(ctx, userCred, jsonutils.NewString(fmt.Sprintf("update copy from url failed %s", err)))
@@ -805,6 +838,9 @@ func (self *SImage) Delete(ctx context.Context, userCred mcclient.TokenCredentia
 }
 
 func (self *SImage) RealDelete(ctx context.Context, userCred mcclient.TokenCredential) error {
+	if err := ImageScanManager.deleteByImageId(self.Id); err != nil {
+		log.Errorf("delete scan of image %s: %s", self.Id, err)
+	}
 	return self.SSharableVirtualResourceBase.Delete(ctx, userCred)
 }
 
@@ -844,6 +880,16 @@ func (self *SImage) startDeleteImageTask(ctx context.Context, userCred mcclient.
 	return nil
 }
 
+// ValidateImageCopyFrom allows only system admins, e.g. image replication,
+// to copy from the image service of another region, as the source image is
+// downloaded with admin session there
+func ValidateImageCopyFrom(userCred mcclient.TokenCredential, copyFrom string) error {
+	if strings.HasPrefix(copyFrom, api.IMAGE_COPY_FROM_GLANCE_PREFIX) && !userCred.HasSystemAdminPrivilege() {
+		return httperrors.NewForbiddenError("copy from %s requires system admin privilege", api.IMAGE_COPY_FROM_GLANCE_PREFIX)
+	}
+	return nil
+}
+
 func (self *SImage) startImageCopyFromUrlTask(ctx context.Context, userCred mcclient.TokenCredential, copyFrom, compress string, parentTaskId string) error {
 	params := jsonutils.NewDict()
 	params.Add(jsonutils.NewString(copyFrom), "copy_from")
@@ -1360,6 +1406,16 @@ func (manager *SImageManager) ListItemFilter(
 	propFilter([]string{api.IMAGE_OS_TYPE}, query.OsTypes, query.OsTypePreciseMatch)
 	propFilter([]string{api.IMAGE_OS_DISTRO, "distro"}, query.Distributions, query.DistributionPreciseMatch)
 
+	if len(query.ScanStatus) > 0 {
+		q = q.In("scan_status", query.ScanStatus)
+	}
+	if len(query.MinVulnSeverity) > 0 {
+		if !sbomutils.IsValidSeverity(query.MinVulnSeverity) {
+			return nil, httperrors.NewInputParameterError("invalid min_vuln_severity %s", query.MinVulnSeverity)
+		}
+		q = q.In("max_vuln_severity", sbomutils.SeveritiesAtLeast(query.MinVulnSeverity))
+	}
+
 	return q, nil
 }
 
@@ -1456,7 +1512,8 @@ func (image *SImage) DoCheckStatus(ctx context.Context, userCred mcclient.TokenC
 	}
 	if IsCheckStatusEnabled(image) {
 		if image.isActive(useFast, true) {
-			if image.Status != api.IMAGE_STATUS_ACTIVE {
+			// image rejected by signature or scan policy stays untrusted, vulnerable or scan_failed
+			if !utils.IsInStringArray(image.Status, []string{api.IMAGE_STATUS_ACTIVE, api.IMAGE_STATUS_UNTRUSTED, api.IMAGE_STATUS_VULNERABLE, api.IMAGE_STATUS_SCAN_FAILED}) {
 				image.SetStatus(ctx, userCred, api.IMAGE_STATUS_ACTIVE, "check active")
 			}
 			if len(image.FastHash) == 0 {
@@ -2097,6 +2154,20 @@ func (img *SImage) Pipeline(ctx context.Context, userCred mcclient.TokenCredenti
 			return errors.Wrap(err, "updateChecksum")
 		}
 	}
+	{
+		// do verify signature of the final checksum
+		err := img.doVerifySignature(ctx, userCred)
+		if err != nil {
+			return errors.Wrap(err, "doVerifySignature")
+		}
+	}
+	{
+		// do scan installed packages before activation
+		err := img.doScan(ctx, userCred)
+		if err != nil {
+			return errors.Wrap(err, "doScan")
+		}
+	}
 	{
 		// do convert
 		converted, err := img.doConvert(ctx, userCred)
@@ -2126,6 +2197,7 @@ func (img *SImage) Pipeline(ctx context.Context, userCred mcclient.TokenCredenti
 	if img.Status != api.IMAGE_STATUS_ACTIVE {
 		img.SetStatus(ctx, userCred, api.IMAGE_STATUS_ACTIVE, "image pipeline complete")
 	}
+	ImageReplicationManager.onImageChanged(ctx, userCred, img.Id)
 	if updated && img.IsGuestImage.IsFalse() {
 		kwargs := jsonutils.NewDict()
 		kwargs.Set("name", jsonutils.NewString(img.GetName()))
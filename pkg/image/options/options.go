@@ -58,6 +58,14 @@ type SImageOptions struct {
 	S3UploadParallel   int    `help:"s3 upload parallel count" default:"4"`
 
 	ImageStreamWorkerCount int `help:"Image stream worker count" default:"10"`
+
+	ImageSignaturePolicy string `help:"Policy of image signature verification before image becomes active" default:"none" choices:"none|warn|enforce"`
+
+	EnableImageScan            bool   `help:"Enable scanning installed packages of images before image becomes active" default:"false"`
+	ImageVulnerabilityFeedPath string `help:"Path of offline vulnerability feed json file to match scanned packages against"`
+	ImageScanBlockSeverity     string `help:"Block images with vulnerabilities at or above the severity" default:"none" choices:"none|low|medium|high|critical"`
+
+	ImageReplicationCheckIntervalSeconds int `help:"Interval of checking image replication targets and triggering scheduled replications" default:"60"`
 }
 
 var (
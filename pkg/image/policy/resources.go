@@ -21,8 +21,11 @@ import (
 
 var (
 	imageSystemResources = []string{}
-	imageDomainResources = []string{}
-	imageUserResources   = []string{}
+	imageDomainResources = []string{
+		"image_signer_keys",
+		"image_replications",
+	}
+	imageUserResources = []string{}
 )
 
 func init() {
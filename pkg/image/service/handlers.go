@@ -61,6 +61,8 @@ func InitHandlers(app *appsrv.Application) {
 		models.ImageMemberManager,
 		models.ImagePropertyManager,
 		models.ImageSubformatManager,
+		models.ImageScanManager,
+		models.ImageReplicationTargetManager,
 
 		models.GuestImageJointManager,
 
@@ -77,6 +79,8 @@ func InitHandlers(app *appsrv.Application) {
 		models.ImageManager,
 
 		models.GuestImageManager,
+		models.ImageSignerKeyManager,
+		models.ImageReplicationManager,
 	} {
 		db.RegisterModelManager(manager)
 		handler := db.NewModelHandler(manager)
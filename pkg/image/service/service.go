@@ -161,6 +161,9 @@ func StartService() {
 		cron.AddJobAtIntervals("CleanPendingDeleteGuestImages",
 			time.Duration(options.Options.PendingDeleteCheckSeconds)*time.Second, models.GuestImageManager.CleanPendingDeleteImages)
 
+		cron.AddJobAtIntervals("SyncImageReplications",
+			time.Duration(options.Options.ImageReplicationCheckIntervalSeconds)*time.Second, models.ImageReplicationManager.SyncImageReplications)
+
 		cron.AddJobEveryFewHour("AutoPurgeSplitable", 4, 30, 0, db.AutoPurgeSplitable, false)
 
 		cron.AddJobAtIntervalsWithStartRun("TaskCleanupJob", time.Duration(options.Options.TaskArchiveIntervalMinutes)*time.Minute, taskman.TaskManager.TaskCleanupJob, true)
@@ -0,0 +1,110 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"net/http"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/util/httputils"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/image/models"
+	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+	"yunion.io/x/onecloud/pkg/util/ovfutils"
+)
+
+type GuestImageImportOvaTask struct {
+	taskman.STask
+}
+
+type GuestImageExportOvaTask struct {
+	taskman.STask
+}
+
+func init() {
+	taskman.RegisterTask(GuestImageImportOvaTask{})
+	taskman.RegisterTask(GuestImageExportOvaTask{})
+}
+
+func (self *GuestImageImportOvaTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guestImage := obj.(*models.SGuestImage)
+	ovaUrl, _ := self.Params.GetString("ova_url")
+
+	log.Infof("Import guest image %s from ova %s", guestImage.Name, ovaUrl)
+
+	self.SetStage("OnOvaImported", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		client := httputils.GetTimeoutClient(0)
+		transport := httputils.GetTransport(true)
+		transport.Proxy = options.Options.HttpTransportProxyFunc()
+		client.Transport = transport
+		resp, err := httputils.Request(client, ctx, httputils.GET, ovaUrl, http.Header{}, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		app, err := guestImage.ImportOva(ctx, self.UserCred, resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return jsonutils.Marshal(app), nil
+	})
+}
+
+func (self *GuestImageImportOvaTask) OnOvaImported(ctx context.Context, guestImage *models.SGuestImage, data jsonutils.JSONObject) {
+	logclient.AddActionLogWithStartable(self, guestImage, logclient.ACT_IMAGE_SAVE, "import from ova", self.UserCred, true)
+	app := &ovfutils.SVirtualAppliance{}
+	data.Unmarshal(app)
+	err := guestImage.CreateGuestTemplate(ctx, self.UserCred, app)
+	if err != nil {
+		// the guest image is usable without the template
+		log.Errorf("create guest template of %s fail %s", guestImage.Name, err)
+		logclient.AddActionLogWithStartable(self, guestImage, logclient.ACT_CREATE, err, self.UserCred, false)
+	}
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *GuestImageImportOvaTask) OnOvaImportedFailed(ctx context.Context, guestImage *models.SGuestImage, data jsonutils.JSONObject) {
+	guestImage.SetStatus(ctx, self.UserCred, api.IMAGE_STATUS_KILLED, data.String())
+	logclient.AddActionLogWithStartable(self, guestImage, logclient.ACT_IMAGE_SAVE, data, self.UserCred, false)
+	self.SetStageFailed(ctx, data)
+}
+
+func (self *GuestImageExportOvaTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	guestImage := obj.(*models.SGuestImage)
+	self.SetStage("OnOvaExported", nil)
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, guestImage.ExportOva(ctx, self.UserCred)
+	})
+}
+
+func (self *GuestImageExportOvaTask) OnOvaExported(ctx context.Context, guestImage *models.SGuestImage, data jsonutils.JSONObject) {
+	guestImage.SetOvaStatus(ctx, self.UserCred, api.GUEST_IMAGE_OVA_STATUS_READY, "")
+	logclient.AddActionLogWithStartable(self, guestImage, logclient.ACT_EXPORT, "export ova", self.UserCred, true)
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *GuestImageExportOvaTask) OnOvaExportedFailed(ctx context.Context, guestImage *models.SGuestImage, data jsonutils.JSONObject) {
+	guestImage.SetOvaStatus(ctx, self.UserCred, api.GUEST_IMAGE_OVA_STATUS_FAILED, data.String())
+	logclient.AddActionLogWithStartable(self, guestImage, logclient.ACT_EXPORT, data, self.UserCred, false)
+	self.SetStageFailed(ctx, data)
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"strings"
 
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
@@ -28,10 +29,13 @@ import (
 	"yunion.io/x/pkg/utils"
 
 	"yunion.io/x/onecloud/pkg/apis"
+	api "yunion.io/x/onecloud/pkg/apis/image"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
 	"yunion.io/x/onecloud/pkg/image/models"
 	"yunion.io/x/onecloud/pkg/image/options"
+	"yunion.io/x/onecloud/pkg/mcclient/auth"
+	image_modules "yunion.io/x/onecloud/pkg/mcclient/modules/image"
 )
 
 type ImageCopyFromUrlTask struct {
@@ -51,6 +55,12 @@ func (self *ImageCopyFromUrlTask) OnInit(ctx context.Context, obj db.IStandalone
 	log.Infof("Copy image from %s with compress %s", copyFrom, compress)
 
 	self.SetStage("OnImageImportComplete", nil)
+	if strings.HasPrefix(copyFrom, api.IMAGE_COPY_FROM_GLANCE_PREFIX) {
+		taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+			return nil, self.copyFromGlance(ctx, image, copyFrom)
+		})
+		return
+	}
 	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
 		header := http.Header{}
 		client := httputils.GetTimeoutClient(0)
@@ -99,6 +109,41 @@ func (self *ImageCopyFromUrlTask) OnInit(ctx context.Context, obj db.IStandalone
 	})
 }
 
+// copyFromGlance downloads the image from the image service of another
+// region and verifies the checksum of the received data
+func (self *ImageCopyFromUrlTask) copyFromGlance(ctx context.Context, image *models.SImage, copyFrom string) error {
+	if err := models.ValidateImageCopyFrom(self.UserCred, copyFrom); err != nil {
+		return err
+	}
+	region, imageId, err := models.ParseGlanceCopyFrom(copyFrom)
+	if err != nil {
+		return errors.Wrap(err, "ParseGlanceCopyFrom")
+	}
+	s := auth.GetAdminSession(ctx, region)
+	meta, reader, size, err := image_modules.Images.Download2(s, imageId, "", false)
+	if err != nil {
+		return errors.Wrapf(err, "download image %s from region %s", imageId, region)
+	}
+	defer reader.Close()
+
+	err = image.SaveImageFromStream(reader, size, true)
+	if err != nil {
+		return errors.Wrap(err, "SaveImageFromStream")
+	}
+	checksum, _ := meta.GetString("checksum")
+	if len(checksum) > 0 && checksum != image.Checksum {
+		return errors.Wrapf(errors.ErrInvalidStatus, "checksum mismatch, source %s received %s", checksum, image.Checksum)
+	}
+	ossChecksum, _ := meta.GetString("oss_checksum")
+	if len(ossChecksum) > 0 {
+		db.Update(image, func() error {
+			image.OssChecksum = ossChecksum
+			return nil
+		})
+	}
+	return nil
+}
+
 func (self *ImageCopyFromUrlTask) OnImageImportComplete(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
 	image := obj.(*models.SImage)
 	image.OnSaveTaskSuccess(self, self.UserCred, "create upload success")
@@ -0,0 +1,60 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/appsrv"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db"
+	"yunion.io/x/onecloud/pkg/cloudcommon/db/taskman"
+	"yunion.io/x/onecloud/pkg/image/models"
+	"yunion.io/x/onecloud/pkg/util/logclient"
+)
+
+type ImageReplicationTask struct {
+	taskman.STask
+}
+
+func init() {
+	replicationWorker := appsrv.NewWorkerManager("ImageReplicationTaskWorkerManager", 4, 512, true)
+	taskman.RegisterTaskAndWorker(ImageReplicationTask{}, replicationWorker)
+}
+
+func (self *ImageReplicationTask) OnInit(ctx context.Context, obj db.IStandaloneModel, data jsonutils.JSONObject) {
+	rep := obj.(*models.SImageReplication)
+	force := jsonutils.QueryBoolean(self.Params, "force", false)
+
+	self.SetStage("OnReplicateComplete", nil)
+
+	taskman.LocalTaskRun(self, func() (jsonutils.JSONObject, error) {
+		return nil, rep.Replicate(ctx, self.UserCred, force)
+	})
+}
+
+func (self *ImageReplicationTask) OnReplicateComplete(ctx context.Context, rep *models.SImageReplication, data jsonutils.JSONObject) {
+	rep.SetStatus(ctx, self.UserCred, image.IMAGE_STATUS_ACTIVE, "")
+	self.SetStageComplete(ctx, nil)
+}
+
+func (self *ImageReplicationTask) OnReplicateCompleteFailed(ctx context.Context, rep *models.SImageReplication, data jsonutils.JSONObject) {
+	rep.SetStatus(ctx, self.UserCred, image.IMAGE_REPLICATION_STATUS_SYNC_FAILED, data.String())
+	db.OpsLog.LogEvent(rep, db.ACT_IMAGE_REPLICATE_FAIL, data, self.UserCred)
+	logclient.AddActionLogWithStartable(self, rep, logclient.ACT_IMAGE_REPLICATE, data, self.UserCred, false)
+	self.SetStageFailed(ctx, data)
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lbagent
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"yunion.io/x/log"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/lbagent/models"
+)
+
+// AcmeHttpChallengeResponder answers acme http-01 challenges published by
+// region on loadbalancer certificates being issued.  Haproxy routes requests
+// with path prefix /.well-known/acme-challenge/ of http listeners to it
+type AcmeHttpChallengeResponder struct {
+	lock       sync.RWMutex
+	challenges map[string]string
+}
+
+func NewAcmeHttpChallengeResponder() *AcmeHttpChallengeResponder {
+	return &AcmeHttpChallengeResponder{
+		challenges: map[string]string{},
+	}
+}
+
+// Update replaces the challenges with those found in certificates of corpus
+func (r *AcmeHttpChallengeResponder) Update(corpus *agentmodels.LoadbalancerCorpus) {
+	challenges := map[string]string{}
+	for _, lbcert := range corpus.LoadbalancerCertificates {
+		if lbcert.AcmeHttpChallenges == nil {
+			continue
+		}
+		for _, c := range lbcert.AcmeHttpChallenges.Challenges {
+			challenges[c.Token] = c.KeyAuthorization
+		}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.challenges = challenges
+}
+
+func (r *AcmeHttpChallengeResponder) keyAuthorization(token string) (string, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	keyAuth, ok := r.challenges[token]
+	return keyAuth, ok
+}
+
+func (r *AcmeHttpChallengeResponder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(req.URL.Path, computeapi.LB_CERT_ACME_HTTP01_PATH_PREFIX) {
+		http.NotFound(w, req)
+		return
+	}
+	token := strings.TrimPrefix(req.URL.Path, computeapi.LB_CERT_ACME_HTTP01_PATH_PREFIX)
+	keyAuth, ok := r.keyAuthorization(token)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(keyAuth))
+}
+
+func (r *AcmeHttpChallengeResponder) ListenAndServe(port int) {
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	log.Infof("acme http-01 challenge responder listening on %s", addr)
+	if err := http.ListenAndServe(addr, r); err != nil {
+		log.Errorf("acme http-01 challenge responder: %s", err)
+	}
+}
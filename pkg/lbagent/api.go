@@ -215,6 +215,22 @@ func (h *ApiHelper) agentPeekOnce(ctx context.Context) (*computemodels.SLoadbala
 	return agent, nil
 }
 
+func (h *ApiHelper) agentPeekDataPlane(ctx context.Context, agent *computemodels.SLoadbalancerAgent) (string, error) {
+	if agent.ClusterId == "" {
+		return api.LB_DATA_PLANE_HAPROXY, nil
+	}
+	s := h.adminClientSession(ctx)
+	data, err := modules.LoadbalancerClusters.Get(s, agent.ClusterId, nil)
+	if err != nil {
+		return "", fmt.Errorf("cluster get error: %s", err)
+	}
+	dataPlane, _ := data.GetString("data_plane")
+	if dataPlane == "" {
+		dataPlane = api.LB_DATA_PLANE_HAPROXY
+	}
+	return dataPlane, nil
+}
+
 func (h *ApiHelper) agentPeekPeers(ctx context.Context, agent *computemodels.SLoadbalancerAgent) ([]*computemodels.SLoadbalancerAgent, error) {
 	vri := agent.Params.Vrrp.VirtualRouterId
 	clusterId := agent.ClusterId
@@ -381,12 +397,18 @@ func (h *ApiHelper) doSyncAgentParams(ctx context.Context) bool {
 		unicastPeer = append(unicastPeer, peer.IP)
 	}
 	useUnicast := len(unicastPeer) == len(peers)-1
+	dataPlane, err := h.agentPeekDataPlane(ctx, agent)
+	if err != nil {
+		log.Errorf("agent get data plane failure: %s", err)
+		return false
+	}
 
 	agentParams, err := agentmodels.NewAgentParams(agent)
 	if err != nil {
 		log.Errorf("agent params prepare failure: %s", err)
 		return false
 	}
+	agentParams.DataPlane = dataPlane
 	agentParams.SetVrrpParams("notify_script", h.haStateProvider.StateScript())
 	if useUnicast {
 		agentParams.SetVrrpParams("unicast_peer", unicastPeer)
@@ -395,6 +417,9 @@ func (h *ApiHelper) doSyncAgentParams(ctx context.Context) bool {
 		if useUnicast {
 			log.Infof("use unicast vrrp from %s to %s", agent.IP, strings.Join(unicastPeer, ","))
 		}
+		if h.agentParams == nil || h.agentParams.DataPlane != dataPlane {
+			log.Infof("use %s data plane", dataPlane)
+		}
 		h.agentParams = agentParams
 		return true
 	}
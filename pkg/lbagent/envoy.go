@@ -0,0 +1,120 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lbagent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"yunion.io/x/log"
+
+	agentmodels "yunion.io/x/onecloud/pkg/lbagent/models"
+)
+
+var envoyXdsPathTypeUrls = map[string]string{
+	"/v3/discovery:listeners": agentmodels.EnvoyTypeUrlListener,
+	"/v3/discovery:clusters":  agentmodels.EnvoyTypeUrlCluster,
+	"/v3/discovery:routes":    agentmodels.EnvoyTypeUrlRoute,
+	"/v3/discovery:endpoints": agentmodels.EnvoyTypeUrlEndpoint,
+}
+
+type envoyDiscoveryRequest struct {
+	VersionInfo   string   `json:"version_info"`
+	ResourceNames []string `json:"resource_names"`
+	TypeUrl       string   `json:"type_url"`
+}
+
+type envoyDiscoveryResponse struct {
+	VersionInfo string                        `json:"version_info"`
+	Resources   agentmodels.EnvoyResourceList `json:"resources"`
+	TypeUrl     string                        `json:"type_url"`
+}
+
+// EnvoyXdsServer serves corpus rendered as envoy resources to the local
+// envoy over REST-JSON xDS (LDS/CDS/RDS/EDS)
+type EnvoyXdsServer struct {
+	lock      sync.RWMutex
+	resources *agentmodels.EnvoyResources
+}
+
+func NewEnvoyXdsServer() *EnvoyXdsServer {
+	return &EnvoyXdsServer{}
+}
+
+func (s *EnvoyXdsServer) Update(resources *agentmodels.EnvoyResources) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.resources = resources
+}
+
+func (s *EnvoyXdsServer) discover(req *envoyDiscoveryRequest) (*envoyDiscoveryResponse, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.resources == nil {
+		return nil, false
+	}
+	all := s.resources.ByTypeUrl(req.TypeUrl)
+	resp := &envoyDiscoveryResponse{
+		VersionInfo: all.Version(),
+		Resources:   all.Find(req.ResourceNames),
+		TypeUrl:     req.TypeUrl,
+	}
+	return resp, true
+}
+
+func (s *EnvoyXdsServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	typeUrl, ok := envoyXdsPathTypeUrls[req.URL.Path]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	discoveryReq := &envoyDiscoveryRequest{}
+	if err := json.NewDecoder(req.Body).Decode(discoveryReq); err != nil {
+		http.Error(w, fmt.Sprintf("decode discovery request: %v", err), http.StatusBadRequest)
+		return
+	}
+	discoveryReq.TypeUrl = typeUrl
+	resp, ok := s.discover(discoveryReq)
+	if !ok {
+		// corpus not ready yet, envoy will retry
+		http.Error(w, "resources not ready", http.StatusServiceUnavailable)
+		return
+	}
+	if resp.VersionInfo == discoveryReq.VersionInfo {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	d, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("marshal discovery response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(d)
+}
+
+func (s *EnvoyXdsServer) ListenAndServe(port int) {
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	log.Infof("envoy xds server listening on %s", addr)
+	if err := http.ListenAndServe(addr, s); err != nil {
+		log.Errorf("envoy xds server: %s", err)
+	}
+}
@@ -46,6 +46,9 @@ type HaproxyHelper struct {
 	lbagentId string
 
 	configDirMan *agentutils.ConfigDirManager
+
+	acmeResponder *AcmeHttpChallengeResponder
+	envoyXds      *EnvoyXdsServer
 }
 
 func NewHaproxyHelper(opts *Options, lbagentId string) (*HaproxyHelper, error) {
@@ -68,6 +71,15 @@ func NewHaproxyHelper(opts *Options, lbagentId string) (*HaproxyHelper, error) {
 
 	system_service.Init()
 
+	if opts.AcmeHttpChallengePort > 0 {
+		helper.acmeResponder = NewAcmeHttpChallengeResponder()
+		go helper.acmeResponder.ListenAndServe(opts.AcmeHttpChallengePort)
+	}
+	if opts.EnvoyXdsPort > 0 {
+		helper.envoyXds = NewEnvoyXdsServer()
+		go helper.envoyXds.ListenAndServe(opts.EnvoyXdsPort)
+	}
+
 	return helper, nil
 }
 
@@ -107,6 +119,7 @@ func (h *HaproxyHelper) handleStopDaemonsCmd(ctx context.Context) {
 	pidFiles := []*agentutils.PidFile{
 		h.gobetweenPidFile(),
 		h.haproxyPidFile(),
+		h.envoyPidFile(),
 		h.telegrafPidFile(),
 	}
 	wg := &sync.WaitGroup{}
@@ -138,6 +151,7 @@ func (h *HaproxyHelper) handleStopDaemonsCmd(ctx context.Context) {
 }
 
 func (h *HaproxyHelper) handleUseCorpusCmd(ctx context.Context, cmd *LbagentCmd) {
+	var envoyResources *agentmodels.EnvoyResources
 	// haproxy config dir
 	dir, err := h.configDirMan.NewDir(func(dir string) error {
 		cmdData := cmd.Data.(*LbagentCmdUseCorpusData)
@@ -147,28 +161,53 @@ func (h *HaproxyHelper) handleUseCorpusCmd(ctx context.Context, cmd *LbagentCmd)
 			opt := fmt.Sprintf("stats socket %s expose-fd listeners", h.haproxyStatsSocketFile())
 			agentParams.SetHaproxyParams("global_stats_socket", opt)
 		}
-		var genHaproxyConfigsResult *agentmodels.GenHaproxyConfigsResult
-		var err error
-		{
-			// haproxy toplevel global/defaults config
-			err = corpus.GenHaproxyToplevelConfig(dir, agentParams)
+		if h.acmeResponder != nil {
+			h.acmeResponder.Update(corpus)
+			agentParams.SetHaproxyParams("acme_http_challenge_port", h.opts.AcmeHttpChallengePort)
+		}
+		var loadbalancersEnabled []*agentmodels.Loadbalancer
+		if agentParams.IsEnvoyDataPlane() {
+			if h.envoyXds == nil {
+				return fmt.Errorf("envoy data plane requires xds server, check option envoy_xds_port")
+			}
+			// envoy bootstrap config, the rest are served over xds
+			err := corpus.GenEnvoyBootstrapConfig(dir, agentParams, h.opts.EnvoyXdsPort, h.opts.EnvoyAdminPort)
 			if err != nil {
-				err = fmt.Errorf("generating haproxy toplevel config failed: %s", err)
+				err = fmt.Errorf("generating envoy bootstrap config failed: %s", err)
 				return err
 			}
-		}
-		{
-			// haproxy configs
-			genHaproxyConfigsResult, err = corpus.GenHaproxyConfigs(dir, agentParams)
+			genEnvoyConfigsResult, err := corpus.GenEnvoyConfigs(agentParams)
 			if err != nil {
-				err = fmt.Errorf("generating haproxy config failed: %s", err)
+				err = fmt.Errorf("generating envoy config failed: %s", err)
 				return err
 			}
+			envoyResources = genEnvoyConfigsResult.Resources
+			loadbalancersEnabled = genEnvoyConfigsResult.LoadbalancersEnabled
+		} else {
+			var genHaproxyConfigsResult *agentmodels.GenHaproxyConfigsResult
+			var err error
+			{
+				// haproxy toplevel global/defaults config
+				err = corpus.GenHaproxyToplevelConfig(dir, agentParams)
+				if err != nil {
+					err = fmt.Errorf("generating haproxy toplevel config failed: %s", err)
+					return err
+				}
+			}
+			{
+				// haproxy configs
+				genHaproxyConfigsResult, err = corpus.GenHaproxyConfigs(dir, agentParams)
+				if err != nil {
+					err = fmt.Errorf("generating haproxy config failed: %s", err)
+					return err
+				}
+			}
+			loadbalancersEnabled = genHaproxyConfigsResult.LoadbalancersEnabled
 		}
 		{
 			// gobetween config
 			opts := &agentmodels.GenGobetweenConfigOptions{
-				LoadbalancersEnabled: genHaproxyConfigsResult.LoadbalancersEnabled,
+				LoadbalancersEnabled: loadbalancersEnabled,
 				AgentParams:          agentParams,
 			}
 			err := corpus.GenGobetweenConfigs(dir, opts)
@@ -180,7 +219,7 @@ func (h *HaproxyHelper) handleUseCorpusCmd(ctx context.Context, cmd *LbagentCmd)
 		{
 			// keepalived config
 			opts := &agentmodels.GenKeepalivedConfigOptions{
-				LoadbalancersEnabled: genHaproxyConfigsResult.LoadbalancersEnabled,
+				LoadbalancersEnabled: loadbalancersEnabled,
 				AgentParams:          agentParams,
 			}
 			log.Infof("GenKeepalivedConfigs %s", dir)
@@ -222,12 +261,14 @@ func (h *HaproxyHelper) handleUseCorpusCmd(ctx context.Context, cmd *LbagentCmd)
 		log.Errorf("prune configs dir failed: %s", err)
 		// continue
 	}
-	if err := h.useConfigs(ctx, dir); err != nil {
+	if err := h.useConfigs(ctx, dir, envoyResources); err != nil {
 		log.Errorf("useConfigs: %s", err)
 	}
 }
 
-func (h *HaproxyHelper) useConfigs(ctx context.Context, d string) error {
+// useConfigs makes configs in dir d effective.  Envoy is used instead of
+// haproxy when envoyResources is not nil
+func (h *HaproxyHelper) useConfigs(ctx context.Context, d string, envoyResources *agentmodels.EnvoyResources) error {
 	lnF := func(old, new string) error {
 		err := os.RemoveAll(new)
 		if err != nil {
@@ -240,11 +281,13 @@ func (h *HaproxyHelper) useConfigs(ctx context.Context, d string) error {
 	gobetweenJson := filepath.Join(h.opts.haproxyConfigDir, "gobetween.json")
 	keepalivedConf := filepath.Join(h.opts.haproxyConfigDir, "keepalived.conf")
 	telegrafConf := filepath.Join(h.opts.haproxyConfigDir, "telegraf.conf")
+	envoyConf := h.envoyConf()
 	dirMap := map[string]string{
 		haproxyConfD:   d,
 		gobetweenJson:  filepath.Join(d, "gobetween.json"),
 		keepalivedConf: filepath.Join(d, "keepalived.conf"),
 		telegrafConf:   filepath.Join(d, "telegraf.conf"),
+		envoyConf:      filepath.Join(d, agentmodels.EnvoyBootstrapFile),
 	}
 	for new, old := range dirMap {
 		err := lnF(old, new)
@@ -255,8 +298,17 @@ func (h *HaproxyHelper) useConfigs(ctx context.Context, d string) error {
 	{
 		var errs []error
 		var err error
-		{
+		if envoyResources != nil {
+			// switch to envoy
+			h.envoyXds.Update(envoyResources)
+			h.stopDaemon(h.haproxyPidFile())
+			err = h.reloadEnvoy(ctx)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		} else {
 			// reload haproxy
+			h.stopDaemon(h.envoyPidFile())
 			err = h.reloadHaproxy(ctx)
 			if err != nil {
 				errs = append(errs, err)
@@ -368,6 +420,60 @@ func (h *HaproxyHelper) reloadHaproxy(ctx context.Context) error {
 	}
 }
 
+func (h *HaproxyHelper) envoyConf() string {
+	return filepath.Join(h.opts.haproxyConfigDir, agentmodels.EnvoyBootstrapFile)
+}
+
+func (h *HaproxyHelper) envoyPidFile() *agentutils.PidFile {
+	pf := agentutils.NewPidFile(
+		filepath.Join(h.opts.haproxyRunDir, "envoy.pid"),
+		"envoy",
+	)
+	return pf
+}
+
+// reloadEnvoy starts envoy if it is not running.  A running envoy picks up
+// changes from xds server and needs no reload
+func (h *HaproxyHelper) reloadEnvoy(ctx context.Context) error {
+	pidFile := h.envoyPidFile()
+	{
+		_, confirmed, err := pidFile.ConfirmOrUnlink()
+		if confirmed {
+			return nil
+		}
+		if err != nil {
+			log.Warningln(err.Error())
+		}
+	}
+	args := []string{
+		h.opts.EnvoyBin,
+		"--config-path", h.envoyConf(),
+	}
+	log.Infof("starting envoy")
+	cmd, err := h.startCmd(args)
+	if err != nil {
+		return err
+	}
+	err = agentutils.WritePidFile(cmd.Process.Pid, pidFile.Path)
+	if err != nil {
+		return fmt.Errorf("writing envoy pid file: %s", err)
+	}
+	return nil
+}
+
+// stopDaemon stops the daemon of the data plane not in use
+func (h *HaproxyHelper) stopDaemon(pidFile *agentutils.PidFile) {
+	proc, confirmed, err := pidFile.ConfirmOrUnlink()
+	if confirmed {
+		log.Infof("stopping %s(%d)", pidFile.Comm, proc.Pid)
+		proc.Signal(syscall.SIGTERM)
+		proc.Wait()
+	}
+	if err != nil {
+		log.Warningln(err.Error())
+	}
+}
+
 func (h *HaproxyHelper) gobetweenConf() string {
 	return filepath.Join(h.opts.haproxyConfigDir, "gobetween.json")
 }
@@ -23,6 +23,7 @@ import (
 	"yunion.io/x/jsonutils"
 	"yunion.io/x/log"
 
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	compute_models "yunion.io/x/onecloud/pkg/compute/models"
 )
 
@@ -43,6 +44,9 @@ type AgentParams struct {
 	HaproxyConfigTmpl    *template.Template
 	TelegrafConfigTmpl   *template.Template
 	Data                 map[string]map[string]interface{}
+
+	// DataPlane is taken from the cluster the agent joined: haproxy or envoy
+	DataPlane string
 }
 
 func NewAgentParams(agent *compute_models.SLoadbalancerAgent) (*AgentParams, error) {
@@ -98,6 +102,9 @@ func (p *AgentParams) Equals(p2 *AgentParams) bool {
 	if !reflect.DeepEqual(agentP.Params, agentP2.Params) {
 		return false
 	}
+	if p.DataPlane != p2.DataPlane {
+		return false
+	}
 	keys := []string{"notify_script", "unicast_peer"}
 	for _, key := range keys {
 		v := p.GetVrrpParams(key)
@@ -142,6 +149,14 @@ func (p *AgentParams) SetHaproxyParams(k string, v interface{}) map[string]inter
 	return p.setXxParams("haproxy", k, v)
 }
 
+func (p *AgentParams) GetHaproxyParams(k string) interface{} {
+	return p.getXxParams("haproxy", k)
+}
+
+func (p *AgentParams) IsEnvoyDataPlane() bool {
+	return p.DataPlane == computeapi.LB_DATA_PLANE_ENVOY
+}
+
 func (p *AgentParams) SetTelegrafParams(k string, v interface{}) map[string]interface{} {
 	return p.setXxParams("telegraf", k, v)
 }
@@ -0,0 +1,1042 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"yunion.io/x/log"
+	"yunion.io/x/pkg/errors"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	compute_models "yunion.io/x/onecloud/pkg/compute/models"
+	agentutils "yunion.io/x/onecloud/pkg/lbagent/utils"
+)
+
+// type urls of envoy v3 xDS resources served by lbagent
+const (
+	EnvoyTypeUrlListener = "type.googleapis.com/envoy.config.listener.v3.Listener"
+	EnvoyTypeUrlCluster  = "type.googleapis.com/envoy.config.cluster.v3.Cluster"
+	EnvoyTypeUrlRoute    = "type.googleapis.com/envoy.config.route.v3.RouteConfiguration"
+	EnvoyTypeUrlEndpoint = "type.googleapis.com/envoy.config.endpoint.v3.ClusterLoadAssignment"
+
+	EnvoyXdsClusterName = "lbagent_xds"
+	EnvoyBootstrapFile  = "envoy.json"
+
+	envoyHttpRateLimitFilter = "envoy.filters.http.local_ratelimit"
+	envoyTransportMatchKey   = "lbagent_transport"
+)
+
+var envoyConfigErrNop = errors.Error("nop envoy config")
+
+// EnvoyResource is an envoy xDS resource in its proto3 json form, with
+// "@type" set
+type EnvoyResource map[string]interface{}
+
+// Name returns the name by which envoy subscribes to the resource
+func (r EnvoyResource) Name() string {
+	if name, ok := r["name"].(string); ok {
+		return name
+	}
+	name, _ := r["cluster_name"].(string)
+	return name
+}
+
+type EnvoyResourceList []EnvoyResource
+
+func (l EnvoyResourceList) Len() int           { return len(l) }
+func (l EnvoyResourceList) Less(i, j int) bool { return l[i].Name() < l[j].Name() }
+func (l EnvoyResourceList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+// Find returns resources with given names, or all of them if names is empty
+func (l EnvoyResourceList) Find(names []string) EnvoyResourceList {
+	if len(names) == 0 {
+		return l
+	}
+	r := EnvoyResourceList{}
+	for _, res := range l {
+		for _, name := range names {
+			if res.Name() == name {
+				r = append(r, res)
+				break
+			}
+		}
+	}
+	return r
+}
+
+// Version is a digest of the list.  Envoy polls with version of the last
+// accepted response and nothing is sent if it is still up to date
+func (l EnvoyResourceList) Version() string {
+	d, _ := json.Marshal(l)
+	sum := sha256.Sum256(d)
+	return fmt.Sprintf("%x", sum[:8])
+}
+
+type EnvoyResources struct {
+	Listeners EnvoyResourceList
+	Clusters  EnvoyResourceList
+	Routes    EnvoyResourceList
+	Endpoints EnvoyResourceList
+}
+
+func NewEnvoyResources() *EnvoyResources {
+	return &EnvoyResources{
+		Listeners: EnvoyResourceList{},
+		Clusters:  EnvoyResourceList{},
+		Routes:    EnvoyResourceList{},
+		Endpoints: EnvoyResourceList{},
+	}
+}
+
+func (r *EnvoyResources) ByTypeUrl(typeUrl string) EnvoyResourceList {
+	switch typeUrl {
+	case EnvoyTypeUrlListener:
+		return r.Listeners
+	case EnvoyTypeUrlCluster:
+		return r.Clusters
+	case EnvoyTypeUrlRoute:
+		return r.Routes
+	case EnvoyTypeUrlEndpoint:
+		return r.Endpoints
+	}
+	return nil
+}
+
+func (r *EnvoyResources) sort() {
+	for _, l := range []EnvoyResourceList{r.Listeners, r.Clusters, r.Routes, r.Endpoints} {
+		sort.Sort(l)
+	}
+}
+
+type GenEnvoyConfigsResult struct {
+	LoadbalancersEnabled []*Loadbalancer
+	Resources            *EnvoyResources
+}
+
+func envoyXdsConfigSource() map[string]interface{} {
+	return map[string]interface{}{
+		"resource_api_version": "V3",
+		"api_config_source": map[string]interface{}{
+			"api_type":              "REST",
+			"transport_api_version": "V3",
+			"cluster_names":         []string{EnvoyXdsClusterName},
+			"refresh_delay":         "1s",
+		},
+	}
+}
+
+func envoySocketAddress(address string, port int) map[string]interface{} {
+	return map[string]interface{}{
+		"socket_address": map[string]interface{}{
+			"address":    address,
+			"port_value": port,
+		},
+	}
+}
+
+func envoyDuration(seconds int) string {
+	return fmt.Sprintf("%ds", seconds)
+}
+
+func envoyStaticCluster(name string, address string, port int) map[string]interface{} {
+	return map[string]interface{}{
+		"name":            name,
+		"type":            "STATIC",
+		"connect_timeout": "1s",
+		"load_assignment": map[string]interface{}{
+			"cluster_name": name,
+			"endpoints": []interface{}{
+				map[string]interface{}{
+					"lb_endpoints": []interface{}{
+						map[string]interface{}{
+							"endpoint": map[string]interface{}{
+								"address": envoySocketAddress(address, port),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// GenEnvoyBootstrapConfig writes envoy bootstrap config pointing lds and cds
+// to the xDS server embedded in lbagent
+func (b *LoadbalancerCorpus) GenEnvoyBootstrapConfig(dir string, opts *AgentParams, xdsPort, adminPort int) error {
+	agent := opts.AgentModel
+	bootstrap := map[string]interface{}{
+		"node": map[string]interface{}{
+			"id":      agent.Id,
+			"cluster": agent.ClusterId,
+		},
+		"admin": map[string]interface{}{
+			"address": envoySocketAddress("127.0.0.1", adminPort),
+		},
+		"dynamic_resources": map[string]interface{}{
+			"lds_config": envoyXdsConfigSource(),
+			"cds_config": envoyXdsConfigSource(),
+		},
+		"static_resources": map[string]interface{}{
+			"clusters": []interface{}{
+				envoyStaticCluster(EnvoyXdsClusterName, "127.0.0.1", xdsPort),
+			},
+		},
+	}
+	d, err := json.MarshalIndent(bootstrap, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal envoy bootstrap")
+	}
+	p := filepath.Join(dir, EnvoyBootstrapFile)
+	return ioutil.WriteFile(p, d, agentutils.FileModeFile)
+}
+
+// GenEnvoyConfigs renders listeners of the corpus into envoy xDS resources.
+// It follows GenHaproxyConfigs in what is served and how
+func (b *LoadbalancerCorpus) GenEnvoyConfigs(opts *AgentParams) (*GenEnvoyConfigsResult, error) {
+	r := &GenEnvoyConfigsResult{
+		LoadbalancersEnabled: []*Loadbalancer{},
+		Resources:            NewEnvoyResources(),
+	}
+	if port := haproxyAcmeHttpChallengePort(opts); port > 0 {
+		cluster := EnvoyResource(envoyStaticCluster(haproxyAcmeHttpChallengeBackendId, "127.0.0.1", port))
+		cluster["@type"] = EnvoyTypeUrlCluster
+		r.Resources.Clusters = append(r.Resources.Clusters, cluster)
+	}
+	for _, lb := range b.Loadbalancers {
+		if lb.ClusterId != opts.AgentModel.ClusterId {
+			continue
+		}
+		if lb.Status != "enabled" {
+			continue
+		}
+		if lb.Address == "" {
+			continue
+		}
+		if len(lb.Listeners) == 0 {
+			continue
+		}
+		hasActiveListener := false
+		for _, listener := range lb.Listeners {
+			if listener.Status != "enabled" {
+				continue
+			}
+			var err error
+			switch listener.ListenerType {
+			case "http", "https":
+				err = b.genEnvoyConfigHttp(r.Resources, listener, opts)
+			case "tcp":
+				err = b.genEnvoyConfigTcp(r.Resources, listener, opts)
+			case "udp":
+				// udp is still served by gobetween
+				r.LoadbalancersEnabled = append(r.LoadbalancersEnabled, lb)
+				continue
+			default:
+				log.Infof("envoy: ignore listener type %s", listener.ListenerType)
+				continue
+			}
+			if err == envoyConfigErrNop {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			hasActiveListener = true
+		}
+		if hasActiveListener {
+			r.LoadbalancersEnabled = append(r.LoadbalancersEnabled, lb)
+		}
+	}
+	r.Resources.sort()
+	return r, nil
+}
+
+func (b *LoadbalancerCorpus) genEnvoyListener(listener *LoadbalancerListener, filters []interface{}) EnvoyResource {
+	lb := listener.loadbalancer
+	filterChain := map[string]interface{}{
+		"filters": filters,
+	}
+	if listener.ListenerType == "https" && listener.certificate != nil {
+		tlsContext := map[string]interface{}{
+			"tls_certificates": []interface{}{
+				map[string]interface{}{
+					"certificate_chain": map[string]interface{}{
+						"inline_string": listener.certificate.Certificate,
+					},
+					"private_key": map[string]interface{}{
+						"inline_string": listener.certificate.PrivateKey,
+					},
+				},
+			},
+		}
+		if listener.TLSCipherPolicy != "" {
+			if ver := agentutils.EnvoyTlsMinimumProtocolVersion(listener.TLSCipherPolicy); ver != "" {
+				tlsContext["tls_params"] = map[string]interface{}{
+					"tls_minimum_protocol_version": ver,
+				}
+			}
+		}
+		if listener.EnableHttp2 {
+			tlsContext["alpn_protocols"] = []string{"h2", "http/1.1"}
+		}
+		filterChain["transport_socket"] = map[string]interface{}{
+			"name": "envoy.transport_sockets.tls",
+			"typed_config": map[string]interface{}{
+				"@type":              "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.DownstreamTlsContext",
+				"common_tls_context": tlsContext,
+			},
+		}
+	}
+	return EnvoyResource{
+		"@type":         EnvoyTypeUrlListener,
+		"name":          listener.Id,
+		"address":       envoySocketAddress(lb.GetAddress(), listener.ListenerPort),
+		"filter_chains": []interface{}{filterChain},
+	}
+}
+
+// genEnvoyAclRules returns rbac rules of listener acl, nil if acl is not in
+// effect
+func (b *LoadbalancerCorpus) genEnvoyAclRules(listener *LoadbalancerListener) map[string]interface{} {
+	if listener.AclStatus != "on" {
+		return nil
+	}
+	lbacl, ok := b.LoadbalancerAcls[listener.AclId]
+	if !ok || lbacl.AclEntries == nil || len(*lbacl.AclEntries) == 0 {
+		return nil
+	}
+	var action string
+	switch listener.AclType {
+	case "black":
+		action = "DENY"
+	case "white":
+		action = "ALLOW"
+	default:
+		return nil
+	}
+	principals := []interface{}{}
+	for _, aclEntry := range *lbacl.AclEntries {
+		prefix, prefixLen, err := envoyCidrRange(aclEntry.Cidr)
+		if err != nil {
+			log.Warningf("acl %s(%s): %v", lbacl.Name, lbacl.Id, err)
+			continue
+		}
+		principals = append(principals, map[string]interface{}{
+			"direct_remote_ip": map[string]interface{}{
+				"address_prefix": prefix,
+				"prefix_len":     prefixLen,
+			},
+		})
+	}
+	return map[string]interface{}{
+		"action": action,
+		"policies": map[string]interface{}{
+			"acl-" + listener.AclId: map[string]interface{}{
+				"permissions": []interface{}{
+					map[string]interface{}{"any": true},
+				},
+				"principals": principals,
+			},
+		},
+	}
+}
+
+func envoyCidrRange(cidr string) (string, int, error) {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return "", 0, fmt.Errorf("invalid acl entry %q", cidr)
+		}
+		if ip.To4() != nil {
+			return ip.String(), 32, nil
+		}
+		return ip.String(), 128, nil
+	}
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid acl entry %q", cidr)
+	}
+	prefixLen, _ := ipnet.Mask.Size()
+	return ipnet.IP.String(), prefixLen, nil
+}
+
+// genEnvoyConnLimitFilter limits concurrent connections of the listener.
+// Unlike haproxy maxconn, excess connections are closed instead of queued.
+// Per source connection limit has no counterpart in envoy and is rejected by
+// region for listeners of envoy clusters
+func (b *LoadbalancerCorpus) genEnvoyConnLimitFilter(listener *LoadbalancerListener) map[string]interface{} {
+	if listener.MaxConnPerSrc > 0 {
+		log.Warningf("envoy: listener %s(%s): max_conn_per_src is not supported, ignored", listener.Name, listener.Id)
+	}
+	if listener.MaxConn <= 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"name": "envoy.filters.network.connection_limit",
+		"typed_config": map[string]interface{}{
+			"@type":           "type.googleapis.com/envoy.extensions.filters.network.connection_limit.v3.ConnectionLimit",
+			"stat_prefix":     listener.Id + "_conn",
+			"max_connections": listener.MaxConn,
+		},
+	}
+}
+
+type envoyTransport struct {
+	proxyProtocol string
+	ssl           bool
+}
+
+func (t envoyTransport) key() string {
+	s := "raw"
+	if t.ssl {
+		s = "tls"
+	}
+	if t.proxyProtocol != "" {
+		s = "proxy_" + strings.ToLower(t.proxyProtocol) + "_" + s
+	}
+	return s
+}
+
+func (t envoyTransport) socket() map[string]interface{} {
+	var socket map[string]interface{}
+	if t.ssl {
+		// verify none, as haproxy does
+		socket = map[string]interface{}{
+			"name": "envoy.transport_sockets.tls",
+			"typed_config": map[string]interface{}{
+				"@type": "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext",
+			},
+		}
+	} else {
+		socket = map[string]interface{}{
+			"name": "envoy.transport_sockets.raw_buffer",
+			"typed_config": map[string]interface{}{
+				"@type": "type.googleapis.com/envoy.extensions.transport_sockets.raw_buffer.v3.RawBuffer",
+			},
+		}
+	}
+	if t.proxyProtocol != "" {
+		socket = map[string]interface{}{
+			"name": "envoy.transport_sockets.upstream_proxy_protocol",
+			"typed_config": map[string]interface{}{
+				"@type": "type.googleapis.com/envoy.extensions.transport_sockets.proxy_protocol.v3.ProxyProtocolUpstreamTransport",
+				"config": map[string]interface{}{
+					"version": t.proxyProtocol,
+				},
+				"transport_socket": socket,
+			},
+		}
+	}
+	return socket
+}
+
+// envoyStickySessionHashPolicy returns route hash policy implementing sticky
+// session of http listeners
+func envoyStickySessionHashPolicy(listener *LoadbalancerListener) map[string]interface{} {
+	if listener.StickySession != "on" {
+		return nil
+	}
+	cookie := listener.StickySessionCookie
+	switch listener.StickySessionType {
+	case "insert":
+		if cookie == "" {
+			cookie = "SERVERID"
+		}
+		// envoy sets the cookie if it is absent when ttl is present
+		return map[string]interface{}{
+			"cookie": map[string]interface{}{
+				"name": cookie,
+				"ttl":  envoyDuration(listener.StickySessionCookieTimeout),
+			},
+		}
+	case "server":
+		if cookie != "" {
+			return map[string]interface{}{
+				"cookie": map[string]interface{}{
+					"name": cookie,
+				},
+			}
+		}
+	}
+	return nil
+}
+
+func envoySourceIpHashPolicy(listener *LoadbalancerListener) bool {
+	return listener.Scheduler == "sch" || listener.Scheduler == "tch"
+}
+
+// genEnvoyCluster adds cluster and its endpoints with backends of the group.
+// It is the counterpart of genHaproxyConfigBackend
+func (b *LoadbalancerCorpus) genEnvoyCluster(res *EnvoyResources, name string, listener *LoadbalancerListener, backendGroup *LoadbalancerBackendGroup) error {
+	lbPolicy, err := agentutils.EnvoyLbPolicy(listener.Scheduler)
+	if err != nil {
+		return err
+	}
+	if listener.ListenerType != "tcp" && envoyStickySessionHashPolicy(listener) != nil {
+		lbPolicy = agentutils.EnvoyLbPolicyRingHash
+	}
+	cluster := EnvoyResource{
+		"@type":           EnvoyTypeUrlCluster,
+		"name":            name,
+		"type":            "EDS",
+		"lb_policy":       lbPolicy,
+		"connect_timeout": envoyDuration(5),
+		"eds_cluster_config": map[string]interface{}{
+			"eds_config":   envoyXdsConfigSource(),
+			"service_name": name,
+		},
+	}
+	if listener.BackendConnectTimeout > 0 {
+		cluster["connect_timeout"] = envoyDuration(listener.BackendConnectTimeout)
+	}
+	if listener.HealthCheck == "on" {
+		rise := listener.HealthCheckRise
+		fall := listener.HealthCheckFall
+		intv := listener.HealthCheckInterval
+		timeout := listener.HealthCheckTimeout
+		if rise == 0 {
+			rise = 2
+		}
+		if fall == 0 {
+			fall = 3
+		}
+		if intv == 0 {
+			intv = 2
+		}
+		if timeout == 0 {
+			timeout = intv
+		}
+		healthCheck := map[string]interface{}{
+			"timeout":             envoyDuration(timeout),
+			"interval":            envoyDuration(intv),
+			"healthy_threshold":   rise,
+			"unhealthy_threshold": fall,
+		}
+		if listener.HealthCheckType == "http" {
+			uri := listener.HealthCheckURI
+			if uri == "" {
+				uri = "/"
+			}
+			httpCheck := map[string]interface{}{
+				"path":   uri,
+				"method": "HEAD",
+			}
+			if listener.HealthCheckDomain != "" {
+				httpCheck["host"] = listener.HealthCheckDomain
+			}
+			if statuses := agentutils.EnvoyHealthCheckExpectedStatuses(listener.HealthCheckHttpCode); len(statuses) > 0 {
+				httpCheck["expected_statuses"] = statuses
+			}
+			healthCheck["http_health_check"] = httpCheck
+		} else {
+			healthCheck["tcp_health_check"] = map[string]interface{}{}
+		}
+		cluster["health_checks"] = []interface{}{healthCheck}
+	}
+
+	listenerProxyProtocol, err := agentutils.EnvoyProxyProtocolVersion(listener.SendProxy)
+	if err != nil {
+		return fmt.Errorf("listener %s(%s): %v", listener.Name, listener.Id, err)
+	}
+	backendIds := []string{}
+	for id := range backendGroup.Backends {
+		backendIds = append(backendIds, id)
+	}
+	sort.Strings(backendIds)
+	var (
+		lbEndpoints = []interface{}{}
+		transports  = map[string]envoyTransport{}
+		transportOf = map[string]string{}
+	)
+	for _, id := range backendIds {
+		backend := backendGroup.Backends[id]
+		transport := envoyTransport{
+			proxyProtocol: listenerProxyProtocol,
+			ssl:           backend.Ssl == "on",
+		}
+		if transport.proxyProtocol == "" {
+			transport.proxyProtocol, err = agentutils.EnvoyProxyProtocolVersion(backend.SendProxy)
+			if err != nil {
+				return fmt.Errorf("backend %s(%s): %v", backend.Name, backend.Id, err)
+			}
+		}
+		transports[transport.key()] = transport
+		transportOf[id] = transport.key()
+
+		weight := backend.Weight
+		if listener.Scheduler == "rr" || weight <= 0 {
+			weight = 1
+		}
+		address, port := backend.GetAddressPort()
+		lbEndpoints = append(lbEndpoints, map[string]interface{}{
+			"endpoint": map[string]interface{}{
+				"address":  envoySocketAddress(address, port),
+				"hostname": backend.Id,
+			},
+			"load_balancing_weight": weight,
+		})
+	}
+	if len(transports) == 1 {
+		for _, transport := range transports {
+			if transport != (envoyTransport{}) {
+				cluster["transport_socket"] = transport.socket()
+			}
+		}
+	} else if len(transports) > 1 {
+		// backends differ in ssl or proxy protocol.  Endpoints pick their
+		// transport socket by metadata
+		keys := []string{}
+		for key := range transports {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		matches := []interface{}{}
+		for _, key := range keys {
+			matches = append(matches, map[string]interface{}{
+				"name": key,
+				"match": map[string]interface{}{
+					envoyTransportMatchKey: key,
+				},
+				"transport_socket": transports[key].socket(),
+			})
+		}
+		cluster["transport_socket_matches"] = matches
+		for i, id := range backendIds {
+			lbEndpoints[i].(map[string]interface{})["metadata"] = map[string]interface{}{
+				"filter_metadata": map[string]interface{}{
+					"envoy.transport_socket_match": map[string]interface{}{
+						envoyTransportMatchKey: transportOf[id],
+					},
+				},
+			}
+		}
+	}
+	endpoints := EnvoyResource{
+		"@type":        EnvoyTypeUrlEndpoint,
+		"cluster_name": name,
+		"endpoints": []interface{}{
+			map[string]interface{}{
+				"lb_endpoints": lbEndpoints,
+			},
+		},
+	}
+	res.Clusters = append(res.Clusters, cluster)
+	res.Endpoints = append(res.Endpoints, endpoints)
+	return nil
+}
+
+func (b *LoadbalancerCorpus) genEnvoyConfigTcp(res *EnvoyResources, listener *LoadbalancerListener, opts *AgentParams) error {
+	lb := listener.loadbalancer
+	if listener.BackendGroupId == "" {
+		return envoyConfigErrNop
+	}
+	backendGroup := lb.BackendGroups[listener.BackendGroupId]
+	clusterName := fmt.Sprintf("backends_listener-%s", listener.Id)
+	if err := b.genEnvoyCluster(res, clusterName, listener, backendGroup); err != nil {
+		return err
+	}
+
+	filters := []interface{}{}
+	if rules := b.genEnvoyAclRules(listener); rules != nil {
+		filters = append(filters, map[string]interface{}{
+			"name": "envoy.filters.network.rbac",
+			"typed_config": map[string]interface{}{
+				"@type":       "type.googleapis.com/envoy.extensions.filters.network.rbac.v3.RBAC",
+				"stat_prefix": listener.Id + "_acl",
+				"rules":       rules,
+			},
+		})
+	}
+	if filter := b.genEnvoyConnLimitFilter(listener); filter != nil {
+		filters = append(filters, filter)
+	}
+	tcpProxy := map[string]interface{}{
+		"@type":       "type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy",
+		"stat_prefix": listener.Id,
+		"cluster":     clusterName,
+	}
+	// haproxy sets timeout tunnel to backend idle timeout, which takes
+	// precedence over client idle timeout once connected
+	if listener.BackendIdleTimeout > 0 {
+		tcpProxy["idle_timeout"] = envoyDuration(listener.BackendIdleTimeout)
+	} else if listener.ClientIdleTimeout > 0 {
+		tcpProxy["idle_timeout"] = envoyDuration(listener.ClientIdleTimeout)
+	}
+	if envoySourceIpHashPolicy(listener) {
+		tcpProxy["hash_policy"] = []interface{}{
+			map[string]interface{}{
+				"source_ip": map[string]interface{}{},
+			},
+		}
+	}
+	filters = append(filters, map[string]interface{}{
+		"name":         "envoy.filters.network.tcp_proxy",
+		"typed_config": tcpProxy,
+	})
+	res.Listeners = append(res.Listeners, b.genEnvoyListener(listener, filters))
+	return nil
+}
+
+// envoyRouteMatch matches like haproxy conditions hdr_dom(host) and path_beg
+func envoyRouteMatch(domain, path string) map[string]interface{} {
+	match := map[string]interface{}{
+		"prefix": "/",
+	}
+	if path != "" {
+		match["prefix"] = path
+	}
+	if domain != "" {
+		regex := fmt.Sprintf(`^(.*\.)?%s(\..*)?(:[0-9]+)?$`, regexp.QuoteMeta(domain))
+		match["headers"] = []interface{}{
+			map[string]interface{}{
+				"name": ":authority",
+				"string_match": map[string]interface{}{
+					"safe_regex": map[string]interface{}{
+						"regex": regex,
+					},
+				},
+			},
+		}
+	}
+	return match
+}
+
+func envoyRedirectAction(r *compute_models.SLoadbalancerHTTPRedirect, listenerType string) map[string]interface{} {
+	scheme := r.RedirectScheme
+	if scheme == "" {
+		scheme = strings.ToLower(listenerType)
+	}
+	redirect := map[string]interface{}{
+		"response_code":   agentutils.EnvoyRedirectResponseCode(r.RedirectCode),
+		"scheme_redirect": scheme,
+	}
+	if r.RedirectHost != "" {
+		redirect["host_redirect"] = r.RedirectHost
+	}
+	if r.RedirectPath != "" {
+		// location of haproxy carries no query string in this case
+		redirect["path_redirect"] = r.RedirectPath
+		redirect["strip_query"] = true
+	}
+	return redirect
+}
+
+// envoyHeaderRewrites sets header manipulation fields of a virtual host or
+// a route
+func envoyHeaderRewrites(target map[string]interface{}, rewrites *computeapi.SLoadbalancerHTTPHeaderRewrites) error {
+	if rewrites == nil {
+		return nil
+	}
+	for i := range *rewrites {
+		rewrite := &(*rewrites)[i]
+		prefix := "request"
+		if rewrite.Direction == computeapi.LB_HTTP_HEADER_DIRECTION_RESPONSE {
+			prefix = "response"
+		}
+		switch rewrite.Action {
+		case computeapi.LB_HTTP_HEADER_ACTION_DEL:
+			key := prefix + "_headers_to_remove"
+			names, _ := target[key].([]string)
+			target[key] = append(names, rewrite.Name)
+		case computeapi.LB_HTTP_HEADER_ACTION_ADD, computeapi.LB_HTTP_HEADER_ACTION_SET:
+			value, err := agentutils.EnvoyHeaderValue(rewrite.Value)
+			if err != nil {
+				return err
+			}
+			appendAction := "APPEND_IF_EXISTS_OR_ADD"
+			if rewrite.Action == computeapi.LB_HTTP_HEADER_ACTION_SET {
+				appendAction = "OVERWRITE_IF_EXISTS_OR_ADD"
+			}
+			key := prefix + "_headers_to_add"
+			headers, _ := target[key].([]interface{})
+			target[key] = append(headers, map[string]interface{}{
+				"header": map[string]interface{}{
+					"key":   rewrite.Name,
+					"value": value,
+				},
+				"append_action": appendAction,
+			})
+		default:
+			return fmt.Errorf("unknown header rewrite action %q", rewrite.Action)
+		}
+	}
+	return nil
+}
+
+// envoyHttpRateLimit returns per route config of local rate limit filter.
+// The token bucket allows bursts of 10 seconds worth of requests, like the
+// 10s window of haproxy stick tables.  Per client limits are rejected by
+// region for listeners of envoy clusters
+func envoyHttpRateLimit(statPrefix string, limiter *compute_models.SLoadbalancerHTTPRateLimiter) map[string]interface{} {
+	if limiter.HTTPRequestRatePerSrc > 0 || limiter.HTTPRequestRatePerHeader > 0 {
+		log.Warningf("envoy: %s: per client request rate limit is not supported, ignored", statPrefix)
+	}
+	if limiter.HTTPRequestRate <= 0 {
+		return nil
+	}
+	enabled := map[string]interface{}{
+		"default_value": map[string]interface{}{
+			"numerator":   100,
+			"denominator": "HUNDRED",
+		},
+		"runtime_key": "local_rate_limit_enabled",
+	}
+	return map[string]interface{}{
+		"@type":       "type.googleapis.com/envoy.extensions.filters.http.local_ratelimit.v3.LocalRateLimit",
+		"stat_prefix": statPrefix,
+		"token_bucket": map[string]interface{}{
+			"max_tokens":      limiter.HTTPRequestRate * 10,
+			"tokens_per_fill": limiter.HTTPRequestRate,
+			"fill_interval":   "1s",
+		},
+		"filter_enabled":  enabled,
+		"filter_enforced": enabled,
+	}
+}
+
+func (b *LoadbalancerCorpus) genEnvoyBackendRoute(
+	res *EnvoyResources,
+	clusterName string,
+	match map[string]interface{},
+	listener *LoadbalancerListener,
+	backendGroup *LoadbalancerBackendGroup,
+	limiter *compute_models.SLoadbalancerHTTPRateLimiter,
+	rewrites *computeapi.SLoadbalancerHTTPHeaderRewrites,
+) (map[string]interface{}, error) {
+	if err := b.genEnvoyCluster(res, clusterName, listener, backendGroup); err != nil {
+		return nil, err
+	}
+	action := map[string]interface{}{
+		"cluster": clusterName,
+		// envoy defaults to 15s, haproxy has no such limit
+		"timeout": "0s",
+	}
+	if listener.BackendIdleTimeout > 0 {
+		action["idle_timeout"] = envoyDuration(listener.BackendIdleTimeout)
+	}
+	hashPolicies := []interface{}{}
+	if policy := envoyStickySessionHashPolicy(listener); policy != nil {
+		hashPolicies = append(hashPolicies, policy)
+	} else if envoySourceIpHashPolicy(listener) {
+		hashPolicies = append(hashPolicies, map[string]interface{}{
+			"connection_properties": map[string]interface{}{
+				"source_ip": true,
+			},
+		})
+	}
+	if len(hashPolicies) > 0 {
+		action["hash_policy"] = hashPolicies
+	}
+	route := map[string]interface{}{
+		"name":  clusterName,
+		"match": match,
+		"route": action,
+	}
+	if rateLimit := envoyHttpRateLimit(clusterName, limiter); rateLimit != nil {
+		route["typed_per_filter_config"] = map[string]interface{}{
+			envoyHttpRateLimitFilter: rateLimit,
+		}
+	}
+	if err := envoyHeaderRewrites(route, rewrites); err != nil {
+		return nil, err
+	}
+	return route, nil
+}
+
+// genEnvoyConfigHttp is the counterpart of genHaproxyConfigHttp.  Note that
+// haproxy evaluates http-request redirect before use_backend, so redirect
+// routes come first
+func (b *LoadbalancerCorpus) genEnvoyConfigHttp(res *EnvoyResources, listener *LoadbalancerListener, opts *AgentParams) error {
+	var (
+		lb = listener.loadbalancer
+	)
+	if listener.ListenerType == "https" && listener.certificate != nil && listener.certificate.Certificate == "" {
+		// acme certificate not issued yet
+		return envoyConfigErrNop
+	}
+
+	var (
+		rules          = listener.rules.OrderedEnabledList()
+		redirectRoutes = []interface{}{}
+		backendRoutes  = []interface{}{}
+
+		ruleBackendIdGen = func(id string) string {
+			return fmt.Sprintf("backends_rule-%s", id)
+		}
+	)
+	for _, rule := range rules {
+		match := envoyRouteMatch(rule.Domain, rule.Path)
+		if rule.Redirect == computeapi.LB_REDIRECT_OFF {
+			if rule.BackendGroupId == "" {
+				// just in case
+				continue
+			}
+			backendGroup := lb.BackendGroups[rule.BackendGroupId]
+			route, err := b.genEnvoyBackendRoute(res, ruleBackendIdGen(rule.Id), match, listener, backendGroup,
+				&rule.SLoadbalancerHTTPRateLimiter, rule.HTTPHeaderRewrites)
+			if err != nil {
+				return err
+			}
+			backendRoutes = append(backendRoutes, route)
+		} else if rule.Redirect == computeapi.LB_REDIRECT_RAW {
+			redirectRoutes = append(redirectRoutes, map[string]interface{}{
+				"name":     fmt.Sprintf("redirect_rule-%s", rule.Id),
+				"match":    match,
+				"redirect": envoyRedirectAction(&rule.SLoadbalancerHTTPRedirect, listener.ListenerType),
+			})
+		} else {
+			return envoyConfigErrNop
+		}
+	}
+	// default is a raw redirect
+	if listener.Redirect == computeapi.LB_REDIRECT_RAW {
+		redirectRoutes = append(redirectRoutes, map[string]interface{}{
+			"name":     fmt.Sprintf("redirect_listener_default-%s", listener.Id),
+			"match":    envoyRouteMatch("", ""),
+			"redirect": envoyRedirectAction(&listener.SLoadbalancerHTTPRedirect, listener.ListenerType),
+		})
+	}
+	// default backend group
+	if listener.Redirect == computeapi.LB_REDIRECT_OFF && listener.BackendGroupId != "" {
+		backendGroup := lb.BackendGroups[listener.BackendGroupId]
+		route, err := b.genEnvoyBackendRoute(res, fmt.Sprintf("backends_listener_default-%s", listener.Id),
+			envoyRouteMatch("", ""), listener, backendGroup, &listener.SLoadbalancerHTTPRateLimiter, nil)
+		if err != nil {
+			return err
+		}
+		backendRoutes = append(backendRoutes, route)
+	}
+	if len(redirectRoutes) == 0 && len(backendRoutes) == 0 {
+		// nothing to serve
+		return envoyConfigErrNop
+	}
+
+	routes := []interface{}{}
+	if listener.ListenerType == "http" && haproxyAcmeHttpChallengePort(opts) > 0 {
+		routes = append(routes, map[string]interface{}{
+			"name": haproxyAcmeHttpChallengeBackendId,
+			"match": map[string]interface{}{
+				"prefix": computeapi.LB_CERT_ACME_HTTP01_PATH_PREFIX,
+			},
+			"route": map[string]interface{}{
+				"cluster": haproxyAcmeHttpChallengeBackendId,
+			},
+		})
+	}
+	routes = append(routes, redirectRoutes...)
+	routes = append(routes, backendRoutes...)
+	if listener.Redirect == computeapi.LB_REDIRECT_OFF && listener.BackendGroupId == "" {
+		// haproxy answers 503 when no backend is chosen
+		routes = append(routes, map[string]interface{}{
+			"name":  fmt.Sprintf("no_backend-%s", listener.Id),
+			"match": envoyRouteMatch("", ""),
+			"direct_response": map[string]interface{}{
+				"status": 503,
+			},
+		})
+	}
+	virtualHost := map[string]interface{}{
+		"name":    listener.Id,
+		"domains": []string{"*"},
+		"routes":  routes,
+	}
+	if err := envoyHeaderRewrites(virtualHost, listener.HTTPHeaderRewrites); err != nil {
+		return errors.Wrap(err, "header rewrites of http listener")
+	}
+	res.Routes = append(res.Routes, EnvoyResource{
+		"@type":         EnvoyTypeUrlRoute,
+		"name":          listener.Id,
+		"virtual_hosts": []interface{}{virtualHost},
+	})
+
+	httpFilters := []interface{}{}
+	if rules := b.genEnvoyAclRules(listener); rules != nil {
+		// http-request deny of haproxy answers 403, so does rbac http filter
+		httpFilters = append(httpFilters, map[string]interface{}{
+			"name": "envoy.filters.http.rbac",
+			"typed_config": map[string]interface{}{
+				"@type": "type.googleapis.com/envoy.extensions.filters.http.rbac.v3.RBAC",
+				"rules": rules,
+			},
+		})
+	}
+	// rate limits are configured per route
+	httpFilters = append(httpFilters, map[string]interface{}{
+		"name": envoyHttpRateLimitFilter,
+		"typed_config": map[string]interface{}{
+			"@type":       "type.googleapis.com/envoy.extensions.filters.http.local_ratelimit.v3.LocalRateLimit",
+			"stat_prefix": listener.Id + "_rate",
+		},
+	})
+	if listener.Gzip {
+		httpFilters = append(httpFilters, map[string]interface{}{
+			"name": "envoy.filters.http.compressor",
+			"typed_config": map[string]interface{}{
+				"@type": "type.googleapis.com/envoy.extensions.filters.http.compressor.v3.Compressor",
+				"compressor_library": map[string]interface{}{
+					"name": "gzip",
+					"typed_config": map[string]interface{}{
+						"@type": "type.googleapis.com/envoy.extensions.compression.gzip.compressor.v3.Gzip",
+					},
+				},
+			},
+		})
+	}
+	httpFilters = append(httpFilters, map[string]interface{}{
+		"name": "envoy.filters.http.router",
+		"typed_config": map[string]interface{}{
+			"@type": "type.googleapis.com/envoy.extensions.filters.http.router.v3.Router",
+		},
+	})
+	hcm := map[string]interface{}{
+		"@type":       "type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+		"stat_prefix": listener.Id,
+		"codec_type":  "AUTO",
+		"rds": map[string]interface{}{
+			"config_source":     envoyXdsConfigSource(),
+			"route_config_name": listener.Id,
+		},
+		"http_filters": httpFilters,
+		// appends client address to X-Forwarded-For like option forwardfor
+		"use_remote_address": listener.XForwardedFor,
+	}
+	if listener.ClientRequestTimeout > 0 {
+		hcm["request_headers_timeout"] = envoyDuration(listener.ClientRequestTimeout)
+	}
+	if listener.ClientIdleTimeout > 0 {
+		hcm["common_http_protocol_options"] = map[string]interface{}{
+			"idle_timeout": envoyDuration(listener.ClientIdleTimeout),
+		}
+	}
+
+	filters := []interface{}{}
+	if filter := b.genEnvoyConnLimitFilter(listener); filter != nil {
+		filters = append(filters, filter)
+	}
+	filters = append(filters, map[string]interface{}{
+		"name":         "envoy.filters.network.http_connection_manager",
+		"typed_config": hcm,
+	})
+	res.Listeners = append(res.Listeners, b.genEnvoyListener(listener, filters))
+	return nil
+}
@@ -75,6 +75,10 @@ func (b *LoadbalancerCorpus) GenHaproxyConfigs(dir string, opts *AgentParams) (*
 			}
 		}
 		for _, lbcert := range b.LoadbalancerCertificates {
+			if lbcert.Certificate == "" {
+				// acme certificate not issued yet
+				continue
+			}
 			d := []byte(lbcert.Certificate)
 			if len(d) > 0 && d[len(d)-1] != '\n' {
 				d = append(d, '\n')
@@ -88,6 +92,21 @@ func (b *LoadbalancerCorpus) GenHaproxyConfigs(dir string, opts *AgentParams) (*
 			}
 		}
 	}
+	if port := haproxyAcmeHttpChallengePort(opts); port > 0 {
+		buf := bytes.NewBufferString("")
+		err := haproxyConfigTmpl.ExecuteTemplate(buf, "acmeHttpChallengeBackend", map[string]interface{}{
+			"id":   haproxyAcmeHttpChallengeBackendId,
+			"port": port,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "acme http challenge backend")
+		}
+		p := filepath.Join(dir, "02-acme.cfg")
+		err = ioutil.WriteFile(p, buf.Bytes(), agentutils.FileModeFile)
+		if err != nil {
+			return nil, fmt.Errorf("write 02-acme.cfg: %s", err)
+		}
+	}
 	for _, lbacl := range b.LoadbalancerAcls {
 		cidrs := []string{}
 		if lbacl.AclEntries != nil {
@@ -230,6 +249,7 @@ func (b *LoadbalancerCorpus) genHaproxyConfigCommon(lb *Loadbalancer, listener *
 			}
 		}
 	}
+	b.genHaproxyConfigConnLimit(data, listener)
 	{
 		// NOTE timeout tunnel is not set.  We may need to prepare a
 		// default section for each frontend/listener
@@ -390,14 +410,58 @@ func (b *LoadbalancerCorpus) genHaproxyConfigBackend(data map[string]interface{}
 	return nil
 }
 
-func (b *LoadbalancerCorpus) genHaproxyConfigHttpRate(data map[string]interface{}, requestRate, requestRatePerSrc int) error {
+// genHaproxyConfigConnLimit limits concurrent connections of the listener.
+// Connections are tracked with sc2 in frontend, leaving sc0, sc1 for request
+// rate limits in backends
+func (b *LoadbalancerCorpus) genHaproxyConfigConnLimit(data map[string]interface{}, listener *LoadbalancerListener) {
+	if listener.MaxConn > 0 {
+		data["maxconn"] = listener.MaxConn
+	}
+	if listener.MaxConnPerSrc > 0 {
+		idConn := listener.Id + "_conn"
+		data["dummy_backends"] = []map[string]string{
+			{
+				"id":          idConn,
+				"stick_table": "stick-table type ip size 1m expire 1m store conn_cur",
+			},
+		}
+		data["conn_rules"] = []string{
+			fmt.Sprintf("tcp-request connection track-sc2 src table %s", idConn),
+			fmt.Sprintf("tcp-request connection reject if { sc2_conn_cur gt %d }", listener.MaxConnPerSrc),
+		}
+	}
+}
+
+func (b *LoadbalancerCorpus) genHaproxyConfigHttpHeaders(data map[string]interface{}, rewrites *computeapi.SLoadbalancerHTTPHeaderRewrites) error {
+	if rewrites == nil {
+		return nil
+	}
+	headerRules := []string{}
+	for i := range *rewrites {
+		line, err := agentutils.HaproxyHeaderRewriteLine(&(*rewrites)[i])
+		if err != nil {
+			return err
+		}
+		headerRules = append(headerRules, line)
+	}
+	data["header_rules"] = headerRules
+	return nil
+}
+
+func (b *LoadbalancerCorpus) genHaproxyConfigHttpRate(data map[string]interface{}, limiter *compute_models.SLoadbalancerHTTPRateLimiter) error {
+	var (
+		requestRate          = limiter.HTTPRequestRate
+		requestRatePerSrc    = limiter.HTTPRequestRatePerSrc
+		requestRatePerHeader = limiter.HTTPRequestRatePerHeader
+		requestRateHeader    = limiter.HTTPRequestRateHeader
+	)
 	periodSecond := 10
 	id := data["id"].(string)
 	dummyBackends := []map[string]string{}
 	rateRules := []string{}
 
 	// order matters here: every src uses up his own quota before touching
-	// the shared one
+	// the shared one.  A client is identified either by src or by header
 	if requestRatePerSrc > 0 {
 		idPerSrc := id + "_persrc"
 		dummyBackends = append(dummyBackends, map[string]string{
@@ -409,6 +473,17 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttpRate(data map[string]interface{
 				idPerSrc, requestRatePerSrc*periodSecond),
 			fmt.Sprintf("http-request track-sc0 src table %s",
 				idPerSrc))
+	} else if requestRatePerHeader > 0 && requestRateHeader != "" {
+		idPerHeader := id + "_perhdr"
+		dummyBackends = append(dummyBackends, map[string]string{
+			"id":          idPerHeader,
+			"stick_table": fmt.Sprintf("stick-table type string len 128 size 1m expire 1m store http_req_rate(%ds)", periodSecond),
+		})
+		rateRules = append(rateRules,
+			fmt.Sprintf("http-request deny deny_status 429 if { req.hdr(%s),table_http_req_rate(%s) gt %d }",
+				requestRateHeader, idPerHeader, requestRatePerHeader*periodSecond),
+			fmt.Sprintf("http-request track-sc0 req.hdr(%s) table %s",
+				requestRateHeader, idPerHeader))
 	}
 	if requestRate > 0 {
 		idTotal := id + "_total"
@@ -447,10 +522,45 @@ func (b *LoadbalancerCorpus) haproxyRedirectLine(r *compute_models.SLoadbalancer
 	return line
 }
 
+const (
+	haproxyAcmeHttpChallengeBackendId = "backends_acme_http_challenge"
+	haproxyAcmeHttpChallengeAcl       = "acme_http_challenge"
+)
+
+func haproxyAcmeHttpChallengePort(opts *AgentParams) int {
+	port, _ := opts.GetHaproxyParams("acme_http_challenge_port").(int)
+	return port
+}
+
+// haproxyAcmeHttpChallengeRules routes acme http-01 challenges of plain http
+// listeners to lbagent and exempts them from redirects, so that domains can be
+// validated before the https listener has a certificate
+func haproxyAcmeHttpChallengeRules(ruleLines []string) []string {
+	ret := []string{
+		fmt.Sprintf("acl %s path_beg %s", haproxyAcmeHttpChallengeAcl, computeapi.LB_CERT_ACME_HTTP01_PATH_PREFIX),
+		fmt.Sprintf("use_backend %s if %s", haproxyAcmeHttpChallengeBackendId, haproxyAcmeHttpChallengeAcl),
+	}
+	for _, line := range ruleLines {
+		if strings.HasPrefix(line, "http-request redirect ") {
+			if strings.Contains(line, " if ") {
+				line += " !" + haproxyAcmeHttpChallengeAcl
+			} else {
+				line += " unless " + haproxyAcmeHttpChallengeAcl
+			}
+		}
+		ret = append(ret, line)
+	}
+	return ret
+}
+
 func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *LoadbalancerListener, opts *AgentParams) error {
 	var (
 		lb = listener.loadbalancer
 	)
+	if listener.ListenerType == "https" && listener.certificate != nil && listener.certificate.Certificate == "" {
+		// acme certificate not issued yet
+		return haproxyConfigErrNop
+	}
 
 	data, err := b.genHaproxyConfigCommon(lb, listener, opts)
 	if err != nil {
@@ -464,6 +574,9 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 		data["xforwardedfor"] = listener.XForwardedFor
 		data["gzip"] = listener.Gzip
 	}
+	if err := b.genHaproxyConfigHttpHeaders(data, listener.HTTPHeaderRewrites); err != nil {
+		return errors.Wrap(err, "header rewrites of http listener")
+	}
 
 	var (
 		rules     = listener.rules.OrderedEnabledList()
@@ -528,7 +641,10 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 			if err := b.genHaproxyConfigBackend(backendData, lb, listener, backendGroup); err != nil {
 				return err
 			}
-			if err := b.genHaproxyConfigHttpRate(backendData, rule.HTTPRequestRate, rule.HTTPRequestRatePerSrc); err != nil {
+			if err := b.genHaproxyConfigHttpRate(backendData, &rule.SLoadbalancerHTTPRateLimiter); err != nil {
+				return err
+			}
+			if err := b.genHaproxyConfigHttpHeaders(backendData, rule.HTTPHeaderRewrites); err != nil {
 				return err
 			}
 			backends = append(backends, backendData)
@@ -545,7 +661,7 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 			if err := b.genHaproxyConfigBackend(backendData, lb, listener, backendGroup); err != nil {
 				return err
 			}
-			if err := b.genHaproxyConfigHttpRate(backendData, listener.HTTPRequestRate, listener.HTTPRequestRatePerSrc); err != nil {
+			if err := b.genHaproxyConfigHttpRate(backendData, &listener.SLoadbalancerHTTPRateLimiter); err != nil {
 				return err
 			}
 			backends = append(backends, backendData)
@@ -557,6 +673,9 @@ func (b *LoadbalancerCorpus) genHaproxyConfigHttp(buf *bytes.Buffer, listener *L
 		// nothing to serve
 		return haproxyConfigErrNop
 	}
+	if listener.ListenerType == "http" && haproxyAcmeHttpChallengePort(opts) > 0 {
+		data["rules"] = haproxyAcmeHttpChallengeRules(ruleLines)
+	}
 	err = haproxyConfigTmpl.ExecuteTemplate(buf, "httpListen", data)
 	return err
 }
@@ -589,12 +708,18 @@ func (b *LoadbalancerCorpus) genHaproxyConfigTcp(buf *bytes.Buffer, listener *Lo
 var haproxyConfigTmpl = template.Must(template.New("").Parse(`
 {{ define "tcpListen" -}}
 # {{ .listener_type }} listener: {{ .comment }}
+{{- range .dummy_backends }}
+backend {{ .id }}
+	{{ println .stick_table }}
+{{- end }}
 listen {{ .id }}
 	bind {{ .bind }}
 	mode tcp
 	{{- println }}
+	{{- if .maxconn }}	maxconn {{ println .maxconn }} {{- end }}
 	{{- if .log }}	{{ println "option tcplog" }} {{- end }}
 	{{- if .acl }}	{{ println .acl }} {{- end}}
+	{{- range .conn_rules }}	{{ println . }} {{- end }}
 	{{- if .client_idle_timeout }}	timeout client {{ println .client_idle_timeout }} {{- end}}
 	default_backend {{ .backend.id }}
 {{ template "backend" .backend }}
@@ -610,12 +735,15 @@ frontend {{ .id }}
 	bind {{ .bind }}
 	mode http
 	{{- println }}
+	{{- if .maxconn }}	maxconn {{ println .maxconn }} {{- end }}
 	{{- if .log }}	{{ println "option httplog clf" }} {{- end }}
 	{{- if .acl }}	{{ println .acl }} {{- end}}
+	{{- range .conn_rules }}	{{ println . }} {{- end }}
 	{{- if .client_request_timeout }}	timeout http-request {{ println .client_request_timeout }} {{- end}}
 	{{- if .client_idle_timeout }}	timeout http-keep-alive {{ println .client_idle_timeout }} {{- end}}
 	{{- if .xforwardedfor }}	{{ println "option forwardfor" }} {{- end}}
 	{{- if .gzip }}	{{ println "compression algo gzip" }} {{- end}}
+	{{- range .header_rules }}	{{ println . }} {{- end }}
 	{{- range .rules }}	{{ println . }} {{- end }}
 	{{- if .default_backend.id }}	default_backend {{ println .default_backend.id }} {{- end }}
 {{- range .backends }}
@@ -623,6 +751,13 @@ frontend {{ .id }}
 {{- end }}
 {{- end }}
 
+{{ define "acmeHttpChallengeBackend" -}}
+# acme http-01 challenges answered by lbagent
+backend {{ .id }}
+	mode http
+	server acme 127.0.0.1:{{ .port }}
+{{ end }}
+
 {{ define "backend" -}}
 # {{ .comment }}
 {{- range .dummy_backends }}
@@ -634,6 +769,7 @@ backend {{ .id }}
 	balance {{ .balanceAlgorithm }}
 	{{- println }}
 	{{- range .rate_rules }}	{{ println . }} {{- end }}
+	{{- range .header_rules }}	{{ println . }} {{- end }}
 	{{- if .backend_connect_timeout }}	timeout connect {{ println .backend_connect_timeout }} {{- end}}
 	{{- if .backend_idle_timeout }}	timeout server {{ println .backend_idle_timeout }} {{- end}}
 	{{- if .timeout_check }}	{{ println .timeout_check }} {{- end }}
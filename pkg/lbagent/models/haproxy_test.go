@@ -0,0 +1,210 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	compute_models "yunion.io/x/onecloud/pkg/compute/models"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files of generated haproxy configs")
+
+func TestHaproxyAcmeHttpChallengeRules(t *testing.T) {
+	ruleLines := []string{
+		`use_backend backends_rule-r1 if { hdr_dom(host) "a.example.com" }`,
+		`http-request redirect code 302 location https://%[req.hdr(host)]%[capture.req.uri] if { path_beg "/old" }`,
+		`http-request redirect code 301 location https://%[req.hdr(host)]%[capture.req.uri]`,
+	}
+	want := []string{
+		`acl acme_http_challenge path_beg /.well-known/acme-challenge/`,
+		`use_backend backends_acme_http_challenge if acme_http_challenge`,
+		`use_backend backends_rule-r1 if { hdr_dom(host) "a.example.com" }`,
+		`http-request redirect code 302 location https://%[req.hdr(host)]%[capture.req.uri] if { path_beg "/old" } !acme_http_challenge`,
+		`http-request redirect code 301 location https://%[req.hdr(host)]%[capture.req.uri] unless acme_http_challenge`,
+	}
+	got := haproxyAcmeHttpChallengeRules(ruleLines)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want\n%v\ngot\n%v", want, got)
+	}
+}
+
+func newTestHaproxyLoadbalancer() *Loadbalancer {
+	lb := &Loadbalancer{
+		SLoadbalancer: &compute_models.SLoadbalancer{},
+		Listeners:     LoadbalancerListeners{},
+		BackendGroups: LoadbalancerBackendGroups{},
+	}
+	lb.Id = "lb0"
+	lb.Name = "lb0"
+	lb.Address = "192.168.0.10"
+
+	lbbg := &LoadbalancerBackendGroup{
+		SLoadbalancerBackendGroup: &compute_models.SLoadbalancerBackendGroup{},
+		Backends:                  LoadbalancerBackends{},
+		loadbalancer:              lb,
+	}
+	lbbg.Id = "lbbg0"
+	lbbg.Name = "lbbg0"
+	backend := &LoadbalancerBackend{
+		SLoadbalancerBackend: &compute_models.SLoadbalancerBackend{},
+		backendGroup:         lbbg,
+	}
+	backend.Id = "backend0"
+	backend.Address = "192.168.0.20"
+	backend.Port = 8080
+	backend.Weight = 1
+	lbbg.Backends[backend.Id] = backend
+	lb.BackendGroups[lbbg.Id] = lbbg
+	return lb
+}
+
+func newTestHaproxyListener(lb *Loadbalancer, id, listenerType string, port int) *LoadbalancerListener {
+	listener := &LoadbalancerListener{
+		SLoadbalancerListener: &compute_models.SLoadbalancerListener{},
+		loadbalancer:          lb,
+		rules:                 LoadbalancerListenerRules{},
+	}
+	listener.Id = id
+	listener.Name = id
+	listener.Status = "enabled"
+	listener.ListenerType = listenerType
+	listener.ListenerPort = port
+	listener.Scheduler = "rr"
+	listener.BackendGroupId = "lbbg0"
+	listener.Redirect = computeapi.LB_REDIRECT_OFF
+	lb.Listeners[id] = listener
+	return listener
+}
+
+func testHaproxyAgentParams() *AgentParams {
+	return &AgentParams{
+		AgentModel: &compute_models.SLoadbalancerAgent{
+			Params: &compute_models.SLoadbalancerAgentParams{},
+		},
+		Data: map[string]map[string]interface{}{},
+	}
+}
+
+func TestGenHaproxyConfigL7Golden(t *testing.T) {
+	cases := []struct {
+		golden string
+		setup  func(lb *Loadbalancer) *LoadbalancerListener
+	}{
+		{
+			golden: "http_header_rewrites",
+			setup: func(lb *Loadbalancer) *LoadbalancerListener {
+				listener := newTestHaproxyListener(lb, "lis0", "http", 80)
+				listener.HTTPHeaderRewrites = &computeapi.SLoadbalancerHTTPHeaderRewrites{
+					{Direction: "request", Action: "set", Name: "X-Real-IP", Value: "%[src]"},
+					{Direction: "request", Action: "del", Name: "X-Debug"},
+					{Direction: "response", Action: "add", Name: "Strict-Transport-Security", Value: "max-age=31536000"},
+					{Direction: "response", Action: "set", Name: "X-Quoted", Value: `say "hi"`},
+					{Direction: "request", Action: "set", Name: "X-Literal", Value: "100% ${HOME} %[env(SECRET)] %[req.hdr(host)]"},
+				}
+				return listener
+			},
+		},
+		{
+			golden: "http_rate_limit_per_header",
+			setup: func(lb *Loadbalancer) *LoadbalancerListener {
+				listener := newTestHaproxyListener(lb, "lis0", "http", 80)
+				listener.HTTPRequestRate = 100
+				listener.HTTPRequestRatePerHeader = 5
+				listener.HTTPRequestRateHeader = "X-Api-Key"
+				return listener
+			},
+		},
+		{
+			golden: "http_conn_limit",
+			setup: func(lb *Loadbalancer) *LoadbalancerListener {
+				listener := newTestHaproxyListener(lb, "lis0", "http", 80)
+				listener.MaxConn = 1000
+				listener.MaxConnPerSrc = 20
+				listener.HTTPRequestRatePerSrc = 10
+				return listener
+			},
+		},
+		{
+			golden: "http_rule",
+			setup: func(lb *Loadbalancer) *LoadbalancerListener {
+				listener := newTestHaproxyListener(lb, "lis0", "http", 80)
+				rule := &LoadbalancerListenerRule{
+					SLoadbalancerListenerRule: &compute_models.SLoadbalancerListenerRule{},
+					listener:                  listener,
+				}
+				rule.Id = "rule0"
+				rule.Name = "rule0"
+				rule.Status = "enabled"
+				rule.Path = "/api"
+				rule.BackendGroupId = "lbbg0"
+				rule.Redirect = computeapi.LB_REDIRECT_OFF
+				rule.HTTPRequestRatePerHeader = 2
+				rule.HTTPRequestRateHeader = "Authorization"
+				rule.HTTPHeaderRewrites = &computeapi.SLoadbalancerHTTPHeaderRewrites{
+					{Direction: "request", Action: "set", Name: "X-Forwarded-Prefix", Value: "/api"},
+				}
+				listener.rules[rule.Id] = rule
+				return listener
+			},
+		},
+		{
+			golden: "tcp_conn_limit",
+			setup: func(lb *Loadbalancer) *LoadbalancerListener {
+				listener := newTestHaproxyListener(lb, "lis0", "tcp", 3306)
+				listener.MaxConn = 500
+				listener.MaxConnPerSrc = 10
+				return listener
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.golden, func(t *testing.T) {
+			lb := newTestHaproxyLoadbalancer()
+			listener := c.setup(lb)
+			b := NewEmptyLoadbalancerCorpus()
+			buf := &bytes.Buffer{}
+			var err error
+			switch listener.ListenerType {
+			case "tcp":
+				err = b.genHaproxyConfigTcp(buf, listener, testHaproxyAgentParams())
+			default:
+				err = b.genHaproxyConfigHttp(buf, listener, testHaproxyAgentParams())
+			}
+			if err != nil {
+				t.Fatalf("gen config: %v", err)
+			}
+			p := filepath.Join("testdata", "haproxy", c.golden+".cfg")
+			if *updateGolden {
+				if err := ioutil.WriteFile(p, buf.Bytes(), 0644); err != nil {
+					t.Fatalf("update golden file: %v", err)
+				}
+			}
+			want, err := ioutil.ReadFile(p)
+			if err != nil {
+				t.Fatalf("read golden file: %v", err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("config mismatch %s, want\n%s\ngot\n%s", p, want, got)
+			}
+		})
+	}
+}
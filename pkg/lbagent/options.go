@@ -49,8 +49,14 @@ type LbagentCommonOptions struct {
 	HaproxyBin    string `default:"haproxy"`
 	GobetweenBin  string `default:"gobetween"`
 	TelegrafBin   string `default:"telegraf"`
+	EnvoyBin      string `default:"envoy"`
 
 	DisableLocalVpc bool `help:"disable local VPC support" default:"false"`
+
+	AcmeHttpChallengePort int `help:"local port answering acme http-01 challenges routed by haproxy, 0 to disable" default:"8899"`
+
+	EnvoyXdsPort   int `help:"local port of xDS server for envoy data plane, 0 to disable" default:"8898"`
+	EnvoyAdminPort int `help:"local port of envoy admin interface" default:"8897"`
 }
 
 type Options struct {
@@ -0,0 +1,121 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+const (
+	EnvoyLbPolicyRoundRobin   = "ROUND_ROBIN"
+	EnvoyLbPolicyLeastRequest = "LEAST_REQUEST"
+	EnvoyLbPolicyRingHash     = "RING_HASH"
+)
+
+// EnvoyLbPolicy maps listener scheduler to envoy cluster lb_policy.  Source
+// hashing is done with RING_HASH and a source_ip hash policy
+func EnvoyLbPolicy(scheduler string) (policy string, err error) {
+	switch scheduler {
+	case "rr", "wrr":
+		policy = EnvoyLbPolicyRoundRobin
+	case "wlc":
+		policy = EnvoyLbPolicyLeastRequest
+	case "sch", "tch":
+		policy = EnvoyLbPolicyRingHash
+	default:
+		err = fmt.Errorf("unknown scheduler type %q", scheduler)
+	}
+	return
+}
+
+// EnvoyTlsMinimumProtocolVersion returns tls_minimum_protocol_version of
+// envoy TlsParameters for the tls cipher policy, or empty string for default
+func EnvoyTlsMinimumProtocolVersion(policy string) string {
+	p := HaproxySslPolicy(policy)
+	if p == nil {
+		return ""
+	}
+	// TLSv1.2 => TLSv1_2
+	return strings.Replace(p.SslMinVer, ".", "_", -1)
+}
+
+func EnvoyRedirectResponseCode(code int) string {
+	switch code {
+	case 301:
+		return "MOVED_PERMANENTLY"
+	case 303:
+		return "SEE_OTHER"
+	case 307:
+		return "TEMPORARY_REDIRECT"
+	case 308:
+		return "PERMANENT_REDIRECT"
+	default:
+		return "FOUND"
+	}
+}
+
+// EnvoyHealthCheckExpectedStatuses converts health check http code like
+// "http_2xx,http_3xx" into envoy Int64Range list, end exclusive
+func EnvoyHealthCheckExpectedStatuses(s string) []map[string]int {
+	r := []map[string]int{}
+	for _, s := range strings.Split(s, ",") {
+		if !strings.HasPrefix(s, "http_") || len(s) != len("http_2xx") {
+			continue
+		}
+		c := s[len("http_")]
+		if c < '1' || c > '5' {
+			continue
+		}
+		start := int(c-'0') * 100
+		r = append(r, map[string]int{
+			"start": start,
+			"end":   start + 100,
+		})
+	}
+	return r
+}
+
+// EnvoyProxyProtocolVersion returns version of envoy upstream proxy protocol
+// transport.  Envoy does not send ssl tlvs, v2-ssl variants degrade to plain v2
+func EnvoyProxyProtocolVersion(s string) (r string, err error) {
+	switch s {
+	case compute.LB_SENDPROXY_OFF, "":
+	case compute.LB_SENDPROXY_V1:
+		r = "V1"
+	case compute.LB_SENDPROXY_V2, compute.LB_SENDPROXY_V2_SSL, compute.LB_SENDPROXY_V2_SSL_CN:
+		r = "V2"
+	default:
+		err = fmt.Errorf("unknown SendProxy: %s", s)
+	}
+	return
+}
+
+const envoyHeaderValueSrc = "%[src]"
+
+// EnvoyHeaderValue translates haproxy log-format header value into envoy
+// header value.  Only %[src] is supported among haproxy sample expressions
+func EnvoyHeaderValue(v string) (string, error) {
+	parts := strings.Split(v, envoyHeaderValueSrc)
+	for i, part := range parts {
+		if strings.Contains(part, "%[") {
+			return "", fmt.Errorf("header value %q: unsupported sample expression for envoy", v)
+		}
+		parts[i] = strings.Replace(part, "%", "%%", -1)
+	}
+	return strings.Join(parts, "%DOWNSTREAM_REMOTE_ADDRESS_WITHOUT_PORT%"), nil
+}
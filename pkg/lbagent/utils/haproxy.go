@@ -102,3 +102,47 @@ func HaproxySendProxy(s string) (r string, err error) {
 	}
 	return
 }
+
+// HaproxyQuote quotes s as a double-quoted log-format argument of haproxy
+// config.  Allowed sample fetches like %[src] inside are kept as is, the rest
+// is escaped to be taken literally, including "$" which would otherwise
+// expand environment variables
+func HaproxyQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, `%`, `%%`)
+	buf := &strings.Builder{}
+	buf.WriteString(`"`)
+	start := 0
+	for _, m := range compute.HTTPHeaderSampleFetchReg.FindAllStringSubmatchIndex(s, -1) {
+		if !compute.IsHTTPHeaderSampleFetchAllowed(s[m[2]:m[3]]) {
+			continue
+		}
+		buf.WriteString(r.Replace(s[start:m[0]]))
+		buf.WriteString(s[m[0]:m[1]])
+		start = m[1]
+	}
+	buf.WriteString(r.Replace(s[start:]))
+	buf.WriteString(`"`)
+	return buf.String()
+}
+
+// HaproxyHeaderRewriteLine returns http-request or http-response rule for
+// the header rewrite
+func HaproxyHeaderRewriteLine(rewrite *compute.SLoadbalancerHTTPHeaderRewrite) (string, error) {
+	var directive string
+	switch rewrite.Direction {
+	case compute.LB_HTTP_HEADER_DIRECTION_REQUEST, "":
+		directive = "http-request"
+	case compute.LB_HTTP_HEADER_DIRECTION_RESPONSE:
+		directive = "http-response"
+	default:
+		return "", fmt.Errorf("unknown header rewrite direction: %s", rewrite.Direction)
+	}
+	switch rewrite.Action {
+	case compute.LB_HTTP_HEADER_ACTION_ADD, compute.LB_HTTP_HEADER_ACTION_SET:
+		return fmt.Sprintf("%s %s-header %s %s", directive, rewrite.Action, rewrite.Name, HaproxyQuote(rewrite.Value)), nil
+	case compute.LB_HTTP_HEADER_ACTION_DEL:
+		return fmt.Sprintf("%s del-header %s", directive, rewrite.Name), nil
+	default:
+		return "", fmt.Errorf("unknown header rewrite action: %s", rewrite.Action)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudnet
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+)
+
+type VpnGatewayManager struct {
+	modulebase.ResourceManager
+}
+
+var (
+	VpnGateways VpnGatewayManager
+)
+
+func init() {
+	VpnGateways = VpnGatewayManager{
+		NewCloudnetManager(
+			"vpngateway",
+			"vpngateways",
+			[]string{
+				"id",
+				"name",
+				"router_id",
+				"vpc_id",
+				"vpc_ip",
+				"local_cidrs",
+				"ifname",
+				"public_key",
+				"listen_port",
+			},
+			[]string{"tenant"},
+		),
+	}
+	registerV2(&VpnGateways)
+}
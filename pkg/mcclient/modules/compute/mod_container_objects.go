@@ -0,0 +1,36 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	ContainerSecrets modulebase.ResourceManager
+	ContainerConfigs modulebase.ResourceManager
+)
+
+func init() {
+	ContainerSecrets = modules.NewComputeManager("container_secret", "container_secrets",
+		[]string{"ID", "Name", "Status", "Keys", "Encrypt_Key_Id", "Container_Count", "Project_Id"},
+		[]string{})
+	ContainerConfigs = modules.NewComputeManager("container_config", "container_configs",
+		[]string{"ID", "Name", "Status", "Keys", "Container_Count", "Project_Id"},
+		[]string{})
+	modules.RegisterCompute(&ContainerSecrets)
+	modules.RegisterCompute(&ContainerConfigs)
+}
@@ -0,0 +1,40 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
+	"yunion.io/x/onecloud/pkg/mcclient/modules"
+)
+
+var (
+	DrProtectionGroups modulebase.ResourceManager
+	DrRecoveryPoints   modulebase.ResourceManager
+)
+
+func init() {
+	DrProtectionGroups = modules.NewComputeManager("dr_protection_group", "dr_protection_groups",
+		[]string{"ID", "Name", "Status", "Enabled", "Target_Region",
+			"Backup_Storage_Id", "Target_Backup_Storage_Id", "Rpo_Minutes",
+			"Retain_Count", "Test_Network_Id", "Failed_Over_At"},
+		[]string{})
+	modules.RegisterCompute(&DrProtectionGroups)
+
+	DrRecoveryPoints = modules.NewComputeManager("dr_recovery_point", "dr_recovery_points",
+		[]string{"ID", "Name", "Status", "Group_Id", "Guest_Id",
+			"Instance_Backup_Id", "Package_Name", "Created_At"},
+		[]string{})
+	modules.RegisterCompute(&DrRecoveryPoints)
+}
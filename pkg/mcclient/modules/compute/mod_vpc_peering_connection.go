@@ -25,7 +25,7 @@ var (
 
 func init() {
 	VpcPeeringConnections = modules.NewComputeManager("vpc_peering_connection", "vpc_peering_connections",
-		[]string{"ID", "Name", "Enabled", "Status", "vpc_id", "peer_vpc_id", "peer_account_id", "Public_Scope", "Domain_Id", "Domain"},
+		[]string{"ID", "Name", "Enabled", "Status", "vpc_id", "peer_vpc_id", "peer_account_id", "transit_cidr", "Public_Scope", "Domain_Id", "Domain"},
 		[]string{})
 
 	modules.RegisterCompute(&VpcPeeringConnections)
@@ -586,7 +586,8 @@ func init() {
 		[]string{"ID", "Name", "Tags", "Disk_format",
 			"Size", "Is_public", "Protected", "Is_Standard",
 			"OS_Type", "OS_Distribution", "OS_version",
-			"Min_disk", "Min_ram", "Status", "Encrypt_Status",
+			"Min_disk", "Min_ram", "Status", "Encrypt_Status", "Signature_Status",
+			"Scan_Status", "Vulnerability_Count", "Max_Vuln_Severity",
 			"Notes", "OS_arch", "Preference",
 			"OS_Codename", "Description",
 			"Checksum", "Tenant_Id", "Tenant",
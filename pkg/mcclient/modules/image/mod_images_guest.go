@@ -15,15 +15,45 @@
 package image
 
 import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"yunion.io/x/jsonutils"
+
+	api "yunion.io/x/onecloud/pkg/apis/image"
+	"yunion.io/x/onecloud/pkg/mcclient"
 	"yunion.io/x/onecloud/pkg/mcclient/modulebase"
 	"yunion.io/x/onecloud/pkg/mcclient/modules"
 )
 
-var GuestImages modulebase.ResourceManager
+type GuestImageManager struct {
+	modulebase.ResourceManager
+}
+
+var GuestImages GuestImageManager
 
 func init() {
-	GuestImages = modules.NewImageManager("guestimage", "guestimages",
-		[]string{"ID", "Name", "Status", "Size"},
-		[]string{})
+	GuestImages = GuestImageManager{modules.NewImageManager("guestimage", "guestimages",
+		[]string{"ID", "Name", "Status", "Size", "Ova_Status"},
+		[]string{})}
 	modules.Register(&GuestImages)
 }
+
+// DownloadOva downloads the ova exported by the export-ova action
+func (this *GuestImageManager) DownloadOva(s *mcclient.ClientSession, id string) (io.ReadCloser, int64, error) {
+	query := jsonutils.NewDict()
+	query.Add(jsonutils.NewString(api.GUEST_IMAGE_FORMAT_OVA), "format")
+	path := fmt.Sprintf("/%s/%s?%s", this.URLPath(), url.PathEscape(id), query.QueryString())
+	resp, err := modulebase.RawRequest(this.ResourceManager, s, "GET", path, nil, nil)
+	if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		sizeBytes, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+		if err != nil {
+			sizeBytes = -1
+		}
+		return resp.Body, sizeBytes, nil
+	}
+	_, _, err = s.ParseJSONResponse("", resp, err)
+	return nil, -1, err
+}
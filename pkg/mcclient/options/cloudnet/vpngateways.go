@@ -0,0 +1,122 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudnet
+
+import (
+	"io/ioutil"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type VpnGatewayCreateOptions struct {
+	NAME string
+
+	Router     string `required:"true"`
+	Vpc        string `required:"true"`
+	VpcIp      string `required:"true" help:"address of the router in the vpc"`
+	LocalCidrs string `help:"vpc cidrs announced to remote sites, concatenated by comma, default to vpc cidr block"`
+}
+
+type VpnGatewayGetOptions struct {
+	ID string `json:"-"`
+}
+
+type VpnGatewayUpdateOptions struct {
+	ID   string `json:"-"`
+	Name string
+
+	LocalCidrs string
+}
+
+type VpnGatewayDeleteOptions struct {
+	ID string `json:"-"`
+}
+
+type VpnGatewayListOptions struct {
+	options.BaseListOptions
+
+	Router string
+	VpcId  string
+}
+
+type VpnGatewayActionRealizeOptions struct {
+	ID string `json:"-"`
+}
+
+type VpnGatewayActionReportStatusOptions struct {
+	ID string `json:"-"`
+
+	WgDump           string   `help:"output of wg show <ifname> dump, or @file to read from file"`
+	IpsecEstablished []string `help:"names of established ipsec connections"`
+}
+
+func (opts *VpnGatewayActionReportStatusOptions) Params() (jsonutils.JSONObject, error) {
+	params, err := options.StructToParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.WgDump != "" && opts.WgDump[0] == '@' {
+		data, err := ioutil.ReadFile(opts.WgDump[1:])
+		if err != nil {
+			return nil, err
+		}
+		params.Set("wg_dump", jsonutils.NewString(string(data)))
+	}
+	return params, nil
+}
+
+type VpnGatewayPeerCreateOptions struct {
+	NAME string
+
+	VpnGateway  string `required:"true"`
+	TunnelType  string `choices:"wireguard|ipsec" default:"wireguard"`
+	RemoteCidrs string `required:"true" help:"remote site cidrs, concatenated by comma"`
+	Endpoint    string `help:"host:port for wireguard, ip address for ipsec"`
+
+	PublicKey           string `help:"wireguard public key of remote site"`
+	PersistentKeepalive int    `json:",omitzero"`
+
+	Psk      string `help:"ipsec pre-shared key"`
+	RemoteId string `help:"ipsec ike identity of remote site, default to endpoint"`
+}
+
+type VpnGatewayPeerGetOptions struct {
+	ID string `json:"-"`
+}
+
+type VpnGatewayPeerUpdateOptions struct {
+	ID   string `json:"-"`
+	Name string
+
+	RemoteCidrs         string
+	Endpoint            string
+	PersistentKeepalive *int
+
+	Psk      string
+	RemoteId string
+}
+
+type VpnGatewayPeerDeleteOptions struct {
+	ID string `json:"-"`
+}
+
+type VpnGatewayPeerListOptions struct {
+	options.BaseListOptions
+
+	VpnGateway string
+	Router     string
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"os"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type ContainerObjectDataOptions struct {
+	Data     []string `help:"Data of the object, the format is: <key>=<value>"`
+	DataFile []string `help:"Data of the object read from file, the format is: <key>=<file_path>"`
+}
+
+func (o *ContainerObjectDataOptions) GetData() (map[string]string, error) {
+	if len(o.Data) == 0 && len(o.DataFile) == 0 {
+		return nil, nil
+	}
+	data := make(map[string]string)
+	for _, d := range o.Data {
+		kv := strings.SplitN(d, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("invalid data: %q", d)
+		}
+		data[kv[0]] = kv[1]
+	}
+	for _, f := range o.DataFile {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("invalid data file: %q", f)
+		}
+		content, err := os.ReadFile(kv[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "read file %s", kv[1])
+		}
+		data[kv[0]] = string(content)
+	}
+	return data, nil
+}
+
+func (o *ContainerObjectDataOptions) params(params jsonutils.JSONObject) (jsonutils.JSONObject, error) {
+	data, err := o.GetData()
+	if err != nil {
+		return nil, err
+	}
+	dict := params.(*jsonutils.JSONDict)
+	dict.Remove("data_file")
+	if data != nil {
+		dict.Set("data", jsonutils.Marshal(data))
+	}
+	return dict, nil
+}
+
+type ContainerObjectListOptions struct {
+	options.BaseListOptions
+}
+
+func (o *ContainerObjectListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(o)
+}
+
+type ContainerConfigCreateOptions struct {
+	options.BaseCreateOptions
+	ContainerObjectDataOptions
+}
+
+func (o *ContainerConfigCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return o.params(jsonutils.Marshal(o))
+}
+
+type ContainerSecretCreateOptions struct {
+	ContainerConfigCreateOptions
+	EncryptKey string `help:"Existing encrypt key id to encrypt the secret, a new key is created if not specified" json:"encrypt_key_id"`
+}
+
+func (o *ContainerSecretCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return o.params(jsonutils.Marshal(o))
+}
+
+type ContainerObjectUpdateOptions struct {
+	options.BaseUpdateOptions
+	ContainerObjectDataOptions
+}
+
+func (o *ContainerObjectUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params, err := o.BaseUpdateOptions.Params()
+	if err != nil {
+		return nil, err
+	}
+	data, err := o.GetData()
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		params.(*jsonutils.JSONDict).Set("data", jsonutils.Marshal(data))
+	}
+	return params, nil
+}
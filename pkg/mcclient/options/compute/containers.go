@@ -48,20 +48,23 @@ type ContainerDeleteOptions struct {
 }
 
 type ContainerCreateCommonOptions struct {
-	IMAGE             string   `help:"Image of container" json:"image"`
 	ImageCredentialId string   `help:"Image credential id" json:"image_credential_id"`
 	Command           []string `help:"Command to execute (i.e., entrypoint for docker)" json:"command"`
 	Args              []string `help:"Args for the Command (i.e. command for docker)" json:"args"`
 	WorkingDir        string   `help:"Current working directory of the command" json:"working_dir"`
 	Env               []string `help:"List of environment variable to set in the container and the format is: <key>=<value>"`
 	RootFs            string   `help:"Root filesystem of the container, e.g.: disk_index=<disk_number>,disk_id=<disk_id>"`
-	VolumeMount       []string `help:"Volume mount of the container and the format is: name=<val>,mount=<container_path>,readonly=<true_or_false>,case_insensitive_paths=p1,p2,disk_index=<disk_number>,disk_id=<disk_id>"`
+	EnvFrom           []string `help:"Environment variable from container secret or config and the format is: <key>=secret:<secret_id>:<secret_key> or <key>=config:<config_id>:<config_key>"`
+	VolumeMount       []string `help:"Volume mount of the container and the format is: name=<val>,mount=<container_path>,readonly=<true_or_false>,case_insensitive_paths=p1,p2,disk_index=<disk_number>,disk_id=<disk_id>,secret=<secret_id>,config=<config_id>,key=<key>,item=<key>:<path>,refresh_policy=<refresh|restart|none>"`
 	Device            []string `help:"Host device: <host_path>:<container_path>:<permissions>, e.g.: /dev/snd:/dev/snd:rwm"`
 	Privileged        bool     `help:"Privileged mode"`
 	Caps              string   `help:"Container capabilities, e.g.: SETPCAP,AUDIT_WRITE,SYS_CHROOT,CHOWN,DAC_OVERRIDE,FOWNER,SETGID,SETUID,SYSLOG,SYS_ADMIN,WAKE_ALARM,SYS_PTRACE,BLOCK_SUSPEND,MKNOD,KILL,SYS_RESOURCE,NET_RAW,NET_ADMIN,NET_BIND_SERVICE,SYS_NICE"`
 	DropCaps          string   `help:"Container dropped capabilities, split by ','"`
 	EnableLxcfs       bool     `help:"Enable lxcfs"`
 	PostStartExec     string   `help:"Post started execution command"`
+	PreStopExec       string   `help:"Pre stop execution command"`
+	PreStopHttpGet    string   `help:"Pre stop http get request, the format is: port=<port>,path=<path>,scheme=<HTTP|HTTPS>,host=<host>"`
+	TerminationGrace  int64    `help:"Seconds to wait for the pre stop handler and the graceful shutdown of the container"`
 	CgroupDeviceAllow []string `help:"Cgroup devices.allow, e.g.: 'c 13:* rwm'"`
 	SimulateCpu       bool     `help:"Simulating /sys/devices/system/cpu files"`
 	ShmSizeMb         int      `help:"Shm size MB"`
@@ -71,10 +74,10 @@ type ContainerCreateCommonOptions struct {
 	Apparmor          string   `help:"Apparmor profile for container"`
 }
 
-func (o ContainerCreateCommonOptions) getCreateSpec() (*computeapi.ContainerSpec, error) {
+func (o ContainerCreateCommonOptions) getCreateSpec(image string) (*computeapi.ContainerSpec, error) {
 	req := &computeapi.ContainerSpec{
 		ContainerSpec: apis.ContainerSpec{
-			Image:              o.IMAGE,
+			Image:              image,
 			ImageCredentialId:  o.ImageCredentialId,
 			Command:            o.Command,
 			Args:               o.Args,
@@ -103,16 +106,36 @@ func (o ContainerCreateCommonOptions) getCreateSpec() (*computeapi.ContainerSpec
 	if o.Apparmor != "" {
 		req.ContainerSpec.SecurityContext.ApparmorProfile = o.Apparmor
 	}
-	if len(o.PostStartExec) != 0 {
+	if len(o.PostStartExec) != 0 || len(o.PreStopExec) != 0 || len(o.PreStopHttpGet) != 0 || o.TerminationGrace > 0 {
 		req.Lifecyle = &apis.ContainerLifecyle{
-			PostStart: &apis.ContainerLifecyleHandler{
-				Type: apis.ContainerLifecyleHandlerTypeExec,
-				Exec: &apis.ContainerLifecyleHandlerExecAction{
-					Command: strings.Split(o.PostStartExec, " "),
-				},
+			TerminationGracePeriodSeconds: o.TerminationGrace,
+		}
+	}
+	if len(o.PostStartExec) != 0 {
+		req.Lifecyle.PostStart = &apis.ContainerLifecyleHandler{
+			Type: apis.ContainerLifecyleHandlerTypeExec,
+			Exec: &apis.ContainerLifecyleHandlerExecAction{
+				Command: strings.Split(o.PostStartExec, " "),
 			},
 		}
 	}
+	if len(o.PreStopExec) != 0 {
+		req.Lifecyle.PreStop = &apis.ContainerLifecyleHandler{
+			Type: apis.ContainerLifecyleHandlerTypeExec,
+			Exec: &apis.ContainerLifecyleHandlerExecAction{
+				Command: strings.Split(o.PreStopExec, " "),
+			},
+		}
+	} else if len(o.PreStopHttpGet) != 0 {
+		httpGet, err := parseContainerHTTPGetAction(o.PreStopHttpGet)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parseContainerHTTPGetAction %s", o.PreStopHttpGet)
+		}
+		req.Lifecyle.PreStop = &apis.ContainerLifecyleHandler{
+			Type:    apis.ContainerLifecyleHandlerTypeHTTPGet,
+			HTTPGet: httpGet,
+		}
+	}
 	if len(o.Caps) != 0 {
 		req.Capabilities.Add = strings.Split(o.Caps, ",")
 	}
@@ -126,6 +149,13 @@ func (o ContainerCreateCommonOptions) getCreateSpec() (*computeapi.ContainerSpec
 		}
 		req.Envs = append(req.Envs, e)
 	}
+	for _, env := range o.EnvFrom {
+		e, err := parseContainerEnvFrom(env)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parseContainerEnvFrom %s", env)
+		}
+		req.Envs = append(req.Envs, e)
+	}
 	if len(o.RootFs) != 0 {
 		rootFs, err := parseContainerRootFs(o.RootFs)
 		if err != nil {
@@ -153,13 +183,14 @@ func (o ContainerCreateCommonOptions) getCreateSpec() (*computeapi.ContainerSpec
 }
 
 type ContainerCreateOptions struct {
+	IMAGE string `help:"Image of container" json:"image"`
 	ContainerCreateCommonOptions
 	PODID string `help:"Name or id of server pod" json:"-"`
 	NAME  string `help:"Name of container" json:"-"`
 }
 
 func (o *ContainerCreateOptions) Params() (jsonutils.JSONObject, error) {
-	spec, err := o.getCreateSpec()
+	spec, err := o.getCreateSpec(o.IMAGE)
 	if err != nil {
 		return nil, errors.Wrap(err, "get container create spec")
 	}
@@ -171,6 +202,35 @@ func (o *ContainerCreateOptions) Params() (jsonutils.JSONObject, error) {
 	return jsonutils.Marshal(req), nil
 }
 
+func parseContainerHTTPGetAction(input string) (*apis.ContainerProbeHTTPGetAction, error) {
+	out := &apis.ContainerProbeHTTPGetAction{}
+	for _, seg := range strings.Split(input, ",") {
+		info := strings.Split(seg, "=")
+		if len(info) != 2 {
+			return nil, errors.Errorf("invalid option %s", seg)
+		}
+		key := info[0]
+		val := info[1]
+		switch key {
+		case "port":
+			port, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid port %s", val)
+			}
+			out.Port = port
+		case "path":
+			out.Path = val
+		case "host":
+			out.Host = val
+		case "scheme":
+			out.Scheme = apis.URIScheme(strings.ToUpper(val))
+		default:
+			return nil, errors.Errorf("unknown option %s", key)
+		}
+	}
+	return out, nil
+}
+
 func parseContainerEnv(env string) (*apis.ContainerKeyValue, error) {
 	kv := strings.Split(env, "=")
 	if len(kv) != 2 {
@@ -182,6 +242,31 @@ func parseContainerEnv(env string) (*apis.ContainerKeyValue, error) {
 	}, nil
 }
 
+func parseContainerEnvFrom(env string) (*apis.ContainerKeyValue, error) {
+	kv := strings.SplitN(env, "=", 2)
+	if len(kv) != 2 {
+		return nil, errors.Errorf("invalid env: %q", env)
+	}
+	ref := strings.Split(kv[1], ":")
+	if len(ref) != 3 {
+		return nil, errors.Errorf("invalid value source: %q", kv[1])
+	}
+	keyRef := &apis.ContainerObjectKeyRef{Id: ref[1], Key: ref[2]}
+	source := &apis.ContainerValueSource{}
+	switch ref[0] {
+	case string(apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET):
+		source.Secret = keyRef
+	case string(apis.CONTAINER_VOLUME_MOUNT_TYPE_CONFIG):
+		source.Config = keyRef
+	default:
+		return nil, errors.Errorf("invalid value source type: %q", ref[0])
+	}
+	return &apis.ContainerKeyValue{
+		Key:       kv[0],
+		ValueFrom: source,
+	}, nil
+}
+
 func parseContainerRootFs(rootFs string) (*apis.ContainerRootfs, error) {
 	out := &apis.ContainerRootfs{
 		Type: apis.CONTAINER_VOLUME_MOUNT_TYPE_DISK,
@@ -210,6 +295,13 @@ func parseContainerRootFs(rootFs string) (*apis.ContainerRootfs, error) {
 
 func parseContainerVolumeMount(vmStr string) (*apis.ContainerVolumeMount, error) {
 	vm := &apis.ContainerVolumeMount{}
+	var obj *apis.ContainerVolumeMountObject
+	getObj := func() *apis.ContainerVolumeMountObject {
+		if obj == nil {
+			obj = &apis.ContainerVolumeMountObject{}
+		}
+		return obj
+	}
 	for _, seg := range strings.Split(vmStr, ",") {
 		info := strings.Split(seg, "=")
 		if len(info) != 2 {
@@ -309,6 +401,33 @@ func parseContainerVolumeMount(vmStr string) (*apis.ContainerVolumeMount, error)
 			vm.CephFS = &apis.ContainerVolumeMountCephFS{
 				Id: val,
 			}
+		case "secret":
+			vm.Type = apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET
+			getObj().Id = val
+		case "config":
+			vm.Type = apis.CONTAINER_VOLUME_MOUNT_TYPE_CONFIG
+			getObj().Id = val
+		case "key":
+			getObj().Key = val
+		case "item":
+			item := strings.SplitN(val, ":", 2)
+			kp := apis.ContainerKeyToPath{Key: item[0]}
+			if len(item) == 2 {
+				kp.Path = item[1]
+			}
+			getObj().Items = append(getObj().Items, kp)
+		case "refresh_policy":
+			getObj().RefreshPolicy = apis.ContainerObjectRefreshPolicy(val)
+		}
+	}
+	switch vm.Type {
+	case apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET:
+		vm.Secret = obj
+	case apis.CONTAINER_VOLUME_MOUNT_TYPE_CONFIG:
+		vm.Config = obj
+	default:
+		if obj != nil {
+			return nil, errors.Errorf("key, item and refresh_policy are only valid for secret or config")
 		}
 	}
 	return vm, nil
@@ -104,6 +104,34 @@ func Test_parseContainerVolumeMount(t *testing.T) {
 				MountPath: "/test",
 			},
 		},
+		{
+			args: "mount_path=/etc/app,item=app.conf:conf/app.conf,item=log.conf,secret=app-secret,refresh_policy=restart",
+			want: &apis.ContainerVolumeMount{
+				MountPath: "/etc/app",
+				Type:      apis.CONTAINER_VOLUME_MOUNT_TYPE_SECRET,
+				Secret: &apis.ContainerVolumeMountObject{
+					Id: "app-secret",
+					Items: []apis.ContainerKeyToPath{
+						{Key: "app.conf", Path: "conf/app.conf"},
+						{Key: "log.conf"},
+					},
+					RefreshPolicy: apis.CONTAINER_OBJECT_REFRESH_POLICY_RESTART,
+				},
+			},
+		},
+		{
+			args: "mount_path=/etc/app.conf,config=app-config,key=app.conf",
+			want: &apis.ContainerVolumeMount{
+				MountPath: "/etc/app.conf",
+				Type:      apis.CONTAINER_VOLUME_MOUNT_TYPE_CONFIG,
+				Config:    &apis.ContainerVolumeMountObject{Id: "app-config", Key: "app.conf"},
+			},
+		},
+		{
+			args:    "mount_path=/test,key=app.conf",
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			args:    "vm1,read_only=True,mount_path=/test",
 			want:    nil,
@@ -0,0 +1,80 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type DiskReplicaListOptions struct {
+	options.BaseListOptions
+
+	DiskId        []string `help:"filter by disk"`
+	GuestId       []string `help:"filter by server"`
+	HostId        []string `help:"filter by host of the replicated disk"`
+	ReplicaHostId []string `help:"filter by host of the replica"`
+	Mode          []string `help:"filter by replication mode" choices:"sync|async"`
+}
+
+func (opts *DiskReplicaListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type DiskReplicaIdOptions struct {
+	ID string `help:"Disk replica Id or name"`
+}
+
+func (opts *DiskReplicaIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *DiskReplicaIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+type DiskReplicaCreateOptions struct {
+	DISK             string `help:"Local disk to replicate" json:"disk_id"`
+	REPLICA_HOST     string `help:"Host to keep the replica" json:"replica_host_id"`
+	Name             string `help:"Name of the replica"`
+	ReplicaStorageId string `help:"Local storage of replica host, choose the least used one if not given"`
+	Mode             string `help:"Replication mode, sync writes return after the replica is written" choices:"sync|async" default:"async"`
+	MaxLagMb         int    `help:"Replica is marked lagging when async replication falls behind more than this"`
+}
+
+func (opts *DiskReplicaCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type DiskReplicaUpdateOptions struct {
+	options.BaseUpdateOptions
+
+	MaxLagMb *int `help:"Replica is marked lagging when async replication falls behind more than this"`
+}
+
+func (opts *DiskReplicaUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type DiskReplicaFailoverOptions struct {
+	DiskReplicaIdOptions
+
+	Force bool `help:"Failover even if the host of the server is online, the server must be stopped"`
+}
+
+func (opts *DiskReplicaFailoverOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(map[string]bool{"force": opts.Force}), nil
+}
@@ -44,11 +44,21 @@ func (opts *SDnsZoneIdOptions) Params() (jsonutils.JSONObject, error) {
 	return nil, nil
 }
 
+type DnsZoneTransferOptions struct {
+	TransferAllowIps  []string `help:"IP or CIDR allowed to transfer zone"`
+	TsigKeyName       string   `help:"TSIG key name required to transfer zone"`
+	TsigAlgorithm     string   `help:"TSIG algorithm" choices:"hmac-md5|hmac-sha1|hmac-sha256|hmac-sha512"`
+	TsigSecret        string   `help:"Base64 encoded TSIG secret"`
+	NotifySecondaries []string `help:"Secondary servers to send NOTIFY, e.g. 10.0.0.2:53"`
+}
+
 type DnsZoneCreateOptions struct {
 	options.EnabledStatusCreateOptions
 	ZoneType  string   `choices:"PublicZone|PrivateZone" metavar:"zone_type" default:"PrivateZone"`
 	VpcIds    []string `help:"Vpc Ids"`
 	ManagerId string   `help:"Manager id"`
+
+	DnsZoneTransferOptions
 }
 
 func (opts *DnsZoneCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -83,3 +93,30 @@ type DnsZoneRemoveVpcsOptions struct {
 func (opts *DnsZoneRemoveVpcsOptions) Params() (jsonutils.JSONObject, error) {
 	return jsonutils.Marshal(map[string]string{"vpc_ids": opts.VPC_IDS}), nil
 }
+
+type DnsZoneSetTransferOptions struct {
+	SDnsZoneIdOptions
+	DnsZoneTransferOptions
+}
+
+func (opts *DnsZoneSetTransferOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts.DnsZoneTransferOptions), nil
+}
+
+type DnsZoneEnableDnssecOptions struct {
+	SDnsZoneIdOptions
+	Algorithm string `help:"Signing algorithm" choices:"RSASHA256|ECDSAP256SHA256|ED25519" default:"ECDSAP256SHA256"`
+}
+
+func (opts *DnsZoneEnableDnssecOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(map[string]string{"algorithm": opts.Algorithm}), nil
+}
+
+type DnsZoneDnssecRolloverOptions struct {
+	SDnsZoneIdOptions
+	KEY_TYPE string `help:"Type of key to rollover" choices:"KSK|ZSK"`
+}
+
+func (opts *DnsZoneDnssecRolloverOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(map[string]string{"key_type": opts.KEY_TYPE}), nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type DrProtectionGroupListOptions struct {
+	options.BaseListOptions
+
+	TargetRegion    []string `help:"filter by target region"`
+	BackupStorageId []string `help:"filter by backup storage"`
+	GuestId         string   `help:"filter by protected server"`
+}
+
+func (opts *DrProtectionGroupListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type DrProtectionGroupIdOptions struct {
+	ID string `help:"Dr protection group Id or name"`
+}
+
+func (opts *DrProtectionGroupIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *DrProtectionGroupIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+func parseDrNetworkMappings(mappings []string) (api.DrNetworkMappings, error) {
+	ret := api.DrNetworkMappings{}
+	for _, mapping := range mappings {
+		parts := strings.Split(mapping, ":")
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid network mapping %s, expect <source_network>:<target_network>", mapping)
+		}
+		ret = append(ret, api.DrNetworkMapping{SourceNetworkId: parts[0], TargetNetworkId: parts[1]})
+	}
+	return ret, nil
+}
+
+type DrProtectionGroupCreateOptions struct {
+	NAME                  string   `help:"Name of the protection group" json:"name"`
+	TARGET_REGION         string   `help:"Region to recover servers in" json:"target_region"`
+	BACKUP_STORAGE        string   `help:"Backup storage keeping the recovery points" json:"backup_storage_id"`
+	TARGET_BACKUP_STORAGE string   `help:"Id of the same backup storage in target region" json:"target_backup_storage_id"`
+	RpoMinutes            int      `help:"Minutes between recovery points, default 5"`
+	RetainCount           int      `help:"Recovery points kept for each server, default 4"`
+	FullInterval          int      `help:"Incremental recovery points in a row before a full one, default 96"`
+	NetworkMapping        []string `help:"Network mapping, <source_network>:<target_network>" json:"-"`
+	TestNetwork           string   `help:"Isolated network of target region for test failover" json:"test_network_id"`
+	Guest                 []string `help:"Server to protect" json:"guest_ids"`
+}
+
+func (opts *DrProtectionGroupCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	mappings, err := parseDrNetworkMappings(opts.NetworkMapping)
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) > 0 {
+		params.Set("network_mappings", jsonutils.Marshal(mappings))
+	}
+	return params, nil
+}
+
+type DrProtectionGroupUpdateOptions struct {
+	options.BaseUpdateOptions
+
+	RpoMinutes     *int     `help:"Minutes between recovery points"`
+	RetainCount    *int     `help:"Recovery points kept for each server"`
+	FullInterval   *int     `help:"Incremental recovery points in a row before a full one"`
+	NetworkMapping []string `help:"Network mapping, <source_network>:<target_network>" json:"-"`
+	TestNetwork    *string  `help:"Isolated network of target region for test failover" json:"test_network_id"`
+}
+
+func (opts *DrProtectionGroupUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	mappings, err := parseDrNetworkMappings(opts.NetworkMapping)
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) > 0 {
+		params.Set("network_mappings", jsonutils.Marshal(mappings))
+	}
+	return params, nil
+}
+
+type DrProtectionGroupGuestsOptions struct {
+	DrProtectionGroupIdOptions
+
+	GUEST []string `help:"Servers to add or remove" json:"guest_ids"`
+}
+
+func (opts *DrProtectionGroupGuestsOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(map[string][]string{"guest_ids": opts.GUEST}), nil
+}
+
+type DrProtectionGroupFailoverOptions struct {
+	DrProtectionGroupIdOptions
+
+	RecoveryPointBefore string `help:"Use the latest recovery points taken before this time, e.g. 2023-01-02T15:04:05Z"`
+}
+
+func (opts *DrProtectionGroupFailoverOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.NewDict()
+	if len(opts.RecoveryPointBefore) > 0 {
+		params.Set("recovery_point_before", jsonutils.NewString(opts.RecoveryPointBefore))
+	}
+	return params, nil
+}
+
+type DrRecoveryPointListOptions struct {
+	options.BaseListOptions
+
+	DrProtectionGroupId string   `help:"filter by dr protection group"`
+	GuestId             []string `help:"filter by server"`
+}
+
+func (opts *DrRecoveryPointListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type DrRecoveryPointIdOptions struct {
+	ID string `help:"Recovery point Id or name"`
+}
+
+func (opts *DrRecoveryPointIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *DrRecoveryPointIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
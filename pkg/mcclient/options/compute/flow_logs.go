@@ -0,0 +1,99 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"yunion.io/x/jsonutils"
+
+	baseoptions "yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type FlowLogListOptions struct {
+	baseoptions.BaseListOptions
+
+	Vpc         string   `help:"vpc id or name"`
+	NetworkId   []string `help:"filter by network id"`
+	ServerId    []string `help:"filter by server id"`
+	TrafficType []string `help:"filter by traffic type" choices:"all|accept|reject"`
+	Target      []string `help:"filter by target" choices:"tsdb|s3"`
+}
+
+func (opts *FlowLogListOptions) Params() (jsonutils.JSONObject, error) {
+	return baseoptions.ListStructToParams(opts)
+}
+
+type FlowLogIdOptions struct {
+	ID string `help:"ID or name of flow log"`
+}
+
+func (opts *FlowLogIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *FlowLogIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+type FlowLogCreateOptions struct {
+	baseoptions.EnabledStatusCreateOptions
+
+	VpcId       string `help:"vpc id or name" required:"true"`
+	NetworkId   string `help:"only log traffic of this network"`
+	ServerId    string `help:"only log traffic of nic of this server, mac_addr is required"`
+	MacAddr     string `help:"mac address of server nic"`
+	TrafficType string `help:"type of traffic to log" choices:"all|accept|reject"`
+	LogRate     *int   `help:"max records per second of each nic, 0 means no limit"`
+
+	Target          string `help:"where records are shipped to" choices:"tsdb|s3"`
+	BackupStorageId string `help:"object storage type backup storage for target s3"`
+	TargetPrefix    string `help:"object key prefix for target s3"`
+}
+
+func (opts *FlowLogCreateOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type FlowLogUpdateOptions struct {
+	baseoptions.BaseUpdateOptions
+
+	TrafficType string `help:"type of traffic to log" choices:"all|accept|reject"`
+	LogRate     *int   `help:"max records per second of each nic, 0 means no limit"`
+}
+
+func (opts *FlowLogUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	return baseoptions.StructToParams(opts)
+}
+
+type FlowLogRecordsOptions struct {
+	baseoptions.BaseIdOptions
+
+	StartTime string `help:"start time, e.g. 2024-01-01T00:00:00Z, default one hour ago"`
+	EndTime   string `help:"end time, default now"`
+
+	SrcIp    string
+	SrcPort  int
+	DstIp    string
+	DstPort  int
+	Protocol string `help:"tcp, udp, icmp, etc."`
+
+	Verdict   string `choices:"accept|reject"`
+	Direction string `choices:"ingress|egress"`
+	ServerId  string
+	Limit     int `help:"max records returned, default 100"`
+}
+
+func (opts *FlowLogRecordsOptions) Params() (jsonutils.JSONObject, error) {
+	return baseoptions.StructToParams(opts)
+}
@@ -57,8 +57,14 @@ type LoadbalancerCertificateCreateOptions struct {
 	Manager string `json:"manager_id"`
 	Region  string `json:"cloudregion"`
 
-	Cert string `required:"true" json:"-" help:"path to certificate file"`
-	Pkey string `required:"true" json:"-" help:"path to private key file"`
+	Cert string `json:"-" help:"path to certificate file"`
+	Pkey string `json:"-" help:"path to private key file"`
+
+	CertSource        string   `help:"source of certificate" choices:"upload|acme"`
+	AcmeDirectoryUrl  string   `help:"directory url of acme server"`
+	AcmeEmail         string   `help:"contact email of acme account"`
+	AcmeChallengeType string   `help:"challenge type to validate domains" choices:"http-01|dns-01"`
+	AcmeDomains       []string `help:"domains of acme certificate"`
 }
 
 func (opts *LoadbalancerCertificateCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -74,6 +80,9 @@ func (opts *LoadbalancerCertificateCreateOptions) Params() (jsonutils.JSONObject
 
 	params.Update(sp)
 
+	if opts.CertSource == "acme" {
+		return params, nil
+	}
 	paramsCertKey, err := loadbalancerCertificateLoadFiles(opts.Cert, opts.Pkey, false)
 	if err != nil {
 		return nil, err
@@ -105,7 +114,8 @@ type LoadbalancerCertificateListOptions struct {
 	PublicKeyBitLen    *int
 	SignatureAlgorithm string
 	Cloudregion        string
-	Usable             *bool `help:"List certificates are usable"`
+	Usable             *bool    `help:"List certificates are usable"`
+	CertSource         []string `help:"List certificates of source" choices:"upload|acme"`
 }
 
 func (opts *LoadbalancerCertificateListOptions) Params() (jsonutils.JSONObject, error) {
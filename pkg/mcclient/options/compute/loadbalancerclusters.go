@@ -23,8 +23,9 @@ import (
 type LoadbalancerClusterCreateOptions struct {
 	NAME string
 
-	Zone string `required:"true"`
-	Wire string
+	Zone      string `required:"true"`
+	Wire      string
+	DataPlane string `choices:"haproxy|envoy" help:"data plane of lbagents in the cluster"`
 }
 
 func (opts *LoadbalancerClusterCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -34,7 +35,8 @@ func (opts *LoadbalancerClusterCreateOptions) Params() (jsonutils.JSONObject, er
 type LoadbalancerClusterUpdateOptions struct {
 	ID string `json:"-"`
 
-	Wire string
+	Wire      string
+	DataPlane string `choices:"haproxy|envoy" help:"data plane of lbagents in the cluster"`
 }
 
 func (opts *LoadbalancerClusterUpdateOptions) Params() (jsonutils.JSONObject, error) {
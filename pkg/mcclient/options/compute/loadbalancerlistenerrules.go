@@ -27,8 +27,12 @@ type LoadbalancerListenerRuleCreateOptions struct {
 	Domain       string
 	Path         string
 
-	HTTPRequestRate       *int
-	HTTPRequestRatePerSrc *int
+	HTTPRequestRate          *int
+	HTTPRequestRatePerSrc    *int
+	HTTPRequestRatePerHeader *int   `help:"max request rate of each client identified by value of --http-request-rate-header"`
+	HTTPRequestRateHeader    string `help:"request header identifying clients, e.g. X-Api-Key"`
+
+	LoadbalancerHTTPHeaderRewriteOptions
 
 	Redirect       *string `choices:"off|raw"`
 	RedirectCode   *int    `choices:"301|302|307"`
@@ -37,6 +41,14 @@ type LoadbalancerListenerRuleCreateOptions struct {
 	RedirectPath   *string `json:",allowempty"`
 }
 
+func (opts *LoadbalancerListenerRuleCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	if err := opts.update(params, false); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
 type LoadbalancerListenerRuleListOptions struct {
 	options.BaseListOptions
 
@@ -62,8 +74,13 @@ type LoadbalancerListenerRuleUpdateOptions struct {
 
 	BackendGroup string
 
-	HTTPRequestRate       *int
-	HTTPRequestRatePerSrc *int
+	HTTPRequestRate          *int
+	HTTPRequestRatePerSrc    *int
+	HTTPRequestRatePerHeader *int    `help:"max request rate of each client identified by value of --http-request-rate-header"`
+	HTTPRequestRateHeader    *string `help:"request header identifying clients, e.g. X-Api-Key" json:",allowempty"`
+
+	LoadbalancerHTTPHeaderRewriteOptions
+	ClearHttpHeaderRewrites bool `json:"-" help:"remove all header rewrites"`
 
 	Redirect       *string `choices:"off|raw"`
 	RedirectCode   *int    `choices:"301|302|307"`
@@ -73,7 +90,14 @@ type LoadbalancerListenerRuleUpdateOptions struct {
 }
 
 func (opts *LoadbalancerListenerRuleUpdateOptions) Params() (jsonutils.JSONObject, error) {
-	return options.StructToParams(opts)
+	params, err := options.StructToParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.update(params, opts.ClearHttpHeaderRewrites); err != nil {
+		return nil, err
+	}
+	return params, nil
 }
 
 type LoadbalancerListenerRuleGetOptions struct {
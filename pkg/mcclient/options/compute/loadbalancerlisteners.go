@@ -15,11 +15,50 @@
 package compute
 
 import (
+	"fmt"
+	"strings"
+
 	"yunion.io/x/jsonutils"
 
+	api "yunion.io/x/onecloud/pkg/apis/compute"
 	"yunion.io/x/onecloud/pkg/mcclient/options"
 )
 
+type LoadbalancerHTTPHeaderRewriteOptions struct {
+	HttpHeaderRewrite []string `json:"-" help:"header rewrite in form of <request|response>:<add|set|del>:<name>[:<value>], e.g. request:set:X-Real-IP:%[src]"`
+}
+
+func (opts *LoadbalancerHTTPHeaderRewriteOptions) rewrites() (api.SLoadbalancerHTTPHeaderRewrites, error) {
+	rewrites := api.SLoadbalancerHTTPHeaderRewrites{}
+	for _, s := range opts.HttpHeaderRewrite {
+		parts := strings.SplitN(s, ":", 4)
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid header rewrite %q", s)
+		}
+		rewrite := api.SLoadbalancerHTTPHeaderRewrite{
+			Direction: parts[0],
+			Action:    parts[1],
+			Name:      parts[2],
+		}
+		if len(parts) == 4 {
+			rewrite.Value = parts[3]
+		}
+		rewrites = append(rewrites, rewrite)
+	}
+	return rewrites, nil
+}
+
+func (opts *LoadbalancerHTTPHeaderRewriteOptions) update(params *jsonutils.JSONDict, clear bool) error {
+	rewrites, err := opts.rewrites()
+	if err != nil {
+		return err
+	}
+	if len(rewrites) > 0 || clear {
+		params.Set("http_header_rewrites", jsonutils.Marshal(rewrites))
+	}
+	return nil
+}
+
 type LoadbalancerListenerCreateOptions struct {
 	NAME string
 
@@ -71,8 +110,15 @@ type LoadbalancerListenerCreateOptions struct {
 	TLSCipherPolicy string
 	EnableHttp2     string `choices:"true|false"`
 
-	HTTPRequestRate       *int
-	HTTPRequestRatePerSrc *int
+	HTTPRequestRate          *int
+	HTTPRequestRatePerSrc    *int
+	HTTPRequestRatePerHeader *int   `help:"max request rate of each client identified by value of --http-request-rate-header"`
+	HTTPRequestRateHeader    string `help:"request header identifying clients, e.g. X-Api-Key"`
+
+	LoadbalancerHTTPHeaderRewriteOptions
+
+	MaxConn       *int `help:"max concurrent connections of the listener"`
+	MaxConnPerSrc *int `help:"max concurrent connections of each source address"`
 
 	Redirect       *string `choices:"off|raw"`
 	RedirectCode   *int    `choices:"301|302|307"`
@@ -81,6 +127,14 @@ type LoadbalancerListenerCreateOptions struct {
 	RedirectPath   *string `json:",allowempty"`
 }
 
+func (opts *LoadbalancerListenerCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	if err := opts.update(params, false); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
 type LoadbalancerListenerListOptions struct {
 	options.BaseListOptions
 
@@ -189,8 +243,16 @@ type LoadbalancerListenerUpdateOptions struct {
 	TLSCipherPolicy string
 	EnableHttp2     string `choices:"true|false"`
 
-	HTTPRequestRate       *int
-	HTTPRequestRatePerSrc *int
+	HTTPRequestRate          *int
+	HTTPRequestRatePerSrc    *int
+	HTTPRequestRatePerHeader *int    `help:"max request rate of each client identified by value of --http-request-rate-header"`
+	HTTPRequestRateHeader    *string `help:"request header identifying clients, e.g. X-Api-Key" json:",allowempty"`
+
+	LoadbalancerHTTPHeaderRewriteOptions
+	ClearHttpHeaderRewrites bool `json:"-" help:"remove all header rewrites"`
+
+	MaxConn       *int `help:"max concurrent connections of the listener"`
+	MaxConnPerSrc *int `help:"max concurrent connections of each source address"`
 
 	Redirect       *string `choices:"off|raw"`
 	RedirectCode   *int    `choices:"301|302|307"`
@@ -200,7 +262,14 @@ type LoadbalancerListenerUpdateOptions struct {
 }
 
 func (opts *LoadbalancerListenerUpdateOptions) Params() (jsonutils.JSONObject, error) {
-	return options.StructToParams(opts)
+	params, err := options.StructToParams(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.update(params, opts.ClearHttpHeaderRewrites); err != nil {
+		return nil, err
+	}
+	return params, nil
 }
 
 type LoadbalancerListenerGetOptions struct {
@@ -113,6 +113,8 @@ type SecgroupsAddRuleOptions struct {
 	Cidr        string `help:"IP or CIRD for rule"`
 	Description string `help:"Desciption for rule"`
 	Ports       string `help:"Port for rule"`
+
+	PeerSecgroupId string `help:"Peer secgroup ID or Name for rule, cidr is ignored when specified"`
 }
 
 func (opts *SecgroupsAddRuleOptions) Params() (jsonutils.JSONObject, error) {
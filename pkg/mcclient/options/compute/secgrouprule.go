@@ -43,6 +43,8 @@ type SecGroupRulesCreateOptions struct {
 	RULE     string `json:"-"`
 	Priority int64  `help:"priority of Rule" default:"50"`
 	Desc     string `help:"Description" json:"description"`
+
+	PeerSecgroup string `help:"Peer secgroup ID or Name, match addresses of its member servers instead of cidr"`
 }
 
 func (opts *SecGroupRulesCreateOptions) Params() (jsonutils.JSONObject, error) {
@@ -50,7 +52,7 @@ func (opts *SecGroupRulesCreateOptions) Params() (jsonutils.JSONObject, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "invalid rule %s", opts.RULE)
 	}
-	return jsonutils.Marshal(map[string]interface{}{
+	params := map[string]interface{}{
 		"direction":   rule.Direction,
 		"action":      rule.Action,
 		"protocol":    rule.Protocol,
@@ -59,7 +61,12 @@ func (opts *SecGroupRulesCreateOptions) Params() (jsonutils.JSONObject, error) {
 		"priority":    opts.Priority,
 		"description": opts.Desc,
 		"secgroup_id": opts.SECGROUP,
-	}), nil
+	}
+	if len(opts.PeerSecgroup) > 0 {
+		delete(params, "cidr")
+		params["peer_secgroup_id"] = opts.PeerSecgroup
+	}
+	return jsonutils.Marshal(params), nil
 }
 
 type SecGroupRulesUpdateOptions struct {
@@ -16,6 +16,7 @@ package compute
 
 import (
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 
@@ -30,17 +31,19 @@ import (
 )
 
 type PodCreateOptions struct {
-	NAME string `help:"Name of server pod" json:"-"`
+	NAME_MEM_IMAGE []string `help:"Name of server pod, memory size MB and image of the container, only the name and an optional memory size are accepted when --from-file is set" metavar:"NAME MEM IMAGE" nargs:"+" json:"-"`
 	ServerCreateCommonConfig
-	MEM         string `help:"Memory size MB" metavar:"MEM" json:"-"`
-	VcpuCount   int    `help:"#CPU cores of VM server, default 1" default:"1" metavar:"<SERVER_CPU_COUNT>" json:"vcpu_count" token:"ncpu"`
-	AllowDelete *bool  `help:"Unlock server to allow deleting" json:"-"`
+	VcpuCount   int   `help:"#CPU cores of VM server, default 1 or the sum of containers' cpu limits of --from-file" metavar:"<SERVER_CPU_COUNT>" json:"vcpu_count" token:"ncpu"`
+	AllowDelete *bool `help:"Unlock server to allow deleting" json:"-"`
 	//PortMapping []string `help:"Port mapping of the pod and the format is: host_port=8080,port=80,protocol=<tcp|udp>,host_port_range=<int>-<int>" short-token:"p"`
-	Arch             string `help:"image arch" choices:"aarch64|x86_64"`
-	AutoStart        bool   `help:"Auto start server after it is created"`
-	ShutdownBehavior string `help:"Behavior after VM server shutdown" metavar:"<SHUTDOWN_BEHAVIOR>" choices:"stop|terminate|stop_release_gpu"`
-	PodUid           int64  `help:"UID of pod" default:"0"`
-	PodGid           int64  `help:"GID of pod" default:"0"`
+	Arch             string   `help:"image arch" choices:"aarch64|x86_64"`
+	AutoStart        bool     `help:"Auto start server after it is created"`
+	ShutdownBehavior string   `help:"Behavior after VM server shutdown" metavar:"<SHUTDOWN_BEHAVIOR>" choices:"stop|terminate|stop_release_gpu"`
+	PodUid           int64    `help:"UID of pod" default:"0"`
+	PodGid           int64    `help:"GID of pod" default:"0"`
+	InitContainer    []string `help:"Init container which runs to completion before the main container, the format is: image=<image>,command=<command>,name=<name>"`
+	FromFile         string   `help:"Create containers from Kubernetes Pod/Deployment manifest or docker-compose file, the container options are ignored" json:"-"`
+	Format           string   `help:"Format of --from-file, detected from the content if not specified" choices:"kubernetes|compose" json:"-"`
 
 	ContainerCreateCommonOptions
 }
@@ -162,6 +165,32 @@ func parsePodPortMapping(input string) (*computeapi.PodPortMapping, error) {
 	}
 }
 
+func parsePodInitContainer(input string) (*computeapi.PodContainerCreateInput, error) {
+	ctr := &computeapi.PodContainerCreateInput{}
+	for _, seg := range strings.Split(input, ",") {
+		info := strings.SplitN(seg, "=", 2)
+		if len(info) != 2 {
+			return nil, errors.Errorf("invalid option %s", seg)
+		}
+		key := info[0]
+		val := info[1]
+		switch key {
+		case "name":
+			ctr.Name = val
+		case "image":
+			ctr.Image = val
+		case "command":
+			ctr.Command = strings.Split(val, " ")
+		default:
+			return nil, errors.Errorf("unknown option %s", key)
+		}
+	}
+	if ctr.Image == "" {
+		return nil, errors.Error("image must specified")
+	}
+	return ctr, nil
+}
+
 func parseContainerDevice(dev string) (*computeapi.ContainerDevice, error) {
 	segs := strings.Split(dev, ":")
 	if len(segs) != 3 {
@@ -195,7 +224,11 @@ func (o *PodCreateOptions) Params() (*computeapi.ServerCreateInput, error) {
 		}
 	}*/
 
-	spec, err := o.getCreateSpec()
+	if len(o.NAME_MEM_IMAGE) != 3 {
+		return nil, errors.Error("NAME, MEM and IMAGE are required")
+	}
+	name, mem, image := o.NAME_MEM_IMAGE[0], o.NAME_MEM_IMAGE[1], o.NAME_MEM_IMAGE[2]
+	spec, err := o.getCreateSpec(image)
 	if err != nil {
 		return nil, errors.Wrap(err, "get container create spec")
 	}
@@ -216,6 +249,14 @@ func (o *PodCreateOptions) Params() (*computeapi.ServerCreateInput, error) {
 		},
 	}
 
+	for idx, initStr := range o.InitContainer {
+		initCtr, err := parsePodInitContainer(initStr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse init container %d: %s", idx, initStr)
+		}
+		params.Pod.InitContainers = append(params.Pod.InitContainers, initCtr)
+	}
+
 	if o.Uid != 0 {
 		params.Pod.SecurityContext.RunAsUser = &o.Uid
 	}
@@ -227,14 +268,17 @@ func (o *PodCreateOptions) Params() (*computeapi.ServerCreateInput, error) {
 		disableDelete := false
 		params.DisableDelete = &disableDelete
 	}
-	if regutils.MatchSize(o.MEM) {
-		memSize, err := fileutils.GetSizeMb(o.MEM, 'M', 1024)
+	if params.VcpuCount == 0 {
+		params.VcpuCount = 1
+	}
+	if regutils.MatchSize(mem) {
+		memSize, err := fileutils.GetSizeMb(mem, 'M', 1024)
 		if err != nil {
 			return nil, err
 		}
 		params.VmemSize = memSize
 	} else {
-		return nil, fmt.Errorf("Invalid memory input: %q", o.MEM)
+		return nil, fmt.Errorf("Invalid memory input: %q", mem)
 	}
 	for idx := range o.IsolatedDevice {
 		tmpIdx := idx
@@ -246,7 +290,7 @@ func (o *PodCreateOptions) Params() (*computeapi.ServerCreateInput, error) {
 			})
 	}
 	params.OsArch = o.Arch
-	params.Name = o.NAME
+	params.Name = name
 	return params, nil
 }
 
@@ -282,3 +326,74 @@ func (o *PodSetPortMappingOptions) Params() (jsonutils.JSONObject, error) {
 		PortMappings: portMappings,
 	}), nil
 }
+
+func (o *PodCreateOptions) ConvertParams() (jsonutils.JSONObject, error) {
+	content, err := os.ReadFile(o.FromFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read file %s", o.FromFile)
+	}
+	return jsonutils.Marshal(&computeapi.PodConvertManifestInput{
+		Format:   computeapi.PodManifestFormat(o.Format),
+		Manifest: string(content),
+	}), nil
+}
+
+func (o *PodCreateOptions) FromFileParams(output *computeapi.PodConvertManifestOutput) (*computeapi.ServerCreateInput, error) {
+	config, err := o.ServerCreateCommonConfig.Data()
+	if err != nil {
+		return nil, errors.Wrapf(err, "get ServerCreateCommonConfig.Data")
+	}
+	config.Hypervisor = computeapi.HYPERVISOR_POD
+	if config.Count <= 1 && output.Count > 1 {
+		config.Count = output.Count
+	}
+	if len(output.PortMappings) > 0 {
+		if len(config.Networks) == 0 {
+			return nil, errors.Error("--net is required by the port mappings of the file")
+		}
+		config.Networks[0].PortMappings = append(config.Networks[0].PortMappings, output.PortMappings...)
+	}
+	for _, ctr := range append(output.Pod.InitContainers, output.Pod.Containers...) {
+		for _, vm := range ctr.VolumeMounts {
+			if vm.Type == apis.CONTAINER_VOLUME_MOUNT_TYPE_DISK && len(config.Disks) == 0 {
+				return nil, errors.Errorf("--disk is required by the volume mount %s of container %s", vm.MountPath, ctr.Name)
+			}
+		}
+	}
+
+	if len(o.NAME_MEM_IMAGE) > 2 {
+		return nil, errors.Error("IMAGE can't be set with --from-file")
+	}
+
+	params := &computeapi.ServerCreateInput{
+		ServerConfigs:    config,
+		VcpuCount:        output.VcpuCount,
+		VmemSize:         output.VmemSize,
+		AutoStart:        o.AutoStart,
+		ShutdownBehavior: o.ShutdownBehavior,
+		Pod:              output.Pod,
+	}
+	if o.VcpuCount > 0 {
+		params.VcpuCount = o.VcpuCount
+	}
+	if params.VcpuCount == 0 {
+		params.VcpuCount = 1
+	}
+	if len(o.NAME_MEM_IMAGE) == 2 {
+		memSize, err := fileutils.GetSizeMb(o.NAME_MEM_IMAGE[1], 'M', 1024)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid memory input: %q", o.NAME_MEM_IMAGE[1])
+		}
+		params.VmemSize = memSize
+	}
+	if params.VmemSize == 0 {
+		return nil, errors.Error("MEM is required since no memory limit is found in the file")
+	}
+	if options.BoolV(o.AllowDelete) {
+		disableDelete := false
+		params.DisableDelete = &disableDelete
+	}
+	params.OsArch = o.Arch
+	params.Name = o.NAME_MEM_IMAGE[0]
+	return params, nil
+}
@@ -369,6 +369,10 @@ type ServerConfigs struct {
 	AutoSwitchToBackupOnHostDown bool   `help:"Auto switch to backup server on host down"`
 	Daemon                       *bool  `help:"Set as a daemon server" json:"is_daemon"`
 
+	NumaNodes        int    `help:"Guest virtual numa node count" json:"numa_nodes"`
+	MemoryBacking    string `help:"Guest memory backing" choices:"hugepages_2m|hugepages_1g" json:"memory_backing"`
+	MemoryStrictBind bool   `help:"Strictly bind guest numa memory to host numa nodes" json:"memory_strict_bind"`
+
 	RaidConfig      []string `help:"Baremetal raid config" json:"-"`
 	RootDiskMatcher string   `help:"Baremetal root disk matcher, e.g. 'device=/dev/sdb' 'size=900G' 'size_start=800G,size_end=900G'" json:"-"`
 }
@@ -382,6 +386,9 @@ func (o ServerConfigs) Data() (*computeapi.ServerConfigs, error) {
 	data.PreferBackupHost = o.BackupHost
 	data.IsDaemon = o.Daemon
 	data.Hypervisor = o.Hypervisor
+	data.NumaNodes = o.NumaNodes
+	data.MemoryBacking = o.MemoryBacking
+	data.MemoryStrictBind = o.MemoryStrictBind
 	if len(o.RaidConfig) > 0 {
 		// if data.Hypervisor != "baremetal" {
 		// 	return nil, fmt.Errorf("RaidConfig is applicable to baremetal ONLY")
@@ -450,6 +457,9 @@ type ServerCreateOptionalOptions struct {
 	CpuSockets     int    `help:"Cpu sockets"`
 	EnableMemclean bool   `help:"clean guest memory after guest exit" json:"enable_memclean"`
 
+	EnableMemoryBalloon bool `help:"enable virtio-balloon device to reclaim guest idle memory" json:"enable_memory_balloon"`
+	BalloonMinMemMb     int  `help:"min memory size in MB balloon can shrink guest to" json:"balloon_min_mem_mb"`
+
 	Keypair          string   `help:"SSH Keypair"`
 	Password         string   `help:"Default user password"`
 	LoginAccount     string   `help:"Guest login account"`
@@ -588,6 +598,9 @@ func (opts *ServerCreateOptionalOptions) OptionalParams() (*computeapi.ServerCre
 		GuestImageID:       opts.GuestImageID,
 		Secgroups:          opts.Secgroups,
 		EnableMemclean:     opts.EnableMemclean,
+
+		EnableMemoryBalloon: opts.EnableMemoryBalloon,
+		BalloonMinMemMb:     opts.BalloonMinMemMb,
 	}
 
 	params.ProjectId = opts.Project
@@ -1579,3 +1592,43 @@ type ServerChangeBillingTypeOptions struct {
 func (o *ServerChangeBillingTypeOptions) Params() (jsonutils.JSONObject, error) {
 	return jsonutils.Marshal(map[string]string{"billing_type": o.BillingType}), nil
 }
+
+type ServerAttachVirtiofsOptions struct {
+	ServerIdOptions
+	TAG         string `help:"Tag used to mount in guest, e.g. mount -t virtiofs <tag> /mnt" json:"tag"`
+	SourceType  string `help:"Source of shared directory" choices:"host_path|cephfs|mount_target" json:"source_type" default:"host_path"`
+	HostPath    string `help:"Host directory, required by host_path source" json:"host_path"`
+	Filesystem  string `help:"CephFS filesystem id or name, required by cephfs source" json:"filesystem_id"`
+	SubPath     string `help:"Sub path of cephfs filesystem" json:"sub_path"`
+	MountTarget string `help:"Mount target id or name, required by mount_target source" json:"mount_target_id"`
+	ReadOnly    bool   `help:"Share directory read only" json:"read_only"`
+}
+
+func (o *ServerAttachVirtiofsOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerDetachVirtiofsOptions struct {
+	ServerIdOptions
+	TAG string `help:"Tag of virtiofs shared directory" json:"tag"`
+}
+
+func (o *ServerDetachVirtiofsOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerSerialLogOptions struct {
+	ServerIdOptions
+	Offset *int64 `help:"Read serial log from the byte offset, read the tail if not specified" json:"offset"`
+	Tail   int64  `help:"Bytes to read from the end of serial log" json:"tail"`
+	Limit  int64  `help:"Max bytes to read from offset" json:"limit"`
+}
+
+func (o *ServerSerialLogOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(o), nil
+}
+
+type ServerScreenDumpOptions struct {
+	ServerIdOptions
+	Output string `help:"Save screendump png to file" default:"screendump.png" json:"-"`
+}
@@ -137,6 +137,15 @@ func (o *StorageSetHardwareInfoOptions) Params() (jsonutils.JSONObject, error) {
 	return jsonutils.Marshal(o), nil
 }
 
+type StorageUsageHistoryOptions struct {
+	options.BaseIdOptions
+	Days int `help:"Only show usage samples of recent days"`
+}
+
+func (o *StorageUsageHistoryOptions) Params() (jsonutils.JSONObject, error) {
+	return options.StructToParams(o)
+}
+
 type StorageSetCommitBoundOptions struct {
 	options.BaseIdOptions
 	Cmtbound *float32 `help:"Storage commit bound"`
@@ -36,6 +36,8 @@ type ImageListOptions struct {
 	OsArchPreciseMatch       bool     `help:"OS arch precise match"`
 	Distribution             []string `help:"Distribution filter, e.g. 'CentOS, Ubuntu, Debian, Windows'"`
 	DistributionPreciseMatch bool     `help:"Distribution precise match"`
+	ScanStatus               []string `help:"Image scan status filter" choices:"scanned|failed"`
+	MinVulnSeverity          string   `help:"List images with vulnerabilities at or above the severity" choices:"low|medium|high|critical"`
 }
 
 func (o *ImageListOptions) Params() (jsonutils.JSONObject, error) {
@@ -89,6 +91,12 @@ func (o *ImageListOptions) Params() (jsonutils.JSONObject, error) {
 	if o.DistributionPreciseMatch {
 		params.Add(jsonutils.JSONTrue, "distribution_precise_match")
 	}
+	if len(o.ScanStatus) > 0 {
+		params.Add(jsonutils.NewStringArray(o.ScanStatus), "scan_status")
+	}
+	if len(o.MinVulnSeverity) > 0 {
+		params.Add(jsonutils.NewString(o.MinVulnSeverity), "min_vuln_severity")
+	}
 	return params, nil
 }
 
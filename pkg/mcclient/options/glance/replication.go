@@ -0,0 +1,113 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glance
+
+import (
+	"strings"
+
+	"yunion.io/x/jsonutils"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type ImageReplicationListOptions struct {
+	options.BaseListOptions
+
+	ImageId []string `help:"filter by source image id or name"`
+	Trigger []string `help:"filter by trigger" choices:"manual|on_change|schedule"`
+}
+
+func (opts *ImageReplicationListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type ImageReplicationIdOptions struct {
+	ID string `help:"ID or name of image replication" json:"-"`
+}
+
+func (opts *ImageReplicationIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *ImageReplicationIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+// parseReplicationTarget parses region[:project]
+func parseReplicationTarget(target string) jsonutils.JSONObject {
+	params := jsonutils.NewDict()
+	region, project, _ := strings.Cut(target, ":")
+	params.Set("region", jsonutils.NewString(region))
+	if len(project) > 0 {
+		params.Set("project", jsonutils.NewString(project))
+	}
+	return params
+}
+
+type ImageReplicationCreateOptions struct {
+	options.BaseCreateOptions
+	IMAGE           string   `help:"ID or name of source image" json:"image_id"`
+	Target          []string `help:"target in format region[:project], project defaults to the project of the image" json:"-"`
+	Trigger         string   `help:"replication trigger" choices:"manual|on_change|schedule" json:"trigger,omitempty"`
+	IntervalMinutes int      `help:"replication interval in minutes of schedule trigger" json:"interval_minutes,omitzero"`
+	ProjectDomainId string   `help:"domain of the replication policy" json:"project_domain_id,omitempty"`
+}
+
+func (opts *ImageReplicationCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	targets := jsonutils.NewArray()
+	for _, target := range opts.Target {
+		targets.Add(parseReplicationTarget(target))
+	}
+	params.Set("targets", targets)
+	return params, nil
+}
+
+type ImageReplicationUpdateOptions struct {
+	options.BaseUpdateOptions
+	Trigger         string `help:"replication trigger" choices:"manual|on_change|schedule"`
+	IntervalMinutes *int   `help:"replication interval in minutes of schedule trigger"`
+}
+
+func (opts *ImageReplicationUpdateOptions) Params() (jsonutils.JSONObject, error) {
+	params, _ := opts.BaseUpdateOptions.Params()
+	dict := params.(*jsonutils.JSONDict)
+	if len(opts.Trigger) > 0 {
+		dict.Set("trigger", jsonutils.NewString(opts.Trigger))
+	}
+	if opts.IntervalMinutes != nil {
+		dict.Set("interval_minutes", jsonutils.NewInt(int64(*opts.IntervalMinutes)))
+	}
+	return dict, nil
+}
+
+type ImageReplicationSyncOptions struct {
+	ImageReplicationIdOptions
+	Force bool `help:"copy image again to all targets, including those already in sync"`
+}
+
+func (opts *ImageReplicationSyncOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
+
+type ImageReplicationTargetOptions struct {
+	ImageReplicationIdOptions
+	REGION  string `help:"target region" json:"region"`
+	Project string `help:"target project, when removing targets defaults to all targets of the region" json:"project,omitempty"`
+}
+
+func (opts *ImageReplicationTargetOptions) Params() (jsonutils.JSONObject, error) {
+	return jsonutils.Marshal(opts), nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glance
+
+import (
+	"os"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/mcclient/options"
+)
+
+type ImageSignerKeyListOptions struct {
+	options.BaseListOptions
+
+	Algorithm   []string `help:"filter by signature algorithm" choices:"ecdsa|ed25519"`
+	Fingerprint []string `help:"filter by public key fingerprint"`
+}
+
+func (opts *ImageSignerKeyListOptions) Params() (jsonutils.JSONObject, error) {
+	return options.ListStructToParams(opts)
+}
+
+type ImageSignerKeyIdOptions struct {
+	ID string `help:"ID or name of image signer key"`
+}
+
+func (opts *ImageSignerKeyIdOptions) GetId() string {
+	return opts.ID
+}
+
+func (opts *ImageSignerKeyIdOptions) Params() (jsonutils.JSONObject, error) {
+	return nil, nil
+}
+
+type ImageSignerKeyCreateOptions struct {
+	options.BaseCreateOptions
+	PUBLIC_KEY_FILE string `help:"PEM encoded ecdsa or ed25519 public key file, e.g. cosign.pub" json:"-"`
+	ProjectDomainId string `help:"domain the key is trusted in" json:"project_domain_id"`
+}
+
+func (opts *ImageSignerKeyCreateOptions) Params() (jsonutils.JSONObject, error) {
+	params := jsonutils.Marshal(opts).(*jsonutils.JSONDict)
+	data, err := os.ReadFile(opts.PUBLIC_KEY_FILE)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", opts.PUBLIC_KEY_FILE)
+	}
+	params.Set("public_key", jsonutils.NewString(string(data)))
+	return params, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"context"
+	"fmt"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
+	"yunion.io/x/onecloud/pkg/scheduler/api"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// MemoryBackingPredicate filter hosts which can't provide the hugepages
+// required by guest memory backing or can't pin the memory of strict bind guest
+// to numa nodes. The free native hugepages of each numa node are checked by
+// MemoryPredicate, the free hugepages of the other reserved page sizes here.
+type MemoryBackingPredicate struct {
+	predicates.BasePredicate
+}
+
+func (p *MemoryBackingPredicate) Name() string {
+	return "host_memory_backing"
+}
+
+func (p *MemoryBackingPredicate) Clone() core.FitPredicate {
+	return &MemoryBackingPredicate{}
+}
+
+func (p *MemoryBackingPredicate) PreExecute(ctx context.Context, u *core.Unit, cs []core.Candidater) (bool, error) {
+	data := u.SchedData()
+	if data.ServerConfigs == nil || (len(data.MemoryBacking) == 0 && !data.MemoryStrictBind) {
+		return false, nil
+	}
+	if data.Hypervisor != computeapi.HYPERVISOR_KVM {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (p *MemoryBackingPredicate) Execute(ctx context.Context, u *core.Unit, c core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	h := predicates.NewPredicateHelper(p, u, c)
+	d := u.SchedData()
+
+	if d.MemoryStrictBind && c.Getter().GetFreeCpuNuma() == nil {
+		h.Exclude("host numa allocate disabled, memory strict bind requires guest memory pinned to numa nodes")
+	}
+	if len(d.MemoryBacking) == 0 {
+		return h.GetResult()
+	}
+
+	reqPageSizeKB := computeapi.GuestMemoryBackingPageSizeKB(d.MemoryBacking)
+	hostPageSizeKB := c.Getter().HugepageSizeKB()
+	if hostPageSizeKB == reqPageSizeKB {
+		if c.Getter().GetFreeCpuNuma() == nil {
+			h.Exclude("host numa allocate disabled, can't account free hugepages")
+		}
+	} else if free := c.Getter().GetFreeHugepages(reqPageSizeKB); free == nil {
+		// hugetlbfs of the page size is mounted for the guest when host native hugepages isn't used
+		h.Exclude(fmt.Sprintf("host has no %dKB hugepages reserved, required by memory backing %s", reqPageSizeKB, d.MemoryBacking))
+	} else if !hugepagesFit(d, reqPageSizeKB, free) {
+		h.Exclude(fmt.Sprintf("host free %dKB hugepages of numa nodes %v, %dMB taken by unpinned guests, can't back guest memory %dMB of memory backing %s",
+			reqPageSizeKB, free.NodeFreeMB, free.UnplacedMB, d.Memory, d.MemoryBacking))
+	}
+
+	return h.GetResult()
+}
+
+// hugepagesFit tells whether the free hugepages can back the memory of each
+// guest numa node, the memory pinned to host numa nodes must fit on them
+func hugepagesFit(d *api.SchedInfo, pageSizeKB int, free *schedapi.SFreeHugepages) bool {
+	pinned := make(map[int]int)
+	for _, pin := range d.CpuNumaPin {
+		if pin.MemSizeMB != nil {
+			pinned[pin.NodeId] += *pin.MemSizeMB
+		}
+	}
+	if len(pinned) > 0 {
+		return free.PinnedFit(pinned)
+	}
+	pageSizeMB := pageSizeKB / 1024
+	nodeMemMB := func(nodeCount int) int {
+		pages := (d.Memory + pageSizeMB*nodeCount - 1) / (pageSizeMB * nodeCount)
+		return pages * pageSizeMB
+	}
+	if d.NumaNodes > 0 {
+		return free.NodesFit(d.NumaNodes, nodeMemMB(d.NumaNodes))
+	}
+	// the guest memory is split into as many numa nodes as needed
+	for nodeCount := 1; nodeCount <= len(free.NodeFreeMB); nodeCount *= 2 {
+		if free.NodesFit(nodeCount, nodeMemMB(nodeCount)) {
+			return true
+		}
+	}
+	return false
+}
@@ -70,9 +70,13 @@ func (p *MemoryPredicate) Execute(ctx context.Context, u *core.Unit, c core.Cand
 
 	if cpuNumaFree := getter.GetFreeCpuNuma(); cpuNumaFree != nil {
 		allcateEnough := false
-		if d.CpuNumaPin != nil {
+		if d.CpuNumaPin != nil || d.NumaNodes > 0 {
 			nodeCount := len(d.CpuNumaPin)
-			if scheduler.NodesFreeCpuEnough(nodeCount, d.Ncpu, cpuNumaFree) &&
+			if nodeCount == 0 {
+				nodeCount = d.NumaNodes
+			}
+			if nodeCount <= len(cpuNumaFree) &&
+				scheduler.NodesFreeCpuEnough(nodeCount, d.Ncpu, cpuNumaFree) &&
 				scheduler.NodesFreeMemSizeEnough(nodeCount, int(reqMemSize), cpuNumaFree) {
 				allcateEnough = true
 			}
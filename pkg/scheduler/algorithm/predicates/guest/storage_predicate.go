@@ -220,7 +220,9 @@ func (p *StoragePredicate) Execute(ctx context.Context, u *core.Unit, c core.Can
 		ss := []string{}
 		for _, s := range getter.Storages() {
 			if candidate.IsStorageBackendMediumMatch(s, backend, mediumType) {
-				if isActual {
+				if candidate.IsStorageScheduledByActualUsage(s.SStorage) {
+					ss = append(ss, fmt.Sprintf("storage %q, actual_total:%d - actual_used:%d - unreported:%d - headroom = free:%d", s.GetName(), s.GetCapacity(), s.ActualCapacityUsed, s.GetActualUnreportedCapacity(), s.ActualFreeCapacity))
+				} else if isActual {
 					total := s.Capacity
 					free := total - s.ActualCapacityUsed
 					ss = append(ss, fmt.Sprintf("storage %q, actual_total:%d - actual_used:%d = free:%d", s.GetName(), total, s.ActualCapacityUsed, free))
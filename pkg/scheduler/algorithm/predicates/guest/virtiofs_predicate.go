@@ -0,0 +1,77 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package guest
+
+import (
+	"context"
+	"fmt"
+
+	"yunion.io/x/pkg/util/sets"
+
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/scheduler/algorithm/predicates"
+	"yunion.io/x/onecloud/pkg/scheduler/core"
+)
+
+// VirtiofsPredicate filter hosts which can't serve the virtiofs shared
+// directories of guest, host must run virtiofsd, be able to mount the
+// source filesystem and locate in the zone of the filesystem.
+type VirtiofsPredicate struct {
+	predicates.BasePredicate
+}
+
+func (p *VirtiofsPredicate) Name() string {
+	return "host_virtiofs"
+}
+
+func (p *VirtiofsPredicate) Clone() core.FitPredicate {
+	return &VirtiofsPredicate{}
+}
+
+func (p *VirtiofsPredicate) PreExecute(ctx context.Context, u *core.Unit, cs []core.Candidater) (bool, error) {
+	data := u.SchedData()
+	if data.ServerConfigs == nil || len(data.Virtiofs) == 0 {
+		return false, nil
+	}
+	if data.Hypervisor != computeapi.HYPERVISOR_KVM {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (p *VirtiofsPredicate) Execute(ctx context.Context, u *core.Unit, c core.Candidater) (bool, []core.PredicateFailureReason, error) {
+	h := predicates.NewPredicateHelper(p, u, c)
+	d := u.SchedData()
+
+	sources := sets.NewString()
+	if host := c.Getter().Host(); host != nil && host.SysInfo != nil {
+		srcs := make([]string, 0)
+		host.SysInfo.Unmarshal(&srcs, "virtiofs_sources")
+		sources.Insert(srcs...)
+	}
+	for _, conf := range d.Virtiofs {
+		// host agent reports the virtiofs source types it can serve
+		if !sources.Has(conf.SourceType) {
+			h.Exclude(fmt.Sprintf("host can't serve virtiofs %s from %s", conf.Tag, conf.SourceType))
+			break
+		}
+		if len(conf.ZoneId) > 0 && c.Getter().Zone() != nil && c.Getter().Zone().Id != conf.ZoneId {
+			h.Exclude(fmt.Sprintf("virtiofs %s filesystem not in zone %s", conf.Tag, c.Getter().Zone().Name))
+			break
+		}
+	}
+
+	return h.GetResult()
+}
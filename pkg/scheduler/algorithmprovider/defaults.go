@@ -39,6 +39,8 @@ func defaultPredicates() sets.String {
 		//factory.RegisterFitPredicate("f-GuestGroupFilter", &predicateguest.GroupPredicate{}),
 		factory.RegisterFitPredicate("g-GuestCPUFilter", &predicateguest.CPUPredicate{}),
 		factory.RegisterFitPredicate("h-GuestMemoryFilter", &predicateguest.MemoryPredicate{}),
+		factory.RegisterFitPredicate("h-GuestMemoryBackingFilter", &predicateguest.MemoryBackingPredicate{}),
+		factory.RegisterFitPredicate("h-GuestVirtiofsFilter", &predicateguest.VirtiofsPredicate{}),
 		factory.RegisterFitPredicate("i-GuestStorageFilter", &predicateguest.StoragePredicate{}),
 		factory.RegisterFitPredicate("j-GuestNetworkFilter", predicates.NewNetworkPredicateWithNicCounter()),
 		factory.RegisterFitPredicate("k-GuestIsolatedDeviceFilter", &predicates.IsolatedDevicePredicate{}),
@@ -53,6 +53,18 @@ func (h *baremetalGetter) GetFreeCpuNuma() []*scheduler.SFreeNumaCpuMem {
 	return nil
 }
 
+func (h *baremetalGetter) HugepageSizeKB() int {
+	return 0
+}
+
+func (h *baremetalGetter) ReservedHugepageSizesKB() []int {
+	return nil
+}
+
+func (h *baremetalGetter) GetFreeHugepages(pageSizeKB int) *scheduler.SFreeHugepages {
+	return nil
+}
+
 func (h baremetalGetter) IsEmpty() bool {
 	return h.bm.ServerID == ""
 }
@@ -27,6 +27,7 @@ import (
 	"yunion.io/x/sqlchemy"
 
 	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/apis/scheduler"
 	"yunion.io/x/onecloud/pkg/cloudcommon/types"
 	computemodels "yunion.io/x/onecloud/pkg/compute/models"
 	"yunion.io/x/onecloud/pkg/scheduler/api"
@@ -37,6 +38,7 @@ import (
 	"yunion.io/x/onecloud/pkg/scheduler/data_manager/sku"
 	"yunion.io/x/onecloud/pkg/scheduler/data_manager/zone"
 	schedmodels "yunion.io/x/onecloud/pkg/scheduler/models"
+	o "yunion.io/x/onecloud/pkg/scheduler/options"
 )
 
 type BaseHostDesc struct {
@@ -803,6 +805,12 @@ func (b *BaseHostDesc) fillStorages(host *computemodels.SHost) error {
 		driver := b.GetHypervisorDriver()
 		if driver == nil || driver.DoScheduleStorageFilter() {
 			cs.FreeCapacity = storage.GetFreeCapacity()
+			if IsStorageScheduledByActualUsage(&storage) {
+				// disks not reported by the host yet count on their allocated size
+				actualUsed := storage.ActualCapacityUsed + storage.GetActualUnreportedCapacity()
+				cs.ActualFreeCapacity = storageActualFreeCapacity(storage.GetCapacity(), actualUsed, o.Options.StorageActualUsageHeadroomPercent)
+				cs.FreeCapacity = cs.ActualFreeCapacity
+			}
 		}
 		ss = append(ss, cs)
 	}
@@ -810,6 +818,18 @@ func (b *BaseHostDesc) fillStorages(host *computemodels.SHost) error {
 	return nil
 }
 
+// IsStorageScheduledByActualUsage tells whether free capacity of storage is
+// counted on the space actually used instead of the allocated disk size
+func IsStorageScheduledByActualUsage(s *computemodels.SStorage) bool {
+	return o.Options.StorageScheduleMode == scheduler.STORAGE_SCHEDULE_MODE_ACTUAL && utils.IsInStringArray(s.StorageType, computeapi.THIN_PROVISIONED_STORAGE)
+}
+
+// storageActualFreeCapacity returns the free capacity on actual usage, with
+// headroomPercent of capacity kept aside for the growth of thin disks
+func storageActualFreeCapacity(capacity, actualUsed int64, headroomPercent float64) int64 {
+	return capacity - actualUsed - int64(float64(capacity)*headroomPercent/100)
+}
+
 func (b *BaseHostDesc) fillInstanceGroups(host *computemodels.SHost) error {
 	candidateSet := make(map[string]*api.CandidateGroup)
 	groups, groupSet, err := host.InstanceGroups()
@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"sort"
+	"strconv"
 	gosync "sync"
 	"time"
 
@@ -80,6 +81,18 @@ func (h *hostGetter) GetFreeCpuNuma() []*scheduler.SFreeNumaCpuMem {
 	return h.h.GetFreeCpuNuma()
 }
 
+func (h *hostGetter) HugepageSizeKB() int {
+	return h.h.HugepageSizeKB
+}
+
+func (h *hostGetter) ReservedHugepageSizesKB() []int {
+	return h.h.ReservedHugepageSizesKB
+}
+
+func (h *hostGetter) GetFreeHugepages(pageSizeKB int) *scheduler.SFreeHugepages {
+	return h.h.GetFreeHugepages(pageSizeKB)
+}
+
 func (h *hostGetter) RunningMemorySize() int64 {
 	return h.h.RunningMemSize
 }
@@ -134,6 +147,13 @@ type HostDesc struct {
 	EnableCpuNumaAllocate bool       `json:"enable_cpu_numa_allocate"`
 	HostTopo              *SHostTopo `json:"host_topo"`
 
+	// native hugepage size, 0 means hugepages disabled
+	HugepageSizeKB int `json:"hugepage_size_kb"`
+	// page sizes of the hugepages reserved on host, which can back guest memory without native hugepages
+	ReservedHugepageSizesKB []int `json:"reserved_hugepage_sizes_kb"`
+	// pageSizeKB: free memory of the reserved hugepages after running guests
+	ReservedHugepages map[int]*scheduler.SFreeHugepages `json:"reserved_hugepages"`
+
 	// storage
 	StorageTypes []string `json:"storage_types"`
 
@@ -930,6 +950,7 @@ func (h *HostDesc) GetFreeStorageSizeOfType(sType string, mediumType string, use
 func (h *HostDesc) freeStorageSizeOfType(storageType string, mediumType string, useRsvd bool, reqMaxSize int64) (int64, int64, error) {
 	var total int64
 	var actualTotal int64
+	isActualScheduled := false
 	foundLEReqStore := false
 	errs := make([]error, 0)
 
@@ -937,6 +958,9 @@ func (h *HostDesc) freeStorageSizeOfType(storageType string, mediumType string,
 		if IsStorageBackendMediumMatch(storage, storageType, mediumType) {
 			total += int64(storage.FreeCapacity)
 			actualTotal += int64(storage.ActualFreeCapacity)
+			if IsStorageScheduledByActualUsage(storage.SStorage) {
+				isActualScheduled = true
+			}
 			if err := checkStorageSize(storage, reqMaxSize, useRsvd); err != nil {
 				errs = append(errs, err)
 			} else {
@@ -955,11 +979,17 @@ func (h *HostDesc) freeStorageSizeOfType(storageType string, mediumType string,
 		return 0, 0, errors.NewAggregate(errs)
 	}
 
+	pendingDiskUsage := int64(h.GetPendingUsage().DiskUsage.Get(storageType))
+	if isActualScheduled {
+		// disks scheduled but not created yet are not in the actual usage either
+		actualTotal -= pendingDiskUsage
+	}
+
 	if useRsvd {
 		return reservedResourceAddCal(total, h.GuestReservedStorageSizeFree(), useRsvd), actualTotal, nil
 	}
 
-	return total - int64(h.GetPendingUsage().DiskUsage.Get(storageType)), actualTotal, nil
+	return total - pendingDiskUsage, actualTotal, nil
 }
 
 func (h *HostDesc) GetFreePort(netId string) int {
@@ -998,6 +1028,30 @@ func (h *HostDesc) GetFreeMemSize(useRsvd bool) int64 {
 	return reservedResourceAddCal(h.FreeMemSize, h.GuestReservedMemSizeFree(), useRsvd) - int64(h.GetPendingUsage().Memory)
 }
 
+// GetFreeHugepages returns the free memory of the reserved hugepages of the
+// page size on each numa node, nil if none is reserved
+func (h *HostDesc) GetFreeHugepages(pageSizeKB int) *scheduler.SFreeHugepages {
+	reserved, ok := h.ReservedHugepages[pageSizeKB]
+	if !ok {
+		return nil
+	}
+	res := &scheduler.SFreeHugepages{
+		NodeFreeMB: make(map[int]int, len(reserved.NodeFreeMB)),
+		UnplacedMB: reserved.UnplacedMB,
+	}
+	for nodeId, free := range reserved.NodeFreeMB {
+		res.NodeFreeMB[nodeId] = free
+	}
+	for nodeId, mem := range h.GetPendingUsage().HugepageMem[pageSizeKB] {
+		if nodeId == schedmodels.UnplacedNumaNode {
+			res.UnplacedMB += mem
+		} else {
+			res.NodeFreeMB[nodeId] -= mem
+		}
+	}
+	return res
+}
+
 func (h *HostDesc) GetFreeCpuNuma() scheduler.SortedFreeNumaCpuMam {
 	if !h.EnableCpuNumaAllocate {
 		return nil
@@ -1427,6 +1481,7 @@ func (b *HostBuilder) BuildOne(host *computemodels.SHost, getter *networkGetter,
 		//b.fillResidentGroups,
 		b.fillMetadata,
 		b.fillCPUIOLoads,
+		b.fillHugepageInfo,
 		b.fillGuestsCpuNumaPin,
 		b.fillGuestsResourceInfo,
 	}
@@ -1441,6 +1496,108 @@ func (b *HostBuilder) BuildOne(host *computemodels.SHost, getter *networkGetter,
 	return desc, nil
 }
 
+func (b *HostBuilder) fillHugepageInfo(desc *HostDesc, host *computemodels.SHost) error {
+	if host.SysInfo == nil {
+		return nil
+	}
+	if sizes, _ := host.SysInfo.GetArray("hugepage_sizes_kb"); len(sizes) > 0 {
+		desc.ReservedHugepageSizesKB = make([]int, 0, len(sizes))
+		for i := range sizes {
+			size, _ := sizes[i].Int()
+			desc.ReservedHugepageSizesKB = append(desc.ReservedHugepageSizesKB, int(size))
+		}
+	}
+	if option, _ := host.SysInfo.GetString("hugepages_option"); option == "native" {
+		hugepageSizeKb, _ := host.SysInfo.Int("hugepage_size_kb")
+		desc.HugepageSizeKB = int(hugepageSizeKb)
+	}
+	return b.fillReservedHugepages(desc, host)
+}
+
+// fillReservedHugepages accounts the hugepages reserved on each numa node
+// of the page sizes other than the native one, the running guests backed
+// by them take the memory of the nodes they are pinned to
+func (b *HostBuilder) fillReservedHugepages(desc *HostDesc, host *computemodels.SHost) error {
+	if !host.SysInfo.Contains("node_reserved_hugepages") {
+		return nil
+	}
+	nodeHugepages := make([]hostapi.HostNodeReservedHugepages, 0)
+	err := host.SysInfo.Unmarshal(&nodeHugepages, "node_reserved_hugepages")
+	if err != nil {
+		return errors.Wrap(err, "unmarshal node reserved hugepages")
+	}
+	desc.ReservedHugepages = make(map[int]*scheduler.SFreeHugepages)
+	for _, hp := range nodeHugepages {
+		if hp.SizeKb == desc.HugepageSizeKB {
+			// native hugepages are accounted by numa nodes of host topology
+			continue
+		}
+		reserved, ok := desc.ReservedHugepages[hp.SizeKb]
+		if !ok {
+			reserved = &scheduler.SFreeHugepages{NodeFreeMB: make(map[int]int)}
+			desc.ReservedHugepages[hp.SizeKb] = reserved
+		}
+		// free pages are only a snapshot when host starts, guests running
+		// since are subtracted from the total below
+		reserved.NodeFreeMB[hp.NodeId] += int(int64(hp.Total) * int64(hp.SizeKb) / 1024)
+	}
+	if len(desc.ReservedHugepages) == 0 {
+		return nil
+	}
+
+	guestsOnHost := append([]interface{}{}, b.hostGuests[host.Id]...)
+	guestsOnHost = append(guestsOnHost, b.hostBackupGuests[host.Id]...)
+	pendingUsage := desc.GetPendingUsage()
+	guests := make(map[string]computemodels.SGuest)
+	for _, gst := range guestsOnHost {
+		guest := gst.(computemodels.SGuest)
+		if IsGuestPendingDelete(guest) || (!IsGuestCreating(guest) && IsGuestStoppedStatus(guest)) {
+			continue
+		}
+		if _, ok := pendingUsage.PendingGuestIds[guest.Id]; ok {
+			continue
+		}
+		guests[guest.Id] = guest
+	}
+	if len(guests) == 0 {
+		return nil
+	}
+	guestIds := make([]string, 0, len(guests))
+	for id := range guests {
+		guestIds = append(guestIds, id)
+	}
+	backings := make([]computedb.SMetadata, 0)
+	err = computedb.Metadata.Query().Equals("obj_type", computemodels.GuestManager.Keyword()).
+		In("obj_id", guestIds).Equals("key", computeapi.VM_METADATA_MEMORY_BACKING).All(&backings)
+	if err != nil {
+		return errors.Wrap(err, "fetch guests memory backing")
+	}
+	for _, backing := range backings {
+		reserved, ok := desc.ReservedHugepages[computeapi.GuestMemoryBackingPageSizeKB(backing.Value)]
+		if !ok {
+			continue
+		}
+		guest := guests[backing.ObjId]
+		placed := false
+		if guest.CpuNumaPin != nil {
+			cpuNumaPin := make([]scheduler.SCpuNumaPin, 0)
+			if err := guest.CpuNumaPin.Unmarshal(&cpuNumaPin); err != nil {
+				return errors.Wrap(err, "unmarshal cpu numa pin")
+			}
+			for i := range cpuNumaPin {
+				if cpuNumaPin[i].MemSizeMB != nil {
+					reserved.NodeFreeMB[cpuNumaPin[i].NodeId] -= *cpuNumaPin[i].MemSizeMB
+					placed = true
+				}
+			}
+		}
+		if !placed {
+			reserved.UnplacedMB += guest.VmemSize
+		}
+	}
+	return nil
+}
+
 func (b *HostBuilder) fillGuestsCpuNumaPin(desc *HostDesc, host *computemodels.SHost) error {
 	if !host.EnableNumaAllocate {
 		return nil
@@ -1655,6 +1812,9 @@ func (b *HostBuilder) fillGuestsResourceInfo(desc *HostDesc, host *computemodels
 		}
 	}
 
+	// memory reclaimed by guest balloon can be overcommitted
+	memFreeSize += desc.getBalloonOvercommitMemSize()
+
 	// free memory size calculate
 	rsvdUseMem := desc.GuestReservedResourceUsed.MemorySize
 	memFreeSize = memFreeSize + rsvdUseMem - desc.GetReservedMemSize()
@@ -1731,6 +1891,14 @@ func (b *HostBuilder) fillResidentGroups(desc *HostDesc, host *computemodels.SHo
 	return nil
 }*/
 
+func (h *HostDesc) getBalloonOvercommitMemSize() int64 {
+	if o.Options.BalloonMemoryOvercommitRatio <= 0 {
+		return 0
+	}
+	reclaimed, _ := strconv.ParseInt(h.Metadata[computeapi.HOST_METADATA_BALLOON_RECLAIMED_MEM_MB], 10, 64)
+	return int64(float64(reclaimed) * o.Options.BalloonMemoryOvercommitRatio)
+}
+
 func (b *HostBuilder) fillMetadata(desc *HostDesc, host *computemodels.SHost) error {
 	metadata, err := host.GetAllMetadata(nil, nil)
 	if err != nil {
@@ -94,6 +94,8 @@ func (item *SchedResultItem) selectCpuNumaPin() []schedapi.SCpuNumaPin {
 	var res []schedapi.SCpuNumaPin
 	if item.SchedData.LiveMigrate && len(item.SchedData.CpuNumaPin) > 0 {
 		res = item.Candidater.AllocCpuNumaPinWithNodeCount(vcpuCount, item.SchedData.Memory*1024, len(item.SchedData.CpuNumaPin))
+	} else if item.SchedData.NumaNodes > 0 {
+		res = item.Candidater.AllocCpuNumaPinWithNodeCount(vcpuCount, item.SchedData.Memory*1024, item.SchedData.NumaNodes)
 	} else {
 		res = item.Candidater.AllocCpuNumaPin(vcpuCount, item.SchedData.Memory*1024, item.SchedData.PreferNumaNodes)
 	}
@@ -99,6 +99,12 @@ type CandidatePropertyGetter interface {
 	FreeMemorySize(useRsvd bool) int64
 	GetFreeCpuNuma() []*schedapi.SFreeNumaCpuMem
 	NumaAllocateEnabled() bool
+	// HugepageSizeKB return host native hugepage size, 0 means hugepages disabled
+	HugepageSizeKB() int
+	// ReservedHugepageSizesKB return the page sizes of hugepages reserved on host
+	ReservedHugepageSizesKB() []int
+	// GetFreeHugepages return the free reserved hugepages of the page size on each numa node
+	GetFreeHugepages(pageSizeKB int) *schedapi.SFreeHugepages
 
 	StorageInfo() []*baremetal.BaremetalStorage
 	GetFreeStorageSizeOfType(storageType string, mediumType string, useRsvd bool, reqMaxSize int64) (int64, int64, error)
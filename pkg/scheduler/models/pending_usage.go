@@ -24,6 +24,7 @@ import (
 	"yunion.io/x/log"
 	"yunion.io/x/pkg/errors"
 
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
 	schedapi "yunion.io/x/onecloud/pkg/apis/scheduler"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/lockman"
 	"yunion.io/x/onecloud/pkg/cloudcommon/db/quotas"
@@ -315,7 +316,10 @@ type SPendingUsage struct {
 	PendingGuestIds map[string]struct{}
 
 	// nodeId: memSizeMB
-	NumaMemPin     map[int]int
+	NumaMemPin map[int]int
+	// pageSizeKB: nodeId: memSizeMB of guests backed by the hugepages of
+	// the page size, memory not pinned to a numa node is on UnplacedNumaNode
+	HugepageMem    map[int]map[int]int
 	IsolatedDevice int
 	DiskUsage      *SResourcePendingUsage
 	NetUsage       *SResourcePendingUsage
@@ -323,6 +327,9 @@ type SPendingUsage struct {
 	InstanceGroupUsage map[string]*api.CandidateGroup
 }
 
+// UnplacedNumaNode keys the pending hugepage memory not pinned to numa nodes
+const UnplacedNumaNode = -1
+
 func NewPendingUsageBySchedInfo(hostId string, req *api.SchedInfo, candidate *schedapi.CandidateResource) *SPendingUsage {
 	u := &SPendingUsage{
 		HostId:          hostId,
@@ -335,6 +342,7 @@ func NewPendingUsageBySchedInfo(hostId string, req *api.SchedInfo, candidate *sc
 	u.InstanceGroupUsage = make(map[string]*api.CandidateGroup)
 	u.CpuPin = make(map[int]int)
 	u.NumaMemPin = make(map[int]int)
+	u.HugepageMem = make(map[int]map[int]int)
 
 	if req == nil {
 		return u
@@ -363,6 +371,23 @@ func NewPendingUsageBySchedInfo(hostId string, req *api.SchedInfo, candidate *sc
 		}
 	}
 
+	if req.ServerConfigs != nil {
+		if pageSizeKB := computeapi.GuestMemoryBackingPageSizeKB(req.MemoryBacking); pageSizeKB > 0 {
+			nodeMem := make(map[int]int)
+			if candidate != nil {
+				for _, cpuNumaPin := range candidate.CpuNumaPin {
+					if cpuNumaPin.MemSizeMB != nil {
+						nodeMem[cpuNumaPin.NodeId] += *cpuNumaPin.MemSizeMB
+					}
+				}
+			}
+			if len(nodeMem) == 0 {
+				nodeMem[UnplacedNumaNode] = req.Memory
+			}
+			u.HugepageMem[pageSizeKB] = nodeMem
+		}
+	}
+
 	for _, disk := range req.Disks {
 		backend := disk.Backend
 		size := disk.SizeMb
@@ -448,6 +473,17 @@ func (self *SPendingUsage) Add(sUsage *SPendingUsage, addGuestId string) {
 			self.NumaMemPin[k] = v1
 		}
 	}
+	for pageSizeKB, nodeMem := range sUsage.HugepageMem {
+		if self.HugepageMem == nil {
+			self.HugepageMem = make(map[int]map[int]int)
+		}
+		if _, ok := self.HugepageMem[pageSizeKB]; !ok {
+			self.HugepageMem[pageSizeKB] = make(map[int]int)
+		}
+		for nodeId, mem := range nodeMem {
+			self.HugepageMem[pageSizeKB][nodeId] += mem
+		}
+	}
 	self.IsolatedDevice = self.IsolatedDevice + sUsage.IsolatedDevice
 	self.DiskUsage.Add(sUsage.DiskUsage)
 	self.NetUsage.Add(sUsage.NetUsage)
@@ -480,6 +516,16 @@ func (self *SPendingUsage) Sub(sUsage *SPendingUsage) {
 		}
 	}
 
+	for pageSizeKB, nodeMem := range sUsage.HugepageMem {
+		if selfNodeMem, ok := self.HugepageMem[pageSizeKB]; ok {
+			for nodeId, mem := range nodeMem {
+				if v, ok := selfNodeMem[nodeId]; ok {
+					selfNodeMem[nodeId] = quotas.NonNegative(v - mem)
+				}
+			}
+		}
+	}
+
 	self.IsolatedDevice = quotas.NonNegative(self.IsolatedDevice - sUsage.IsolatedDevice)
 	self.DiskUsage.Sub(sUsage.DiskUsage)
 	self.NetUsage.Sub(sUsage.NetUsage)
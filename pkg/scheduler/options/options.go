@@ -35,6 +35,11 @@ type SchedOptions struct {
 	SchedulerPort           int  `help:"The port that the scheduler's http service runs on" default:"8897"`
 	IgnoreFakeDeletedGuests bool `help:"Ignore fake deleted guests when build host memory and cpu size" default:"false"`
 
+	BalloonMemoryOvercommitRatio float64 `help:"Ratio of memory reclaimed by guest balloon counted as host free memory, 0 means disable" default:"0"`
+
+	StorageScheduleMode               string  `help:"Count free capacity of thin provisioned storages on allocated disk size or on the space actually used" default:"allocated" choices:"allocated|actual"`
+	StorageActualUsageHeadroomPercent float64 `help:"Percent of capacity kept free as safety headroom when storages are scheduled on actual usage" default:"15"`
+
 	AlwaysCheckAllPredicates    bool   `help:"Excute all predicates when scheduling" default:"false"`
 	DisableBaremetalPredicates  bool   `help:"Switch to trigger baremetal related predicates" default:"false"`
 	SchedulerTestLimit          int    `help:"Scheduler test items' limitations" default:"100"`
@@ -163,6 +163,42 @@ func (mr *MockCandidatePropertyGetterMockRecorder) NumaAllocateEnabled() *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NumaAllocateEnabled", reflect.TypeOf((*MockCandidatePropertyGetter)(nil).NumaAllocateEnabled))
 }
 
+func (m *MockCandidatePropertyGetter) HugepageSizeKB() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HugepageSizeKB")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+func (mr *MockCandidatePropertyGetterMockRecorder) HugepageSizeKB() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HugepageSizeKB", reflect.TypeOf((*MockCandidatePropertyGetter)(nil).HugepageSizeKB))
+}
+
+func (m *MockCandidatePropertyGetter) ReservedHugepageSizesKB() []int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReservedHugepageSizesKB")
+	ret0, _ := ret[0].([]int)
+	return ret0
+}
+
+func (mr *MockCandidatePropertyGetterMockRecorder) ReservedHugepageSizesKB() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReservedHugepageSizesKB", reflect.TypeOf((*MockCandidatePropertyGetter)(nil).ReservedHugepageSizesKB))
+}
+
+func (m *MockCandidatePropertyGetter) GetFreeHugepages(pageSizeKB int) *scheduler.SFreeHugepages {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFreeHugepages", pageSizeKB)
+	ret0, _ := ret[0].(*scheduler.SFreeHugepages)
+	return ret0
+}
+
+func (mr *MockCandidatePropertyGetterMockRecorder) GetFreeHugepages(pageSizeKB interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFreeHugepages", reflect.TypeOf((*MockCandidatePropertyGetter)(nil).GetFreeHugepages), pageSizeKB)
+}
+
 func (m *MockCandidatePropertyGetter) GetFreeCpuNuma() []*scheduler.SFreeNumaCpuMem {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetFreeCpuNuma")
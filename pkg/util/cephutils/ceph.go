@@ -194,6 +194,52 @@ func (cli *CephClient) GetCapacity() (*SCapacity, error) {
 	return result, nil
 }
 
+type SImageUsage struct {
+	ProvisionedSizeByte int64
+	UsedSizeByte        int64
+}
+
+type imageDu struct {
+	Images []struct {
+		Name            string `json:"name"`
+		Snapshot        string `json:"snapshot"`
+		ProvisionedSize int64  `json:"provisioned_size"`
+		UsedSize        int64  `json:"used_size"`
+	} `json:"images"`
+}
+
+/*
+ * {"images":[{"name":"disk","snapshot":"snap","id":"5e6b8b4567","snapshot_id":4,"provisioned_size":10737418240,"used_size":1203765248},{"name":"disk","id":"5e6b8b4567","provisioned_size":10737418240,"used_size":41943040}],"total_provisioned_size":10737418240,"total_used_size":1245708288}
+ */
+// DiskUsage returns the space provisioned and actually used by each image of
+// the pool, usage of snapshots is accounted to their image
+func (cli *CephClient) DiskUsage() (map[string]*SImageUsage, error) {
+	opts := cli.options()
+	opts = append(opts, []string{"du", "--pool", cli.pool}...)
+	resp, err := cli.output("rbd", opts, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "output")
+	}
+	du := imageDu{}
+	err = resp.Unmarshal(&du)
+	if err != nil {
+		return nil, errors.Wrapf(err, "ret.Unmarshal %s", resp)
+	}
+	result := map[string]*SImageUsage{}
+	for _, image := range du.Images {
+		usage, ok := result[image.Name]
+		if !ok {
+			usage = &SImageUsage{}
+			result[image.Name] = usage
+		}
+		if len(image.Snapshot) == 0 {
+			usage.ProvisionedSizeByte = image.ProvisionedSize
+		}
+		usage.UsedSizeByte += image.UsedSize
+	}
+	return result, nil
+}
+
 func writeFile(pattern string, content string) (string, error) {
 	file, err := ioutil.TempFile(cephConfTmpDir, pattern)
 	if err != nil {
@@ -0,0 +1,188 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dnssec contains helpers to generate DNSSEC keys and sign
+// responses online, shared by the region service and region-dns
+package dnssec // import "yunion.io/x/onecloud/pkg/util/dnssec"
+
+import (
+	"crypto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	FlagZSK = 256
+	FlagKSK = 257
+
+	DnsKeyTTL = 3600
+
+	// signatures are valid from one hour before to one week after signing
+	signatureInceptionOffset = time.Hour
+	signatureValidity        = 7 * 24 * time.Hour
+)
+
+// AlgorithmBits returns the key size to generate for a signing algorithm
+func AlgorithmBits(algorithm uint8) (int, error) {
+	switch algorithm {
+	case dns.RSASHA256:
+		return 2048, nil
+	case dns.ECDSAP256SHA256, dns.ED25519:
+		return 256, nil
+	}
+	return 0, errors.Wrapf(errors.ErrNotSupported, "algorithm %d", algorithm)
+}
+
+// GenerateKey generates a key pair for zone, returning the DNSKEY record and
+// the private key in BIND private key format
+func GenerateKey(zone string, flags uint16, algorithm string) (*dns.DNSKEY, string, error) {
+	alg, ok := dns.StringToAlgorithm[algorithm]
+	if !ok {
+		return nil, "", errors.Wrapf(errors.ErrNotSupported, "algorithm %s", algorithm)
+	}
+	bits, err := AlgorithmBits(alg)
+	if err != nil {
+		return nil, "", err
+	}
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: dns.Fqdn(strings.ToLower(zone)), Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: DnsKeyTTL},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: alg,
+	}
+	priv, err := key.Generate(bits)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "Generate")
+	}
+	return key, key.PrivateKeyString(priv), nil
+}
+
+// ParseKey parses a DNSKEY record and its private key
+func ParseKey(dnskey, private string) (*dns.DNSKEY, crypto.Signer, error) {
+	rr, err := dns.NewRR(dnskey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parse DNSKEY")
+	}
+	key, ok := rr.(*dns.DNSKEY)
+	if !ok {
+		return nil, nil, errors.Wrapf(errors.ErrInvalidFormat, "not a DNSKEY record: %s", dnskey)
+	}
+	if len(private) == 0 {
+		return key, nil, nil
+	}
+	priv, err := key.NewPrivateKey(private)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parse private key")
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.Wrap(errors.ErrNotSupported, "private key not a signer")
+	}
+	return key, signer, nil
+}
+
+// SKey is a DNSKEY with its private key, able to sign rrsets
+type SKey struct {
+	DnsKey *dns.DNSKEY
+	Signer crypto.Signer
+}
+
+// Sign signs one rrset
+func (k *SKey) Sign(rrset []dns.RR, now time.Time) (*dns.RRSIG, error) {
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Ttl: rrset[0].Header().Ttl},
+		KeyTag:     k.DnsKey.KeyTag(),
+		SignerName: k.DnsKey.Hdr.Name,
+		Algorithm:  k.DnsKey.Algorithm,
+		Inception:  uint32(now.Add(-signatureInceptionOffset).Unix()),
+		Expiration: uint32(now.Add(signatureValidity).Unix()),
+	}
+	err := sig.Sign(k.Signer, rrset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "sign %s %s", rrset[0].Header().Name, dns.TypeToString[rrset[0].Header().Rrtype])
+	}
+	return sig, nil
+}
+
+// SignSection appends signatures of every rrset found in rrs.  OPT, RRSIG
+// and, as they are signed by the key signing keys, DNSKEY records are not
+// signed
+func SignSection(rrs []dns.RR, keys []*SKey, now time.Time) ([]dns.RR, error) {
+	ret := make([]dns.RR, 0, len(rrs)*2)
+	for _, rrset := range SplitRRSets(rrs) {
+		ret = append(ret, rrset...)
+		switch rrset[0].Header().Rrtype {
+		case dns.TypeOPT, dns.TypeRRSIG, dns.TypeDNSKEY:
+			continue
+		}
+		for _, key := range keys {
+			sig, err := key.Sign(rrset, now)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, sig)
+		}
+	}
+	return ret, nil
+}
+
+// SplitRRSets groups records by owner name, class and type, keeping the
+// order of first appearance
+func SplitRRSets(rrs []dns.RR) [][]dns.RR {
+	idx := map[string]int{}
+	ret := [][]dns.RR{}
+	for _, rr := range rrs {
+		h := rr.Header()
+		k := strings.ToLower(h.Name) + "/" + dns.ClassToString[h.Class] + "/" + dns.TypeToString[h.Rrtype]
+		if i, ok := idx[k]; ok {
+			ret[i] = append(ret[i], rr)
+			continue
+		}
+		idx[k] = len(ret)
+		ret = append(ret, []dns.RR{rr})
+	}
+	return ret
+}
+
+// DenialNSEC builds the NSEC record proving that qname has none of the
+// types besides types.  Following the "black lies" variant of the minimally
+// covering NSEC of RFC 4470, the record is owned by qname itself and the next
+// name is its immediate successor, so that nonexistent names are answered as
+// NODATA and no other names of the zone are disclosed
+func DenialNSEC(qname string, ttl uint32, types []uint16) *dns.NSEC {
+	qname = dns.Fqdn(strings.ToLower(qname))
+	bitmap := []uint16{dns.TypeRRSIG, dns.TypeNSEC}
+	for _, t := range types {
+		if t != dns.TypeRRSIG && t != dns.TypeNSEC {
+			bitmap = append(bitmap, t)
+		}
+	}
+	sort.Slice(bitmap, func(i, j int) bool { return bitmap[i] < bitmap[j] })
+	dedup := bitmap[:0]
+	for i := range bitmap {
+		if i == 0 || bitmap[i] != bitmap[i-1] {
+			dedup = append(dedup, bitmap[i])
+		}
+	}
+	return &dns.NSEC{
+		Hdr:        dns.RR_Header{Name: qname, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: ttl},
+		NextDomain: "\\000." + qname,
+		TypeBitMap: dedup,
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnssec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	for _, alg := range []string{"RSASHA256", "ECDSAP256SHA256", "ED25519"} {
+		dnskey, priv, err := GenerateKey("example.com", FlagZSK, alg)
+		if err != nil {
+			t.Fatalf("%s GenerateKey: %v", alg, err)
+		}
+		key, signer, err := ParseKey(dnskey.String(), priv)
+		if err != nil {
+			t.Fatalf("%s ParseKey: %v", alg, err)
+		}
+		if key.KeyTag() != dnskey.KeyTag() {
+			t.Errorf("%s: key tag mismatch %d != %d", alg, key.KeyTag(), dnskey.KeyTag())
+		}
+
+		a1, _ := dns.NewRR("www.example.com. 300 IN A 10.0.0.1")
+		a2, _ := dns.NewRR("www.example.com. 300 IN A 10.0.0.2")
+		txt, _ := dns.NewRR("www.example.com. 300 IN TXT hello")
+		rrs, err := SignSection([]dns.RR{a1, txt, a2}, []*SKey{{DnsKey: key, Signer: signer}}, time.Now())
+		if err != nil {
+			t.Fatalf("%s SignSection: %v", alg, err)
+		}
+		if len(rrs) != 5 {
+			t.Fatalf("%s: want 5 records, got %d", alg, len(rrs))
+		}
+		sig, ok := rrs[2].(*dns.RRSIG)
+		if !ok {
+			t.Fatalf("%s: want RRSIG after A rrset, got %s", alg, rrs[2])
+		}
+		if err := sig.Verify(key, []dns.RR{a1, a2}); err != nil {
+			t.Errorf("%s: verify: %v", alg, err)
+		}
+		if !sig.ValidityPeriod(time.Now()) {
+			t.Errorf("%s: signature not valid now", alg)
+		}
+	}
+}
+
+func TestDenialNSEC(t *testing.T) {
+	nsec := DenialNSEC("WWW.example.com", 30, []uint16{dns.TypeA, dns.TypeNSEC, dns.TypeA})
+	if nsec.Hdr.Name != "www.example.com." {
+		t.Errorf("owner %s", nsec.Hdr.Name)
+	}
+	if nsec.NextDomain != "\\000.www.example.com." {
+		t.Errorf("next domain %s", nsec.NextDomain)
+	}
+	want := []uint16{dns.TypeA, dns.TypeRRSIG, dns.TypeNSEC}
+	if len(nsec.TypeBitMap) != len(want) {
+		t.Fatalf("want bitmap %v, got %v", want, nsec.TypeBitMap)
+	}
+	for i := range want {
+		if nsec.TypeBitMap[i] != want[i] {
+			t.Errorf("want bitmap %v, got %v", want, nsec.TypeBitMap)
+		}
+	}
+}
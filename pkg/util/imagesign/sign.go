@@ -0,0 +1,144 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagesign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	ALGORITHM_ECDSA   = "ecdsa"
+	ALGORITHM_ED25519 = "ed25519"
+
+	// POLICY_NONE skips signature verification, POLICY_WARN verifies and
+	// only logs failures, POLICY_ENFORCE rejects unsigned or invalid images
+	POLICY_NONE    = "none"
+	POLICY_WARN    = "warn"
+	POLICY_ENFORCE = "enforce"
+
+	ErrSignatureInvalid = errors.Error("signature invalid")
+)
+
+// SPublicKey is a trusted signer key parsed from a PEM encoded PKIX
+// public key, the same format used by cosign
+type SPublicKey struct {
+	Algorithm   string
+	Fingerprint string
+
+	key crypto.PublicKey
+}
+
+func ParsePublicKey(pemStr string) (*SPublicKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(pemStr)))
+	if block == nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "no pem block found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, errors.Wrapf(errors.ErrInvalidFormat, "unexpected pem type %s", block.Type)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "ParsePKIXPublicKey")
+	}
+	pub := &SPublicKey{key: key}
+	switch key.(type) {
+	case *ecdsa.PublicKey:
+		pub.Algorithm = ALGORITHM_ECDSA
+	case ed25519.PublicKey:
+		pub.Algorithm = ALGORITHM_ED25519
+	default:
+		return nil, errors.Wrapf(errors.ErrNotSupported, "public key type %T", key)
+	}
+	sum := sha256.Sum256(block.Bytes)
+	pub.Fingerprint = hex.EncodeToString(sum[:])
+	return pub, nil
+}
+
+// Verify checks a base64 encoded detached signature of digest.  The
+// signed payload is the hex digest string itself, ecdsa signatures are
+// ASN.1 encoded over its sha256 sum as cosign does
+func (k *SPublicKey) Verify(digest string, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return errors.Wrap(ErrSignatureInvalid, "decode base64")
+	}
+	payload := []byte(strings.ToLower(digest))
+	var ok bool
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(payload)
+		ok = ecdsa.VerifyASN1(key, sum[:], sig)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, payload, sig)
+	}
+	if !ok {
+		return errors.Wrapf(ErrSignatureInvalid, "digest %s with key %s", digest, k.Fingerprint)
+	}
+	return nil
+}
+
+// Sign produces a signature of digest that Verify accepts
+func Sign(priv crypto.Signer, digest string) (string, error) {
+	payload := []byte(strings.ToLower(digest))
+	var sig []byte
+	var err error
+	switch key := priv.(type) {
+	case *ecdsa.PrivateKey:
+		sum := sha256.Sum256(payload)
+		sig, err = ecdsa.SignASN1(rand.Reader, key, sum[:])
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(key, payload)
+	default:
+		return "", errors.Wrapf(errors.ErrNotSupported, "private key type %T", priv)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "sign")
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS8 or EC private key
+func ParsePrivateKey(pemStr string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(pemStr)))
+	if block == nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "no pem block found")
+	}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "ParsePKCS8PrivateKey")
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.Wrapf(errors.ErrNotSupported, "private key type %T", key)
+		}
+		return signer, nil
+	}
+	return nil, errors.Wrapf(errors.ErrInvalidFormat, "unexpected pem type %s", block.Type)
+}
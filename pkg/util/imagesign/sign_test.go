@@ -0,0 +1,115 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagesign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"yunion.io/x/pkg/errors"
+)
+
+func publicKeyPem(t *testing.T, pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func privateKeyPem(t *testing.T, priv crypto.Signer) string {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestSignVerify(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const digest = "0cc175b9c0f1b6a831c399e269772661"
+	for _, c := range []struct {
+		priv crypto.Signer
+		alg  string
+	}{
+		{ecKey, ALGORITHM_ECDSA},
+		{edKey, ALGORITHM_ED25519},
+	} {
+		priv, err := ParsePrivateKey(privateKeyPem(t, c.priv))
+		if err != nil {
+			t.Fatalf("%s: ParsePrivateKey %v", c.alg, err)
+		}
+		pub, err := ParsePublicKey(publicKeyPem(t, c.priv.Public()))
+		if err != nil {
+			t.Fatalf("%s: ParsePublicKey %v", c.alg, err)
+		}
+		if pub.Algorithm != c.alg || len(pub.Fingerprint) != 64 {
+			t.Errorf("%s: got algorithm %s fingerprint %s", c.alg, pub.Algorithm, pub.Fingerprint)
+		}
+		sig, err := Sign(priv, digest)
+		if err != nil {
+			t.Fatalf("%s: Sign %v", c.alg, err)
+		}
+		if err := pub.Verify(digest, sig); err != nil {
+			t.Errorf("%s: Verify %v", c.alg, err)
+		}
+		if err := pub.Verify("0CC175B9C0F1B6A831C399E269772661", sig); err != nil {
+			t.Errorf("%s: Verify upper case digest %v", c.alg, err)
+		}
+		if err := pub.Verify("92eb5ffee6ae2fec3ad71c777531578f", sig); errors.Cause(err) != ErrSignatureInvalid {
+			t.Errorf("%s: expect invalid signature of other digest, got %v", c.alg, err)
+		}
+		if err := pub.Verify(digest, "not base64!"); errors.Cause(err) != ErrSignatureInvalid {
+			t.Errorf("%s: expect invalid signature of garbage, got %v", c.alg, err)
+		}
+	}
+
+	// signature of another key must not verify
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	pub, err := ParsePublicKey(publicKeyPem(t, other))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, _ := Sign(edKey, digest)
+	if err := pub.Verify(digest, sig); err == nil {
+		t.Errorf("expect signature of another key to fail")
+	}
+}
+
+func TestParsePublicKeyInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"not a pem",
+		"-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+		"-----BEGIN PUBLIC KEY-----\nAAAA\n-----END PUBLIC KEY-----\n",
+	} {
+		if _, err := ParsePublicKey(s); err == nil {
+			t.Errorf("expect error parsing %q", s)
+		}
+	}
+}
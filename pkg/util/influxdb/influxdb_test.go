@@ -14,6 +14,42 @@
 
 package influxdb
 
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetDatabase(t *testing.T) {
+	created := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch q := r.URL.Query().Get("q"); q {
+		case "SHOW DATABASES":
+			fmt.Fprint(w, `{"results":[{"series":[{"name":"databases","columns":["name"],"values":[["telegraf"]]}]}]}`)
+		default:
+			created = q
+			fmt.Fprint(w, `{"results":[{}]}`)
+		}
+	}))
+	defer srv.Close()
+
+	for _, dbName := range []string{"telegraf", "flow_log"} {
+		db := NewInfluxdb(srv.URL)
+		if err := db.SetDatabase(dbName); err != nil {
+			t.Fatalf("SetDatabase %s: %s", dbName, err)
+		}
+		// database name is also set when the database is just created
+		if db.dbName != dbName {
+			t.Errorf("SetDatabase %s: got db name %q", dbName, db.dbName)
+		}
+	}
+	if created != "CREATE DATABASE flow_log" {
+		t.Errorf("unexpected create query %q", created)
+	}
+}
+
 // TODO: rewrite this test
 /*
 import (
@@ -102,6 +102,8 @@ const (
 	ACT_UNBIND_DISK                  = "unbind_disk"
 	ACT_ATTACH_HOST                  = "attach_host"
 	ACT_DETACH_HOST                  = "detach_host"
+	ACT_ATTACH_VIRTIOFS              = "attach_virtiofs"
+	ACT_DETACH_VIRTIOFS              = "detach_virtiofs"
 	ACT_VM_IO_THROTTLE               = "vm_io_throttle"
 	ACT_VM_RESET                     = "vm_reset"
 	ACT_VM_SNAPSHOT_AND_CLONE        = "vm_snapshot_and_clone"
@@ -315,4 +317,12 @@ const (
 
 	ACT_CLONE   = "clone"
 	ACT_REBUILD = "rebuild"
+
+	ACT_VERIFY_SIGNATURE      = "verify_signature"
+	ACT_IMAGE_SCAN            = "image_scan"
+	ACT_IMAGE_REPLICATE       = "image_replicate"
+	ACT_DISK_REPLICA_FAILOVER = "disk_replica_failover"
+	ACT_DR_FAILOVER           = "dr_failover"
+	ACT_DR_TEST_FAILOVER      = "dr_test_failover"
+	ACT_DR_FAILBACK           = "dr_failback"
 )
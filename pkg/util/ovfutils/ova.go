@@ -0,0 +1,261 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovfutils
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+)
+
+var manifestLineRegexp = regexp.MustCompile(`^(SHA1|SHA256)\((.+)\)\s*=\s*([0-9a-fA-F]+)$`)
+
+type manifestDigest struct {
+	alg    string
+	digest string
+}
+
+func parseManifest(r io.Reader) (map[string]manifestDigest, error) {
+	digests := map[string]manifestDigest{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		m := manifestLineRegexp.FindStringSubmatch(line)
+		if m == nil {
+			return nil, errors.Wrapf(errors.ErrInvalidFormat, "manifest line %q", line)
+		}
+		digests[m[2]] = manifestDigest{alg: m[1], digest: strings.ToLower(m[3])}
+	}
+	return digests, scanner.Err()
+}
+
+func newHash(alg string) hash.Hash {
+	if alg == "SHA1" {
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+// digestVerifier returns a function draining r and checking the digest of
+// everything read through it.  The result is remembered so that it can be
+// called more than once
+func digestVerifier(name string, r io.Reader, h hash.Hash, digest manifestDigest) func() error {
+	var (
+		verified bool
+		err      error
+	)
+	return func() error {
+		if verified {
+			return err
+		}
+		verified = true
+		if _, err = io.Copy(io.Discard, r); err != nil {
+			err = errors.Wrapf(err, "read %s", name)
+			return err
+		}
+		if sum := hex.EncodeToString(h.Sum(nil)); sum != digest.digest {
+			err = errors.Wrapf(errors.ErrInvalidFormat, "%s digest mismatch, expect %s got %s", name, digest.digest, sum)
+		}
+		return err
+	}
+}
+
+// ReadOva walks through an ova archive without buffering it.  The ovf
+// descriptor must be the first entry and the manifest, if any, must come
+// before disk files as required by the spec.  onDescriptor is called with
+// the parsed descriptor, then onDisk is called for each disk file in the
+// archive.  onDisk must call verify after consuming r and before accepting
+// the disk, verify checks the digest of the disk file against the manifest
+func ReadOva(
+	r io.Reader,
+	onDescriptor func(app *SVirtualAppliance) error,
+	onDisk func(disk *SDisk, r io.Reader, size int64, verify func() error) error,
+) error {
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		return errors.Wrap(err, "read ovf descriptor")
+	}
+	if !strings.EqualFold(path.Ext(hdr.Name), ".ovf") {
+		return errors.Wrapf(errors.ErrInvalidFormat, "first entry of ova is %s, not ovf descriptor", hdr.Name)
+	}
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return errors.Wrap(err, "read ovf descriptor")
+	}
+	app, err := ParseOvf(data)
+	if err != nil {
+		return errors.Wrapf(err, "parse %s", hdr.Name)
+	}
+	if err := onDescriptor(app); err != nil {
+		return err
+	}
+
+	pending := map[string]*SDisk{}
+	for i := range app.Disks {
+		pending[app.Disks[i].FileHref] = &app.Disks[i]
+	}
+	var digests map[string]manifestDigest
+	diskRead := false
+	for len(pending) > 0 {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "read ova")
+		}
+		name := path.Clean(hdr.Name)
+		if strings.EqualFold(path.Ext(name), ".mf") {
+			if diskRead {
+				return errors.Wrapf(errors.ErrInvalidFormat, "manifest %s after disk files", name)
+			}
+			digests, err = parseManifest(tr)
+			if err != nil {
+				return errors.Wrapf(err, "parse %s", name)
+			}
+			continue
+		}
+		disk, ok := pending[name]
+		if !ok {
+			continue
+		}
+		delete(pending, name)
+		diskRead = true
+		var reader io.Reader = tr
+		verify := func() error { return nil }
+		if digest, ok := digests[name]; ok {
+			h := newHash(digest.alg)
+			reader = io.TeeReader(tr, h)
+			verify = digestVerifier(name, reader, h, digest)
+		}
+		if err := onDisk(disk, reader, hdr.Size, verify); err != nil {
+			return err
+		}
+		// in case onDisk returns without verifying
+		if err := verify(); err != nil {
+			return err
+		}
+	}
+	if len(pending) > 0 {
+		missing := make([]string, 0, len(pending))
+		for href := range pending {
+			missing = append(missing, href)
+		}
+		sort.Strings(missing)
+		return errors.Wrapf(errors.ErrNotFound, "disk files %s in ova", strings.Join(missing, ","))
+	}
+	return nil
+}
+
+func fileSha256(fn string) (string, int64, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// WriteOva writes an ova archive of the appliance to w.  diskPaths are
+// local files of app.Disks in the same order, FileSize of disks is filled
+// in from the files
+func WriteOva(w io.Writer, app *SVirtualAppliance, diskPaths []string) error {
+	if len(diskPaths) != len(app.Disks) {
+		return errors.Wrapf(errors.ErrInvalidFormat, "%d disk files for %d disks", len(diskPaths), len(app.Disks))
+	}
+	manifest := &bytes.Buffer{}
+	ovfName := app.Name + ".ovf"
+	sums := make([]string, len(diskPaths))
+	for i, fn := range diskPaths {
+		sum, size, err := fileSha256(fn)
+		if err != nil {
+			return errors.Wrapf(err, "digest %s", fn)
+		}
+		sums[i] = sum
+		app.Disks[i].FileSize = size
+	}
+	ovf := []byte(GenerateOvf(app))
+	ovfSum := sha256.Sum256(ovf)
+	fmt.Fprintf(manifest, "SHA256(%s)= %s\n", ovfName, hex.EncodeToString(ovfSum[:]))
+	for i := range app.Disks {
+		fmt.Fprintf(manifest, "SHA256(%s)= %s\n", app.Disks[i].FileHref, sums[i])
+	}
+
+	now := time.Now()
+	tw := tar.NewWriter(w)
+	writeHeader := func(name string, size int64) error {
+		return tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    size,
+			ModTime: now,
+		})
+	}
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{ovfName, ovf},
+		{app.Name + ".mf", manifest.Bytes()},
+	} {
+		if err := writeHeader(entry.name, int64(len(entry.data))); err != nil {
+			return errors.Wrapf(err, "write header of %s", entry.name)
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return errors.Wrapf(err, "write %s", entry.name)
+		}
+	}
+	for i, fn := range diskPaths {
+		err := func() error {
+			f, err := os.Open(fn)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if err := writeHeader(app.Disks[i].FileHref, app.Disks[i].FileSize); err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, f)
+			return err
+		}()
+		if err != nil {
+			return errors.Wrapf(err, "write %s", fn)
+		}
+	}
+	return tw.Close()
+}
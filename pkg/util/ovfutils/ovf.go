@@ -0,0 +1,520 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovfutils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	FIRMWARE_BIOS = "bios"
+	FIRMWARE_EFI  = "efi"
+
+	OS_TYPE_LINUX   = "Linux"
+	OS_TYPE_WINDOWS = "Windows"
+
+	CONTROLLER_IDE  = "ide"
+	CONTROLLER_SCSI = "scsi"
+	CONTROLLER_SATA = "sata"
+
+	DISK_FORMAT_STREAM_OPTIMIZED = "http://www.vmware.com/interfaces/specifications/vmdk.html#streamOptimized"
+)
+
+// CIM_ResourceAllocationSettingData ResourceType
+const (
+	resourceTypeProcessor      = 3
+	resourceTypeMemory         = 4
+	resourceTypeIdeController  = 5
+	resourceTypeScsiController = 6
+	resourceTypeEthernet       = 10
+	resourceTypeDiskDrive      = 17
+	resourceTypeSataController = 20
+)
+
+// CIM_OperatingSystem OsType
+const (
+	cimOsOther = 1
+	cimOsLinux = 36
+)
+
+// SVirtualAppliance is the part of an ovf descriptor needed to recreate
+// the virtual machine
+type SVirtualAppliance struct {
+	Name     string
+	OsType   string
+	OsDesc   string
+	CpuCount int
+	MemoryMB int
+	Firmware string
+	Disks    []SDisk
+	Nics     []SNic
+}
+
+type SDisk struct {
+	Id            string
+	FileHref      string
+	FileSize      int64
+	CapacityBytes int64
+	// bus of the controller the disk attached to, ide, scsi or sata
+	Controller string
+	// controller model, e.g. lsilogic
+	ControllerModel string
+}
+
+type SNic struct {
+	Network string
+	// adapter model, e.g. E1000, VmxNet3
+	Model string
+}
+
+type ovfEnvelope struct {
+	XMLName    xml.Name           `xml:"Envelope"`
+	Files      []ovfFile          `xml:"References>File"`
+	Disks      []ovfDisk          `xml:"DiskSection>Disk"`
+	System     *ovfVirtualSystem  `xml:"VirtualSystem"`
+	Collection []ovfVirtualSystem `xml:"VirtualSystemCollection>VirtualSystem"`
+}
+
+type ovfFile struct {
+	Id          string `xml:"id,attr"`
+	Href        string `xml:"href,attr"`
+	Size        int64  `xml:"size,attr"`
+	Compression string `xml:"compression,attr"`
+	ChunkSize   int64  `xml:"chunkSize,attr"`
+}
+
+type ovfDisk struct {
+	DiskId                  string `xml:"diskId,attr"`
+	FileRef                 string `xml:"fileRef,attr"`
+	Capacity                string `xml:"capacity,attr"`
+	CapacityAllocationUnits string `xml:"capacityAllocationUnits,attr"`
+	Format                  string `xml:"format,attr"`
+}
+
+type ovfVirtualSystem struct {
+	Id       string      `xml:"id,attr"`
+	Name     string      `xml:"Name"`
+	Os       ovfOs       `xml:"OperatingSystemSection"`
+	Hardware ovfHardware `xml:"VirtualHardwareSection"`
+}
+
+type ovfOs struct {
+	Id          int    `xml:"id,attr"`
+	OsType      string `xml:"osType,attr"`
+	Description string `xml:"Description"`
+}
+
+type ovfHardware struct {
+	Items         []ovfItem   `xml:"Item"`
+	StorageItems  []ovfItem   `xml:"StorageItem"`
+	EthernetItems []ovfItem   `xml:"EthernetPortItem"`
+	Configs       []ovfConfig `xml:"Config"`
+	ExtraConfigs  []ovfConfig `xml:"ExtraConfig"`
+}
+
+type ovfItem struct {
+	InstanceID      string   `xml:"InstanceID"`
+	ElementName     string   `xml:"ElementName"`
+	ResourceType    int      `xml:"ResourceType"`
+	ResourceSubType string   `xml:"ResourceSubType"`
+	VirtualQuantity int64    `xml:"VirtualQuantity"`
+	AllocationUnits string   `xml:"AllocationUnits"`
+	HostResource    []string `xml:"HostResource"`
+	Parent          string   `xml:"Parent"`
+	Connection      []string `xml:"Connection"`
+	AddressOnParent string   `xml:"AddressOnParent"`
+}
+
+type ovfConfig struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+var (
+	bytePowerRegexp = regexp.MustCompile(`^byte\*(\d+)\^(\d+)$`)
+	byteMulRegexp   = regexp.MustCompile(`^byte\*(\d+)$`)
+)
+
+// parseAllocationUnits returns the number of bytes of the programmatic
+// units, e.g. "byte * 2^20", defaults to def when units is empty
+func parseAllocationUnits(units string, def int64) (int64, error) {
+	u := strings.ToLower(strings.Join(strings.Fields(units), ""))
+	switch u {
+	case "":
+		return def, nil
+	case "byte", "bytes":
+		return 1, nil
+	case "kilobytes", "kb":
+		return 1 << 10, nil
+	case "megabytes", "mb":
+		return 1 << 20, nil
+	case "gigabytes", "gb":
+		return 1 << 30, nil
+	case "terabytes", "tb":
+		return 1 << 40, nil
+	}
+	if m := bytePowerRegexp.FindStringSubmatch(u); m != nil {
+		base, _ := strconv.ParseInt(m[1], 10, 64)
+		exp, _ := strconv.Atoi(m[2])
+		r := int64(1)
+		for i := 0; i < exp; i++ {
+			r *= base
+			if r <= 0 || r > 1<<50 {
+				return 0, errors.Wrapf(errors.ErrInvalidFormat, "allocation units %q", units)
+			}
+		}
+		return r, nil
+	}
+	if m := byteMulRegexp.FindStringSubmatch(u); m != nil {
+		r, _ := strconv.ParseInt(m[1], 10, 64)
+		return r, nil
+	}
+	return 0, errors.Wrapf(errors.ErrNotSupported, "allocation units %q", units)
+}
+
+func parseOsType(os ovfOs) string {
+	s := strings.ToLower(os.OsType + " " + os.Description)
+	switch {
+	case strings.Contains(s, "win"):
+		return OS_TYPE_WINDOWS
+	case os.Id == cimOsLinux, strings.Contains(s, "linux"), strings.Contains(s, "centos"),
+		strings.Contains(s, "rhel"), strings.Contains(s, "ubuntu"), strings.Contains(s, "debian"):
+		return OS_TYPE_LINUX
+	}
+	return ""
+}
+
+func controllerBus(resourceType int) string {
+	switch resourceType {
+	case resourceTypeIdeController:
+		return CONTROLLER_IDE
+	case resourceTypeScsiController:
+		return CONTROLLER_SCSI
+	case resourceTypeSataController:
+		return CONTROLLER_SATA
+	}
+	return ""
+}
+
+// diskIdOfHostResource extracts the disk id from host resource of a disk
+// drive, e.g. "ovf:/disk/vmdisk1"
+func diskIdOfHostResource(res string) (string, bool) {
+	for _, prefix := range []string{"ovf:/disk/", "/disk/"} {
+		if strings.HasPrefix(res, prefix) {
+			return res[len(prefix):], true
+		}
+	}
+	return "", false
+}
+
+// ParseOvf parses an ovf descriptor.  Disks are returned in the order
+// the disk drives appear in the virtual hardware section, the first one
+// is taken as the system disk
+func ParseOvf(data []byte) (*SVirtualAppliance, error) {
+	env := ovfEnvelope{}
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil, errors.Wrap(err, "xml.Unmarshal")
+	}
+	sys := env.System
+	if sys == nil {
+		if len(env.Collection) != 1 {
+			return nil, errors.Wrapf(errors.ErrNotSupported, "ovf contains %d virtual systems", len(env.Collection))
+		}
+		sys = &env.Collection[0]
+	}
+
+	app := &SVirtualAppliance{
+		Name:     sys.Name,
+		OsType:   parseOsType(sys.Os),
+		OsDesc:   sys.Os.Description,
+		Firmware: FIRMWARE_BIOS,
+	}
+	if len(app.Name) == 0 {
+		app.Name = sys.Id
+	}
+	for _, conf := range append(sys.Hardware.Configs, sys.Hardware.ExtraConfigs...) {
+		if conf.Key == "firmware" && strings.EqualFold(conf.Value, FIRMWARE_EFI) {
+			app.Firmware = FIRMWARE_EFI
+		}
+	}
+
+	files := map[string]ovfFile{}
+	for _, f := range env.Files {
+		files[f.Id] = f
+	}
+	disks := map[string]ovfDisk{}
+	for _, d := range env.Disks {
+		disks[d.DiskId] = d
+	}
+
+	items := append(append(sys.Hardware.Items, sys.Hardware.StorageItems...), sys.Hardware.EthernetItems...)
+	itemById := map[string]ovfItem{}
+	for _, item := range items {
+		itemById[item.InstanceID] = item
+	}
+	for _, item := range items {
+		switch item.ResourceType {
+		case resourceTypeProcessor:
+			app.CpuCount = int(item.VirtualQuantity)
+		case resourceTypeMemory:
+			units, err := parseAllocationUnits(item.AllocationUnits, 1<<20)
+			if err != nil {
+				return nil, errors.Wrap(err, "memory")
+			}
+			app.MemoryMB = int(item.VirtualQuantity * units >> 20)
+		case resourceTypeEthernet:
+			nic := SNic{Model: item.ResourceSubType}
+			if len(item.Connection) > 0 {
+				nic.Network = item.Connection[0]
+			}
+			app.Nics = append(app.Nics, nic)
+		case resourceTypeDiskDrive:
+			if len(item.HostResource) == 0 {
+				continue
+			}
+			diskId, ok := diskIdOfHostResource(item.HostResource[0])
+			if !ok {
+				return nil, errors.Wrapf(errors.ErrNotSupported, "disk host resource %s", item.HostResource[0])
+			}
+			disk, err := parseDisk(disks, files, diskId)
+			if err != nil {
+				return nil, err
+			}
+			if ctrl, ok := itemById[item.Parent]; ok {
+				disk.Controller = controllerBus(ctrl.ResourceType)
+				disk.ControllerModel = ctrl.ResourceSubType
+			}
+			app.Disks = append(app.Disks, *disk)
+		}
+	}
+	if len(app.Disks) == 0 {
+		return nil, errors.Wrap(errors.ErrEmpty, "no disk found in ovf")
+	}
+	return app, nil
+}
+
+func parseDisk(disks map[string]ovfDisk, files map[string]ovfFile, diskId string) (*SDisk, error) {
+	d, ok := disks[diskId]
+	if !ok {
+		return nil, errors.Wrapf(errors.ErrNotFound, "disk %s", diskId)
+	}
+	f, ok := files[d.FileRef]
+	if !ok {
+		return nil, errors.Wrapf(errors.ErrNotFound, "file %s of disk %s", d.FileRef, diskId)
+	}
+	if len(f.Compression) > 0 || f.ChunkSize > 0 {
+		return nil, errors.Wrapf(errors.ErrNotSupported, "compressed or chunked file %s", f.Href)
+	}
+	capacity, err := strconv.ParseInt(d.Capacity, 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "capacity %q of disk %s", d.Capacity, diskId)
+	}
+	units, err := parseAllocationUnits(d.CapacityAllocationUnits, 1)
+	if err != nil {
+		return nil, errors.Wrapf(err, "disk %s", diskId)
+	}
+	return &SDisk{
+		Id:            diskId,
+		FileHref:      f.Href,
+		FileSize:      f.Size,
+		CapacityBytes: capacity * units,
+	}, nil
+}
+
+func xmlEscape(s string) string {
+	b := &bytes.Buffer{}
+	xml.EscapeText(b, []byte(s))
+	return b.String()
+}
+
+type ovfController struct {
+	instanceId   int
+	resourceType int
+	subType      string
+	name         string
+	nextAddress  int
+}
+
+// GenerateOvf renders an ovf 1.0 descriptor of the appliance.  Disk files
+// are expected to be streamOptimized vmdk
+func GenerateOvf(app *SVirtualAppliance) string {
+	b := &strings.Builder{}
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<Envelope vmw:buildId="build-0" xmlns="http://schemas.dmtf.org/ovf/envelope/1"` +
+		` xmlns:cim="http://schemas.dmtf.org/wbem/wscim/1/common"` +
+		` xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1"` +
+		` xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData"` +
+		` xmlns:vmw="http://www.vmware.com/schema/ovf"` +
+		` xmlns:vssd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_VirtualSystemSettingData"` +
+		` xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` + "\n")
+
+	b.WriteString("  <References>\n")
+	for i, disk := range app.Disks {
+		fmt.Fprintf(b, "    <File ovf:href=\"%s\" ovf:id=\"file%d\" ovf:size=\"%d\"/>\n", xmlEscape(disk.FileHref), i+1, disk.FileSize)
+	}
+	b.WriteString("  </References>\n")
+
+	b.WriteString("  <DiskSection>\n    <Info>Virtual disk information</Info>\n")
+	for i, disk := range app.Disks {
+		fmt.Fprintf(b, "    <Disk ovf:capacity=\"%d\" ovf:capacityAllocationUnits=\"byte\" ovf:diskId=\"vmdisk%d\" ovf:fileRef=\"file%d\" ovf:format=\"%s\"/>\n",
+			disk.CapacityBytes, i+1, i+1, DISK_FORMAT_STREAM_OPTIMIZED)
+	}
+	b.WriteString("  </DiskSection>\n")
+
+	networks := []string{}
+	for _, nic := range app.Nics {
+		if len(nic.Network) > 0 && !containsString(networks, nic.Network) {
+			networks = append(networks, nic.Network)
+		}
+	}
+	if len(networks) > 0 {
+		b.WriteString("  <NetworkSection>\n    <Info>The list of logical networks</Info>\n")
+		for _, net := range networks {
+			fmt.Fprintf(b, "    <Network ovf:name=\"%s\">\n      <Description>%s</Description>\n    </Network>\n", xmlEscape(net), xmlEscape(net))
+		}
+		b.WriteString("  </NetworkSection>\n")
+	}
+
+	fmt.Fprintf(b, "  <VirtualSystem ovf:id=\"%s\">\n    <Info>A virtual machine</Info>\n    <Name>%s</Name>\n", xmlEscape(app.Name), xmlEscape(app.Name))
+	osId := cimOsOther
+	if app.OsType == OS_TYPE_LINUX {
+		osId = cimOsLinux
+	}
+	osDesc := app.OsDesc
+	if len(osDesc) == 0 {
+		osDesc = app.OsType
+	}
+	fmt.Fprintf(b, "    <OperatingSystemSection ovf:id=\"%d\">\n      <Info>The kind of installed guest operating system</Info>\n      <Description>%s</Description>\n    </OperatingSystemSection>\n",
+		osId, xmlEscape(osDesc))
+
+	b.WriteString("    <VirtualHardwareSection>\n      <Info>Virtual hardware requirements</Info>\n")
+	b.WriteString("      <System>\n        <vssd:ElementName>Virtual Hardware Family</vssd:ElementName>\n        <vssd:InstanceID>0</vssd:InstanceID>\n")
+	fmt.Fprintf(b, "        <vssd:VirtualSystemIdentifier>%s</vssd:VirtualSystemIdentifier>\n", xmlEscape(app.Name))
+	b.WriteString("        <vssd:VirtualSystemType>vmx-10</vssd:VirtualSystemType>\n      </System>\n")
+
+	instanceId := 1
+	writeItem := func(fields [][2]string) {
+		b.WriteString("      <Item>\n")
+		for _, f := range fields {
+			fmt.Fprintf(b, "        <rasd:%s>%s</rasd:%s>\n", f[0], xmlEscape(f[1]), f[0])
+		}
+		b.WriteString("      </Item>\n")
+		instanceId++
+	}
+	cpuCount := app.CpuCount
+	if cpuCount <= 0 {
+		cpuCount = 1
+	}
+	writeItem([][2]string{
+		{"AllocationUnits", "hertz * 10^6"},
+		{"ElementName", fmt.Sprintf("%d virtual CPU(s)", cpuCount)},
+		{"InstanceID", strconv.Itoa(instanceId)},
+		{"ResourceType", strconv.Itoa(resourceTypeProcessor)},
+		{"VirtualQuantity", strconv.Itoa(cpuCount)},
+	})
+	writeItem([][2]string{
+		{"AllocationUnits", "byte * 2^20"},
+		{"ElementName", fmt.Sprintf("%dMB of memory", app.MemoryMB)},
+		{"InstanceID", strconv.Itoa(instanceId)},
+		{"ResourceType", strconv.Itoa(resourceTypeMemory)},
+		{"VirtualQuantity", strconv.Itoa(app.MemoryMB)},
+	})
+
+	ctrls := []*ovfController{}
+	ctrlOf := func(disk SDisk) *ovfController {
+		rt, subType, name := resourceTypeScsiController, disk.ControllerModel, "SCSI Controller"
+		switch disk.Controller {
+		case CONTROLLER_IDE:
+			rt, subType, name = resourceTypeIdeController, "", "IDE Controller"
+		case CONTROLLER_SATA:
+			rt, subType, name = resourceTypeSataController, "vmware.sata.ahci", "SATA Controller"
+		default:
+			if len(subType) == 0 {
+				subType = "lsilogic"
+			}
+		}
+		for _, c := range ctrls {
+			if c.resourceType == rt {
+				return c
+			}
+		}
+		c := &ovfController{instanceId: instanceId, resourceType: rt, subType: subType, name: name}
+		ctrls = append(ctrls, c)
+		fields := [][2]string{
+			{"Address", "0"},
+			{"ElementName", name},
+			{"InstanceID", strconv.Itoa(instanceId)},
+		}
+		if len(subType) > 0 {
+			fields = append(fields, [2]string{"ResourceSubType", subType})
+		}
+		fields = append(fields, [2]string{"ResourceType", strconv.Itoa(rt)})
+		writeItem(fields)
+		return c
+	}
+	for i, disk := range app.Disks {
+		ctrl := ctrlOf(disk)
+		writeItem([][2]string{
+			{"AddressOnParent", strconv.Itoa(ctrl.nextAddress)},
+			{"ElementName", fmt.Sprintf("Hard disk %d", i+1)},
+			{"HostResource", fmt.Sprintf("ovf:/disk/vmdisk%d", i+1)},
+			{"InstanceID", strconv.Itoa(instanceId)},
+			{"Parent", strconv.Itoa(ctrl.instanceId)},
+			{"ResourceType", strconv.Itoa(resourceTypeDiskDrive)},
+		})
+		ctrl.nextAddress++
+	}
+	for i, nic := range app.Nics {
+		fields := [][2]string{
+			{"AddressOnParent", strconv.Itoa(7 + i)},
+			{"AutomaticAllocation", "true"},
+		}
+		if len(nic.Network) > 0 {
+			fields = append(fields, [2]string{"Connection", nic.Network})
+		}
+		fields = append(fields,
+			[2]string{"ElementName", fmt.Sprintf("Network adapter %d", i+1)},
+			[2]string{"InstanceID", strconv.Itoa(instanceId)},
+		)
+		if len(nic.Model) > 0 {
+			fields = append(fields, [2]string{"ResourceSubType", nic.Model})
+		}
+		fields = append(fields, [2]string{"ResourceType", strconv.Itoa(resourceTypeEthernet)})
+		writeItem(fields)
+	}
+	if app.Firmware == FIRMWARE_EFI {
+		fmt.Fprintf(b, "      <vmw:Config ovf:required=\"false\" vmw:key=\"firmware\" vmw:value=\"%s\"/>\n", FIRMWARE_EFI)
+	}
+	b.WriteString("    </VirtualHardwareSection>\n")
+	b.WriteString("  </VirtualSystem>\n")
+	b.WriteString("</Envelope>\n")
+	return b.String()
+}
+
+func containsString(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,337 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovfutils
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/util/qemuimg"
+)
+
+func TestParseOvf(t *testing.T) {
+	cases := []struct {
+		file string
+		want SVirtualAppliance
+	}{
+		{
+			file: "appliance.ovf",
+			want: SVirtualAppliance{
+				Name:     "appliance",
+				OsType:   OS_TYPE_LINUX,
+				OsDesc:   "Red Hat Enterprise Linux 7 (64-bit)",
+				CpuCount: 2,
+				MemoryMB: 4096,
+				Firmware: FIRMWARE_EFI,
+				Disks: []SDisk{
+					{
+						Id:              "vmdisk1",
+						FileHref:        "appliance-disk1.vmdk",
+						FileSize:        1048576,
+						CapacityBytes:   16 << 30,
+						Controller:      CONTROLLER_SCSI,
+						ControllerModel: "VirtualSCSI",
+					},
+					{
+						Id:            "vmdisk2",
+						FileHref:      "appliance-disk2.vmdk",
+						FileSize:      65536,
+						CapacityBytes: 20 << 30,
+						Controller:    CONTROLLER_IDE,
+					},
+				},
+				Nics: []SNic{{Network: "VM Network", Model: "VmxNet3"}},
+			},
+		},
+		{
+			file: "vbox.ovf",
+			want: SVirtualAppliance{
+				Name:     "win",
+				OsType:   OS_TYPE_WINDOWS,
+				OsDesc:   "Windows10_64",
+				CpuCount: 1,
+				MemoryMB: 2048,
+				Firmware: FIRMWARE_BIOS,
+				Disks: []SDisk{
+					{
+						Id:              "vmdisk1",
+						FileHref:        "win-disk001.vmdk",
+						CapacityBytes:   50 << 30,
+						Controller:      CONTROLLER_SATA,
+						ControllerModel: "AHCI",
+					},
+				},
+				Nics: []SNic{{Network: "NAT", Model: "E1000"}},
+			},
+		},
+	}
+	for _, c := range cases {
+		data, err := os.ReadFile(filepath.Join("testdata", c.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		app, err := ParseOvf(data)
+		if err != nil {
+			t.Fatalf("%s: %v", c.file, err)
+		}
+		if !reflect.DeepEqual(*app, c.want) {
+			t.Errorf("%s:\ngot  %#v\nwant %#v", c.file, *app, c.want)
+		}
+	}
+}
+
+func TestParseAllocationUnits(t *testing.T) {
+	cases := []struct {
+		units string
+		want  int64
+		err   bool
+	}{
+		{"", 7, false},
+		{"byte", 1, false},
+		{"byte * 2^20", 1 << 20, false},
+		{"byte*2^30", 1 << 30, false},
+		{"byte * 1024", 1024, false},
+		{"MegaBytes", 1 << 20, false},
+		{"hertz * 10^6", 0, true},
+	}
+	for _, c := range cases {
+		got, err := parseAllocationUnits(c.units, 7)
+		if (err != nil) != c.err {
+			t.Errorf("%q: unexpected error %v", c.units, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q: got %d, want %d", c.units, got, c.want)
+		}
+	}
+}
+
+func TestGenerateOvf(t *testing.T) {
+	app := &SVirtualAppliance{
+		Name:     "web <1>",
+		OsType:   OS_TYPE_LINUX,
+		OsDesc:   "Linux",
+		CpuCount: 4,
+		MemoryMB: 8192,
+		Firmware: FIRMWARE_EFI,
+		Disks: []SDisk{
+			{Id: "vmdisk1", FileHref: "web-disk1.vmdk", FileSize: 100, CapacityBytes: 30 << 30, Controller: CONTROLLER_SCSI, ControllerModel: "lsilogic"},
+			{Id: "vmdisk2", FileHref: "web-disk2.vmdk", FileSize: 200, CapacityBytes: 10 << 30, Controller: CONTROLLER_SCSI, ControllerModel: "lsilogic"},
+			{Id: "vmdisk3", FileHref: "web-disk3.vmdk", FileSize: 300, CapacityBytes: 1 << 30, Controller: CONTROLLER_IDE},
+		},
+		Nics: []SNic{
+			{Network: "net-a", Model: "E1000"},
+			{Network: "net-a", Model: "VmxNet3"},
+		},
+	}
+	got, err := ParseOvf([]byte(GenerateOvf(app)))
+	if err != nil {
+		t.Fatalf("parse generated ovf: %v", err)
+	}
+	if !reflect.DeepEqual(got, app) {
+		t.Errorf("round trip:\ngot  %#v\nwant %#v", got, app)
+	}
+}
+
+func writeTestOva(t *testing.T, dir string, app *SVirtualAppliance, contents [][]byte) []byte {
+	paths := make([]string, len(contents))
+	for i := range contents {
+		paths[i] = filepath.Join(dir, app.Disks[i].FileHref)
+		if err := os.WriteFile(paths[i], contents[i], 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteOva(buf, app, paths); err != nil {
+		t.Fatalf("WriteOva: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestOvaRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	app := &SVirtualAppliance{
+		Name:     "app",
+		CpuCount: 1,
+		MemoryMB: 512,
+		Firmware: FIRMWARE_BIOS,
+		Disks: []SDisk{
+			{Id: "vmdisk1", FileHref: "app-disk1.vmdk", CapacityBytes: 1 << 30, Controller: CONTROLLER_SCSI, ControllerModel: "lsilogic"},
+			{Id: "vmdisk2", FileHref: "app-disk2.vmdk", CapacityBytes: 2 << 30, Controller: CONTROLLER_SCSI, ControllerModel: "lsilogic"},
+		},
+	}
+	contents := [][]byte{[]byte("root disk"), bytes.Repeat([]byte("d"), 4096)}
+	ova := writeTestOva(t, dir, app, contents)
+
+	var parsed *SVirtualAppliance
+	got := map[string][]byte{}
+	err := ReadOva(bytes.NewReader(ova), func(a *SVirtualAppliance) error {
+		parsed = a
+		return nil
+	}, func(disk *SDisk, r io.Reader, size int64, verify func() error) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if int64(len(data)) != size {
+			t.Errorf("%s: read %d bytes, size %d", disk.FileHref, len(data), size)
+		}
+		got[disk.Id] = data
+		return verify()
+	})
+	if err != nil {
+		t.Fatalf("ReadOva: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, app) {
+		t.Errorf("descriptor:\ngot  %#v\nwant %#v", parsed, app)
+	}
+	for i, disk := range app.Disks {
+		if !bytes.Equal(got[disk.Id], contents[i]) {
+			t.Errorf("disk %s: content mismatch", disk.Id)
+		}
+	}
+
+	// a disk handler reading nothing must not break digest verification
+	err = ReadOva(bytes.NewReader(ova), func(*SVirtualAppliance) error { return nil },
+		func(*SDisk, io.Reader, int64, func() error) error { return nil })
+	if err != nil {
+		t.Errorf("ReadOva skipping content: %v", err)
+	}
+
+	// corrupt the root disk, the mismatch must be seen by the disk handler
+	// before it accepts the disk
+	corrupted := bytes.Replace(ova, []byte("root disk"), []byte("r00t disk"), 1)
+	accepted := []string{}
+	err = ReadOva(bytes.NewReader(corrupted), func(*SVirtualAppliance) error { return nil },
+		func(disk *SDisk, r io.Reader, size int64, verify func() error) error {
+			if _, err := io.Copy(io.Discard, r); err != nil {
+				return err
+			}
+			if err := verify(); err != nil {
+				return err
+			}
+			accepted = append(accepted, disk.Id)
+			return nil
+		})
+	if err == nil {
+		t.Errorf("expect digest mismatch of corrupted ova")
+	}
+	if len(accepted) > 0 {
+		t.Errorf("corrupted disks accepted: %v", accepted)
+	}
+}
+
+func TestOvaManifestAfterDisks(t *testing.T) {
+	app := &SVirtualAppliance{
+		Name:     "app",
+		CpuCount: 1,
+		MemoryMB: 512,
+		Firmware: FIRMWARE_BIOS,
+		Disks: []SDisk{
+			{Id: "vmdisk1", FileHref: "app-disk1.vmdk", CapacityBytes: 1 << 30},
+			{Id: "vmdisk2", FileHref: "app-disk2.vmdk", CapacityBytes: 1 << 30},
+		},
+	}
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{"app.ovf", []byte(GenerateOvf(app))},
+		{"app-disk1.vmdk", []byte("root disk")},
+		{"app.mf", []byte("SHA256(app-disk1.vmdk)= 00\n")},
+		{"app-disk2.vmdk", []byte("data disk")},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	read := []string{}
+	err := ReadOva(bytes.NewReader(buf.Bytes()), func(*SVirtualAppliance) error { return nil },
+		func(disk *SDisk, r io.Reader, size int64, verify func() error) error {
+			read = append(read, disk.Id)
+			return verify()
+		})
+	if err == nil {
+		t.Errorf("expect error of manifest after disk files")
+	}
+	if !reflect.DeepEqual(read, []string{"vmdisk1"}) {
+		t.Errorf("disks read: %v", read)
+	}
+}
+
+func TestOvaQemuImg(t *testing.T) {
+	if _, err := exec.LookPath("qemu-img"); err != nil {
+		t.Skip("qemu-img not found")
+	}
+	dir := t.TempDir()
+	img, err := qemuimg.NewQemuImage(filepath.Join(dir, "root.qcow2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := img.CreateQcow2(64, true, "", "", "", ""); err != nil {
+		t.Fatalf("CreateQcow2: %v", err)
+	}
+	vmdkPath := filepath.Join(dir, "app-disk1.vmdk")
+	if _, err := img.CloneVmdk(vmdkPath, true); err != nil {
+		t.Fatalf("CloneVmdk: %v", err)
+	}
+	app := &SVirtualAppliance{
+		Name:     "app",
+		CpuCount: 1,
+		MemoryMB: 512,
+		Firmware: FIRMWARE_BIOS,
+		Disks:    []SDisk{{Id: "vmdisk1", FileHref: "app-disk1.vmdk", CapacityBytes: 64 << 20}},
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteOva(buf, app, []string{vmdkPath}); err != nil {
+		t.Fatalf("WriteOva: %v", err)
+	}
+	extracted := filepath.Join(dir, "extracted")
+	err = ReadOva(buf, func(*SVirtualAppliance) error { return nil }, func(disk *SDisk, r io.Reader, size int64, verify func() error) error {
+		f, err := os.Create(extracted)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := io.Copy(f, r); err != nil {
+			return err
+		}
+		return verify()
+	})
+	if err != nil {
+		t.Fatalf("ReadOva: %v", err)
+	}
+	out, err := qemuimg.NewQemuImage(extracted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Format != "vmdk" || out.SizeBytes != 64<<20 {
+		t.Errorf("extracted disk format %s size %d", out.Format, out.SizeBytes)
+	}
+}
@@ -0,0 +1,475 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/fileutils"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+var (
+	composeServiceFields = supportedFields{}.add("", true,
+		"image", "entrypoint", "command", "environment", "working_dir", "privileged", "user",
+		"cap_add", "cap_drop", "ports", "volumes", "restart", "stop_grace_period", "container_name",
+		"mem_limit", "cpus", "shm_size",
+		"deploy.resources.limits.cpus", "deploy.resources.limits.memory",
+		"configs[].source", "configs[].target",
+	)
+
+	composeFileFields = supportedFields{}.
+				add("", true, "version", "name", "volumes").
+				add("configs.*", true, "content")
+)
+
+func convertCompose(doc map[string]interface{}) (*computeapi.PodConvertManifestOutput, error) {
+	c := newConverter(computeapi.POD_MANIFEST_FORMAT_COMPOSE)
+	services, ok := doc["services"].(map[string]interface{})
+	if !ok || len(services) == 0 {
+		return nil, errors.Errorf("services of compose file is empty")
+	}
+	if name, ok := doc["name"].(string); ok {
+		c.output.Name = name
+	}
+	for k, v := range doc {
+		if k == "services" {
+			continue
+		}
+		if k == "configs" {
+			for _, f := range composeFileFields.findUnmapped(v, "configs", "configs") {
+				c.unmapped(f)
+			}
+			continue
+		}
+		if _, _, ok := composeFileFields.lookup("", k); !ok && !isEmptyValue(v) {
+			c.unmapped(k)
+		}
+	}
+	configs, _ := doc["configs"].(map[string]interface{})
+
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		svc, ok := services[name].(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("service %s isn't an object", name)
+		}
+		svcPath := "services." + name
+		for _, f := range composeServiceFields.findUnmapped(svc, svcPath, "") {
+			c.unmapped(f)
+		}
+		ctr, err := c.convertService(name, svc, svcPath, configs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "convert service %s", name)
+		}
+		c.output.Pod.Containers = append(c.output.Pod.Containers, ctr)
+	}
+	return c.finish(), nil
+}
+
+// toStringSlice converts the shell form string or the exec form list to string slice
+func toStringSlice(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return strings.Fields(val)
+	case []interface{}:
+		ret := make([]string, 0, len(val))
+		for _, item := range val {
+			ret = append(ret, fmt.Sprintf("%v", item))
+		}
+		return ret
+	}
+	return nil
+}
+
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func parseComposeMemory(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case float64:
+		// bytes
+		return int64(math.Ceil(val / 1024 / 1024)), nil
+	case string:
+		s := strings.ToLower(strings.TrimSpace(val))
+		s = strings.TrimSuffix(s, "b")
+		if s == "" {
+			return 0, nil
+		}
+		last := s[len(s)-1]
+		if last >= '0' && last <= '9' {
+			bytes, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return (bytes + 1024*1024 - 1) / 1024 / 1024, nil
+		}
+		mb, err := fileutils.GetSizeMb(s, 'M', 1024)
+		if err != nil {
+			return 0, err
+		}
+		return int64(mb), nil
+	}
+	return 0, errors.Errorf("invalid memory %v", v)
+}
+
+func parseComposeCpus(v interface{}) (float64, error) {
+	return strconv.ParseFloat(toString(v), 64)
+}
+
+func (c *converter) convertService(name string, svc map[string]interface{}, svcPath string, configs map[string]interface{}) (*computeapi.PodContainerCreateInput, error) {
+	ctrName := name
+	if cn, ok := svc["container_name"].(string); ok && cn != "" {
+		ctrName = cn
+	}
+	input := newContainerInput(ctrName)
+	spec := &input.ContainerSpec
+	spec.Image, _ = svc["image"].(string)
+	if spec.Image == "" {
+		return nil, errors.Errorf("image of service %s is empty", name)
+	}
+	spec.Command = toStringSlice(svc["entrypoint"])
+	spec.Args = toStringSlice(svc["command"])
+	spec.WorkingDir, _ = svc["working_dir"].(string)
+	spec.Privileged, _ = svc["privileged"].(bool)
+
+	switch envs := svc["environment"].(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(envs))
+		for k := range envs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			spec.Envs = append(spec.Envs, &apis.ContainerKeyValue{Key: k, Value: toString(envs[k])})
+		}
+	case []interface{}:
+		for _, env := range envs {
+			kv := strings.SplitN(toString(env), "=", 2)
+			if len(kv) != 2 {
+				// value is taken from the shell environment of compose
+				c.unmapped("%s.environment: %s", svcPath, kv[0])
+				continue
+			}
+			spec.Envs = append(spec.Envs, &apis.ContainerKeyValue{Key: kv[0], Value: kv[1]})
+		}
+	}
+
+	if user, ok := svc["user"]; ok {
+		parts := strings.SplitN(toString(user), ":", 2)
+		sc := &apis.ContainerSecurityContext{}
+		uid, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			c.unmapped("%s.user: user name %q", svcPath, parts[0])
+		} else {
+			sc.RunAsUser = &uid
+		}
+		if len(parts) == 2 {
+			gid, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				c.unmapped("%s.user: group name %q", svcPath, parts[1])
+			} else {
+				sc.RunAsGroup = &gid
+			}
+		}
+		if sc.RunAsUser != nil || sc.RunAsGroup != nil {
+			spec.SecurityContext = sc
+		}
+	}
+
+	capAdd := toStringSlice(svc["cap_add"])
+	capDrop := toStringSlice(svc["cap_drop"])
+	if len(capAdd) > 0 || len(capDrop) > 0 {
+		spec.Capabilities = &apis.ContainerCapability{Add: capAdd, Drop: capDrop}
+	}
+
+	if restart, ok := svc["restart"].(string); ok {
+		switch restart {
+		case "always", "unless-stopped":
+			spec.AlwaysRestart = true
+		case "no":
+		default:
+			c.unmapped("%s.restart: %s", svcPath, restart)
+		}
+	}
+
+	if sgp, ok := svc["stop_grace_period"].(string); ok {
+		d, err := time.ParseDuration(sgp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid stop_grace_period %s", sgp)
+		}
+		spec.Lifecyle = &apis.ContainerLifecyle{
+			TerminationGracePeriodSeconds: int64(math.Ceil(d.Seconds())),
+		}
+	}
+
+	if shm, ok := svc["shm_size"]; ok {
+		mb, err := parseComposeMemory(shm)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid shm_size")
+		}
+		spec.ShmSizeMB = int(mb)
+	}
+
+	if err := c.convertServiceResources(svc, spec); err != nil {
+		return nil, err
+	}
+
+	if ports, ok := svc["ports"].([]interface{}); ok {
+		for i, p := range ports {
+			if err := c.convertComposePort(p, fmt.Sprintf("%s.ports[%d]", svcPath, i)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if vols, ok := svc["volumes"].([]interface{}); ok {
+		for i, v := range vols {
+			vm := c.convertComposeVolume(v, fmt.Sprintf("%s.volumes[%d]", svcPath, i))
+			if vm != nil {
+				spec.VolumeMounts = append(spec.VolumeMounts, vm)
+			}
+		}
+	}
+
+	if cfgs, ok := svc["configs"].([]interface{}); ok {
+		for i, cfg := range cfgs {
+			vm := c.convertComposeConfig(cfg, fmt.Sprintf("%s.configs[%d]", svcPath, i), configs)
+			if vm != nil {
+				spec.VolumeMounts = append(spec.VolumeMounts, vm)
+			}
+		}
+	}
+	return input, nil
+}
+
+func (c *converter) convertServiceResources(svc map[string]interface{}, spec *computeapi.ContainerSpec) error {
+	var cpus, mem interface{}
+	if deploy, ok := svc["deploy"].(map[string]interface{}); ok {
+		if res, ok := deploy["resources"].(map[string]interface{}); ok {
+			if limits, ok := res["limits"].(map[string]interface{}); ok {
+				cpus = limits["cpus"]
+				mem = limits["memory"]
+			}
+		}
+	}
+	if cpus == nil {
+		cpus = svc["cpus"]
+	}
+	if mem == nil {
+		mem = svc["mem_limit"]
+	}
+	var cpuMilli, memMB int64
+	if cpus != nil {
+		quota, err := parseComposeCpus(cpus)
+		if err != nil {
+			return errors.Wrapf(err, "invalid cpus %v", cpus)
+		}
+		spec.ResourcesLimit = &apis.ContainerResources{CpuCfsQuota: &quota}
+		cpuMilli = int64(math.Ceil(quota * 1000))
+	}
+	if mem != nil {
+		mb, err := parseComposeMemory(mem)
+		if err != nil {
+			return errors.Wrapf(err, "invalid memory limit %v", mem)
+		}
+		memMB = mb
+	}
+	c.addResources(cpuMilli, memMB)
+	return nil
+}
+
+// convertComposePort parses the short syntax "[HOST_IP:][HOST_PORT:]CONTAINER_PORT[/PROTOCOL]"
+// or the long syntax with target/published/host_ip/protocol
+func (c *converter) convertComposePort(p interface{}, fieldPath string) error {
+	var (
+		proto     = computeapi.GuestPortMappingProtocolTCP
+		ctrPort   string
+		hostPort  string
+		hostIp    string
+		protoName string
+	)
+	switch val := p.(type) {
+	case map[string]interface{}:
+		ctrPort = toString(val["target"])
+		hostPort = toString(val["published"])
+		hostIp = toString(val["host_ip"])
+		protoName = toString(val["protocol"])
+		for k := range val {
+			if !sets(k, "target", "published", "host_ip", "protocol", "mode") {
+				c.unmapped("%s.%s", fieldPath, k)
+			}
+		}
+	default:
+		s := toString(val)
+		if idx := strings.LastIndex(s, "/"); idx >= 0 {
+			protoName = s[idx+1:]
+			s = s[:idx]
+		}
+		segs := strings.Split(s, ":")
+		switch len(segs) {
+		case 1:
+			ctrPort = segs[0]
+		case 2:
+			hostPort, ctrPort = segs[0], segs[1]
+		case 3:
+			hostIp, hostPort, ctrPort = segs[0], segs[1], segs[2]
+		default:
+			return errors.Errorf("%s: invalid port %q", fieldPath, toString(val))
+		}
+	}
+	switch protoName {
+	case "", "tcp":
+	case "udp":
+		proto = computeapi.GuestPortMappingProtocolUDP
+	default:
+		c.unmapped("%s: protocol %s", fieldPath, protoName)
+		return nil
+	}
+	if strings.Contains(ctrPort, "-") || strings.Contains(hostPort, "-") {
+		c.unmapped("%s: port range", fieldPath)
+		return nil
+	}
+	cp, err := strconv.Atoi(ctrPort)
+	if err != nil {
+		return errors.Wrapf(err, "%s: invalid container port %q", fieldPath, ctrPort)
+	}
+	hp := 0
+	if hostPort != "" {
+		hp, err = strconv.Atoi(hostPort)
+		if err != nil {
+			return errors.Wrapf(err, "%s: invalid host port %q", fieldPath, hostPort)
+		}
+	}
+	c.addPortMapping(proto, cp, hp, hostIp)
+	return nil
+}
+
+// convertComposeVolume converts the named volume to the sub directory of the pod disk
+// and absolute bind mount to host path
+func (c *converter) convertComposeVolume(v interface{}, fieldPath string) *apis.ContainerVolumeMount {
+	var (
+		typ      string
+		source   string
+		target   string
+		readOnly bool
+	)
+	switch val := v.(type) {
+	case map[string]interface{}:
+		typ = toString(val["type"])
+		source = toString(val["source"])
+		target = toString(val["target"])
+		readOnly, _ = val["read_only"].(bool)
+		for k := range val {
+			if !sets(k, "type", "source", "target", "read_only") {
+				c.unmapped("%s.%s", fieldPath, k)
+			}
+		}
+	default:
+		segs := strings.Split(toString(val), ":")
+		switch len(segs) {
+		case 1:
+			target = segs[0]
+		case 2, 3:
+			source, target = segs[0], segs[1]
+			if len(segs) == 3 {
+				for _, opt := range strings.Split(segs[2], ",") {
+					switch opt {
+					case "ro":
+						readOnly = true
+					case "rw":
+					default:
+						c.unmapped("%s: option %s", fieldPath, opt)
+					}
+				}
+			}
+		}
+	}
+	if target == "" {
+		c.unmapped("%s: target is empty", fieldPath)
+		return nil
+	}
+	if typ == "" {
+		switch {
+		case source == "":
+			typ = "anonymous"
+		case strings.HasPrefix(source, "/") || strings.HasPrefix(source, "."):
+			typ = "bind"
+		default:
+			typ = "volume"
+		}
+	}
+	switch typ {
+	case "volume":
+		if source == "" {
+			break
+		}
+		return newEmptyDirVolumeMount(source, "", target, readOnly)
+	case "bind":
+		if !path.IsAbs(source) {
+			c.unmapped("%s: relative bind source %s", fieldPath, source)
+			return nil
+		}
+		return newHostPathVolumeMount(source, apis.CONTAINER_VOLUME_MOUNT_HOST_PATH_TYPE_DIRECTORY, target, readOnly)
+	}
+	c.unmapped("%s: %s volume", fieldPath, typ)
+	return nil
+}
+
+// convertComposeConfig mounts the inline content of the top level config as text file
+func (c *converter) convertComposeConfig(cfg interface{}, fieldPath string, configs map[string]interface{}) *apis.ContainerVolumeMount {
+	var source, target string
+	switch val := cfg.(type) {
+	case map[string]interface{}:
+		source = toString(val["source"])
+		target = toString(val["target"])
+	default:
+		source = toString(val)
+	}
+	if target == "" {
+		target = "/" + source
+	}
+	def, ok := configs[source].(map[string]interface{})
+	if !ok {
+		c.unmapped("%s: config %s isn't defined", fieldPath, source)
+		return nil
+	}
+	content, ok := def["content"].(string)
+	if !ok {
+		// file or environment source is reported by the unmapped fields walker
+		return nil
+	}
+	return newTextVolumeMount(content, target)
+}
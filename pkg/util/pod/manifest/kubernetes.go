@@ -0,0 +1,506 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+const (
+	kindPod        = "Pod"
+	kindDeployment = "Deployment"
+	kindConfigMap  = "ConfigMap"
+)
+
+var (
+	k8sHandlerFields = []string{
+		"exec.command",
+		"httpGet.path", "httpGet.port", "httpGet.host", "httpGet.scheme", "httpGet.httpHeaders",
+	}
+	k8sProbeFields = append([]string{
+		"tcpSocket.port", "tcpSocket.host",
+		"timeoutSeconds", "periodSeconds", "successThreshold", "failureThreshold",
+	}, k8sHandlerFields...)
+
+	k8sContainerFields = supportedFields{}.add("", true,
+		"name", "image", "imagePullPolicy", "command", "args", "workingDir",
+		"env[].name", "env[].value",
+		"resources.limits.cpu", "resources.limits.memory",
+		"resources.requests.cpu", "resources.requests.memory",
+		"ports[].name", "ports[].containerPort", "ports[].hostPort", "ports[].hostIP", "ports[].protocol",
+		"volumeMounts[].name", "volumeMounts[].mountPath", "volumeMounts[].readOnly", "volumeMounts[].subPath",
+		"securityContext.privileged", "securityContext.runAsUser", "securityContext.runAsGroup",
+		"securityContext.capabilities.add", "securityContext.capabilities.drop", "securityContext.procMount",
+	).
+		add("lifecycle.postStart", true, k8sHandlerFields...).
+		add("lifecycle.preStop", true, k8sHandlerFields...).
+		add("startupProbe", true, k8sProbeFields...)
+
+	k8sPodSpecFields = supportedFields{}.add("", true,
+		"hostIPC", "terminationGracePeriodSeconds", "restartPolicy",
+		"securityContext.runAsUser", "securityContext.runAsGroup",
+		"volumes[].name",
+		"volumes[].hostPath.path", "volumes[].hostPath.type",
+		"volumes[].configMap.name", "volumes[].configMap.items[].key", "volumes[].configMap.items[].path",
+	).
+		add("", false, "volumes[].emptyDir")
+
+	k8sConfigMapFields = supportedFields{}.add("", true, "apiVersion", "kind", "metadata.name", "data")
+)
+
+func init() {
+	// containers and initContainers share the same fields
+	for _, prefix := range []string{"containers[]", "initContainers[]"} {
+		for k, v := range k8sContainerFields {
+			k8sPodSpecFields.add(prefix, v, k)
+		}
+	}
+}
+
+func getDocKind(doc map[string]interface{}) (string, string) {
+	kind, _ := doc["kind"].(string)
+	name := ""
+	if meta, ok := doc["metadata"].(map[string]interface{}); ok {
+		name, _ = meta["name"].(string)
+	}
+	return kind, name
+}
+
+func unmarshalDoc(doc map[string]interface{}, obj interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return errors.Wrap(err, "marshal document")
+	}
+	return json.Unmarshal(data, obj)
+}
+
+func convertKubernetes(docs []map[string]interface{}) (*computeapi.PodConvertManifestOutput, error) {
+	c := newConverter(computeapi.POD_MANIFEST_FORMAT_KUBERNETES)
+	configMaps := make(map[string]*corev1.ConfigMap)
+	var workload map[string]interface{}
+	for idx, doc := range docs {
+		kind, name := getDocKind(doc)
+		switch kind {
+		case kindConfigMap:
+			cm := new(corev1.ConfigMap)
+			if err := unmarshalDoc(doc, cm); err != nil {
+				return nil, errors.Wrapf(err, "unmarshal ConfigMap %s", name)
+			}
+			configMaps[cm.Name] = cm
+			for _, f := range k8sConfigMapFields.findUnmapped(doc, "", "") {
+				c.unmapped("ConfigMap/%s: %s", name, f)
+			}
+		case kindPod, kindDeployment:
+			if workload != nil {
+				c.unmapped("%s/%s: only one workload is supported", kind, name)
+				continue
+			}
+			workload = doc
+		default:
+			c.unmapped("document %d: unsupported kind %q", idx, kind)
+		}
+	}
+	if workload == nil {
+		return nil, errors.Errorf("not found Pod or Deployment in manifest")
+	}
+
+	kind, _ := getDocKind(workload)
+	var (
+		meta     map[string]interface{}
+		specPath string
+		podSpec  corev1.PodSpec
+	)
+	switch kind {
+	case kindPod:
+		pod := new(corev1.Pod)
+		if err := unmarshalDoc(workload, pod); err != nil {
+			return nil, errors.Wrap(err, "unmarshal Pod")
+		}
+		c.output.Name = pod.Name
+		podSpec = pod.Spec
+		specPath = "spec"
+		meta, _ = workload["metadata"].(map[string]interface{})
+		for k := range workload {
+			if !sets(k, "apiVersion", "kind", "metadata", "spec", "status") {
+				c.unmapped(k)
+			}
+		}
+	case kindDeployment:
+		deploy := new(appsv1.Deployment)
+		if err := unmarshalDoc(workload, deploy); err != nil {
+			return nil, errors.Wrap(err, "unmarshal Deployment")
+		}
+		c.output.Name = deploy.Name
+		if deploy.Spec.Replicas != nil {
+			c.output.Count = int(*deploy.Spec.Replicas)
+		}
+		podSpec = deploy.Spec.Template.Spec
+		specPath = "spec.template.spec"
+		meta, _ = workload["metadata"].(map[string]interface{})
+		spec, _ := workload["spec"].(map[string]interface{})
+		for k, v := range spec {
+			if !sets(k, "replicas", "template", "selector") && !isEmptyValue(v) {
+				c.unmapped("spec.%s", k)
+			}
+		}
+		if tmpl, ok := spec["template"].(map[string]interface{}); ok {
+			for k, v := range tmpl {
+				if !sets(k, "spec", "metadata") && !isEmptyValue(v) {
+					c.unmapped("spec.template.%s", k)
+				}
+			}
+		}
+	}
+	for k, v := range meta {
+		// labels of pod are only used by kubernetes selectors
+		if !sets(k, "name", "labels") && !isEmptyValue(v) {
+			c.unmapped("metadata.%s", k)
+		}
+	}
+
+	// find unmapped fields of pod spec
+	specObj := workload
+	for _, seg := range strings.Split(specPath, ".") {
+		specObj, _ = specObj[seg].(map[string]interface{})
+	}
+	for _, f := range k8sPodSpecFields.findUnmapped(specObj, specPath, "") {
+		c.unmapped(f)
+	}
+
+	if err := c.convertPodSpec(&podSpec, specPath, configMaps); err != nil {
+		return nil, errors.Wrap(err, "convert pod spec")
+	}
+	return c.finish(), nil
+}
+
+func sets(key string, candidates ...string) bool {
+	for _, c := range candidates {
+		if key == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *converter) convertPodSpec(spec *corev1.PodSpec, specPath string, configMaps map[string]*corev1.ConfigMap) error {
+	pod := c.output.Pod
+	pod.HostIPC = spec.HostIPC
+	if spec.SecurityContext != nil && (spec.SecurityContext.RunAsUser != nil || spec.SecurityContext.RunAsGroup != nil) {
+		pod.SecurityContext = &computeapi.PodSecurityContext{
+			RunAsUser:  spec.SecurityContext.RunAsUser,
+			RunAsGroup: spec.SecurityContext.RunAsGroup,
+		}
+	}
+	volumes := make(map[string]*corev1.Volume)
+	for i := range spec.Volumes {
+		volumes[spec.Volumes[i].Name] = &spec.Volumes[i]
+	}
+	var gracePeriod int64
+	if spec.TerminationGracePeriodSeconds != nil {
+		gracePeriod = *spec.TerminationGracePeriodSeconds
+	}
+	for i := range spec.InitContainers {
+		ctrPath := fmt.Sprintf("%s.initContainers[%d]", specPath, i)
+		ctr, err := c.convertContainer(&spec.InitContainers[i], ctrPath, volumes, configMaps, true)
+		if err != nil {
+			return errors.Wrapf(err, "convert init container %s", spec.InitContainers[i].Name)
+		}
+		pod.InitContainers = append(pod.InitContainers, ctr)
+	}
+	for i := range spec.Containers {
+		ctrPath := fmt.Sprintf("%s.containers[%d]", specPath, i)
+		ctr, err := c.convertContainer(&spec.Containers[i], ctrPath, volumes, configMaps, false)
+		if err != nil {
+			return errors.Wrapf(err, "convert container %s", spec.Containers[i].Name)
+		}
+		if gracePeriod > 0 {
+			if ctr.Lifecyle == nil {
+				ctr.Lifecyle = &apis.ContainerLifecyle{}
+			}
+			ctr.Lifecyle.TerminationGracePeriodSeconds = gracePeriod
+		}
+		if spec.RestartPolicy == corev1.RestartPolicyAlways {
+			ctr.AlwaysRestart = true
+		}
+		pod.Containers = append(pod.Containers, ctr)
+	}
+	return nil
+}
+
+func (c *converter) convertContainer(
+	ctr *corev1.Container, ctrPath string,
+	volumes map[string]*corev1.Volume, configMaps map[string]*corev1.ConfigMap,
+	isInit bool,
+) (*computeapi.PodContainerCreateInput, error) {
+	input := newContainerInput(ctr.Name)
+	spec := &input.ContainerSpec
+	spec.Image = ctr.Image
+	switch ctr.ImagePullPolicy {
+	case corev1.PullAlways:
+		spec.ImagePullPolicy = apis.ImagePullPolicyAlways
+	case corev1.PullIfNotPresent:
+		spec.ImagePullPolicy = apis.ImagePullPolicyIfNotPresent
+	case "":
+	default:
+		c.unmapped("%s.imagePullPolicy", ctrPath)
+	}
+	spec.Command = ctr.Command
+	spec.Args = ctr.Args
+	spec.WorkingDir = ctr.WorkingDir
+	for _, env := range ctr.Env {
+		if env.ValueFrom != nil {
+			continue
+		}
+		spec.Envs = append(spec.Envs, &apis.ContainerKeyValue{Key: env.Name, Value: env.Value})
+	}
+
+	// resources, limits are preferred, init containers run before the
+	// application containers so they don't need extra resources of the pod
+	for _, rl := range []corev1.ResourceList{ctr.Resources.Limits, ctr.Resources.Requests} {
+		cpu, hasCpu := rl[corev1.ResourceCPU]
+		mem, hasMem := rl[corev1.ResourceMemory]
+		if !hasCpu && !hasMem {
+			continue
+		}
+		var cpuMilli, memMB int64
+		if hasCpu {
+			cpuMilli = cpu.MilliValue()
+			quota := float64(cpuMilli) / 1000
+			spec.ResourcesLimit = &apis.ContainerResources{CpuCfsQuota: &quota}
+		}
+		if hasMem {
+			memMB = (mem.Value() + 1024*1024 - 1) / 1024 / 1024
+		}
+		if !isInit {
+			c.addResources(cpuMilli, memMB)
+		}
+		break
+	}
+
+	namedPorts := make(map[string]int)
+	for _, p := range ctr.Ports {
+		if p.Name != "" {
+			namedPorts[p.Name] = int(p.ContainerPort)
+		}
+		proto := computeapi.GuestPortMappingProtocolTCP
+		switch p.Protocol {
+		case corev1.ProtocolUDP:
+			proto = computeapi.GuestPortMappingProtocolUDP
+		case corev1.ProtocolTCP, "":
+		default:
+			c.unmapped("%s.ports[%s].protocol", ctrPath, p.Protocol)
+			continue
+		}
+		c.addPortMapping(proto, int(p.ContainerPort), int(p.HostPort), p.HostIP)
+	}
+
+	if sc := ctr.SecurityContext; sc != nil {
+		if sc.Privileged != nil {
+			spec.Privileged = *sc.Privileged
+		}
+		if sc.RunAsUser != nil || sc.RunAsGroup != nil || sc.ProcMount != nil {
+			spec.SecurityContext = &apis.ContainerSecurityContext{
+				RunAsUser:  sc.RunAsUser,
+				RunAsGroup: sc.RunAsGroup,
+			}
+			if sc.ProcMount != nil {
+				spec.SecurityContext.ProcMount = apis.ContainerProcMountType(*sc.ProcMount)
+			}
+		}
+		if sc.Capabilities != nil {
+			spec.Capabilities = &apis.ContainerCapability{}
+			for _, cap := range sc.Capabilities.Add {
+				spec.Capabilities.Add = append(spec.Capabilities.Add, string(cap))
+			}
+			for _, cap := range sc.Capabilities.Drop {
+				spec.Capabilities.Drop = append(spec.Capabilities.Drop, string(cap))
+			}
+		}
+	}
+
+	if ctr.Lifecycle != nil {
+		spec.Lifecyle = &apis.ContainerLifecyle{}
+		if ctr.Lifecycle.PostStart != nil {
+			spec.Lifecyle.PostStart = c.convertLifecycleHandler(ctr.Lifecycle.PostStart, ctrPath+".lifecycle.postStart", namedPorts)
+		}
+		if ctr.Lifecycle.PreStop != nil {
+			spec.Lifecyle.PreStop = c.convertLifecycleHandler(ctr.Lifecycle.PreStop, ctrPath+".lifecycle.preStop", namedPorts)
+		}
+	}
+	if ctr.StartupProbe != nil {
+		spec.StartupProbe = c.convertProbe(ctr.StartupProbe, ctrPath+".startupProbe", namedPorts)
+	}
+
+	for i, vm := range ctr.VolumeMounts {
+		vms, err := c.convertVolumeMount(vm, fmt.Sprintf("%s.volumeMounts[%d]", ctrPath, i), volumes, configMaps)
+		if err != nil {
+			return nil, err
+		}
+		spec.VolumeMounts = append(spec.VolumeMounts, vms...)
+	}
+	return input, nil
+}
+
+func (c *converter) resolvePort(port intstr.IntOrString, fieldPath string, namedPorts map[string]int) int {
+	if port.Type == intstr.Int {
+		return port.IntValue()
+	}
+	if p, ok := namedPorts[port.StrVal]; ok {
+		return p
+	}
+	c.unmapped("%s: unknown named port %q", fieldPath, port.StrVal)
+	return 0
+}
+
+func (c *converter) convertHTTPGet(action *corev1.HTTPGetAction, fieldPath string, namedPorts map[string]int) *apis.ContainerProbeHTTPGetAction {
+	ret := &apis.ContainerProbeHTTPGetAction{
+		Path:   action.Path,
+		Port:   c.resolvePort(action.Port, fieldPath+".port", namedPorts),
+		Host:   action.Host,
+		Scheme: apis.URIScheme(action.Scheme),
+	}
+	for _, h := range action.HTTPHeaders {
+		ret.HTTPHeaders = append(ret.HTTPHeaders, apis.HTTPHeader{Name: h.Name, Value: h.Value})
+	}
+	return ret
+}
+
+func (c *converter) convertLifecycleHandler(h *corev1.Handler, fieldPath string, namedPorts map[string]int) *apis.ContainerLifecyleHandler {
+	if h.Exec != nil {
+		return &apis.ContainerLifecyleHandler{
+			Type: apis.ContainerLifecyleHandlerTypeExec,
+			Exec: &apis.ContainerLifecyleHandlerExecAction{Command: h.Exec.Command},
+		}
+	}
+	if h.HTTPGet != nil {
+		return &apis.ContainerLifecyleHandler{
+			Type:    apis.ContainerLifecyleHandlerTypeHTTPGet,
+			HTTPGet: c.convertHTTPGet(h.HTTPGet, fieldPath+".httpGet", namedPorts),
+		}
+	}
+	// tcpSocket is reported by the unmapped fields walker
+	return nil
+}
+
+func (c *converter) convertProbe(p *corev1.Probe, fieldPath string, namedPorts map[string]int) *apis.ContainerProbe {
+	ret := &apis.ContainerProbe{
+		TimeoutSeconds:   p.TimeoutSeconds,
+		PeriodSeconds:    p.PeriodSeconds,
+		SuccessThreshold: p.SuccessThreshold,
+		FailureThreshold: p.FailureThreshold,
+	}
+	switch {
+	case p.Exec != nil:
+		ret.Exec = &apis.ContainerProbeHandlerExecAction{Command: p.Exec.Command}
+	case p.HTTPGet != nil:
+		ret.HTTPGet = c.convertHTTPGet(p.HTTPGet, fieldPath+".httpGet", namedPorts)
+	case p.TCPSocket != nil:
+		ret.TCPSocket = &apis.ContainerProbeTCPSocketAction{
+			Port: c.resolvePort(p.TCPSocket.Port, fieldPath+".tcpSocket.port", namedPorts),
+			Host: p.TCPSocket.Host,
+		}
+	default:
+		return nil
+	}
+	return ret
+}
+
+func (c *converter) convertVolumeMount(
+	vm corev1.VolumeMount, fieldPath string,
+	volumes map[string]*corev1.Volume, configMaps map[string]*corev1.ConfigMap,
+) ([]*apis.ContainerVolumeMount, error) {
+	vol, ok := volumes[vm.Name]
+	if !ok {
+		return nil, errors.Errorf("%s: not found volume %q", fieldPath, vm.Name)
+	}
+	switch {
+	case vol.EmptyDir != nil:
+		return []*apis.ContainerVolumeMount{newEmptyDirVolumeMount(vol.Name, vm.SubPath, vm.MountPath, vm.ReadOnly)}, nil
+	case vol.HostPath != nil:
+		typ := apis.CONTAINER_VOLUME_MOUNT_HOST_PATH_TYPE_DIRECTORY
+		if vol.HostPath.Type != nil {
+			switch *vol.HostPath.Type {
+			case corev1.HostPathFile, corev1.HostPathFileOrCreate:
+				typ = apis.CONTAINER_VOLUME_MOUNT_HOST_PATH_TYPE_FILE
+			}
+		}
+		hostPath := vol.HostPath.Path
+		if vm.SubPath != "" {
+			hostPath = path.Join(hostPath, vm.SubPath)
+		}
+		return []*apis.ContainerVolumeMount{newHostPathVolumeMount(hostPath, typ, vm.MountPath, vm.ReadOnly)}, nil
+	case vol.ConfigMap != nil:
+		return c.convertConfigMapVolumeMount(vol, vm, fieldPath, configMaps), nil
+	}
+	// unsupported volume sources are reported by the unmapped fields walker
+	c.unmapped("%s: volume %q isn't converted", fieldPath, vm.Name)
+	return nil, nil
+}
+
+// convertConfigMapVolumeMount mounts each key of the config map as a text file
+func (c *converter) convertConfigMapVolumeMount(vol *corev1.Volume, vm corev1.VolumeMount, fieldPath string, configMaps map[string]*corev1.ConfigMap) []*apis.ContainerVolumeMount {
+	cm, ok := configMaps[vol.ConfigMap.Name]
+	if !ok {
+		c.unmapped("%s: ConfigMap %q isn't found in manifest", fieldPath, vol.ConfigMap.Name)
+		return nil
+	}
+	// key to the relative path in the volume
+	files := make(map[string]string)
+	if len(vol.ConfigMap.Items) > 0 {
+		for _, item := range vol.ConfigMap.Items {
+			files[item.Path] = item.Key
+		}
+	} else {
+		for key := range cm.Data {
+			files[key] = key
+		}
+	}
+	ret := make([]*apis.ContainerVolumeMount, 0)
+	if vm.SubPath != "" {
+		key, ok := files[vm.SubPath]
+		if !ok {
+			c.unmapped("%s.subPath: %q isn't found in ConfigMap %s", fieldPath, vm.SubPath, cm.Name)
+			return nil
+		}
+		return append(ret, newTextVolumeMount(cm.Data[key], vm.MountPath))
+	}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		content, ok := cm.Data[files[p]]
+		if !ok {
+			c.unmapped("%s: key %q isn't found in ConfigMap %s", fieldPath, files[p], cm.Name)
+			continue
+		}
+		ret = append(ret, newTextVolumeMount(content, path.Join(vm.MountPath, p)))
+	}
+	return ret
+}
@@ -0,0 +1,279 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"yunion.io/x/jsonutils"
+	"yunion.io/x/pkg/errors"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+var (
+	yamlDocSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+)
+
+// Convert converts Kubernetes Pod/Deployment manifest or docker-compose file to the pod create input,
+// the format is detected from the content if it's empty.
+func Convert(format computeapi.PodManifestFormat, content string) (*computeapi.PodConvertManifestOutput, error) {
+	docs, err := parseDocuments(content)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = detectFormat(docs)
+	}
+	switch format {
+	case computeapi.POD_MANIFEST_FORMAT_KUBERNETES:
+		return convertKubernetes(docs)
+	case computeapi.POD_MANIFEST_FORMAT_COMPOSE:
+		if len(docs) != 1 {
+			return nil, errors.Errorf("compose file should contain exactly one document, got %d", len(docs))
+		}
+		return convertCompose(docs[0])
+	}
+	return nil, errors.Wrapf(errors.ErrNotSupported, "manifest format %q", format)
+}
+
+func parseDocuments(content string) ([]map[string]interface{}, error) {
+	docs := make([]map[string]interface{}, 0)
+	for idx, seg := range yamlDocSeparator.Split(content, -1) {
+		if strings.TrimSpace(seg) == "" {
+			continue
+		}
+		obj, err := jsonutils.ParseYAML(seg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse yaml document %d", idx)
+		}
+		if obj == nil || obj == jsonutils.JSONNull {
+			continue
+		}
+		doc := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(obj.String()), &doc); err != nil {
+			return nil, errors.Wrapf(err, "document %d isn't an object", idx)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, errors.Wrap(errors.ErrEmpty, "manifest")
+	}
+	return docs, nil
+}
+
+func detectFormat(docs []map[string]interface{}) computeapi.PodManifestFormat {
+	for _, doc := range docs {
+		if _, ok := doc["services"]; ok {
+			return computeapi.POD_MANIFEST_FORMAT_COMPOSE
+		}
+	}
+	return computeapi.POD_MANIFEST_FORMAT_KUBERNETES
+}
+
+// supportedFields describes which fields can be converted, the key is the field
+// pattern with '[]' standing for any array index and '*' for any map key.
+// true means the whole subtree is converted, false means only the listed children are converted.
+type supportedFields map[string]bool
+
+func (sf supportedFields) add(prefix string, subtree bool, paths ...string) supportedFields {
+	for _, p := range paths {
+		key := p
+		if prefix != "" {
+			key = prefix + "." + p
+		}
+		sf[key] = subtree
+		// mark all the parents as partially supported
+		for i := len(key) - 1; i > 0; i-- {
+			if key[i] == '.' {
+				parent := strings.TrimSuffix(key[:i], "[]")
+				if _, ok := sf[parent]; !ok {
+					sf[parent] = false
+				}
+			}
+		}
+	}
+	return sf
+}
+
+func (sf supportedFields) lookup(pattern, key string) (string, bool, bool) {
+	for _, k := range []string{key, "*"} {
+		p := k
+		if pattern != "" {
+			p = pattern + "." + k
+		}
+		if subtree, ok := sf[p]; ok {
+			return p, subtree, true
+		}
+	}
+	return "", false, false
+}
+
+// findUnmapped walks the object and returns the paths of the fields which aren't supported
+func (sf supportedFields) findUnmapped(obj interface{}, path, pattern string) []string {
+	ret := make([]string, 0)
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			subPath := k
+			if path != "" {
+				subPath = path + "." + k
+			}
+			subPattern, subtree, ok := sf.lookup(pattern, k)
+			if !ok {
+				if !isEmptyValue(v[k]) {
+					ret = append(ret, subPath)
+				}
+				continue
+			}
+			if subtree {
+				continue
+			}
+			ret = append(ret, sf.findUnmapped(v[k], subPath, subPattern)...)
+		}
+	case []interface{}:
+		for i := range v {
+			ret = append(ret, sf.findUnmapped(v[i], fmt.Sprintf("%s[%d]", path, i), pattern+"[]")...)
+		}
+	}
+	return ret
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(val) == 0
+	case []interface{}:
+		return len(val) == 0
+	case string:
+		return val == ""
+	}
+	return false
+}
+
+type converter struct {
+	output *computeapi.PodConvertManifestOutput
+	// cpu limit in milli cores
+	cpuMilli int64
+	memMB    int64
+}
+
+func newConverter(format computeapi.PodManifestFormat) *converter {
+	return &converter{
+		output: &computeapi.PodConvertManifestOutput{
+			Format: format,
+			Pod: &computeapi.PodCreateInput{
+				Containers: make([]*computeapi.PodContainerCreateInput, 0),
+			},
+			PortMappings: make(computeapi.GuestPortMappings, 0),
+			Unmapped:     make([]string, 0),
+		},
+	}
+}
+
+func (c *converter) unmapped(format string, args ...interface{}) {
+	c.output.Unmapped = append(c.output.Unmapped, fmt.Sprintf(format, args...))
+}
+
+func (c *converter) addPortMapping(proto computeapi.GuestPortMappingProtocol, containerPort int, hostPort int, hostIp string) {
+	pm := &computeapi.GuestPortMapping{
+		Protocol: proto,
+		Port:     containerPort,
+		HostIp:   hostIp,
+	}
+	if hostPort > 0 {
+		pm.HostPort = &hostPort
+	}
+	c.output.PortMappings = append(c.output.PortMappings, pm)
+}
+
+func (c *converter) addResources(cpuMilli int64, memMB int64) {
+	c.cpuMilli += cpuMilli
+	c.memMB += memMB
+}
+
+func (c *converter) finish() *computeapi.PodConvertManifestOutput {
+	if c.cpuMilli > 0 {
+		c.output.VcpuCount = int((c.cpuMilli + 999) / 1000)
+	}
+	c.output.VmemSize = int(c.memMB)
+	sort.Strings(c.output.Unmapped)
+	return c.output
+}
+
+func newContainerInput(name string) *computeapi.PodContainerCreateInput {
+	return &computeapi.PodContainerCreateInput{
+		Name: name,
+		ContainerSpec: computeapi.ContainerSpec{
+			ContainerSpec: apis.ContainerSpec{
+				Envs: make([]*apis.ContainerKeyValue, 0),
+			},
+			VolumeMounts: make([]*apis.ContainerVolumeMount, 0),
+		},
+	}
+}
+
+// newEmptyDirVolumeMount uses the sub directory of the first disk of the pod as the volume
+func newEmptyDirVolumeMount(volName string, subPath string, mountPath string, readOnly bool) *apis.ContainerVolumeMount {
+	idx := 0
+	subDir := volName
+	if subPath != "" {
+		subDir = volName + "/" + strings.Trim(subPath, "/")
+	}
+	return &apis.ContainerVolumeMount{
+		Type: apis.CONTAINER_VOLUME_MOUNT_TYPE_DISK,
+		Disk: &apis.ContainerVolumeMountDisk{
+			Index:        &idx,
+			SubDirectory: subDir,
+		},
+		MountPath: mountPath,
+		ReadOnly:  readOnly,
+	}
+}
+
+func newHostPathVolumeMount(hostPath string, typ apis.ContainerVolumeMountHostPathType, mountPath string, readOnly bool) *apis.ContainerVolumeMount {
+	return &apis.ContainerVolumeMount{
+		Type: apis.CONTAINER_VOLUME_MOUNT_TYPE_HOST_PATH,
+		HostPath: &apis.ContainerVolumeMountHostPath{
+			Type: typ,
+			Path: hostPath,
+		},
+		MountPath: mountPath,
+		ReadOnly:  readOnly,
+	}
+}
+
+func newTextVolumeMount(content string, mountPath string) *apis.ContainerVolumeMount {
+	return &apis.ContainerVolumeMount{
+		Type: apis.CONTAINER_VOLUME_MOUNT_TYPE_TEXT,
+		Text: &apis.ContainerVolumeMountText{
+			Content: content,
+		},
+		MountPath: mountPath,
+		ReadOnly:  true,
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"yunion.io/x/onecloud/pkg/apis"
+	computeapi "yunion.io/x/onecloud/pkg/apis/compute"
+)
+
+func convertFixture(t *testing.T, name string) *computeapi.PodConvertManifestOutput {
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
+	}
+	out, err := Convert("", string(content))
+	if err != nil {
+		t.Fatalf("convert %s: %v", name, err)
+	}
+	return out
+}
+
+func assertEqual(t *testing.T, field string, got, want interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got %#v, want %#v", field, got, want)
+	}
+}
+
+func TestConvertKubernetesPod(t *testing.T) {
+	out := convertFixture(t, "pod.yaml")
+	assertEqual(t, "format", out.Format, computeapi.POD_MANIFEST_FORMAT_KUBERNETES)
+	assertEqual(t, "name", out.Name, "web")
+	assertEqual(t, "vcpu_count", out.VcpuCount, 2)
+	assertEqual(t, "vmem_size", out.VmemSize, 512)
+	if len(out.Pod.InitContainers) != 1 || out.Pod.InitContainers[0].Image != "busybox:1.36" {
+		t.Fatalf("init containers: %#v", out.Pod.InitContainers)
+	}
+	if len(out.Pod.Containers) != 1 {
+		t.Fatalf("containers: %#v", out.Pod.Containers)
+	}
+	ctr := out.Pod.Containers[0]
+	assertEqual(t, "image_pull_policy", ctr.ImagePullPolicy, apis.ImagePullPolicy(apis.ImagePullPolicyIfNotPresent))
+	assertEqual(t, "always_restart", ctr.AlwaysRestart, true)
+	assertEqual(t, "envs", ctr.Envs, []*apis.ContainerKeyValue{{Key: "MODE", Value: "production"}})
+	assertEqual(t, "cpu_cfs_quota", *ctr.ResourcesLimit.CpuCfsQuota, 1.5)
+	assertEqual(t, "termination_grace_period", ctr.Lifecyle.TerminationGracePeriodSeconds, int64(60))
+	assertEqual(t, "pre_stop port", ctr.Lifecyle.PreStop.HTTPGet.Port, 80)
+	assertEqual(t, "startup_probe port", ctr.StartupProbe.TCPSocket.Port, 80)
+
+	if len(ctr.VolumeMounts) != 2 {
+		t.Fatalf("volume mounts: %#v", ctr.VolumeMounts)
+	}
+	assertEqual(t, "empty_dir sub_directory", ctr.VolumeMounts[0].Disk.SubDirectory, "cache")
+	assertEqual(t, "host_path", ctr.VolumeMounts[1].HostPath.Path, "/var/log/web")
+
+	hostPort := 8080
+	assertEqual(t, "port_mappings", out.PortMappings, computeapi.GuestPortMappings{
+		{Protocol: computeapi.GuestPortMappingProtocolTCP, Port: 80, HostPort: &hostPort},
+		{Protocol: computeapi.GuestPortMappingProtocolUDP, Port: 53},
+	})
+	assertEqual(t, "unmapped", out.Unmapped, []string{
+		"metadata.namespace",
+		"spec.containers[0].env[1].valueFrom",
+		"spec.containers[0].livenessProbe",
+	})
+}
+
+func TestConvertKubernetesDeployment(t *testing.T) {
+	out := convertFixture(t, "deployment.yaml")
+	assertEqual(t, "name", out.Name, "app")
+	assertEqual(t, "count", out.Count, 3)
+	// requests of app and limits of sidecar
+	assertEqual(t, "vcpu_count", out.VcpuCount, 2)
+	assertEqual(t, "vmem_size", out.VmemSize, 256+1024)
+	assertEqual(t, "run_as_user", *out.Pod.SecurityContext.RunAsUser, int64(1000))
+	if len(out.Pod.Containers) != 2 {
+		t.Fatalf("containers: %#v", out.Pod.Containers)
+	}
+	app := out.Pod.Containers[0]
+	assertEqual(t, "capabilities", app.Capabilities.Add, []string{"NET_ADMIN"})
+	mounts := make(map[string]string)
+	for _, vm := range app.VolumeMounts {
+		if vm.Type != apis.CONTAINER_VOLUME_MOUNT_TYPE_TEXT {
+			t.Fatalf("volume mount type of %s: %s", vm.MountPath, vm.Type)
+		}
+		mounts[vm.MountPath] = vm.Text.Content
+	}
+	assertEqual(t, "config mounts", mounts, map[string]string{
+		"/etc/app/app.conf": "listen 8000\n",
+		"/etc/app/log.conf": "level info\n",
+		"/etc/log.conf":     "level info\n",
+	})
+	assertEqual(t, "unmapped", out.Unmapped, []string{
+		"document 2: unsupported kind \"Service\"",
+		"spec.strategy",
+	})
+}
+
+func TestConvertCompose(t *testing.T) {
+	out := convertFixture(t, "compose.yaml")
+	assertEqual(t, "format", out.Format, computeapi.POD_MANIFEST_FORMAT_COMPOSE)
+	if len(out.Pod.Containers) != 2 {
+		t.Fatalf("containers: %#v", out.Pod.Containers)
+	}
+	// services are sorted by name
+	db, web := out.Pod.Containers[0], out.Pod.Containers[1]
+	assertEqual(t, "vcpu_count", out.VcpuCount, 2)
+	assertEqual(t, "vmem_size", out.VmemSize, 1024+256)
+
+	assertEqual(t, "db command", db.Command, []string{"docker-entrypoint.sh"})
+	assertEqual(t, "db args", db.Args, []string{"postgres", "-c", "max_connections=200"})
+	assertEqual(t, "db envs", db.Envs, []*apis.ContainerKeyValue{{Key: "POSTGRES_PASSWORD", Value: "secret"}})
+	assertEqual(t, "db run_as_user", *db.SecurityContext.RunAsUser, int64(999))
+	assertEqual(t, "db run_as_group", *db.SecurityContext.RunAsGroup, int64(999))
+	assertEqual(t, "db shm_size", db.ShmSizeMB, 128)
+	assertEqual(t, "db termination_grace_period", db.Lifecyle.TerminationGracePeriodSeconds, int64(90))
+
+	assertEqual(t, "web always_restart", web.AlwaysRestart, true)
+	assertEqual(t, "web envs", web.Envs, []*apis.ContainerKeyValue{
+		{Key: "MODE", Value: "production"},
+		{Key: "WORKERS", Value: "4"},
+	})
+	assertEqual(t, "web cpu_cfs_quota", *web.ResourcesLimit.CpuCfsQuota, 0.5)
+	if len(web.VolumeMounts) != 3 {
+		t.Fatalf("web volume mounts: %#v", web.VolumeMounts)
+	}
+	assertEqual(t, "named volume", web.VolumeMounts[0].Disk.SubDirectory, "data")
+	assertEqual(t, "named volume read_only", web.VolumeMounts[0].ReadOnly, true)
+	assertEqual(t, "bind volume", web.VolumeMounts[1].HostPath.Path, "/var/log/web")
+	assertEqual(t, "config", web.VolumeMounts[2].Text.Content, "worker_processes 1;\n")
+
+	p8080, p8443, p5353 := 8080, 8443, 5353
+	assertEqual(t, "port_mappings", out.PortMappings, computeapi.GuestPortMappings{
+		{Protocol: computeapi.GuestPortMappingProtocolTCP, Port: 80, HostPort: &p8080},
+		{Protocol: computeapi.GuestPortMappingProtocolTCP, Port: 443, HostPort: &p8443, HostIp: "127.0.0.1"},
+		{Protocol: computeapi.GuestPortMappingProtocolUDP, Port: 53, HostPort: &p5353},
+	})
+	assertEqual(t, "unmapped", out.Unmapped, []string{
+		"networks",
+		"services.db.environment: POSTGRES_USER",
+		"services.web.depends_on",
+		"services.web.deploy.replicas",
+		"services.web.healthcheck",
+		"services.web.volumes[2]: relative bind source ./conf",
+	})
+}
+
+func TestConvertErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		format  computeapi.PodManifestFormat
+		content string
+	}{
+		{"empty", "", "---\n"},
+		{"no workload", computeapi.POD_MANIFEST_FORMAT_KUBERNETES, "kind: Service\nmetadata:\n  name: svc\n"},
+		{"no image", computeapi.POD_MANIFEST_FORMAT_COMPOSE, "services:\n  web:\n    restart: always\n"},
+		{"unknown format", "helm", "kind: Pod\n"},
+	}
+	for _, c := range cases {
+		if _, err := Convert(c.format, c.content); err == nil {
+			t.Errorf("%s: expect error", c.name)
+		}
+	}
+}
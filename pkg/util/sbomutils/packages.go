@@ -0,0 +1,148 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbomutils
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strings"
+)
+
+const (
+	PACKAGE_TYPE_RPM     = "rpm"
+	PACKAGE_TYPE_DEB     = "deb"
+	PACKAGE_TYPE_APK     = "apk"
+	PACKAGE_TYPE_WINDOWS = "windows"
+)
+
+// RPM_QUERY_FORMAT is the rpm --queryformat understood by ParseRpmQueryOutput
+const RPM_QUERY_FORMAT = `%{NAME}\t%{EPOCHNUM}:%{VERSION}-%{RELEASE}\t%{ARCH}\n`
+
+type SPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+	Type    string `json:"type"`
+}
+
+// parseStanzas splits control-file style data into stanzas separated by
+// blank lines, continuation lines are folded into the previous field
+func parseStanzas(data []byte, sep string) []map[string]string {
+	stanzas := []map[string]string{}
+	cur := map[string]string{}
+	lastKey := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(strings.TrimSpace(line)) == 0 {
+			if len(cur) > 0 {
+				stanzas = append(stanzas, cur)
+				cur = map[string]string{}
+			}
+			lastKey = ""
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(lastKey) > 0 {
+				cur[lastKey] += "\n" + strings.TrimSpace(line)
+			}
+			continue
+		}
+		idx := strings.Index(line, sep)
+		if idx <= 0 {
+			continue
+		}
+		lastKey = line[:idx]
+		cur[lastKey] = strings.TrimSpace(line[idx+len(sep):])
+	}
+	if len(cur) > 0 {
+		stanzas = append(stanzas, cur)
+	}
+	return stanzas
+}
+
+// ParseDpkgStatus parses /var/lib/dpkg/status, only packages in the
+// installed state are returned
+func ParseDpkgStatus(data []byte) []SPackage {
+	pkgs := []SPackage{}
+	for _, st := range parseStanzas(data, ":") {
+		name := st["Package"]
+		if len(name) == 0 {
+			continue
+		}
+		if status, ok := st["Status"]; ok && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+		pkgs = append(pkgs, SPackage{
+			Name:    name,
+			Version: st["Version"],
+			Arch:    st["Architecture"],
+			Type:    PACKAGE_TYPE_DEB,
+		})
+	}
+	SortPackages(pkgs)
+	return pkgs
+}
+
+// ParseApkInstalled parses the alpine package database /lib/apk/db/installed
+func ParseApkInstalled(data []byte) []SPackage {
+	pkgs := []SPackage{}
+	for _, st := range parseStanzas(data, ":") {
+		name := st["P"]
+		if len(name) == 0 {
+			continue
+		}
+		pkgs = append(pkgs, SPackage{
+			Name:    name,
+			Version: st["V"],
+			Arch:    st["A"],
+			Type:    PACKAGE_TYPE_APK,
+		})
+	}
+	SortPackages(pkgs)
+	return pkgs
+}
+
+// ParseRpmQueryOutput parses output of rpm -qa --qf RPM_QUERY_FORMAT, a zero
+// epoch is dropped from the version
+func ParseRpmQueryOutput(data []byte) []SPackage {
+	pkgs := []SPackage{}
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.Split(strings.TrimSpace(line), "\t")
+		if len(parts) != 3 || len(parts[0]) == 0 || strings.HasPrefix(parts[0], "gpg-pubkey") {
+			continue
+		}
+		version := strings.TrimPrefix(parts[1], "0:")
+		pkgs = append(pkgs, SPackage{
+			Name:    parts[0],
+			Version: version,
+			Arch:    parts[2],
+			Type:    PACKAGE_TYPE_RPM,
+		})
+	}
+	SortPackages(pkgs)
+	return pkgs
+}
+
+func SortPackages(pkgs []SPackage) {
+	sort.SliceStable(pkgs, func(i, j int) bool {
+		if pkgs[i].Name != pkgs[j].Name {
+			return pkgs[i].Name < pkgs[j].Name
+		}
+		return pkgs[i].Arch < pkgs[j].Arch
+	})
+}
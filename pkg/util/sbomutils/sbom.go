@@ -0,0 +1,198 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbomutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	FORMAT_SPDX      = "spdx"
+	FORMAT_CYCLONEDX = "cyclonedx"
+
+	SBOM_TOOL = "cloudpods-image-scan"
+)
+
+// SDocument describes the packages of an image
+type SDocument struct {
+	// Id is the uuid of the image
+	Id       string
+	Name     string
+	Distro   string
+	Version  string
+	Created  time.Time
+	Packages []SPackage
+}
+
+func (doc *SDocument) purlNamespace() string {
+	distro := strings.ToLower(strings.TrimSpace(doc.Distro))
+	return url.PathEscape(strings.ReplaceAll(distro, " ", "-"))
+}
+
+// Purl returns the package url of pkg, empty for packages without purl
+// type such as windows programs
+func (doc *SDocument) Purl(pkg SPackage) string {
+	switch pkg.Type {
+	case PACKAGE_TYPE_RPM, PACKAGE_TYPE_DEB, PACKAGE_TYPE_APK:
+	default:
+		return ""
+	}
+	purl := "pkg:" + pkg.Type + "/"
+	if ns := doc.purlNamespace(); len(ns) > 0 {
+		purl += ns + "/"
+	}
+	purl += url.PathEscape(pkg.Name) + "@" + url.PathEscape(pkg.Version)
+	if len(pkg.Arch) > 0 {
+		purl += "?arch=" + url.QueryEscape(pkg.Arch)
+	}
+	return purl
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxRelationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+type spdxDocument struct {
+	SpdxVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	SPDXID            string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	Packages      []spdxPackage      `json:"packages"`
+	Relationships []spdxRelationship `json:"relationships"`
+}
+
+// SPDX renders the document in SPDX 2.3 json
+func (doc *SDocument) SPDX() ([]byte, error) {
+	out := spdxDocument{
+		SpdxVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              doc.Name,
+		DocumentNamespace: fmt.Sprintf("https://www.cloudpods.org/spdx/images/%s-%d", doc.Id, doc.Created.Unix()),
+		Packages:          make([]spdxPackage, 0, len(doc.Packages)),
+		Relationships:     make([]spdxRelationship, 0, len(doc.Packages)),
+	}
+	out.CreationInfo.Created = doc.Created.UTC().Format(time.RFC3339)
+	out.CreationInfo.Creators = []string{"Tool: " + SBOM_TOOL}
+	for i, pkg := range doc.Packages {
+		p := spdxPackage{
+			Name:             pkg.Name,
+			SPDXID:           fmt.Sprintf("SPDXRef-Package-%d", i+1),
+			VersionInfo:      pkg.Version,
+			DownloadLocation: "NOASSERTION",
+		}
+		if purl := doc.Purl(pkg); len(purl) > 0 {
+			p.ExternalRefs = []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl,
+			}}
+		}
+		out.Packages = append(out.Packages, p)
+		out.Relationships = append(out.Relationships, spdxRelationship{
+			SpdxElementId:      out.SPDXID,
+			RelationshipType:   "DESCRIBES",
+			RelatedSpdxElement: p.SPDXID,
+		})
+	}
+	return json.Marshal(out)
+}
+
+type cdxComponent struct {
+	Type    string `json:"type"`
+	BomRef  string `json:"bom-ref,omitempty"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Purl    string `json:"purl,omitempty"`
+}
+
+type cdxDocument struct {
+	BomFormat    string `json:"bomFormat"`
+	SpecVersion  string `json:"specVersion"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	Version      int    `json:"version"`
+	Metadata     struct {
+		Timestamp string `json:"timestamp"`
+		Tools     []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+		Component cdxComponent `json:"component"`
+	} `json:"metadata"`
+	Components []cdxComponent `json:"components"`
+}
+
+// CycloneDX renders the document in CycloneDX 1.4 json
+func (doc *SDocument) CycloneDX() ([]byte, error) {
+	out := cdxDocument{
+		BomFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		Version:     1,
+		Components:  make([]cdxComponent, 0, len(doc.Packages)),
+	}
+	if len(doc.Id) > 0 {
+		out.SerialNumber = "urn:uuid:" + doc.Id
+	}
+	out.Metadata.Timestamp = doc.Created.UTC().Format(time.RFC3339)
+	out.Metadata.Tools = []struct {
+		Name string `json:"name"`
+	}{{Name: SBOM_TOOL}}
+	out.Metadata.Component = cdxComponent{
+		Type:    "operating-system",
+		Name:    doc.Name,
+		Version: doc.Version,
+	}
+	for _, pkg := range doc.Packages {
+		c := cdxComponent{
+			Type:    "library",
+			Name:    pkg.Name,
+			Version: pkg.Version,
+			Purl:    doc.Purl(pkg),
+		}
+		if len(c.Purl) > 0 {
+			c.BomRef = c.Purl
+		} else {
+			c.Type = "application"
+			c.BomRef = fmt.Sprintf("%s/%s@%s", pkg.Type, pkg.Name, pkg.Version)
+		}
+		out.Components = append(out.Components, c)
+	}
+	return json.Marshal(out)
+}
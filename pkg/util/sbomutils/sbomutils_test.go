@@ -0,0 +1,188 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbomutils
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDpkgStatus(t *testing.T) {
+	data := `Package: openssl
+Status: install ok installed
+Architecture: amd64
+Version: 1.1.1f-1ubuntu2.16
+Description: Secure Sockets Layer toolkit
+ This package contains the openssl binary.
+
+Package: removed
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0
+
+Package: bash
+Status: install ok installed
+Architecture: amd64
+Version: 5.0-6ubuntu1.2
+`
+	want := []SPackage{
+		{Name: "bash", Version: "5.0-6ubuntu1.2", Arch: "amd64", Type: PACKAGE_TYPE_DEB},
+		{Name: "openssl", Version: "1.1.1f-1ubuntu2.16", Arch: "amd64", Type: PACKAGE_TYPE_DEB},
+	}
+	if got := ParseDpkgStatus([]byte(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v want %#v", got, want)
+	}
+}
+
+func TestParseApkInstalled(t *testing.T) {
+	data := `C:Q1abc=
+P:musl
+V:1.2.3-r4
+A:x86_64
+
+C:Q1def=
+P:busybox
+V:1.35.0-r17
+A:x86_64
+`
+	want := []SPackage{
+		{Name: "busybox", Version: "1.35.0-r17", Arch: "x86_64", Type: PACKAGE_TYPE_APK},
+		{Name: "musl", Version: "1.2.3-r4", Arch: "x86_64", Type: PACKAGE_TYPE_APK},
+	}
+	if got := ParseApkInstalled([]byte(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v want %#v", got, want)
+	}
+}
+
+func TestParseRpmQueryOutput(t *testing.T) {
+	data := "openssl-libs\t1:1.0.2k-25.el7_9\tx86_64\nbash\t0:4.2.46-35.el7_9\tx86_64\ngpg-pubkey\t0:f4a80eb5-53a7ff4b\t(none)\n\n"
+	want := []SPackage{
+		{Name: "bash", Version: "4.2.46-35.el7_9", Arch: "x86_64", Type: PACKAGE_TYPE_RPM},
+		{Name: "openssl-libs", Version: "1:1.0.2k-25.el7_9", Arch: "x86_64", Type: PACKAGE_TYPE_RPM},
+	}
+	if got := ParseRpmQueryOutput([]byte(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v want %#v", got, want)
+	}
+}
+
+func TestCompareVersion(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.01", "1.1", 0},
+		{"1.0a", "1.0", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.1.1f-1ubuntu2.16", "1.1.1f-1ubuntu2.17", -1},
+		{"1.0.2k-25.el7_9", "1.0.2k-19.el7", 1},
+		{"1:1.0", "2.0", 1},
+		{"1.2.3-r4", "1.2.3-r10", -1},
+		{"1.2.3-r4", "1.2.3", 0},
+		{"2.0", "2.0a", -1},
+		{"1.0.1", "1.0a", 1},
+	}
+	for _, c := range cases {
+		if got := CompareVersion(c.a, c.b); got != c.want {
+			t.Errorf("CompareVersion(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := CompareVersion(c.b, c.a); got != -c.want {
+			t.Errorf("CompareVersion(%q, %q) = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+}
+
+func TestVulnerabilityFeedMatch(t *testing.T) {
+	feed, err := ParseVulnerabilityFeed([]byte(`{"vulnerabilities": [
+		{"id": "CVE-1", "package": "openssl", "fixed_version": "1.1.1f-1ubuntu2.17", "severity": "HIGH"},
+		{"id": "CVE-2", "package": "openssl", "type": "rpm", "fixed_version": "9.9", "severity": "critical"},
+		{"id": "CVE-3", "package": "bash", "fixed_version": "5.0-6ubuntu1.2", "severity": "critical"},
+		{"id": "CVE-4", "package": "bash", "severity": "low"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs := []SPackage{
+		{Name: "bash", Version: "5.0-6ubuntu1.2", Type: PACKAGE_TYPE_DEB},
+		{Name: "openssl", Version: "1.1.1f-1ubuntu2.16", Type: PACKAGE_TYPE_DEB},
+	}
+	findings := feed.Match(pkgs)
+	ids := []string{}
+	for _, f := range findings {
+		ids = append(ids, f.Id)
+	}
+	if want := []string{"CVE-1", "CVE-4"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("findings %v, want %v", ids, want)
+	}
+	if max := MaxSeverity(findings); max != SEVERITY_HIGH {
+		t.Errorf("max severity %q", max)
+	}
+	if sevs := SeveritiesAtLeast(SEVERITY_HIGH); !reflect.DeepEqual(sevs, []string{SEVERITY_HIGH, SEVERITY_CRITICAL}) {
+		t.Errorf("severities at least high %v", sevs)
+	}
+	if _, err := ParseVulnerabilityFeed([]byte(`{"vulnerabilities": [{"id": "CVE-1"}]}`)); err == nil {
+		t.Errorf("expect error of entry without package")
+	}
+}
+
+func TestSbom(t *testing.T) {
+	doc := &SDocument{
+		Id:      "4f0a3c2e-6d0b-4b5e-8c53-1a0c6f8e9d10",
+		Name:    "ubuntu",
+		Distro:  "Ubuntu",
+		Version: "20.04",
+		Created: time.Unix(1700000000, 0),
+		Packages: []SPackage{
+			{Name: "openssl", Version: "1.1.1f-1ubuntu2.16", Arch: "amd64", Type: PACKAGE_TYPE_DEB},
+			{Name: "Mozilla Firefox", Version: "115.0", Type: PACKAGE_TYPE_WINDOWS},
+		},
+	}
+	if purl := doc.Purl(doc.Packages[0]); purl != "pkg:deb/ubuntu/openssl@1.1.1f-1ubuntu2.16?arch=amd64" {
+		t.Errorf("purl %s", purl)
+	}
+
+	data, err := doc.SPDX()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spdx := map[string]interface{}{}
+	if err := json.Unmarshal(data, &spdx); err != nil {
+		t.Fatal(err)
+	}
+	if spdx["spdxVersion"] != "SPDX-2.3" || len(spdx["packages"].([]interface{})) != 2 {
+		t.Errorf("spdx %s", data)
+	}
+	if !strings.Contains(string(data), `"referenceLocator":"pkg:deb/ubuntu/openssl@`) {
+		t.Errorf("spdx without purl: %s", data)
+	}
+
+	data, err = doc.CycloneDX()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdx := map[string]interface{}{}
+	if err := json.Unmarshal(data, &cdx); err != nil {
+		t.Fatal(err)
+	}
+	if cdx["bomFormat"] != "CycloneDX" || cdx["serialNumber"] != "urn:uuid:"+doc.Id || len(cdx["components"].([]interface{})) != 2 {
+		t.Errorf("cyclonedx %s", data)
+	}
+}
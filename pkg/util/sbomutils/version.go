@@ -0,0 +1,136 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbomutils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// splitVersion splits a [epoch:]upstream[-release] version string
+func splitVersion(v string) (int, string, string) {
+	epoch := 0
+	if idx := strings.Index(v, ":"); idx > 0 {
+		if e, err := strconv.Atoi(v[:idx]); err == nil {
+			epoch = e
+			v = v[idx+1:]
+		}
+	}
+	release := ""
+	if idx := strings.LastIndex(v, "-"); idx > 0 {
+		release = v[idx+1:]
+		v = v[:idx]
+	}
+	return epoch, v, release
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func trimSeparators(s string) string {
+	i := 0
+	for i < len(s) && !isDigit(s[i]) && !isAlpha(s[i]) && s[i] != '~' {
+		i++
+	}
+	return s[i:]
+}
+
+// compareSegments compares alternating numeric and alphabetic segments in
+// the way of rpmvercmp, a tilde sorts before anything, even the end
+func compareSegments(a, b string) int {
+	for {
+		a, b = trimSeparators(a), trimSeparators(b)
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if len(a) == 0 || len(b) == 0 {
+			break
+		}
+		numeric := isDigit(a[0])
+		match := isAlpha
+		if numeric {
+			match = isDigit
+		}
+		i, j := 0, 0
+		for i < len(a) && match(a[i]) {
+			i++
+		}
+		for j < len(b) && match(b[j]) {
+			j++
+		}
+		if j == 0 {
+			// numeric segment is newer than alphabetic one
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+		sa, sb := a[:i], b[:j]
+		a, b = a[i:], b[j:]
+		if numeric {
+			sa, sb = strings.TrimLeft(sa, "0"), strings.TrimLeft(sb, "0")
+			if len(sa) != len(sb) {
+				if len(sa) > len(sb) {
+					return 1
+				}
+				return -1
+			}
+		}
+		if c := strings.Compare(sa, sb); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// CompareVersion compares two package versions of rpm, deb or apk style,
+// returns -1, 0 or 1
+func CompareVersion(a, b string) int {
+	ea, va, ra := splitVersion(a)
+	eb, vb, rb := splitVersion(b)
+	if ea != eb {
+		if ea > eb {
+			return 1
+		}
+		return -1
+	}
+	if c := compareSegments(va, vb); c != 0 {
+		return c
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		// release not given means any release
+		return 0
+	}
+	return compareSegments(ra, rb)
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbomutils
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+)
+
+const (
+	SEVERITY_NONE     = ""
+	SEVERITY_LOW      = "low"
+	SEVERITY_MEDIUM   = "medium"
+	SEVERITY_HIGH     = "high"
+	SEVERITY_CRITICAL = "critical"
+)
+
+var severityRanks = map[string]int{
+	SEVERITY_LOW:      1,
+	SEVERITY_MEDIUM:   2,
+	SEVERITY_HIGH:     3,
+	SEVERITY_CRITICAL: 4,
+}
+
+// SeverityRank orders severities from none (0) to critical (4)
+func SeverityRank(severity string) int {
+	return severityRanks[strings.ToLower(severity)]
+}
+
+func IsValidSeverity(severity string) bool {
+	_, ok := severityRanks[strings.ToLower(severity)]
+	return ok
+}
+
+// SeveritiesAtLeast returns severities not lower than severity
+func SeveritiesAtLeast(severity string) []string {
+	rank := SeverityRank(severity)
+	ret := []string{}
+	for _, sev := range []string{SEVERITY_LOW, SEVERITY_MEDIUM, SEVERITY_HIGH, SEVERITY_CRITICAL} {
+		if severityRanks[sev] >= rank {
+			ret = append(ret, sev)
+		}
+	}
+	return ret
+}
+
+// SVulnerability is an entry of the offline vulnerability feed.  Versions
+// of Package lower than FixedVersion are affected, an empty FixedVersion
+// means all versions are affected.  Type restricts the entry to a package
+// type if given
+type SVulnerability struct {
+	Id           string `json:"id"`
+	Package      string `json:"package"`
+	Type         string `json:"type,omitempty"`
+	FixedVersion string `json:"fixed_version,omitempty"`
+	Severity     string `json:"severity"`
+	Description  string `json:"description,omitempty"`
+}
+
+type SVulnerabilityFeed struct {
+	Vulnerabilities []SVulnerability `json:"vulnerabilities"`
+
+	byPackage map[string][]*SVulnerability
+}
+
+type SFinding struct {
+	Id           string `json:"id"`
+	Package      string `json:"package"`
+	Version      string `json:"version"`
+	FixedVersion string `json:"fixed_version,omitempty"`
+	Severity     string `json:"severity"`
+	Description  string `json:"description,omitempty"`
+}
+
+func ParseVulnerabilityFeed(data []byte) (*SVulnerabilityFeed, error) {
+	feed := &SVulnerabilityFeed{}
+	if err := json.Unmarshal(data, feed); err != nil {
+		return nil, errors.Wrap(err, "unmarshal vulnerability feed")
+	}
+	feed.byPackage = map[string][]*SVulnerability{}
+	for i := range feed.Vulnerabilities {
+		v := &feed.Vulnerabilities[i]
+		if len(v.Id) == 0 || len(v.Package) == 0 {
+			return nil, errors.Wrapf(errors.ErrInvalidFormat, "vulnerability entry %d without id or package", i)
+		}
+		v.Severity = strings.ToLower(v.Severity)
+		feed.byPackage[v.Package] = append(feed.byPackage[v.Package], v)
+	}
+	return feed, nil
+}
+
+func LoadVulnerabilityFeed(fn string) (*SVulnerabilityFeed, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s", fn)
+	}
+	return ParseVulnerabilityFeed(data)
+}
+
+// Match returns findings of packages affected by the feed, ordered by
+// severity from critical to low
+func (feed *SVulnerabilityFeed) Match(pkgs []SPackage) []SFinding {
+	findings := []SFinding{}
+	for _, pkg := range pkgs {
+		for _, v := range feed.byPackage[pkg.Name] {
+			if len(v.Type) > 0 && v.Type != pkg.Type {
+				continue
+			}
+			if len(v.FixedVersion) > 0 && CompareVersion(pkg.Version, v.FixedVersion) >= 0 {
+				continue
+			}
+			findings = append(findings, SFinding{
+				Id:           v.Id,
+				Package:      pkg.Name,
+				Version:      pkg.Version,
+				FixedVersion: v.FixedVersion,
+				Severity:     v.Severity,
+				Description:  v.Description,
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		ri, rj := SeverityRank(findings[i].Severity), SeverityRank(findings[j].Severity)
+		if ri != rj {
+			return ri > rj
+		}
+		if findings[i].Id != findings[j].Id {
+			return findings[i].Id < findings[j].Id
+		}
+		return findings[i].Package < findings[j].Package
+	})
+	return findings
+}
+
+func MaxSeverity(findings []SFinding) string {
+	max := SEVERITY_NONE
+	for _, f := range findings {
+		if SeverityRank(f.Severity) > SeverityRank(max) {
+			max = f.Severity
+		}
+	}
+	return max
+}
@@ -15,6 +15,7 @@
 package sysutils
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"regexp"
@@ -80,7 +81,16 @@ func fetchHugepageInfo(sizeKb int, dir string) (SHugepageInfo, error) {
 }
 
 func GetHugepages() (THugepages, error) {
-	const hugepageDir = "/sys/kernel/mm/hugepages"
+	return getHugepagesOfDir("/sys/kernel/mm/hugepages")
+}
+
+// GetNodeHugepages returns the hugepages of each page size reserved on the
+// numa node
+func GetNodeHugepages(nodeId int) (THugepages, error) {
+	return getHugepagesOfDir(fmt.Sprintf("/sys/devices/system/node/node%d/hugepages", nodeId))
+}
+
+func getHugepagesOfDir(hugepageDir string) (THugepages, error) {
 	files, err := ioutil.ReadDir(hugepageDir)
 	if err != nil {
 		return nil, errors.Wrapf(err, "ReadDir %s", hugepageDir)
@@ -759,3 +759,37 @@ func (w *SWinRegTool) ResetUSBProfile() {
 	key = w.GetCcsKeyPath() + `\Enum\USB`
 	w.DelRegistry(key)
 }
+
+type SInstalledProgram struct {
+	Name    string
+	Version string
+	Arch    string
+}
+
+// GetInstalledPrograms lists programs registered in the Uninstall keys,
+// entries without DisplayName such as updates are skipped
+func (w *SWinRegTool) GetInstalledPrograms() []SInstalledProgram {
+	ret := []SInstalledProgram{}
+	for _, uninstall := range []struct {
+		key  string
+		arch string
+	}{
+		{`HKLM\SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall`, ""},
+		{`HKLM\SOFTWARE\WOW6432Node\Microsoft\Windows\CurrentVersion\Uninstall`, "x86"},
+	} {
+		keys, _ := w.ListRegistry(uninstall.key)
+		for _, key := range keys {
+			prefix := uninstall.key + `\` + key + `\`
+			name := strings.TrimSpace(w.GetRegistry(prefix + "DisplayName"))
+			if len(name) == 0 {
+				continue
+			}
+			ret = append(ret, SInstalledProgram{
+				Name:    name,
+				Version: strings.TrimSpace(w.GetRegistry(prefix + "DisplayVersion")),
+				Arch:    uninstall.arch,
+			})
+		}
+	}
+	return ret
+}
@@ -54,6 +54,13 @@ type (
 	LoadbalancerNetworks  map[string]*LoadbalancerNetwork // key: networkId/loadbalancerId
 	LoadbalancerListeners map[string]*LoadbalancerListener
 	LoadbalancerAcls      map[string]*LoadbalancerAcl
+
+	VpcPeeringConnections map[string]*VpcPeeringConnection
+	FlowLogs              map[string]*FlowLog
+
+	NatGateways map[string]*NatGateway
+	NatSEntries map[string]*NatSEntry
+	NatDEntries map[string]*NatDEntry
 )
 
 func (set Vpcs) ModelManager() mcclient_modulebase.IBaseManager {
@@ -1139,3 +1146,239 @@ func (set LoadbalancerAcls) Copy() apihelper.IModelSet {
 	}
 	return setCopy
 }
+
+func (ms Vpcs) joinVpcPeeringConnections(subEntries VpcPeeringConnections) bool {
+	for _, m := range ms {
+		m.PeeringConnections = VpcPeeringConnections{}
+	}
+	correct := true
+	for _, subEntry := range subEntries {
+		m, ok := ms[subEntry.VpcId]
+		if !ok {
+			log.Warningf("vpc_id %s of vpc peering connection %s(%s) is not present", subEntry.VpcId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		peer, ok := ms[subEntry.PeerVpcId]
+		if !ok {
+			log.Warningf("peer_vpc_id %s of vpc peering connection %s(%s) is not present", subEntry.PeerVpcId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		subEntry.Vpc = m
+		subEntry.PeerVpc = peer
+		m.PeeringConnections[subEntry.Id] = subEntry
+		peer.PeeringConnections[subEntry.Id] = subEntry
+	}
+	return correct
+}
+
+func (set VpcPeeringConnections) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.VpcPeeringConnections
+}
+
+func (set VpcPeeringConnections) DBModelManager() db.IModelManager {
+	return models.VpcPeeringConnectionManager
+}
+
+func (set VpcPeeringConnections) NewModel() db.IModel {
+	return &VpcPeeringConnection{}
+}
+
+func (set VpcPeeringConnections) AddModel(i db.IModel) {
+	m := i.(*VpcPeeringConnection)
+	set[m.Id] = m
+}
+
+func (set VpcPeeringConnections) Copy() apihelper.IModelSet {
+	setCopy := VpcPeeringConnections{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms Vpcs) joinFlowLogs(subEntries FlowLogs) bool {
+	for _, m := range ms {
+		m.FlowLogs = FlowLogs{}
+	}
+	correct := true
+	for _, subEntry := range subEntries {
+		vpcId := subEntry.VpcId
+		m, ok := ms[vpcId]
+		if !ok {
+			log.Warningf("vpc_id %s of flow log %s(%s) is not present", vpcId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		subEntry.Vpc = m
+		m.FlowLogs[subEntry.Id] = subEntry
+	}
+	return correct
+}
+
+func (set FlowLogs) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.FlowLogs
+}
+
+func (set FlowLogs) DBModelManager() db.IModelManager {
+	return models.FlowLogManager
+}
+
+func (set FlowLogs) NewModel() db.IModel {
+	return &FlowLog{}
+}
+
+func (set FlowLogs) AddModel(i db.IModel) {
+	m := i.(*FlowLog)
+	set[m.Id] = m
+}
+
+func (set FlowLogs) Copy() apihelper.IModelSet {
+	setCopy := FlowLogs{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms Vpcs) joinNatGateways(subEntries NatGateways) bool {
+	for _, m := range ms {
+		m.NatGateways = NatGateways{}
+	}
+	correct := true
+	for _, subEntry := range subEntries {
+		vpcId := subEntry.VpcId
+		m, ok := ms[vpcId]
+		if !ok {
+			log.Warningf("vpc_id %s of natgateway %s(%s) is not present", vpcId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		subEntry.Vpc = m
+		m.NatGateways[subEntry.Id] = subEntry
+	}
+	return correct
+}
+
+func (set NatGateways) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatGateways
+}
+
+func (set NatGateways) DBModelManager() db.IModelManager {
+	return models.NatGatewayManager
+}
+
+func (set NatGateways) NewModel() db.IModel {
+	return &NatGateway{}
+}
+
+func (set NatGateways) AddModel(i db.IModel) {
+	m := i.(*NatGateway)
+	set[m.Id] = m
+}
+
+func (set NatGateways) Copy() apihelper.IModelSet {
+	setCopy := NatGateways{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (ms NatGateways) joinNatSEntries(subEntries NatSEntries, networks Networks) bool {
+	for _, m := range ms {
+		m.NatSEntries = NatSEntries{}
+	}
+	correct := true
+	for _, subEntry := range subEntries {
+		natId := subEntry.NatgatewayId
+		m, ok := ms[natId]
+		if !ok {
+			log.Warningf("natgateway_id %s of snat entry %s(%s) is not present", natId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		if subEntry.NetworkId != "" {
+			network, ok := networks[subEntry.NetworkId]
+			if !ok {
+				log.Warningf("network_id %s of snat entry %s(%s) is not present", subEntry.NetworkId, subEntry.Name, subEntry.Id)
+				correct = false
+				continue
+			}
+			subEntry.Network = network
+		}
+		subEntry.NatGateway = m
+		m.NatSEntries[subEntry.Id] = subEntry
+	}
+	return correct
+}
+
+func (ms NatGateways) joinNatDEntries(subEntries NatDEntries) bool {
+	for _, m := range ms {
+		m.NatDEntries = NatDEntries{}
+	}
+	correct := true
+	for _, subEntry := range subEntries {
+		natId := subEntry.NatgatewayId
+		m, ok := ms[natId]
+		if !ok {
+			log.Warningf("natgateway_id %s of dnat entry %s(%s) is not present", natId, subEntry.Name, subEntry.Id)
+			correct = false
+			continue
+		}
+		subEntry.NatGateway = m
+		m.NatDEntries[subEntry.Id] = subEntry
+	}
+	return correct
+}
+
+func (set NatSEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatSTable
+}
+
+func (set NatSEntries) DBModelManager() db.IModelManager {
+	return models.NatSEntryManager
+}
+
+func (set NatSEntries) NewModel() db.IModel {
+	return &NatSEntry{}
+}
+
+func (set NatSEntries) AddModel(i db.IModel) {
+	m := i.(*NatSEntry)
+	set[m.Id] = m
+}
+
+func (set NatSEntries) Copy() apihelper.IModelSet {
+	setCopy := NatSEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
+
+func (set NatDEntries) ModelManager() mcclient_modulebase.IBaseManager {
+	return &mcclient_modules.NatDTable
+}
+
+func (set NatDEntries) DBModelManager() db.IModelManager {
+	return models.NatDEntryManager
+}
+
+func (set NatDEntries) NewModel() db.IModel {
+	return &NatDEntry{}
+}
+
+func (set NatDEntries) AddModel(i db.IModel) {
+	m := i.(*NatDEntry)
+	set[m.Id] = m
+}
+
+func (set NatDEntries) Copy() apihelper.IModelSet {
+	setCopy := NatDEntries{}
+	for id, el := range set {
+		setCopy[id] = el.Copy()
+	}
+	return setCopy
+}
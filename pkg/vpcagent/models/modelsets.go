@@ -44,12 +44,19 @@ type ModelSetsMaxUpdatedAt struct {
 
 	RouteTables time.Time
 
+	VpcPeeringConnections time.Time
+	FlowLogs              time.Time
+
 	Groupguests   time.Time
 	Groupnetworks time.Time
 
 	LoadbalancerNetworks  time.Time
 	LoadbalancerListeners time.Time
 	LoadbalancerAcls      time.Time
+
+	NatGateways time.Time
+	NatSEntries time.Time
+	NatDEntries time.Time
 }
 
 func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
@@ -71,12 +78,19 @@ func NewModelSetsMaxUpdatedAt() *ModelSetsMaxUpdatedAt {
 
 		RouteTables: apihelper.PseudoZeroTime,
 
+		VpcPeeringConnections: apihelper.PseudoZeroTime,
+		FlowLogs:              apihelper.PseudoZeroTime,
+
 		Groupguests:   apihelper.PseudoZeroTime,
 		Groupnetworks: apihelper.PseudoZeroTime,
 
 		LoadbalancerNetworks:  apihelper.PseudoZeroTime,
 		LoadbalancerListeners: apihelper.PseudoZeroTime,
 		LoadbalancerAcls:      apihelper.PseudoZeroTime,
+
+		NatGateways: apihelper.PseudoZeroTime,
+		NatSEntries: apihelper.PseudoZeroTime,
+		NatDEntries: apihelper.PseudoZeroTime,
 	}
 }
 
@@ -98,6 +112,9 @@ type ModelSets struct {
 
 	RouteTables RouteTables
 
+	VpcPeeringConnections VpcPeeringConnections
+	FlowLogs              FlowLogs
+
 	Groupguests   Groupguests
 	Groupnetworks Groupnetworks
 	Groups        Groups
@@ -105,6 +122,10 @@ type ModelSets struct {
 	LoadbalancerNetworks  LoadbalancerNetworks
 	LoadbalancerListeners LoadbalancerListeners
 	LoadbalancerAcls      LoadbalancerAcls
+
+	NatGateways NatGateways
+	NatSEntries NatSEntries
+	NatDEntries NatDEntries
 }
 
 func NewModelSets() *ModelSets {
@@ -126,6 +147,9 @@ func NewModelSets() *ModelSets {
 
 		RouteTables: RouteTables{},
 
+		VpcPeeringConnections: VpcPeeringConnections{},
+		FlowLogs:              FlowLogs{},
+
 		Groupguests:   Groupguests{},
 		Groupnetworks: Groupnetworks{},
 		Groups:        Groups{},
@@ -133,6 +157,10 @@ func NewModelSets() *ModelSets {
 		LoadbalancerNetworks:  LoadbalancerNetworks{},
 		LoadbalancerListeners: LoadbalancerListeners{},
 		LoadbalancerAcls:      LoadbalancerAcls{},
+
+		NatGateways: NatGateways{},
+		NatSEntries: NatSEntries{},
+		NatDEntries: NatDEntries{},
 	}
 }
 
@@ -156,6 +184,9 @@ func (mss *ModelSets) ModelSetList() []apihelper.IModelSet {
 
 		mss.RouteTables,
 
+		mss.VpcPeeringConnections,
+		mss.FlowLogs,
+
 		mss.Groupguests,
 		mss.Groupnetworks,
 		mss.Groups,
@@ -163,6 +194,10 @@ func (mss *ModelSets) ModelSetList() []apihelper.IModelSet {
 		mss.LoadbalancerNetworks,
 		mss.LoadbalancerListeners,
 		mss.LoadbalancerAcls,
+
+		mss.NatGateways,
+		mss.NatSEntries,
+		mss.NatDEntries,
 	}
 }
 
@@ -189,6 +224,9 @@ func (mss *ModelSets) copy_() *ModelSets {
 
 		RouteTables: mss.RouteTables.Copy().(RouteTables),
 
+		VpcPeeringConnections: mss.VpcPeeringConnections.Copy().(VpcPeeringConnections),
+		FlowLogs:              mss.FlowLogs.Copy().(FlowLogs),
+
 		Groupguests:   mss.Groupguests.Copy().(Groupguests),
 		Groupnetworks: mss.Groupnetworks.Copy().(Groupnetworks),
 		Groups:        mss.Groups.Copy().(Groups),
@@ -196,6 +234,10 @@ func (mss *ModelSets) copy_() *ModelSets {
 		LoadbalancerNetworks:  mss.LoadbalancerNetworks.Copy().(LoadbalancerNetworks),
 		LoadbalancerListeners: mss.LoadbalancerListeners.Copy().(LoadbalancerListeners),
 		LoadbalancerAcls:      mss.LoadbalancerAcls.Copy().(LoadbalancerAcls),
+
+		NatGateways: mss.NatGateways.Copy().(NatGateways),
+		NatSEntries: mss.NatSEntries.Copy().(NatSEntries),
+		NatDEntries: mss.NatDEntries.Copy().(NatDEntries),
 	}
 	return mssCopy
 }
@@ -291,6 +333,16 @@ func (mss *ModelSets) join() bool {
 	msg = append(msg, "mss.LoadbalancerListeners.joinLoadbalancerAcls(mss.LoadbalancerAcls)")
 	p = append(p, mss.DnsZones.joinRecords(mss.DnsRecords))
 	msg = append(msg, "mss.Vpcs.joinRecords(mss.DnsRecords)")
+	p = append(p, mss.Vpcs.joinVpcPeeringConnections(mss.VpcPeeringConnections))
+	msg = append(msg, "mss.Vpcs.joinVpcPeeringConnections(mss.VpcPeeringConnections)")
+	p = append(p, mss.Vpcs.joinFlowLogs(mss.FlowLogs))
+	msg = append(msg, "mss.Vpcs.joinFlowLogs(mss.FlowLogs)")
+	p = append(p, mss.Vpcs.joinNatGateways(mss.NatGateways))
+	msg = append(msg, "mss.Vpcs.joinNatGateways(mss.NatGateways)")
+	p = append(p, mss.NatGateways.joinNatSEntries(mss.NatSEntries, mss.Networks))
+	msg = append(msg, "mss.NatGateways.joinNatSEntries(mss.NatSEntries, mss.Networks)")
+	p = append(p, mss.NatGateways.joinNatDEntries(mss.NatDEntries))
+	msg = append(msg, "mss.NatGateways.joinNatDEntries(mss.NatDEntries)")
 	ret := true
 	var failMsg []string
 	for i, b := range p {
@@ -43,6 +43,8 @@ type VpcAgentOptions struct {
 	OvnNorthDatabase       string `help:"address for accessing ovn north database.  Default to local unix socket"`
 	OvnUnderlayMtu         int    `help:"mtu of ovn underlay network" default:"1500"`
 
+	OvnNatGatewayChassis []string `help:"names of ovn chassis where nat gateways of vpcs are processed, ordered by priority"`
+
 	DhcpLeaseTime   int `default:"100663296" help:"DHCP lease time in seconds"`
 	DhcpRenewalTime int `default:"67108864" help:"DHCP renewal time in seconds"`
 
@@ -0,0 +1,133 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"fmt"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/pkg/util/secrules"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+const (
+	// externalKeyOcFlowLog is set on acls and meters of guest nics to
+	// record flow log settings they were built with.  It is always set so
+	// that turning off logging also counts as a change
+	externalKeyOcFlowLog = "oc-flow-log"
+
+	flowLogNone = "none"
+
+	flowLogAclSeverity = "info"
+	flowLogMeterUnit   = "pktps"
+)
+
+// guestnetworkFlowLog returns the most specific enabled flow log covering
+// the guest nic.  A flow log for the nic takes precedence over that for its
+// network, which in turn takes precedence over that for the whole vpc
+func guestnetworkFlowLog(guestnetwork *agentmodels.Guestnetwork) *agentmodels.FlowLog {
+	var (
+		vpc    = guestnetwork.Network.Vpc
+		found  *agentmodels.FlowLog
+		fScore = -1
+	)
+	for _, flowLog := range vpc.FlowLogs {
+		if !flowLog.Enabled.IsTrue() {
+			continue
+		}
+		score := 0
+		if flowLog.GuestId != "" {
+			if flowLog.GuestId != guestnetwork.GuestId || flowLog.MacAddr != guestnetwork.MacAddr {
+				continue
+			}
+			score = 2
+		} else if flowLog.NetworkId != "" {
+			if flowLog.NetworkId != guestnetwork.NetworkId {
+				continue
+			}
+			score = 1
+		}
+		if score > fScore || (score == fScore && flowLog.Id < found.Id) {
+			found = flowLog
+			fScore = score
+		}
+	}
+	return found
+}
+
+func flowLogOcValue(flowLog *agentmodels.FlowLog) string {
+	if flowLog == nil {
+		return flowLogNone
+	}
+	return fmt.Sprintf("%s/%s/%d", flowLog.Id, flowLog.TrafficType, flowLog.LogRate)
+}
+
+// flowLogAcl turns on logging of acl built from the security group rule
+// when the flow log wants traffic of the rule's verdict.  The acl name
+// carries direction and rule id for host collectors
+func flowLogAcl(acl *ovn_nb.ACL, rule *agentmodels.SecurityGroupRule, flowLog *agentmodels.FlowLog, meterName string) {
+	if acl.ExternalIds == nil {
+		acl.ExternalIds = map[string]string{}
+	}
+	acl.ExternalIds[externalKeyOcFlowLog] = flowLogOcValue(flowLog)
+	if flowLog == nil || rule.Protocol == "arp" {
+		return
+	}
+
+	var verdict string
+	switch secrules.TSecurityRuleAction(rule.Action) {
+	case secrules.SecurityRuleAllow:
+		verdict = api.FLOW_LOG_TRAFFIC_TYPE_ACCEPT
+	default:
+		verdict = api.FLOW_LOG_TRAFFIC_TYPE_REJECT
+	}
+	if flowLog.TrafficType != api.FLOW_LOG_TRAFFIC_TYPE_ALL && flowLog.TrafficType != verdict {
+		return
+	}
+
+	direction := api.FLOW_LOG_DIRECTION_EGRESS
+	if acl.Direction == aclDirToLport {
+		direction = api.FLOW_LOG_DIRECTION_INGRESS
+	}
+	acl.Log = true
+	acl.Name = ptr(api.FlowLogAclName(direction, rule.Id))
+	acl.Severity = ptr(flowLogAclSeverity)
+	if flowLog.LogRate > 0 {
+		acl.Meter = ptr(meterName)
+	}
+}
+
+// flowLogMeter returns meter limiting rate of acl logs of a guest nic.  The
+// returned meter has no bands, the band is created along with it
+func flowLogMeter(meterName string, flowLog *agentmodels.FlowLog) (*ovn_nb.Meter, *ovn_nb.MeterBand) {
+	if flowLog == nil || flowLog.LogRate <= 0 {
+		return nil, nil
+	}
+	meter := &ovn_nb.Meter{
+		Name: meterName,
+		Unit: flowLogMeterUnit,
+		ExternalIds: map[string]string{
+			externalKeyOcFlowLog: fmt.Sprintf("%d", flowLog.LogRate),
+		},
+	}
+	band := &ovn_nb.MeterBand{
+		Action:    "drop",
+		Rate:      int64(flowLog.LogRate),
+		BurstSize: int64(flowLog.LogRate),
+	}
+	return meter, band
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"testing"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/pkg/tristate"
+	"yunion.io/x/pkg/util/secrules"
+
+	api "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func newTestFlowLog(id, networkId, guestId, mac, trafficType string, logRate int) *agentmodels.FlowLog {
+	flowLog := &agentmodels.FlowLog{}
+	flowLog.Id = id
+	flowLog.Enabled = tristate.True
+	flowLog.NetworkId = networkId
+	flowLog.GuestId = guestId
+	flowLog.MacAddr = mac
+	flowLog.TrafficType = trafficType
+	flowLog.LogRate = logRate
+	return flowLog
+}
+
+func TestGuestnetworkFlowLog(t *testing.T) {
+	vpc := &agentmodels.Vpc{
+		FlowLogs: agentmodels.FlowLogs{},
+	}
+	network := &agentmodels.Network{Vpc: vpc}
+	network.Id = "net0"
+	gn := &agentmodels.Guestnetwork{Network: network}
+	gn.GuestId = "guest0"
+	gn.NetworkId = "net0"
+	gn.MacAddr = "00:22:00:00:00:01"
+
+	if got := guestnetworkFlowLog(gn); got != nil {
+		t.Fatalf("want no flow log, got %s", got.Id)
+	}
+	add := func(fl *agentmodels.FlowLog) {
+		vpc.FlowLogs[fl.Id] = fl
+	}
+	add(newTestFlowLog("fl-vpc", "", "", "", api.FLOW_LOG_TRAFFIC_TYPE_ALL, 100))
+	add(newTestFlowLog("fl-other-net", "net1", "", "", api.FLOW_LOG_TRAFFIC_TYPE_ALL, 100))
+	if got := guestnetworkFlowLog(gn); got == nil || got.Id != "fl-vpc" {
+		t.Fatalf("want fl-vpc, got %v", got)
+	}
+	add(newTestFlowLog("fl-net", "net0", "", "", api.FLOW_LOG_TRAFFIC_TYPE_ALL, 100))
+	if got := guestnetworkFlowLog(gn); got == nil || got.Id != "fl-net" {
+		t.Fatalf("want fl-net, got %v", got)
+	}
+	add(newTestFlowLog("fl-other-nic", "net0", "guest0", "00:22:00:00:00:02", api.FLOW_LOG_TRAFFIC_TYPE_ALL, 100))
+	nic := newTestFlowLog("fl-nic", "net0", "guest0", "00:22:00:00:00:01", api.FLOW_LOG_TRAFFIC_TYPE_ALL, 100)
+	add(nic)
+	if got := guestnetworkFlowLog(gn); got == nil || got.Id != "fl-nic" {
+		t.Fatalf("want fl-nic, got %v", got)
+	}
+	nic.Enabled = tristate.False
+	if got := guestnetworkFlowLog(gn); got == nil || got.Id != "fl-net" {
+		t.Fatalf("want fl-net for disabled nic flow log, got %v", got)
+	}
+}
+
+func TestFlowLogAcl(t *testing.T) {
+	newRule := func(direction, action, protocol string) *agentmodels.SecurityGroupRule {
+		return &agentmodels.SecurityGroupRule{
+			SSecurityGroupRule: models.SSecurityGroupRule{
+				Direction: direction,
+				Action:    action,
+				Protocol:  protocol,
+			},
+		}
+	}
+	newAcl := func(rule *agentmodels.SecurityGroupRule) *ovn_nb.ACL {
+		acl, err := ruleToAcl("lport0", "vpc0", rule, false)
+		if err != nil {
+			t.Fatalf("ruleToAcl: %v", err)
+		}
+		return acl
+	}
+
+	allowIn := newRule(string(secrules.SecurityRuleIngress), string(secrules.SecurityRuleAllow), secrules.PROTO_TCP)
+	allowIn.Id = "rule-allow-in"
+	denyIn := newRule(string(secrules.SecurityRuleIngress), string(secrules.SecurityRuleDeny), secrules.PROTO_ANY)
+	allowOut := newRule(string(secrules.SecurityRuleEgress), string(secrules.SecurityRuleAllow), secrules.PROTO_ANY)
+	allowOut.Id = "rule-allow-out"
+	arpIn := newRule(string(secrules.SecurityRuleIngress), string(secrules.SecurityRuleAllow), "arp")
+
+	t.Run("no flow log", func(t *testing.T) {
+		acl := newAcl(allowIn)
+		flowLogAcl(acl, allowIn, nil, "meter0")
+		if acl.Log || acl.Name != nil || acl.ExternalIds[externalKeyOcFlowLog] != flowLogNone {
+			t.Errorf("unexpected acl %#v", acl)
+		}
+	})
+	t.Run("all", func(t *testing.T) {
+		flowLog := newTestFlowLog("fl0", "", "", "", api.FLOW_LOG_TRAFFIC_TYPE_ALL, 10)
+		cases := []struct {
+			rule *agentmodels.SecurityGroupRule
+			name string
+		}{
+			{allowIn, "fl/ingress/rule-allow-in"},
+			{denyIn, "fl/ingress/default"},
+			{allowOut, "fl/egress/rule-allow-out"},
+		}
+		for _, c := range cases {
+			acl := newAcl(c.rule)
+			flowLogAcl(acl, c.rule, flowLog, "meter0")
+			if !acl.Log || acl.Name == nil || *acl.Name != c.name || acl.Meter == nil || *acl.Meter != "meter0" {
+				t.Errorf("want logged acl %s, got %#v", c.name, acl)
+			}
+			if acl.ExternalIds[externalKeyOcFlowLog] != "fl0/all/10" {
+				t.Errorf("bad external ids %#v", acl.ExternalIds)
+			}
+		}
+		acl := newAcl(arpIn)
+		flowLogAcl(acl, arpIn, flowLog, "meter0")
+		if acl.Log {
+			t.Errorf("arp acl should not be logged")
+		}
+	})
+	t.Run("reject only without rate", func(t *testing.T) {
+		flowLog := newTestFlowLog("fl1", "", "", "", api.FLOW_LOG_TRAFFIC_TYPE_REJECT, 0)
+		acl := newAcl(allowIn)
+		flowLogAcl(acl, allowIn, flowLog, "meter0")
+		if acl.Log {
+			t.Errorf("allow acl should not be logged for reject flow log")
+		}
+		acl = newAcl(denyIn)
+		flowLogAcl(acl, denyIn, flowLog, "meter0")
+		if !acl.Log || acl.Meter != nil {
+			t.Errorf("want logged acl without meter, got %#v", acl)
+		}
+		if meter, band := flowLogMeter("meter0", flowLog); meter != nil || band != nil {
+			t.Errorf("want no meter for zero log rate")
+		}
+	})
+}
+
+func TestParseFlowLogAclName(t *testing.T) {
+	for _, dir := range []string{api.FLOW_LOG_DIRECTION_INGRESS, api.FLOW_LOG_DIRECTION_EGRESS} {
+		for _, ruleId := range []string{"", "rule0"} {
+			gotDir, gotRule, ok := api.ParseFlowLogAclName(api.FlowLogAclName(dir, ruleId))
+			if !ok || gotDir != dir || gotRule != ruleId {
+				t.Errorf("%s %s: got %s %s %v", dir, ruleId, gotDir, gotRule, ok)
+			}
+		}
+	}
+	for _, name := range []string{"", "fl/in/rule0", "xx/ingress/rule0", "fl/ingress"} {
+		if _, _, ok := api.ParseFlowLogAclName(name); ok {
+			t.Errorf("%q should not be parsed", name)
+		}
+	}
+}
@@ -60,6 +60,11 @@ func DumpOVNNorthbound(ctx context.Context, cli *ovnutil.OvnNbCtl) (*OVNNorthbou
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.NAT,
+		&db.LoadBalancer,
+		&db.AddressSet,
+		&db.Meter,
+		&db.GatewayChassis,
 	}
 	args := []string{"--format=json", "list", "<tbl>"}
 	for _, itbl := range itbls {
@@ -771,7 +776,13 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 		}
 	}
 
-	var acls []*ovn_nb.ACL
+	var (
+		acls []*ovn_nb.ACL
+
+		flowLog         = guestnetworkFlowLog(guestnetwork)
+		flMeterName     = flowLogMeterName(lportName)
+		flMeter, flBand = flowLogMeter(flMeterName, flowLog)
+	)
 	{
 		enableIPv6 := false
 		if len(guestnetwork.Ip6Addr) > 0 {
@@ -779,8 +790,7 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 		}
 		sgrs := guest.OrderedSecurityGroupRules()
 		for _, sgr := range sgrs {
-			// kvm not support peer secgroup
-			acl, err := ruleToAcl(lportName, sgr, enableIPv6)
+			acl, err := ruleToAcl(lportName, vpc.Id, sgr, enableIPv6)
 			if err != nil {
 				log.Errorf("converting security group rule to acl: %v", err)
 				break
@@ -788,6 +798,7 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 			acl.ExternalIds = map[string]string{
 				externalKeyOcRef: ocAclRef,
 			}
+			flowLogAcl(acl, sgr, flowLog, flMeterName)
 			acls = append(acls, acl)
 		}
 	}
@@ -811,6 +822,9 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 	if hasQoSEip {
 		irows = append(irows, qosEipIn, qosEipOut)
 	}
+	if flMeter != nil {
+		irows = append(irows, flMeter)
+	}
 	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
 	if allFound {
 		return nil
@@ -828,6 +842,12 @@ func (keeper *OVNNorthboundKeeper) ClaimGuestnetwork(ctx context.Context, guestn
 		args = append(args, ovnCreateArgs(gnrDefault, "gnrDefault")...)
 		args = append(args, "--", "add", "Logical_Router", vpcExtLrName(vpc.Id), "static_routes", "@gnrDefault")
 	}
+	if flMeter != nil {
+		meter := *flMeter
+		meter.Bands = []string{"@flBand"}
+		args = append(args, ovnCreateArgs(flBand, "flBand")...)
+		args = append(args, ovnCreateArgs(&meter, "flMeter")...)
+	}
 	for i, acl := range acls {
 		ref := fmt.Sprintf("acl%d", i)
 		args = append(args, ovnCreateArgs(acl, ref)...)
@@ -1236,6 +1256,167 @@ func (keeper *OVNNorthboundKeeper) ClaimGroupnetwork(ctx context.Context, groupn
 	return keeper.cli.Must(ctx, "ClaimGroupnetworks", args)
 }
 
+func (keeper *OVNNorthboundKeeper) ClaimNatGateway(ctx context.Context, natgateway *agentmodels.NatGateway, opts *options.Options) error {
+	var (
+		vpc       = natgateway.Vpc
+		ocVersion = fmt.Sprintf("%s.%d", natgateway.UpdatedAt, natgateway.UpdateVersion)
+	)
+	rows, errs := natGatewayToRows(natgateway, opts.OvnNatGatewayChassis)
+	for _, err := range errs {
+		log.Errorf("natgateway %s(%s): %v", natgateway.Name, natgateway.Id, err)
+	}
+
+	var irows []types.IRow
+	for _, gc := range rows.chassis {
+		irows = append(irows, gc)
+	}
+	for _, nat := range rows.nats {
+		irows = append(irows, nat)
+	}
+	for _, lb := range rows.lbs {
+		irows = append(irows, lb)
+	}
+	for _, route := range rows.routes {
+		irows = append(irows, route)
+	}
+	if len(irows) == 0 {
+		return nil
+	}
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
+	if allFound {
+		return nil
+	}
+
+	// gateway chassis may be shared by nat gateways of the vpc, reuse the
+	// existing rows and replace the whole column of the port
+	var gcRefs []string
+	for i, gc := range rows.chassis {
+		if m := keeper.DB.FindOneMatchByAnyIndex(gc); m != nil {
+			if keeper.DB.FindOneMatchNonZeros(gc) == nil {
+				args = append(args, "--", "set", "Gateway_Chassis", m.OvsdbUuid(), fmt.Sprintf("priority=%d", gc.Priority))
+			}
+			gcRefs = append(gcRefs, m.OvsdbUuid())
+			continue
+		}
+		ref := fmt.Sprintf("natGc%d", i)
+		args = append(args, ovnCreateArgs(gc, ref)...)
+		gcRefs = append(gcRefs, "@"+ref)
+	}
+	args = append(args, "--", "set", "Logical_Router_Port", vpcRepName(vpc.Id), "gateway_chassis="+strings.Join(gcRefs, ","))
+
+	lrName := vpcExtLrName(vpc.Id)
+	for i, nat := range rows.nats {
+		ref := fmt.Sprintf("nat%d", i)
+		args = append(args, ovnCreateArgs(nat, ref)...)
+		args = append(args, "--", "add", "Logical_Router", lrName, "nat", "@"+ref)
+	}
+	for i, lb := range rows.lbs {
+		ref := fmt.Sprintf("natLb%d", i)
+		args = append(args, ovnCreateArgs(lb, ref)...)
+		args = append(args, "--", "add", "Logical_Router", lrName, "load_balancer", "@"+ref)
+	}
+	for i, route := range rows.routes {
+		ref := fmt.Sprintf("natRoute%d", i)
+		args = append(args, ovnCreateArgs(route, ref)...)
+		args = append(args, "--", "add", "Logical_Router", lrName, "static_routes", "@"+ref)
+	}
+	return keeper.cli.Must(ctx, "ClaimNatGateway", args)
+}
+
+// ClaimVpcPeering connects logical routers of the two vpcs with a transit
+// switch.  Routes to the peer are installed by ClaimRoutes
+func (keeper *OVNNorthboundKeeper) ClaimVpcPeering(ctx context.Context, peering *agentmodels.VpcPeeringConnection) error {
+	ip, peerIp, err := vpcPeeringTransitIPs(peering)
+	if err != nil {
+		return err
+	}
+	var (
+		ocVersion = fmt.Sprintf("%s.%d", peering.UpdatedAt, peering.UpdateVersion)
+		ends      = []struct {
+			vpcId string
+			ip    netutils.IPV4Addr
+		}{
+			{peering.VpcId, ip},
+			{peering.PeerVpcId, peerIp},
+		}
+		peerLs = &ovn_nb.LogicalSwitch{
+			Name: vpcPeerLsName(peering.Id),
+		}
+		irows = []types.IRow{peerLs}
+		lrps  []*ovn_nb.LogicalRouterPort
+		lsps  []*ovn_nb.LogicalSwitchPort
+	)
+	for _, end := range ends {
+		lrp := &ovn_nb.LogicalRouterPort{
+			Name:     vpcPeerRpName(peering.Id, end.vpcId),
+			Mac:      end.ip.ToMac("ee:ee:"),
+			Networks: []string{fmt.Sprintf("%s/%d", end.ip.String(), apis.VpcPeeringTransitMask)},
+		}
+		lsp := &ovn_nb.LogicalSwitchPort{
+			Name:      vpcPeerSpName(peering.Id, end.vpcId),
+			Type:      "router",
+			Addresses: []string{"router"},
+			Options: map[string]string{
+				"router-port": lrp.Name,
+			},
+		}
+		lrps = append(lrps, lrp)
+		lsps = append(lsps, lsp)
+		irows = append(irows, lrp, lsp)
+	}
+
+	allFound, args := cmp(&keeper.DB, ocVersion, irows...)
+	if allFound {
+		return nil
+	}
+	args = append(args, ovnCreateArgs(peerLs, "peerLs")...)
+	for i, end := range ends {
+		lrp, lsp := lrps[i], lsps[i]
+		lrpRef := fmt.Sprintf("peerLrp%d", i)
+		lspRef := fmt.Sprintf("peerLsp%d", i)
+		args = append(args, ovnCreateArgs(lrp, lrpRef)...)
+		args = append(args, ovnCreateArgs(lsp, lspRef)...)
+		args = append(args, "--", "add", "Logical_Router", vpcLrName(end.vpcId), "ports", "@"+lrpRef)
+		args = append(args, "--", "add", "Logical_Switch", peerLs.Name, "ports", "@"+lspRef)
+	}
+	return keeper.cli.Must(ctx, "ClaimVpcPeering", args)
+}
+
+// ClaimSecgroupAddressSets maintains member addresses of security groups for
+// peer secgroup rules.  Existing sets are updated in place so that acls
+// referring to them need not be changed as guests join and leave
+func (keeper *OVNNorthboundKeeper) ClaimSecgroupAddressSets(ctx context.Context, vpcs agentmodels.Vpcs) error {
+	var (
+		args      []string
+		ocVersion = "secgroups"
+	)
+	for i, addrSet := range secgroupAddrSets(vpcs) {
+		found := keeper.DB.AddressSet.GetByName(addrSet)
+		if found == nil {
+			args = append(args, ovnCreateArgs(addrSet, fmt.Sprintf("addrSet%d", i))...)
+			continue
+		}
+		found.SetExternalId(externalKeyOcVersion, ocVersion)
+		add, remove := addrSetDiff(found.Addresses, addrSet.Addresses)
+		if len(add) > 0 {
+			args = append(args, "--", "add", "Address_Set", found.Uuid, "addresses")
+			for _, addr := range add {
+				args = append(args, types.OvsdbCmdArgString(addr))
+			}
+		}
+		if len(remove) > 0 {
+			args = append(args, "--", "remove", "Address_Set", found.Uuid, "addresses")
+			for _, addr := range remove {
+				args = append(args, types.OvsdbCmdArgString(addr))
+			}
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return keeper.cli.Must(ctx, "ClaimSecgroupAddressSets", args)
+}
+
 func (keeper *OVNNorthboundKeeper) Mark(ctx context.Context) {
 	db := &keeper.DB
 	itbls := []types.ITable{
@@ -1248,6 +1429,11 @@ func (keeper *OVNNorthboundKeeper) Mark(ctx context.Context) {
 		&db.DHCPOptions,
 		&db.QoS,
 		&db.DNS,
+		&db.NAT,
+		&db.LoadBalancer,
+		&db.AddressSet,
+		&db.Meter,
+		&db.GatewayChassis,
 	}
 	for _, itbl := range itbls {
 		for _, irow := range itbl.Rows() {
@@ -1266,6 +1452,9 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 		&db.LogicalRouter,
 		&db.DHCPOptions,
 		&db.DNS,
+		&db.LoadBalancer,
+		&db.AddressSet,
+		&db.Meter,
 	}
 	var irows []types.IRow
 	for _, itbl := range itbls {
@@ -1309,6 +1498,34 @@ func (keeper *OVNNorthboundKeeper) Sweep(ctx context.Context) error {
 			keeper.cli.Must(ctx, "Sweep acls", args)
 		}
 	}
+	{
+		var args []string
+		for _, irow := range db.NAT.Rows() {
+			_, ok := irow.GetExternalId(externalKeyOcVersion)
+			if !ok {
+				for _, lr := range db.LogicalRouter.FindNATReferrer_nat(irow.OvsdbUuid()) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Router", lr.Name, "nat", irow.OvsdbUuid())
+				}
+			}
+		}
+		if len(args) > 0 {
+			keeper.cli.Must(ctx, "Sweep nat", args)
+		}
+	}
+	{
+		var args []string
+		for _, irow := range db.GatewayChassis.Rows() {
+			_, ok := irow.GetExternalId(externalKeyOcVersion)
+			if !ok {
+				for _, lrp := range db.LogicalRouterPort.FindGatewayChassisReferrer_gateway_chassis(irow.OvsdbUuid()) {
+					args = append(args, "--", "--if-exists", "remove", "Logical_Router_Port", lrp.Name, "gateway_chassis", irow.OvsdbUuid())
+				}
+			}
+		}
+		if len(args) > 0 {
+			keeper.cli.Must(ctx, "Sweep gateway chassis", args)
+		}
+	}
 	{ //  remove unused QoS rows
 		var args []string
 		for _, irow := range db.QoS.Rows() {
@@ -114,3 +114,21 @@ func vipName(netId string, groupId string, ipaddr string) string {
 func lbpName(lbId string) string {
 	return fmt.Sprintf("iface/lb/%s", lbId)
 }
+
+// vpc peering
+func vpcPeerLsName(peeringId string) string {
+	return fmt.Sprintf("vpc-peer/%s", peeringId)
+}
+
+func vpcPeerRpName(peeringId string, vpcId string) string {
+	return fmt.Sprintf("vpc-peer-r/%s/%s", peeringId, vpcId)
+}
+
+func vpcPeerSpName(peeringId string, vpcId string) string {
+	return fmt.Sprintf("vpc-peer-s/%s/%s", peeringId, vpcId)
+}
+
+// flow log
+func flowLogMeterName(lportName string) string {
+	return fmt.Sprintf("fl/%s", lportName)
+}
@@ -0,0 +1,198 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/netutils"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+// natSEntryLogicalIp returns the source range of snat entry, which is either
+// specified as cidr or the whole subnet
+func natSEntryLogicalIp(snat *agentmodels.NatSEntry) (string, error) {
+	if snat.SourceCIDR != "" {
+		prefix, err := netutils.NewIPV4Prefix(snat.SourceCIDR)
+		if err != nil {
+			return "", errors.Wrapf(err, "snat source cidr %s", snat.SourceCIDR)
+		}
+		return prefix.String(), nil
+	}
+	network := snat.Network
+	if network == nil {
+		return "", errors.Errorf("snat entry %s has neither source cidr nor network", snat.Id)
+	}
+	startIp, err := netutils.NewIPV4Addr(network.GuestIpStart)
+	if err != nil {
+		return "", errors.Wrapf(err, "network %s guest ip start", network.Id)
+	}
+	return fmt.Sprintf("%s/%d", startIp.NetAddr(network.GuestIpMask).String(), network.GuestIpMask), nil
+}
+
+func natSEntryToNat(snat *agentmodels.NatSEntry) (*ovn_nb.NAT, error) {
+	logicalIp, err := natSEntryLogicalIp(snat)
+	if err != nil {
+		return nil, err
+	}
+	return &ovn_nb.NAT{
+		Type:       "snat",
+		LogicalIp:  logicalIp,
+		ExternalIp: snat.IP,
+		ExternalIds: map[string]string{
+			externalKeyOcRef: fmt.Sprintf("snat/%s/%s", snat.NatgatewayId, snat.Id),
+		},
+	}, nil
+}
+
+// natDEntryToLb translates port forwarding to load balancer vip, as the
+// NAT table does not match on transport ports
+func natDEntryToLb(dnat *agentmodels.NatDEntry) (*ovn_nb.LoadBalancer, error) {
+	proto := strings.ToLower(dnat.IpProtocol)
+	switch proto {
+	case "tcp", "udp":
+	default:
+		return nil, errors.Errorf("dnat entry %s: unsupported protocol %q", dnat.Id, dnat.IpProtocol)
+	}
+	if dnat.ExternalPort <= 0 || dnat.InternalPort <= 0 {
+		return nil, errors.Errorf("dnat entry %s: invalid port %d -> %d", dnat.Id, dnat.ExternalPort, dnat.InternalPort)
+	}
+	return &ovn_nb.LoadBalancer{
+		Name:     fmt.Sprintf("dnat/%s", dnat.Id),
+		Protocol: ptr(proto),
+		Vips: map[string]string{
+			fmt.Sprintf("%s:%d", dnat.ExternalIP, dnat.ExternalPort): fmt.Sprintf("%s:%d", dnat.InternalIP, dnat.InternalPort),
+		},
+		ExternalIds: map[string]string{
+			externalKeyOcRef: fmt.Sprintf("dnat/%s/%s", dnat.NatgatewayId, dnat.Id),
+		},
+	}, nil
+}
+
+// natRoute steers traffic from logicalIp to eipgw, where snat and dnat
+// replies leave the vpc
+func natRoute(vpcId, natId, logicalIp string) *ovn_nb.LogicalRouterStaticRoute {
+	return &ovn_nb.LogicalRouterStaticRoute{
+		Policy:     ptr("src-ip"),
+		IpPrefix:   logicalIp,
+		Nexthop:    apis.VpcEipGatewayIP3().String(),
+		OutputPort: ptr(vpcRepName(vpcId)),
+		ExternalIds: map[string]string{
+			externalKeyOcRef: fmt.Sprintf("nat-route/%s/%s", natId, logicalIp),
+		},
+	}
+}
+
+// natGatewayChassis makes the eip router port of vpcExtLr a distributed
+// gateway port.  OVN only applies NAT and load balancers of a distributed
+// router on the chassis of its gateway port
+func natGatewayChassis(vpcId string, chassisNames []string) []*ovn_nb.GatewayChassis {
+	gcs := make([]*ovn_nb.GatewayChassis, 0, len(chassisNames))
+	for i, name := range chassisNames {
+		gcs = append(gcs, &ovn_nb.GatewayChassis{
+			Name:        fmt.Sprintf("%s-%s", vpcRepName(vpcId), name),
+			ChassisName: name,
+			Priority:    int64(len(chassisNames) - i),
+			ExternalIds: map[string]string{
+				externalKeyOcRef: fmt.Sprintf("nat-gateway-chassis/%s", vpcId),
+			},
+		})
+	}
+	return gcs
+}
+
+type natGatewayRows struct {
+	chassis []*ovn_nb.GatewayChassis
+	nats    []*ovn_nb.NAT
+	lbs     []*ovn_nb.LoadBalancer
+	routes  []*ovn_nb.LogicalRouterStaticRoute
+}
+
+// natGatewayToRows generates rows of available snat and dnat entries.
+// Multiple entries may share the same eip, but a source range can only be
+// translated once
+func natGatewayToRows(natgateway *agentmodels.NatGateway, chassisNames []string) (*natGatewayRows, []error) {
+	var (
+		vpcId  = natgateway.VpcId
+		rows   = &natGatewayRows{}
+		errs   []error
+		routed = map[string]struct{}{}
+	)
+	if len(chassisNames) == 0 {
+		return rows, []error{errors.Errorf("no ovn_nat_gateway_chassis configured to process nat")}
+	}
+	rows.chassis = natGatewayChassis(vpcId, chassisNames)
+	addRoute := func(logicalIp string) {
+		if _, ok := routed[logicalIp]; ok {
+			return
+		}
+		routed[logicalIp] = struct{}{}
+		rows.routes = append(rows.routes, natRoute(vpcId, natgateway.Id, logicalIp))
+	}
+
+	snats := make([]*agentmodels.NatSEntry, 0, len(natgateway.NatSEntries))
+	for _, snat := range natgateway.NatSEntries {
+		if snat.Status == apis.NAT_STAUTS_AVAILABLE {
+			snats = append(snats, snat)
+		}
+	}
+	sort.Slice(snats, func(i, j int) bool {
+		return snats[i].Id < snats[j].Id
+	})
+	snated := map[string]string{}
+	for _, snat := range snats {
+		nat, err := natSEntryToNat(snat)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if id, ok := snated[nat.LogicalIp]; ok {
+			errs = append(errs, errors.Errorf("snat entry %s: source %s already translated by %s", snat.Id, nat.LogicalIp, id))
+			continue
+		}
+		snated[nat.LogicalIp] = snat.Id
+		rows.nats = append(rows.nats, nat)
+		addRoute(nat.LogicalIp)
+	}
+
+	dnats := make([]*agentmodels.NatDEntry, 0, len(natgateway.NatDEntries))
+	for _, dnat := range natgateway.NatDEntries {
+		if dnat.Status == apis.NAT_STAUTS_AVAILABLE {
+			dnats = append(dnats, dnat)
+		}
+	}
+	sort.Slice(dnats, func(i, j int) bool {
+		return dnats[i].Id < dnats[j].Id
+	})
+	for _, dnat := range dnats {
+		lb, err := natDEntryToLb(dnat)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rows.lbs = append(rows.lbs, lb)
+		addRoute(dnat.InternalIP + "/32")
+	}
+	if len(rows.nats) == 0 && len(rows.lbs) == 0 {
+		rows.chassis = nil
+	}
+	return rows, errs
+}
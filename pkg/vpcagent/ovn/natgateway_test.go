@@ -0,0 +1,256 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	"yunion.io/x/onecloud/pkg/compute/models"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func newTestNatSEntry(id, ip, cidr string, network *agentmodels.Network) *agentmodels.NatSEntry {
+	snat := &agentmodels.NatSEntry{
+		Network: network,
+	}
+	snat.Id = id
+	snat.NatgatewayId = "nat0"
+	snat.Status = apis.NAT_STAUTS_AVAILABLE
+	snat.IP = ip
+	snat.SourceCIDR = cidr
+	return snat
+}
+
+func newTestNatDEntry(id, eip string, eport int, ip string, port int, proto string) *agentmodels.NatDEntry {
+	dnat := &agentmodels.NatDEntry{
+		SNatDEntry: models.SNatDEntry{
+			ExternalIP:   eip,
+			ExternalPort: eport,
+			InternalIP:   ip,
+			InternalPort: port,
+			IpProtocol:   proto,
+		},
+	}
+	dnat.Id = id
+	dnat.NatgatewayId = "nat0"
+	dnat.Status = apis.NAT_STAUTS_AVAILABLE
+	return dnat
+}
+
+func TestNatGatewayToRows(t *testing.T) {
+	network := &agentmodels.Network{}
+	network.Id = "net0"
+	network.GuestIpStart = "192.168.1.10"
+	network.GuestIpMask = 24
+
+	natgateway := &agentmodels.NatGateway{
+		NatSEntries: agentmodels.NatSEntries{
+			"s0": newTestNatSEntry("s0", "10.0.0.100", "", network),
+			// shares eip with s0
+			"s1": newTestNatSEntry("s1", "10.0.0.100", "192.168.2.0/24", nil),
+			// duplicate source range
+			"s2": newTestNatSEntry("s2", "10.0.0.101", "192.168.2.0/24", nil),
+		},
+		NatDEntries: agentmodels.NatDEntries{
+			"d0": newTestNatDEntry("d0", "10.0.0.100", 2222, "192.168.1.20", 22, "TCP"),
+			"d1": newTestNatDEntry("d1", "10.0.0.100", 5353, "192.168.1.20", 53, "udp"),
+			"d2": newTestNatDEntry("d2", "10.0.0.100", 80, "192.168.1.20", 80, "icmp"),
+		},
+	}
+	natgateway.Id = "nat0"
+	natgateway.VpcId = "vpc0"
+	pending := newTestNatDEntry("d3", "10.0.0.100", 8080, "192.168.1.30", 80, "tcp")
+	pending.Status = apis.NAT_STATUS_ALLOCATE
+	natgateway.NatDEntries["d3"] = pending
+
+	rows, errs := natGatewayToRows(natgateway, []string{"gw0"})
+	if len(errs) != 2 {
+		t.Errorf("want 2 errors, got %v", errs)
+	}
+	if len(rows.chassis) != 1 || rows.chassis[0].ChassisName != "gw0" {
+		t.Errorf("unexpected gateway chassis %#v", rows.chassis)
+	}
+
+	wantNats := []*ovn_nb.NAT{
+		{
+			Type:        "snat",
+			LogicalIp:   "192.168.1.0/24",
+			ExternalIp:  "10.0.0.100",
+			ExternalIds: map[string]string{externalKeyOcRef: "snat/nat0/s0"},
+		},
+		{
+			Type:        "snat",
+			LogicalIp:   "192.168.2.0/24",
+			ExternalIp:  "10.0.0.100",
+			ExternalIds: map[string]string{externalKeyOcRef: "snat/nat0/s1"},
+		},
+	}
+	if !reflect.DeepEqual(rows.nats, wantNats) {
+		t.Errorf("nats mismatch, got %#v", rows.nats)
+	}
+
+	wantLbs := []*ovn_nb.LoadBalancer{
+		{
+			Name:        "dnat/d0",
+			Protocol:    ptr("tcp"),
+			Vips:        map[string]string{"10.0.0.100:2222": "192.168.1.20:22"},
+			ExternalIds: map[string]string{externalKeyOcRef: "dnat/nat0/d0"},
+		},
+		{
+			Name:        "dnat/d1",
+			Protocol:    ptr("udp"),
+			Vips:        map[string]string{"10.0.0.100:5353": "192.168.1.20:53"},
+			ExternalIds: map[string]string{externalKeyOcRef: "dnat/nat0/d1"},
+		},
+	}
+	if !reflect.DeepEqual(rows.lbs, wantLbs) {
+		t.Errorf("lbs mismatch, got %#v", rows.lbs)
+	}
+
+	var prefixes []string
+	for _, route := range rows.routes {
+		if *route.OutputPort != vpcRepName("vpc0") || route.Nexthop != apis.VpcEipGatewayIP3().String() {
+			t.Errorf("route %s not via eipgw", route.IpPrefix)
+		}
+		prefixes = append(prefixes, route.IpPrefix)
+	}
+	wantPrefixes := []string{"192.168.1.0/24", "192.168.2.0/24", "192.168.1.20/32"}
+	if !reflect.DeepEqual(prefixes, wantPrefixes) {
+		t.Errorf("route prefixes want %v, got %v", wantPrefixes, prefixes)
+	}
+}
+
+func TestNatGatewayToRowsWithoutChassis(t *testing.T) {
+	natgateway := &agentmodels.NatGateway{
+		NatSEntries: agentmodels.NatSEntries{
+			"s0": newTestNatSEntry("s0", "10.0.0.100", "192.168.2.0/24", nil),
+		},
+	}
+	natgateway.Id = "nat0"
+	natgateway.VpcId = "vpc0"
+	rows, errs := natGatewayToRows(natgateway, nil)
+	if len(errs) != 1 || len(rows.nats) != 0 || len(rows.routes) != 0 {
+		t.Errorf("nat without gateway chassis should not be claimed, got %#v, %v", rows, errs)
+	}
+}
+
+func prefixContains(t *testing.T, prefix, ip string) (bool, int) {
+	if !strings.Contains(prefix, "/") {
+		prefix += "/32"
+	}
+	_, ipnet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		t.Fatalf("invalid prefix %s", prefix)
+	}
+	ones, _ := ipnet.Mask.Size()
+	return ipnet.Contains(net.ParseIP(ip)), ones
+}
+
+// TestNatGatewayTrafficPath walks the outgoing snat and incoming dnat
+// traffic of a guest through the generated rows of vpcExtLr
+func TestNatGatewayTrafficPath(t *testing.T) {
+	network := &agentmodels.Network{}
+	network.Id = "net0"
+	network.GuestIpStart = "192.168.1.10"
+	network.GuestIpMask = 24
+
+	natgateway := &agentmodels.NatGateway{
+		NatSEntries: agentmodels.NatSEntries{
+			"s0": newTestNatSEntry("s0", "10.0.0.100", "", network),
+		},
+		NatDEntries: agentmodels.NatDEntries{
+			"d0": newTestNatDEntry("d0", "10.0.0.101", 2222, "192.168.1.20", 22, "tcp"),
+		},
+	}
+	natgateway.Id = "nat0"
+	natgateway.VpcId = "vpc0"
+
+	rows, errs := natGatewayToRows(natgateway, []string{"gw0", "gw1"})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	// the longest src-ip route of the guest decides the port leaving vpcExtLr
+	outPort := func(src string) string {
+		var (
+			port    string
+			longest = -1
+		)
+		for _, route := range rows.routes {
+			if route.Policy == nil || *route.Policy != "src-ip" {
+				continue
+			}
+			if ok, ones := prefixContains(t, route.IpPrefix, src); ok && ones > longest {
+				port, longest = *route.OutputPort, ones
+				if route.Nexthop != apis.VpcEipGatewayIP3().String() {
+					t.Errorf("route %s not via eipgw", route.IpPrefix)
+				}
+			}
+		}
+		return port
+	}
+	// nat of vpcExtLr only takes effect on the chassis of a distributed gateway port
+	gatewayChassis := func(port string) string {
+		var (
+			chassis  string
+			priority int64
+		)
+		for _, gc := range rows.chassis {
+			if strings.HasPrefix(gc.Name, port+"-") && gc.Priority > priority {
+				chassis, priority = gc.ChassisName, gc.Priority
+			}
+		}
+		return chassis
+	}
+
+	// guest 192.168.1.30 goes out with snat eip
+	port := outPort("192.168.1.30")
+	if port != vpcRepName("vpc0") {
+		t.Fatalf("outgoing traffic leaves from %q", port)
+	}
+	if chassis := gatewayChassis(port); chassis != "gw0" {
+		t.Fatalf("outgoing port %s has gateway chassis %q", port, chassis)
+	}
+	snatIp := ""
+	for _, nat := range rows.nats {
+		if ok, _ := prefixContains(t, nat.LogicalIp, "192.168.1.30"); ok && nat.Type == "snat" {
+			snatIp = nat.ExternalIp
+		}
+	}
+	if snatIp != "10.0.0.100" {
+		t.Errorf("outgoing traffic translated to %q", snatIp)
+	}
+
+	// port forwarding to guest 192.168.1.20 and the reply through the same gateway port
+	backend := ""
+	for _, lb := range rows.lbs {
+		if *lb.Protocol == "tcp" {
+			backend = lb.Vips["10.0.0.101:2222"]
+		}
+	}
+	if backend != "192.168.1.20:22" {
+		t.Fatalf("incoming traffic forwarded to %q", backend)
+	}
+	port = outPort("192.168.1.20")
+	if port != vpcRepName("vpc0") || gatewayChassis(port) != "gw0" {
+		t.Errorf("reply of port forwarding leaves from %q", port)
+	}
+}
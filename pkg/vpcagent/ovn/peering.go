@@ -0,0 +1,103 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"strings"
+
+	"yunion.io/x/pkg/errors"
+	"yunion.io/x/pkg/util/netutils"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func vpcCidrPrefixes(vpc *agentmodels.Vpc) ([]netutils.IPV4Prefix, error) {
+	var r []netutils.IPV4Prefix
+	for _, cidr := range strings.Split(vpc.CidrBlock, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		prefix, err := netutils.NewIPV4Prefix(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "vpc %s cidr %s", vpc.Id, cidr)
+		}
+		r = append(r, prefix)
+	}
+	return r, nil
+}
+
+// vpcPeeringCheck tells whether the peering connection can be realized.  Both
+// ends must be present, and their address ranges must not overlap
+func vpcPeeringCheck(peering *agentmodels.VpcPeeringConnection) error {
+	if peering.Status != apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE {
+		return errors.Errorf("status %s", peering.Status)
+	}
+	if peering.TransitCidr == "" {
+		return errors.Errorf("no transit cidr")
+	}
+	if peering.Vpc == nil || peering.PeerVpc == nil {
+		return errors.Errorf("vpc not present")
+	}
+	prefixes, err := vpcCidrPrefixes(peering.Vpc)
+	if err != nil {
+		return err
+	}
+	peerPrefixes, err := vpcCidrPrefixes(peering.PeerVpc)
+	if err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		for _, peerPrefix := range peerPrefixes {
+			if prefix.ToIPRange().IsOverlap(peerPrefix.ToIPRange()) {
+				return errors.Errorf("cidr %s of vpc %s overlaps with cidr %s of vpc %s",
+					prefix.String(), peering.VpcId, peerPrefix.String(), peering.PeerVpcId)
+			}
+		}
+	}
+	return nil
+}
+
+// vpcPeeringTransitIPs returns address of vpc router port and peer vpc
+// router port on the transit switch
+func vpcPeeringTransitIPs(peering *agentmodels.VpcPeeringConnection) (netutils.IPV4Addr, netutils.IPV4Addr, error) {
+	prefix, err := netutils.NewIPV4Prefix(peering.TransitCidr)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "transit cidr %s", peering.TransitCidr)
+	}
+	if prefix.MaskLen != apis.VpcPeeringTransitMask {
+		return 0, 0, errors.Errorf("transit cidr %s: want mask length %d", peering.TransitCidr, apis.VpcPeeringTransitMask)
+	}
+	netAddr := prefix.Address.NetAddr(prefix.MaskLen)
+	return netAddr + 1, netAddr + 2, nil
+}
+
+// vpcPeeringRemote returns the peer end of peering as seen from vpcId, and
+// the nexthop address to reach it
+func vpcPeeringRemote(peering *agentmodels.VpcPeeringConnection, vpcId string) (*agentmodels.Vpc, string, error) {
+	ip, peerIp, err := vpcPeeringTransitIPs(peering)
+	if err != nil {
+		return nil, "", err
+	}
+	switch vpcId {
+	case peering.VpcId:
+		return peering.PeerVpc, peerIp.String(), nil
+	case peering.PeerVpcId:
+		return peering.Vpc, ip.String(), nil
+	default:
+		return nil, "", errors.Errorf("vpc %s is not an end of peering %s", vpcId, peering.Id)
+	}
+}
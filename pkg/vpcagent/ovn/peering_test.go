@@ -0,0 +1,98 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"reflect"
+	"testing"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+func newTestPeeringVpc(id, cidr string) *agentmodels.Vpc {
+	vpc := &agentmodels.Vpc{
+		PeeringConnections: agentmodels.VpcPeeringConnections{},
+	}
+	vpc.Id = id
+	vpc.CidrBlock = cidr
+	return vpc
+}
+
+func newTestPeering(id string, vpc, peerVpc *agentmodels.Vpc) *agentmodels.VpcPeeringConnection {
+	peering := &agentmodels.VpcPeeringConnection{
+		Vpc:     vpc,
+		PeerVpc: peerVpc,
+	}
+	peering.Id = id
+	peering.VpcId = vpc.Id
+	peering.PeerVpcId = peerVpc.Id
+	peering.Status = apis.VPC_PEERING_CONNECTION_STATUS_ACTIVE
+	peering.TransitCidr = "100.65.64.4/30"
+	vpc.PeeringConnections[id] = peering
+	peerVpc.PeeringConnections[id] = peering
+	return peering
+}
+
+func TestVpcPeering(t *testing.T) {
+	vpc0 := newTestPeeringVpc("vpc0", "192.168.0.0/16")
+	vpc1 := newTestPeeringVpc("vpc1", "10.1.0.0/16,10.2.0.0/16")
+	peering := newTestPeering("p0", vpc0, vpc1)
+
+	if err := vpcPeeringCheck(peering); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	t.Run("remote", func(t *testing.T) {
+		remote, nextHop, err := vpcPeeringRemote(peering, "vpc0")
+		if err != nil || remote != vpc1 || nextHop != "100.65.64.6" {
+			t.Errorf("vpc0 side: got %v %s %v", remote, nextHop, err)
+		}
+		remote, nextHop, err = vpcPeeringRemote(peering, "vpc1")
+		if err != nil || remote != vpc0 || nextHop != "100.65.64.5" {
+			t.Errorf("vpc1 side: got %v %s %v", remote, nextHop, err)
+		}
+		if _, _, err := vpcPeeringRemote(peering, "vpc2"); err == nil {
+			t.Errorf("want error for non-end vpc")
+		}
+	})
+	t.Run("routes", func(t *testing.T) {
+		got := resolvePeeringRoutes(vpc0)
+		want := resolvedRoutes{
+			{Cidr: "10.1.0.0/16", NextHop: "100.65.64.6"},
+			{Cidr: "10.2.0.0/16", NextHop: "100.65.64.6"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("want %#v, got %#v", want, got)
+		}
+	})
+	t.Run("overlap", func(t *testing.T) {
+		vpc2 := newTestPeeringVpc("vpc2", "192.168.10.0/24")
+		p := newTestPeering("p1", vpc0, vpc2)
+		if err := vpcPeeringCheck(p); err == nil {
+			t.Errorf("want overlap error")
+		}
+		for _, route := range resolvePeeringRoutes(vpc2) {
+			t.Errorf("unexpected route %#v", route)
+		}
+	})
+	t.Run("bad transit", func(t *testing.T) {
+		vpc3 := newTestPeeringVpc("vpc3", "172.16.0.0/16")
+		p := newTestPeering("p2", vpc1, vpc3)
+		p.TransitCidr = "100.65.64.0/29"
+		if _, _, err := vpcPeeringTransitIPs(p); err == nil {
+			t.Errorf("want mask length error")
+		}
+	})
+}
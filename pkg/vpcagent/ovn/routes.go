@@ -15,6 +15,8 @@
 package ovn
 
 import (
+	"sort"
+
 	computeapis "yunion.io/x/onecloud/pkg/apis/compute"
 	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
 )
@@ -28,7 +30,47 @@ type resolvedRoute struct {
 	Guestnetwork *agentmodels.Guestnetwork
 }
 
+// resolvePeeringRoutes returns routes to cidrs of peer vpcs
+func resolvePeeringRoutes(vpc *agentmodels.Vpc) resolvedRoutes {
+	var r resolvedRoutes
+	for _, peering := range vpc.PeeringConnections {
+		if err := vpcPeeringCheck(peering); err != nil {
+			continue
+		}
+		remote, nextHop, err := vpcPeeringRemote(peering, vpc.Id)
+		if err != nil {
+			continue
+		}
+		prefixes, _ := vpcCidrPrefixes(remote)
+		for i := range prefixes {
+			r = append(r, resolvedRoute{
+				Cidr:    prefixes[i].String(),
+				NextHop: nextHop,
+			})
+		}
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Cidr < r[j].Cidr
+	})
+	return r
+}
+
 func resolveRoutes(vpc *agentmodels.Vpc, mss *agentmodels.ModelSets) resolvedRoutes {
+	r := resolveRouteTableRoutes(vpc, mss)
+	// route table entries take precedence over implicit peering routes
+	explicit := map[string]struct{}{}
+	for _, route := range r {
+		explicit[route.Cidr] = struct{}{}
+	}
+	for _, route := range resolvePeeringRoutes(vpc) {
+		if _, ok := explicit[route.Cidr]; !ok {
+			r = append(r, route)
+		}
+	}
+	return r
+}
+
+func resolveRouteTableRoutes(vpc *agentmodels.Vpc, mss *agentmodels.ModelSets) resolvedRoutes {
 	if vpc.RouteTable == nil || vpc.RouteTable.Routes == nil {
 		return nil
 	}
@@ -37,6 +79,19 @@ func resolveRoutes(vpc *agentmodels.Vpc, mss *agentmodels.ModelSets) resolvedRou
 	routesModel := *vpc.RouteTable.Routes
 	for _, routeModel := range routesModel {
 		switch routeModel.NextHopType {
+		case computeapis.NEXT_HOP_TYPE_VPCPEERING:
+			peering, ok := vpc.PeeringConnections[routeModel.NextHopId]
+			if !ok || vpcPeeringCheck(peering) != nil {
+				break
+			}
+			_, nextHop, err := vpcPeeringRemote(peering, vpc.Id)
+			if err != nil {
+				break
+			}
+			r = append(r, resolvedRoute{
+				Cidr:    routeModel.Cidr,
+				NextHop: nextHop,
+			})
 		case computeapis.NEXT_HOP_TYPE_IP:
 			r = append(r, resolvedRoute{
 				Cidr:    routeModel.Cidr,
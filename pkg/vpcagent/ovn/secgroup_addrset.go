@@ -0,0 +1,149 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ovn
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"yunion.io/x/ovsdb/schema/ovn_nb"
+
+	apis "yunion.io/x/onecloud/pkg/apis/compute"
+	agentmodels "yunion.io/x/onecloud/pkg/vpcagent/models"
+)
+
+const (
+	addrSetAfIp4 = "ip4"
+	addrSetAfIp6 = "ip6"
+)
+
+func addrSetNameId(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.':
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// secgroupAddrSetName returns name of address set holding member addresses
+// of secgroup in the vpc.  Vpcs may have overlapping address ranges, so a
+// secgroup has one set in each vpc.  Address set names are referred to in
+// acl match as $name and can only contain letters, digits, '_' and '.'
+func secgroupAddrSetName(secgroupId, vpcId, af string) string {
+	return fmt.Sprintf("sg_%s_%s_%s", addrSetNameId(secgroupId), addrSetNameId(vpcId), af)
+}
+
+// secgroupAddrSets collects addresses of vpc guests by security group and
+// vpc.  Address sets are also generated for secgroups referenced only as peer
+// so that acl matches never refer to missing sets
+func secgroupAddrSets(vpcs agentmodels.Vpcs) []*ovn_nb.AddressSet {
+	var (
+		members = map[string]map[string]struct{}{}
+		refs    = map[string]string{}
+		ensure  = func(secgroupId, vpcId string) {
+			for _, af := range []string{addrSetAfIp4, addrSetAfIp6} {
+				name := secgroupAddrSetName(secgroupId, vpcId, af)
+				if _, ok := members[name]; !ok {
+					members[name] = map[string]struct{}{}
+					refs[name] = fmt.Sprintf("secgroup/%s/%s/%s", secgroupId, vpcId, af)
+				}
+			}
+		}
+		ensurePeers = func(secgroup *agentmodels.SecurityGroup, vpcId string) {
+			for _, rule := range secgroup.SecurityGroupRules {
+				if rule.PeerSecgroupId != "" {
+					ensure(rule.PeerSecgroupId, vpcId)
+				}
+			}
+		}
+	)
+	for _, vpc := range vpcs {
+		if vpc.Id == apis.DEFAULT_VPC_ID {
+			continue
+		}
+		for _, network := range vpc.Networks {
+			for _, guestnetwork := range network.Guestnetworks {
+				guest := guestnetwork.Guest
+				if guest == nil {
+					continue
+				}
+				for secgroupId, secgroup := range guest.SecurityGroups {
+					ensure(secgroupId, vpc.Id)
+					if guestnetwork.IpAddr != "" {
+						members[secgroupAddrSetName(secgroupId, vpc.Id, addrSetAfIp4)][guestnetwork.IpAddr] = struct{}{}
+					}
+					if guestnetwork.Ip6Addr != "" {
+						members[secgroupAddrSetName(secgroupId, vpc.Id, addrSetAfIp6)][guestnetwork.Ip6Addr] = struct{}{}
+					}
+					if secgroup != nil {
+						ensurePeers(secgroup, vpc.Id)
+					}
+				}
+				if guest.AdminSecurityGroup != nil {
+					ensurePeers(guest.AdminSecurityGroup, vpc.Id)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	r := make([]*ovn_nb.AddressSet, 0, len(names))
+	for _, name := range names {
+		addrs := make([]string, 0, len(members[name]))
+		for addr := range members[name] {
+			addrs = append(addrs, addr)
+		}
+		sort.Strings(addrs)
+		r = append(r, &ovn_nb.AddressSet{
+			Name:      name,
+			Addresses: addrs,
+			ExternalIds: map[string]string{
+				externalKeyOcRef: refs[name],
+			},
+		})
+	}
+	return r
+}
+
+// addrSetDiff returns addresses to be added to and removed from have to
+// reach want
+func addrSetDiff(have, want []string) (add, remove []string) {
+	haveSet := map[string]struct{}{}
+	for _, addr := range have {
+		haveSet[addr] = struct{}{}
+	}
+	wantSet := map[string]struct{}{}
+	for _, addr := range want {
+		wantSet[addr] = struct{}{}
+		if _, ok := haveSet[addr]; !ok {
+			add = append(add, addr)
+		}
+	}
+	for _, addr := range have {
+		if _, ok := wantSet[addr]; !ok {
+			remove = append(remove, addr)
+		}
+	}
+	sort.Strings(add)
+	sort.Strings(remove)
+	return add, remove
+}
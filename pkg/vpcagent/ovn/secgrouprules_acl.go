@@ -37,7 +37,9 @@ const (
 	aclDirFromLport = "from-lport"
 )
 
-func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule, enableIPv6 bool) (*ovn_nb.ACL, error) {
+// ruleToAcl converts secgroup rule of port to acl, peer secgroups are
+// matched with their address sets in the vpc of the port
+func ruleToAcl(lport, vpcId string, rule *agentmodels.SecurityGroupRule, enableIPv6 bool) (*ovn_nb.ACL, error) {
 	var (
 		dir    string
 		action string
@@ -79,7 +81,15 @@ func ruleToAcl(lport string, rule *agentmodels.SecurityGroupRule, enableIPv6 boo
 		} else {
 			matches = append(matches, "ip4")
 		}
-		if cidr := strings.TrimSpace(rule.CIDR); cidr != "" {
+		if peerId := rule.PeerSecgroupId; peerId != "" {
+			ip4Match := fmt.Sprintf("ip4.%s == $%s", l3subfn, secgroupAddrSetName(peerId, vpcId, addrSetAfIp4))
+			if enableIPv6 {
+				ip6Match := fmt.Sprintf("ip6.%s == $%s", l3subfn, secgroupAddrSetName(peerId, vpcId, addrSetAfIp6))
+				matches = append(matches, fmt.Sprintf("( %s || %s )", ip4Match, ip6Match))
+			} else {
+				matches = append(matches, ip4Match)
+			}
+		} else if cidr := strings.TrimSpace(rule.CIDR); cidr != "" {
 			if regutils.MatchCIDR(cidr) {
 				matches = append(matches, fmt.Sprintf("ip4.%s == %s", l3subfn, cidr))
 			} else if regutils.MatchCIDR6(cidr) {
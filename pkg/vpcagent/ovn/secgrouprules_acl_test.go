@@ -160,10 +160,49 @@ func TestRuleToACL(t *testing.T) {
 				Priority:  100,
 			},
 		},
+		{
+			// ingress allow ssh from peer secgroup, cidr ignored
+			rule: &agentmodels.SecurityGroupRule{
+				SSecurityGroupRule: models.SSecurityGroupRule{
+					Direction:      string(secrules.SecurityRuleIngress),
+					CIDR:           "10.0.0.0/8",
+					PeerSecgroupId: "a5c1e0b2-75a0-4c43-8b9e-5b0ff0f6a1d3",
+					Action:         string(secrules.SecurityRuleAllow),
+					Protocol:       secrules.PROTO_TCP,
+					Ports:          "22",
+					Priority:       100,
+				},
+			},
+			acl: &ovn_nb.ACL{
+				Direction: aclDirToLport,
+				Action:    "allow-related",
+				Match:     fmt.Sprintf("outport == %q && ip4 && ip4.src == $sg_a5c1e0b2_75a0_4c43_8b9e_5b0ff0f6a1d3_vpc0_ip4 && tcp && tcp.dst == 22", lport),
+				Priority:  100,
+			},
+		},
+		{
+			// egress deny any to peer secgroup
+			rule: &agentmodels.SecurityGroupRule{
+				SSecurityGroupRule: models.SSecurityGroupRule{
+					Direction:      string(secrules.SecurityRuleEgress),
+					PeerSecgroupId: "default",
+					Action:         string(secrules.SecurityRuleDeny),
+					Protocol:       secrules.PROTO_ANY,
+					Priority:       50,
+				},
+			},
+			ipv6: true,
+			acl: &ovn_nb.ACL{
+				Direction: aclDirFromLport,
+				Action:    "drop",
+				Match:     fmt.Sprintf("inport == %q && (ip4 || ip6) && ( ip4.dst == $sg_default_vpc0_ip4 || ip6.dst == $sg_default_vpc0_ip6 )", lport),
+				Priority:  50,
+			},
+		},
 	}
 
 	for _, c := range cases {
-		got, err := ruleToAcl(lport, c.rule, c.ipv6)
+		got, err := ruleToAcl(lport, "vpc0", c.rule, c.ipv6)
 		if err != nil {
 			t.Errorf("ruleToACL fail %s", err)
 		} else {
@@ -173,3 +212,62 @@ func TestRuleToACL(t *testing.T) {
 		}
 	}
 }
+
+func TestSecgroupAddrSets(t *testing.T) {
+	newGuestnetwork := func(guest *agentmodels.Guest, ip, ip6 string) *agentmodels.Guestnetwork {
+		gn := &agentmodels.Guestnetwork{Guest: guest}
+		gn.IpAddr = ip
+		gn.Ip6Addr = ip6
+		return gn
+	}
+	peerRule := &agentmodels.SecurityGroupRule{}
+	peerRule.PeerSecgroupId = "sg-peer"
+	web := &agentmodels.SecurityGroup{
+		SecurityGroupRules: agentmodels.SecurityGroupRules{"r0": peerRule},
+	}
+	web.Id = "sg-web"
+
+	g0 := &agentmodels.Guest{SecurityGroups: agentmodels.SecurityGroups{"sg-web": web}}
+	g1 := &agentmodels.Guest{SecurityGroups: agentmodels.SecurityGroups{"sg-web": web}}
+	network := &agentmodels.Network{
+		Guestnetworks: agentmodels.Guestnetworks{
+			"gn0": newGuestnetwork(g0, "192.168.1.2", "fd00::2"),
+			"gn1": newGuestnetwork(g1, "192.168.1.3", ""),
+			"gn2": newGuestnetwork(nil, "192.168.1.4", ""),
+		},
+	}
+	vpc := &agentmodels.Vpc{
+		Networks: agentmodels.Networks{"net0": network},
+	}
+	vpc.Id = "vpc0"
+	// another vpc reusing the same address range
+	g2 := &agentmodels.Guest{SecurityGroups: agentmodels.SecurityGroups{"sg-web": web}}
+	vpc1 := &agentmodels.Vpc{
+		Networks: agentmodels.Networks{"net1": &agentmodels.Network{
+			Guestnetworks: agentmodels.Guestnetworks{
+				"gn3": newGuestnetwork(g2, "192.168.1.2", ""),
+			},
+		}},
+	}
+	vpc1.Id = "vpc1"
+
+	got := secgroupAddrSets(agentmodels.Vpcs{"vpc0": vpc, "vpc1": vpc1})
+	want := []*ovn_nb.AddressSet{
+		{Name: "sg_sg_peer_vpc0_ip4", Addresses: []string{}, ExternalIds: map[string]string{externalKeyOcRef: "secgroup/sg-peer/vpc0/ip4"}},
+		{Name: "sg_sg_peer_vpc0_ip6", Addresses: []string{}, ExternalIds: map[string]string{externalKeyOcRef: "secgroup/sg-peer/vpc0/ip6"}},
+		{Name: "sg_sg_peer_vpc1_ip4", Addresses: []string{}, ExternalIds: map[string]string{externalKeyOcRef: "secgroup/sg-peer/vpc1/ip4"}},
+		{Name: "sg_sg_peer_vpc1_ip6", Addresses: []string{}, ExternalIds: map[string]string{externalKeyOcRef: "secgroup/sg-peer/vpc1/ip6"}},
+		{Name: "sg_sg_web_vpc0_ip4", Addresses: []string{"192.168.1.2", "192.168.1.3"}, ExternalIds: map[string]string{externalKeyOcRef: "secgroup/sg-web/vpc0/ip4"}},
+		{Name: "sg_sg_web_vpc0_ip6", Addresses: []string{"fd00::2"}, ExternalIds: map[string]string{externalKeyOcRef: "secgroup/sg-web/vpc0/ip6"}},
+		{Name: "sg_sg_web_vpc1_ip4", Addresses: []string{"192.168.1.2"}, ExternalIds: map[string]string{externalKeyOcRef: "secgroup/sg-web/vpc1/ip4"}},
+		{Name: "sg_sg_web_vpc1_ip6", Addresses: []string{}, ExternalIds: map[string]string{externalKeyOcRef: "secgroup/sg-web/vpc1/ip6"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want: %s got: %s", jsonutils.Marshal(want), jsonutils.Marshal(got))
+	}
+
+	add, remove := addrSetDiff([]string{"192.168.1.2", "192.168.1.9"}, want[4].Addresses)
+	if !reflect.DeepEqual(add, []string{"192.168.1.3"}) || !reflect.DeepEqual(remove, []string{"192.168.1.9"}) {
+		t.Errorf("addrSetDiff: add %v, remove %v", add, remove)
+	}
+}
@@ -130,6 +130,7 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 	}
 
 	ovndb.Mark(ctx)
+	ovndb.ClaimSecgroupAddressSets(ctx, mss.Vpcs)
 	for _, vpc := range mss.Vpcs {
 		if vpc.Id == apis.DEFAULT_VPC_ID {
 			continue
@@ -187,6 +188,18 @@ func (w *Worker) run(ctx context.Context, mss *agentmodels.ModelSets) (err error
 		}
 		routes := resolveRoutes(vpc, mss)
 		ovndb.ClaimRoutes(ctx, vpc, routes)
+		if vpcHasEipgw(vpc) {
+			for _, natgateway := range vpc.NatGateways {
+				ovndb.ClaimNatGateway(ctx, natgateway, w.opts)
+			}
+		}
+	}
+	for _, peering := range mss.VpcPeeringConnections {
+		if err := vpcPeeringCheck(peering); err != nil {
+			log.Warningf("vpc peering %s(%s) skipped: %v", peering.Name, peering.Id, err)
+			continue
+		}
+		ovndb.ClaimVpcPeering(ctx, peering)
 	}
 	for _, vpc := range mss.Vpcs {
 		if vpc.Id == apis.DEFAULT_VPC_ID {
@@ -192,6 +192,8 @@ func OvnNbctlArgsDestroy(irows []types.IRow) []string {
 		case *ovn_nb.LogicalRouterStaticRoute:
 		case *ovn_nb.ACL:
 		case *ovn_nb.QoS:
+		case *ovn_nb.NAT:
+		case *ovn_nb.GatewayChassis:
 		default:
 			if !irow.OvsdbIsRoot() {
 				panic(irow.OvsdbTableName())
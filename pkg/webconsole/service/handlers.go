@@ -68,6 +68,8 @@ func initHandlers(app *appsrv.Application) {
 	app.AddHandler("POST", ApiPathPrefix+"server/<id>", auth.Authenticate(handleServerRemoteConsole))
 	app.AddHandler("POST", ApiPathPrefix+"adb/<id>/shell", auth.Authenticate(handleAdbShell))
 	app.AddHandler("POST", ApiPathPrefix+"server-rdp/<id>", auth.Authenticate(handleServerRemoteRDPConsole))
+	app.AddHandler("POST", ApiPathPrefix+"server/<id>/serial-log", auth.Authenticate(handleServerSerialLog))
+	app.AddHandler("POST", ApiPathPrefix+"server/<id>/screendump", auth.Authenticate(handleServerScreenDump))
 	app.AddHandler("GET", ApiPathPrefix+"sftp/<session-id>/list", server.HandleSftpList)
 	app.AddHandler("GET", ApiPathPrefix+"sftp/<session-id>/download", server.HandleSftpDownload)
 	app.AddHandler("POST", ApiPathPrefix+"sftp/<session-id>/upload", server.HandleSftpUpload)
@@ -350,6 +352,30 @@ func handleServerRemoteRDPConsole(ctx context.Context, w http.ResponseWriter, r
 	handleDataSession(ctx, info, w, "rdp", url.Values{"password": {info.GetPassword()}}, false)
 }
 
+// handleServerSerialLog returns a page of guest serial console log,
+// use offset of the response to scroll back
+func handleServerSerialLog(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	handleServerBootDiagnostics(ctx, w, r, "serial-log")
+}
+
+func handleServerScreenDump(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	handleServerBootDiagnostics(ctx, w, r, "screendump")
+}
+
+func handleServerBootDiagnostics(ctx context.Context, w http.ResponseWriter, r *http.Request, specific string) {
+	env, err := fetchCloudEnv(ctx, w, r)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	ret, err := modules.Servers.GetSpecific(env.ClientSessin, env.Params["<id>"], specific, env.Body)
+	if err != nil {
+		httperrors.GeneralServerError(ctx, w, err)
+		return
+	}
+	sendJSON(w, ret)
+}
+
 func responsePublicCloudConsole(ctx context.Context, info *session.RemoteConsoleInfo, w http.ResponseWriter) {
 	params, err := info.GetConnectParams()
 	if err != nil {